@@ -0,0 +1,311 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/renan-dev/devinit/internal/browser"
+	"github.com/renan-dev/devinit/internal/docs"
+	"github.com/renan-dev/devinit/internal/generator"
+	"github.com/renan-dev/devinit/internal/i18n"
+	"github.com/renan-dev/devinit/internal/template"
+	"github.com/spf13/cobra"
+)
+
+func newBrowseCmd() *cobra.Command {
+	var filter template.Filter
+	var advanced bool
+
+	cmd := &cobra.Command{
+		Use:   "browse",
+		Short: "Browse templates and create a project from one",
+		Long: `Browse the templates in a terminal UI: type to narrow the list down by fuzzy
+search over names, descriptions and tags, read the README and the variables
+of the highlighted template in the side pane, and press Enter to create a
+project from it. devinit then asks for the project name, the profile and the
+variables, defaulting to those of the template, and runs devinit new with
+them, printing the command line to repeat it with. Variables the template
+marks advanced or hidden are only asked for with --advanced.
+
+Keys:
+  ↑/↓, Ctrl-P/Ctrl-N  highlight the previous or next template
+  PgUp/PgDn           scroll the side pane
+  Ctrl-U              clear the search
+  Enter               create a project from the highlighted template
+  Esc                 clear the search, or quit
+
+Examples:
+  devinit browse
+  devinit browse --lang python
+  devinit browse --advanced`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			templates, err := getGenerator().FindTemplates(filter)
+			if err != nil {
+				return err
+			}
+			if len(templates) == 0 {
+				fmt.Println(i18n.T("templates.no_matches"))
+				return nil
+			}
+
+			items := make([]browser.Item, len(templates))
+			for i, tmpl := range templates {
+				items[i] = browserItem(tmpl)
+			}
+			chosen, err := browser.Run(items, os.Stdin, os.Stdout)
+			if errors.Is(err, browser.ErrNotTerminal) {
+				return usageError(i18n.Errorf("browse.not_terminal"))
+			}
+			if err != nil || chosen < 0 {
+				return err
+			}
+
+			newArgs, err := newProjectWizard(templates[chosen], advanced, bufio.NewReader(os.Stdin), os.Stdout)
+			if err != nil || newArgs == nil {
+				return err
+			}
+			line := []string{"devinit", "new"}
+			for _, arg := range newArgs {
+				line = append(line, shellQuote(arg))
+			}
+			fmt.Fprintln(os.Stderr, i18n.T("browse.running", strings.Join(line, " ")))
+
+			return runNew(cmd, newArgs)
+		},
+	}
+
+	cmd.Flags().StringVar(&filter.Language, "lang", "", "only list templates for this language")
+	cmd.Flags().StringVar(&filter.Type, "type", "", "only list templates of this project type")
+	cmd.Flags().StringSliceVar(&filter.Tags, "tag", nil, "only list templates with this tag (repeatable)")
+	cmd.Flags().BoolVar(&advanced, "advanced", false, "also ask for the advanced and hidden variables of the template")
+
+	return cmd
+}
+
+// runNew runs the new command of the tree of cmd with args, the flags
+// missing from them read from the environment as for devinit new
+func runNew(cmd *cobra.Command, args []string) error {
+	newCmd, _, err := cmd.Root().Find([]string{"new"})
+	if err != nil {
+		return err
+	}
+	if err := newCmd.ParseFlags(args); err != nil {
+		return err
+	}
+	if err := applyEnv(newCmd.Flags()); err != nil {
+		return err
+	}
+	args = newCmd.Flags().Args()
+	if err := newCmd.ValidateArgs(args); err != nil {
+		return err
+	}
+	return newCmd.RunE(newCmd, args)
+}
+
+// browserItem lists a template in the browser, with its README, when it
+// has one, and its reference documentation as the detail
+func browserItem(tmpl *template.Template) browser.Item {
+	detail := docs.Markdown(tmpl)
+	if readme, err := os.ReadFile(filepath.Join(tmpl.Path, "README.md")); err == nil {
+		detail = strings.TrimSpace(string(readme)) + "\n\n" + detail
+	}
+	if notice := tmpl.DeprecationNotice(); notice != "" {
+		detail = i18n.T("templates.deprecated", notice) + "\n\n" + detail
+	}
+	return browser.Item{
+		Title:       tmpl.ID,
+		Description: tmpl.Description,
+		Keywords:    strings.Join(append([]string{tmpl.Name, tmpl.Description, tmpl.GetType()}, tmpl.Tags...), " "),
+		Detail:      detail,
+	}
+}
+
+// wizardFlags maps the template variables standing for the common
+// variables (see flagVariables) to the flags of new setting them
+var wizardFlags = map[string]string{
+	"python_version": "python-version",
+	"include_docker": "docker",
+	"database":       "database",
+	"include_tests":  "tests",
+	"ci_provider":    "ci",
+	"observability":  "observability",
+	"api_docs":       "api-docs",
+	"docker_base":    "docker-base",
+	"image":          "image",
+}
+
+// newProjectWizard asks for the name of a project, the profile and the
+// variables of the template, defaulting to the template's, and returns the
+// arguments of devinit new generating it. Only the variables changed from
+// their defaults are passed; advanced variables are only asked for with
+// advanced (see Variable.Prompted). It returns nil when the input ends.
+func newProjectWizard(tmpl *template.Template, advanced bool, in *bufio.Reader, out io.Writer) ([]string, error) {
+	fmt.Fprintln(out, i18n.T("browse.template", tmpl.ID))
+
+	ask := func(prompt string, valid func(string) error) (string, bool) {
+		for {
+			fmt.Fprint(out, prompt)
+			line, err := in.ReadString('\n')
+			if err != nil && line == "" {
+				fmt.Fprintln(out)
+				return "", false
+			}
+			value := strings.TrimSpace(line)
+			if err := valid(value); err != nil {
+				fmt.Fprintln(out, err)
+				continue
+			}
+			return value, true
+		}
+	}
+
+	name, ok := ask(i18n.T("browse.name"), generator.ValidateProjectName)
+	if !ok {
+		return nil, nil
+	}
+	lang, framework, _ := strings.Cut(tmpl.ID, "/")
+	args := []string{name, "--lang", lang, "--framework", framework}
+
+	var profile template.Profile
+	if names := tmpl.ProfileNames(); len(names) > 0 {
+		chosen, ok := ask(i18n.T("browse.profile", strings.Join(names, ", ")), func(value string) error {
+			if value != "" && !contains(names, value) {
+				return i18n.Errorf("plan.invalid", value)
+			}
+			return nil
+		})
+		if !ok {
+			return nil, nil
+		}
+		if chosen != "" {
+			profile = tmpl.Profiles[chosen]
+			args = append(args, "--profile", chosen)
+		}
+	}
+
+	keys := make([]string, 0, len(tmpl.Variables))
+	for key := range tmpl.Variables {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		variable := tmpl.Variables[key]
+		// The project name was asked first
+		if key == "project_name" || !variable.Prompted(advanced) {
+			continue
+		}
+		def := ""
+		if variable.Default != nil {
+			def = fmt.Sprint(variable.Default)
+		}
+		// Profiles may set the built-in variable (IncludeDocker) a
+		// template variable (include_docker) stands for
+		for name, value := range profile.Variables {
+			if strings.EqualFold(name, strings.ReplaceAll(key, "_", "")) || name == key {
+				def = fmt.Sprint(value)
+			}
+		}
+
+		label := key
+		if variable.Description != "" {
+			label = variable.Description + " (" + key + ")"
+		}
+		hint := def
+		switch {
+		case len(variable.Choices) > 0:
+			hint = strings.Join(variable.ChoiceValues(), "/") + ": " + def
+			printChoices(out, variable.Choices)
+		case variable.Type == template.VariableTypeBool:
+			hint = "y/n: " + def
+		}
+
+		value, ok := ask(i18n.T("browse.variable", label, hint), func(value string) error {
+			return checkWizardValue(key, variable, choiceValue(variable, value), def)
+		})
+		if !ok {
+			return nil, nil
+		}
+		value = choiceValue(variable, value)
+		if variable.Type == template.VariableTypeBool && value != "" {
+			value = strconv.FormatBool(strings.HasPrefix(strings.ToLower(value), "y") || strings.EqualFold(value, "true"))
+		}
+		switch {
+		case value == "" || value == def:
+		case wizardFlags[key] != "":
+			args = append(args, "--"+wizardFlags[key]+"="+value)
+		default:
+			args = append(args, "--var", key+"="+value)
+		}
+	}
+	return args, nil
+}
+
+// printChoices lists choices with their labels and descriptions before
+// asking for one, when any has them
+func printChoices(out io.Writer, choices []template.Choice) {
+	width, labeled := 0, false
+	for _, choice := range choices {
+		width = max(width, len(choice.Value))
+		labeled = labeled || choice.Labeled()
+	}
+	if !labeled {
+		return
+	}
+	for _, choice := range choices {
+		if choice.Labeled() {
+			fmt.Fprintf(out, "  %-*s  %s\n", width, choice.Value, choice)
+		} else {
+			fmt.Fprintf(out, "  %s\n", choice.Value)
+		}
+	}
+}
+
+// choiceValue returns the value of the choice of a variable whose label was
+// entered, so either may be typed; other values are returned as they are
+func choiceValue(variable template.Variable, value string) string {
+	for _, choice := range variable.Choices {
+		if choice.Label != "" && strings.EqualFold(choice.Label, value) {
+			return choice.Value
+		}
+	}
+	return value
+}
+
+// checkWizardValue checks a value entered for a variable; empty values
+// keep the default, which required variables must have
+func checkWizardValue(key string, variable template.Variable, value, def string) error {
+	if value == "" {
+		if variable.Required && def == "" {
+			return i18n.Errorf("browse.required", key)
+		}
+		return nil
+	}
+	switch {
+	case len(variable.Choices) > 0 && !contains(variable.ChoiceValues(), value):
+		return i18n.Errorf("browse.invalid", value, strings.Join(variable.ChoiceValues(), ", "))
+	case variable.Type == template.VariableTypeBool:
+		switch strings.ToLower(value) {
+		case "y", "yes", "true", "n", "no", "false":
+		default:
+			return i18n.Errorf("browse.invalid", value, "y/n")
+		}
+	case variable.Type == template.VariableTypeInt:
+		if _, err := strconv.Atoi(value); err != nil {
+			return i18n.Errorf("browse.invalid", value, err)
+		}
+	case variable.Pattern != "":
+		if re, err := regexp.Compile(variable.Pattern); err == nil && !re.MatchString(value) {
+			return i18n.Errorf("browse.invalid", value, variable.Pattern)
+		}
+	}
+	return nil
+}
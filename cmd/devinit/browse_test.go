@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/renan-dev/devinit/internal/generator"
+)
+
+func TestBrowseNotTerminal(t *testing.T) {
+	templatesDir := t.TempDir()
+	writeTemplate(t, templatesDir, "test/basic", `version: "1.0.0"
+name: basic
+language: test
+framework: basic
+`, nil)
+
+	// The output of execute is a pipe
+	if _, err := execute(t, "--templates-dir", templatesDir, "browse"); exitCode(err) != exitUsage {
+		t.Errorf("browse without a terminal exit code = %d (%v), want %d", exitCode(err), err, exitUsage)
+	}
+}
+
+func TestRunNew(t *testing.T) {
+	// The root sets --templates-dir to its default
+	browse, _, err := newRootCmd().Find([]string{"browse"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	isolate(t)
+	templatesDirFlag = t.TempDir()
+	writeTemplate(t, templatesDirFlag, "test/basic", `version: "1.0.0"
+name: basic
+language: test
+framework: basic
+files:
+  - src: README.md.tmpl
+    dest: README.md
+`, map[string]string{"README.md.tmpl": "# {{ .ProjectName }}\n"})
+	t.Chdir(t.TempDir())
+
+	// The flags of new are read from the environment as for devinit new
+	t.Setenv("DEVINIT_DRY_RUN", "true")
+	if err := runNew(browse, []string{"api", "demo", "--lang", "test", "--framework", "basic"}); err != nil {
+		t.Fatalf("runNew() unexpected error: %v", err)
+	}
+	if _, err := os.Stat("demo"); !os.IsNotExist(err) {
+		t.Errorf("DEVINIT_DRY_RUN was ignored, demo was generated: %v", err)
+	}
+
+	if err := runNew(browse, []string{"api", "demo", "extra", "--lang", "test"}); exitCode(err) != exitUsage {
+		t.Errorf("runNew() with too many arguments exit code = %d (%v), want %d", exitCode(err), err, exitUsage)
+	}
+}
+
+func TestNewProjectWizard(t *testing.T) {
+	templatesDir := t.TempDir()
+	writeTemplate(t, templatesDir, "test/basic", `version: "1.0.0"
+name: basic
+language: test
+framework: basic
+variables:
+  docker_base:
+    type: choice
+    choices: ["slim", "alpine"]
+    default: slim
+  port:
+    type: int
+    default: 8080
+`, nil)
+	tmpl, err := generator.NewGenerator(templatesDir).GetTemplate("test/basic")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A variable of the template named port is its own, not .Port
+	args, err := newProjectWizard(tmpl, false, bufio.NewReader(strings.NewReader("demo\nalpine\n9000\n")), io.Discard)
+	want := []string{"demo", "--lang", "test", "--framework", "basic", "--docker-base=alpine", "--var", "port=9000"}
+	if err != nil || !reflect.DeepEqual(args, want) {
+		t.Errorf("newProjectWizard() = %q, %v, want %q", args, err, want)
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/renan-dev/devinit/internal/audit"
+	"github.com/renan-dev/devinit/internal/config"
+	"github.com/renan-dev/devinit/internal/generator"
+	"github.com/renan-dev/devinit/internal/i18n"
+	"github.com/renan-dev/devinit/internal/paths"
+	"github.com/spf13/cobra"
+)
+
+// Caches removed by cache clean
+const (
+	cacheRender   = "render"
+	cacheProbes   = "probes"
+	cacheClones   = "clones"
+	cacheEmbedded = "embedded"
+)
+
+// location is a file or directory devinit keeps outside projects
+type location struct {
+	name string
+	path string // empty when disabled
+}
+
+func newCacheCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Show and clean the files devinit keeps",
+		Long: `Show and clean the files devinit keeps outside projects.
+
+They follow the XDG base directories: config.yaml in $XDG_CONFIG_HOME/devinit,
+caches in $XDG_CACHE_HOME/devinit, user templates in $XDG_DATA_HOME/devinit and
+the history of generated projects in $XDG_STATE_HOME/devinit (~/.config,
+~/.cache, ~/.local/share and ~/.local/state when unset).
+
+DEVINIT_CACHE_DIR and DEVINIT_STATE_DIR set the cache and state directories
+themselves. Cache and state directories that cannot be written, as in
+read-only containers, fall back to the temporary directory.`,
+	}
+
+	cmd.AddCommand(newCacheInfoCmd())
+	cmd.AddCommand(newCacheCleanCmd())
+
+	return cmd
+}
+
+func newCacheInfoCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "info",
+		Short: "Show where devinit keeps its files and their sizes",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			locations, err := devinitLocations()
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			defer w.Flush()
+			fmt.Fprintln(w, i18n.T("cache.table_header"))
+			for _, loc := range locations {
+				if loc.path == "" {
+					fmt.Fprintf(w, "%s\t%s\t\t\n", loc.name, i18n.T("cache.disabled"))
+					continue
+				}
+				size, files, err := paths.Size(loc.path)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%d\n", loc.name, loc.path, formatSize(size), files)
+			}
+			return nil
+		},
+	}
+}
+
+func newCacheCleanCmd() *cobra.Command {
+	var only []string
+
+	cmd := &cobra.Command{
+		Use:   "clean",
+		Short: "Remove cached files",
+		Long: `Remove cached rendered files, probed tool versions, leftover clones of
+remote templates and extracted built-in templates. The config, user templates and the history of generated
+projects are never removed. Only cache entries are deleted, so a cache.dir
+shared with other files is safe to clean.
+
+Examples:
+  devinit cache clean
+  devinit cache clean --only probes`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			selected := map[string]bool{}
+			for _, name := range only {
+				switch name {
+				case cacheRender, cacheProbes, cacheClones, cacheEmbedded:
+					selected[name] = true
+				default:
+					return usageError(i18n.Errorf("cache.unknown", name, cacheRender, cacheProbes, cacheClones, cacheEmbedded))
+				}
+			}
+
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			var total int64
+			clean := func(name, path string, remove func() (int64, error)) error {
+				if path == "" || (len(selected) > 0 && !selected[name]) {
+					return nil
+				}
+				freed, err := remove()
+				if err != nil {
+					return i18n.Errorf("cache.clean_failed", path, err)
+				}
+				total += freed
+				fmt.Println(i18n.T("cache.cleaned", name, formatSize(freed)))
+				return nil
+			}
+
+			renderDir, err := cfg.Cache.RenderDir()
+			if err != nil {
+				return err
+			}
+			if err := clean(cacheRender, renderDir, generator.NewRenderCache(renderDir).Clean); err != nil {
+				return err
+			}
+
+			probeFile, err := cfg.Cache.ProbeFile()
+			if err != nil {
+				return err
+			}
+			if err := clean(cacheProbes, probeFile, func() (int64, error) { return removeAll(probeFile) }); err != nil {
+				return err
+			}
+
+			clones, err := cloneDir()
+			if err != nil {
+				return err
+			}
+			if err := clean(cacheClones, clones, func() (int64, error) { return removeAll(clones) }); err != nil {
+				return err
+			}
+
+			extracted, err := embeddedDir()
+			if err != nil {
+				return err
+			}
+			if err := clean(cacheEmbedded, extracted, func() (int64, error) { return removeAll(extracted) }); err != nil {
+				return err
+			}
+
+			fmt.Println(i18n.T("cache.freed", formatSize(total)))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&only, "only", nil, "clean only these caches: render, probes, clones, embedded")
+
+	return cmd
+}
+
+// devinitLocations lists the files and directories devinit keeps outside
+// projects
+func devinitLocations() ([]location, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	configFile, err := config.DefaultPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := paths.DataDir()
+	if err != nil {
+		return nil, err
+	}
+	history, err := audit.DefaultPath()
+	if err != nil {
+		return nil, err
+	}
+	renderDir, err := cfg.Cache.RenderDir()
+	if err != nil {
+		return nil, err
+	}
+	probeFile, err := cfg.Cache.ProbeFile()
+	if err != nil {
+		return nil, err
+	}
+	clones, err := cloneDir()
+	if err != nil {
+		return nil, err
+	}
+	extracted, err := embeddedDir()
+	if err != nil {
+		return nil, err
+	}
+
+	return []location{
+		{"config", configFile},
+		{"templates", filepath.Join(data, "templates")},
+		{"history", history},
+		{cacheRender, renderDir},
+		{cacheProbes, probeFile},
+		{cacheClones, clones},
+		{cacheEmbedded, extracted},
+	}, nil
+}
+
+// cloneDir returns the directory remote templates are cloned into while
+// they are imported
+func cloneDir() (string, error) {
+	dir, err := paths.CacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, cacheClones), nil
+}
+
+// embeddedDir returns the directory the templates embedded in the binary are
+// extracted to
+func embeddedDir() (string, error) {
+	dir, err := paths.CacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, cacheEmbedded), nil
+}
+
+// removeAll removes path and returns the bytes freed
+func removeAll(path string) (int64, error) {
+	size, _, err := paths.Size(path)
+	if err != nil {
+		return 0, err
+	}
+	return size, os.RemoveAll(path)
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/renan-dev/devinit/internal/config"
+	"github.com/renan-dev/devinit/internal/errcode"
+	"github.com/renan-dev/devinit/internal/i18n"
+	"github.com/renan-dev/devinit/internal/template"
+	"github.com/renan-dev/devinit/internal/validator"
+	"github.com/spf13/cobra"
+)
+
+func newDoctorCmd() *cobra.Command {
+	var (
+		templateName     string
+		strict           bool
+		warningsAsErrors bool
+		refresh          bool
+		porcelain        bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check system requirements",
+		Long: `Check that all required system dependencies are installed.
+
+Without --template, the requirements of every available template are checked.
+
+Exits with 4 when a required dependency is missing. Version mismatches and
+missing optional dependencies are warnings; --strict turns version mismatches
+into errors and --warnings-as-errors fails on any warning.
+
+Tool versions are cached for a short time (cache.probe_ttl, default 10m) and
+re-probed when the executable changes; --refresh probes every tool again.`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			gen := getGenerator()
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			var templates []*template.Template
+			if templateName != "" {
+				tmpl, err := loadTemplateRef(gen, templateName)
+				if err != nil {
+					return err
+				}
+				templates = append(templates, tmpl)
+			} else {
+				all, err := gen.FindTemplates(template.Filter{})
+				if err != nil {
+					return err
+				}
+				templates = all
+			}
+
+			level := validator.ValidationBasic
+			if strict {
+				level = validator.ValidationStrict
+			}
+
+			cache := openProbeCache(cfg, refresh)
+			defer cache.Save()
+
+			if !porcelain {
+				fmt.Println(i18n.T("doctor.checking"))
+			}
+			failed, warned := 0, 0
+			for _, tmpl := range templates {
+				f, w := checkTemplateRequirements(tmpl, newSystemValidator(cfg, level, cache), porcelain)
+				failed += f
+				warned += w
+			}
+			if warningsAsErrors {
+				failed += warned
+			}
+
+			if failed > 0 {
+				return errcode.New(errcode.RequirementMissing, i18n.Errorf("doctor.failed", failed))
+			}
+
+			if !porcelain {
+				fmt.Println("\n" + i18n.T("doctor.ok"))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&templateName, "template", "", "check requirements for specific template (name[@version])")
+	cmd.Flags().BoolVar(&strict, "strict", false, "treat version mismatches as errors")
+	cmd.Flags().BoolVar(&warningsAsErrors, "warnings-as-errors", false, "fail when any check produces a warning")
+	cmd.Flags().BoolVar(&refresh, "refresh", false, "probe every tool again instead of reusing cached versions")
+	cmd.Flags().BoolVar(&porcelain, "porcelain", false, porcelainUsage)
+
+	return cmd
+}
+
+// newSystemValidator returns a validator for level that applies the
+// requirement policy of cfg and reuses tool versions from cache
+func newSystemValidator(cfg *config.Config, level validator.ValidationLevel, cache *validator.ProbeCache) *validator.SystemValidator {
+	v := validator.NewSystemValidator(level)
+	v.Policy, _ = cfg.Policy.RequirementLevels() // validated when loading
+	v.Cache = cache
+	return v
+}
+
+// openProbeCache opens the tool version cache configured in cfg, or returns
+// nil when caching is disabled or unavailable
+func openProbeCache(cfg *config.Config, refresh bool) *validator.ProbeCache {
+	path, err := cfg.Cache.ProbeFile()
+	if err != nil || path == "" {
+		return nil
+	}
+	ttl, _ := cfg.Cache.TTL() // validated when loading
+	return validator.OpenProbeCache(path, ttl, refresh)
+}
+
+// checkTemplateRequirements prints the status of each system requirement and
+// check of a template, as porcelain records if asked, and returns the number
+// of errors and warnings
+func checkTemplateRequirements(tmpl *template.Template, v *validator.SystemValidator, porcelain bool) (failed, warned int) {
+	var record requirementRecorder
+	if porcelain {
+		record = func(status, name, message string) {
+			printRecord(os.Stdout, "requirement", tmpl.ID, status, name, message)
+		}
+	} else {
+		fmt.Printf("\n%s (%s)\n", tmpl.ID, tmpl.Version)
+		if len(tmpl.Requirements.System) == 0 && len(tmpl.Requirements.Checks) == 0 {
+			fmt.Println("  " + i18n.T("doctor.no_requirements"))
+			return 0, 0
+		}
+	}
+
+	reqs := make([]validator.Requirement, 0, len(tmpl.Requirements.System))
+	for _, sysReq := range tmpl.Requirements.System {
+		reqs = append(reqs, validator.FromTemplateRequirement(sysReq))
+	}
+	checks := make([]validator.Check, 0, len(tmpl.Requirements.Checks))
+	for _, check := range tmpl.Requirements.Checks {
+		checks = append(checks, validator.FromTemplateCheck(check, tmpl.Path))
+	}
+
+	unmet, warned := checkRequirements(os.Stdout, reqs, checks, v, record)
+	return len(unmet), warned
+}
+
+// requirementRecorder receives the outcome of a requirement or check: its
+// status (ok, warning or error), name and problem
+type requirementRecorder func(status, name, message string)
+
+// checkRequirements writes the status of each requirement and check to w, or
+// passes it to record when set, and returns the commands and checks that are
+// unmet and the number of warnings
+func checkRequirements(w io.Writer, reqs []validator.Requirement, checks []validator.Check, v *validator.SystemValidator, record requirementRecorder) (unmet []string, warned int) {
+	report := func(name, label string, result *validator.ValidationResult) {
+		var outcome int
+		if record != nil {
+			outcome = recordResult(record, name, result)
+		} else {
+			outcome = printResult(w, label, result)
+		}
+		switch outcome {
+		case resultFailed:
+			unmet = append(unmet, name)
+		case resultWarned:
+			warned++
+		}
+	}
+
+	for _, req := range reqs {
+		label := req.Command
+		if req.Version != "" {
+			label += " " + req.Version
+		}
+		if req.When != "" {
+			label += " (" + i18n.T("doctor.when", req.When) + ")"
+		}
+		if req.Required != v.Required(req) {
+			key := "doctor.optional_at"
+			if v.Required(req) {
+				key = "doctor.required_at"
+			}
+			label += " (" + i18n.T(key, v.Level) + ")"
+		}
+
+		result, err := v.Validate([]validator.Requirement{req})
+		if err != nil {
+			if record != nil {
+				record("error", req.Command, err.Error())
+			} else {
+				fmt.Fprintf(w, "  ✗ %s: %v\n", label, err)
+			}
+			unmet = append(unmet, req.Command)
+			continue
+		}
+
+		if age, ok := v.CachedAge(req.Command); ok {
+			label += " [" + i18n.T("doctor.cached", age.Round(time.Second)) + "]"
+		}
+		report(req.Command, label, result)
+	}
+
+	for _, check := range checks {
+		label := check.Label()
+		if check.When != "" {
+			label += " (" + i18n.T("doctor.when", check.When) + ")"
+		}
+		report(check.Label(), label, v.ValidateCheck(check))
+	}
+
+	return unmet, warned
+}
+
+// Outcomes of a requirement or check reported by printResult and
+// recordResult
+const (
+	resultOK = iota
+	resultWarned
+	resultFailed
+)
+
+// printResult writes one doctor line for label with the first error or
+// warning of result and how to fix it
+func printResult(w io.Writer, label string, result *validator.ValidationResult) int {
+	switch {
+	case result.HasErrors():
+		printProblem(w, "✗", label, result.Errors[0])
+		return resultFailed
+	case result.HasWarnings():
+		printProblem(w, "!", label, result.Warnings[0])
+		return resultWarned
+	default:
+		fmt.Fprintf(w, "  ✓ %s\n", label)
+		return resultOK
+	}
+}
+
+// recordResult passes the outcome of result with its first error or warning
+// to record
+func recordResult(record requirementRecorder, name string, result *validator.ValidationResult) int {
+	switch {
+	case result.HasErrors():
+		record("error", name, result.Errors[0].Message)
+		return resultFailed
+	case result.HasWarnings():
+		record("warning", name, result.Warnings[0].Message)
+		return resultWarned
+	default:
+		record("ok", name, "")
+		return resultOK
+	}
+}
+
+func printProblem(w io.Writer, mark, label string, problem validator.ValidationError) {
+	fmt.Fprintf(w, "  %s %s: %s\n", mark, label, problem.Message)
+	if problem.InstallHint != "" {
+		fmt.Fprintln(w, "      "+i18n.T("doctor.fix", problem.InstallHint))
+	}
+}
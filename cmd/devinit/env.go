@@ -0,0 +1,89 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/renan-dev/devinit/internal/i18n"
+	"github.com/renan-dev/devinit/internal/ownership"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// envPrefix starts the environment variables that stand in for flags
+const envPrefix = "DEVINIT_"
+
+// envAnnotation marks flags set from the environment with the variable name
+const envAnnotation = "devinit_env"
+
+// envNames holds the variables of flags not named after them
+var envNames = map[string]string{
+	"chown": ownership.EnvOwner,
+}
+
+// envSkipped are the flags that only make sense on the command line
+var envSkipped = map[string]bool{
+	"help":    true,
+	"version": true,
+}
+
+// envHelp documents the environment variables in --help
+const envHelp = `Environment:
+  Every flag can also be set with DEVINIT_<FLAG>, the flag name in upper case
+  with dashes as underscores (DEVINIT_NO_COLOR=true, DEVINIT_CI=github).
+  Flags on the command line win over the environment, which wins over the
+  config file. --chown is DEVINIT_OWNER; DEVINIT_LOCALE selects the message
+  language.`
+
+// flagEnvName returns the environment variable of a flag
+func flagEnvName(flag string) string {
+	if name, ok := envNames[flag]; ok {
+		return name
+	}
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
+
+// bindEnv makes the commands below root read flags missing from the command
+// line from their environment variables before they run
+func bindEnv(root *cobra.Command) {
+	next := root.PersistentPreRunE
+	root.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if err := applyEnv(cmd.Flags()); err != nil {
+			return err
+		}
+		if next != nil {
+			return next(cmd, args)
+		}
+		return nil
+	}
+}
+
+// applyEnv sets the flags not given on the command line from the non-empty
+// environment variables. They count as changed, so they override the config
+// file and template defaults like the flags would.
+func applyEnv(flags *pflag.FlagSet) error {
+	var err error
+	flags.VisitAll(func(f *pflag.Flag) {
+		if err != nil || f.Changed || envSkipped[f.Name] {
+			return
+		}
+		name := flagEnvName(f.Name)
+		value := os.Getenv(name)
+		if value == "" {
+			return
+		}
+		if setErr := flags.Set(f.Name, value); setErr != nil {
+			err = usageError(i18n.Errorf("usage.env_invalid", name, value, setErr))
+			return
+		}
+		flags.SetAnnotation(f.Name, envAnnotation, []string{name})
+	})
+	return err
+}
+
+// fromEnv reports whether a flag was set from the environment rather than
+// the command line
+func fromEnv(cmd *cobra.Command, flag string) bool {
+	f := cmd.Flags().Lookup(flag)
+	return f != nil && len(f.Annotations[envAnnotation]) > 0
+}
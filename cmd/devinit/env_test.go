@@ -0,0 +1,139 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/renan-dev/devinit/internal/errcode"
+	"github.com/spf13/cobra"
+)
+
+func TestFlagEnvName(t *testing.T) {
+	tests := []struct {
+		flag string
+		want string
+	}{
+		{flag: "lang", want: "DEVINIT_LANG"},
+		{flag: "ci", want: "DEVINIT_CI"},
+		{flag: "no-color", want: "DEVINIT_NO_COLOR"},
+		{flag: "templates-dir", want: "DEVINIT_TEMPLATES_DIR"},
+		{flag: "chown", want: "DEVINIT_OWNER"},
+	}
+
+	for _, tt := range tests {
+		if got := flagEnvName(tt.flag); got != tt.want {
+			t.Errorf("flagEnvName(%q) = %q, want %q", tt.flag, got, tt.want)
+		}
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		env      map[string]string
+		wantLang string
+		wantCI   string // "config" when the config file would decide
+		wantEnv  bool   // --lang set from the environment
+		wantCode errcode.Code
+	}{
+		{
+			name:     "defaults",
+			wantLang: "python",
+			wantCI:   "config",
+		},
+		{
+			name:     "environment",
+			env:      map[string]string{"DEVINIT_LANG": "go"},
+			wantLang: "go",
+			wantCI:   "config",
+			wantEnv:  true,
+		},
+		{
+			name:     "flag beats environment",
+			args:     []string{"--lang", "node"},
+			env:      map[string]string{"DEVINIT_LANG": "go"},
+			wantLang: "node",
+			wantCI:   "config",
+		},
+		{
+			name:     "environment beats config",
+			env:      map[string]string{"DEVINIT_CI": "gitlab"},
+			wantLang: "python",
+			wantCI:   "gitlab",
+		},
+		{
+			name:     "empty variables are ignored",
+			env:      map[string]string{"DEVINIT_LANG": "", "DEVINIT_CI": ""},
+			wantLang: "python",
+			wantCI:   "config",
+		},
+		{
+			name:     "invalid value",
+			env:      map[string]string{"DEVINIT_RETRIES": "many"},
+			wantCode: errcode.Usage,
+		},
+		{
+			name:     "help and version are skipped",
+			env:      map[string]string{"DEVINIT_HELP": "true", "DEVINIT_VERSION": "true"},
+			wantLang: "python",
+			wantCI:   "config",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			var lang, ci string
+			var retries int
+			var fromEnvironment, ran, skippedSet bool
+			cmd := &cobra.Command{
+				Use:           "test",
+				Version:       "1.0.0",
+				SilenceErrors: true,
+				SilenceUsage:  true,
+				RunE: func(cmd *cobra.Command, args []string) error {
+					ran = true
+					// Commands fall back to the config file for flags that
+					// were not changed
+					if !cmd.Flags().Changed("ci") {
+						ci = "config"
+					}
+					fromEnvironment = fromEnv(cmd, "lang")
+					skippedSet = cmd.Flags().Changed("help") || cmd.Flags().Changed("version")
+					return nil
+				},
+			}
+			cmd.Flags().StringVar(&lang, "lang", "python", "")
+			cmd.Flags().StringVar(&ci, "ci", "", "")
+			cmd.Flags().IntVar(&retries, "retries", 0, "")
+			bindEnv(cmd)
+			cmd.SetArgs(tt.args)
+
+			err := cmd.Execute()
+			if tt.wantCode != "" {
+				if errcode.Of(err) != tt.wantCode {
+					t.Fatalf("Execute() error = %v, want code %s", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Execute() unexpected error: %v", err)
+			}
+			if !ran {
+				t.Fatal("the command did not run")
+			}
+			if lang != tt.wantLang || ci != tt.wantCI {
+				t.Errorf("lang, ci = %q, %q, want %q, %q", lang, ci, tt.wantLang, tt.wantCI)
+			}
+			if skippedSet {
+				t.Error("--help or --version was set from the environment")
+			}
+			if fromEnvironment != tt.wantEnv {
+				t.Errorf("fromEnv(lang) = %v, want %v", fromEnvironment, tt.wantEnv)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/renan-dev/devinit/internal/errcode"
+	"github.com/renan-dev/devinit/internal/i18n"
+	"github.com/spf13/cobra"
+)
+
+// Exit codes. They are part of the CLI contract and documented in the help
+// of the root command.
+const (
+	exitOK          = 0
+	exitFailure     = 1
+	exitUsage       = 2
+	exitValidation  = 3
+	exitRequirement = 4
+	exitConflict    = 5
+	exitTemplate    = 6
+)
+
+// exitCodeHelp documents the exit codes in --help
+const exitCodeHelp = `Exit codes:
+  0  success
+  1  other failure (e.g. a lifecycle hook failed)
+  2  usage error (unknown command, bad flag or argument)
+  3  validation error (invalid project name, variable or profile, or a
+     project drifting from its template)
+  4  missing system requirement
+  5  conflict with existing files
+  6  template error (not found, invalid or failed to render)`
+
+// exitCode maps an error returned by a command to the process exit code
+func exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+
+	switch errcode.Of(err) {
+	case errcode.Usage:
+		return exitUsage
+	case errcode.VariableInvalid, errcode.Validation:
+		return exitValidation
+	case errcode.RequirementMissing:
+		return exitRequirement
+	case errcode.Conflict:
+		return exitConflict
+	case errcode.TemplateNotFound, errcode.TemplateInvalid:
+		return exitTemplate
+	default:
+		return exitFailure
+	}
+}
+
+// usageError classifies an error caused by how the command was invoked
+func usageError(err error) error {
+	return errcode.New(errcode.Usage, err)
+}
+
+// classifyUsageErrors makes flag and argument errors of root and its
+// subcommands usage errors
+func classifyUsageErrors(root *cobra.Command) {
+	root.SetFlagErrorFunc(func(c *cobra.Command, err error) error {
+		return usageError(err)
+	})
+	classifyArgErrors(root)
+}
+
+// classifyArgErrors wraps the argument validation of cmd and its subcommands
+func classifyArgErrors(cmd *cobra.Command) {
+	if args := cmd.Args; args != nil {
+		cmd.Args = func(c *cobra.Command, a []string) error {
+			return usageError(args(c, a))
+		}
+	}
+
+	for _, sub := range cmd.Commands() {
+		classifyArgErrors(sub)
+	}
+}
+
+// unknownCommand rejects arguments to a command that only groups
+// subcommands, suggesting close matches
+func unknownCommand(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return nil
+	}
+
+	msg := i18n.T("usage.unknown_command", args[0], cmd.CommandPath())
+	if suggestions := cmd.SuggestionsFor(args[0]); len(suggestions) > 0 {
+		msg += "\n\n" + i18n.T("usage.did_you_mean") + "\n\t" + strings.Join(suggestions, "\n\t")
+	}
+	return usageError(errors.New(msg))
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/renan-dev/devinit/internal/errcode"
+)
+
+func TestExitCode(t *testing.T) {
+	failure := errors.New("failure")
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "success", err: nil, want: exitOK},
+		{name: "unclassified", err: failure, want: exitFailure},
+		{name: "usage", err: errcode.New(errcode.Usage, failure), want: exitUsage},
+		{name: "invalid variable", err: errcode.New(errcode.VariableInvalid, failure), want: exitValidation},
+		{name: "validation failed", err: errcode.New(errcode.Validation, failure), want: exitValidation},
+		{name: "missing requirement", err: errcode.New(errcode.RequirementMissing, failure), want: exitRequirement},
+		{name: "conflict", err: errcode.New(errcode.Conflict, failure), want: exitConflict},
+		{name: "template not found", err: errcode.New(errcode.TemplateNotFound, failure), want: exitTemplate},
+		{name: "invalid template", err: errcode.New(errcode.TemplateInvalid, failure), want: exitTemplate},
+		{name: "hook failed", err: errcode.New(errcode.HookFailed, failure), want: exitFailure},
+		{name: "smoke test failed", err: errcode.New(errcode.SmokeTestFailed, failure), want: exitFailure},
+		{name: "scan failed", err: errcode.New(errcode.ScanFailed, failure), want: exitFailure},
+		{name: "wrapped", err: fmt.Errorf("failed to generate project: %w", errcode.New(errcode.Conflict, failure)), want: exitConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.err); got != tt.want {
+				t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExitCodeWarningsAsErrors(t *testing.T) {
+	templatesDir := t.TempDir()
+	writeTemplate(t, templatesDir, "test/old", `version: "1.0.0"
+name: old
+language: test
+framework: old
+deprecated: true
+requirements:
+  system:
+    - command: devinit-test-missing-tool
+      required: false
+files:
+  - src: README.md
+    dest: README.md
+`, map[string]string{"README.md": "# old\n"})
+
+	tests := []struct {
+		args []string
+		want int
+	}{
+		{args: []string{"templates", "validate"}, want: exitOK},
+		{args: []string{"templates", "validate", "--warnings-as-errors"}, want: exitTemplate},
+		{args: []string{"doctor"}, want: exitOK},
+		{args: []string{"doctor", "--warnings-as-errors"}, want: exitRequirement},
+		{args: []string{"templates", "validate", "--warnings-as-error"}, want: exitUsage},
+	}
+
+	for _, tt := range tests {
+		_, err := execute(t, append([]string{"--templates-dir", templatesDir}, tt.args...)...)
+		if got := exitCode(err); got != tt.want {
+			t.Errorf("devinit %v exit code = %d (%v), want %d", tt.args, got, err, tt.want)
+		}
+	}
+}
+
+func TestExitCodeDrift(t *testing.T) {
+	templatesDir := t.TempDir()
+	writeTemplate(t, templatesDir, "test/basic", `version: "1.0.0"
+name: basic
+language: test
+framework: basic
+files:
+  - src: README.md
+    dest: README.md
+`, map[string]string{"README.md": "# basic\n"})
+	t.Chdir(t.TempDir())
+
+	if _, err := execute(t, "--templates-dir", templatesDir, "new", "api", "demo", "--lang", "test", "--framework", "basic"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := execute(t, "--templates-dir", templatesDir, "validate", "demo", "--drift"); exitCode(err) != exitOK {
+		t.Errorf("validate --drift of an unchanged project exit code = %d (%v), want %d", exitCode(err), err, exitOK)
+	}
+	if err := os.WriteFile(filepath.Join("demo", "README.md"), []byte("# changed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := execute(t, "--templates-dir", templatesDir, "validate", "demo", "--drift")
+	if got := exitCode(err); got != exitValidation || errcode.Of(err) != errcode.Validation {
+		t.Errorf("validate --drift of a changed project = %d (%v), want %d and %s", got, err, exitValidation, errcode.Validation)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/renan-dev/devinit/internal/i18n"
+	"github.com/spf13/cobra"
+	"github.com/spf13/cobra/doc"
+)
+
+func newGenDocsCmd() *cobra.Command {
+	var manDir, markdownDir string
+
+	cmd := &cobra.Command{
+		Use:   "gen-docs",
+		Short: "Generate man pages and Markdown reference",
+		Long: `Generate a man page (section 1) and a Markdown page for every command,
+from the command tree itself, for packagers to ship with the binary.
+
+Markdown pages carry no generation date and man pages are dated from
+SOURCE_DATE_EPOCH when it is set, so builds are reproducible.
+
+Example:
+  devinit gen-docs --man share/man/man1 --markdown docs/cli`,
+		Hidden: true,
+		Args:   cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if manDir == "" && markdownDir == "" {
+				return usageError(i18n.Errorf("gendocs.dir_required"))
+			}
+
+			root := cmd.Root()
+			root.DisableAutoGenTag = true
+
+			if manDir != "" {
+				if err := os.MkdirAll(manDir, 0755); err != nil {
+					return err
+				}
+				header := &doc.GenManHeader{
+					Title:   "DEVINIT",
+					Section: "1",
+					Source:  "devinit " + version,
+					Manual:  "devinit Manual",
+				}
+				if err := doc.GenManTree(root, header, manDir); err != nil {
+					return i18n.Errorf("gendocs.failed", err)
+				}
+				fmt.Println(i18n.T("gendocs.man_written", manDir))
+			}
+
+			if markdownDir != "" {
+				if err := os.MkdirAll(markdownDir, 0755); err != nil {
+					return err
+				}
+				if err := doc.GenMarkdownTree(root, markdownDir); err != nil {
+					return i18n.Errorf("gendocs.failed", err)
+				}
+				fmt.Println(i18n.T("gendocs.markdown_written", markdownDir))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&manDir, "man", "", "directory to write man pages to")
+	cmd.Flags().StringVar(&markdownDir, "markdown", "", "directory to write Markdown pages to")
+
+	return cmd
+}
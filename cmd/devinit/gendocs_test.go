@@ -0,0 +1,72 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func TestGenDocs(t *testing.T) {
+	dir := t.TempDir()
+	manDir, markdownDir := filepath.Join(dir, "man"), filepath.Join(dir, "markdown")
+	t.Setenv("SOURCE_DATE_EPOCH", "1767225600")
+	if _, err := execute(t, "gen-docs", "--man", manDir, "--markdown", markdownDir); err != nil {
+		t.Fatalf("gen-docs unexpected error: %v", err)
+	}
+
+	var check func(cmd *cobra.Command)
+	check = func(cmd *cobra.Command) {
+		for _, sub := range cmd.Commands() {
+			if sub.IsAvailableCommand() && !sub.IsAdditionalHelpTopicCommand() {
+				check(sub)
+			}
+		}
+		name := strings.ReplaceAll(cmd.CommandPath(), " ", "_")
+		data, err := os.ReadFile(filepath.Join(markdownDir, name+".md"))
+		if err != nil {
+			t.Errorf("no Markdown page for %s: %v", cmd.CommandPath(), err)
+			return
+		}
+		page := string(data)
+		// The synopsis is the long description, examples included
+		if !strings.Contains(page, cmd.Short) || !strings.Contains(page, cmd.Long) {
+			t.Errorf("Markdown page of %s lacks its description", cmd.CommandPath())
+		}
+		if cmd.Example != "" && !strings.Contains(page, cmd.Example) {
+			t.Errorf("Markdown page of %s lacks its examples", cmd.CommandPath())
+		}
+		cmd.NonInheritedFlags().VisitAll(func(f *pflag.Flag) {
+			if !f.Hidden && !strings.Contains(page, "--"+f.Name) {
+				t.Errorf("Markdown page of %s lacks --%s", cmd.CommandPath(), f.Name)
+			}
+		})
+		if strings.Contains(page, "Auto generated") {
+			t.Errorf("Markdown page of %s is dated", cmd.CommandPath())
+		}
+
+		man, err := os.ReadFile(filepath.Join(manDir, strings.ReplaceAll(cmd.CommandPath(), " ", "-")+".1"))
+		if err != nil {
+			t.Errorf("no man page for %s: %v", cmd.CommandPath(), err)
+			return
+		}
+		if !strings.Contains(string(man), `.TH "DEVINIT" "1" "Jan 2026"`) {
+			t.Errorf("man page of %s is not dated from SOURCE_DATE_EPOCH: %.80q", cmd.CommandPath(), man)
+		}
+	}
+	check(newRootCmd())
+
+	// gen-docs itself is hidden
+	if _, err := os.Stat(filepath.Join(markdownDir, "devinit_gen-docs.md")); !os.IsNotExist(err) {
+		t.Errorf("the hidden gen-docs command has a page: %v", err)
+	}
+}
+
+func TestGenDocsWithoutDirectory(t *testing.T) {
+	if _, err := execute(t, "gen-docs"); exitCode(err) != exitUsage {
+		t.Errorf("gen-docs without a directory exit code = %d (%v), want %d", exitCode(err), err, exitUsage)
+	}
+}
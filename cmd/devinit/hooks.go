@@ -0,0 +1,250 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/renan-dev/devinit/internal/generator"
+	"github.com/renan-dev/devinit/internal/i18n"
+	"github.com/spf13/cobra"
+)
+
+// hookInfo is the JSON representation of a hook of a generated project
+type hookInfo struct {
+	Name       string   `json:"name"`
+	Stage      string   `json:"stage"`
+	Command    string   `json:"command"`
+	When       string   `json:"when,omitempty"`
+	After      []string `json:"after,omitempty"`
+	Idempotent bool     `json:"idempotent"`
+}
+
+func newHooksCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "hooks",
+		Short: "List and run the hooks of a generated project",
+		Long: `List and run again the lifecycle hooks of a generated project, such as
+regenerating certificates or seeding a database, without remembering their
+commands. The hooks are those recorded in the project's .devinit.yaml, else
+those of the template version it was generated from.`,
+		Args: unknownCommand,
+	}
+
+	cmd.AddCommand(newHooksListCmd())
+	cmd.AddCommand(newHooksRunCmd())
+
+	return cmd
+}
+
+func newHooksListCmd() *cobra.Command {
+	var (
+		dir       string
+		output    string
+		porcelain bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the hooks of a generated project",
+		Long: `List the hooks of a generated project in the order they run. Hooks
+without a name are named after their stage and position, e.g.
+post_generate[1].`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := selectPorcelain(cmd, porcelain, &output); err != nil {
+				return err
+			}
+			if output != outputPorcelain {
+				if err := validateOutputFormat(output); err != nil {
+					return err
+				}
+			}
+
+			gen, err := projectGenerator(dir)
+			if err != nil {
+				return err
+			}
+			hooks, err := gen.ProjectHooks(dir)
+			if err != nil {
+				return err
+			}
+
+			switch output {
+			case outputJSON:
+				infos := make([]hookInfo, 0, len(hooks))
+				for _, h := range hooks {
+					infos = append(infos, hookInfo{Name: h.Label, Stage: h.Stage, Command: h.Run, When: h.When, After: h.After, Idempotent: h.Idempotent})
+				}
+				return printJSON(infos)
+			case outputPorcelain:
+				for _, h := range hooks {
+					printRecord(os.Stdout, "hook", h.Label, h.Stage, fmt.Sprint(h.Idempotent), h.Run)
+				}
+				return nil
+			}
+
+			if len(hooks) == 0 {
+				fmt.Println(i18n.T("hooks.none", dir))
+				return nil
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			defer w.Flush()
+			fmt.Fprintln(w, i18n.T("hooks.table_header"))
+			for _, h := range hooks {
+				idempotent := ""
+				if h.Idempotent {
+					idempotent = "✓"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", h.Label, h.Stage, idempotent, h.When, strings.Join(strings.Fields(h.Run), " "))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&dir, "dir", ".", "directory of the generated project")
+	cmd.Flags().StringVarP(&output, "output", "o", outputText, "output format (text, json)")
+	cmd.Flags().BoolVar(&porcelain, "porcelain", false, porcelainUsage)
+
+	return cmd
+}
+
+func newHooksRunCmd() *cobra.Command {
+	var (
+		dir        string
+		idempotent bool
+		force      bool
+		output     string
+		porcelain  bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "run [name...]",
+		Short: "Run hooks of a generated project again",
+		Long: `Run the named hooks of a generated project again, with the variables it
+was generated with, in the order they run at generation. The hooks they run
+after are not run first; when conditions still apply. Output is streamed and
+logged to .devinit/logs.
+
+Hooks not marked idempotent may not be safe to run twice and need --force.
+--idempotent runs every hook that is.
+
+Examples:
+  devinit hooks run seed-db
+  devinit hooks run --idempotent
+  devinit hooks run post_generate[0] --force`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := selectPorcelain(cmd, porcelain, &output); err != nil {
+				return err
+			}
+			if output != outputPorcelain {
+				if err := validateOutputFormat(output); err != nil {
+					return err
+				}
+			}
+			if len(args) == 0 && !idempotent {
+				return usageError(i18n.Errorf("hooks.nothing_to_run"))
+			}
+
+			gen, err := projectGenerator(dir)
+			if err != nil {
+				return err
+			}
+			hooks, err := gen.ProjectHooks(dir)
+			if err != nil {
+				return err
+			}
+			selected, err := selectHooks(hooks, args, idempotent, force)
+			if err != nil {
+				return err
+			}
+
+			run, err := gen.RunProjectHooks(dir, selected, &generator.Options{
+				Progress:   os.Stderr,
+				HookOutput: os.Stderr,
+			})
+			if run == nil || output == outputJSON && err != nil {
+				return err
+			}
+
+			switch output {
+			case outputJSON:
+				return printJSON(run)
+			case outputPorcelain:
+				for _, h := range run.Hooks {
+					printHookRecord(os.Stdout, h)
+				}
+				for _, warning := range run.Warnings {
+					printRecord(os.Stdout, "warning", warning)
+				}
+			default:
+				printHookRun(run)
+			}
+			return err
+		},
+	}
+
+	cmd.Flags().StringVar(&dir, "dir", ".", "directory of the generated project")
+	cmd.Flags().BoolVar(&idempotent, "idempotent", false, "run every hook marked idempotent")
+	cmd.Flags().BoolVar(&force, "force", false, "run named hooks that are not marked idempotent")
+	cmd.Flags().StringVarP(&output, "output", "o", outputText, "output format (text, json)")
+	cmd.Flags().BoolVar(&porcelain, "porcelain", false, porcelainUsage)
+
+	return cmd
+}
+
+// selectHooks returns the hooks named, in the order they run, plus every
+// idempotent hook when idempotent is set. Hooks not marked idempotent are
+// refused unless force is set.
+func selectHooks(hooks []generator.ProjectHook, names []string, idempotent, force bool) ([]generator.ProjectHook, error) {
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		found := false
+		for _, h := range hooks {
+			if h.Label == name {
+				found = true
+				if !h.Idempotent && !force {
+					return nil, usageError(i18n.Errorf("hooks.not_idempotent", name))
+				}
+			}
+		}
+		if !found {
+			return nil, usageError(i18n.Errorf("hooks.unknown", name))
+		}
+		wanted[name] = true
+	}
+
+	var selected []generator.ProjectHook
+	for _, h := range hooks {
+		if wanted[h.Label] || (idempotent && h.Idempotent) {
+			selected = append(selected, h)
+		}
+	}
+	return selected, nil
+}
+
+// printHookRun prints the human-readable result of hooks run again
+func printHookRun(run *generator.HookRun) {
+	fmt.Println(i18n.T("summary.hooks", hooksRun(run.Hooks)))
+	printHookResults(run.Hooks, "  ")
+	for _, warning := range run.Warnings {
+		fmt.Println("  ! " + warning)
+	}
+	fmt.Println(i18n.T("summary.hook_log", run.HookLog))
+	fmt.Println(i18n.T("summary.duration", run.Duration.Round(time.Millisecond)))
+}
+
+// printHookRecord writes the porcelain record of a hook result
+func printHookRecord(w io.Writer, h generator.HookResult) {
+	status := "ok"
+	switch {
+	case h.Skipped:
+		status = "skipped"
+	case h.Error != "":
+		status = "failed"
+	}
+	printRecord(w, "hook", h.Stage, status, h.Command, h.Name)
+}
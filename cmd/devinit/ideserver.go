@@ -0,0 +1,414 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/renan-dev/devinit/internal/config"
+	"github.com/renan-dev/devinit/internal/docs"
+	"github.com/renan-dev/devinit/internal/errcode"
+	"github.com/renan-dev/devinit/internal/generator"
+	"github.com/renan-dev/devinit/internal/i18n"
+	"github.com/renan-dev/devinit/internal/jsonrpc"
+	"github.com/renan-dev/devinit/internal/template"
+	"github.com/renan-dev/devinit/internal/validator"
+	"github.com/spf13/cobra"
+)
+
+// ideProtocolVersion is the version of the ide-server protocol; it changes
+// when methods or results change incompatibly
+const ideProtocolVersion = 1
+
+func newIDEServerCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "ide-server",
+		Short: "Serve editor plugins over JSON-RPC on stdio",
+		Long: `Serve editor plugins (VS Code, JetBrains, ...) over JSON-RPC 2.0 on standard
+input and output, framed like the Language Server Protocol with
+Content-Length headers, so they integrate devinit without scraping its output.
+
+Methods:
+  initialize          server name, version, protocol version and methods
+  templates/list      templates matching {language, type, tags, query}
+  templates/describe  variables, profiles, versions, Markdown reference and
+                      JSON Schema of the variables of {template}
+  project/validate    problems of the inputs of a project: {template, name,
+                      profile, variables}
+  project/generate    generates {template, name, directory, profile,
+                      variables, dry_run, skip_hooks, skip_validation} and
+                      returns the result of devinit new --output json
+
+With a progress_token, project/generate reports requirement checks, hook
+output and retries in $/progress notifications shaped like those of LSP
+(begin, report, end). Generations run one at a time, so each is given a port
+of its own. Failed requests carry the devinit error code (such as
+VARIABLE_INVALID) in error.data.code. $/cancelRequest, shutdown and exit
+work as in LSP.
+
+Example:
+  printf 'Content-Length: 46\r\n\r\n{"jsonrpc":"2.0","id":1,"method":"initialize"}' | devinit ide-server`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return newIDEServer().Serve(cmd.Context(), os.Stdin, os.Stdout)
+		},
+	}
+}
+
+// ideErrorData is the data of failed requests
+type ideErrorData struct {
+	Code errcode.Code `json:"code"`
+}
+
+// newIDEServer returns the JSON-RPC server of ide-server
+func newIDEServer() *jsonrpc.Server {
+	s := jsonrpc.NewServer()
+	s.ErrorData = func(err error) interface{} {
+		return ideErrorData{Code: errcode.Of(err)}
+	}
+	methods := map[string]jsonrpc.Handler{
+		"templates/list":     ideListTemplates,
+		"templates/describe": ideDescribeTemplate,
+		"project/validate":   ideValidateProject,
+		"project/generate":   ideGenerateProject,
+	}
+	names := []string{"initialize"}
+	for method, handler := range methods {
+		s.Register(method, handler)
+		names = append(names, method)
+	}
+	sort.Strings(names)
+
+	s.Register("initialize", func(ctx context.Context, params json.RawMessage, notify jsonrpc.Notifier) (interface{}, error) {
+		return map[string]interface{}{
+			"name":             "devinit",
+			"version":          version,
+			"protocol_version": ideProtocolVersion,
+			"methods":          names,
+		}, nil
+	})
+	return s
+}
+
+// ideListParams are the parameters of templates/list
+type ideListParams struct {
+	Language string   `json:"language"`
+	Type     string   `json:"type"`
+	Tags     []string `json:"tags"`
+	Query    string   `json:"query"`
+}
+
+func ideListTemplates(ctx context.Context, params json.RawMessage, notify jsonrpc.Notifier) (interface{}, error) {
+	var p ideListParams
+	if err := jsonrpc.DecodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	templates, err := getGenerator().FindTemplates(template.Filter{Language: p.Language, Type: p.Type, Tags: p.Tags, Query: p.Query})
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]templateListEntry, 0, len(templates))
+	for _, tmpl := range templates {
+		entries = append(entries, newTemplateListEntry(tmpl))
+	}
+	return map[string]interface{}{"templates": entries}, nil
+}
+
+// ideTemplateDescription is the result of templates/describe
+type ideTemplateDescription struct {
+	templateListEntry
+	Versions  []string         `json:"versions"`
+	Variables []ideVariable    `json:"variables"`
+	Profiles  []ideProfile     `json:"profiles"`
+	Docs      string           `json:"docs"`
+	Schema    *docs.Schema     `json:"schema"`
+	Origin    *template.Origin `json:"origin,omitempty"`
+	NextSteps []string         `json:"next_steps,omitempty"`
+}
+
+// ideVariable describes a template variable; project_name is the name of
+// the project. Defaults computed from other variables are given as their
+// expression instead of a default.
+type ideVariable struct {
+	Name              string      `json:"name"`
+	Type              string      `json:"type"`
+	Required          bool        `json:"required"`
+	Default           interface{} `json:"default,omitempty"`
+	DefaultExpression string      `json:"default_expression,omitempty"`
+	Choices           []string    `json:"choices,omitempty"`
+	Pattern           string      `json:"pattern,omitempty"`
+	Description       string      `json:"description,omitempty"`
+	Advanced          bool        `json:"advanced,omitempty"`
+	Hidden            bool        `json:"hidden,omitempty"`
+}
+
+// ideProfile describes a template profile
+type ideProfile struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Variables   map[string]interface{} `json:"variables"`
+}
+
+func ideDescribeTemplate(ctx context.Context, params json.RawMessage, notify jsonrpc.Notifier) (interface{}, error) {
+	var p struct {
+		Template string `json:"template"`
+	}
+	if err := jsonrpc.DecodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	gen := getGenerator()
+	tmpl, err := loadTemplateRef(gen, p.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	description := ideTemplateDescription{
+		templateListEntry: newTemplateListEntry(tmpl),
+		Variables:         []ideVariable{},
+		Profiles:          []ideProfile{},
+		Docs:              docs.Markdown(tmpl),
+		Schema:            docs.JSONSchema(tmpl),
+		Origin:            tmpl.Origin,
+		NextSteps:         tmpl.NextSteps,
+	}
+	if description.Versions, err = gen.ListTemplateVersions(tmpl.ID); err != nil || description.Versions == nil {
+		description.Versions = []string{}
+	}
+	for name, variable := range tmpl.Variables {
+		entry := ideVariable{
+			Name:              name,
+			Type:              string(variable.Type),
+			Required:          variable.Required,
+			Default:           variable.Default,
+			DefaultExpression: variable.DefaultExpression(),
+			Choices:           variable.ChoiceValues(),
+			Pattern:           variable.Pattern,
+			Description:       variable.Description,
+			Advanced:          variable.Advanced,
+			Hidden:            variable.Hidden,
+		}
+		if entry.DefaultExpression != "" {
+			entry.Default = nil
+		}
+		description.Variables = append(description.Variables, entry)
+	}
+	sort.Slice(description.Variables, func(i, j int) bool { return description.Variables[i].Name < description.Variables[j].Name })
+	for _, name := range tmpl.ProfileNames() {
+		profile := tmpl.Profiles[name]
+		description.Profiles = append(description.Profiles, ideProfile{Name: name, Description: profile.Description, Variables: profile.Variables})
+	}
+	return description, nil
+}
+
+// ideProjectParams are the parameters of project/validate and
+// project/generate
+type ideProjectParams struct {
+	Template  string                 `json:"template"` // python/fastapi[@version]
+	Name      string                 `json:"name"`
+	Directory string                 `json:"directory"` // parent of the project, the server's working directory by default
+	Profile   string                 `json:"profile"`
+	Variables map[string]interface{} `json:"variables"`
+
+	DryRun         bool `json:"dry_run"`
+	SkipHooks      bool `json:"skip_hooks"`
+	SkipValidation bool `json:"skip_validation"`
+
+	ProgressToken json.RawMessage `json:"progress_token,omitempty"`
+}
+
+// outputDir returns the directory the project is generated in
+func (p *ideProjectParams) outputDir() string {
+	return filepath.Join(p.Directory, p.Name)
+}
+
+// variables returns the variables of the parameters with whole numbers,
+// which JSON decodes as float64, as int
+func (p *ideProjectParams) variables() map[string]interface{} {
+	variables := make(map[string]interface{}, len(p.Variables))
+	for key, value := range p.Variables {
+		if f, ok := value.(float64); ok && f == math.Trunc(f) && math.Abs(f) < math.MaxInt32 {
+			value = int(f)
+		}
+		variables[key] = value
+	}
+	return variables
+}
+
+// ideProblem is an invalid input reported by project/validate
+type ideProblem struct {
+	Field   string       `json:"field"` // name, profile or variables.<name>
+	Code    errcode.Code `json:"code"`
+	Message string       `json:"message"`
+}
+
+func ideValidateProject(ctx context.Context, params json.RawMessage, notify jsonrpc.Notifier) (interface{}, error) {
+	var p ideProjectParams
+	if err := jsonrpc.DecodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	tmpl, err := loadTemplateRef(getGenerator(), p.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	problems := []ideProblem{}
+	add := func(field string, err error) {
+		problems = append(problems, ideProblem{Field: field, Code: errcode.Of(err), Message: err.Error()})
+	}
+	if err := generator.ValidateProjectDir(p.Name, p.outputDir()); err != nil {
+		add("name", err)
+	}
+	if _, ok := tmpl.Profiles[p.Profile]; p.Profile != "" && !ok {
+		add("profile", errcode.New(errcode.VariableInvalid, i18n.Errorf("generator.unknown_profile", p.Profile, tmpl.Language, tmpl.Framework, strings.Join(tmpl.ProfileNames(), ", "))))
+	}
+	variables := p.variables()
+	names := make([]string, 0, len(tmpl.Variables))
+	for name := range tmpl.Variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value, ok := variables[name]
+		switch {
+		case ok:
+			if err := generator.ValidateVariables(tmpl, map[string]interface{}{name: value}, validator.ValidationStrict); err != nil {
+				add("variables."+name, err)
+			}
+		case tmpl.Variables[name].Required && tmpl.Variables[name].Default == nil && name != "project_name":
+			if _, ok := tmpl.Profiles[p.Profile].Variables[name]; !ok {
+				add("variables."+name, errcode.New(errcode.VariableInvalid, i18n.Errorf("browse.required", name)))
+			}
+		}
+	}
+	return map[string]interface{}{"valid": len(problems) == 0, "problems": problems}, nil
+}
+
+// ideProgress is the value of a $/progress notification
+type ideProgress struct {
+	Kind    string `json:"kind"` // begin, report or end
+	Title   string `json:"title,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// progressWriter reports each line written to it as progress
+type progressWriter struct {
+	mu     sync.Mutex
+	buf    []byte
+	report func(message string)
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, p...)
+	for {
+		i := strings.IndexByte(string(w.buf), '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		if line := strings.TrimSpace(string(w.buf[:i])); line != "" {
+			w.report(line)
+		}
+		w.buf = w.buf[i+1:]
+	}
+}
+
+// ideGenerating serializes project/generate: a port is reserved by the
+// audit log entry of the generation using it, so concurrent generations
+// would otherwise be given the same port
+var ideGenerating sync.Mutex
+
+func ideGenerateProject(ctx context.Context, params json.RawMessage, notify jsonrpc.Notifier) (interface{}, error) {
+	var p ideProjectParams
+	if err := jsonrpc.DecodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if err := generator.ValidateProjectDir(p.Name, p.outputDir()); err != nil {
+		return nil, err
+	}
+	progress := func(value ideProgress) {
+		if p.ProgressToken != nil {
+			notify("$/progress", map[string]interface{}{"token": p.ProgressToken, "value": value})
+		}
+	}
+	out := &progressWriter{report: func(message string) {
+		progress(ideProgress{Kind: "report", Message: message})
+	}}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	name, templateVersion := splitTemplateRef(p.Template)
+	lang, framework, _ := strings.Cut(name, "/")
+	variables := p.variables()
+	variables["ProjectName"] = p.Name
+
+	// The common variables default like the flags of devinit new left unset
+	flagOpts := &newOptions{}
+	flagCmd := &cobra.Command{}
+	addVariableFlags(flagCmd, flagOpts)
+	defaults := flagVariables(flagCmd, flagOpts, variables)
+
+	genOpts := &generator.Options{
+		ProjectName:      p.Name,
+		Language:         lang,
+		Framework:        framework,
+		TemplateVersion:  templateVersion,
+		Variables:        variables,
+		Defaults:         defaults,
+		Profile:          p.Profile,
+		DryRun:           p.DryRun,
+		SkipHooks:        p.SkipHooks,
+		SkipValidation:   p.SkipValidation,
+		HookOutput:       out,
+		Progress:         out,
+		RejectDeprecated: cfg.Policy.DeprecatedTemplates == config.PolicyError,
+	}
+	if p.Directory != "" {
+		genOpts.OutputDir = p.outputDir()
+	}
+
+	ideGenerating.Lock()
+	defer ideGenerating.Unlock()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	gen := getGenerator()
+	if _, ok := variables["Port"]; !ok {
+		if port := defaultPort(gen, name, templateVersion, false); port != 0 {
+			defaults["Port"] = port
+		}
+	}
+
+	progress(ideProgress{Kind: "begin", Title: i18n.T("new.creating", lang, framework, p.Name)})
+	result, err := func() (*generator.GenerationResult, error) {
+		if !p.SkipValidation {
+			cache := openProbeCache(cfg, false)
+			defer cache.Save()
+			if err := checkProjectRequirements(out, gen, genOpts, newSystemValidator(cfg, genOpts.ValidationLevel(), cache)); err != nil {
+				return nil, err
+			}
+		}
+		// Generation itself cannot be interrupted
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return gen.Generate(genOpts)
+	}()
+	if err != nil {
+		progress(ideProgress{Kind: "end", Message: err.Error()})
+		return nil, err
+	}
+
+	if !p.DryRun {
+		recordGeneration(result, p.Name)
+	}
+	progress(ideProgress{Kind: "end", Message: i18n.T("summary.created", result.OutputDir)})
+	return result, nil
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/renan-dev/devinit/internal/errcode"
+	"github.com/renan-dev/devinit/internal/generator"
+)
+
+// ideTemplate isolates the test and makes a template with variables and a
+// port its templates directory
+func ideTemplate(t *testing.T) {
+	t.Helper()
+	isolate(t)
+	templatesDir := t.TempDir()
+	writeTemplate(t, templatesDir, "test/basic", `version: "1.0.0"
+name: basic
+language: test
+framework: basic
+requirements:
+  system:
+    - command: devinit-test-missing-tool
+      required: false
+variables:
+  owner:
+    type: string
+    required: true
+  workers:
+    type: int
+    default: 2
+  module:
+    type: string
+    default: acme
+    pattern: "^[a-z]+$"
+profiles:
+  team:
+    variables:
+      owner: platform
+healthcheck:
+  command: "true"
+  port: 18080
+files:
+  - src: README.md.tmpl
+    dest: README.md
+`, map[string]string{"README.md.tmpl": "{{ .Variables.owner }} {{ .Variables.workers }} {{ .Port }}\n"})
+	templatesDirFlag = templatesDir
+}
+
+func TestIDEValidateProject(t *testing.T) {
+	ideTemplate(t)
+
+	tests := []struct {
+		name   string
+		params string
+		want   []string // fields of the problems
+	}{
+		{
+			name:   "valid",
+			params: `{"template": "test/basic", "name": "demo", "variables": {"owner": "me", "workers": 4}}`,
+			want:   []string{},
+		},
+		{
+			name:   "whole numbers are ints",
+			params: `{"template": "test/basic", "name": "demo", "variables": {"owner": "me", "workers": 4.0}}`,
+			want:   []string{},
+		},
+		{
+			name:   "fractions are not",
+			params: `{"template": "test/basic", "name": "demo", "variables": {"owner": "me", "workers": 2.5}}`,
+			want:   []string{"variables.workers"},
+		},
+		{
+			name:   "required variable",
+			params: `{"template": "test/basic", "name": "demo"}`,
+			want:   []string{"variables.owner"},
+		},
+		{
+			name:   "required variable from the profile",
+			params: `{"template": "test/basic", "name": "demo", "profile": "team"}`,
+			want:   []string{},
+		},
+		{
+			name:   "every problem",
+			params: `{"template": "test/basic", "name": "Demo Project", "profile": "missing", "variables": {"module": "Acme"}}`,
+			want:   []string{"name", "profile", "variables.module", "variables.owner"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ideValidateProject(context.Background(), json.RawMessage(tt.params), nil)
+			if err != nil {
+				t.Fatalf("ideValidateProject() unexpected error: %v", err)
+			}
+			got := result.(map[string]interface{})
+			problems := got["problems"].([]ideProblem)
+			fields := []string{}
+			for _, problem := range problems {
+				fields = append(fields, problem.Field)
+				if problem.Code == "" || problem.Message == "" {
+					t.Errorf("problem %+v has no code or message", problem)
+				}
+			}
+			if !reflect.DeepEqual(fields, tt.want) || got["valid"] != (len(tt.want) == 0) {
+				t.Errorf("valid, fields = %v, %q, want %v, %q", got["valid"], fields, len(tt.want) == 0, tt.want)
+			}
+		})
+	}
+
+	if _, err := ideValidateProject(context.Background(), json.RawMessage(`{"template": "test/missing", "name": "demo"}`), nil); errcode.Of(err) != errcode.TemplateNotFound {
+		t.Errorf("ideValidateProject() of a missing template error = %v, want %s", err, errcode.TemplateNotFound)
+	}
+}
+
+func TestIDEProjectVariables(t *testing.T) {
+	p := ideProjectParams{Variables: map[string]interface{}{
+		"workers": 4.0,
+		"ratio":   0.5,
+		"huge":    1e12,
+		"name":    "demo",
+		"debug":   true,
+	}}
+	want := map[string]interface{}{
+		"workers": 4,
+		"ratio":   0.5,
+		"huge":    1e12,
+		"name":    "demo",
+		"debug":   true,
+	}
+	if got := p.variables(); !reflect.DeepEqual(got, want) {
+		t.Errorf("variables() = %#v, want %#v", got, want)
+	}
+}
+
+// ideNotifications collects the notifications of a handler
+type ideNotifications struct {
+	mu     sync.Mutex
+	values []ideProgress
+}
+
+func (n *ideNotifications) notify(method string, params interface{}) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if method != "$/progress" {
+		return
+	}
+	n.values = append(n.values, params.(map[string]interface{})["value"].(ideProgress))
+}
+
+func TestIDEGenerateProject(t *testing.T) {
+	ideTemplate(t)
+	dir := t.TempDir()
+
+	var notifications ideNotifications
+	params := `{"template": "test/basic", "name": "demo", "directory": "` + filepath.ToSlash(dir) + `", "variables": {"owner": "me", "workers": 3.0}, "dry_run": true, "progress_token": "gen-1"}`
+	result, err := ideGenerateProject(context.Background(), json.RawMessage(params), notifications.notify)
+	if err != nil {
+		t.Fatalf("ideGenerateProject() unexpected error: %v", err)
+	}
+	generated := result.(*generator.GenerationResult)
+	if !generated.DryRun || generated.OutputDir != filepath.Join(dir, "demo") || len(generated.Files) != 1 {
+		t.Errorf("result = %+v, want a dry run planning demo/README.md", generated)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "demo")); !os.IsNotExist(err) {
+		t.Errorf("the dry run wrote the project: %v", err)
+	}
+
+	values := notifications.values
+	if len(values) < 3 || values[0].Kind != "begin" || values[0].Title == "" || values[len(values)-1].Kind != "end" {
+		t.Fatalf("progress = %+v, want begin, reports and end", values)
+	}
+	reported := false
+	for _, value := range values[1 : len(values)-1] {
+		reported = reported || value.Kind == "report" && value.Message != ""
+		if value.Kind != "report" {
+			t.Errorf("progress %+v between begin and end, want reports", value)
+		}
+	}
+	if !reported {
+		t.Errorf("progress = %+v, want the requirement check reported", values)
+	}
+
+	// Without a progress token there are no notifications
+	notifications.values = nil
+	if _, err := ideGenerateProject(context.Background(), json.RawMessage(`{"template": "test/basic", "name": "demo", "variables": {"owner": "me"}, "dry_run": true}`), notifications.notify); err != nil {
+		t.Fatal(err)
+	}
+	if len(notifications.values) != 0 {
+		t.Errorf("progress without a token = %+v, want none", notifications.values)
+	}
+
+	if _, err := ideGenerateProject(context.Background(), json.RawMessage(`{"template": "test/basic", "name": "Demo Project"}`), notifications.notify); err == nil {
+		t.Error("ideGenerateProject() of an invalid name succeeded")
+	}
+}
+
+func TestIDEProjectDirectory(t *testing.T) {
+	ideTemplate(t)
+	dir := t.TempDir()
+	params := func(name string) json.RawMessage {
+		return json.RawMessage(`{"template": "test/basic", "name": "` + name + `", "directory": "` + filepath.ToSlash(dir) + `", "variables": {"owner": "me"}, "skip_validation": true}`)
+	}
+
+	// A project already in the directory is a conflict, and left alone
+	existing := filepath.Join(dir, "svc", "README.md")
+	if err := os.MkdirAll(filepath.Dir(existing), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(existing, []byte("mine\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	result, err := ideValidateProject(context.Background(), params("svc"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if problems := result.(map[string]interface{})["problems"].([]ideProblem); len(problems) != 1 || problems[0].Field != "name" || problems[0].Code != errcode.Conflict {
+		t.Errorf("problems of an existing project = %+v, want a conflict on the name", problems)
+	}
+	if _, err := ideGenerateProject(context.Background(), params("svc"), func(string, interface{}) {}); errcode.Of(err) != errcode.Conflict {
+		t.Errorf("ideGenerateProject() into an existing project error = %v, want %s", err, errcode.Conflict)
+	}
+	if data, err := os.ReadFile(existing); err != nil || string(data) != "mine\n" {
+		t.Errorf("existing README.md = %q, %v, want it untouched", data, err)
+	}
+
+	// A directory of the name where the server runs is not the project's
+	t.Chdir(t.TempDir())
+	if err := os.Mkdir("api", 0755); err != nil {
+		t.Fatal(err)
+	}
+	result, err = ideValidateProject(context.Background(), params("api"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if problems := result.(map[string]interface{})["problems"].([]ideProblem); len(problems) != 0 {
+		t.Errorf("problems with the name taken in the working directory = %+v, want none", problems)
+	}
+	if _, err := ideGenerateProject(context.Background(), params("api"), func(string, interface{}) {}); err != nil {
+		t.Fatalf("ideGenerateProject() with the name taken in the working directory unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "api", "README.md")); err != nil {
+		t.Errorf("the project was not generated in its directory: %v", err)
+	}
+}
+
+func TestIDEGenerateProjectConcurrently(t *testing.T) {
+	ideTemplate(t)
+	dir := t.TempDir()
+
+	// Each generation reserves its port before the next one picks its own
+	names := []string{"first", "second", "third"}
+	var wg sync.WaitGroup
+	errs := make([]error, len(names))
+	for i, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			params := `{"template": "test/basic", "name": "` + name + `", "directory": "` + filepath.ToSlash(dir) + `", "variables": {"owner": "me"}, "skip_validation": true}`
+			_, errs[i] = ideGenerateProject(context.Background(), json.RawMessage(params), func(string, interface{}) {})
+		}()
+	}
+	wg.Wait()
+
+	seen := make(map[string]string)
+	for i, name := range names {
+		if errs[i] != nil {
+			t.Fatalf("ideGenerateProject(%s) unexpected error: %v", name, errs[i])
+		}
+		data, err := os.ReadFile(filepath.Join(dir, name, "README.md"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if other, ok := seen[string(data)]; ok {
+			t.Errorf("%s and %s were generated with the same port: %q", other, name, data)
+		}
+		seen[string(data)] = name
+	}
+}
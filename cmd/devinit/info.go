@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/renan-dev/devinit/internal/generator"
+	"github.com/renan-dev/devinit/internal/i18n"
+	"github.com/renan-dev/devinit/internal/sbom"
+	"github.com/renan-dev/devinit/internal/template"
+	"github.com/spf13/cobra"
+)
+
+// projectInfo is the JSON representation of a generated project
+type projectInfo struct {
+	Template        string                 `json:"template"`
+	TemplateVersion string                 `json:"template_version"`
+	Environment     *template.Environment  `json:"environment,omitempty"`
+	Provenance      bool                   `json:"provenance"`
+	Variables       map[string]interface{} `json:"variables"`
+	Overrides       []string               `json:"overrides,omitempty"`
+	SBOM            string                 `json:"sbom,omitempty"`
+}
+
+func newInfoCmd() *cobra.Command {
+	var (
+		showSBOM bool
+		output   string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "info [dir]",
+		Short: "Show how a project was generated",
+		Long: `Show the template, version, environment and variables recorded in a
+generated project's .devinit.yaml.
+
+With --sbom, print the CycloneDX bill of materials of the dependencies the
+project declares, stored in .devinit/sbom.cdx.json at generation (after
+hooks installed dependencies). Projects without one are scanned now.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateOutputFormat(output); err != nil {
+				return err
+			}
+			dir := "."
+			if len(args) > 0 {
+				dir = args[0]
+			}
+
+			if showSBOM {
+				return printSBOM(dir)
+			}
+
+			metadata, err := generator.ReadMetadata(dir)
+			if err != nil {
+				return err
+			}
+			info := projectInfo{
+				Template:        metadata.Template.Name,
+				TemplateVersion: metadata.Template.Version,
+				Environment:     metadata.Environment,
+				Provenance:      metadata.Provenance,
+				Variables:       metadata.Variables,
+				Overrides:       metadata.Overrides,
+			}
+			if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(sbom.FileName))); err == nil {
+				info.SBOM = sbom.FileName
+			}
+
+			if output == outputJSON {
+				return printJSON(info)
+			}
+			printProjectInfo(info)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&showSBOM, "sbom", false, "print the CycloneDX bill of materials of the project's dependencies")
+	cmd.Flags().StringVarP(&output, "output", "o", outputText, "output format (text, json)")
+
+	return cmd
+}
+
+func printProjectInfo(info projectInfo) {
+	fmt.Println(i18n.T("info.template", info.Template, info.TemplateVersion))
+	if env := info.Environment; env != nil {
+		fmt.Println(i18n.T("info.generated", env.DevinitVersion, env.OS, env.Arch, env.User, env.Year))
+	}
+	if info.Provenance {
+		fmt.Println(i18n.T("info.provenance"))
+	}
+	if info.SBOM != "" {
+		fmt.Println(i18n.T("info.sbom", info.SBOM))
+	}
+	if len(info.Overrides) > 0 {
+		fmt.Println(i18n.T("info.overrides"))
+		for _, source := range info.Overrides {
+			fmt.Printf("  %s\n", source)
+		}
+	}
+
+	keys := make([]string, 0, len(info.Variables))
+	for key := range info.Variables {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	fmt.Println(i18n.T("info.variables"))
+	for _, key := range keys {
+		fmt.Printf("  %s: %v\n", key, info.Variables[key])
+	}
+}
+
+// printSBOM prints the stored bill of materials of the project in dir, or
+// one scanned now when none was stored
+func printSBOM(dir string) error {
+	data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(sbom.FileName)))
+	if err == nil {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if !os.IsNotExist(err) {
+		return err
+	}
+
+	fmt.Fprintln(os.Stderr, i18n.T("info.sbom_scanned", sbom.FileName))
+	components, warnings, err := sbom.Scan(dir)
+	if err != nil {
+		return err
+	}
+	for _, warning := range warnings {
+		fmt.Fprintln(os.Stderr, i18n.T("generator.sbom_skipped", warning))
+	}
+
+	project := filepath.Base(dir)
+	if abs, err := filepath.Abs(dir); err == nil {
+		project = filepath.Base(abs)
+	}
+	data, err = sbom.CycloneDX(project, sbom.Tool{Name: "devinit", Version: version}, components, time.Now())
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(data)
+	return err
+}
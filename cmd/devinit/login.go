@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/renan-dev/devinit/internal/credentials"
+	"github.com/renan-dev/devinit/internal/errcode"
+	"github.com/renan-dev/devinit/internal/i18n"
+	"github.com/spf13/cobra"
+)
+
+func newTemplatesLoginCmd() *cobra.Command {
+	var username string
+
+	cmd := &cobra.Command{
+		Use:   "login <host>",
+		Short: "Store a token for private template sources",
+		Long: `Store the token of a host in the OS keychain (the macOS keychain, or the
+Secret Service of GNOME Keyring or KWallet through secret-tool), for
+templates import, pull and push to reach private sources on it. The token is
+read from standard input, without echo on a terminal, so it stays out of the
+shell history:
+
+  echo "$TOKEN" | devinit templates login artifacts.acme.dev
+
+HTTPS archive hosts get the token as a bearer token, or in basic
+authentication with --username. Git hosts and registries always get basic
+authentication, with --username or the username they expect with tokens
+(x-access-token on GitHub, oauth2 on GitLab).
+
+Credentials are looked up in this order:
+  $DEVINIT_TOKEN_<HOST>  the host in upper case, other characters as _, such
+                         as DEVINIT_TOKEN_GITHUB_COM; $GITHUB_TOKEN, $GH_TOKEN
+                         and $GITLAB_TOKEN for github.com and gitlab.com
+  the keychain           tokens stored by this command
+  the netrc file         $NETRC or ~/.netrc
+
+Git sources are cloned by git, so its credential helpers and SSH keys in the
+agent are used first; registries use the credentials of docker login first,
+and s3:// archives those of the AWS CLI.
+
+Example:
+  devinit templates login github.com
+  devinit templates login nexus.acme.dev --username ci
+  devinit templates logout github.com`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			host := credentials.Host(args[0])
+			if host == "" {
+				return usageError(i18n.Errorf("templates.login_host", args[0]))
+			}
+			token, err := readToken(host)
+			if err != nil {
+				return err
+			}
+			if token == "" {
+				return usageError(i18n.Errorf("templates.login_empty"))
+			}
+
+			err = credentials.Store(host, credentials.Credentials{Username: username, Secret: token})
+			if errors.Is(err, credentials.ErrNoKeychain) {
+				return errcode.New(errcode.RequirementMissing, i18n.Errorf("templates.login_no_keychain", err, credentials.EnvVar(host)))
+			}
+			if err != nil {
+				return i18n.Errorf("templates.login_failed", err)
+			}
+			fmt.Println(i18n.T("templates.logged_in", host, credentials.KeychainName()))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&username, "username", "", "username to send with the token in basic authentication")
+
+	return cmd
+}
+
+func newTemplatesLogoutCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "logout <host>",
+		Short: "Remove the token stored for a host",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			host := credentials.Host(args[0])
+			err := credentials.Delete(host)
+			if errors.Is(err, credentials.ErrNotFound) {
+				fmt.Println(i18n.T("templates.logout_none", host))
+				return nil
+			}
+			if err != nil {
+				return i18n.Errorf("templates.login_failed", err)
+			}
+			fmt.Println(i18n.T("templates.logged_out", host))
+			return nil
+		},
+	}
+}
+
+// readToken reads a token from standard input, prompting for it without
+// echo on a terminal
+func readToken(host string) (string, error) {
+	info, err := os.Stdin.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		data, err := io.ReadAll(io.LimitReader(os.Stdin, 64<<10))
+		return strings.TrimSpace(string(data)), err
+	}
+
+	fmt.Fprint(os.Stderr, i18n.T("templates.login_prompt", host))
+	// stty is missing on Windows, where the token is echoed
+	if echo(false) == nil {
+		defer func() {
+			echo(true)
+			fmt.Fprintln(os.Stderr)
+		}()
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// echo turns the echo of the terminal on standard input on or off
+func echo(on bool) error {
+	mode := "-echo"
+	if on {
+		mode = "echo"
+	}
+	cmd := exec.Command("stty", mode)
+	cmd.Stdin = os.Stdin
+	return cmd.Run()
+}
+
+// newTemplatesGitCredentialCmd is the git credential helper template
+// imports configure, answering git with the credentials of package
+// credentials (see gitcredentials(7))
+func newTemplatesGitCredentialCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:    "git-credential <get|store|erase>",
+		Short:  "Answer git with the credentials of a host",
+		Hidden: true,
+		Args:   cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Only git's own helpers store and erase credentials
+			if args[0] != "get" {
+				return nil
+			}
+			request := make(map[string]string)
+			scanner := bufio.NewScanner(os.Stdin)
+			for scanner.Scan() && scanner.Text() != "" {
+				if key, value, ok := strings.Cut(scanner.Text(), "="); ok {
+					request[key] = value
+				}
+			}
+			if request["protocol"] != "https" || request["host"] == "" {
+				return nil
+			}
+
+			host := credentials.Host(request["host"])
+			creds, err := credentials.Lookup(host)
+			if err != nil || creds == nil {
+				return err
+			}
+			username := creds.BasicUsername(host)
+			if request["username"] != "" && creds.Username == "" {
+				username = request["username"]
+			}
+			fmt.Printf("username=%s\npassword=%s\n", username, creds.Secret)
+			return nil
+		},
+	}
+}
+
+// gitCredentialHelper returns the git credential helper running devinit
+// templates git-credential
+func gitCredentialHelper() string {
+	exe, err := os.Executable()
+	if err != nil {
+		return ""
+	}
+	return "!'" + strings.ReplaceAll(exe, "'", `'\''`) + "' templates git-credential"
+}
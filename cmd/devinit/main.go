@@ -4,8 +4,15 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 
+	"github.com/renan-dev/devinit/internal/config"
+	"github.com/renan-dev/devinit/internal/errcode"
 	"github.com/renan-dev/devinit/internal/generator"
+	"github.com/renan-dev/devinit/internal/i18n"
+	"github.com/renan-dev/devinit/internal/paths"
+	"github.com/renan-dev/devinit/internal/template"
+	embedded "github.com/renan-dev/devinit/templates"
 	"github.com/spf13/cobra"
 )
 
@@ -16,10 +23,14 @@ var (
 )
 
 func main() {
-	if err := newRootCmd().Execute(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+	setLocale()
+
+	cmd, err := newRootCmd().ExecuteC()
+	stopProfiling()
+	if err != nil {
+		printError(cmd, err)
 	}
+	os.Exit(exitCode(err))
 }
 
 func newRootCmd() *cobra.Command {
@@ -28,294 +39,257 @@ func newRootCmd() *cobra.Command {
 		Short: "Multi-language project scaffolding CLI",
 		Long: `devinit is a CLI tool that creates production-ready projects
 for multiple languages and frameworks with standardized structure,
-Docker support, and best practices built-in.`,
+Docker support, and best practices built-in.
+
+` + exitCodeHelp + "\n\n" + envHelp,
 		Version: fmt.Sprintf("%s (commit: %s, built: %s)", version, commit, date),
+
+		Args: unknownCommand,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+
+		// main prints errors in the active locale; usage is only hinted at
+		SilenceErrors: true,
+		SilenceUsage:  true,
 	}
 
 	// Add subcommands
 	rootCmd.AddCommand(newNewCmd())
+	rootCmd.AddCommand(newBrowseCmd())
+	rootCmd.AddCommand(newIDEServerCmd())
 	rootCmd.AddCommand(newValidateCmd())
 	rootCmd.AddCommand(newDoctorCmd())
 	rootCmd.AddCommand(newTemplatesCmd())
+	rootCmd.AddCommand(newInfoCmd())
+	rootCmd.AddCommand(newCacheCmd())
+	rootCmd.AddCommand(newHooksCmd())
+	rootCmd.AddCommand(newRenderCmd())
+	rootCmd.AddCommand(newGenDocsCmd())
 
 	// Global flags
 	rootCmd.PersistentFlags().BoolP("verbose", "v", false, "verbose output")
 	rootCmd.PersistentFlags().Bool("no-color", false, "disable colored output")
+	rootCmd.PersistentFlags().BoolVar(&offline, "offline", false, "skip what needs the network but is optional, such as the daily check of imported templates for updates")
+	rootCmd.PersistentFlags().StringVar(&templatesDirFlag, "templates-dir", "", "templates directory, ignoring the project's .devinit/templates (default: the user store, else the built-in templates)")
+	addProfilingFlags(rootCmd)
+	bindEnv(rootCmd)
 
-	return rootCmd
-}
-
-func newNewCmd() *cobra.Command {
-	var (
-		lang        string
-		framework   string
-		docker      bool
-		database    string
-		ci          string
-		noValidate  bool
-		dryRun      bool
-		pythonVersion string
-		includeTests  bool
-	)
-
-	cmd := &cobra.Command{
-		Use:   "new [type] [name]",
-		Short: "Create a new project",
-		Long: `Create a new project with the specified language and framework.
-
-Examples:
-  # Interactive mode
-  devinit new
-
-  # Non-interactive mode
-  devinit new api my-service --lang python --framework fastapi
-
-  # With all options
-  devinit new api my-service \
-    --lang python \
-    --framework fastapi \
-    --docker \
-    --database postgres \
-    --ci github`,
-		Args: cobra.MaximumNArgs(2),
-		RunE: func(cmd *cobra.Command, args []string) error {
-			return runNewCommand(args, lang, framework, database, pythonVersion, docker, includeTests, dryRun)
-		},
-	}
-
-	cmd.Flags().StringVar(&lang, "lang", "", "programming language (python, nodejs, kotlin)")
-	cmd.Flags().StringVar(&framework, "framework", "", "framework to use")
-	cmd.Flags().BoolVar(&docker, "docker", true, "include Docker configuration")
-	cmd.Flags().StringVar(&database, "database", "none", "database to configure (postgres, sqlite, none)")
-	cmd.Flags().StringVar(&ci, "ci", "", "CI provider (github, gitlab, none)")
-	cmd.Flags().BoolVar(&noValidate, "no-validate", false, "skip validation")
-	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "show what would be done without doing it")
-	cmd.Flags().StringVar(&pythonVersion, "python-version", "3.11", "Python version (python only)")
-	cmd.Flags().BoolVar(&includeTests, "tests", true, "include test setup")
+	classifyUsageErrors(rootCmd)
 
-	return cmd
+	return rootCmd
 }
 
 func newValidateCmd() *cobra.Command {
-	return &cobra.Command{
-		Use:   "validate",
-		Short: "Validate project structure",
-		Long:  "Validate that the current project follows devinit standards",
-		RunE: func(cmd *cobra.Command, args []string) error {
-			// TODO: Implement validation
-			fmt.Println("Validating project...")
-			return nil
-		},
-	}
-}
-
-func newDoctorCmd() *cobra.Command {
-	var templateName string
-
-	cmd := &cobra.Command{
-		Use:   "doctor",
-		Short: "Check system requirements",
-		Long:  "Check that all required system dependencies are installed",
-		RunE: func(cmd *cobra.Command, args []string) error {
-			// TODO: Implement doctor checks
-			fmt.Println("Checking system requirements...")
-			return nil
-		},
-	}
-
-	cmd.Flags().StringVar(&templateName, "template", "", "check requirements for specific template")
-
-	return cmd
-}
+	var drift, update, porcelain bool
 
-func newTemplatesCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "templates",
-		Short: "Manage templates",
-		Long:  "List, show, and manage project templates",
-	}
-
-	cmd.AddCommand(newTemplatesListCmd())
-	cmd.AddCommand(newTemplatesShowCmd())
-	cmd.AddCommand(newTemplatesValidateCmd())
-
-	return cmd
-}
-
-func newTemplatesListCmd() *cobra.Command {
-	return &cobra.Command{
-		Use:   "list",
-		Short: "List available templates",
+		Use:   "validate [dir]",
+		Short: "Validate project structure",
+		Long: `Validate that the current project follows devinit standards.
+
+With --drift, the template version recorded in .devinit.yaml is rendered
+again with the recorded variables and every generated file that was deleted
+or changed since is reported. Paths listed in the project's .devinitignore
+are user-owned and skipped. Projects generated from imported templates pin
+them in devinit.lock; when the template changed since, the check fails
+unless --update accepts it and updates the lock.
+
+The project's .devinit/config.yaml can pin the templates directory to
+render from, override the naming of the global config and list the files
+devinit manages; drift checks skip the others.`,
+		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			gen := getGenerator()
-			templates, err := gen.ListTemplates()
-			if err != nil {
-				return err
+			dir := "."
+			if len(args) > 0 {
+				dir = args[0]
 			}
 
-			fmt.Println("Available templates:")
-			for _, tmpl := range templates {
-				fmt.Printf("  - %s\n", tmpl)
+			if !porcelain {
+				fmt.Println(i18n.T("validate.running"))
+			}
+			if !drift {
+				// TODO: Implement structure validation
+				return nil
 			}
-			return nil
-		},
-	}
-}
 
-func newTemplatesShowCmd() *cobra.Command {
-	return &cobra.Command{
-		Use:   "show [template]",
-		Short: "Show template details",
-		Args:  cobra.ExactArgs(1),
-		RunE: func(cmd *cobra.Command, args []string) error {
-			gen := getGenerator()
-			tmpl, err := gen.GetTemplate(args[0])
+			gen, err := projectGenerator(dir)
 			if err != nil {
 				return err
 			}
-
-			fmt.Printf("Name: %s\n", tmpl.Name)
-			fmt.Printf("Version: %s\n", tmpl.Version)
-			fmt.Printf("Description: %s\n", tmpl.Description)
-			fmt.Printf("Language: %s\n", tmpl.Language)
-			fmt.Printf("Framework: %s\n", tmpl.Framework)
-			fmt.Println("\nVariables:")
-			for key, variable := range tmpl.Variables {
-				fmt.Printf("  %s (%s): %s\n", key, variable.Type, variable.Description)
-			}
-			return nil
-		},
-	}
-}
-
-func newTemplatesValidateCmd() *cobra.Command {
-	return &cobra.Command{
-		Use:   "validate",
-		Short: "Validate all templates",
-		RunE: func(cmd *cobra.Command, args []string) error {
-			gen := getGenerator()
-			templates, err := gen.ListTemplates()
+			gen.SetUpdateLock(update)
+			metadata, entries, err := gen.Drift(dir)
 			if err != nil {
 				return err
 			}
 
-			fmt.Println("Validating templates...")
-			errors := 0
-			for _, name := range templates {
-				_, err := gen.GetTemplate(name)
-				if err != nil {
-					fmt.Printf("  ✗ %s: %v\n", name, err)
-					errors++
-				} else {
-					fmt.Printf("  ✓ %s\n", name)
+			name, version := metadata.Template.Name, metadata.Template.Version
+			if len(entries) == 0 {
+				if !porcelain {
+					fmt.Println(i18n.T("validate.no_drift", name, version))
 				}
+				return nil
 			}
 
-			if errors > 0 {
-				return fmt.Errorf("%d template(s) failed validation", errors)
+			for _, entry := range entries {
+				if porcelain {
+					printRecord(os.Stdout, "drift", entry.Status, entry.Path)
+					continue
+				}
+				fmt.Printf("  ✗ %s\n", i18n.T("validate.drift_"+entry.Status, entry.Path))
 			}
-
-			fmt.Println("\nAll templates valid!")
-			return nil
+			return errcode.New(errcode.Validation, i18n.Errorf("validate.drift_found", len(entries), name, version))
 		},
 	}
+
+	cmd.Flags().BoolVar(&drift, "drift", false, "report generated files that differ from the template")
+	cmd.Flags().BoolVar(&update, "update", false, "with --drift, use the template as it is now and update devinit.lock")
+	cmd.Flags().BoolVar(&porcelain, "porcelain", false, porcelainUsage)
+
+	return cmd
 }
 
 // Helper functions
 
-func getTemplatesDir() string {
-	// Get executable directory
-	exe, err := os.Executable()
-	if err != nil {
-		// Fallback to current directory
-		return "templates"
-	}
-
-	exeDir := filepath.Dir(exe)
+// templatesDirFlag is --templates-dir, which DEVINIT_TEMPLATES_DIR also sets
+var templatesDirFlag string
 
-	// Check if templates directory exists relative to executable
-	templatesDir := filepath.Join(exeDir, "..", "templates")
-	if _, err := os.Stat(templatesDir); err == nil {
-		return templatesDir
-	}
+// resolved caches the templates directory of getTemplatesDir
+var resolved struct {
+	once   sync.Once
+	dir    string
+	source paths.TemplatesSource
+}
 
-	// Fallback to templates in current directory (development mode)
-	return "templates"
+// getTemplatesDir returns the templates directory, see resolveTemplatesDir
+func getTemplatesDir() string {
+	dir, _ := resolveTemplatesDir()
+	return dir
 }
 
-func getGenerator() *generator.Generator {
-	return generator.NewGenerator(getTemplatesDir())
+// resolveTemplatesDir returns the templates directory and where it comes
+// from: --templates-dir, the user store, else the templates embedded in the
+// binary, extracted to the cache. When the embedded templates cannot be
+// extracted it warns and falls back to ./templates, the layout of a source
+// checkout.
+func resolveTemplatesDir() (string, paths.TemplatesSource) {
+	resolved.once.Do(func() {
+		dir, source, err := paths.TemplatesDir(templatesDirFlag)
+		if err == nil && source == paths.TemplatesEmbedded {
+			dir, err = extractEmbedded()
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, i18n.T("templates.dir_failed", err))
+			dir, source = "templates", paths.TemplatesExplicit
+		}
+		resolved.dir, resolved.source = dir, source
+	})
+	return resolved.dir, resolved.source
 }
 
-func runNewCommand(args []string, lang, framework, database, pythonVersion string, docker, includeTests, dryRun bool) error {
-	// Determine project name
-	projectName := ""
-	if len(args) >= 2 {
-		projectName = args[1]
-	} else if len(args) == 1 {
-		projectName = args[0]
-	} else {
-		return fmt.Errorf("project name is required")
+// extractEmbedded extracts the templates embedded in the binary to the
+// cache and returns their directory
+func extractEmbedded() (string, error) {
+	cache, err := embeddedDir()
+	if err != nil {
+		return "", err
 	}
+	return paths.ExtractTemplates(embedded.FS, cache)
+}
 
-	// Validate project name (security: prevent path traversal, ensure valid format)
-	if err := generator.ValidateProjectName(projectName); err != nil {
-		return err
+// embeddedFallback returns the built-in templates for versions the user
+// store lacks, or "" when they are the store or cannot be extracted. They
+// are extracted once, on first use.
+var embeddedFallback = sync.OnceValue(func() string {
+	if _, source := resolveTemplatesDir(); source != paths.TemplatesUser {
+		return ""
 	}
-
-	// Determine language and framework
-	if lang == "" {
-		return fmt.Errorf("--lang flag is required")
+	dir, err := extractEmbedded()
+	if err != nil {
+		return ""
 	}
-
-	if framework == "" {
-		return fmt.Errorf("--framework flag is required")
+	return dir
+})
+
+// projectTemplatesDir returns the .devinit/templates of the project dir is
+// in, layered over the templates directory unless --templates-dir names the
+// one to use
+func projectTemplatesDir(dir string) string {
+	if _, source := resolveTemplatesDir(); source == paths.TemplatesExplicit {
+		return ""
 	}
-
-	// Build variables
-	variables := map[string]interface{}{
-		"ProjectName":    projectName,
-		"PythonVersion":  pythonVersion,
-		"IncludeDocker":  docker,
-		"Database":       database,
-		"IncludeTests":   includeTests,
+	if abs, err := filepath.Abs(dir); err == nil {
+		return paths.ProjectTemplatesDir(abs)
 	}
+	return ""
+}
 
-	// Create generator options
-	opts := &generator.Options{
-		ProjectName: projectName,
-		Language:    lang,
-		Framework:   framework,
-		Variables:   variables,
-		DryRun:      dryRun,
+// setLocale selects the message language from DEVINIT_LOCALE, the config file
+// and the system locale. Config errors are left to the commands to report.
+func setLocale() {
+	configured := ""
+	if cfg, err := config.Load(); err == nil {
+		configured = cfg.Locale
 	}
+	i18n.SetLocale(i18n.Detect(configured))
+}
 
-	// Generate project
-	gen := getGenerator()
+func getGenerator() *generator.Generator {
+	return newGenerator(getTemplatesDir(), ".", nil)
+}
 
-	fmt.Printf("Creating %s/%s project: %s\n", lang, framework, projectName)
-	if dryRun {
-		fmt.Println("(dry run - no files will be created)")
+// projectGenerator returns the generator of the commands working on the
+// generated project in dir, configured by its .devinit/config.yaml: the
+// templates directory it pins, unless --templates-dir names one, its naming
+// and the files it manages
+func projectGenerator(dir string) (*generator.Generator, error) {
+	project, err := config.LoadProject(dir)
+	if err != nil {
+		return nil, err
 	}
+	templatesDir, source := resolveTemplatesDir()
+	if pinned := project.TemplatesDir(); pinned != "" && source != paths.TemplatesExplicit {
+		templatesDir = pinned
+	}
+	gen := newGenerator(templatesDir, dir, project)
+	gen.SetManaged(project.ManagedFiles())
+	return gen, nil
+}
 
-	if err := gen.Generate(opts); err != nil {
-		return fmt.Errorf("failed to generate project: %w", err)
+// newGenerator returns a generator of the templates in templatesDir, with
+// the .devinit/templates of the project dir is in layered over them and the
+// global configuration applied, under the project's when given
+func newGenerator(templatesDir, dir string, project *config.Project) *generator.Generator {
+	gen := generator.NewGenerator(templatesDir)
+	gen.SetVersion(version)
+	gen.SetOverlay(projectTemplatesDir(dir))
+	// A pinned templates directory is the one to use, not a fallback
+	if project == nil || project.Templates == "" {
+		gen.SetFallback(embeddedFallback)
 	}
 
-	if !dryRun {
-		fmt.Printf("\n✓ Project created successfully at: ./%s\n", projectName)
-		fmt.Println("\nNext steps:")
-		fmt.Printf("  cd %s\n", projectName)
-
-		if lang == "python" {
-			fmt.Println("  poetry install")
-			if docker {
-				fmt.Println("  docker compose up")
-			} else {
-				fmt.Println("  poetry run uvicorn src.main:app --reload")
-			}
+	// Invalid configs are reported by the commands that load them
+	if cfg, err := config.Load(); err == nil {
+		names := cfg.Naming
+		if project != nil {
+			names = project.Naming.Over(names)
+		}
+		naming := template.DefaultNaming()
+		naming.Registry = names.Registry
+		if min, max, _ := names.Ports(); min != 0 {
+			naming.PortMin, naming.PortMax = min, max
+		}
+		gen.SetNaming(naming)
+
+		if policy, err := cfg.Retry.Policy(); err == nil {
+			gen.SetRetryPolicy(policy)
+		}
+		gen.SetNetwork(cfg.Network.Settings())
+
+		if dir, err := cfg.Cache.RenderDir(); err == nil && dir != "" {
+			gen.SetRenderCache(generator.NewRenderCache(dir))
 		}
 	}
 
-	return nil
+	return gen
 }
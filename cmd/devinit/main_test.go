@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// isolate gives the test an environment of its own: no config file, and
+// cache, data and state directories of the test
+func isolate(t *testing.T) {
+	t.Helper()
+	home := t.TempDir()
+	for _, name := range []string{"XDG_CONFIG_HOME", "XDG_CACHE_HOME", "XDG_DATA_HOME", "XDG_STATE_HOME"} {
+		t.Setenv(name, filepath.Join(home, name))
+	}
+	t.Setenv("HOME", home)
+	t.Setenv("DEVINIT_CONFIG", filepath.Join(home, "config.yaml"))
+
+	// Flags bound to globals keep their values across commands
+	templatesDirFlag, offline = "", false
+	resolved.once = sync.Once{}
+}
+
+// execute runs devinit with args in an isolated environment, see isolate.
+// It returns what the command wrote to standard output.
+func execute(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	isolate(t)
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	var out bytes.Buffer
+	done := make(chan struct{})
+	go func() {
+		io.Copy(&out, r)
+		close(done)
+	}()
+
+	root := newRootCmd()
+	root.SetArgs(args)
+	root.SetOut(w)
+	_, err = root.ExecuteC()
+	w.Close()
+	<-done
+	return out.String(), err
+}
+
+// writeTemplate writes a template with its files to
+// templatesDir/language/framework
+func writeTemplate(t *testing.T, templatesDir, name, metadata string, files map[string]string) {
+	t.Helper()
+	dir := filepath.Join(templatesDir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Join(dir, "files"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "template.yaml"), []byte(metadata), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for path, content := range files {
+		path = filepath.Join(dir, "files", filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
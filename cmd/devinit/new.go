@@ -0,0 +1,633 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/renan-dev/devinit/internal/audit"
+	"github.com/renan-dev/devinit/internal/config"
+	"github.com/renan-dev/devinit/internal/dbschema"
+	"github.com/renan-dev/devinit/internal/errcode"
+	"github.com/renan-dev/devinit/internal/generator"
+	"github.com/renan-dev/devinit/internal/i18n"
+	"github.com/renan-dev/devinit/internal/openapi"
+	"github.com/renan-dev/devinit/internal/ownership"
+	"github.com/renan-dev/devinit/internal/ports"
+	"github.com/renan-dev/devinit/internal/proto"
+	"github.com/renan-dev/devinit/internal/validator"
+	"github.com/renan-dev/devinit/internal/vulnscan"
+	"github.com/spf13/cobra"
+)
+
+// fromDBTimeout bounds reading the schema of --from-db
+const fromDBTimeout = 30 * time.Second
+
+// newOptions holds the flags of the new command
+type newOptions struct {
+	lang          string
+	framework     string
+	version       string // of the template, from --framework name@version
+	docker        bool
+	database      string
+	ci            string
+	noValidate    bool
+	strict        bool
+	dryRun        bool
+	tree          bool
+	plan          bool
+	pythonVersion string
+	includeTests  bool
+	observability bool
+	apiDocs       bool
+	profile       string
+	dockerBase    string
+	image         string
+	port          int
+	noHooks       bool
+	noWorkspace   bool
+	showOutput    bool
+	retryAttempts int
+	smokeTest     string
+	scan          string
+	scanFailOn    string
+	provenance    bool
+	owner         string
+	fromProject   string
+	fromOpenAPI   string
+	fromProto     string
+	fromDB        string
+	repeatLast    bool
+	output        string
+	porcelain     bool
+	vars          []string
+}
+
+// addVariableFlags registers the flags setting the common template variables
+// (see flagVariables)
+func addVariableFlags(cmd *cobra.Command, opts *newOptions) {
+	cmd.Flags().BoolVar(&opts.docker, "docker", true, "include Docker configuration")
+	cmd.Flags().StringVar(&opts.database, "database", "none", "database to configure (postgres, sqlite, none)")
+	cmd.Flags().StringVar(&opts.ci, "ci", "", "CI provider (github, gitlab, none)")
+	cmd.Flags().StringVar(&opts.dockerBase, "docker-base", "", "Docker base image (slim, alpine, distroless), for templates using .DockerBase (default depends on the template)")
+	cmd.Flags().StringVar(&opts.image, "image", "", "reference of the built image, for templates using .Image (default <name>:latest)")
+	cmd.Flags().StringVar(&opts.pythonVersion, "python-version", "3.11", "Python version (python only)")
+	cmd.Flags().BoolVar(&opts.includeTests, "tests", true, "include test setup")
+	cmd.Flags().BoolVar(&opts.observability, "observability", false, "include logging, metrics and tracing setup (where the template supports it)")
+	cmd.Flags().BoolVar(&opts.apiDocs, "api-docs", false, "include OpenAPI export, docs hosting and spec drift checks (where the template supports it)")
+}
+
+// flagVariables sets the common variables of the flags given on the command
+// line in variables, and returns those of the flags left at their default:
+// they only fill in what the template defaults, the profile and --var values
+// leave unset
+func flagVariables(cmd *cobra.Command, opts *newOptions, variables map[string]interface{}) map[string]interface{} {
+	defaults := make(map[string]interface{})
+	for _, f := range []struct {
+		flag  string
+		key   string
+		value interface{}
+	}{
+		{"python-version", "PythonVersion", opts.pythonVersion},
+		{"docker", "IncludeDocker", opts.docker},
+		{"database", "Database", opts.database},
+		{"tests", "IncludeTests", opts.includeTests},
+		{"ci", "CIProvider", opts.ci},
+		{"observability", "Observability", opts.observability},
+		{"api-docs", "APIDocs", opts.apiDocs},
+		{"docker-base", "DockerBase", opts.dockerBase},
+		{"image", "Image", opts.image},
+	} {
+		if cmd.Flags().Changed(f.flag) {
+			variables[f.key] = f.value
+		} else {
+			defaults[f.key] = f.value
+		}
+	}
+	return defaults
+}
+
+func newNewCmd() *cobra.Command {
+	opts := &newOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "new [type] [name]",
+		Short: "Create a new project",
+		Long: `Create a new project with the specified language and framework.
+
+Examples:
+  # Interactive mode
+  devinit new
+
+  # Non-interactive mode
+  devinit new api my-service --lang python --framework fastapi
+
+  # With all options
+  devinit new api my-service \
+    --lang python \
+    --framework fastapi \
+    --docker \
+    --database postgres \
+    --ci github
+
+  # Leaner project from a template profile
+  devinit new api my-service --lang python --framework fastapi --profile minimal
+
+  # Planned files as a tree, with the conditions that included or excluded them
+  devinit new api my-service --lang python --framework fastapi --dry-run --tree
+
+  # Review the planned files and switch docker, tests or CI on and off first
+  devinit new api my-service --lang python --framework fastapi --plan
+
+  # Route stubs and models from an OpenAPI contract
+  devinit new api my-service --lang python --framework fastapi --from-openapi openapi.yaml
+
+  # gRPC handler stubs and a client from a protobuf contract
+  devinit new api my-service --lang go --framework grpc --from-proto pets.proto
+
+  # SQLAlchemy models from the tables of an existing database
+  devinit new api my-service --lang python --framework fastapi --from-db postgres://app@localhost:5432/shop
+
+  # Sibling of an existing project: same template and variables, new name
+  devinit new api billing-service --from-project ../orders-service
+
+  # The last successful generation again, under a new name and without Docker
+  devinit new --repeat-last my-service-2 --docker=false
+
+  # Machine-readable summary
+  devinit new api my-service --lang python --framework fastapi --output json
+
+  # One tab-separated record per file, hook and step, for shell scripts
+  devinit new api my-service --lang python --framework fastapi --porcelain`,
+		Args: cobra.MaximumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// The recorded flags, --templates-dir among them, apply
+			// before the update check resolves the templates directory
+			if opts.repeatLast {
+				var err error
+				if args, err = repeatLastGeneration(cmd, args); err != nil {
+					return err
+				}
+			}
+			defer startUpdateCheck()()
+			return runNewCommand(cmd, args, opts)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.lang, "lang", "", "programming language (python, nodejs, kotlin)")
+	cmd.Flags().StringVar(&opts.framework, "framework", "", "framework to use, optionally with a template version (fastapi@1.4.0)")
+	addVariableFlags(cmd, opts)
+	cmd.Flags().IntVar(&opts.port, "port", 0, "port the service listens on, for templates using .Port (default: the template's port, or the next free one)")
+	cmd.Flags().BoolVar(&opts.noValidate, "no-validate", false, "skip requirement checks and validation of variable values")
+	cmd.Flags().BoolVar(&opts.strict, "strict", false, "treat requirement version mismatches as errors and check variable types")
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "show what would be done without doing it")
+	cmd.Flags().BoolVar(&opts.tree, "tree", false, "with --dry-run, show the planned files as a tree annotated with their conditions")
+	cmd.Flags().BoolVar(&opts.plan, "plan", false, "review the planned files and switch optional groups (docker, tests, ci) before generating")
+	cmd.Flags().StringVar(&opts.profile, "profile", "", "template profile presetting a group of variables (see 'templates show')")
+	cmd.Flags().StringArrayVar(&opts.vars, "var", nil, "template variable as KEY=VALUE (repeatable)")
+	cmd.Flags().StringVar(&opts.fromOpenAPI, "from-openapi", "", "OpenAPI 3 document (YAML or JSON) to scaffold routes and models from")
+	cmd.Flags().StringVar(&opts.fromProto, "from-proto", "", "protobuf file to scaffold gRPC handler stubs, clients and buf configuration from")
+	cmd.Flags().StringVar(&opts.fromDB, "from-db", "", "PostgreSQL or MySQL URL of an existing database to scaffold ORM models from (read with psql or mysql)")
+	cmd.Flags().StringVar(&opts.fromProject, "from-project", "", "generate with the template and variables recorded in another project's .devinit.yaml")
+	cmd.Flags().BoolVar(&opts.repeatLast, "repeat-last", false, "repeat the last successful generation with its arguments and flags, optionally under a new name; flags given override them")
+	cmd.Flags().BoolVar(&opts.noHooks, "no-hooks", false, "do not run template lifecycle hooks")
+	cmd.Flags().BoolVar(&opts.noWorkspace, "no-workspace", false, "do not wire the service into the devinit workspace it is generated in")
+	cmd.Flags().BoolVar(&opts.showOutput, "show-output", false, "stream hook output live (it is always logged to .devinit/logs)")
+	cmd.Flags().IntVar(&opts.retryAttempts, "retry-attempts", 0, "attempts for network hooks such as dependency installs (default from config retry.attempts, else 3)")
+	cmd.Flags().StringVar(&opts.smokeTest, "smoke-test", "", "run the template's tests on the generated project: local (in a temporary copy) or container")
+	cmd.Flags().Lookup("smoke-test").NoOptDefVal = string(generator.SmokeLocal)
+	cmd.Flags().StringVar(&opts.scan, "scan", "", "scan for vulnerabilities with pip-audit, npm audit, govulncheck or trivy: project (dependencies) or image")
+	cmd.Flags().Lookup("scan").NoOptDefVal = string(generator.ScanProject)
+	cmd.Flags().StringVar(&opts.scanFailOn, "scan-fail-on", "", "severity that fails the scan: low, medium, high, critical or none (default from config scan.fail_on, else high)")
+	cmd.Flags().BoolVar(&opts.provenance, "provenance", false, "start generated text files with a comment naming devinit and the template")
+	cmd.Flags().StringVar(&opts.owner, "chown", "", "give the generated files to user[:group], or with auto to the owner of the directory they are generated in (also $"+ownership.EnvOwner+"; default the user who ran sudo)")
+	cmd.Flags().StringVarP(&opts.output, "output", "o", outputText, "summary format (text, json)")
+	cmd.Flags().BoolVar(&opts.porcelain, "porcelain", false, porcelainUsage)
+
+	return cmd
+}
+
+func runNewCommand(cmd *cobra.Command, args []string, opts *newOptions) error {
+	last := newLastGeneration(cmd, args)
+	if err := validateOutputFormat(opts.output); err != nil {
+		return err
+	}
+	if err := selectPorcelain(cmd, opts.porcelain, &opts.output); err != nil {
+		return err
+	}
+
+	// Determine project name
+	projectName := ""
+	if len(args) >= 2 {
+		projectName = args[1]
+	} else if len(args) == 1 {
+		projectName = args[0]
+	} else {
+		return usageError(i18n.Errorf("new.name_required"))
+	}
+
+	// Validate project name (security: prevent path traversal, ensure valid format)
+	if err := generator.ValidateProjectName(projectName); err != nil {
+		return err
+	}
+
+	if err := validateDockerBase(opts.dockerBase); err != nil {
+		return err
+	}
+
+	if opts.noValidate && opts.strict {
+		return usageError(i18n.Errorf("new.validate_conflict"))
+	}
+
+	if opts.plan && (opts.dryRun || opts.output != outputText) {
+		return usageError(i18n.Errorf("new.plan_conflict"))
+	}
+
+	if opts.tree && (!opts.dryRun || opts.output != outputText) {
+		return usageError(i18n.Errorf("new.tree_conflict"))
+	}
+
+	if cmd.Flags().Changed("port") && (opts.port < 1 || opts.port > 65535) {
+		return usageError(i18n.Errorf("new.invalid_port", opts.port))
+	}
+
+	if cmd.Flags().Changed("retry-attempts") && opts.retryAttempts < 1 {
+		return usageError(i18n.Errorf("new.invalid_retry_attempts", opts.retryAttempts))
+	}
+	switch generator.SmokeMode(opts.smokeTest) {
+	case "", generator.SmokeLocal, generator.SmokeContainer:
+	default:
+		return usageError(i18n.Errorf("new.invalid_smoke_test", opts.smokeTest))
+	}
+	switch generator.ScanTarget(opts.scan) {
+	case "", generator.ScanProject, generator.ScanImage:
+	default:
+		return usageError(i18n.Errorf("new.invalid_scan", opts.scan))
+	}
+
+	// Build variables (--var values override template defaults)
+	variables, err := parseVarFlags(opts.vars)
+	if err != nil {
+		return err
+	}
+
+	opts.framework, opts.version = splitTemplateRef(opts.framework)
+
+	// A sibling of another project takes its template and variables; flags
+	// and --var values still override them
+	var exemplar *generator.Metadata
+	if opts.fromProject != "" {
+		if exemplar, err = generator.ReadMetadata(opts.fromProject); err != nil {
+			return usageError(i18n.Errorf("new.invalid_from_project", err))
+		}
+		lang, framework, _ := strings.Cut(exemplar.Template.Name, "/")
+		if (opts.lang != "" && opts.lang != lang) || (opts.framework != "" && opts.framework != framework) {
+			return usageError(i18n.Errorf("new.from_project_conflict", opts.fromProject, exemplar.Template.Name))
+		}
+		opts.lang, opts.framework = lang, framework
+		for key, value := range exemplar.SiblingVariables() {
+			if _, ok := variables[key]; !ok {
+				variables[key] = value
+			}
+		}
+	}
+
+	var spec *openapi.Spec
+	if opts.fromOpenAPI != "" {
+		if spec, err = openapi.Load(opts.fromOpenAPI); err != nil {
+			return usageError(i18n.Errorf("new.invalid_openapi", err))
+		}
+	}
+	var protoFile *proto.File
+	if opts.fromProto != "" {
+		if protoFile, err = proto.Load(opts.fromProto); err != nil {
+			return usageError(i18n.Errorf("new.invalid_proto", err))
+		}
+	}
+	var dbSchema *dbschema.Schema
+	if opts.fromDB != "" {
+		if _, _, err = dbschema.ParseDSN(opts.fromDB); err != nil {
+			return usageError(i18n.Errorf("new.invalid_from_db", err))
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), fromDBTimeout)
+		dbSchema, err = dbschema.Introspect(ctx, opts.fromDB)
+		cancel()
+		if errors.Is(err, dbschema.ErrClientMissing) {
+			return errcode.New(errcode.RequirementMissing, i18n.Errorf("new.from_db_failed", err))
+		}
+		if err != nil {
+			return i18n.Errorf("new.from_db_failed", err)
+		}
+	}
+
+	// Determine language and framework
+	if opts.lang == "" {
+		return usageError(i18n.Errorf("new.lang_required"))
+	}
+
+	if opts.framework == "" {
+		return usageError(i18n.Errorf("new.framework_required"))
+	}
+
+	variables["ProjectName"] = projectName
+
+	defaults := flagVariables(cmd, opts, variables)
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	scanFailOn, err := cfg.Scan.Threshold()
+	if err != nil {
+		return err
+	}
+	if cmd.Flags().Changed("scan-fail-on") {
+		if scanFailOn, err = vulnscan.ParseThreshold(opts.scanFailOn); err != nil {
+			return usageError(i18n.Errorf("new.invalid_scan_fail_on", err))
+		}
+	}
+
+	// Run as root, in containers or under sudo, devinit gives the project to
+	// the user meant to edit it
+	owner, err := ownership.Parse(opts.owner, projectName)
+	if err != nil {
+		return usageError(i18n.Errorf("new.invalid_owner", err))
+	}
+
+	// Create generator options
+	genOpts := &generator.Options{
+		ProjectName:     projectName,
+		Language:        opts.lang,
+		Framework:       opts.framework,
+		TemplateVersion: opts.version,
+		Variables:       variables,
+		Defaults:        defaults,
+		Profile:         opts.profile,
+		DryRun:          opts.dryRun,
+		SkipHooks:       opts.noHooks,
+		SkipWorkspace:   opts.noWorkspace,
+		Provenance:      opts.provenance,
+		OpenAPI:         spec,
+		Proto:           protoFile,
+		DBSchema:        dbSchema,
+
+		RetryAttempts: opts.retryAttempts,
+		Progress:      os.Stderr,
+		SmokeTest:     generator.SmokeMode(opts.smokeTest),
+		Scan:          generator.ScanTarget(opts.scan),
+		ScanFailOn:    scanFailOn,
+
+		SkipValidation:   opts.noValidate,
+		StrictValidation: opts.strict,
+
+		RejectDeprecated: cfg.Policy.DeprecatedTemplates == config.PolicyError,
+	}
+	if opts.showOutput {
+		genOpts.HookOutput = os.Stderr
+	}
+
+	// Generate project
+	gen := getGenerator()
+
+	if exemplar != nil && opts.version == "" {
+		if tmpl, err := gen.GetTemplate(exemplar.Template.Name); err == nil && tmpl.Version != exemplar.Template.Version {
+			fmt.Fprintln(os.Stderr, i18n.T("new.from_project_version", opts.fromProject, exemplar.Template.Name, exemplar.Template.Version, tmpl.Version, opts.framework, exemplar.Template.Version))
+		}
+	}
+
+	if err := checkFieldFlags(cmd, gen, opts.lang+"/"+opts.framework, opts.version); err != nil {
+		return err
+	}
+
+	if opts.output == outputText {
+		fmt.Println(i18n.T("new.creating", opts.lang, opts.framework, projectName))
+		if opts.dryRun {
+			fmt.Println(i18n.T("new.dry_run"))
+		}
+	}
+
+	if cmd.Flags().Changed("port") {
+		variables["Port"] = opts.port
+	} else if _, ok := variables["Port"]; !ok {
+		if port := defaultPort(gen, opts.lang+"/"+opts.framework, opts.version, opts.output == outputText); port != 0 {
+			defaults["Port"] = port
+		}
+	}
+
+	// Requirements depend on the reviewed groups, so they are checked after
+	if opts.plan {
+		proceed, err := reviewPlan(gen, genOpts, bufio.NewReader(os.Stdin), os.Stdout)
+		if err != nil {
+			return err
+		}
+		if !proceed {
+			fmt.Println(i18n.T("plan.cancelled"))
+			return nil
+		}
+	}
+
+	if !opts.noValidate {
+		out := io.Discard
+		if opts.output == outputText {
+			out = os.Stdout
+		}
+		cache := openProbeCache(cfg, false)
+		defer cache.Save()
+		if err := checkProjectRequirements(out, gen, genOpts, newSystemValidator(cfg, genOpts.ValidationLevel(), cache)); err != nil {
+			return err
+		}
+	}
+
+	result, err := gen.Generate(genOpts)
+	if err != nil {
+		return i18n.Errorf("new.failed", err)
+	}
+
+	if !opts.dryRun {
+		recordGeneration(result, projectName)
+	}
+	if owner != nil && !opts.dryRun {
+		if err := owner.Apply(result.OutputDir); err != nil {
+			return i18n.Errorf("new.owner_failed", owner, err)
+		}
+	}
+
+	var smokeErr, scanErr error
+	if smoke := result.SmokeTest; smoke != nil && !smoke.Passed && !smoke.Skipped() {
+		smokeErr = errcode.New(errcode.SmokeTestFailed, i18n.Errorf("new.smoke_test_failed", smoke.Error, result.HookLog))
+	}
+	if scan := result.Scan; scan != nil && scan.Failed {
+		failing := vulnscan.Failing(scan.Findings, scan.FailOn)
+		scanErr = errcode.New(errcode.ScanFailed, i18n.Errorf("new.scan_failed", len(failing), scan.FailOn, result.HookLog))
+	}
+	if !opts.dryRun && smokeErr == nil && scanErr == nil {
+		last.save()
+	}
+
+	if opts.output == outputJSON {
+		if err := printJSON(result); err != nil {
+			return err
+		}
+		if smokeErr != nil {
+			return reportedError{smokeErr}
+		}
+		if scanErr != nil {
+			return reportedError{scanErr}
+		}
+		return nil
+	}
+
+	if opts.output == outputPorcelain {
+		printGenerationRecords(os.Stdout, result)
+		if smokeErr != nil {
+			return smokeErr
+		}
+		return scanErr
+	}
+
+	printGenerationSummary(result, opts.tree)
+	if smokeErr != nil && len(result.SmokeTest.Output) > 0 {
+		smokeErr = fmt.Errorf("%w\n  %s", smokeErr, strings.Join(result.SmokeTest.Output, "\n  "))
+	}
+	if smokeErr != nil {
+		return smokeErr
+	}
+	return scanErr
+}
+
+// checkProjectRequirements checks the system requirements and checks that
+// apply to the project before it is generated, writing a doctor-style
+// summary to w
+func checkProjectRequirements(w io.Writer, gen *generator.Generator, opts *generator.Options, v *validator.SystemValidator) error {
+	reqs, err := gen.Requirements(opts)
+	if err != nil {
+		return err
+	}
+	checks, err := gen.Checks(opts)
+	if err != nil {
+		return err
+	}
+	if len(reqs) == 0 && len(checks) == 0 {
+		return nil
+	}
+
+	v.OutputDir = opts.OutputDir
+	if v.OutputDir == "" {
+		v.OutputDir = opts.ProjectName
+	}
+
+	fmt.Fprintln(w, i18n.T("new.checking_requirements"))
+	unmet, _ := checkRequirements(w, reqs, checks, v, nil)
+	if len(unmet) > 0 {
+		return errcode.New(errcode.RequirementMissing, i18n.Errorf("new.requirements_unmet", strings.Join(unmet, ", ")))
+	}
+	fmt.Fprintln(w)
+
+	return nil
+}
+
+// fieldFlags are the flags of new templates only use through a field of
+// the context
+var fieldFlags = []struct{ flag, field string }{
+	{"port", "Port"},
+	{"docker-base", "DockerBase"},
+	{"image", "Image"},
+}
+
+// checkFieldFlags rejects the flags of fieldFlags set for a template whose
+// files do not use their field, which they would have no effect on.
+// Templates that fail to load are reported by the generation.
+func checkFieldFlags(cmd *cobra.Command, gen *generator.Generator, templateName, version string) error {
+	tmpl, err := gen.GetTemplateVersion(templateName, version)
+	if err != nil {
+		return nil
+	}
+	for _, f := range fieldFlags {
+		if !cmd.Flags().Changed(f.flag) {
+			continue
+		}
+		if used, err := tmpl.ReferencesField(f.field); err == nil && !used {
+			return usageError(i18n.Errorf("new.flag_unused", "--"+f.flag, tmpl.ID))
+		}
+	}
+	return nil
+}
+
+// defaultPort returns the template's port, or the next free one when it is
+// bound locally or recorded for another generated project. It returns 0 when
+// the template declares no port, or hardcodes it instead of using .Port, so
+// its own port is recorded.
+func defaultPort(gen *generator.Generator, templateName, version string, verbose bool) int {
+	tmpl, err := gen.GetTemplateVersion(templateName, version)
+	if err != nil || tmpl.Healthcheck == nil || tmpl.Healthcheck.Port == 0 {
+		return 0
+	}
+	if used, err := tmpl.ReferencesField("Port"); err != nil || !used {
+		return 0
+	}
+	preferred := tmpl.Healthcheck.Port
+
+	var reserved map[int]bool
+	if path, err := audit.DefaultPath(); err == nil {
+		if entries, err := audit.Load(path); err == nil {
+			reserved = audit.ReservedPorts(entries)
+		}
+	}
+
+	port, err := ports.Allocate(preferred, reserved, ports.Available)
+	if err != nil {
+		return preferred
+	}
+	if port != preferred && verbose {
+		fmt.Println(i18n.T("new.port_taken", preferred, port))
+	}
+	return port
+}
+
+// recordGeneration appends the generated project to the audit log. Failures
+// are reported but do not fail the command.
+func recordGeneration(result *generator.GenerationResult, projectName string) {
+	entry := audit.Entry{
+		Time:            time.Now().UTC(),
+		Project:         projectName,
+		Dir:             result.OutputDir,
+		Template:        result.TemplateName,
+		TemplateVersion: result.TemplateVersion,
+	}
+	if dir, err := filepath.Abs(result.OutputDir); err == nil {
+		entry.Dir = dir
+	}
+	if result.Healthcheck != nil {
+		entry.Port = result.Healthcheck.Port
+	}
+
+	path, err := audit.DefaultPath()
+	if err == nil {
+		err = audit.Append(path, entry)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("new.record_failed", err))
+	}
+}
+
+// dockerBases are the accepted values of --docker-base
+var dockerBases = []string{"slim", "alpine", "distroless"}
+
+// validateDockerBase checks the value of the --docker-base flag
+func validateDockerBase(base string) error {
+	if base == "" {
+		return nil
+	}
+	for _, b := range dockerBases {
+		if base == b {
+			return nil
+		}
+	}
+	return usageError(i18n.Errorf("new.invalid_docker_base", base, strings.Join(dockerBases, ", ")))
+}
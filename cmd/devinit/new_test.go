@@ -0,0 +1,152 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/renan-dev/devinit/internal/audit"
+)
+
+func TestNewPort(t *testing.T) {
+	auditLog := filepath.Join(t.TempDir(), "audit.log")
+	t.Setenv(audit.EnvLogPath, auditLog)
+	templatesDir := t.TempDir()
+	for framework, readme := range map[string]string{
+		"fixed": "# {{ .ProjectName }} listens on 18080\n",
+		"port":  "# {{ .ProjectName }} listens on {{ .Port }}\n",
+	} {
+		writeTemplate(t, templatesDir, "test/"+framework, `version: "1.0.0"
+name: `+framework+`
+language: test
+framework: `+framework+`
+healthcheck:
+  command: "true"
+  port: 18080
+files:
+  - src: README.md.tmpl
+    dest: README.md
+`, map[string]string{"README.md.tmpl": readme})
+	}
+	t.Chdir(t.TempDir())
+	newProject := func(name, framework string, args ...string) error {
+		args = append([]string{"--templates-dir", templatesDir, "new", "api", name, "--lang", "test", "--framework", framework}, args...)
+		_, err := execute(t, args...)
+		return err
+	}
+	recorded := func() int {
+		entries, err := audit.Load(auditLog)
+		if err != nil || len(entries) == 0 {
+			t.Fatalf("audit log = %v, %v", entries, err)
+		}
+		return entries[len(entries)-1].Port
+	}
+
+	// --port only applies to templates using .Port
+	if err := newProject("fixed", "fixed", "--port", "9000"); exitCode(err) != exitUsage {
+		t.Errorf("--port for a template without .Port exit code = %d (%v), want %d", exitCode(err), err, exitUsage)
+	}
+	if err := newProject("chosen", "port", "--port", "9000"); err != nil {
+		t.Fatal(err)
+	}
+	if port := recorded(); port != 9000 {
+		t.Errorf("recorded port = %d, want 9000", port)
+	}
+
+	// Templates hardcoding their port get no other, and it is what is
+	// recorded, even when another project has it
+	for _, name := range []string{"first", "second"} {
+		if err := newProject(name, "fixed"); err != nil {
+			t.Fatal(err)
+		}
+		if port := recorded(); port != 18080 {
+			t.Errorf("recorded port of %s = %d, want the template's 18080", name, port)
+		}
+	}
+	if err := newProject("moved", "port"); err != nil {
+		t.Fatal(err)
+	}
+	if port := recorded(); port == 18080 || port == 9000 {
+		t.Errorf("recorded port = %d, want a port no other project has", port)
+	}
+}
+
+func TestValidateDockerBase(t *testing.T) {
+	for _, base := range []string{"", "slim", "alpine", "distroless"} {
+		if err := validateDockerBase(base); err != nil {
+			t.Errorf("validateDockerBase(%q) unexpected error: %v", base, err)
+		}
+	}
+	for _, base := range []string{"ubuntu", "Slim", "python:3.11-slim"} {
+		if err := validateDockerBase(base); exitCode(err) != exitUsage {
+			t.Errorf("validateDockerBase(%q) exit code = %d (%v), want %d", base, exitCode(err), err, exitUsage)
+		}
+	}
+}
+
+func TestNewDockerFlags(t *testing.T) {
+	templatesDir := t.TempDir()
+	for framework, dockerfile := range map[string]string{
+		"plain":  "FROM alpine\nLABEL name={{ .ProjectName }}\n",
+		"docker": "FROM python:3.11-{{ if eq .DockerBase \"alpine\" }}alpine{{ else }}slim{{ end }}\nLABEL image={{ .Image }}\n",
+	} {
+		writeTemplate(t, templatesDir, "test/"+framework, `version: "1.0.0"
+name: `+framework+`
+language: test
+framework: `+framework+`
+files:
+  - src: Dockerfile.tmpl
+    dest: Dockerfile
+`, map[string]string{"Dockerfile.tmpl": dockerfile})
+	}
+	t.Chdir(t.TempDir())
+	newProject := func(name, framework string, args ...string) (string, error) {
+		args = append([]string{"--templates-dir", templatesDir, "new", "api", name, "--lang", "test", "--framework", framework}, args...)
+		if _, err := execute(t, args...); err != nil {
+			return "", err
+		}
+		data, err := os.ReadFile(filepath.Join(name, "Dockerfile"))
+		return string(data), err
+	}
+
+	tests := []struct {
+		name      string
+		framework string
+		args      []string
+		want      string
+	}{
+		{
+			name:      "default image",
+			framework: "docker",
+			want:      "FROM python:3.11-slim\nLABEL image=default-image:latest\n",
+		},
+		{
+			name:      "chosen",
+			framework: "docker",
+			args:      []string{"--docker-base", "alpine", "--image", "ghcr.io/acme/api:dev"},
+			want:      "FROM python:3.11-alpine\nLABEL image=ghcr.io/acme/api:dev\n",
+		},
+		{
+			name:      "no flags",
+			framework: "plain",
+			want:      "FROM alpine\nLABEL name=no-flags\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name := strings.ReplaceAll(tt.name, " ", "-")
+			got, err := newProject(name, tt.framework, tt.args...)
+			if err != nil || got != tt.want {
+				t.Errorf("Dockerfile = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+
+	// The flags fail on templates that would ignore them
+	for _, args := range [][]string{{"--docker-base", "alpine"}, {"--image", "ghcr.io/acme/api:dev"}} {
+		if _, err := newProject("ignored", "plain", args...); exitCode(err) != exitUsage {
+			t.Errorf("%v for a template without the field exit code = %d (%v), want %d", args, exitCode(err), err, exitUsage)
+		}
+	}
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/renan-dev/devinit/internal/errcode"
+	"github.com/renan-dev/devinit/internal/generator"
+	"github.com/renan-dev/devinit/internal/i18n"
+	"github.com/renan-dev/devinit/internal/vulnscan"
+	"github.com/spf13/cobra"
+)
+
+// Output formats accepted by --output
+const (
+	outputText = "text"
+	outputJSON = "json"
+	outputYAML = "yaml" // templates context only
+)
+
+// validateOutputFormat checks the value of an --output flag
+func validateOutputFormat(format string) error {
+	switch format {
+	case outputText, outputJSON:
+		return nil
+	default:
+		return usageError(i18n.Errorf("new.invalid_output", format, outputText, outputJSON))
+	}
+}
+
+// printJSON writes v to stdout as indented JSON
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// errorOutput is the JSON representation of a failed command
+type errorOutput struct {
+	Error struct {
+		Code    errcode.Code `json:"code"`
+		Message string       `json:"message"`
+	} `json:"error"`
+}
+
+// reportedError is a failure the command already described in its output,
+// e.g. in a JSON result; only its exit code remains to be set
+type reportedError struct {
+	error
+}
+
+func (e reportedError) Unwrap() error {
+	return e.error
+}
+
+// printError reports a failed command with its error code: as JSON on stdout
+// when the command was asked for JSON output, otherwise on stderr
+func printError(cmd *cobra.Command, err error) {
+	if errors.As(err, new(reportedError)) {
+		return
+	}
+	code := errcode.Of(err)
+
+	if cmd != nil {
+		if f := cmd.Flags().Lookup("output"); f != nil && f.Value.String() == outputJSON {
+			var out errorOutput
+			out.Error.Code = code
+			out.Error.Message = err.Error()
+			printJSON(out)
+			return
+		}
+	}
+
+	if code == errcode.Unknown {
+		fmt.Fprintln(os.Stderr, i18n.T("error", err))
+	} else {
+		fmt.Fprintln(os.Stderr, i18n.T("error_code", code, err))
+	}
+	if code == errcode.Usage && cmd != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("usage.hint", cmd.CommandPath()))
+	}
+}
+
+// printGenerationSummary prints the human-readable result of a generation,
+// listing the files of dry runs as a tree when tree is set
+func printGenerationSummary(result *generator.GenerationResult, tree bool) {
+	if result.Deprecation != "" {
+		printDeprecationBanner(result.Deprecation)
+	}
+
+	if tree {
+		printFileTree(os.Stdout, result)
+	} else {
+		printFileList(result)
+	}
+	fmt.Println("\n" + i18n.T("summary.title"))
+	fmt.Println("  " + i18n.T("summary.template", result.TemplateName, result.TemplateVersion))
+	filesKey := "summary.files_created"
+	if result.DryRun {
+		filesKey = "summary.files_planned"
+	}
+	fmt.Println("  " + i18n.T(filesKey, result.FilesWritten(), formatSize(result.TotalSize())))
+	if overrides := overriddenFiles(result); len(overrides) > 0 {
+		fmt.Println("  " + i18n.T("summary.overrides", strings.Join(overrides, ", ")))
+	}
+
+	if len(result.Hooks) > 0 {
+		fmt.Println("  " + i18n.T("summary.hooks", hooksRun(result.Hooks)))
+		printHookResults(result.Hooks, "    ")
+		fmt.Println("  " + i18n.T("summary.hook_log", result.HookLog))
+	}
+	if ws := result.Workspace; ws != nil && len(ws.Files) > 0 {
+		fmt.Println("  " + i18n.T("summary.workspace", ws.Root, strings.Join(ws.Files, ", ")))
+	}
+	if result.SBOM != nil {
+		fmt.Println("  " + i18n.T("summary.sbom", result.SBOM.Components, result.SBOM.Path))
+	}
+	if result.Scan != nil {
+		printScanSummary(result.Scan)
+	}
+	if smoke := result.SmokeTest; smoke != nil {
+		switch {
+		case smoke.Skipped():
+			fmt.Println("  " + i18n.T("summary.smoke_skipped"))
+		case smoke.Passed:
+			fmt.Println("  " + i18n.T("summary.smoke_passed", smoke.Command, smoke.Duration.Round(time.Millisecond)))
+		default:
+			fmt.Println("  " + i18n.T("summary.smoke_failed", smoke.Command))
+		}
+	}
+
+	if result.Healthcheck != nil {
+		fmt.Println("  " + i18n.T("summary.port", result.Healthcheck.Port, result.Healthcheck.Command))
+	}
+	fmt.Println("  " + i18n.T("summary.duration", result.Duration.Round(time.Millisecond)))
+
+	if len(result.Warnings) > 0 {
+		fmt.Println("\n" + i18n.T("summary.warnings", len(result.Warnings)))
+		for _, w := range result.Warnings {
+			fmt.Printf("  ! %s\n", w)
+		}
+	}
+
+	if result.DryRun {
+		return
+	}
+
+	fmt.Println("\n" + i18n.T("summary.success", result.OutputDir))
+	if len(result.NextSteps) > 0 {
+		fmt.Println("\n" + i18n.T("next_steps"))
+		for _, step := range result.NextSteps {
+			fmt.Printf("  %s\n", step)
+		}
+	}
+}
+
+// overriddenFiles returns the sources of the generated files that came from
+// the project's overrides
+func overriddenFiles(result *generator.GenerationResult) []string {
+	var sources []string
+	for _, f := range result.Files {
+		if f.Override {
+			sources = append(sources, f.Source)
+		}
+	}
+	return sources
+}
+
+// printFileList prints what happened to each file of a generation
+func printFileList(result *generator.GenerationResult) {
+	for _, f := range result.Files {
+		switch f.Action {
+		case generator.FileActionCreated:
+			fmt.Println(i18n.T("summary.created", f.Path))
+		case generator.FileActionUpdated:
+			fmt.Println(i18n.T("summary.updated", f.Path, f.Mode))
+		case generator.FileActionPlanned:
+			key := "summary.would_copy"
+			if f.Rendered {
+				key = "summary.would_render"
+			}
+			fmt.Println(i18n.T(key, f.Source, f.Path))
+		case generator.FileActionSkipped:
+			if result.DryRun {
+				fmt.Println(i18n.T("summary.skipped", f.Path))
+			}
+		}
+	}
+}
+
+// scanFindingsShown bounds the findings listed in the text summary
+const scanFindingsShown = 20
+
+// printScanSummary prints the scanners that did not run and the findings of
+// a vulnerability scan, most severe first
+func printScanSummary(scan *generator.ScanResult) {
+	for _, run := range scan.Scanners {
+		switch {
+		case run.Skipped != "":
+			fmt.Println("  " + i18n.T("summary.scan_skipped", run.Scanner, run.Skipped))
+		case run.Error != "":
+			fmt.Println("  " + i18n.T("summary.scan_error", run.Scanner, run.Error))
+		}
+	}
+	if !scan.Scanned() {
+		return
+	}
+	if len(scan.Findings) == 0 {
+		fmt.Println("  " + i18n.T("summary.scan_clean"))
+		return
+	}
+
+	counts := vulnscan.Count(scan.Findings)
+	var parts []string
+	for _, severity := range []vulnscan.Severity{vulnscan.SeverityCritical, vulnscan.SeverityHigh, vulnscan.SeverityUnknown, vulnscan.SeverityMedium, vulnscan.SeverityLow} {
+		if counts[severity] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[severity], severity))
+		}
+	}
+	fmt.Println("  " + i18n.T("summary.scan", len(scan.Findings), "("+strings.Join(parts, ", ")+")", scan.FailOn))
+
+	for i, f := range scan.Findings {
+		if i == scanFindingsShown {
+			fmt.Println("    " + i18n.T("summary.scan_more", len(scan.Findings)-i))
+			break
+		}
+		status := "!"
+		if f.Severity.AtLeast(scan.FailOn) {
+			status = "✗"
+		}
+		line := fmt.Sprintf("    %s [%s] %s", status, f.Severity, f.Package)
+		if f.Version != "" {
+			line += " " + f.Version
+		}
+		line += ": " + f.ID
+		if f.FixedVersion != "" {
+			line += " (" + i18n.T("summary.scan_fixed", f.FixedVersion) + ")"
+		}
+		fmt.Println(line)
+	}
+}
+
+// printDeprecationBanner prints a prominent deprecation warning to stderr
+func printDeprecationBanner(notice string) {
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "⚠ ================================================================")
+	fmt.Fprintln(os.Stderr, "⚠ "+i18n.T("summary.deprecated", notice))
+	fmt.Fprintln(os.Stderr, "⚠ ================================================================")
+	fmt.Fprintln(os.Stderr, "")
+}
+
+// formatSize formats a byte count for display
+func formatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
+// hooksRun counts the hooks that were executed
+func hooksRun(hooks []generator.HookResult) int {
+	n := 0
+	for _, h := range hooks {
+		if h.Ran() {
+			n++
+		}
+	}
+	return n
+}
+
+// printHookResults lists hooks with their status, indented by indent
+func printHookResults(hooks []generator.HookResult, indent string) {
+	for _, h := range hooks {
+		if h.Skipped {
+			reason := i18n.T("summary.hook_when", h.When)
+			if h.BlockedBy != "" {
+				reason = i18n.T("summary.hook_blocked", h.BlockedBy)
+			}
+			fmt.Printf("%s- %s (%s)\n", indent, h.Command, reason)
+			continue
+		}
+		status := "✓"
+		if h.Error != "" {
+			status = "✗"
+		}
+		details := h.Duration.Round(time.Millisecond).String()
+		if h.Attempts > 1 {
+			details += ", " + i18n.T("summary.hook_attempts", h.Attempts)
+		}
+		fmt.Printf("%s%s %s (%s)\n", indent, status, h.Command, details)
+		for _, line := range h.Output {
+			fmt.Printf("%s    %s\n", indent, line)
+		}
+	}
+}
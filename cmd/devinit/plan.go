@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/renan-dev/devinit/internal/generator"
+	"github.com/renan-dev/devinit/internal/i18n"
+)
+
+// reviewPlan shows the files generation with opts would write and lets the
+// user switch optional file groups on and off, planning again after each
+// change, until they generate or cancel. Switched groups are stored in
+// opts.Variables, so generation writes exactly the reviewed files. It
+// returns false when the user cancels.
+func reviewPlan(gen *generator.Generator, opts *generator.Options, in *bufio.Reader, out io.Writer) (bool, error) {
+	for {
+		planOpts := *opts
+		planOpts.DryRun = true
+		result, err := gen.Generate(&planOpts)
+		if err != nil {
+			return false, i18n.Errorf("new.failed", err)
+		}
+		groups, err := gen.FileGroups(opts)
+		if err != nil {
+			return false, err
+		}
+		printPlan(out, result, groups)
+
+		fmt.Fprint(out, i18n.T("plan.prompt"))
+		line, err := in.ReadString('\n')
+		if err != nil && line == "" {
+			fmt.Fprintln(out)
+			return false, nil
+		}
+
+		switch answer := strings.ToLower(strings.TrimSpace(line)); answer {
+		case "g", "y", "yes":
+			return true, nil
+		case "q", "n", "no":
+			return false, nil
+		case "":
+		default:
+			selected, err := parseGroupNumbers(answer, len(groups))
+			if err != nil {
+				fmt.Fprintln(out, err)
+				continue
+			}
+			for _, i := range selected {
+				toggleGroup(groups[i], opts.Variables, in, out)
+			}
+		}
+	}
+}
+
+// printPlan prints the planned files and the optional file groups, numbered
+// for toggling
+func printPlan(out io.Writer, result *generator.GenerationResult, groups []generator.FileGroup) {
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, i18n.T("plan.files", result.FilesWritten(), formatSize(result.TotalSize())))
+	for _, path := range generator.PlannedFiles(result) {
+		fmt.Fprintf(out, "  %s\n", path)
+	}
+
+	if len(groups) == 0 {
+		fmt.Fprintln(out, i18n.T("plan.no_groups"))
+		return
+	}
+	fmt.Fprintln(out, "\n"+i18n.T("plan.groups"))
+	for i, group := range groups {
+		mark := " "
+		if group.Enabled {
+			mark = "x"
+		}
+		name := group.Variable
+		if !group.Bool && group.Enabled {
+			name += "=" + fmt.Sprint(group.Value)
+		}
+		if group.Description != "" {
+			name += ": " + group.Description
+		}
+		fmt.Fprintf(out, "  %d. [%s] %s (%s)\n", i+1, mark, name, i18n.T("plan.group_files", len(group.Files)))
+	}
+}
+
+// parseGroupNumbers parses a list of group numbers such as "1 3" or "1,3"
+// into indexes
+func parseGroupNumbers(answer string, count int) ([]int, error) {
+	var indexes []int
+	for _, field := range strings.FieldsFunc(answer, func(r rune) bool { return r == ',' || r == ' ' }) {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 1 || n > count {
+			return nil, i18n.Errorf("plan.invalid", field)
+		}
+		indexes = append(indexes, n-1)
+	}
+	return indexes, nil
+}
+
+// toggleGroup switches a file group by setting its variable. Switching on a
+// variable that is not boolean needs a value: the only choice of the
+// template, or one the user enters.
+func toggleGroup(group generator.FileGroup, variables map[string]interface{}, in *bufio.Reader, out io.Writer) {
+	switch {
+	case group.Bool:
+		variables[group.Variable] = !group.Enabled
+		return
+	case group.Enabled:
+		variables[group.Variable] = "none"
+		return
+	}
+
+	var choices []string
+	for _, choice := range group.Choices {
+		if choice != "none" {
+			choices = append(choices, choice)
+		}
+	}
+	if len(choices) == 1 {
+		variables[group.Variable] = choices[0]
+		return
+	}
+
+	if len(choices) > 0 {
+		fmt.Fprint(out, i18n.T("plan.choose_from", group.Variable, strings.Join(choices, ", ")))
+	} else {
+		fmt.Fprint(out, i18n.T("plan.choose", group.Variable))
+	}
+	line, _ := in.ReadString('\n')
+	value := strings.TrimSpace(line)
+	switch {
+	case value == "":
+	case len(choices) > 0 && !contains(choices, value):
+		fmt.Fprintln(out, i18n.T("plan.invalid", value))
+	default:
+		variables[group.Variable] = value
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
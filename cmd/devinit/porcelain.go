@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/renan-dev/devinit/internal/generator"
+	"github.com/renan-dev/devinit/internal/i18n"
+	"github.com/spf13/cobra"
+)
+
+// outputPorcelain is the output format selected by --porcelain: one
+// tab-separated record per item, starting with the kind of item, in English
+// and without decoration. The fields of a record only ever get appended to,
+// so scripts can rely on their positions.
+const outputPorcelain = "porcelain"
+
+// porcelainUsage is the help of the --porcelain flags
+const porcelainUsage = "stable tab-separated records for scripts (see README)"
+
+// selectPorcelain sets output to the porcelain format when --porcelain is
+// given, which cannot be combined with --output. When only one of them comes
+// from the environment, the one on the command line wins.
+func selectPorcelain(cmd *cobra.Command, porcelain bool, output *string) error {
+	if !porcelain {
+		return nil
+	}
+	if cmd.Flags().Changed("output") {
+		outputEnv, porcelainEnv := fromEnv(cmd, "output"), fromEnv(cmd, "porcelain")
+		if outputEnv == porcelainEnv {
+			return usageError(i18n.Errorf("usage.porcelain_conflict"))
+		}
+		if porcelainEnv {
+			return nil
+		}
+	}
+	*output = outputPorcelain
+	return nil
+}
+
+// printRecord writes a porcelain record. Tabs and line breaks inside fields
+// become spaces so that every record stays one line.
+func printRecord(w io.Writer, kind string, fields ...string) {
+	clean := strings.NewReplacer("\t", " ", "\r\n", " ", "\n", " ", "\r", " ")
+	line := kind
+	for _, field := range fields {
+		line += "\t" + clean.Replace(field)
+	}
+	fmt.Fprintln(w, line)
+}
+
+// printGenerationRecords prints the result of a generation as porcelain
+// records: the project, then its files, hooks, warnings and next steps
+func printGenerationRecords(w io.Writer, result *generator.GenerationResult) {
+	printRecord(w, "project", result.TemplateName, result.TemplateVersion, result.OutputDir, fmt.Sprint(result.DryRun))
+	if result.Deprecation != "" {
+		printRecord(w, "deprecated", result.Deprecation)
+	}
+	for _, f := range result.Files {
+		origin := "template"
+		if f.Override {
+			origin = "override"
+		}
+		printRecord(w, "file", string(f.Action), f.Path, f.Source, origin)
+	}
+	for _, h := range result.Hooks {
+		printHookRecord(w, h)
+	}
+	for _, warning := range result.Warnings {
+		printRecord(w, "warning", warning)
+	}
+	for _, step := range result.NextSteps {
+		printRecord(w, "next", step)
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/renan-dev/devinit/internal/i18n"
+)
+
+// runPorcelain runs devinit with the message locale set to locale and
+// returns the records it printed, split into fields
+func runPorcelain(t *testing.T, locale string, args ...string) [][]string {
+	t.Helper()
+	t.Setenv(i18n.EnvLocale, locale)
+	setLocale()
+	t.Cleanup(func() { i18n.SetLocale("en") })
+	if i18n.Locale() != locale {
+		t.Fatalf("locale = %s, want %s", i18n.Locale(), locale)
+	}
+
+	out, err := execute(t, args...)
+	if err != nil && exitCode(err) != exitRequirement && exitCode(err) != exitValidation {
+		t.Fatalf("devinit %v unexpected error: %v", args, err)
+	}
+	if strings.Contains(out, "\x1b") {
+		t.Errorf("devinit %v printed ANSI escapes: %q", args, out)
+	}
+
+	var records [][]string
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+		if line != "" {
+			records = append(records, strings.Split(line, "\t"))
+		}
+	}
+	return records
+}
+
+func TestPorcelain(t *testing.T) {
+	templatesDir := t.TempDir()
+	writeTemplate(t, templatesDir, "test/basic", `version: "1.2.0"
+name: basic
+description: Basic test template
+language: test
+framework: basic
+tags: [api, test]
+requirements:
+  system:
+    - command: devinit-test-missing-tool
+      required: false
+files:
+  - src: README.md.tmpl
+    dest: README.md
+`, map[string]string{"README.md.tmpl": "# {{ .ProjectName }}\n"})
+	t.Chdir(t.TempDir())
+
+	tests := []struct {
+		name  string
+		args  []string
+		setup func(t *testing.T)
+		want  [][]string
+	}{
+		{
+			name: "new",
+			args: []string{"new", "api", "demo", "--lang", "test", "--framework", "basic", "--dry-run", "--porcelain"},
+			want: [][]string{
+				{"project", "test/basic", "1.2.0", "demo", "true"},
+				{"file", "planned", "demo/README.md", "README.md.tmpl", "template"},
+			},
+		},
+		{
+			name: "validate",
+			args: []string{"validate", "demo", "--drift", "--porcelain"},
+			setup: func(t *testing.T) {
+				if _, err := execute(t, "--templates-dir", templatesDir, "new", "api", "demo", "--lang", "test", "--framework", "basic", "--porcelain"); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join("demo", "README.md"), []byte("# changed\n"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: [][]string{{"drift", "modified", "README.md"}},
+		},
+		{
+			name: "doctor",
+			args: []string{"doctor", "--porcelain"},
+			want: [][]string{{"requirement", "test/basic", "warning", "devinit-test-missing-tool"}},
+		},
+		{
+			name: "templates list",
+			args: []string{"templates", "list", "--porcelain"},
+			want: [][]string{{"template", "test/basic", "api", "1.2.0", "active", "api,test", "Basic test template"}},
+		},
+		{
+			name: "templates validate",
+			args: []string{"templates", "validate", "--porcelain"},
+			want: [][]string{{"template", "test/basic", "ok", ""}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.setup != nil {
+				tt.setup(t)
+			}
+			args := append([]string{"--templates-dir", templatesDir}, tt.args...)
+			got := runPorcelain(t, "en", args...)
+
+			// Records are compared up to the fields the test names, so
+			// that messages may be reworded and fields added at the end
+			trimmed := make([][]string, len(got))
+			for i, record := range got {
+				trimmed[i] = record
+				if i < len(tt.want) && len(record) > len(tt.want[i]) {
+					trimmed[i] = record[:len(tt.want[i])]
+				}
+			}
+			if !reflect.DeepEqual(trimmed, tt.want) {
+				t.Errorf("records = %q, want %q", got, tt.want)
+			}
+
+			// The records are the same whatever the message language
+			if localized := runPorcelain(t, "pt", args...); !reflect.DeepEqual(localized, got) {
+				t.Errorf("records in Portuguese = %q, want %q", localized, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+
+	"github.com/renan-dev/devinit/internal/i18n"
+	"github.com/spf13/cobra"
+)
+
+// stopProfiling finishes the profiles started by startProfiling; main calls
+// it once the command returns
+var stopProfiling = func() {}
+
+// addProfilingFlags adds --cpu-profile and --mem-profile, which write pprof
+// data for `go tool pprof`. (--profile selects template variable presets.)
+func addProfilingFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().String("cpu-profile", "", "write a CPU profile (pprof) to this file")
+	cmd.PersistentFlags().String("mem-profile", "", "write a heap profile (pprof) to this file on exit")
+	cmd.PersistentPreRunE = startProfiling
+}
+
+func startProfiling(cmd *cobra.Command, args []string) error {
+	cpuProfile, _ := cmd.Flags().GetString("cpu-profile")
+	memProfile, _ := cmd.Flags().GetString("mem-profile")
+
+	var stops []func()
+	if cpuProfile != "" {
+		f, err := os.Create(cpuProfile)
+		if err != nil {
+			return i18n.Errorf("pprof.cpu_failed", err)
+		}
+		if err := pprof.StartCPUProfile(f); err != nil {
+			f.Close()
+			return i18n.Errorf("pprof.cpu_failed", err)
+		}
+		stops = append(stops, func() {
+			pprof.StopCPUProfile()
+			f.Close()
+		})
+	}
+	if memProfile != "" {
+		stops = append(stops, func() {
+			f, err := os.Create(memProfile)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("pprof.heap_failed", err))
+				return
+			}
+			defer f.Close()
+			runtime.GC()
+			if err := pprof.WriteHeapProfile(f); err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("pprof.heap_failed", err))
+			}
+		})
+	}
+
+	stopProfiling = func() {
+		for _, stop := range stops {
+			stop()
+		}
+	}
+	return nil
+}
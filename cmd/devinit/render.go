@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/renan-dev/devinit/internal/errcode"
+	"github.com/renan-dev/devinit/internal/generator"
+	"github.com/renan-dev/devinit/internal/i18n"
+	"github.com/renan-dev/devinit/internal/output"
+	"github.com/renan-dev/devinit/internal/template"
+	"github.com/spf13/cobra"
+)
+
+func newRenderCmd() *cobra.Command {
+	var (
+		templateDir string
+		out         string
+		name        string
+		engine      string
+		delims      []string
+		vars        []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "render [file|-]",
+		Short: "Render a template file or directory outside of projects",
+		Long: `Render one-off files with devinit's template engine, helpers and
+variables, without the conventions of templates: no template.yaml, file
+conditions, hooks or .devinit.yaml.
+
+Without --template-dir, render a single template read from a file, or from
+stdin when it is - or missing, to stdout or the file named by --out.
+
+With --template-dir, render every file below the directory into the --out
+directory: files ending in .tmpl are rendered and lose the suffix, others
+are copied, and template expressions in paths are rendered too.
+
+Templates see the variables as {{ .Variables.name }} (or {{ name }} with
+--engine jinja2) and the project name as {{ .ProjectName }}. Files full of
+{{ }} of their own, such as GitHub Actions workflows, can use other
+delimiters with --delims.
+
+Examples:
+  echo 'port: {{ .Variables.port }}' | devinit render --var port=8080
+  devinit render deploy.yaml.tmpl --name billing --out deploy.yaml
+  devinit render --template-dir ./mytmpl --var team=core --out ./out
+  devinit render ci.yml.tmpl --delims '[[,]]' --var go=1.22`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if templateDir != "" && len(args) > 0 {
+				return usageError(i18n.Errorf("render.dir_and_file"))
+			}
+			if templateDir != "" && out == "" {
+				return usageError(i18n.Errorf("render.out_required"))
+			}
+			switch engine {
+			case template.EngineGoTemplate, template.EngineJinja2:
+			default:
+				return usageError(i18n.Errorf("render.invalid_engine", engine, template.EngineGoTemplate, template.EngineJinja2))
+			}
+			if len(delims) > 0 && (engine == template.EngineJinja2 || len(delims) != 2 || delims[0] == "" || delims[1] == "") {
+				return usageError(i18n.Errorf("render.invalid_delims", strings.Join(delims, ",")))
+			}
+
+			variables, err := parseVarFlags(vars)
+			if err != nil {
+				return err
+			}
+			opts := &generator.RenderOptions{ProjectName: name, Variables: variables, Engine: engine, Delimiters: delims}
+			gen := getGenerator()
+
+			if templateDir != "" {
+				written, err := gen.RenderTree(templateDir, opts, output.NewDir(out))
+				if err != nil {
+					return errcode.New(errcode.TemplateInvalid, i18n.Errorf("render.failed", err))
+				}
+				fmt.Fprintln(os.Stderr, i18n.T("render.written", len(written), out))
+				return nil
+			}
+
+			source := "-"
+			if len(args) > 0 {
+				source = args[0]
+			}
+			var text []byte
+			if source == "-" {
+				text, err = io.ReadAll(os.Stdin)
+				source = "stdin"
+			} else {
+				text, err = os.ReadFile(source)
+			}
+			if err != nil {
+				return usageError(i18n.Errorf("render.read_failed", err))
+			}
+
+			rendered, err := gen.RenderText(source, string(text), opts)
+			if err != nil {
+				return errcode.New(errcode.TemplateInvalid, i18n.Errorf("render.failed", err))
+			}
+			if out == "" {
+				_, err = io.WriteString(os.Stdout, rendered)
+				return err
+			}
+			return os.WriteFile(out, []byte(rendered), 0644)
+		},
+	}
+
+	cmd.Flags().StringVar(&templateDir, "template-dir", "", "render every file of this directory instead of a single template")
+	cmd.Flags().StringVar(&out, "out", "", "file (or directory with --template-dir) to write to (default stdout)")
+	cmd.Flags().StringVar(&name, "name", "", "project name for {{ .ProjectName }} and the names derived from it")
+	cmd.Flags().StringVar(&engine, "engine", template.EngineGoTemplate, "template engine: gotemplate or jinja2")
+	cmd.Flags().StringSliceVar(&delims, "delims", nil, "left and right action delimiters of Go templates instead of {{ and }}, e.g. '[[,]]'")
+	cmd.Flags().StringArrayVar(&vars, "var", nil, "template variable as KEY=VALUE (repeatable)")
+
+	return cmd
+}
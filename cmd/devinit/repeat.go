@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/renan-dev/devinit/internal/i18n"
+	"github.com/renan-dev/devinit/internal/paths"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// lastGenerationFile is the file in the state directory holding the
+// parameters of the last project devinit new generated
+const lastGenerationFile = "last-generation.json"
+
+// repeatSkipped are the flags of new that are not repeated: --repeat-last
+// itself and --dry-run, as dry runs are not recorded anyway
+var repeatSkipped = map[string]bool{
+	"repeat-last": true,
+	"dry-run":     true,
+}
+
+// repeatPaths are the flags naming files, recorded as absolute paths so
+// the generation can be repeated from another directory; so are the files
+// of --var KEY=file:path values
+var repeatPaths = map[string]bool{
+	"from-openapi":  true,
+	"from-proto":    true,
+	"from-project":  true,
+	"templates-dir": true,
+}
+
+// lastGeneration is the command line of the last project devinit new
+// generated
+type lastGeneration struct {
+	Time  time.Time           `json:"time"`
+	Args  []string            `json:"args"`
+	Flags map[string][]string `json:"flags"`
+}
+
+// lastGenerationPath returns the file recording the last generation
+func lastGenerationPath() (string, error) {
+	dir, err := paths.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, lastGenerationFile), nil
+}
+
+// newLastGeneration returns the arguments and the flags given on the
+// command line (not from the environment) of a generation, taken before
+// the command changes any of them
+func newLastGeneration(cmd *cobra.Command, args []string) *lastGeneration {
+	last := &lastGeneration{Args: args, Flags: make(map[string][]string)}
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		if repeatSkipped[f.Name] || fromEnv(cmd, f.Name) || (cmd.LocalFlags().Lookup(f.Name) == nil && f.Name != "templates-dir") {
+			return
+		}
+		values := []string{f.Value.String()}
+		if slice, ok := f.Value.(pflag.SliceValue); ok {
+			values = slice.GetSlice()
+		}
+		for i, value := range values {
+			switch {
+			case repeatPaths[f.Name]:
+				if abs, err := filepath.Abs(value); err == nil {
+					values[i] = abs
+				}
+			case f.Name == "var":
+				values[i] = absVarFile(value)
+			}
+		}
+		last.Flags[f.Name] = values
+	})
+	return last
+}
+
+// absVarFile returns a --var KEY=file:path value with the path made
+// absolute, and other values unchanged
+func absVarFile(value string) string {
+	key, path, ok := strings.Cut(value, "="+varFromFile)
+	if !ok || strings.Contains(key, "=") {
+		return value
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return value
+	}
+	return key + "=" + varFromFile + abs
+}
+
+// save records a successful generation for --repeat-last. Failures are
+// reported but do not fail the command.
+func (last *lastGeneration) save() {
+	last.Time = time.Now().UTC()
+	path, err := lastGenerationPath()
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), 0755)
+	}
+	var data []byte
+	if err == nil {
+		data, err = json.MarshalIndent(last, "", "  ")
+	}
+	// --from-db may hold a password
+	if err == nil {
+		err = os.WriteFile(path, append(data, '\n'), 0600)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("new.record_last_failed", err))
+	}
+}
+
+// repeatLastGeneration applies the recorded last generation to a new
+// command: its flags fill in those not given on the command line and its
+// arguments are used, with the project name replaced by name when given.
+// It returns the arguments to generate with.
+func repeatLastGeneration(cmd *cobra.Command, args []string) ([]string, error) {
+	if len(args) > 1 {
+		return nil, usageError(i18n.Errorf("new.repeat_last_args"))
+	}
+	path, err := lastGenerationPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, usageError(i18n.Errorf("new.repeat_last_none"))
+	}
+	if err != nil {
+		return nil, err
+	}
+	var last lastGeneration
+	if err := json.Unmarshal(data, &last); err != nil || len(last.Args) == 0 {
+		return nil, i18n.Errorf("new.repeat_last_invalid", path, err)
+	}
+
+	repeated := append([]string(nil), last.Args...)
+	if len(args) == 1 {
+		repeated[len(repeated)-1] = args[0]
+	}
+	names := make([]string, 0, len(last.Flags))
+	for name := range last.Flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	line := append([]string{"devinit", "new"}, repeated...)
+	for _, name := range names {
+		f := cmd.Flags().Lookup(name)
+		if f == nil || f.Changed {
+			continue
+		}
+		for _, value := range last.Flags[name] {
+			if err := cmd.Flags().Set(name, value); err != nil {
+				return nil, i18n.Errorf("new.repeat_last_invalid", path, err)
+			}
+			if name == "from-db" {
+				if u, err := url.Parse(value); err == nil {
+					value = u.Redacted()
+				}
+			}
+			line = append(line, "--"+name+"="+shellQuote(value))
+		}
+	}
+	fmt.Fprintln(os.Stderr, i18n.T("new.repeating", strings.Join(line, " ")))
+	return repeated, nil
+}
+
+// shellQuote quotes a value for display in a command line when it needs it
+func shellQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\n\"'\\$`*?[]{}()<>|&;#~") {
+		return s
+	}
+	return strconv.Quote(s)
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/renan-dev/devinit/internal/errcode"
+	"github.com/renan-dev/devinit/internal/paths"
+)
+
+func TestRepeatLast(t *testing.T) {
+	stateDir := t.TempDir()
+	t.Setenv(paths.EnvStateDir, stateDir)
+	templatesDir := t.TempDir()
+	writeTemplate(t, templatesDir, "test/basic", `version: "1.0.0"
+name: basic
+language: test
+framework: basic
+variables:
+  greeting:
+    type: string
+    default: hello
+files:
+  - src: README.md.tmpl
+    dest: README.md
+`, map[string]string{"README.md.tmpl": "# {{ .ProjectName }}\n{{ .Variables.greeting }}\n"})
+
+	work := t.TempDir()
+	t.Chdir(work)
+	if err := os.WriteFile("greeting.txt", []byte("hi\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing to repeat yet
+	if _, err := execute(t, "new", "--repeat-last"); errcode.Of(err) != errcode.Usage {
+		t.Fatalf("new --repeat-last before any generation error = %v, want a usage error", err)
+	}
+
+	// Dry runs are not recorded, generations are
+	args := []string{"--templates-dir", templatesDir, "new", "api", "demo", "--lang", "test", "--framework", "basic", "--var", "greeting=file:greeting.txt"}
+	if _, err := execute(t, append(args, "--dry-run")...); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(stateDir, lastGenerationFile)); !os.IsNotExist(err) {
+		t.Fatalf("a dry run was recorded: %v", err)
+	}
+	if _, err := execute(t, args...); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(stateDir, lastGenerationFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var last lastGeneration
+	if err := json.Unmarshal(data, &last); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]string{
+		"templates-dir": {templatesDir},
+		"lang":          {"test"},
+		"framework":     {"basic"},
+		"var":           {"greeting=file:" + filepath.Join(work, "greeting.txt")},
+	}
+	if !reflect.DeepEqual(last.Args, []string{"api", "demo"}) || !reflect.DeepEqual(last.Flags, want) {
+		t.Errorf("recorded %v %v, want [api demo] %v", last.Args, last.Flags, want)
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		dir      string
+		want     string
+		wantCode errcode.Code
+	}{
+		{
+			name: "from another directory",
+			args: []string{"new", "--repeat-last"},
+			dir:  "demo",
+			want: "# demo\nhi\n",
+		},
+		{
+			name: "new name",
+			args: []string{"new", "--repeat-last", "other"},
+			dir:  "other",
+			want: "# other\nhi\n",
+		},
+		{
+			name: "command-line flag wins",
+			args: []string{"new", "--repeat-last", "third", "--var", "greeting=hey"},
+			dir:  "third",
+			want: "# third\nhey\n",
+		},
+		{
+			name:     "too many arguments",
+			args:     []string{"new", "--repeat-last", "api", "fourth"},
+			wantCode: errcode.Usage,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			_, err := execute(t, tt.args...)
+			if tt.wantCode != "" {
+				if errcode.Of(err) != tt.wantCode {
+					t.Fatalf("devinit %v error = %v, want code %s", tt.args, err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("devinit %v unexpected error: %v", tt.args, err)
+			}
+			got, err := os.ReadFile(filepath.Join(tt.dir, "README.md"))
+			if err != nil || string(got) != tt.want {
+				t.Errorf("README.md = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAbsVarFile(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "key=file:notes.txt", want: "key=file:" + filepath.Join(dir, "notes.txt")},
+		{value: "key=file:" + filepath.Join(dir, "a", "..", "notes.txt"), want: "key=file:" + filepath.Join(dir, "notes.txt")},
+		{value: "key=value", want: "key=value"},
+		{value: "key=raw:file:notes.txt", want: "key=raw:file:notes.txt"},
+		{value: "key=a=file:notes.txt", want: "key=a=file:notes.txt"},
+		{value: "key=env:FILE", want: "key=env:FILE"},
+	}
+
+	for _, tt := range tests {
+		if got := absVarFile(tt.value); got != tt.want {
+			t.Errorf("absVarFile(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
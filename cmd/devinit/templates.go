@@ -0,0 +1,1149 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/renan-dev/devinit/internal/archive"
+	"github.com/renan-dev/devinit/internal/config"
+	"github.com/renan-dev/devinit/internal/diff"
+	"github.com/renan-dev/devinit/internal/docs"
+	"github.com/renan-dev/devinit/internal/errcode"
+	"github.com/renan-dev/devinit/internal/generator"
+	"github.com/renan-dev/devinit/internal/harness"
+	"github.com/renan-dev/devinit/internal/i18n"
+	"github.com/renan-dev/devinit/internal/importer"
+	"github.com/renan-dev/devinit/internal/oci"
+	"github.com/renan-dev/devinit/internal/paths"
+	"github.com/renan-dev/devinit/internal/template"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+func newTemplatesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "templates",
+		Short: "Manage templates",
+		Long:  "List, show, and manage project templates",
+		Args:  unknownCommand,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+
+	cmd.AddCommand(newTemplatesListCmd())
+	cmd.AddCommand(newTemplatesShowCmd())
+	cmd.AddCommand(newTemplatesValidateCmd())
+	cmd.AddCommand(newTemplatesSearchCmd())
+	cmd.AddCommand(newTemplatesDiffCmd())
+	cmd.AddCommand(newTemplatesDocsCmd())
+	cmd.AddCommand(newTemplatesSchemaCmd())
+	cmd.AddCommand(newTemplatesContextCmd())
+	cmd.AddCommand(newTemplatesTestCmd())
+	cmd.AddCommand(newTemplatesIndexCmd())
+	cmd.AddCommand(newTemplatesImportCmd())
+	cmd.AddCommand(newTemplatesPushCmd())
+	cmd.AddCommand(newTemplatesPullCmd())
+	cmd.AddCommand(newTemplatesUpdateCmd())
+	cmd.AddCommand(newTemplatesLoginCmd())
+	cmd.AddCommand(newTemplatesLogoutCmd())
+	cmd.AddCommand(newTemplatesGitCredentialCmd())
+
+	return cmd
+}
+
+// templateListEntry is the JSON representation of a template in listings
+type templateListEntry struct {
+	Name         string   `json:"name"`
+	Language     string   `json:"language"`
+	Framework    string   `json:"framework"`
+	Type         string   `json:"type"`
+	Version      string   `json:"version"`
+	Description  string   `json:"description"`
+	Tags         []string `json:"tags"`
+	Deprecated   bool     `json:"deprecated"`
+	SupersededBy string   `json:"superseded_by,omitempty"`
+	SunsetDate   string   `json:"sunset_date,omitempty"`
+}
+
+func newTemplateListEntry(tmpl *template.Template) templateListEntry {
+	tags := tmpl.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	return templateListEntry{
+		Name:         tmpl.ID,
+		Language:     tmpl.Language,
+		Framework:    tmpl.Framework,
+		Type:         tmpl.GetType(),
+		Version:      tmpl.Version,
+		Description:  tmpl.Description,
+		Tags:         tags,
+		Deprecated:   tmpl.Deprecated,
+		SupersededBy: tmpl.SupersededBy,
+		SunsetDate:   tmpl.SunsetDate,
+	}
+}
+
+func newTemplatesListCmd() *cobra.Command {
+	var (
+		filter    template.Filter
+		output    string
+		porcelain bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List available templates",
+		Long: `List available templates grouped by language.
+
+Examples:
+  devinit templates list
+  devinit templates list --lang python
+  devinit templates list --type api --tag async
+  devinit templates list --output json
+  devinit templates list --porcelain | cut -f2`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateOutputFormat(output); err != nil {
+				return err
+			}
+			if err := selectPorcelain(cmd, porcelain, &output); err != nil {
+				return err
+			}
+			defer startUpdateCheck()()
+
+			gen := getGenerator()
+			templates, err := gen.FindTemplates(filter)
+			if err != nil {
+				return err
+			}
+
+			if output == outputJSON {
+				entries := make([]templateListEntry, 0, len(templates))
+				for _, tmpl := range templates {
+					entries = append(entries, newTemplateListEntry(tmpl))
+				}
+				return printJSON(entries)
+			}
+			if output == outputPorcelain {
+				printTemplateRecords(templates)
+				return nil
+			}
+
+			if len(templates) == 0 {
+				fmt.Println(i18n.T("templates.none"))
+				return nil
+			}
+
+			printTemplateTable(templates)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&filter.Language, "lang", "", "only show templates for this language")
+	cmd.Flags().StringVar(&filter.Type, "type", "", "only show templates of this project type (api, worker, lib, ...)")
+	cmd.Flags().StringSliceVar(&filter.Tags, "tag", nil, "only show templates with this tag (repeatable)")
+	cmd.Flags().StringVarP(&output, "output", "o", outputText, "output format (text, json)")
+	cmd.Flags().BoolVar(&porcelain, "porcelain", false, porcelainUsage)
+
+	return cmd
+}
+
+// printTemplateTable prints templates as a table grouped by language.
+// Templates must be sorted by name, which keeps languages contiguous.
+func printTemplateTable(templates []*template.Template) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	language := ""
+	for _, tmpl := range templates {
+		if tmpl.Language != language {
+			if language != "" {
+				fmt.Fprintln(w)
+			}
+			language = tmpl.Language
+			fmt.Fprintf(w, "%s\n", language)
+			fmt.Fprintln(w, "  "+i18n.T("templates.table_header"))
+		}
+
+		status := i18n.T("templates.status_active")
+		if tmpl.Deprecated {
+			status = i18n.T("templates.status_deprecated")
+			if tmpl.SupersededBy != "" {
+				status += " (" + i18n.T("templates.status_use", tmpl.SupersededBy) + ")"
+			}
+		}
+
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\t%s\n", tmpl.ID, tmpl.GetType(), tmpl.Version, status, strings.Join(tmpl.Tags, ","), tmpl.Description)
+	}
+}
+
+// printTemplateRecords prints templates as porcelain records
+func printTemplateRecords(templates []*template.Template) {
+	for _, tmpl := range templates {
+		status := "active"
+		if tmpl.Deprecated {
+			status = "deprecated"
+		}
+		printRecord(os.Stdout, "template", tmpl.ID, tmpl.GetType(), tmpl.Version, status, strings.Join(tmpl.Tags, ","), tmpl.Description)
+	}
+}
+
+func newTemplatesSearchCmd() *cobra.Command {
+	var (
+		filter    template.Filter
+		output    string
+		porcelain bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "search [query]",
+		Short: "Search templates by name, description, and tags",
+		Long: `Search templates by free text and capability tags.
+
+Examples:
+  devinit templates search grpc
+  devinit templates search --tag async --tag docker
+  devinit templates search "rest api" --lang python`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateOutputFormat(output); err != nil {
+				return err
+			}
+			if err := selectPorcelain(cmd, porcelain, &output); err != nil {
+				return err
+			}
+			if len(args) == 1 {
+				filter.Query = args[0]
+			}
+			if filter.Query == "" && len(filter.Tags) == 0 {
+				return usageError(i18n.Errorf("templates.query_required"))
+			}
+
+			gen := getGenerator()
+			templates, err := gen.FindTemplates(filter)
+			if err != nil {
+				return err
+			}
+
+			if output == outputJSON {
+				entries := make([]templateListEntry, 0, len(templates))
+				for _, tmpl := range templates {
+					entries = append(entries, newTemplateListEntry(tmpl))
+				}
+				return printJSON(entries)
+			}
+			if output == outputPorcelain {
+				printTemplateRecords(templates)
+				return nil
+			}
+
+			if len(templates) == 0 {
+				fmt.Println(i18n.T("templates.no_matches"))
+				return nil
+			}
+
+			printTemplateTable(templates)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&filter.Language, "lang", "", "only search templates for this language")
+	cmd.Flags().StringVar(&filter.Type, "type", "", "only search templates of this project type")
+	cmd.Flags().StringSliceVar(&filter.Tags, "tag", nil, "require this tag (repeatable)")
+	cmd.Flags().StringVarP(&output, "output", "o", outputText, "output format (text, json)")
+	cmd.Flags().BoolVar(&porcelain, "porcelain", false, porcelainUsage)
+
+	return cmd
+}
+
+func newTemplatesShowCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "show [template[@version]]",
+		Short: "Show template details",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			gen := getGenerator()
+			tmpl, err := loadTemplateRef(gen, args[0])
+			if err != nil {
+				return err
+			}
+
+			fmt.Println(i18n.T("templates.name", tmpl.Name))
+			fmt.Println(i18n.T("templates.version", tmpl.Version))
+			if versions, err := gen.ListTemplateVersions(tmpl.ID); err == nil && len(versions) > 1 {
+				fmt.Println(i18n.T("templates.versions", strings.Join(versions, ", ")))
+			}
+			fmt.Println(i18n.T("templates.description", tmpl.Description))
+			fmt.Println(i18n.T("templates.language", tmpl.Language))
+			fmt.Println(i18n.T("templates.framework", tmpl.Framework))
+			fmt.Println(i18n.T("templates.type", tmpl.GetType()))
+			if len(tmpl.Tags) > 0 {
+				fmt.Println(i18n.T("templates.tags", strings.Join(tmpl.Tags, ", ")))
+			}
+			if len(tmpl.Needs) > 0 {
+				fmt.Println(i18n.T("templates.needs", strings.Join(tmpl.Needs, ", ")))
+			}
+			if notice := tmpl.DeprecationNotice(); notice != "" {
+				fmt.Println(i18n.T("templates.deprecated", notice))
+			}
+			fmt.Println("\n" + i18n.T("templates.variables"))
+			for key, variable := range tmpl.Variables {
+				fmt.Printf("  %s (%s): %s\n", key, variable.Type, variable.Description)
+			}
+			if len(tmpl.Profiles) > 0 {
+				fmt.Println("\n" + i18n.T("templates.profiles"))
+				for _, name := range tmpl.ProfileNames() {
+					profile := tmpl.Profiles[name]
+					fmt.Printf("  %s: %s\n", name, profile.Description)
+					keys := make([]string, 0, len(profile.Variables))
+					for key := range profile.Variables {
+						keys = append(keys, key)
+					}
+					sort.Strings(keys)
+					for _, key := range keys {
+						fmt.Printf("    %s = %v\n", key, profile.Variables[key])
+					}
+				}
+			}
+			if len(tmpl.NextSteps) > 0 {
+				fmt.Println("\n" + i18n.T("next_steps"))
+				for _, step := range tmpl.NextSteps {
+					fmt.Printf("  %s\n", step)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func newTemplatesDocsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "docs [template[@version]]",
+		Short: "Print Markdown documentation of a template",
+		Long: `Print a Markdown reference of a template: its variables, profiles,
+requirements, the files generated under each condition, hooks and next steps.
+The document is always in English, for publishing template catalogs.
+
+Example:
+  devinit templates docs python/fastapi > docs/templates/python-fastapi.md`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tmpl, err := loadTemplateRef(getGenerator(), args[0])
+			if err != nil {
+				return err
+			}
+
+			fmt.Print(docs.Markdown(tmpl))
+			return nil
+		},
+	}
+}
+
+func newTemplatesSchemaCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "schema [template[@version]]",
+		Short: "Print a JSON Schema of the variables of a template",
+		Long: `Print a JSON Schema (draft 2020-12) of the variables of a template: their
+types, defaults, choices as enums and patterns, with the variables the
+template requires without a default as required. Web forms and editor plugins
+build input UIs for any template from it. The template and its profiles are
+under x-devinit.
+
+Example:
+  devinit templates schema python/fastapi > fastapi.schema.json`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tmpl, err := loadTemplateRef(getGenerator(), args[0])
+			if err != nil {
+				return err
+			}
+
+			return printJSON(docs.JSONSchema(tmpl))
+		},
+	}
+}
+
+func newTemplatesContextCmd() *cobra.Command {
+	var (
+		projectName string
+		output      string
+	)
+	opts := &newOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "context [template[@version]]",
+		Short: "Print the context a template renders with",
+		Long: `Print the context devinit new renders a template with, as YAML: the names
+computed from the project name, the variables after defaults, the profile,
+flags and --var values are merged (with where each value comes from), the
+common fields, and the conditions of files, requirements and hooks
+evaluated. Nothing is written.
+
+Examples:
+  devinit templates context python/fastapi --var Database=postgres
+  devinit templates context python/fastapi@1.4.0 --profile minimal --docker=false`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if output != outputYAML && output != outputJSON {
+				return usageError(i18n.Errorf("new.invalid_output", output, outputYAML, outputJSON))
+			}
+
+			variables, err := parseVarFlags(opts.vars)
+			if err != nil {
+				return err
+			}
+			variables["ProjectName"] = projectName
+			defaults := flagVariables(cmd, opts, variables)
+
+			name, version := splitTemplateRef(args[0])
+			lang, framework, _ := strings.Cut(name, "/")
+			report, err := getGenerator().DescribeContext(&generator.Options{
+				ProjectName:     projectName,
+				Language:        lang,
+				Framework:       framework,
+				TemplateVersion: version,
+				Profile:         opts.profile,
+				Variables:       variables,
+				Defaults:        defaults,
+			})
+			if err != nil {
+				return err
+			}
+
+			if output == outputJSON {
+				return printJSON(report)
+			}
+			enc := yaml.NewEncoder(os.Stdout)
+			enc.SetIndent(2)
+			if err := enc.Encode(report); err != nil {
+				return err
+			}
+			return enc.Close()
+		},
+	}
+
+	cmd.Flags().StringVar(&projectName, "name", "example", "project name used for rendering")
+	cmd.Flags().StringVar(&opts.profile, "profile", "", "template profile presetting a group of variables")
+	cmd.Flags().StringArrayVar(&opts.vars, "var", nil, "template variable as KEY=VALUE (repeatable)")
+	addVariableFlags(cmd, opts)
+	cmd.Flags().StringVarP(&output, "output", "o", outputYAML, "output format (yaml, json)")
+
+	return cmd
+}
+
+func newTemplatesValidateCmd() *cobra.Command {
+	var warningsAsErrors, porcelain bool
+
+	cmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Validate all templates",
+		Long: `Validate the metadata of all templates, and that their files only
+reference variables they declare.
+
+Exits with 6 when a template is invalid. Deprecated templates are reported as
+warnings, as are sequences of template files that look meant for the
+generated file but would be rendered, such as ${{ of GitHub Actions outside
+raw blocks; --warnings-as-errors fails on them too.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			gen := getGenerator()
+			templates, err := gen.ListTemplates()
+			if err != nil {
+				return err
+			}
+
+			if !porcelain {
+				fmt.Println(i18n.T("templates.validating"))
+			}
+			errors, warnings := 0, 0
+			for _, name := range templates {
+				tmpl, err := gen.GetTemplate(name)
+				var notices []string
+				if err == nil {
+					err = generator.CheckVariableRefs(tmpl, nil)
+				}
+				if err == nil {
+					notices, err = templateWarnings(tmpl)
+				}
+				switch {
+				case err != nil && porcelain:
+					printRecord(os.Stdout, "template", name, "error", err.Error())
+					errors++
+				case err != nil:
+					fmt.Printf("  ✗ %s: %v\n", name, err)
+					errors++
+				case len(notices) > 0:
+					for _, notice := range notices {
+						if porcelain {
+							printRecord(os.Stdout, "template", name, "warning", notice)
+						} else {
+							fmt.Printf("  ! %s: %s\n", name, notice)
+						}
+					}
+					warnings += len(notices)
+				case porcelain:
+					printRecord(os.Stdout, "template", name, "ok", "")
+				default:
+					fmt.Printf("  ✓ %s\n", name)
+				}
+			}
+			if warningsAsErrors {
+				errors += warnings
+			}
+
+			if errors > 0 {
+				return errcode.New(errcode.TemplateInvalid, i18n.Errorf("templates.validate_failed", errors))
+			}
+
+			if !porcelain {
+				fmt.Println("\n" + i18n.T("templates.all_valid"))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&warningsAsErrors, "warnings-as-errors", false, "fail on warnings, such as deprecated templates")
+	cmd.Flags().BoolVar(&porcelain, "porcelain", false, porcelainUsage)
+
+	return cmd
+}
+
+// templateWarnings returns the warnings of templates validate for tmpl
+func templateWarnings(tmpl *template.Template) ([]string, error) {
+	var warnings []string
+	if tmpl.Deprecated {
+		warnings = append(warnings, tmpl.DeprecationNotice())
+	}
+	conflicts, err := tmpl.Lint()
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range conflicts {
+		warnings = append(warnings, i18n.T("templates.conflict", c.File, c.Line, c.Text))
+	}
+	return warnings, nil
+}
+
+func newTemplatesIndexCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "index",
+		Short: "Write the template index",
+		Long: `Write index.yaml at the root of the templates directory, listing its
+templates. Listing then reads the index instead of walking the directory,
+which matters for large, remote or registry template stores. Templates added
+or removed afterwards are not listed until the index is written again.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			templates, err := getGenerator().WriteTemplateIndex()
+			if err != nil {
+				return err
+			}
+
+			fmt.Println(i18n.T("templates.indexed", len(templates), filepath.Join(getTemplatesDir(), template.IndexFile)))
+			return nil
+		},
+	}
+}
+
+func newTemplatesImportCmd() *cobra.Command {
+	var (
+		format    string
+		language  string
+		framework string
+		versions  bool
+		force     bool
+	)
+
+	cmd := &cobra.Command{
+		Use:     "import <url|path>",
+		Aliases: []string{"import-cookiecutter"},
+		Short:   "Convert a cookiecutter or copier template",
+		Long: `Convert a cookiecutter or copier template into a devinit template rendered
+with the jinja2 engine, written to <language>/<framework> in the templates
+directory. The format is detected from cookiecutter.json or copier.yml, and the
+language defaults to its name. Devinit templates (template.yaml) are copied as
+they are, under their own language.
+
+The source is a local directory or a git repository (a URL, a git@ address,
+the gh:, gl: and bb: abbreviations or a github.com/, gitlab.com/ or
+bitbucket.org/ path), cloned with git. A repository source may name the
+subdirectory of the template after // and the branch, tag or commit to import
+with ?ref=; only that subdirectory is checked out, so a template of a large
+monorepo is imported without downloading the rest. oci://registry/repository:tag
+pulls a template pushed with devinit templates push (see templates pull), and
+an https:// or s3:// URL of a .tar.gz or .tgz archive downloads a template
+published to an artifact store, s3:// with the AWS CLI. An archive source may
+also name a subdirectory after // and pins the archive with
+?checksum=sha256:<hex>: a download with another checksum is refused.
+
+Cookiecutter variables and copier questions become template variables: strings, booleans and numbers
+keep their defaults, lists become choices and defaults rendered from other
+variables are applied when the variable is not set. The directory naming the
+project is replaced by the project name, files named with {% if %} blocks get
+conditions, and post_gen_project hooks or copier tasks become post_generate
+hooks. What cannot be converted, such as copier migrations, is listed as
+warnings to finish by hand. Yeoman generators are programs rather than
+templates and are refused.
+
+With --versions the version tags of a git repository (1.2.0 or v1.2.0) are
+imported too: the newest becomes the template and the others its versions,
+which devinit templates diff compares.
+
+Example:
+  devinit templates import gh:acme/cookiecutter-fastapi
+  devinit new my-api --lang cookiecutter --framework fastapi
+  devinit templates import gh:acme/copier-django --versions
+  devinit templates import 'github.com/acme/platform//templates/python/fastapi?ref=v3'
+  devinit templates import 'https://artifacts.acme.dev/templates/fastapi-3.1.0.tar.gz?checksum=sha256:9f86d0...'`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			source := args[0]
+			if framework == "" && !importer.IsOCI(source) {
+				framework = importedName(source)
+			}
+			if format == "auto" {
+				format = ""
+			}
+
+			return importTemplate(importer.Options{
+				Source:    source,
+				Format:    importer.Format(format),
+				Language:  language,
+				Framework: framework,
+				Versions:  versions,
+				Force:     force,
+			})
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", "auto", "format of the source: auto, cookiecutter, copier or devinit")
+	cmd.Flags().StringVar(&language, "lang", "", "language of the imported template (default: the format, or the language of a devinit template)")
+	cmd.Flags().StringVar(&framework, "framework", "", "framework of the imported template (default: the source name without cookiecutter- or copier-)")
+	cmd.Flags().BoolVar(&versions, "versions", false, "import the version tags of a git source as template versions")
+	cmd.Flags().BoolVar(&force, "force", false, "replace an existing template")
+
+	return cmd
+}
+
+// importTemplate imports a template into the user's templates directory and
+// prints the result
+func importTemplate(opts importer.Options) error {
+	clones, err := cloneDir()
+	if err != nil {
+		return err
+	}
+	if opts.HTTPClient, err = httpClient(); err != nil {
+		return err
+	}
+	// The extracted built-in templates are a cache, so imports go to the
+	// user store instead
+	templatesDir, store := resolveTemplatesDir()
+	if store == paths.TemplatesEmbedded {
+		if templatesDir, err = paths.UserTemplatesDir(); err != nil {
+			return err
+		}
+	}
+	opts.TemplatesDir, opts.CloneDir = templatesDir, clones
+	opts.GitCredentialHelper = gitCredentialHelper()
+
+	result, err := importer.Import(context.Background(), opts)
+	switch {
+	case errors.Is(err, importer.ErrExists):
+		return errcode.New(errcode.Conflict, i18n.Errorf("templates.import_exists", err))
+	case errors.Is(err, importer.ErrGitMissing), errors.Is(err, importer.ErrAWSMissing):
+		return errcode.New(errcode.RequirementMissing, i18n.Errorf("templates.import_failed", err))
+	case errors.Is(err, importer.ErrUnsupported):
+		return errcode.New(errcode.Usage, i18n.Errorf("templates.import_failed", err))
+	case err != nil:
+		return errcode.New(errcode.TemplateInvalid, i18n.Errorf("templates.import_failed", err))
+	}
+
+	fmt.Println(i18n.T("templates.imported", result.Language+"/"+result.Framework, result.Files, len(result.Variables), result.Dir))
+	if len(result.Versions) > 0 {
+		fmt.Println(i18n.T("templates.import_versions", result.Version, strings.Join(result.Versions, ", ")))
+	}
+	if len(result.Warnings) > 0 {
+		fmt.Println("\n" + i18n.T("templates.import_warnings"))
+		for _, warning := range result.Warnings {
+			fmt.Printf("  ! %s\n", warning)
+		}
+	}
+	return nil
+}
+
+func newTemplatesPushCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "push <template[@version]|dir> <registry/repository[:tag]>",
+		Short: "Push a template to an OCI registry",
+		Long: `Push a template to a container registry (ghcr.io, ECR, Harbor, ...) as an
+OCI artifact, for teams that distribute versioned templates through their
+registry rather than git. The template is a name from the templates directory
+or the directory of a template, such as . in the repository of one. The tag
+defaults to the version of the template; older versions are not pushed with it,
+push each of them.
+
+The credentials are those of docker login or podman login: their config files
+(~/.docker/config.json, $REGISTRY_AUTH_FILE, containers/auth.json) and the
+credential helpers they name, such as ecr-login or desktop, and otherwise the
+token of devinit templates login. Registries on localhost are spoken to over
+plain HTTP.
+
+Example:
+  devinit templates push python/fastapi ghcr.io/acme/templates/python-fastapi:1.2.0
+  devinit templates push . ghcr.io/acme/templates/python-fastapi
+  devinit templates pull ghcr.io/acme/templates/python-fastapi:1.2.0`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tmpl, err := loadPushTemplate(args[0])
+			if err != nil {
+				return err
+			}
+			ref, err := oci.ParseReference(args[1])
+			if err != nil {
+				return usageError(err)
+			}
+			if ref.Digest != "" {
+				return usageError(i18n.Errorf("templates.push_digest", ref))
+			}
+			if ref.Tag == "" {
+				ref.Tag = tmpl.Version
+			}
+
+			// Versions are pushed one tag each
+			bundle, err := archive.Pack(tmpl.Path, func(rel string) bool { return rel == "versions" })
+			if err != nil {
+				return i18n.Errorf("templates.push_failed", err)
+			}
+			client, err := httpClient()
+			if err != nil {
+				return err
+			}
+			digest, err := oci.NewClient(client).Push(context.Background(), ref, oci.Config{
+				Name:        tmpl.Name,
+				Language:    tmpl.Language,
+				Framework:   tmpl.Framework,
+				Version:     tmpl.Version,
+				Description: tmpl.Description,
+			}, bundle)
+			if err != nil {
+				return i18n.Errorf("templates.push_failed", err)
+			}
+
+			fmt.Println(i18n.T("templates.pushed", tmpl.Language+"/"+tmpl.Framework, tmpl.Version, ref, digest))
+			return nil
+		},
+	}
+}
+
+// loadPushTemplate loads the template to push: a directory with a
+// template.yaml, or a template of the templates directory
+func loadPushTemplate(arg string) (*template.Template, error) {
+	if _, err := os.Stat(filepath.Join(arg, "template.yaml")); err == nil {
+		dir, err := filepath.Abs(arg)
+		if err != nil {
+			return nil, err
+		}
+		return template.NewLoader(filepath.Dir(dir)).Load(filepath.Base(dir))
+	}
+	return loadTemplateRef(getGenerator(), arg)
+}
+
+func newTemplatesPullCmd() *cobra.Command {
+	var (
+		language  string
+		framework string
+		force     bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "pull <registry/repository[:tag|@digest]>",
+		Short: "Pull a template from an OCI registry",
+		Long: `Pull a template pushed with devinit templates push into the templates
+directory, as <language>/<framework> of the template unless --lang or
+--framework say otherwise. The template records the digest of what was pulled
+as its origin, which the devinit.lock of projects generated from it pins.
+Credentials are found as for push.
+
+Example:
+  devinit templates pull ghcr.io/acme/templates/python-fastapi:1.2.0
+  devinit new my-api --lang python --framework fastapi`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ref, err := oci.ParseReference(args[0])
+			if err != nil {
+				return usageError(err)
+			}
+			return importTemplate(importer.Options{
+				Source:    oci.Prefix + ref.String(),
+				Format:    importer.FormatDevinit,
+				Language:  language,
+				Framework: framework,
+				Force:     force,
+			})
+		},
+	}
+
+	cmd.Flags().StringVar(&language, "lang", "", "language of the pulled template (default: its own)")
+	cmd.Flags().StringVar(&framework, "framework", "", "framework of the pulled template (default: its own)")
+	cmd.Flags().BoolVar(&force, "force", false, "replace an existing template")
+
+	return cmd
+}
+
+// downloadTimeout bounds each request downloading or uploading a template
+const downloadTimeout = 5 * time.Minute
+
+// httpClient returns the client downloading templates, with the network
+// settings of the config
+func httpClient() (*http.Client, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	return cfg.Network.Settings().Client(downloadTimeout)
+}
+
+// splitTemplateRef splits a template reference like python/fastapi@1.4.0
+// (or a framework like fastapi@v1.4.0) into the name and the version, empty
+// when the reference selects the current version
+func splitTemplateRef(ref string) (name, version string) {
+	name, version, _ = strings.Cut(ref, "@")
+	return name, version
+}
+
+// loadTemplateRef loads the template and version of a reference like
+// python/fastapi@1.4.0
+func loadTemplateRef(gen *generator.Generator, ref string) (*template.Template, error) {
+	name, version := splitTemplateRef(ref)
+	return gen.GetTemplateVersion(name, version)
+}
+
+// archiveVersion matches the version ending the name of an archive
+var archiveVersion = regexp.MustCompile(`[-_]v?\d+(\.\d+)*([-+.][0-9A-Za-z.-]*)?$`)
+
+// importedName derives a template name from an import source, e.g. fastapi
+// for gh:acme/cookiecutter-fastapi.git,
+// github.com/acme/platform//templates/python/fastapi?ref=v3 or
+// https://artifacts.acme.dev/fastapi-3.1.0.tar.gz
+func importedName(source string) string {
+	if importer.IsRemote(source) {
+		source, _, _ = strings.Cut(source, "?")
+	}
+	name := strings.TrimSuffix(strings.TrimRight(filepath.ToSlash(source), "/"), ".git")
+	if i := strings.LastIndexAny(name, "/:"); i >= 0 {
+		name = name[i+1:]
+	}
+	if importer.IsArchive(source) {
+		name = strings.TrimSuffix(strings.TrimSuffix(name, ".tgz"), ".tar.gz")
+		name = archiveVersion.ReplaceAllString(name, "")
+	}
+	for _, prefix := range []string{"cookiecutter-", "cookiecutter_", "copier-", "copier_"} {
+		name = strings.TrimPrefix(name, prefix)
+	}
+	return name
+}
+
+// templateTestReport is the JSON output of templates test
+type templateTestReport struct {
+	Cases    []harness.CaseResult `json:"cases"`
+	Coverage []templateCoverage   `json:"coverage,omitempty"`
+}
+
+// templateCoverage is the condition and branch coverage of a template
+type templateCoverage struct {
+	Template  string                 `json:"template"`
+	Exercised int                    `json:"exercised"`
+	Total     int                    `json:"total"`
+	Items     []harness.CoverageItem `json:"items"`
+}
+
+func newTemplatesTestCmd() *cobra.Command {
+	var (
+		opts       harness.Options
+		showOutput bool
+		coverage   bool
+		output     string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "test [template...]",
+		Short: "Generate projects from template test fixtures",
+		Long: `Generate a project for every test case of the given templates (all
+templates by default) and report the cases that fail. Test cases are declared
+under tests: in template.yaml; templates without them are tested with their
+defaults.
+
+--container also verifies each project with Docker: it runs the hooks and the
+smoke test, builds the generated Dockerfile, starts the services with docker
+compose (or the built image) and waits for the healthcheck.
+
+--coverage reports the file conditions that the cases never saw true or
+never saw false, and the {{ if }}, {{ with }} and {{ range }} actions of
+template files that never ran one way or the other, so template authors know
+which variable combinations lack a test case.
+
+--jobs runs several cases at a time. Results are still reported in order;
+with --show-output, every line of hook and docker output is prefixed with
+its template and case.
+
+Exits with 6 when a test case fails.
+
+Examples:
+  devinit templates test
+  devinit templates test python/fastapi --coverage
+  devinit templates test python/fastapi --container
+  devinit templates test --container --jobs 4`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateOutputFormat(output); err != nil {
+				return err
+			}
+			if showOutput {
+				opts.Log = os.Stderr
+			}
+
+			gen := getGenerator()
+			names := args
+			if len(names) == 0 {
+				var err error
+				if names, err = gen.ListTemplates(); err != nil {
+					return err
+				}
+			}
+
+			if output == outputText {
+				fmt.Println(i18n.T("templates.testing"))
+			}
+			report := templateTestReport{Cases: []harness.CaseResult{}}
+			failed := 0
+			// Cases of every template are queued together; results and
+			// coverage are printed in order as they complete
+			var jobs []harness.Job
+			for _, name := range names {
+				tmpl, err := gen.GetTemplate(name)
+				if err != nil {
+					if len(args) > 0 {
+						return err
+					}
+					report.Cases = append(report.Cases, harness.CaseResult{Template: name, Steps: []harness.StepResult{{Step: harness.StepGenerate, Error: err.Error()}}})
+					if output == outputText {
+						fmt.Printf("  ✗ %s: %v\n", name, err)
+					}
+					failed++
+					continue
+				}
+
+				var cov *harness.Coverage
+				if coverage {
+					if cov, err = harness.NewCoverage(tmpl); err != nil {
+						return err
+					}
+				}
+				for _, tc := range harness.Cases(tmpl) {
+					jobs = append(jobs, harness.Job{Template: tmpl, Case: tc, Coverage: cov})
+				}
+			}
+
+			harness.RunJobs(gen, jobs, opts, func(i int, result harness.CaseResult) {
+				report.Cases = append(report.Cases, result)
+				if !result.Passed {
+					failed++
+				}
+				if output == outputText {
+					printCaseResult(result, opts.Container)
+				}
+
+				// The last case of a template completes its coverage
+				cov := jobs[i].Coverage
+				if cov == nil || (i+1 < len(jobs) && jobs[i+1].Template == jobs[i].Template) {
+					return
+				}
+				exercised, total := cov.Summary()
+				report.Coverage = append(report.Coverage, templateCoverage{Template: jobs[i].Template.ID, Exercised: exercised, Total: total, Items: cov.Items()})
+				if output == outputText {
+					printCoverage(jobs[i].Template.ID, cov)
+				}
+			})
+
+			var err error
+			if failed > 0 {
+				err = errcode.New(errcode.TemplateInvalid, i18n.Errorf("templates.tests_failed", failed, len(report.Cases)))
+			}
+			if output == outputJSON {
+				if jsonErr := printJSON(report); jsonErr != nil {
+					return jsonErr
+				}
+				if err != nil {
+					return reportedError{err}
+				}
+				return nil
+			}
+
+			if err == nil {
+				fmt.Println("\n" + i18n.T("templates.tests_passed", len(report.Cases)))
+			}
+			return err
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.Container, "container", false, "also build, start and healthcheck each project with Docker and run its smoke test")
+	cmd.Flags().DurationVar(&opts.StartTimeout, "start-timeout", harness.DefaultStartTimeout, "how long started services may take to pass the healthcheck")
+	cmd.Flags().BoolVar(&showOutput, "show-output", false, "stream hook and docker output to stderr")
+	cmd.Flags().BoolVar(&coverage, "coverage", false, "report conditions and template branches the test cases do not exercise both ways")
+	cmd.Flags().IntVarP(&opts.Jobs, "jobs", "j", 1, "number of test cases to run at a time")
+	cmd.Flags().StringVarP(&output, "output", "o", outputText, "output format (text, json)")
+
+	return cmd
+}
+
+// printCoverage prints the coverage of a template and the items the test
+// cases did not exercise both ways
+func printCoverage(name string, coverage *harness.Coverage) {
+	exercised, total := coverage.Summary()
+	fmt.Println("  " + i18n.T("templates.coverage", name, exercised, total))
+	for _, item := range coverage.Untested() {
+		location := item.File
+		if item.Line > 0 {
+			location = fmt.Sprintf("%s:%d", item.File, item.Line)
+		}
+
+		var missing string
+		switch {
+		case item.Kind == harness.CoverageCondition && item.True+item.False == 0:
+			missing = i18n.T("templates.coverage_condition_unused")
+		case item.Kind == harness.CoverageCondition && item.True == 0:
+			missing = i18n.T("templates.coverage_never_true")
+		case item.Kind == harness.CoverageCondition:
+			missing = i18n.T("templates.coverage_never_false")
+		case item.True+item.False == 0:
+			missing = i18n.T("templates.coverage_branch_unused")
+		case item.True == 0:
+			missing = i18n.T("templates.coverage_never_taken")
+		default:
+			missing = i18n.T("templates.coverage_never_skipped")
+		}
+		fmt.Printf("    ! %s: %s (%s)\n", location, item.Text, missing)
+	}
+}
+
+// printCaseResult prints a test case, with its steps when verbose or failed
+// and the output of the failed one
+func printCaseResult(result harness.CaseResult, verbose bool) {
+	failed := result.Failed()
+	if failed == nil {
+		fmt.Printf("  ✓ %s [%s] %s\n", result.Template, result.Case, i18n.T("templates.test_files", result.Files))
+	} else {
+		fmt.Printf("  ✗ %s [%s]\n", result.Template, result.Case)
+	}
+	if verbose || failed != nil {
+		for _, step := range result.Steps {
+			if step.Passed {
+				fmt.Printf("      ✓ %s (%s)\n", step.Step, step.Duration.Round(time.Millisecond))
+				continue
+			}
+			fmt.Printf("      ✗ %s: %s\n", step.Step, step.Error)
+			for _, line := range step.Output {
+				fmt.Printf("          %s\n", line)
+			}
+		}
+	}
+}
+
+func newTemplatesDiffCmd() *cobra.Command {
+	var (
+		from        string
+		to          string
+		projectName string
+		vars        []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "diff [template]",
+		Short: "Show how the rendered output changes between two template versions",
+		Long: `Render two versions of a template with identical variables and print a
+unified diff of the generated files.
+
+Examples:
+  devinit templates diff python/fastapi --from 1.0.0 --to 1.1.0
+  devinit templates diff python/fastapi --from 1.0.0 --to 1.1.0 --var Database=postgres`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			variables, err := parseVarFlags(vars)
+			if err != nil {
+				return err
+			}
+			variables["ProjectName"] = projectName
+
+			gen := getGenerator()
+
+			fromTmpl, err := gen.GetTemplateVersion(args[0], from)
+			if err != nil {
+				return err
+			}
+			toTmpl, err := gen.GetTemplateVersion(args[0], to)
+			if err != nil {
+				return err
+			}
+
+			fromFiles, err := gen.RenderFiles(fromTmpl, projectName, variables)
+			if err != nil {
+				return i18n.Errorf("templates.render_failed", args[0], fromTmpl.Version, err)
+			}
+			toFiles, err := gen.RenderFiles(toTmpl, projectName, variables)
+			if err != nil {
+				return i18n.Errorf("templates.render_failed", args[0], toTmpl.Version, err)
+			}
+
+			paths := make(map[string]bool)
+			for path := range fromFiles {
+				paths[path] = true
+			}
+			for path := range toFiles {
+				paths[path] = true
+			}
+			sorted := make([]string, 0, len(paths))
+			for path := range paths {
+				sorted = append(sorted, path)
+			}
+			sort.Strings(sorted)
+
+			changed := 0
+			for _, path := range sorted {
+				fromName, toName := "a/"+path, "b/"+path
+				if _, ok := fromFiles[path]; !ok {
+					fromName = "/dev/null"
+				}
+				if _, ok := toFiles[path]; !ok {
+					toName = "/dev/null"
+				}
+
+				out := diff.Unified(fromName, toName, string(fromFiles[path]), string(toFiles[path]), 3)
+				if out != "" {
+					fmt.Print(out)
+					changed++
+				}
+			}
+
+			if changed == 0 {
+				fmt.Fprintln(os.Stderr, i18n.T("templates.no_differences", args[0], fromTmpl.Version, args[0], toTmpl.Version))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&from, "from", "", "base template version (default: current)")
+	cmd.Flags().StringVar(&to, "to", "", "target template version (default: current)")
+	cmd.Flags().StringVar(&projectName, "name", "example", "project name used for rendering")
+	cmd.Flags().StringArrayVar(&vars, "var", nil, "template variable as KEY=VALUE (repeatable)")
+
+	return cmd
+}
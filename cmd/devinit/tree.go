@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/renan-dev/devinit/internal/generator"
+	"github.com/renan-dev/devinit/internal/i18n"
+)
+
+// fileTree is a directory of the planned project, or a file when it has
+// results
+type fileTree struct {
+	name     string
+	children map[string]*fileTree
+	results  []generator.FileResult
+}
+
+// buildFileTree arranges the files of a generation by directory, relative
+// to its output directory
+func buildFileTree(result *generator.GenerationResult) *fileTree {
+	root := &fileTree{name: result.OutputDir, children: map[string]*fileTree{}}
+	for _, f := range result.Files {
+		rel, err := filepath.Rel(result.OutputDir, f.Path)
+		if err != nil {
+			rel = f.Path
+		}
+
+		node := root
+		for _, part := range strings.Split(filepath.ToSlash(rel), "/") {
+			child := node.children[part]
+			if child == nil {
+				child = &fileTree{name: part, children: map[string]*fileTree{}}
+				node.children[part] = child
+			}
+			node = child
+		}
+		node.results = append(node.results, f)
+	}
+	return root
+}
+
+// printFileTree prints the files of a dry run as a tree, like tree(1),
+// annotating conditional files with the conditions that included or
+// excluded them
+func printFileTree(w io.Writer, result *generator.GenerationResult) {
+	root := buildFileTree(result)
+	fmt.Fprintln(w, filepath.ToSlash(root.name)+"/")
+	printFileTreeChildren(w, root, "")
+}
+
+func printFileTreeChildren(w io.Writer, dir *fileTree, prefix string) {
+	names := make([]string, 0, len(dir.children))
+	for name := range dir.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for i, name := range names {
+		node := dir.children[name]
+		branch, indent := "├── ", "│   "
+		if i == len(names)-1 {
+			branch, indent = "└── ", "    "
+		}
+
+		if len(node.children) > 0 {
+			fmt.Fprintln(w, prefix+branch+name+"/")
+			printFileTreeChildren(w, node, prefix+indent)
+			continue
+		}
+		line := prefix + branch + name
+		if notes := fileTreeNotes(node.results); notes != "" {
+			line += "  " + notes
+		}
+		fmt.Fprintln(w, line)
+	}
+}
+
+// fileTreeNotes describes how the file specs writing a file were applied:
+// skipped ones with the conditions not met, conditional ones with their
+// conditions and those combined with the file with their write mode
+func fileTreeNotes(results []generator.FileResult) string {
+	var notes []string
+	for _, f := range results {
+		switch {
+		case f.Action == generator.FileActionSkipped:
+			notes = append(notes, i18n.T("tree.skipped", strings.Join(f.Conditions, ", ")))
+		case len(f.Conditions) > 0:
+			notes = append(notes, i18n.T("tree.included", strings.Join(f.Conditions, ", ")))
+		}
+		if f.Mode != "" && f.Action != generator.FileActionSkipped {
+			notes = append(notes, string(f.Mode))
+		}
+		if f.Override {
+			notes = append(notes, i18n.T("tree.override"))
+		}
+	}
+	if len(notes) == 0 {
+		return ""
+	}
+	return "[" + strings.Join(notes, "; ") + "]"
+}
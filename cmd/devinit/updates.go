@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/renan-dev/devinit/internal/config"
+	"github.com/renan-dev/devinit/internal/errcode"
+	"github.com/renan-dev/devinit/internal/i18n"
+	"github.com/renan-dev/devinit/internal/importer"
+	"github.com/renan-dev/devinit/internal/paths"
+	"github.com/renan-dev/devinit/internal/template"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// updateCheckInterval is how often templates list and new check
+	// imported templates for updates
+	updateCheckInterval = 24 * time.Hour
+
+	// updateCheckTimeout bounds the check, which commands wait for before
+	// they exit
+	updateCheckTimeout = 5 * time.Second
+)
+
+// offline skips everything optional that needs the network
+var offline bool
+
+func newTemplatesUpdateCmd() *cobra.Command {
+	var check bool
+
+	cmd := &cobra.Command{
+		Use:   "update [template...]",
+		Short: "Update imported templates from their sources",
+		Long: `Import templates again from the git repository, registry or archive they were
+imported from, when their source has changed: the commit of the branch or tag
+imported, the newest version tag for templates imported with --versions, or the
+digest of an OCI tag. Templates pinned to a commit, digest or checksum do not
+change. Without arguments every imported template is checked.
+
+Once a day templates list and new check imported templates in the background
+and tell when some have updates. Set updates.disabled in the config file, or
+pass --offline, to skip the check.
+
+Example:
+  devinit templates update --check
+  devinit templates update python/fastapi`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			templates, err := importedTemplates(args)
+			if err != nil {
+				return err
+			}
+			if len(templates) == 0 {
+				fmt.Println(i18n.T("templates.update_none"))
+				return nil
+			}
+
+			client, err := httpClient()
+			if err != nil {
+				return err
+			}
+			opts := importer.Options{HTTPClient: client, GitCredentialHelper: gitCredentialHelper()}
+			var failed int
+			for _, tmpl := range templates {
+				name := tmpl.ID
+				update, err := importer.CheckUpdate(context.Background(), tmpl, opts)
+				switch {
+				case err != nil:
+					failed++
+					fmt.Fprintln(os.Stderr, i18n.T("templates.update_failed", name, err))
+					continue
+				case update == nil:
+					fmt.Println(i18n.T("templates.up_to_date", name))
+					continue
+				}
+
+				fmt.Println(i18n.T("templates.update_available", name, update.Current, update.Latest))
+				if check {
+					continue
+				}
+				_, err = os.Stat(filepath.Join(tmpl.Path, "versions"))
+				if err := importTemplate(importer.Options{
+					Source:    tmpl.Origin.Source,
+					Dir:       tmpl.Path,
+					Language:  tmpl.Language,
+					Framework: tmpl.Framework,
+					Versions:  err == nil,
+					Force:     true,
+				}); err != nil {
+					return err
+				}
+			}
+			if failed > 0 {
+				return errcode.New(errcode.TemplateInvalid, i18n.Errorf("templates.update_incomplete", failed))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&check, "check", false, "only list the templates that have updates")
+
+	return cmd
+}
+
+// importedTemplates loads the named templates, or every imported template
+// of the templates directory. Named templates must have been imported.
+func importedTemplates(names []string) ([]*template.Template, error) {
+	loader := template.NewLoader(getTemplatesDir())
+	explicit := len(names) > 0
+	if !explicit {
+		var err error
+		if names, err = loader.List(); err != nil {
+			return nil, err
+		}
+	}
+
+	var templates []*template.Template
+	for _, name := range names {
+		tmpl, err := loader.Load(name)
+		if err != nil {
+			if explicit {
+				return nil, errcode.New(errcode.TemplateNotFound, err)
+			}
+			continue
+		}
+		if tmpl.Origin == nil {
+			if explicit {
+				return nil, usageError(i18n.Errorf("templates.update_not_imported", name))
+			}
+			continue
+		}
+		templates = append(templates, tmpl)
+	}
+	return templates, nil
+}
+
+// updateCheck records when imported templates were last checked for
+// updates
+type updateCheck struct {
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// updateCheckFile returns the file recording the last update check
+func updateCheckFile() (string, error) {
+	dir, err := paths.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "update-check.json"), nil
+}
+
+// startUpdateCheck checks the imported templates for updates in the
+// background, unless they were checked in the last day, and returns the
+// function to call before the command exits, which waits for the check and
+// tells how many templates have updates. The check is skipped offline, in
+// CI and when the config disables it.
+func startUpdateCheck() (wait func()) {
+	wait = func() {}
+	if offline || os.Getenv("CI") != "" {
+		return wait
+	}
+	cfg, err := config.Load()
+	if err != nil || cfg.Updates.Disabled {
+		return wait
+	}
+	file, err := updateCheckFile()
+	if err != nil {
+		return wait
+	}
+	var last updateCheck
+	if data, err := os.ReadFile(file); err == nil && json.Unmarshal(data, &last) == nil && time.Since(last.CheckedAt) < updateCheckInterval {
+		return wait
+	}
+	templates, err := importedTemplates(nil)
+	if err != nil || len(templates) == 0 {
+		return wait
+	}
+
+	// The check is recorded when it starts, so an unreachable source is
+	// not waited for on every run
+	data, _ := json.Marshal(updateCheck{CheckedAt: time.Now()})
+	if os.MkdirAll(filepath.Dir(file), 0755) != nil || os.WriteFile(file, data, 0644) != nil {
+		return wait
+	}
+	client, err := cfg.Network.Settings().Client(updateCheckTimeout)
+	if err != nil {
+		return wait
+	}
+	opts := importer.Options{HTTPClient: client, GitCredentialHelper: gitCredentialHelper()}
+
+	ctx, cancel := context.WithTimeout(context.Background(), updateCheckTimeout)
+	found := make(chan int, 1)
+	go func() {
+		results := make(chan bool, len(templates))
+		for _, tmpl := range templates {
+			go func() {
+				update, err := importer.CheckUpdate(ctx, tmpl, opts)
+				results <- err == nil && update != nil
+			}()
+		}
+		var n int
+		for range templates {
+			if <-results {
+				n++
+			}
+		}
+		found <- n
+	}()
+
+	return func() {
+		defer cancel()
+		select {
+		case n := <-found:
+			switch {
+			case n == 1:
+				fmt.Fprintln(os.Stderr, "\n"+i18n.T("templates.update_notice_one"))
+			case n > 1:
+				fmt.Fprintln(os.Stderr, "\n"+i18n.T("templates.update_notice", n))
+			}
+		case <-ctx.Done():
+		}
+	}
+}
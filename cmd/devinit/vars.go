@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/renan-dev/devinit/internal/i18n"
+)
+
+// Prefixes of --var values taken from elsewhere
+const (
+	varFromEnv  = "env:"  // the value of an environment variable
+	varFromFile = "file:" // the content of a file
+	varRaw      = "raw:"  // the rest as is, for values starting with a prefix
+)
+
+// parseVarFlags parses repeated --var KEY=VALUE flags into template variables.
+// "true" and "false" on the command line become booleans; everything else
+// stays a string. Values can come from an environment variable (KEY=env:NAME)
+// or a file (KEY=file:path, without its final line break), and stay strings
+// like raw ones: what a variable holds does not depend on where it is read.
+func parseVarFlags(vars []string) (map[string]interface{}, error) {
+	variables := make(map[string]interface{})
+
+	for _, v := range vars {
+		key, value, ok := strings.Cut(v, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, usageError(i18n.Errorf("new.invalid_var", v))
+		}
+
+		switch {
+		case strings.HasPrefix(value, varRaw):
+			variables[key] = strings.TrimPrefix(value, varRaw)
+		case strings.HasPrefix(value, varFromEnv):
+			name := strings.TrimPrefix(value, varFromEnv)
+			env, ok := os.LookupEnv(name)
+			if !ok {
+				return nil, usageError(i18n.Errorf("new.var_env_unset", key, name))
+			}
+			variables[key] = env
+		case strings.HasPrefix(value, varFromFile):
+			data, err := os.ReadFile(strings.TrimPrefix(value, varFromFile))
+			if err != nil {
+				return nil, usageError(i18n.Errorf("new.var_file_failed", key, err))
+			}
+			variables[key] = strings.TrimSuffix(strings.TrimSuffix(string(data), "\n"), "\r")
+		case value == "true":
+			variables[key] = true
+		case value == "false":
+			variables[key] = false
+		default:
+			variables[key] = value
+		}
+	}
+
+	return variables, nil
+}
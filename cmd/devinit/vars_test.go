@@ -0,0 +1,103 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/renan-dev/devinit/internal/errcode"
+)
+
+func TestParseVarFlags(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{"key.pub": "ssh-ed25519 AAAA\r\n", "flag": "false\n", "two-lines": "a\nb\n\n"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("DEVINIT_TEST_REGISTRY", "ghcr.io/acme")
+	t.Setenv("DEVINIT_TEST_FLAG", "true")
+	t.Setenv("DEVINIT_TEST_EMPTY", "")
+
+	tests := []struct {
+		name    string
+		vars    []string
+		want    map[string]interface{}
+		wantErr bool
+	}{
+		{
+			name: "plain values",
+			vars: []string{"name=demo", " spaced =x", "empty="},
+			want: map[string]interface{}{"name": "demo", "spaced": "x", "empty": ""},
+		},
+		{
+			name: "booleans on the command line",
+			vars: []string{"docker=true", "kafka=false", "upper=True"},
+			want: map[string]interface{}{"docker": true, "kafka": false, "upper": "True"},
+		},
+		{
+			name: "environment",
+			vars: []string{"registry=env:DEVINIT_TEST_REGISTRY", "empty=env:DEVINIT_TEST_EMPTY"},
+			want: map[string]interface{}{"registry": "ghcr.io/acme", "empty": ""},
+		},
+		{
+			name: "environment values stay strings",
+			vars: []string{"docker=env:DEVINIT_TEST_FLAG"},
+			want: map[string]interface{}{"docker": "true"},
+		},
+		{
+			name:    "unset environment variable",
+			vars:    []string{"registry=env:DEVINIT_TEST_UNSET"},
+			wantErr: true,
+		},
+		{
+			name: "file without its final line break",
+			vars: []string{"key=file:" + filepath.Join(dir, "key.pub"), "lines=file:" + filepath.Join(dir, "two-lines")},
+			want: map[string]interface{}{"key": "ssh-ed25519 AAAA", "lines": "a\nb\n"},
+		},
+		{
+			name: "file values stay strings",
+			vars: []string{"kafka=file:" + filepath.Join(dir, "flag")},
+			want: map[string]interface{}{"kafka": "false"},
+		},
+		{
+			name:    "missing file",
+			vars:    []string{"key=file:" + filepath.Join(dir, "missing")},
+			wantErr: true,
+		},
+		{
+			name: "raw",
+			vars: []string{"url=raw:file:///srv/docs", "env=raw:env:prod", "flag=raw:true"},
+			want: map[string]interface{}{"url": "file:///srv/docs", "env": "env:prod", "flag": "true"},
+		},
+		{
+			name:    "missing key",
+			vars:    []string{"=value"},
+			wantErr: true,
+		},
+		{
+			name:    "missing value",
+			vars:    []string{"name"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseVarFlags(tt.vars)
+			if tt.wantErr {
+				if errcode.Of(err) != errcode.Usage {
+					t.Fatalf("parseVarFlags() error = %v, want a usage error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseVarFlags() unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseVarFlags() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
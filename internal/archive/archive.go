@@ -0,0 +1,171 @@
+// Package archive packs templates into gzip-compressed tar archives, the
+// bundles devinit distributes them in, and unpacks such archives safely.
+//
+// Packing is reproducible: entries are sorted and carry neither times nor
+// owners, so the same files always give the same bytes and digest.
+// Unpacking keeps entries inside the destination and skips links and
+// devices.
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// MaxSize is the most Unpack writes, against archives that expand without
+// end
+const MaxSize = 1 << 30
+
+// Pack returns a gzip-compressed tar archive of the files below dir. skip,
+// when set, leaves out the files and directories (slash-separated, relative
+// to dir) it returns true for; .git directories are always left out.
+func Pack(dir string, skip func(rel string) bool) ([]byte, error) {
+	var b bytes.Buffer
+	gz := gzip.NewWriter(&b)
+	tw := tar.NewWriter(gz)
+
+	// WalkDir visits entries in lexical order
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if d.Name() == ".git" || (skip != nil && skip(rel)) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		header := &tar.Header{Name: rel, Mode: int64(info.Mode().Perm()), Format: tar.FormatPAX}
+		switch {
+		case d.IsDir():
+			header.Typeflag, header.Name, header.Mode = tar.TypeDir, rel+"/", 0755
+			return tw.WriteHeader(header)
+		case !d.Type().IsRegular():
+			return fmt.Errorf("%s is not a regular file", rel)
+		}
+
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		header.Typeflag, header.Size = tar.TypeReg, int64(len(data))
+		if header.Mode&0111 != 0 {
+			header.Mode = 0755
+		} else {
+			header.Mode = 0644
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		_, err = tw.Write(data)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// Unpack extracts the gzip-compressed tar archive read from r into dest,
+// which is created if needed. strip removes that many leading directories
+// from the names of the entries, as tar --strip-components does.
+func Unpack(r io.Reader, dest string, strip int) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("not a gzip-compressed archive: %w", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return err
+	}
+	var written int64
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid archive: %w", err)
+		}
+
+		name := entryName(header.Name, strip)
+		if name == "" {
+			continue
+		}
+		target := filepath.Join(dest, filepath.FromSlash(name))
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if written += header.Size; written > MaxSize {
+				return fmt.Errorf("archive expands to more than %d bytes", MaxSize)
+			}
+			perm := fs.FileMode(0644)
+			if header.Mode&0111 != 0 {
+				perm = 0755
+			}
+			if err := writeFile(target, tr, perm); err != nil {
+				return err
+			}
+		}
+		// Links, devices and the like have no place in a template
+	}
+}
+
+// entryName returns the name of an archive entry without its strip leading
+// directories. Names are cleaned as if rooted, so ../ cannot leave dest.
+func entryName(name string, strip int) string {
+	name = strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(name, `\`, "/")), "/")
+	parts := strings.Split(name, "/")
+	if len(parts) <= strip {
+		return ""
+	}
+	return path.Join(parts[strip:]...)
+}
+
+// writeFile writes the content of r to path
+func writeFile(path string, r io.Reader, perm fs.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,100 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPackUnpack(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"template.yaml":        "version: 1.0.0\n",
+		"files/main.go.tmpl":   "package main\n",
+		"files/scripts/run.sh": "#!/bin/sh\n",
+		"versions/0.9.0/x":     "old\n",
+		".git/HEAD":            "ref: refs/heads/main\n",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Chmod(filepath.Join(dir, "files", "scripts", "run.sh"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	skip := func(rel string) bool { return rel == "versions" }
+	packed, err := Pack(dir, skip)
+	if err != nil {
+		t.Fatalf("Pack() unexpected error: %v", err)
+	}
+
+	// The same files give the same archive, whenever they were written
+	later := time.Now().Add(time.Hour)
+	os.Chtimes(filepath.Join(dir, "template.yaml"), later, later)
+	if again, err := Pack(dir, skip); err != nil || !bytes.Equal(again, packed) {
+		t.Errorf("Pack() is not reproducible: %v", err)
+	}
+
+	dest := t.TempDir()
+	if err := Unpack(bytes.NewReader(packed), dest, 0); err != nil {
+		t.Fatalf("Unpack() unexpected error: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dest, "files", "main.go.tmpl")); err != nil || string(data) != "package main\n" {
+		t.Errorf("files/main.go.tmpl = %q, %v", data, err)
+	}
+	if info, err := os.Stat(filepath.Join(dest, "files", "scripts", "run.sh")); err != nil || info.Mode().Perm() != 0755 {
+		t.Errorf("run.sh mode = %v, %v, want executable", info, err)
+	}
+	for _, name := range []string{"versions", ".git"} {
+		if _, err := os.Stat(filepath.Join(dest, name)); !os.IsNotExist(err) {
+			t.Errorf("%s unpacked, want it left out", name)
+		}
+	}
+}
+
+func TestUnpackEntries(t *testing.T) {
+	var b bytes.Buffer
+	gz := gzip.NewWriter(&b)
+	tw := tar.NewWriter(gz)
+	for _, header := range []*tar.Header{
+		{Name: "api-1.0.0/template.yaml", Typeflag: tar.TypeReg, Size: 1, Mode: 0644},
+		{Name: "api-1.0.0/../../escape", Typeflag: tar.TypeReg, Size: 1, Mode: 0644},
+		{Name: "api-1.0.0/link", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"},
+	} {
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if header.Size > 0 {
+			tw.Write([]byte("x"))
+		}
+	}
+	tw.Close()
+	gz.Close()
+
+	dest := filepath.Join(t.TempDir(), "out")
+	if err := Unpack(bytes.NewReader(b.Bytes()), dest, 1); err != nil {
+		t.Fatalf("Unpack() unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "template.yaml")); err != nil {
+		t.Errorf("template.yaml not unpacked below the stripped directory: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(dest), "escape")); !os.IsNotExist(err) {
+		t.Errorf("entry written outside the destination")
+	}
+	if _, err := os.Lstat(filepath.Join(dest, "link")); !os.IsNotExist(err) {
+		t.Errorf("symbolic link unpacked, want it skipped")
+	}
+
+	if err := Unpack(bytes.NewReader([]byte("not gzip")), t.TempDir(), 0); err == nil {
+		t.Errorf("Unpack() of a non-archive succeeded")
+	}
+}
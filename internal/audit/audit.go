@@ -0,0 +1,113 @@
+// Package audit records the projects devinit generates in an append-only
+// log (audit.log in the devinit state directory, ~/.local/state/devinit by
+// default), one JSON entry per line.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/renan-dev/devinit/internal/paths"
+)
+
+// EnvLogPath overrides the location of the audit log
+const EnvLogPath = "DEVINIT_AUDIT_LOG"
+
+// Entry describes one generated project
+type Entry struct {
+	Time            time.Time `json:"time"`
+	Project         string    `json:"project"`
+	Dir             string    `json:"dir"`
+	Template        string    `json:"template"`
+	TemplateVersion string    `json:"template_version"`
+	Port            int       `json:"port,omitempty"`
+}
+
+// DefaultPath returns the audit log location, honoring DEVINIT_AUDIT_LOG.
+// A log kept in the config directory by earlier versions is still used.
+func DefaultPath() (string, error) {
+	if path := os.Getenv(EnvLogPath); path != "" {
+		return path, nil
+	}
+
+	state, err := paths.StateDir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(state, "audit.log")
+
+	if config, err := paths.ConfigDir(); err == nil {
+		path = paths.Migrated(path, filepath.Join(config, "audit.log"))
+	}
+	return path, nil
+}
+
+// Append adds an entry to the log at path, creating it if needed
+func Append(path string, entry Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
+
+// Load reads all entries of the log at path. A missing log yields no entries;
+// lines that cannot be parsed are skipped.
+func Load(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	return entries, nil
+}
+
+// ReservedPorts returns the ports recorded for projects that still exist on disk
+func ReservedPorts(entries []Entry) map[int]bool {
+	ports := make(map[int]bool)
+	for _, entry := range entries {
+		if entry.Port == 0 {
+			continue
+		}
+		if _, err := os.Stat(entry.Dir); err != nil {
+			continue
+		}
+		ports[entry.Port] = true
+	}
+	return ports
+}
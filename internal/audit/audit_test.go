@@ -0,0 +1,55 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAppendLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "audit.log")
+
+	entries, err := Load(path)
+	if err != nil || entries != nil {
+		t.Fatalf("Load() of missing log = %v, %v; want nil, nil", entries, err)
+	}
+
+	first := Entry{Time: time.Unix(0, 0).UTC(), Project: "a", Dir: "/tmp/a", Template: "python/fastapi", Port: 8000}
+	second := Entry{Time: time.Unix(60, 0).UTC(), Project: "b", Dir: "/tmp/b", Template: "nodejs/express"}
+	for _, e := range []Entry{first, second} {
+		if err := Append(path, e); err != nil {
+			t.Fatalf("Append() unexpected error: %v", err)
+		}
+	}
+
+	// Corrupt lines are skipped
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("not json\n")
+	f.Close()
+
+	entries, err = Load(path)
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+	if len(entries) != 2 || entries[0] != first || entries[1] != second {
+		t.Errorf("Load() = %+v, want %+v", entries, []Entry{first, second})
+	}
+}
+
+func TestReservedPorts(t *testing.T) {
+	existing := t.TempDir()
+	entries := []Entry{
+		{Dir: existing, Port: 8000},
+		{Dir: filepath.Join(existing, "deleted"), Port: 8001},
+		{Dir: existing},
+	}
+
+	got := ReservedPorts(entries)
+	if len(got) != 1 || !got[8000] {
+		t.Errorf("ReservedPorts() = %v, want map[8000:true]", got)
+	}
+}
@@ -0,0 +1,259 @@
+// Package browser is the terminal UI of devinit browse: a list of items
+// narrowed down by fuzzy search as the user types, next to a pane with the
+// details of the highlighted one.
+package browser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/renan-dev/devinit/internal/i18n"
+)
+
+// Item is an entry of the browser
+type Item struct {
+	Title       string // listed and searched
+	Description string // listed after the title
+	Keywords    string // searched after the title
+	Detail      string // shown in the side pane
+}
+
+// KeyCode identifies a key read from the terminal
+type KeyCode int
+
+// Keys of the browser
+const (
+	KeyRune KeyCode = iota
+	KeyEnter
+	KeyEscape
+	KeyBackspace
+	KeyClear
+	KeyUp
+	KeyDown
+	KeyPageUp
+	KeyPageDown
+	KeyInterrupt
+)
+
+// Key is a key press; Rune is the character typed for KeyRune
+type Key struct {
+	Code KeyCode
+	Rune rune
+}
+
+// Action is what a key press does to the browser
+type Action int
+
+// Actions of Handle
+const (
+	Continue Action = iota // keep browsing
+	Choose                 // the highlighted item was chosen
+	Quit                   // the user left without choosing
+)
+
+// Browser is the state of the browser: the search query, the items
+// matching it and the one highlighted
+type Browser struct {
+	items   []Item
+	query   []rune
+	matches []int // indexes of the items matching the query, best first
+
+	cursor int // index in matches of the highlighted item
+	offset int // index in matches of the first item listed
+	scroll int // first line of the detail shown
+	height int // rows of the list and the detail in the last view
+}
+
+// New returns a browser listing items, in their order until a query ranks
+// them
+func New(items []Item) *Browser {
+	b := &Browser{items: items, height: 1}
+	b.filter()
+	return b
+}
+
+// Query returns the search query
+func (b *Browser) Query() string {
+	return string(b.query)
+}
+
+// Selected returns the index of the highlighted item, false when no item
+// matches the query
+func (b *Browser) Selected() (int, bool) {
+	if len(b.matches) == 0 {
+		return -1, false
+	}
+	return b.matches[b.cursor], true
+}
+
+// Matches returns the indexes of the items matching the query, best first
+func (b *Browser) Matches() []int {
+	return b.matches
+}
+
+// filter ranks the items matching the query: fuzzy matches of the title,
+// then items whose keywords contain every word of the query (a fuzzy match
+// of long keywords would match most queries)
+func (b *Browser) filter() {
+	type match struct{ index, score int }
+	var matches []match
+	for i, item := range b.items {
+		if score, ok := Score(string(b.query), item.Title); ok {
+			matches = append(matches, match{i, score + 1})
+		} else if containsWords(item.Keywords, string(b.query)) {
+			matches = append(matches, match{i, 0})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+
+	b.matches = b.matches[:0]
+	for _, m := range matches {
+		b.matches = append(b.matches, m.index)
+	}
+	b.cursor, b.offset, b.scroll = 0, 0, 0
+}
+
+// containsWords reports whether text contains every word of query,
+// ignoring case
+func containsWords(text, query string) bool {
+	text = strings.ToLower(text)
+	for _, word := range strings.Fields(strings.ToLower(query)) {
+		if !strings.Contains(text, word) {
+			return false
+		}
+	}
+	return true
+}
+
+// Handle applies a key press and returns what it does
+func (b *Browser) Handle(key Key) Action {
+	switch key.Code {
+	case KeyRune:
+		b.query = append(b.query, key.Rune)
+		b.filter()
+	case KeyBackspace:
+		if len(b.query) > 0 {
+			b.query = b.query[:len(b.query)-1]
+			b.filter()
+		}
+	case KeyClear:
+		b.query = nil
+		b.filter()
+	case KeyUp:
+		b.move(-1)
+	case KeyDown:
+		b.move(1)
+	case KeyPageUp:
+		b.scroll = max(b.scroll-b.height/2, 0)
+	case KeyPageDown:
+		b.scroll += b.height / 2
+	case KeyEnter:
+		if len(b.matches) > 0 {
+			return Choose
+		}
+	case KeyEscape:
+		// Escape clears the query first, then leaves
+		if len(b.query) == 0 {
+			return Quit
+		}
+		b.query = nil
+		b.filter()
+	case KeyInterrupt:
+		return Quit
+	}
+	return Continue
+}
+
+// move moves the highlight by delta items, keeping it listed
+func (b *Browser) move(delta int) {
+	if len(b.matches) == 0 {
+		return
+	}
+	b.cursor = min(max(b.cursor+delta, 0), len(b.matches)-1)
+	b.scroll = 0
+}
+
+// View renders the browser on a terminal of width columns and height rows:
+// the search line, the list and the detail of the highlighted item side by
+// side, and the keys. Lines are padded to the width.
+func (b *Browser) View(width, height int) string {
+	width, height = max(width, 20), max(height, 5)
+	b.height = height - 3
+	listWidth := min(max(width*2/5, 16), width-4)
+	detailWidth := width - listWidth - 3
+
+	switch {
+	case b.cursor < b.offset:
+		b.offset = b.cursor
+	case b.cursor >= b.offset+b.height:
+		b.offset = b.cursor - b.height + 1
+	}
+	var detail []string
+	if i, ok := b.Selected(); ok {
+		detail = wrap(b.items[i].Detail, detailWidth)
+	}
+	b.scroll = max(min(b.scroll, len(detail)-b.height), 0)
+
+	var out strings.Builder
+	count := fmt.Sprintf("%d/%d", len(b.matches), len(b.items))
+	search := i18n.T("browse.search", string(b.query)+"_")
+	out.WriteString(fit(search, width-len(count)-1) + " " + count + "\n")
+	out.WriteString(strings.Repeat("─", listWidth+1) + "┬" + strings.Repeat("─", detailWidth+1) + "\n")
+	for row := 0; row < b.height; row++ {
+		line := fit("", listWidth)
+		if n := b.offset + row; n < len(b.matches) {
+			item := b.items[b.matches[n]]
+			line = fit(strings.TrimSpace(item.Title+"  "+item.Description), listWidth)
+			if n == b.cursor {
+				// Reverse video highlights the item
+				line = "\x1b[7m" + line + "\x1b[0m"
+			}
+		} else if row == 0 && len(b.matches) == 0 {
+			line = fit(i18n.T("templates.no_matches"), listWidth)
+		}
+		text := ""
+		if n := b.scroll + row; n < len(detail) {
+			text = detail[n]
+		}
+		out.WriteString(" " + line + "│ " + fit(text, detailWidth) + "\n")
+	}
+	out.WriteString(fit(i18n.T("browse.keys"), width))
+	return out.String()
+}
+
+// fit truncates or pads s with spaces to width columns
+func fit(s string, width int) string {
+	s = strings.ReplaceAll(s, "\t", "    ")
+	if n := utf8.RuneCountInString(s); n <= width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	runes := []rune(s)
+	if width < 1 {
+		return ""
+	}
+	return string(runes[:width-1]) + "…"
+}
+
+// wrap breaks text into lines of at most width columns, at spaces where
+// possible
+func wrap(text string, width int) []string {
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\t", "    "), "\n") {
+		runes := []rune(strings.TrimRight(line, " \r"))
+		for len(runes) > width {
+			cut := width
+			for i := width; i > width/2; i-- {
+				if runes[i] == ' ' {
+					cut = i
+					break
+				}
+			}
+			lines = append(lines, strings.TrimRight(string(runes[:cut]), " "))
+			runes = []rune(strings.TrimLeft(string(runes[cut:]), " "))
+		}
+		lines = append(lines, string(runes))
+	}
+	return lines
+}
@@ -0,0 +1,171 @@
+package browser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestScore(t *testing.T) {
+	if _, ok := Score("fapi", "python/fastapi"); !ok {
+		t.Error("Score() did not match a subsequence")
+	}
+	if _, ok := Score("ipaf", "python/fastapi"); ok {
+		t.Error("Score() matched characters out of order")
+	}
+	if _, ok := Score("", "anything"); !ok {
+		t.Error("Score() of an empty query did not match")
+	}
+
+	// Consecutive characters and word starts rank first
+	exact, _ := Score("fast", "python/fastapi")
+	scattered, _ := Score("fast", "go/flask-style")
+	if exact <= scattered {
+		t.Errorf("Score(fast) = %d for fastapi, %d for a scattered match", exact, scattered)
+	}
+	start, _ := Score("go", "go/grpc")
+	inner, _ := Score("go", "python/django")
+	if start <= inner {
+		t.Errorf("Score(go) = %d at a word start, %d inside a word", start, inner)
+	}
+}
+
+func TestKeys(t *testing.T) {
+	var got []Key
+	for _, msg := range []tea.KeyMsg{
+		{Type: tea.KeyRunes, Runes: []rune("gé")},
+		{Type: tea.KeySpace, Runes: []rune(" ")},
+		{Type: tea.KeyRunes, Runes: []rune("x"), Alt: true},
+		{Type: tea.KeyUp},
+		{Type: tea.KeyCtrlN},
+		{Type: tea.KeyPgUp},
+		{Type: tea.KeyPgDown},
+		{Type: tea.KeyRight},
+		{Type: tea.KeyBackspace},
+		{Type: tea.KeyEnter},
+		{Type: tea.KeyCtrlU},
+		{Type: tea.KeyEsc},
+		{Type: tea.KeyCtrlC},
+	} {
+		got = append(got, Keys(msg)...)
+	}
+	want := []Key{
+		{Code: KeyRune, Rune: 'g'},
+		{Code: KeyRune, Rune: 'é'},
+		{Code: KeyRune, Rune: ' '},
+		{Code: KeyUp},
+		{Code: KeyDown},
+		{Code: KeyPageUp},
+		{Code: KeyPageDown},
+		{Code: KeyBackspace},
+		{Code: KeyEnter},
+		{Code: KeyClear},
+		{Code: KeyEscape},
+		{Code: KeyInterrupt},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Keys() = %v, want %v", got, want)
+	}
+}
+
+func typeQuery(b *Browser, query string) {
+	for _, r := range query {
+		b.Handle(Key{Code: KeyRune, Rune: r})
+	}
+}
+
+func TestBrowser(t *testing.T) {
+	b := New([]Item{
+		{Title: "go/grpc", Description: "gRPC service", Keywords: "grpc protobuf", Detail: "# go/grpc"},
+		{Title: "python/fastapi", Description: "REST API", Keywords: "rest async", Detail: "# python/fastapi\n\n" + strings.Repeat("line\n", 40)},
+		{Title: "python/grpc", Description: "gRPC service", Keywords: "grpc protobuf", Detail: "# python/grpc"},
+	})
+	if !reflect.DeepEqual(b.Matches(), []int{0, 1, 2}) {
+		t.Errorf("Matches() = %v, want every item in order", b.Matches())
+	}
+
+	typeQuery(b, "pygrpc")
+	if !reflect.DeepEqual(b.Matches(), []int{2}) {
+		t.Errorf("Matches() = %v, want python/grpc", b.Matches())
+	}
+	b.Handle(Key{Code: KeyClear})
+
+	// Titles rank before keywords
+	typeQuery(b, "async")
+	if !reflect.DeepEqual(b.Matches(), []int{1}) {
+		t.Errorf("Matches() = %v, want the keyword match", b.Matches())
+	}
+	b.Handle(Key{Code: KeyEscape})
+	if b.Query() != "" || len(b.Matches()) != 3 {
+		t.Errorf("Escape left query %q", b.Query())
+	}
+
+	b.Handle(Key{Code: KeyDown})
+	b.Handle(Key{Code: KeyDown})
+	b.Handle(Key{Code: KeyDown})
+	b.Handle(Key{Code: KeyUp})
+	if i, ok := b.Selected(); !ok || i != 1 {
+		t.Errorf("Selected() = %d, %v, want 1", i, ok)
+	}
+
+	view := b.View(60, 10)
+	lines := strings.Split(view, "\n")
+	if len(lines) != 10 {
+		t.Fatalf("View() has %d lines, want 10:\n%s", len(lines), view)
+	}
+	for _, line := range lines {
+		plain := strings.NewReplacer("\x1b[7m", "", "\x1b[0m", "").Replace(line)
+		if utf8.RuneCountInString(plain) != 60 {
+			t.Errorf("View() line %q is not 60 columns wide", plain)
+		}
+	}
+	if !strings.Contains(view, "\x1b[7mpython/fastapi") || !strings.Contains(view, "# python/fastapi") {
+		t.Errorf("View() does not highlight python/fastapi and show its detail:\n%s", view)
+	}
+
+	b.Handle(Key{Code: KeyPageDown})
+	if view := b.View(60, 10); strings.Contains(view, "# python/fastapi") {
+		t.Error("View() did not scroll the detail")
+	}
+
+	typeQuery(b, "zzz")
+	if b.Handle(Key{Code: KeyEnter}) != Continue {
+		t.Error("Enter without matches chose an item")
+	}
+	b.Handle(Key{Code: KeyClear})
+	if b.Handle(Key{Code: KeyEnter}) != Choose {
+		t.Error("Enter did not choose the highlighted item")
+	}
+	if b.Handle(Key{Code: KeyEscape}) != Quit {
+		t.Error("Escape with an empty query did not quit")
+	}
+}
+
+func TestWrap(t *testing.T) {
+	got := wrap("one two three\tfour\n\nfive", 9)
+	want := []string{"one two", "three", "four", "", "five"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrap() = %q, want %q", got, want)
+	}
+}
+
+func TestModel(t *testing.T) {
+	m := &model{b: New([]Item{{Title: "go/grpc"}, {Title: "python/fastapi"}}), chosen: -1}
+	m.Update(tea.WindowSizeMsg{Width: 60, Height: 10})
+	if lines := strings.Split(m.View(), "\n"); len(lines) != 10 {
+		t.Errorf("View() after a resize has %d lines, want 10", len(lines))
+	}
+
+	m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	if _, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter}); cmd == nil || m.chosen != 1 {
+		t.Errorf("Enter chose %d, want python/fastapi and the program to quit", m.chosen)
+	}
+
+	m = &model{b: New([]Item{{Title: "go/grpc"}}), chosen: -1}
+	if _, cmd := m.Update(tea.KeyMsg{Type: tea.KeyCtrlC}); cmd == nil || m.chosen != -1 {
+		t.Errorf("Ctrl-C chose %d, want no item and the program to quit", m.chosen)
+	}
+}
@@ -0,0 +1,43 @@
+package browser
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Score matches a query against text as a fuzzy subsequence, ignoring case
+// and the spaces of the query, and reports how well it matches: characters
+// following the previous match or starting a word score higher, gaps
+// between matches lower
+func Score(query, text string) (int, bool) {
+	q := []rune(strings.ToLower(strings.Join(strings.Fields(query), "")))
+	t := []rune(strings.ToLower(text))
+
+	score, matched, prev := 0, 0, -1
+	for i := 0; i < len(t) && matched < len(q); i++ {
+		if t[i] != q[matched] {
+			continue
+		}
+		score++
+		switch {
+		case prev >= 0 && i == prev+1:
+			score += 5
+		case i == 0 || isSeparator(t[i-1]):
+			score += 3
+		}
+		if prev >= 0 && i > prev+1 {
+			score -= min(i-prev-1, 3)
+		}
+		prev = i
+		matched++
+	}
+	if matched < len(q) {
+		return 0, false
+	}
+	return score, true
+}
+
+// isSeparator reports whether a character separates the words of a name
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
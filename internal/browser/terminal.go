@@ -0,0 +1,99 @@
+package browser
+
+import (
+	"errors"
+	"os"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/term"
+)
+
+// ErrNotTerminal is returned when the browser cannot take over the terminal
+var ErrNotTerminal = errors.New("the browser needs an interactive terminal")
+
+// model runs a Browser as a bubbletea program
+type model struct {
+	b             *Browser
+	width, height int
+	chosen        int
+}
+
+func (m *model) Init() tea.Cmd {
+	return nil
+}
+
+func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		// Terminals that cannot tell their size keep the 80x24 default
+		if msg.Width > 0 && msg.Height > 0 {
+			m.width, m.height = msg.Width, msg.Height
+		}
+	case tea.KeyMsg:
+		for _, key := range Keys(msg) {
+			switch m.b.Handle(key) {
+			case Choose:
+				m.chosen, _ = m.b.Selected()
+				return m, tea.Quit
+			case Quit:
+				return m, tea.Quit
+			}
+		}
+	}
+	return m, nil
+}
+
+func (m *model) View() string {
+	return m.b.View(m.width, m.height)
+}
+
+// Run shows the browser of items on the terminal of in and out until the
+// user chooses an item, returning its index, or leaves, returning -1. The
+// browser takes the alternate screen, which keeps the shell's screen
+// intact, and gives the terminal back on return.
+func Run(items []Item, in, out *os.File) (int, error) {
+	if !term.IsTerminal(in.Fd()) || !term.IsTerminal(out.Fd()) {
+		return -1, ErrNotTerminal
+	}
+	m := &model{b: New(items), width: 80, height: 24, chosen: -1}
+	program := tea.NewProgram(m, tea.WithInput(in), tea.WithOutput(out), tea.WithAltScreen())
+	if _, err := program.Run(); err != nil {
+		return -1, err
+	}
+	return m.chosen, nil
+}
+
+// Keys returns the keys of the browser of a key press read by bubbletea;
+// pasted text is a key per character. Other keys are dropped.
+func Keys(msg tea.KeyMsg) []Key {
+	switch msg.Type {
+	case tea.KeyRunes, tea.KeySpace:
+		if msg.Alt {
+			return nil
+		}
+		keys := make([]Key, len(msg.Runes))
+		for i, r := range msg.Runes {
+			keys[i] = Key{Code: KeyRune, Rune: r}
+		}
+		return keys
+	case tea.KeyEnter:
+		return []Key{{Code: KeyEnter}}
+	case tea.KeyEsc:
+		return []Key{{Code: KeyEscape}}
+	case tea.KeyBackspace, tea.KeyCtrlH:
+		return []Key{{Code: KeyBackspace}}
+	case tea.KeyCtrlU:
+		return []Key{{Code: KeyClear}}
+	case tea.KeyUp, tea.KeyCtrlP:
+		return []Key{{Code: KeyUp}}
+	case tea.KeyDown, tea.KeyCtrlN:
+		return []Key{{Code: KeyDown}}
+	case tea.KeyPgUp:
+		return []Key{{Code: KeyPageUp}}
+	case tea.KeyPgDown:
+		return []Key{{Code: KeyPageDown}}
+	case tea.KeyCtrlC, tea.KeyCtrlD:
+		return []Key{{Code: KeyInterrupt}}
+	}
+	return nil
+}
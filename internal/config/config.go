@@ -0,0 +1,361 @@
+// Package config loads the global devinit configuration
+// (config.yaml in the devinit config directory, ~/.config/devinit by
+// default).
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/renan-dev/devinit/internal/i18n"
+	"github.com/renan-dev/devinit/internal/network"
+	"github.com/renan-dev/devinit/internal/paths"
+	"github.com/renan-dev/devinit/internal/retry"
+	"github.com/renan-dev/devinit/internal/validator"
+	"github.com/renan-dev/devinit/internal/vulnscan"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvConfigPath overrides the location of the global config file
+const EnvConfigPath = "DEVINIT_CONFIG"
+
+// PolicyAction controls how devinit reacts to a policy violation
+type PolicyAction string
+
+const (
+	PolicyWarn  PolicyAction = "warn"
+	PolicyError PolicyAction = "error"
+)
+
+// Config is the global devinit configuration
+type Config struct {
+	Version string  `yaml:"version"`
+	Policy  Policy  `yaml:"policy"`
+	Naming  Naming  `yaml:"naming"`
+	Retry   Retry   `yaml:"retry"`
+	Network Network `yaml:"network"`
+	Cache   Cache   `yaml:"cache"`
+	Scan    Scan    `yaml:"scan"`
+	Updates Updates `yaml:"updates"`
+
+	// Locale selects the language of messages (en, pt). DEVINIT_LOCALE
+	// overrides it; when both are empty the system locale is used.
+	Locale string `yaml:"locale,omitempty"`
+
+	// Internal fields (not in YAML)
+	Path string `yaml:"-"` // File the config was loaded from (empty if defaults)
+}
+
+// Policy holds organization-wide rules
+type Policy struct {
+	// DeprecatedTemplates decides whether using a deprecated template warns or fails
+	DeprecatedTemplates PolicyAction `yaml:"deprecated_templates,omitempty"`
+
+	// Requirements override, by command, at which validation levels the
+	// system requirements of templates are required
+	Requirements map[string]RequirementPolicy `yaml:"requirements,omitempty"`
+}
+
+// RequirementPolicy sets how strictly a command is required
+type RequirementPolicy struct {
+	// RequiredIn lists the validation levels (basic, strict) at which the
+	// command is required; an empty list makes it optional everywhere
+	RequiredIn []string `yaml:"required_in"`
+}
+
+// RequirementLevels returns the validation levels at which each command of
+// Requirements is required
+func (p Policy) RequirementLevels() (map[string][]validator.ValidationLevel, error) {
+	if len(p.Requirements) == 0 {
+		return nil, nil
+	}
+	levels := make(map[string][]validator.ValidationLevel, len(p.Requirements))
+	for command, req := range p.Requirements {
+		levels[command] = []validator.ValidationLevel{}
+		for _, name := range req.RequiredIn {
+			level, err := validator.ParseValidationLevel(name)
+			if err != nil {
+				return nil, fmt.Errorf("policy.requirements.%s.required_in: %w", command, err)
+			}
+			levels[command] = append(levels[command], level)
+		}
+	}
+	return levels, nil
+}
+
+// Naming controls the values templates derive from project names
+type Naming struct {
+	// Registry prefixes generated image names (e.g. ghcr.io/acme)
+	Registry string `yaml:"registry,omitempty"`
+
+	// PortRange bounds the ports handed out by portFor, e.g. "10000-19999"
+	PortRange string `yaml:"port_range,omitempty"`
+}
+
+// Ports returns the bounds of PortRange, or zeros if it is empty
+func (n Naming) Ports() (min, max int, err error) {
+	if n.PortRange == "" {
+		return 0, 0, nil
+	}
+
+	lo, hi, ok := strings.Cut(n.PortRange, "-")
+	if ok {
+		min, err = strconv.Atoi(strings.TrimSpace(lo))
+		if err == nil {
+			max, err = strconv.Atoi(strings.TrimSpace(hi))
+		}
+	}
+	if !ok || err != nil || min < 1 || max > 65535 || min > max {
+		return 0, 0, fmt.Errorf("naming.port_range must look like 10000-19999 (got %q)", n.PortRange)
+	}
+	return min, max, nil
+}
+
+// Retry controls how network-dependent steps (hooks marked network: true)
+// are retried. Empty fields keep the defaults of retry.Default.
+type Retry struct {
+	// Attempts is the total number of tries; 1 disables retries
+	Attempts int `yaml:"attempts,omitempty"`
+
+	// Backoff is the wait after the first failure (e.g. "2s"), doubled after
+	// each further failure up to MaxBackoff
+	Backoff    string `yaml:"backoff,omitempty"`
+	MaxBackoff string `yaml:"max_backoff,omitempty"`
+}
+
+// Policy returns the retry policy described by r
+func (r Retry) Policy() (retry.Policy, error) {
+	policy := retry.Default()
+	if r.Attempts < 0 {
+		return policy, fmt.Errorf("retry.attempts must be at least 1 (got %d)", r.Attempts)
+	}
+	if r.Attempts > 0 {
+		policy.Attempts = r.Attempts
+	}
+
+	for _, d := range []struct {
+		name  string
+		value string
+		dst   *time.Duration
+	}{
+		{"retry.backoff", r.Backoff, &policy.Backoff},
+		{"retry.max_backoff", r.MaxBackoff, &policy.MaxBackoff},
+	} {
+		if d.value == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(d.value)
+		if err != nil || parsed < 0 {
+			return policy, fmt.Errorf("%s must be a duration like 2s (got %q)", d.name, d.value)
+		}
+		*d.dst = parsed
+	}
+
+	return policy, nil
+}
+
+// Network configures TLS for network operations (hooks and downloads).
+// Proxies come from HTTP_PROXY, HTTPS_PROXY and NO_PROXY.
+type Network struct {
+	// CABundle is a PEM file of certificate authorities to trust, e.g. the
+	// CA of a TLS-intercepting corporate proxy
+	CABundle string `yaml:"ca_bundle,omitempty"`
+
+	// Insecure disables TLS certificate verification where possible
+	Insecure bool `yaml:"insecure,omitempty"`
+}
+
+// Settings returns the network settings described by n
+func (n Network) Settings() network.Settings {
+	return network.Settings{CABundle: n.CABundle, Insecure: n.Insecure}
+}
+
+// Cache controls the on-disk caches of rendered template files and of the
+// tool versions probed by requirement checks
+type Cache struct {
+	// Dir overrides the cache location (default: render/ in the devinit
+	// cache directory, ~/.cache/devinit on Linux)
+	Dir string `yaml:"dir,omitempty"`
+
+	// Disabled renders every file and probes every tool every time
+	Disabled bool `yaml:"disabled,omitempty"`
+
+	// ProbeTTL is how long probed tool versions are reused (e.g. "10m",
+	// the default)
+	ProbeTTL string `yaml:"probe_ttl,omitempty"`
+}
+
+// ProbeFile returns the file of the tool version cache, or "" when caching
+// is disabled: probes.json in Dir, or in the devinit cache directory
+func (c Cache) ProbeFile() (string, error) {
+	if c.Disabled {
+		return "", nil
+	}
+	if c.Dir != "" {
+		return filepath.Join(c.Dir, "probes.json"), nil
+	}
+
+	dir, err := paths.CacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "probes.json"), nil
+}
+
+// TTL returns the lifetime of probed tool versions
+func (c Cache) TTL() (time.Duration, error) {
+	if c.ProbeTTL == "" {
+		return validator.DefaultProbeTTL, nil
+	}
+	ttl, err := time.ParseDuration(c.ProbeTTL)
+	if err != nil || ttl < 0 {
+		return 0, fmt.Errorf("cache.probe_ttl must be a duration like 10m (got %q)", c.ProbeTTL)
+	}
+	return ttl, nil
+}
+
+// RenderDir returns the directory of the render cache, or "" when caching is
+// disabled
+func (c Cache) RenderDir() (string, error) {
+	if c.Disabled {
+		return "", nil
+	}
+	if c.Dir != "" {
+		return c.Dir, nil
+	}
+
+	dir, err := paths.CacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "render"), nil
+}
+
+// Scan configures the vulnerability scan of generated projects (--scan)
+type Scan struct {
+	// FailOn is the severity findings must reach to fail generation: low,
+	// medium, high (the default), critical or none
+	FailOn string `yaml:"fail_on,omitempty"`
+}
+
+// Threshold returns the severity FailOn names
+func (s Scan) Threshold() (vulnscan.Severity, error) {
+	if s.FailOn == "" {
+		return vulnscan.SeverityHigh, nil
+	}
+	threshold, err := vulnscan.ParseThreshold(s.FailOn)
+	if err != nil {
+		return "", fmt.Errorf("scan.fail_on: %w", err)
+	}
+	return threshold, nil
+}
+
+// Updates controls the daily check of imported templates against their
+// sources that templates list and new run in the background
+type Updates struct {
+	// Disabled never checks; --offline skips single runs
+	Disabled bool `yaml:"disabled,omitempty"`
+}
+
+// Default returns the configuration used when no config file exists
+func Default() *Config {
+	return &Config{
+		Version: "1.0",
+		Policy: Policy{
+			DeprecatedTemplates: PolicyWarn,
+		},
+	}
+}
+
+// DefaultPath returns the config file location, honoring DEVINIT_CONFIG
+func DefaultPath() (string, error) {
+	if path := os.Getenv(EnvConfigPath); path != "" {
+		return path, nil
+	}
+
+	dir, err := paths.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "config.yaml"), nil
+}
+
+// Load loads the global config. A missing file yields the defaults.
+func Load() (*Config, error) {
+	path, err := DefaultPath()
+	if err != nil {
+		return nil, err
+	}
+
+	return LoadFile(path)
+}
+
+// LoadFile loads a config from a specific path. A missing file yields the defaults.
+func LoadFile(path string) (*Config, error) {
+	cfg := Default()
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	cfg.Path = path
+
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+// validate checks config values
+func (c *Config) validate() error {
+	switch c.Policy.DeprecatedTemplates {
+	case "":
+		c.Policy.DeprecatedTemplates = PolicyWarn
+	case PolicyWarn, PolicyError:
+	default:
+		return fmt.Errorf("policy.deprecated_templates must be %q or %q", PolicyWarn, PolicyError)
+	}
+
+	if _, err := c.Policy.RequirementLevels(); err != nil {
+		return err
+	}
+
+	if _, _, err := c.Naming.Ports(); err != nil {
+		return err
+	}
+
+	if _, err := c.Retry.Policy(); err != nil {
+		return err
+	}
+
+	if err := c.Network.Settings().Validate(); err != nil {
+		return fmt.Errorf("network.ca_bundle: %w", err)
+	}
+
+	if _, err := c.Cache.TTL(); err != nil {
+		return err
+	}
+
+	if _, err := c.Scan.Threshold(); err != nil {
+		return err
+	}
+
+	if c.Locale != "" && !i18n.Supported(c.Locale) {
+		return fmt.Errorf("locale must be one of %s (got %q)", strings.Join(i18n.Locales(), ", "), c.Locale)
+	}
+
+	return nil
+}
@@ -0,0 +1,251 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/renan-dev/devinit/internal/retry"
+)
+
+func TestLoadFile(t *testing.T) {
+	tests := []struct {
+		name       string
+		content    string // empty means no file
+		wantPolicy PolicyAction
+		wantErr    bool
+	}{
+		{
+			name:       "missing file uses defaults",
+			wantPolicy: PolicyWarn,
+		},
+		{
+			name:       "error policy",
+			content:    "policy:\n  deprecated_templates: error\n",
+			wantPolicy: PolicyError,
+		},
+		{
+			name:       "empty policy falls back to warn",
+			content:    "version: \"1.0\"\n",
+			wantPolicy: PolicyWarn,
+		},
+		{
+			name:    "invalid policy",
+			content: "policy:\n  deprecated_templates: explode\n",
+			wantErr: true,
+		},
+		{
+			name:       "supported locale",
+			content:    "locale: pt_BR\n",
+			wantPolicy: PolicyWarn,
+		},
+		{
+			name:    "invalid retry backoff",
+			content: "retry:\n  backoff: soon\n",
+			wantErr: true,
+		},
+		{
+			name:    "missing CA bundle",
+			content: "network:\n  ca_bundle: /nonexistent/ca.pem\n",
+			wantErr: true,
+		},
+		{
+			name:       "requirement policy",
+			content:    "policy:\n  requirements:\n    docker:\n      required_in: [strict]\n",
+			wantPolicy: PolicyWarn,
+		},
+		{
+			name:    "invalid requirement level",
+			content: "policy:\n  requirements:\n    docker:\n      required_in: [always]\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid probe TTL",
+			content: "cache:\n  probe_ttl: often\n",
+			wantErr: true,
+		},
+		{
+			name:       "scan threshold",
+			content:    "scan:\n  fail_on: critical\n",
+			wantPolicy: PolicyWarn,
+		},
+		{
+			name:    "invalid scan threshold",
+			content: "scan:\n  fail_on: severe\n",
+			wantErr: true,
+		},
+		{
+			name:    "unsupported locale",
+			content: "locale: xx\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if tt.content != "" {
+				if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			cfg, err := LoadFile(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if cfg.Policy.DeprecatedTemplates != tt.wantPolicy {
+				t.Errorf("DeprecatedTemplates = %q, want %q", cfg.Policy.DeprecatedTemplates, tt.wantPolicy)
+			}
+		})
+	}
+}
+
+func TestLoadProject(t *testing.T) {
+	tests := []struct {
+		name          string
+		content       string // empty means no file
+		wantTemplates string // relative to the project root
+		wantManaged   bool
+		wantErr       bool
+	}{
+		{name: "missing file"},
+		{name: "empty file", content: "\n"},
+		{
+			name:          "overrides",
+			content:       "templates: vendor/templates\nmanaged: [\"**\", \"!docs/**\"]\nnaming:\n  registry: ghcr.io/acme\n",
+			wantTemplates: "vendor/templates",
+			wantManaged:   true,
+		},
+		{
+			name:    "templates directory missing",
+			content: "templates: nowhere\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid port range",
+			content: "naming:\n  port_range: 8000\n",
+			wantErr: true,
+		},
+		{
+			name:    "unknown key",
+			content: "template: vendor/templates\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.MkdirAll(filepath.Join(dir, "vendor", "templates"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if tt.content != "" {
+				path := filepath.Join(dir, filepath.FromSlash(ProjectFile))
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			project, err := LoadProject(dir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadProject() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			wantTemplates := ""
+			if tt.wantTemplates != "" {
+				wantTemplates = filepath.Join(dir, filepath.FromSlash(tt.wantTemplates))
+			}
+			if got := project.TemplatesDir(); got != wantTemplates {
+				t.Errorf("TemplatesDir() = %q, want %q", got, wantTemplates)
+			}
+			if got := project.ManagedFiles() != nil; got != tt.wantManaged {
+				t.Errorf("ManagedFiles() != nil = %v, want %v", got, tt.wantManaged)
+			}
+		})
+	}
+}
+
+func TestNamingOver(t *testing.T) {
+	base := Naming{Registry: "docker.io/me", PortRange: "20000-20999"}
+	got := Naming{Registry: "ghcr.io/acme"}.Over(base)
+	want := Naming{Registry: "ghcr.io/acme", PortRange: "20000-20999"}
+	if got != want {
+		t.Errorf("Over() = %+v, want %+v", got, want)
+	}
+}
+
+func TestNamingPorts(t *testing.T) {
+	tests := []struct {
+		name    string
+		rng     string
+		wantMin int
+		wantMax int
+		wantErr bool
+	}{
+		{name: "unset", rng: ""},
+		{name: "range", rng: "20000-20999", wantMin: 20000, wantMax: 20999},
+		{name: "spaces", rng: "20000 - 20999", wantMin: 20000, wantMax: 20999},
+		{name: "single port", rng: "8080-8080", wantMin: 8080, wantMax: 8080},
+		{name: "reversed", rng: "9000-8000", wantErr: true},
+		{name: "out of range", rng: "60000-70000", wantErr: true},
+		{name: "not a range", rng: "8000", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			min, max, err := Naming{PortRange: tt.rng}.Ports()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Ports() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if min != tt.wantMin || max != tt.wantMax {
+				t.Errorf("Ports() = %d, %d, want %d, %d", min, max, tt.wantMin, tt.wantMax)
+			}
+		})
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		retry   Retry
+		want    retry.Policy
+		wantErr bool
+	}{
+		{name: "unset", want: retry.Default()},
+		{
+			name:  "all fields",
+			retry: Retry{Attempts: 5, Backoff: "500ms", MaxBackoff: "10s"},
+			want:  retry.Policy{Attempts: 5, Backoff: 500 * time.Millisecond, MaxBackoff: 10 * time.Second},
+		},
+		{
+			name:  "disabled",
+			retry: Retry{Attempts: 1},
+			want:  retry.Policy{Attempts: 1, Backoff: retry.Default().Backoff, MaxBackoff: retry.Default().MaxBackoff},
+		},
+		{name: "negative attempts", retry: Retry{Attempts: -1}, wantErr: true},
+		{name: "invalid backoff", retry: Retry{Backoff: "soon"}, wantErr: true},
+		{name: "negative max backoff", retry: Retry{MaxBackoff: "-1s"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.retry.Policy()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Policy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("Policy() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/renan-dev/devinit/internal/ignore"
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectFile is the configuration a generated project can carry for
+// itself, relative to its root
+const ProjectFile = ".devinit/config.yaml"
+
+// Project is the configuration of a generated project. The commands working
+// on a generated project (validate, hooks) read it over the global
+// configuration, so that a repository can diverge from the user's settings
+// in a tracked, reviewable file.
+type Project struct {
+	// Templates pins the templates directory the template of the project is
+	// loaded from, such as a vendored copy, relative to the project root.
+	// --templates-dir overrides it.
+	Templates string `yaml:"templates,omitempty"`
+
+	// Managed lists the generated files devinit manages, as .devinitignore
+	// patterns ("!" leaves files out); drift checks skip the others, like
+	// the paths of the project's .devinitignore. Empty manages every
+	// generated file.
+	Managed []string `yaml:"managed,omitempty"`
+
+	// Naming overrides the fields of the global naming it sets
+	Naming Naming `yaml:"naming,omitempty"`
+
+	// Internal fields (not in YAML)
+	Dir  string `yaml:"-"` // Root of the project
+	Path string `yaml:"-"` // File the config was loaded from (empty if none)
+}
+
+// LoadProject loads the configuration of the project whose root is dir. A
+// missing file yields an empty configuration.
+func LoadProject(dir string) (*Project, error) {
+	project := &Project{Dir: dir}
+	path := filepath.Join(dir, filepath.FromSlash(ProjectFile))
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return project, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	// Typos would silently leave the project with the global settings
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(project); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	project.Path = path
+
+	if _, _, err := project.Naming.Ports(); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	if templates := project.TemplatesDir(); templates != "" {
+		if info, err := os.Stat(templates); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("invalid config %s: templates %s is not a directory", path, project.Templates)
+		}
+	}
+	return project, nil
+}
+
+// TemplatesDir returns the templates directory the project pins, resolved
+// against the project root, or "" when it pins none
+func (p *Project) TemplatesDir() string {
+	if p.Templates == "" || filepath.IsAbs(p.Templates) {
+		return p.Templates
+	}
+	return filepath.Join(p.Dir, filepath.FromSlash(p.Templates))
+}
+
+// ManagedFiles returns the matcher of Managed, or nil when every generated
+// file is managed
+func (p *Project) ManagedFiles() *ignore.Matcher {
+	if len(p.Managed) == 0 {
+		return nil
+	}
+	return ignore.Parse(strings.Join(p.Managed, "\n"))
+}
+
+// Over returns n with the fields it leaves empty taken from base
+func (n Naming) Over(base Naming) Naming {
+	if n.Registry == "" {
+		n.Registry = base.Registry
+	}
+	if n.PortRange == "" {
+		n.PortRange = base.PortRange
+	}
+	return n
+}
@@ -0,0 +1,157 @@
+// Package credentials resolves the credentials of private template
+// sources. Tokens come, in this order, from environment variables, from
+// the OS keychain where devinit templates login stored them and from the
+// netrc file.
+//
+// Git sources are cloned by git, so its credential helpers and the SSH
+// agent keep working; these credentials are offered to git as one more
+// helper. HTTPS downloads send them as an Authorization header, and OCI
+// registries fall back to them after the credentials of docker login.
+package credentials
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// EnvPrefix starts the variables holding the token of a host:
+// DEVINIT_TOKEN_GITHUB_COM for github.com, DEVINIT_TOKEN_REGISTRY_ACME_DEV_5000
+// for registry.acme.dev:5000
+const EnvPrefix = "DEVINIT_TOKEN_"
+
+// hostEnv lists the variables other tools read the tokens of well-known
+// hosts from, which devinit reads too
+var hostEnv = map[string][]string{
+	"github.com": {"GITHUB_TOKEN", "GH_TOKEN"},
+	"gitlab.com": {"GITLAB_TOKEN"},
+}
+
+// defaultUsernames are the usernames hosts expect with a token in basic
+// authentication
+var defaultUsernames = map[string]string{
+	"github.com":          "x-access-token",
+	"ghcr.io":             "x-access-token",
+	"gitlab.com":          "oauth2",
+	"registry.gitlab.com": "oauth2",
+	"bitbucket.org":       "x-token-auth",
+}
+
+// ErrNotFound is returned when a host has no credentials to remove
+var ErrNotFound = errors.New("no credentials stored")
+
+// Credentials log in to a host. Username is empty for a bare token.
+type Credentials struct {
+	Username string `json:"username,omitempty"`
+	Secret   string `json:"secret"`
+	// Source tells where the credentials were found, for messages
+	Source string `json:"-"`
+}
+
+// BasicUsername returns the username to send with the token in basic
+// authentication: the one stored, or the one host expects
+func (c *Credentials) BasicUsername(host string) string {
+	if c.Username != "" {
+		return c.Username
+	}
+	if name, ok := defaultUsernames[host]; ok {
+		return name
+	}
+	return "oauth2"
+}
+
+// Header returns the Authorization header for HTTPS downloads: basic
+// authentication with a username, a bearer token without one
+func (c *Credentials) Header() string {
+	if c.Username == "" {
+		return "Bearer " + c.Secret
+	}
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(c.Username+":"+c.Secret))
+}
+
+// Host returns the host, with its port, of a URL or host name, in lower
+// case: github.com for https://GitHub.com/acme/api
+func Host(s string) string {
+	if !strings.Contains(s, "://") {
+		s = "https://" + s
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Host)
+}
+
+// EnvVar returns the variable holding the token of host
+func EnvVar(host string) string {
+	return EnvPrefix + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, host)
+}
+
+// Lookup returns the credentials of host from the environment, the
+// keychain or the netrc file; nil when there are none
+func Lookup(host string) (*Credentials, error) {
+	host = Host(host)
+	if host == "" {
+		return nil, nil
+	}
+	for _, name := range append([]string{EnvVar(host)}, hostEnv[host]...) {
+		if token := os.Getenv(name); token != "" {
+			return &Credentials{Secret: token, Source: "$" + name}, nil
+		}
+	}
+
+	creds, err := load(host)
+	if err != nil || creds != nil {
+		return creds, err
+	}
+	return netrcCredentials(host)
+}
+
+// Store stores the credentials of host in the OS keychain
+func Store(host string, creds Credentials) error {
+	if creds.Secret == "" {
+		return errors.New("empty token")
+	}
+	data, err := json.Marshal(creds)
+	if err != nil {
+		return err
+	}
+	return keychain.set(Host(host), data)
+}
+
+// Delete removes the credentials of host from the OS keychain
+func Delete(host string) error {
+	return keychain.remove(Host(host))
+}
+
+// KeychainName names the keychain credentials are stored in, for messages
+func KeychainName() string {
+	return keychain.name()
+}
+
+// load returns the credentials stored in the keychain for host. A
+// keychain that cannot be used has none.
+func load(host string) (*Credentials, error) {
+	data, err := keychain.get(host)
+	if err != nil || data == nil {
+		return nil, nil
+	}
+	var creds Credentials
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return nil, fmt.Errorf("invalid credentials of %s in the %s: %w", host, keychain.name(), err)
+	}
+	creds.Source = keychain.name()
+	return &creds, nil
+}
@@ -0,0 +1,106 @@
+package credentials
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// memoryKeychain is a keychain in memory
+type memoryKeychain map[string][]byte
+
+func (memoryKeychain) name() string { return "test keychain" }
+
+func (k memoryKeychain) get(host string) ([]byte, error) { return k[host], nil }
+
+func (k memoryKeychain) set(host string, data []byte) error {
+	k[host] = data
+	return nil
+}
+
+func (k memoryKeychain) remove(host string) error {
+	if _, ok := k[host]; !ok {
+		return ErrNotFound
+	}
+	delete(k, host)
+	return nil
+}
+
+func useKeychain(t *testing.T) memoryKeychain {
+	t.Helper()
+	k := memoryKeychain{}
+	original := keychain
+	t.Cleanup(func() { keychain = original })
+	keychain = k
+	return k
+}
+
+func TestHostAndEnvVar(t *testing.T) {
+	for _, tt := range []struct{ in, host, env string }{
+		{"https://GitHub.com/acme/api.git", "github.com", "DEVINIT_TOKEN_GITHUB_COM"},
+		{"registry.acme.dev:5000", "registry.acme.dev:5000", "DEVINIT_TOKEN_REGISTRY_ACME_DEV_5000"},
+		{"s3-artifacts.acme.dev", "s3-artifacts.acme.dev", "DEVINIT_TOKEN_S3_ARTIFACTS_ACME_DEV"},
+	} {
+		if got := Host(tt.in); got != tt.host {
+			t.Errorf("Host(%q) = %q, want %q", tt.in, got, tt.host)
+		}
+		if got := EnvVar(tt.host); got != tt.env {
+			t.Errorf("EnvVar(%q) = %q, want %q", tt.host, got, tt.env)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	k := useKeychain(t)
+	netrc := filepath.Join(t.TempDir(), "netrc")
+	t.Setenv("NETRC", netrc)
+	t.Setenv("GITHUB_TOKEN", "")
+	t.Setenv("DEVINIT_TOKEN_GITHUB_COM", "")
+	os.WriteFile(netrc, []byte(`machine artifacts.acme.dev login ci password n3trc
+macdef init
+machine evil.dev login x password y
+
+default login anonymous password guest
+`), 0600)
+
+	if err := Store("https://github.com", Credentials{Secret: "k3ychain"}); err != nil {
+		t.Fatal(err)
+	}
+	creds, err := Lookup("github.com")
+	if err != nil || creds == nil || creds.Secret != "k3ychain" || creds.Source != "test keychain" {
+		t.Fatalf("Lookup() = %+v, %v, want the keychain token", creds, err)
+	}
+	if creds.BasicUsername("github.com") != "x-access-token" || creds.Header() != "Bearer k3ychain" {
+		t.Errorf("BasicUsername, Header = %s, %s", creds.BasicUsername("github.com"), creds.Header())
+	}
+
+	// The environment comes first
+	t.Setenv("GITHUB_TOKEN", "env")
+	if creds, _ := Lookup("github.com"); creds == nil || creds.Secret != "env" || creds.Source != "$GITHUB_TOKEN" {
+		t.Errorf("Lookup() = %+v, want $GITHUB_TOKEN", creds)
+	}
+	t.Setenv("DEVINIT_TOKEN_GITHUB_COM", "devinit")
+	if creds, _ := Lookup("github.com"); creds == nil || creds.Secret != "devinit" {
+		t.Errorf("Lookup() = %+v, want $DEVINIT_TOKEN_GITHUB_COM", creds)
+	}
+
+	// Then the netrc file, whose entries have no port
+	creds, err = Lookup("artifacts.acme.dev:8443")
+	if err != nil || creds == nil || creds.Username != "ci" || creds.Secret != "n3trc" {
+		t.Errorf("Lookup() = %+v, %v, want the netrc entry", creds, err)
+	}
+	if creds.Header() != "Basic Y2k6bjN0cmM=" {
+		t.Errorf("Header() = %s, want basic authentication", creds.Header())
+	}
+	if creds, _ := Lookup("evil.dev"); creds == nil || creds.Username != "anonymous" {
+		t.Errorf("Lookup() = %+v, want the default entry rather than one inside a macro", creds)
+	}
+
+	if err := Delete("github.com"); err != nil || len(k) != 0 {
+		t.Errorf("Delete() = %v, keychain %v", err, k)
+	}
+	if err := Delete("github.com"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Delete() of missing credentials = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,124 @@
+package credentials
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// service names the keychain entries of devinit
+const service = "devinit"
+
+// ErrNoKeychain is returned when there is no OS keychain to store
+// credentials in
+var ErrNoKeychain = errors.New("no OS keychain available")
+
+// store holds credentials by host
+type store interface {
+	name() string
+	get(host string) ([]byte, error)
+	set(host string, data []byte) error
+	remove(host string) error
+}
+
+// keychain is the OS keychain; tests replace it
+var keychain store = systemKeychain()
+
+// systemKeychain returns the keychain of the OS, reached through its
+// command line tool so devinit needs no cgo
+func systemKeychain() store {
+	switch runtime.GOOS {
+	case "darwin":
+		return macKeychain{}
+	case "linux", "freebsd", "openbsd", "netbsd":
+		return secretService{}
+	}
+	return noKeychain{}
+}
+
+// run runs a keychain tool with stdin and returns its output
+func run(stdin string, name string, args ...string) ([]byte, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if errors.Is(err, exec.ErrNotFound) {
+		return nil, fmt.Errorf("%w: %s is not installed", ErrNoKeychain, name)
+	}
+	if err != nil {
+		return out, fmt.Errorf("%s: %w: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// macKeychain is the login keychain of macOS, through security(1)
+type macKeychain struct{}
+
+func (macKeychain) name() string { return "macOS keychain" }
+
+func (macKeychain) get(host string) ([]byte, error) {
+	out, err := run("", "security", "find-generic-password", "-s", service, "-a", host, "-w")
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimSpace(out), nil
+}
+
+func (macKeychain) set(host string, data []byte) error {
+	// Commands read from stdin keep the secret out of the process list
+	command := fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n", service, quote(host), quote(string(data)))
+	_, err := run(command, "security", "-i")
+	return err
+}
+
+func (macKeychain) remove(host string) error {
+	if _, err := run("", "security", "delete-generic-password", "-s", service, "-a", host); err != nil {
+		return fmt.Errorf("%w for %s", ErrNotFound, host)
+	}
+	return nil
+}
+
+// quote quotes an argument of security -i
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// secretService is the Secret Service of GNOME Keyring or KWallet, through
+// secret-tool(1)
+type secretService struct{}
+
+func (secretService) name() string { return "Secret Service keyring" }
+
+func (secretService) get(host string) ([]byte, error) {
+	out, err := run("", "secret-tool", "lookup", "service", service, "host", host)
+	if err != nil || len(out) == 0 {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (secretService) set(host string, data []byte) error {
+	_, err := run(string(data), "secret-tool", "store", "--label", "devinit "+host, "service", service, "host", host)
+	return err
+}
+
+func (s secretService) remove(host string) error {
+	if data, _ := s.get(host); data == nil {
+		return fmt.Errorf("%w for %s", ErrNotFound, host)
+	}
+	_, err := run("", "secret-tool", "clear", "service", service, "host", host)
+	return err
+}
+
+// noKeychain stands for the keychain on systems devinit cannot reach one
+// on
+type noKeychain struct{}
+
+func (noKeychain) name() string               { return "keychain" }
+func (noKeychain) get(string) ([]byte, error) { return nil, nil }
+func (noKeychain) set(string, []byte) error   { return ErrNoKeychain }
+func (noKeychain) remove(host string) error   { return fmt.Errorf("%w for %s", ErrNotFound, host) }
@@ -0,0 +1,95 @@
+package credentials
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// netrcFile returns the netrc file: $NETRC, or .netrc (_netrc on Windows)
+// in the home directory
+func netrcFile() string {
+	if file := os.Getenv("NETRC"); file != "" {
+		return file
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	if runtime.GOOS == "windows" {
+		return filepath.Join(home, "_netrc")
+	}
+	return filepath.Join(home, ".netrc")
+}
+
+// netrcCredentials returns the login and password of host in the netrc
+// file, or of its default entry; nil when there are none
+func netrcCredentials(host string) (*Credentials, error) {
+	file := netrcFile()
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, nil
+	}
+	// Entries name hosts without their port
+	name := host
+	if i := strings.LastIndex(host, ":"); i > strings.LastIndex(host, "]") {
+		name = host[:i]
+	}
+	creds := parseNetrc(string(data), name)
+	if creds != nil {
+		creds.Source = file
+	}
+	return creds, nil
+}
+
+// parseNetrc returns the entry of machine in a netrc file, or the default
+// entry
+func parseNetrc(data, machine string) *Credentials {
+	var (
+		found, fallback *Credentials
+		current         *Credentials
+		key             string // the keyword waiting for its value
+		macro           bool
+	)
+	for _, line := range strings.Split(data, "\n") {
+		// Macro definitions run until an empty line
+		if macro {
+			macro = strings.TrimSpace(line) != ""
+			continue
+		}
+		for _, field := range strings.Fields(line) {
+			switch {
+			case key == "machine":
+				current = nil
+				if found == nil && strings.EqualFold(field, machine) {
+					found = &Credentials{}
+					current = found
+				}
+			case key == "login" && current != nil:
+				current.Username = field
+			case key == "password" && current != nil:
+				current.Secret = field
+			case key == "macdef":
+				current, macro = nil, true
+			case key != "":
+			case field == "default":
+				current = nil
+				if fallback == nil {
+					fallback = &Credentials{}
+					current = fallback
+				}
+			default:
+				key = field
+				continue
+			}
+			key = ""
+		}
+	}
+	for _, creds := range []*Credentials{found, fallback} {
+		if creds != nil && creds.Secret != "" {
+			return creds
+		}
+	}
+	return nil
+}
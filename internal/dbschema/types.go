@@ -0,0 +1,247 @@
+package dbschema
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Type is the kind of value a column holds, independent of the database
+type Type string
+
+const (
+	TypeString    Type = "string" // bounded text (varchar, char)
+	TypeText      Type = "text"
+	TypeInteger   Type = "integer"
+	TypeBigInt    Type = "bigint"
+	TypeBoolean   Type = "boolean"
+	TypeFloat     Type = "float"
+	TypeDecimal   Type = "decimal"
+	TypeDate      Type = "date"
+	TypeTime      Type = "time"
+	TypeTimestamp Type = "timestamp"
+	TypeUUID      Type = "uuid"
+	TypeJSON      Type = "json"
+	TypeBytes     Type = "bytes"
+
+	// TypeUnknown is a type without a portable equivalent (arrays,
+	// intervals, geometry, ...)
+	TypeUnknown Type = "unknown"
+)
+
+// normalize maps a data type reported by information_schema, with the
+// native type (udt_name, column_type) where it tells more, to a Type
+func normalize(dataType, native string) Type {
+	native = strings.ToLower(native)
+	switch dataType = strings.ToLower(dataType); dataType {
+	case "tinyint":
+		// MySQL has no boolean type; BOOLEAN columns are tinyint(1)
+		if native == "tinyint(1)" {
+			return TypeBoolean
+		}
+		return TypeInteger
+	case "smallint", "mediumint", "int", "integer":
+		return TypeInteger
+	case "bigint":
+		return TypeBigInt
+	case "boolean", "bool":
+		return TypeBoolean
+	case "real", "double precision", "float", "double":
+		return TypeFloat
+	case "numeric", "decimal":
+		return TypeDecimal
+	case "character varying", "varchar", "character", "char":
+		return TypeString
+	case "text", "tinytext", "mediumtext", "longtext", "enum", "set":
+		return TypeText
+	case "date":
+		return TypeDate
+	case "time", "time without time zone", "time with time zone":
+		return TypeTime
+	case "timestamp", "timestamp without time zone", "timestamp with time zone", "datetime":
+		return TypeTimestamp
+	case "uuid":
+		return TypeUUID
+	case "json", "jsonb":
+		return TypeJSON
+	case "bytea", "binary", "varbinary", "blob", "tinyblob", "mediumblob", "longblob":
+		return TypeBytes
+	case "user-defined":
+		// PostgreSQL enums and extension types such as citext
+		if native == "citext" {
+			return TypeText
+		}
+	}
+	return TypeUnknown
+}
+
+// Column is a column of a table. Templates turn it into the field of a
+// model with {{ .Go }}, {{ .Python }}, {{ .SQLAlchemy }} or {{ .Prisma }}.
+type Column struct {
+	Name string `yaml:"name"`
+
+	// DataType is the type as the database reports it, e.g. character
+	// varying, and Type its portable kind
+	DataType string `yaml:"data_type"`
+	Type     Type   `yaml:"type"`
+
+	Nullable      bool   `yaml:"nullable,omitempty"`
+	Default       string `yaml:"default,omitempty"`
+	MaxLength     int    `yaml:"max_length,omitempty"`
+	PrimaryKey    bool   `yaml:"primary_key,omitempty"`
+	AutoIncrement bool   `yaml:"auto_increment,omitempty"`
+}
+
+// Go returns the Go type of the column for GORM models, a pointer when
+// the column is nullable
+func (c Column) Go() string {
+	var t string
+	switch c.Type {
+	case TypeInteger:
+		t = "int32"
+	case TypeBigInt:
+		t = "int64"
+	case TypeBoolean:
+		t = "bool"
+	case TypeFloat:
+		t = "float64"
+	case TypeDate, TypeTime, TypeTimestamp:
+		t = "time.Time"
+	case TypeJSON, TypeBytes:
+		return "[]byte"
+	default:
+		// Decimals are kept as text so no precision is lost
+		t = "string"
+	}
+	if c.Nullable {
+		return "*" + t
+	}
+	return t
+}
+
+// Python returns the Python type of the column, e.g. str | None
+func (c Column) Python() string {
+	var t string
+	switch c.Type {
+	case TypeString, TypeText:
+		t = "str"
+	case TypeInteger, TypeBigInt:
+		t = "int"
+	case TypeBoolean:
+		t = "bool"
+	case TypeFloat:
+		t = "float"
+	case TypeDecimal:
+		t = "Decimal"
+	case TypeDate:
+		t = "date"
+	case TypeTime:
+		t = "time"
+	case TypeTimestamp:
+		t = "datetime"
+	case TypeUUID:
+		t = "uuid.UUID"
+	case TypeBytes:
+		t = "bytes"
+	default:
+		return "Any"
+	}
+	if c.Nullable {
+		return t + " | None"
+	}
+	return t
+}
+
+// SQLAlchemy returns the SQLAlchemy column type, e.g. String(255)
+func (c Column) SQLAlchemy() string {
+	switch c.Type {
+	case TypeString:
+		if c.MaxLength > 0 {
+			return fmt.Sprintf("String(%d)", c.MaxLength)
+		}
+		return "String"
+	case TypeText:
+		return "Text"
+	case TypeInteger:
+		return "Integer"
+	case TypeBigInt:
+		return "BigInteger"
+	case TypeBoolean:
+		return "Boolean"
+	case TypeFloat:
+		return "Float"
+	case TypeDecimal:
+		return "Numeric"
+	case TypeDate:
+		return "Date"
+	case TypeTime:
+		return "Time"
+	case TypeTimestamp:
+		if strings.Contains(c.DataType, "with time zone") {
+			return "DateTime(timezone=True)"
+		}
+		return "DateTime"
+	case TypeUUID:
+		return "Uuid"
+	case TypeJSON:
+		return "JSON"
+	case TypeBytes:
+		return "LargeBinary"
+	}
+	return "types.NullType"
+}
+
+// Prisma returns the Prisma field type, e.g. String? for a nullable text
+// column
+func (c Column) Prisma() string {
+	var t string
+	switch c.Type {
+	case TypeString, TypeText, TypeUUID:
+		t = "String"
+	case TypeInteger:
+		t = "Int"
+	case TypeBigInt:
+		t = "BigInt"
+	case TypeBoolean:
+		t = "Boolean"
+	case TypeFloat:
+		t = "Float"
+	case TypeDecimal:
+		t = "Decimal"
+	case TypeDate, TypeTime, TypeTimestamp:
+		t = "DateTime"
+	case TypeJSON:
+		t = "Json"
+	case TypeBytes:
+		t = "Bytes"
+	default:
+		t = fmt.Sprintf("Unsupported(%q)", c.DataType)
+	}
+	if c.Nullable {
+		return t + "?"
+	}
+	return t
+}
+
+// SQLAlchemyImports returns the names models of the schema import from
+// sqlalchemy: the column types in use, plus ForeignKey when a table has one
+func (s *Schema) SQLAlchemyImports() []string {
+	seen := make(map[string]bool)
+	for _, table := range s.Tables {
+		for _, column := range table.Columns {
+			name, _, _ := strings.Cut(column.SQLAlchemy(), "(")
+			name, _, _ = strings.Cut(name, ".")
+			seen[name] = true
+		}
+		if len(table.ForeignKeys) > 0 {
+			seen["ForeignKey"] = true
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,141 @@
+package diff
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// hunkHeader matches "@@ -l,s +l,s @@"; counts default to 1
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// hunk is a parsed unified diff hunk
+type hunk struct {
+	oldStart int
+	old, new []string
+}
+
+// Apply applies a unified diff of a single file, such as one produced by
+// Unified, to text.
+// Hunks are located by their context, so they still apply when lines were
+// added or removed elsewhere in text.
+func Apply(text, patch string) (string, error) {
+	hunks, err := parseHunks(patch)
+	if err != nil {
+		return "", err
+	}
+
+	src := splitLines(text)
+	var out strings.Builder
+	pos := 0
+	for i, h := range hunks {
+		at := findHunk(src, pos, h)
+		if at < 0 {
+			return "", fmt.Errorf("hunk %d (line %d) does not apply", i+1, h.oldStart)
+		}
+		for _, line := range src[pos:at] {
+			out.WriteString(line)
+		}
+		for _, line := range h.new {
+			out.WriteString(line)
+		}
+		pos = at + len(h.old)
+	}
+	for _, line := range src[pos:] {
+		out.WriteString(line)
+	}
+
+	return out.String(), nil
+}
+
+// parseHunks reads the hunks of a unified diff, skipping file headers
+func parseHunks(patch string) ([]hunk, error) {
+	var (
+		hunks []hunk
+		cur   *hunk
+		last  byte // kind of the previous hunk line
+	)
+	for n, line := range splitLines(patch) {
+		if match := hunkHeader.FindStringSubmatch(line); match != nil {
+			start, _ := strconv.Atoi(match[1])
+			hunks = append(hunks, hunk{oldStart: start})
+			cur = &hunks[len(hunks)-1]
+			continue
+		}
+		if cur == nil {
+			continue // "---"/"+++" headers and other preamble
+		}
+
+		if !strings.HasSuffix(line, "\n") {
+			line += "\n"
+		}
+		switch line[0] {
+		case ' ':
+			cur.old = append(cur.old, line[1:])
+			cur.new = append(cur.new, line[1:])
+		case '-':
+			cur.old = append(cur.old, line[1:])
+		case '+':
+			cur.new = append(cur.new, line[1:])
+		case '\\':
+			// "\ No newline at end of file" applies to the previous line
+			if last != '+' {
+				trimLast(cur.old)
+			}
+			if last != '-' {
+				trimLast(cur.new)
+			}
+		default:
+			if strings.TrimSpace(line) == "" {
+				cur = nil // end of the diff
+				continue
+			}
+			return nil, fmt.Errorf("line %d: unexpected %q in hunk", n+1, strings.TrimSuffix(line, "\n"))
+		}
+		last = line[0]
+	}
+
+	if len(hunks) == 0 {
+		return nil, fmt.Errorf("no hunks found")
+	}
+	return hunks, nil
+}
+
+func trimLast(lines []string) {
+	if len(lines) > 0 {
+		lines[len(lines)-1] = strings.TrimSuffix(lines[len(lines)-1], "\n")
+	}
+}
+
+// findHunk returns the index at or after pos where the old lines of h start,
+// preferring the position closest to the one given in its header, or -1
+func findHunk(src []string, pos int, h hunk) int {
+	expected := h.oldStart - 1
+	if len(h.old) == 0 {
+		expected = h.oldStart // pure insertions follow line oldStart
+	}
+	expected = max(pos, min(expected, len(src)))
+
+	matches := func(at int) bool {
+		if at < pos || at+len(h.old) > len(src) {
+			return false
+		}
+		for i, line := range h.old {
+			if src[at+i] != line {
+				return false
+			}
+		}
+		return true
+	}
+
+	for offset := 0; expected-offset >= pos || expected+offset <= len(src); offset++ {
+		if matches(expected - offset) {
+			return expected - offset
+		}
+		if matches(expected + offset) {
+			return expected + offset
+		}
+	}
+	return -1
+}
@@ -0,0 +1,123 @@
+package diff
+
+import "testing"
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want string
+	}{
+		{
+			name: "identical",
+			a:    "one\ntwo\n",
+			b:    "one\ntwo\n",
+			want: "",
+		},
+		{
+			name: "changed line",
+			a:    "one\ntwo\nthree\n",
+			b:    "one\n2\nthree\n",
+			want: "--- a\n+++ b\n@@ -1,3 +1,3 @@\n one\n-two\n+2\n three\n",
+		},
+		{
+			name: "new file",
+			a:    "",
+			b:    "one\n",
+			want: "--- a\n+++ b\n@@ -0,0 +1,1 @@\n+one\n",
+		},
+		{
+			name: "deleted file",
+			a:    "one\n",
+			b:    "",
+			want: "--- a\n+++ b\n@@ -1,1 +0,0 @@\n-one\n",
+		},
+		{
+			name: "separate hunks",
+			a:    "a\nb\nc\nd\ne\nf\ng\nh\n",
+			b:    "A\nb\nc\nd\ne\nf\ng\nH\n",
+			want: "--- a\n+++ b\n@@ -1,2 +1,2 @@\n-a\n+A\n b\n@@ -7,2 +7,2 @@\n g\n-h\n+H\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Unified("a", "b", tt.a, tt.b, 1)
+			if got != tt.want {
+				t.Errorf("Unified() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		patch string
+		want  string
+		error bool
+	}{
+		{
+			name:  "changed line",
+			text:  "one\ntwo\nthree\n",
+			patch: "--- a\n+++ b\n@@ -1,3 +1,3 @@\n one\n-two\n+2\n three\n",
+			want:  "one\n2\nthree\n",
+		},
+		{
+			name:  "offset by added lines",
+			text:  "zero\nzero\none\ntwo\nthree\n",
+			patch: "@@ -1,3 +1,4 @@\n one\n two\n+two and a half\n three\n",
+			want:  "zero\nzero\none\ntwo\ntwo and a half\nthree\n",
+		},
+		{
+			name:  "append to empty file",
+			text:  "",
+			patch: "@@ -0,0 +1,1 @@\n+one\n",
+			want:  "one\n",
+		},
+		{
+			name:  "no newline at end",
+			text:  "one\ntwo",
+			patch: "@@ -1,2 +1,2 @@\n one\n-two\n\\ No newline at end of file\n+2\n",
+			want:  "one\n2\n",
+		},
+		{
+			name:  "context mismatch",
+			text:  "one\nTWO\nthree\n",
+			patch: "@@ -1,3 +1,3 @@\n one\n-two\n+2\n three\n",
+			error: true,
+		},
+		{
+			name:  "no hunks",
+			text:  "one\n",
+			patch: "not a diff\n",
+			error: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Apply(tt.text, tt.patch)
+			if tt.error {
+				if err == nil {
+					t.Errorf("Apply() = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Apply() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Apply() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Unified output applies back
+	a, b := "a\nb\nc\nd\ne\nf\ng\nh\n", "A\nb\nc\nd\ne\nf\ng\nH\ni"
+	if got, err := Apply(a, Unified("a", "b", a, b, 3)); err != nil || got != b {
+		t.Errorf("Apply(Unified()) = %q, %v, want %q", got, err, b)
+	}
+}
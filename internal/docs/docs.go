@@ -0,0 +1,363 @@
+// Package docs renders reference documentation for templates: Markdown for
+// publishing template catalogs, and JSON Schemas of their variables for
+// tools building input forms. Documents are always written in English so
+// published catalogs do not depend on the locale of whoever generated them.
+package docs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/renan-dev/devinit/internal/template"
+)
+
+// Markdown returns the reference document of a template: its variables,
+// variableNames returns the names of the variables of a template, sorted
+func variableNames(tmpl *template.Template) []string {
+	names := make([]string, 0, len(tmpl.Variables))
+	for name := range tmpl.Variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// profiles, requirements, files grouped by condition, hooks and next steps
+func Markdown(tmpl *template.Template) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# %s\n\n", tmpl.ID)
+	if tmpl.Name != "" && tmpl.Name != tmpl.ID {
+		fmt.Fprintf(&b, "**%s**", tmpl.Name)
+		if tmpl.Description != "" {
+			b.WriteString(" — " + tmpl.Description)
+		}
+		b.WriteString("\n\n")
+	} else if tmpl.Description != "" {
+		b.WriteString(tmpl.Description + "\n\n")
+	}
+
+	if notice := tmpl.DeprecationNotice(); notice != "" {
+		fmt.Fprintf(&b, "> **Deprecated:** %s\n\n", notice)
+	}
+
+	table(&b, []string{"Property", "Value"}, [][]string{
+		{"Version", tmpl.Version},
+		{"Language", tmpl.Language},
+		{"Framework", tmpl.Framework},
+		{"Type", tmpl.GetType()},
+		{"Tags", strings.Join(tmpl.Tags, ", ")},
+	})
+
+	b.WriteString("## Usage\n\n```sh\n")
+	fmt.Fprintf(&b, "devinit new my-project --lang %s --framework %s\n", tmpl.Language, tmpl.Framework)
+	b.WriteString("```\n\n")
+
+	writeVariables(&b, tmpl)
+	writeProfiles(&b, tmpl)
+	writeRequirements(&b, tmpl)
+	writeFiles(&b, tmpl)
+	writeHooks(&b, tmpl)
+
+	if tmpl.Healthcheck != nil {
+		b.WriteString("## Health check\n\n")
+		fmt.Fprintf(&b, "`%s` on port %d", tmpl.Healthcheck.Command, tmpl.Healthcheck.Port)
+		if tmpl.Healthcheck.Timeout != "" {
+			fmt.Fprintf(&b, " (timeout %s)", tmpl.Healthcheck.Timeout)
+		}
+		b.WriteString("\n\n")
+	}
+
+	if smoke := tmpl.SmokeTest; smoke != nil {
+		b.WriteString("## Smoke test\n\n`devinit new --smoke-test` runs:\n\n```sh\n")
+		for _, step := range smoke.Setup {
+			b.WriteString(step + "\n")
+		}
+		b.WriteString(smoke.Run + "\n```\n\n")
+		if smoke.Image != "" {
+			fmt.Fprintf(&b, "With `--smoke-test=container` the commands run in `%s`.\n\n", smoke.Image)
+		}
+	}
+
+	if len(tmpl.NextSteps) > 0 {
+		b.WriteString("## Next steps\n\n```sh\n")
+		for _, step := range tmpl.NextSteps {
+			b.WriteString(step + "\n")
+		}
+		b.WriteString("```\n\n")
+	}
+
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+func writeVariables(b *strings.Builder, tmpl *template.Template) {
+	if len(tmpl.Variables) == 0 {
+		return
+	}
+
+	names := variableNames(tmpl)
+	rows := make([][]string, 0, len(names))
+	for _, name := range names {
+		variable := tmpl.Variables[name]
+		if variable.Hidden {
+			continue
+		}
+
+		allowed := ""
+		switch {
+		case len(variable.Choices) > 0:
+			allowed = choiceList(variable.Choices)
+		case variable.Pattern != "":
+			allowed = "matches " + code(variable.Pattern)
+		}
+
+		defaultValue := ""
+		if variable.Default != nil {
+			defaultValue = code(fmt.Sprint(variable.Default))
+		}
+
+		description := variable.Description
+		if variable.Advanced {
+			description = strings.TrimSpace(description + " *(advanced)*")
+		}
+
+		rows = append(rows, []string{code(name), string(variable.Type), yesNo(variable.Required), defaultValue, allowed, description})
+	}
+	if len(rows) == 0 {
+		return
+	}
+
+	b.WriteString("## Variables\n\n")
+	b.WriteString("Set with `--var NAME=VALUE`.\n\n")
+	table(b, []string{"Name", "Type", "Required", "Default", "Allowed values", "Description"}, rows)
+}
+
+func writeProfiles(b *strings.Builder, tmpl *template.Template) {
+	if len(tmpl.Profiles) == 0 {
+		return
+	}
+
+	rows := make([][]string, 0, len(tmpl.Profiles))
+	for _, name := range tmpl.ProfileNames() {
+		profile := tmpl.Profiles[name]
+
+		keys := make([]string, 0, len(profile.Variables))
+		for key := range profile.Variables {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		values := make([]string, 0, len(keys))
+		for _, key := range keys {
+			values = append(values, code(fmt.Sprintf("%s=%v", key, profile.Variables[key])))
+		}
+
+		rows = append(rows, []string{code(name), profile.Description, strings.Join(values, " ")})
+	}
+
+	b.WriteString("## Profiles\n\n")
+	b.WriteString("Select with `--profile NAME`.\n\n")
+	table(b, []string{"Profile", "Description", "Sets"}, rows)
+}
+
+func writeRequirements(b *strings.Builder, tmpl *template.Template) {
+	reqs := tmpl.Requirements
+	if len(reqs.System) == 0 && len(reqs.Environment) == 0 && len(reqs.Checks) == 0 {
+		return
+	}
+
+	b.WriteString("## Requirements\n\n")
+
+	if len(reqs.System) > 0 {
+		rows := make([][]string, 0, len(reqs.System))
+		for _, req := range reqs.System {
+			required := yesNo(req.Required)
+			if len(req.RequiredIn) > 0 {
+				required = strings.Join(req.RequiredIn, ", ")
+			}
+			rows = append(rows, []string{code(req.Command), req.Version, required, code(req.When), req.InstallHint})
+		}
+		table(b, []string{"Command", "Version", "Required", "When", "Install"}, rows)
+	}
+
+	if len(reqs.Environment) > 0 {
+		rows := make([][]string, 0, len(reqs.Environment))
+		for _, req := range reqs.Environment {
+			rows = append(rows, []string{code(req.Variable), yesNo(req.Required), code(req.When)})
+		}
+		table(b, []string{"Environment variable", "Required", "When"}, rows)
+	}
+
+	if len(reqs.Checks) > 0 {
+		rows := make([][]string, 0, len(reqs.Checks))
+		for _, check := range reqs.Checks {
+			required := yesNo(check.Required)
+			if len(check.RequiredIn) > 0 {
+				required = strings.Join(check.RequiredIn, ", ")
+			}
+			rows = append(rows, []string{check.Name, checkTarget(check), required, code(check.When), check.Fix})
+		}
+		table(b, []string{"Check", "Verifies", "Required", "When", "Fix"}, rows)
+	}
+}
+
+// checkTarget describes what a doctor check verifies, e.g. "`disk_free` 2GB"
+func checkTarget(check template.Check) string {
+	kind := check.Kind()
+	switch kind {
+	case "disk_free":
+		return code(kind) + " " + check.DiskFree
+	case "memory":
+		return code(kind) + " " + check.Memory
+	case "url":
+		return code(kind) + " " + check.URL
+	case "docker_network":
+		return code(kind) + " " + code(check.DockerNetwork)
+	case "script":
+		return code(kind) + " " + code(check.Script) + fmt.Sprintf(" exits %d", check.ExitCode)
+	}
+	return ""
+}
+
+// writeFiles lists generated files grouped by their conditions, in the order
+// the groups first appear in the template
+func writeFiles(b *strings.Builder, tmpl *template.Template) {
+	if len(tmpl.Files) == 0 {
+		return
+	}
+
+	var order []string
+	groups := make(map[string][]string)
+	for _, file := range tmpl.Files {
+		key := strings.Join(file.Conditions, " and ")
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		entry := code(file.Destination)
+		if file.Mode != "" && file.Mode != template.WriteOverwrite {
+			entry += fmt.Sprintf(" (%s)", file.Mode)
+		}
+		groups[key] = append(groups[key], entry)
+	}
+
+	b.WriteString("## Files\n\n")
+	for _, key := range order {
+		if key == "" {
+			b.WriteString("Always generated:\n\n")
+		} else {
+			fmt.Fprintf(b, "When %s:\n\n", code(key))
+		}
+		for _, entry := range groups[key] {
+			fmt.Fprintf(b, "- %s\n", entry)
+		}
+		b.WriteString("\n")
+	}
+}
+
+func writeHooks(b *strings.Builder, tmpl *template.Template) {
+	if len(tmpl.Hooks.PreGenerate) == 0 && len(tmpl.Hooks.PostGenerate) == 0 {
+		return
+	}
+
+	b.WriteString("## Hooks\n\n")
+	b.WriteString("Skipped with `--no-hooks`.\n\n")
+	for _, stage := range []struct {
+		title string
+		hooks []template.Hook
+	}{
+		{"Before generating files", tmpl.Hooks.PreGenerate},
+		{"After generating files", tmpl.Hooks.PostGenerate},
+	} {
+		if len(stage.hooks) == 0 {
+			continue
+		}
+
+		// Loaded templates have valid hooks; keep the file order otherwise
+		hooks, err := template.SortHooks(stage.hooks)
+		if err != nil {
+			hooks = stage.hooks
+		}
+
+		fmt.Fprintf(b, "%s:\n\n", stage.title)
+		for i, hook := range hooks {
+			var notes []string
+			if hook.Name != "" {
+				notes = append(notes, "name: "+hook.Name)
+			}
+			if hook.When != "" {
+				notes = append(notes, "when "+code(hook.When))
+			}
+			if len(hook.After) > 0 {
+				notes = append(notes, "after "+strings.Join(hook.After, ", "))
+			}
+			level := hook.ErrorLevel
+			if level == "" {
+				level = template.ErrorLevelError
+			}
+			notes = append(notes, "on failure: "+string(level))
+			if hook.Network {
+				notes = append(notes, "retried")
+			}
+			if hook.Idempotent {
+				notes = append(notes, "idempotent")
+			}
+			fmt.Fprintf(b, "%d. %s (%s)\n", i+1, code(strings.Join(strings.Fields(hook.Run), " ")), strings.Join(notes, ", "))
+		}
+		b.WriteString("\n")
+	}
+}
+
+// table writes a Markdown table
+func table(b *strings.Builder, header []string, rows [][]string) {
+	writeRow(b, header)
+	separators := make([]string, len(header))
+	for i := range separators {
+		separators[i] = "---"
+	}
+	writeRow(b, separators)
+	for _, row := range rows {
+		writeRow(b, row)
+	}
+	b.WriteString("\n")
+}
+
+func writeRow(b *strings.Builder, cells []string) {
+	b.WriteString("|")
+	for _, cell := range cells {
+		cell = strings.ReplaceAll(cell, "|", `\|`)
+		cell = strings.Join(strings.Fields(cell), " ")
+		b.WriteString(" " + cell + " |")
+	}
+	b.WriteString("\n")
+}
+
+// code formats values as inline code, joined with commas; empty values
+// yield an empty string
+func code(values ...string) string {
+	var parts []string
+	for _, value := range values {
+		if value != "" {
+			parts = append(parts, "`"+value+"`")
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// choiceList lists choices as code, each followed by its label
+func choiceList(choices []template.Choice) string {
+	parts := make([]string, len(choices))
+	for i, choice := range choices {
+		parts[i] = code(choice.Value)
+		if choice.Label != "" {
+			parts[i] += " (" + choice.Label + ")"
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,125 @@
+package docs
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/renan-dev/devinit/internal/template"
+)
+
+func TestMarkdown(t *testing.T) {
+	tmpl := &template.Template{
+		ID:          "python/fastapi",
+		Name:        "FastAPI API",
+		Description: "Production-ready FastAPI service",
+		Version:     "1.2.0",
+		Language:    "python",
+		Framework:   "fastapi",
+		Tags:        []string{"rest", "async"},
+		Requirements: template.Requirements{
+			System: []template.SystemRequirement{
+				{Command: "docker", Version: ">=24.0", When: "{{ .IncludeDocker }}", RequiredIn: []string{"strict"}},
+			},
+			Checks: []template.Check{
+				{Name: "Package mirror", URL: "https://pypi.internal/simple/", Required: true, Fix: "Connect to the VPN"},
+				{DiskFree: "2GB"},
+			},
+		},
+		Variables: map[string]template.Variable{
+			"database":     {Type: template.VariableTypeChoice, Choices: []template.Choice{{Value: "postgres", Label: "PostgreSQL"}, {Value: "none"}}, Default: "none", Description: "Database to configure"},
+			"package_name": {Type: template.VariableTypeString, Required: true, Pattern: "^[a-z|_]+$"},
+			"workers":      {Type: template.VariableTypeInt, Default: 4, Description: "Worker processes", Advanced: true},
+			"build_id":     {Type: template.VariableTypeString, Hidden: true},
+		},
+		Profiles: map[string]template.Profile{
+			"minimal": {Description: "No Docker", Variables: map[string]interface{}{"IncludeDocker": false}},
+		},
+		Files: []template.FileSpec{
+			{Source: "main.py.tmpl", Destination: "src/main.py"},
+			{Source: "Dockerfile", Destination: "Dockerfile", Conditions: []string{"{{ .IncludeDocker }}"}},
+			{Source: "README.md.tmpl", Destination: "README.md"},
+		},
+		Hooks: template.Hooks{
+			PostGenerate: []template.Hook{
+				{Run: "poetry install", ErrorLevel: template.ErrorLevelWarn, Network: true},
+				{Run: "git init"},
+			},
+		},
+		NextSteps: []string{"cd {{ .ProjectName }}"},
+	}
+
+	got := Markdown(tmpl)
+
+	for _, want := range []string{
+		"# python/fastapi\n",
+		"**FastAPI API** — Production-ready FastAPI service",
+		"| Version | 1.2.0 |",
+		"devinit new my-project --lang python --framework fastapi",
+		"| `database` | choice | no | `none` | `postgres` (PostgreSQL), `none` | Database to configure |",
+		"| `package_name` | string | yes |  | matches `^[a-z\\|_]+$` |  |",
+		"| `workers` | int | no | `4` |  | Worker processes *(advanced)* |",
+		"| `minimal` | No Docker | `IncludeDocker=false` |",
+		"| `docker` | >=24.0 | strict | `{{ .IncludeDocker }}` |  |",
+		"| Package mirror | `url` https://pypi.internal/simple/ | yes |  | Connect to the VPN |",
+		"|  | `disk_free` 2GB | no |  |  |",
+		"Always generated:\n\n- `src/main.py`\n- `README.md`\n",
+		"When `{{ .IncludeDocker }}`:\n\n- `Dockerfile`\n",
+		"1. `poetry install` (on failure: warn, retried)",
+		"2. `git init` (on failure: error)",
+		"```sh\ncd {{ .ProjectName }}\n```",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Markdown() missing %q in:\n%s", want, got)
+		}
+	}
+
+	for _, unwanted := range []string{"Deprecated", "## Health check", "Before generating files", "build_id"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("Markdown() contains %q for a template without it", unwanted)
+		}
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	tmpl := &template.Template{
+		ID:          "python/fastapi",
+		Description: "Production-ready FastAPI service",
+		Version:     "1.2.0",
+		Variables: map[string]template.Variable{
+			"database":     {Type: template.VariableTypeChoice, Choices: []template.Choice{{Value: "postgres", Label: "PostgreSQL", Description: "production-grade relational DB"}, {Value: "none"}}, Default: "none", Description: "Database to configure"},
+			"ci":           {Type: template.VariableTypeChoice, Choices: []template.Choice{{Value: "github"}, {Value: "gitlab"}}},
+			"db_port":      {Type: template.VariableTypeInt, Default: "5432"},
+			"docker":       {Type: template.VariableTypeBool, Default: false},
+			"package_name": {Type: template.VariableTypeString, Required: true, Pattern: "^[a-z_]+$"},
+			"author":       {Required: true, Default: "Jane"},
+			"build_id":     {Type: template.VariableTypeString, Hidden: true},
+		},
+		Profiles: map[string]template.Profile{
+			"minimal": {Variables: map[string]interface{}{"IncludeDocker": false}},
+		},
+	}
+
+	data, err := json.Marshal(JSONSchema(tmpl))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(data)
+	for _, want := range []string{
+		`"$schema":"https://json-schema.org/draft/2020-12/schema"`,
+		`"title":"python/fastapi"`,
+		`"type":"object"`,
+		`"database":{"type":"string","description":"Database to configure","default":"none","enum":["postgres","none"],"oneOf":[{"const":"postgres","title":"PostgreSQL","description":"production-grade relational DB"},{"const":"none"}]}`,
+		`"ci":{"type":"string","enum":["github","gitlab"]}`,
+		`"db_port":{"type":"integer","default":5432}`,
+		`"docker":{"type":"boolean","default":false}`,
+		`"package_name":{"type":"string","pattern":"^[a-z_]+$"}`,
+		`"build_id":{"type":"string","x-devinit-advanced":true}`,
+		`"required":["package_name"]`,
+		`"x-devinit":{"template":"python/fastapi","version":"1.2.0","profiles":{"minimal":{"IncludeDocker":false}}}`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("JSONSchema() missing %s in:\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package docs
+
+import (
+	"strconv"
+
+	"github.com/renan-dev/devinit/internal/template"
+)
+
+// SchemaDialect is the JSON Schema version of the schemas of templates
+const SchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is a JSON Schema of the variables of a template, from which web
+// forms and editor plugins build their input UIs
+type Schema struct {
+	Dialect     string                    `json:"$schema"`
+	Title       string                    `json:"title"`
+	Description string                    `json:"description,omitempty"`
+	Type        string                    `json:"type"`
+	Properties  map[string]SchemaProperty `json:"properties"`
+	Required    []string                  `json:"required,omitempty"`
+	Devinit     SchemaTemplate            `json:"x-devinit"`
+}
+
+// SchemaProperty describes a variable. Defaults computed from other
+// variables have no JSON Schema keyword either: they are given as their
+// expression.
+type SchemaProperty struct {
+	Type              string         `json:"type"`
+	Description       string         `json:"description,omitempty"`
+	Default           interface{}    `json:"default,omitempty"`
+	DefaultExpression string         `json:"x-devinit-default,omitempty"`
+	Enum              []string       `json:"enum,omitempty"`
+	OneOf             []SchemaChoice `json:"oneOf,omitempty"`
+	Pattern           string         `json:"pattern,omitempty"`
+
+	// Advanced variables, which forms may tuck away; hidden ones are
+	// advanced too
+	Advanced bool `json:"x-devinit-advanced,omitempty"`
+}
+
+// SchemaChoice is a choice with a label or description, which forms show
+// instead of the value
+type SchemaChoice struct {
+	Const       string `json:"const"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// SchemaTemplate is what JSON Schema has no keyword for: the template the
+// schema describes and its profiles, which preset groups of variables
+type SchemaTemplate struct {
+	Template string                            `json:"template"`
+	Version  string                            `json:"version"`
+	Profiles map[string]map[string]interface{} `json:"profiles,omitempty"`
+}
+
+// JSONSchema returns the JSON Schema of the variables of a template: their
+// types, defaults, choices as enums and patterns. Variables are required
+// when the template requires them and has no default for them.
+func JSONSchema(tmpl *template.Template) *Schema {
+	schema := &Schema{
+		Dialect:     SchemaDialect,
+		Title:       tmpl.ID,
+		Description: tmpl.Description,
+		Type:        "object",
+		Properties:  make(map[string]SchemaProperty, len(tmpl.Variables)),
+		Devinit:     SchemaTemplate{Template: tmpl.ID, Version: tmpl.Version},
+	}
+
+	for _, name := range variableNames(tmpl) {
+		variable := tmpl.Variables[name]
+		property := SchemaProperty{
+			Type:        schemaType(variable.Type),
+			Description: variable.Description,
+			Default:     schemaValue(variable.Type, variable.Default),
+			Enum:        variable.ChoiceValues(),
+			Advanced:    variable.Advanced || variable.Hidden,
+		}
+		for _, choice := range variable.Choices {
+			if choice.Labeled() {
+				property.OneOf = schemaChoices(variable.Choices)
+				break
+			}
+		}
+		if property.Type == "string" {
+			property.Pattern = variable.Pattern
+		}
+		if expr := variable.DefaultExpression(); expr != "" {
+			property.Default, property.DefaultExpression = nil, expr
+		}
+		schema.Properties[name] = property
+		if variable.Required && variable.Default == nil {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+
+	if len(tmpl.Profiles) > 0 {
+		schema.Devinit.Profiles = make(map[string]map[string]interface{}, len(tmpl.Profiles))
+		for name, profile := range tmpl.Profiles {
+			schema.Devinit.Profiles[name] = profile.Variables
+		}
+	}
+	return schema
+}
+
+// schemaChoices returns the oneOf of a variable with labeled choices
+func schemaChoices(choices []template.Choice) []SchemaChoice {
+	oneOf := make([]SchemaChoice, len(choices))
+	for i, choice := range choices {
+		oneOf[i] = SchemaChoice{Const: choice.Value, Title: choice.Label, Description: choice.Description}
+	}
+	return oneOf
+}
+
+// schemaType returns the JSON Schema type of a variable type; choices are
+// strings with an enum
+func schemaType(typ template.VariableType) string {
+	switch typ {
+	case template.VariableTypeBool:
+		return "boolean"
+	case template.VariableTypeInt:
+		return "integer"
+	default:
+		return "string"
+	}
+}
+
+// schemaValue converts a default written as a string in template.yaml,
+// such as "5432" for an int, to the JSON type of the variable
+func schemaValue(typ template.VariableType, value interface{}) interface{} {
+	s, ok := value.(string)
+	if !ok {
+		return value
+	}
+	switch typ {
+	case template.VariableTypeBool:
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b
+		}
+	case template.VariableTypeInt:
+		if n, err := strconv.Atoi(s); err == nil {
+			return n
+		}
+	}
+	return value
+}
@@ -0,0 +1,82 @@
+// Package errcode classifies devinit errors with stable, machine-readable
+// codes so scripts can branch on the failure reason instead of matching
+// (possibly translated) messages.
+package errcode
+
+import "errors"
+
+// Code identifies a class of failure. Codes are part of the CLI contract
+// and must not change once released.
+type Code string
+
+const (
+	// Unknown is reported for errors that carry no code
+	Unknown Code = "UNKNOWN"
+
+	Usage              Code = "USAGE"
+	TemplateNotFound   Code = "TEMPLATE_NOT_FOUND"
+	TemplateInvalid    Code = "TEMPLATE_INVALID"
+	VariableInvalid    Code = "VARIABLE_INVALID"
+	Validation         Code = "VALIDATION_FAILED"
+	Conflict           Code = "CONFLICT"
+	HookFailed         Code = "HOOK_FAILED"
+	RequirementMissing Code = "REQUIREMENT_MISSING"
+	SmokeTestFailed    Code = "SMOKE_TEST_FAILED"
+	ScanFailed         Code = "SCAN_FAILED"
+)
+
+// Error attaches a code to an underlying error. Its message is the message
+// of the underlying error.
+type Error struct {
+	Code Code
+	Err  error
+}
+
+// Sentinels for errors.Is, e.g. errors.Is(err, errcode.ErrConflict)
+var (
+	ErrUsage              = &Error{Code: Usage}
+	ErrTemplateNotFound   = &Error{Code: TemplateNotFound}
+	ErrTemplateInvalid    = &Error{Code: TemplateInvalid}
+	ErrVariableInvalid    = &Error{Code: VariableInvalid}
+	ErrValidation         = &Error{Code: Validation}
+	ErrConflict           = &Error{Code: Conflict}
+	ErrHookFailed         = &Error{Code: HookFailed}
+	ErrRequirementMissing = &Error{Code: RequirementMissing}
+	ErrSmokeTestFailed    = &Error{Code: SmokeTestFailed}
+	ErrScanFailed         = &Error{Code: ScanFailed}
+)
+
+// New returns err classified with code, or nil if err is nil
+func New(code Code, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Code: code, Err: err}
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return string(e.Code)
+	}
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is the sentinel for the code of e
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Err == nil && t.Code == e.Code
+}
+
+// Of returns the code of the outermost classified error in err's chain,
+// or Unknown if there is none
+func Of(err error) Code {
+	var coded *Error
+	if errors.As(err, &coded) {
+		return coded.Code
+	}
+	return Unknown
+}
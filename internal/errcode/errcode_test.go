@@ -0,0 +1,48 @@
+package errcode
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestErrorCodes(t *testing.T) {
+	base := errors.New("directory 'demo' already exists")
+	wrapped := fmt.Errorf("failed to generate project: %w", New(Conflict, base))
+
+	tests := []struct {
+		name     string
+		err      error
+		wantCode Code
+		is       error
+		isNot    error
+	}{
+		{name: "wrapped", err: wrapped, wantCode: Conflict, is: ErrConflict, isNot: ErrHookFailed},
+		{name: "plain error", err: base, wantCode: Unknown, isNot: ErrConflict},
+		{name: "nil", err: nil, wantCode: Unknown, isNot: ErrConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Of(tt.err); got != tt.wantCode {
+				t.Errorf("Of() = %s, want %s", got, tt.wantCode)
+			}
+			if tt.is != nil && !errors.Is(tt.err, tt.is) {
+				t.Errorf("errors.Is(%v, %v) = false, want true", tt.err, tt.is)
+			}
+			if errors.Is(tt.err, tt.isNot) {
+				t.Errorf("errors.Is(%v, %v) = true, want false", tt.err, tt.isNot)
+			}
+		})
+	}
+
+	if New(Conflict, nil) != nil {
+		t.Error("New() of nil error should be nil")
+	}
+	if !errors.Is(wrapped, base) {
+		t.Error("classified error should still wrap the original error")
+	}
+	if wrapped.Error() != "failed to generate project: directory 'demo' already exists" {
+		t.Errorf("Error() = %q, code must not change the message", wrapped.Error())
+	}
+}
@@ -0,0 +1,180 @@
+package generator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/renan-dev/devinit/internal/output"
+)
+
+// writeLargeTree writes templates lang<i%10>/fw<i> with files/
+// directories of filesPerTemplate static files spread over nested folders,
+// like a big template store
+func writeLargeTree(tb testing.TB, templates, filesPerTemplate int) string {
+	tb.Helper()
+	dir := tb.TempDir()
+	for i := 0; i < templates; i++ {
+		templateDir := filepath.Join(dir, fmt.Sprintf("lang%d", i%10), fmt.Sprintf("fw%d", i))
+		metadata := fmt.Sprintf("version: \"1.0.0\"\nname: fw%d\nlanguage: lang%d\nframework: fw%d\nfiles:\n  - src: readme.txt\n    dest: README.txt\n", i, i%10, i)
+		for f := 0; f < filesPerTemplate; f++ {
+			path := filepath.Join(templateDir, "files", fmt.Sprintf("pkg%d", f%8), fmt.Sprintf("sub%d", f%3), fmt.Sprintf("file%d.txt", f))
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				tb.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte("static\n"), 0644); err != nil {
+				tb.Fatal(err)
+			}
+		}
+		if err := os.WriteFile(filepath.Join(templateDir, "files", "readme.txt"), []byte("readme\n"), 0644); err != nil {
+			tb.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(templateDir, "template.yaml"), []byte(metadata), 0644); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// writeRenderTemplate writes test/basic with n rendered files of roughly
+// size bytes each, full of actions and branches
+func writeRenderTemplate(tb testing.TB, n, size int) string {
+	tb.Helper()
+	dir := tb.TempDir()
+
+	chunk := "{{ .ProjectName }} {{ .ProjectNamePascal }} {{ if .IncludeDocker }}docker{{ else }}plain{{ end }} {{ snake .ProjectName }}\n"
+	content := strings.Repeat(chunk, max(1, size/len(chunk)))
+
+	var metadata strings.Builder
+	metadata.WriteString("version: \"1.0.0\"\nname: basic\nlanguage: test\nframework: basic\nfiles:\n")
+	files := make(map[string]string, n)
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("file%d.txt.tmpl", i)
+		fmt.Fprintf(&metadata, "  - src: %s\n    dest: out/file%d.txt\n", name, i)
+		files[name] = content
+	}
+	writeTestTemplate(tb, dir, metadata.String(), files)
+	return dir
+}
+
+func BenchmarkListTemplates(b *testing.B) {
+	gen := NewGenerator(writeLargeTree(b, 200, 100))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := gen.ListTemplates(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRenderFiles(b *testing.B) {
+	for _, bb := range []struct {
+		name  string
+		files int
+		size  int
+	}{
+		{name: "many-small", files: 200, size: 1 << 10},
+		{name: "few-large", files: 4, size: 1 << 20},
+	} {
+		b.Run(bb.name, func(b *testing.B) {
+			gen := NewGenerator(writeRenderTemplate(b, bb.files, bb.size))
+			tmpl, err := gen.GetTemplate("test/basic")
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.SetBytes(int64(bb.files * bb.size))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := gen.RenderFiles(tmpl, "demo", nil); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkGenerate(b *testing.B) {
+	gen := NewGenerator(writeRenderTemplate(b, 50, 4<<10))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := gen.Generate(&Options{
+			ProjectName: "demo",
+			Language:    "test",
+			Framework:   "basic",
+			OutputDir:   "demo",
+			Output:      output.NewMemory(),
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// TestPerformanceBudget keeps allocation counts, which unlike timings are
+// stable across machines, within budgets set about 50% above what
+// generation needs today. Raise a budget only with a reason in the commit.
+func TestPerformanceBudget(t *testing.T) {
+	listGen := NewGenerator(writeLargeTree(t, 20, 100))
+	renderGen := NewGenerator(writeRenderTemplate(t, 20, 1<<10))
+	tmpl, err := renderGen.GetTemplate("test/basic")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		budget float64
+		run    func() error
+	}{
+		{
+			name:   "list 20 templates",
+			budget: 400,
+			run: func() error {
+				_, err := listGen.ListTemplates()
+				return err
+			},
+		},
+		{
+			name:   "render 20 files",
+			budget: 18000,
+			run: func() error {
+				_, err := renderGen.RenderFiles(tmpl, "demo", nil)
+				return err
+			},
+		},
+		{
+			name:   "generate 20 files",
+			budget: 19500,
+			run: func() error {
+				_, err := renderGen.Generate(&Options{
+					ProjectName: "demo",
+					Language:    "test",
+					Framework:   "basic",
+					OutputDir:   "demo",
+					Output:      output.NewMemory(),
+				})
+				return err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var runErr error
+			allocs := testing.AllocsPerRun(5, func() {
+				if err := tt.run(); err != nil {
+					runErr = err
+				}
+			})
+			if runErr != nil {
+				t.Fatalf("unexpected error: %v", runErr)
+			}
+			t.Logf("%.0f allocations, budget %.0f", allocs, tt.budget)
+			if allocs > tt.budget {
+				t.Errorf("%.0f allocations exceed the budget of %.0f", allocs, tt.budget)
+			}
+		})
+	}
+}
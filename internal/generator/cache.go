@@ -0,0 +1,144 @@
+package generator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/renan-dev/devinit/internal/template"
+)
+
+// RenderCache keeps rendered file contents on disk, keyed by the template
+// version, the source file and the rendering context, so repeated dry runs
+// and template diffs skip files whose inputs did not change. Entries are
+// never invalidated, only superseded by new keys; deleting the directory is
+// always safe.
+type RenderCache struct {
+	dir string
+}
+
+// NewRenderCache returns a cache stored in dir
+func NewRenderCache(dir string) *RenderCache {
+	return &RenderCache{dir: dir}
+}
+
+// SetRenderCache makes rendering reuse and fill cache (nil disables caching)
+func (g *Generator) SetRenderCache(cache *RenderCache) {
+	g.cache = cache
+}
+
+// renderKey identifies the content of a file spec rendered from source in
+// ctx. The context, including the template metadata, is part of the key as
+// JSON; naming settings are too, as they change helper results.
+func renderKey(ctx *template.Context, naming template.Naming, fileSpec template.FileSpec, source []byte) (string, error) {
+	state, err := json.Marshal(struct {
+		Context    *template.Context
+		Naming     template.Naming
+		Markers    template.MarkerMode
+		Delimiters []string
+	}{ctx, naming, fileSpec.Markers, fileSpec.Delimiters})
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	for _, part := range [][]byte{[]byte(ctx.Template.ID), []byte(ctx.Template.Version), []byte(fileSpec.Source), source, state} {
+		h.Write(part)
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Dir returns the directory of the cache
+func (c *RenderCache) Dir() string {
+	return c.dir
+}
+
+// Clean removes the entries of the cache and returns the bytes freed. Other
+// files are left alone, as the directory may be shared (cache.dir).
+func (c *RenderCache) Clean() (int64, error) {
+	dirs, err := os.ReadDir(c.dir)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var freed int64
+	for _, dir := range dirs {
+		if !dir.IsDir() || !isHex(dir.Name(), 2) {
+			continue
+		}
+		path := filepath.Join(c.dir, dir.Name())
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return freed, err
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if !entry.Type().IsRegular() || !(isHex(name, sha256.Size*2) || strings.HasPrefix(name, ".tmp-")) {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil {
+				return freed, err
+			}
+			if err := os.Remove(filepath.Join(path, name)); err != nil {
+				return freed, err
+			}
+			freed += info.Size()
+		}
+		os.Remove(path) // kept if anything else is in it
+	}
+	return freed, nil
+}
+
+// isHex reports whether s is n hexadecimal digits, like the keys and key
+// prefixes naming cache entries
+func isHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil && s == strings.ToLower(s)
+}
+
+func (c *RenderCache) path(key string) string {
+	return filepath.Join(c.dir, key[:2], key)
+}
+
+// get returns the cached content for key
+func (c *RenderCache) get(key string) ([]byte, bool) {
+	data, err := os.ReadFile(c.path(key))
+	return data, err == nil
+}
+
+// put stores content under key. Failures are ignored: the cache only saves
+// time.
+func (c *RenderCache) put(key string, data []byte) {
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+
+	// Write to a temporary file first so concurrent runs never read a
+	// partial entry
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+}
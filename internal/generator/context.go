@@ -0,0 +1,211 @@
+package generator
+
+import "github.com/renan-dev/devinit/internal/template"
+
+// Sources of the variables of a ContextReport
+const (
+	SourceVar      = "var"      // set with --var or a flag
+	SourceProfile  = "profile"  // preset by the selected profile
+	SourceTemplate = "template" // the template's default
+	SourceDevinit  = "devinit"  // a flag left at its default, or derived by devinit
+	SourceUnset    = "unset"    // declared without a default and not set
+)
+
+// ContextReport is the context the template selected by some options renders
+// with: the computed names, the variables with where their values come from,
+// and the conditions of files, requirements and hooks evaluated. It helps
+// template authors see why a condition or value is not what they expect.
+type ContextReport struct {
+	Template string `yaml:"template" json:"template"`
+	Version  string `yaml:"version" json:"version"`
+
+	ProjectName         string `yaml:"project_name" json:"project_name"`
+	ProjectNameSnake    string `yaml:"project_name_snake" json:"project_name_snake"`
+	ProjectNameCamel    string `yaml:"project_name_camel" json:"project_name_camel"`
+	ProjectNamePascal   string `yaml:"project_name_pascal" json:"project_name_pascal"`
+	ProjectNameKebab    string `yaml:"project_name_kebab" json:"project_name_kebab"`
+	ProjectNameConstant string `yaml:"project_name_constant" json:"project_name_constant"`
+
+	// Variables are {{ .Variables.name }}; declared variables without a
+	// value are null
+	Variables map[string]interface{} `yaml:"variables" json:"variables"`
+	Sources   map[string]string      `yaml:"sources" json:"sources"`
+
+	// Fields are the common fields of the context ({{ .Port }}, ...)
+	Fields ContextFields `yaml:"fields" json:"fields"`
+
+	Environment template.Environment `yaml:"environment" json:"environment"`
+
+	Files        []FileConditions      `yaml:"files" json:"files"`
+	Requirements []ConditionValue      `yaml:"requirements,omitempty" json:"requirements,omitempty"`
+	Hooks        []HookConditionReport `yaml:"hooks,omitempty" json:"hooks,omitempty"`
+}
+
+// ContextFields are the common fields of a context
+type ContextFields struct {
+	PythonVersion  string `yaml:"python_version" json:"python_version"`
+	IncludeDocker  bool   `yaml:"include_docker" json:"include_docker"`
+	Database       string `yaml:"database" json:"database"`
+	IncludeTests   bool   `yaml:"include_tests" json:"include_tests"`
+	CIProvider     string `yaml:"ci_provider" json:"ci_provider"`
+	Observability  bool   `yaml:"observability" json:"observability"`
+	APIDocs        bool   `yaml:"api_docs" json:"api_docs"`
+	Profile        string `yaml:"profile" json:"profile"`
+	DockerBase     string `yaml:"docker_base" json:"docker_base"`
+	Image          string `yaml:"image" json:"image"`
+	Port           int    `yaml:"port" json:"port"`
+	PackageManager string `yaml:"package_manager" json:"package_manager"`
+}
+
+// FileConditions is a file spec with its destination rendered and its
+// conditions evaluated
+type FileConditions struct {
+	Source      string           `yaml:"src" json:"src"`
+	Destination string           `yaml:"dest" json:"dest"`
+	Generated   bool             `yaml:"generated" json:"generated"`
+	Conditions  []ConditionValue `yaml:"conditions,omitempty" json:"conditions,omitempty"`
+	Error       string           `yaml:"error,omitempty" json:"error,omitempty"`
+}
+
+// ConditionValue is an evaluated condition; conditions that fail to
+// evaluate are false, with the error
+type ConditionValue struct {
+	Name      string `yaml:"name,omitempty" json:"name,omitempty"` // of requirements, their command
+	Condition string `yaml:"condition" json:"condition"`
+	Value     bool   `yaml:"value" json:"value"`
+	Error     string `yaml:"error,omitempty" json:"error,omitempty"`
+}
+
+// HookConditionReport is a hook with its when condition evaluated
+type HookConditionReport struct {
+	Name string `yaml:"name" json:"name"`
+	// Stage is pre_generate or post_generate
+	Stage string `yaml:"stage" json:"stage"`
+	When  string `yaml:"when,omitempty" json:"when,omitempty"`
+	Runs  bool   `yaml:"runs" json:"runs"`
+	Error string `yaml:"error,omitempty" json:"error,omitempty"`
+}
+
+// DescribeContext returns the context generation with opts renders the
+// template with. Nothing is rendered but destinations and conditions.
+func (g *Generator) DescribeContext(opts *Options) (*ContextReport, error) {
+	tmpl, ctx, err := g.resolve(opts)
+	if err != nil {
+		return nil, err
+	}
+	profileVars, err := profileVariables(tmpl, opts.Profile)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ContextReport{
+		Template:            tmpl.ID,
+		Version:             tmpl.Version,
+		ProjectName:         ctx.ProjectName,
+		ProjectNameSnake:    ctx.ProjectNameSnake,
+		ProjectNameCamel:    ctx.ProjectNameCamel,
+		ProjectNamePascal:   ctx.ProjectNamePascal,
+		ProjectNameKebab:    ctx.ProjectNameKebab,
+		ProjectNameConstant: ctx.ProjectNameConstant,
+		Variables:           make(map[string]interface{}, len(ctx.Variables)),
+		Sources:             make(map[string]string, len(ctx.Variables)),
+		Fields: ContextFields{
+			PythonVersion:  ctx.PythonVersion,
+			IncludeDocker:  ctx.IncludeDocker,
+			Database:       ctx.Database,
+			IncludeTests:   ctx.IncludeTests,
+			CIProvider:     ctx.CIProvider,
+			Observability:  ctx.Observability,
+			APIDocs:        ctx.APIDocs,
+			Profile:        ctx.Profile,
+			DockerBase:     ctx.DockerBase,
+			Image:          ctx.Image,
+			Port:           ctx.Port,
+			PackageManager: ctx.PackageManager,
+		},
+		Environment: ctx.Environment,
+	}
+
+	for key, value := range ctx.Variables {
+		report.Variables[key] = value
+		switch _, declared := tmpl.Variables[key]; {
+		case hasKey(opts.Variables, key):
+			report.Sources[key] = SourceVar
+		case hasKey(profileVars, key):
+			report.Sources[key] = SourceProfile
+		case declared && tmpl.Variables[key].Default != nil:
+			report.Sources[key] = SourceTemplate
+		default:
+			report.Sources[key] = SourceDevinit
+		}
+	}
+	for key := range tmpl.Variables {
+		if _, ok := ctx.Variables[key]; !ok {
+			report.Variables[key] = nil
+			report.Sources[key] = SourceUnset
+		}
+	}
+
+	for _, fileSpec := range tmpl.Files {
+		file := FileConditions{Source: fileSpec.Source, Generated: true}
+		if dest, err := g.outputPath(fileSpec, ctx); err != nil {
+			file.Destination, file.Error = fileSpec.Destination, err.Error()
+		} else {
+			file.Destination = dest
+		}
+		for _, condition := range fileSpec.Conditions {
+			value := g.conditionReport("", condition, ctx)
+			file.Generated = file.Generated && value.Value
+			file.Conditions = append(file.Conditions, value)
+		}
+		report.Files = append(report.Files, file)
+	}
+
+	for _, req := range tmpl.Requirements.System {
+		if req.When != "" {
+			report.Requirements = append(report.Requirements, g.conditionReport(req.Command, req.When, ctx))
+		}
+	}
+
+	for _, stage := range []struct {
+		name  string
+		hooks []template.Hook
+	}{
+		{"pre_generate", tmpl.Hooks.PreGenerate},
+		{"post_generate", tmpl.Hooks.PostGenerate},
+	} {
+		sorted, err := template.SortHooks(stage.hooks)
+		if err != nil {
+			return nil, err
+		}
+		for i, hook := range sorted {
+			if hook.Run == "" {
+				continue
+			}
+			h := HookConditionReport{Name: hookLabel(stage.name, i, hook), Stage: stage.name, When: hook.When, Runs: true}
+			if hook.When != "" {
+				value := g.conditionReport("", hook.When, ctx)
+				h.Runs, h.Error = value.Value, value.Error
+			}
+			report.Hooks = append(report.Hooks, h)
+		}
+	}
+
+	return report, nil
+}
+
+// conditionReport evaluates a condition for a ContextReport
+func (g *Generator) conditionReport(name, condition string, ctx *template.Context) ConditionValue {
+	value, err := g.conditionValue(condition, ctx)
+	report := ConditionValue{Name: name, Condition: condition, Value: value}
+	if err != nil {
+		report.Error = err.Error()
+	}
+	return report
+}
+
+// hasKey reports whether m has key
+func hasKey(m map[string]interface{}, key string) bool {
+	_, ok := m[key]
+	return ok
+}
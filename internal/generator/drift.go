@@ -0,0 +1,184 @@
+package generator
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/renan-dev/devinit/internal/dbschema"
+	"github.com/renan-dev/devinit/internal/i18n"
+	"github.com/renan-dev/devinit/internal/ignore"
+	"github.com/renan-dev/devinit/internal/openapi"
+	"github.com/renan-dev/devinit/internal/proto"
+	"github.com/renan-dev/devinit/internal/template"
+	"gopkg.in/yaml.v3"
+)
+
+// MetadataFile is written to the root of every generated project
+const MetadataFile = ".devinit.yaml"
+
+// Metadata records how a project was generated
+type Metadata struct {
+	SchemaVersion string `yaml:"schema_version"`
+	Template      struct {
+		Name    string `yaml:"name"`
+		Version string `yaml:"version"`
+	} `yaml:"template"`
+	Variables map[string]interface{} `yaml:"variables"`
+
+	// Environment the project was generated in, so drift checks render
+	// {{ .Year }}, {{ .User }} and the like as they were
+	Environment *template.Environment `yaml:"environment,omitempty"`
+
+	// Provenance records that generated files got provenance headers
+	Provenance bool `yaml:"provenance,omitempty"`
+
+	// OpenAPI and Proto are the contracts the project was scaffolded from,
+	// so drift checks render the files they produced
+	OpenAPI *openapi.Spec `yaml:"openapi,omitempty"`
+	Proto   *proto.File   `yaml:"proto,omitempty"`
+
+	// DBSchema is the database schema the project was scaffolded from; the
+	// URL it was read from is not recorded
+	DBSchema *dbschema.Schema `yaml:"db_schema,omitempty"`
+
+	// Overrides are the template files replaced by the project's overrides
+	// (.devinit/templates) when it was generated
+	Overrides []string `yaml:"overrides,omitempty"`
+
+	// Hooks are the lifecycle hooks of the template, which devinit hooks run
+	// runs again
+	Hooks *template.Hooks `yaml:"hooks,omitempty"`
+}
+
+// siblingExcluded are the recorded variables that belong to the recorded
+// project alone: its name, image and port
+var siblingExcluded = map[string]bool{"ProjectName": true, "Image": true, "Port": true}
+
+// SiblingVariables returns the recorded variables a sibling project
+// generated from the same template shares, leaving out those that name or
+// address the recorded project
+func (m *Metadata) SiblingVariables() map[string]interface{} {
+	variables := make(map[string]interface{}, len(m.Variables))
+	for key, value := range m.Variables {
+		if !siblingExcluded[key] {
+			variables[key] = value
+		}
+	}
+	return variables
+}
+
+// marshalMetadata encodes the metadata of a project generated from tmpl
+func marshalMetadata(ctx *template.Context, tmpl *template.Template, provenance bool) ([]byte, error) {
+	metadata := Metadata{SchemaVersion: "1.0", Variables: ctx.Variables, Environment: &ctx.Environment, Provenance: provenance, OpenAPI: ctx.OpenAPI, Proto: ctx.Proto, DBSchema: ctx.DBSchema}
+	metadata.Template.Name = tmpl.Language + "/" + tmpl.Framework
+	metadata.Template.Version = tmpl.Version
+	metadata.Overrides = tmpl.OverriddenSources()
+	if len(tmpl.Hooks.PreGenerate)+len(tmpl.Hooks.PostGenerate) > 0 {
+		metadata.Hooks = &tmpl.Hooks
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(metadata); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ReadMetadata reads the metadata of the project in dir
+func ReadMetadata(dir string) (*Metadata, error) {
+	data, err := os.ReadFile(filepath.Join(dir, MetadataFile))
+	if err != nil {
+		return nil, i18n.Errorf("generator.read_project_file", MetadataFile, err)
+	}
+
+	var metadata Metadata
+	if err := yaml.Unmarshal(data, &metadata); err != nil {
+		return nil, i18n.Errorf("generator.read_project_file", MetadataFile, err)
+	}
+	return &metadata, nil
+}
+
+// Drift states
+const (
+	DriftMissing  = "missing"
+	DriftModified = "modified"
+)
+
+// DriftEntry is a generated file that no longer matches its template
+type DriftEntry struct {
+	Path   string `json:"path"`
+	Status string `json:"status"`
+}
+
+// SetManaged limits drift checks to the generated files m matches, such as
+// those a project's configuration lists as managed. nil checks them all.
+func (g *Generator) SetManaged(m *ignore.Matcher) {
+	g.managed = m
+}
+
+// Drift renders the template version recorded in the metadata of the project
+// in dir with the recorded variables, environment and options, and reports the generated files that
+// were deleted or changed since. Paths matched by the project's
+// .devinitignore are user-owned and skipped, as are paths left out of the
+// managed files (see SetManaged).
+func (g *Generator) Drift(dir string) (*Metadata, []DriftEntry, error) {
+	metadata, err := ReadMetadata(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ignored, err := ignore.Load(filepath.Join(dir, ignore.FileName))
+	if err != nil {
+		return nil, nil, i18n.Errorf("generator.read_project_file", ignore.FileName, err)
+	}
+
+	tmpl, err := g.GetTemplateVersion(metadata.Template.Name, metadata.Template.Version)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := g.checkLock(dir, tmpl); err != nil {
+		return nil, nil, err
+	}
+
+	projectName, _ := metadata.Variables["ProjectName"].(string)
+	if projectName == "" {
+		projectName = filepath.Base(dir)
+	}
+
+	files, err := g.renderFiles(tmpl, projectName, metadata.Variables, metadata)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var drift []DriftEntry
+	for _, path := range paths {
+		if ignored.Match(path, false) || g.managed != nil && !g.managed.Match(path, false) {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(path)))
+		switch {
+		case os.IsNotExist(err):
+			drift = append(drift, DriftEntry{Path: path, Status: DriftMissing})
+		case err != nil:
+			return nil, nil, err
+		case !bytes.Equal(data, files[path]):
+			drift = append(drift, DriftEntry{Path: path, Status: DriftModified})
+		}
+	}
+
+	return metadata, drift, nil
+}
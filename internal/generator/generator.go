@@ -1,18 +1,54 @@
 package generator
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
-
+	"time"
+
+	"github.com/renan-dev/devinit/internal/dbschema"
+	"github.com/renan-dev/devinit/internal/errcode"
+	"github.com/renan-dev/devinit/internal/i18n"
+	"github.com/renan-dev/devinit/internal/ignore"
+	"github.com/renan-dev/devinit/internal/network"
+	"github.com/renan-dev/devinit/internal/openapi"
+	"github.com/renan-dev/devinit/internal/output"
+	"github.com/renan-dev/devinit/internal/proto"
+	"github.com/renan-dev/devinit/internal/retry"
 	"github.com/renan-dev/devinit/internal/template"
+	"github.com/renan-dev/devinit/internal/validator"
+	"github.com/renan-dev/devinit/internal/vulnscan"
+	"github.com/renan-dev/devinit/internal/workspace"
 )
 
-// Generator generates projects from templates
+// identifierPattern matches a plain (optionally dot-prefixed) variable name
+var identifierPattern = regexp.MustCompile(`^\.?[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Generator generates projects from templates. Once configured with its
+// setters, which must not run concurrently with generation, a Generator is
+// safe for concurrent use: state belonging to a single generation, such as
+// its branch recorder, lives in Options, and every generation loads its own
+// copy of the template.
 type Generator struct {
 	loader   *template.Loader
 	renderer *template.Renderer
+	retry    retry.Policy
+	network  network.Settings
+	cache    *RenderCache
+	version  string
+
+	// updateLock accepts templates that changed since a project's lockfile
+	updateLock bool
+
+	// managed matches the generated files drift checks compare (nil: all)
+	managed *ignore.Matcher
 }
 
 // NewGenerator creates a new project generator
@@ -20,9 +56,45 @@ func NewGenerator(templatesDir string) *Generator {
 	return &Generator{
 		loader:   template.NewLoader(templatesDir),
 		renderer: template.NewRenderer(),
+		retry:    retry.Default(),
 	}
 }
 
+// SetNaming configures how ports, database and image names are derived from
+// project names
+func (g *Generator) SetNaming(naming template.Naming) {
+	g.renderer.SetNaming(naming)
+}
+
+// SetVersion sets the devinit version templates see as {{ .DevinitVersion }}
+func (g *Generator) SetVersion(version string) {
+	g.version = version
+}
+
+// SetNetwork configures the CA bundle and TLS verification passed on to
+// hook commands
+func (g *Generator) SetNetwork(settings network.Settings) {
+	g.network = settings
+}
+
+// SetOverlay layers a project's templates directory over the templates
+// directory, for project-local templates and file overrides (see
+// template.Loader.SetOverlay)
+func (g *Generator) SetOverlay(dir string) {
+	g.loader.SetOverlay(dir)
+}
+
+// SetFallback sets the store searched for template versions the templates
+// directory lacks (see template.Loader.SetFallback)
+func (g *Generator) SetFallback(fallback func() string) {
+	g.loader.SetFallback(fallback)
+}
+
+// SetRetryPolicy configures how hooks marked network: true are retried
+func (g *Generator) SetRetryPolicy(policy retry.Policy) {
+	g.retry = policy
+}
+
 // Options for project generation
 type Options struct {
 	ProjectName string
@@ -31,99 +103,546 @@ type Options struct {
 	OutputDir   string
 	Variables   map[string]interface{}
 	DryRun      bool
+
+	// TemplateVersion selects a version of the template; the current one
+	// when empty
+	TemplateVersion string
+
+	// Profile selects one of the template's named variable presets
+	Profile string
+
+	// Defaults hold values (such as unset CLI flags) that apply only when
+	// neither the template defaults, the profile nor Variables provide one
+	Defaults map[string]interface{}
+
+	// RejectDeprecated fails generation for deprecated templates instead of warning
+	RejectDeprecated bool
+
+	// SkipHooks disables pre/post generation hooks
+	SkipHooks bool
+
+	// OpenAPI is the contract templates scaffold routes and models from
+	// (nil if none)
+	OpenAPI *openapi.Spec
+
+	// Proto is the contract templates scaffold handler stubs and clients
+	// from (nil if none)
+	Proto *proto.File
+
+	// DBSchema is the database templates scaffold ORM models from (nil if
+	// none)
+	DBSchema *dbschema.Schema
+
+	// SkipWorkspace generates into a devinit workspace without wiring the
+	// service into it or exposing its sibling services
+	SkipWorkspace bool
+
+	// SkipValidation disables variable validation (--no-validate)
+	SkipValidation bool
+
+	// StrictValidation also checks that variable values match their declared
+	// types (--strict)
+	StrictValidation bool
+
+	// HookOutput additionally receives stdout/stderr of hook commands as they
+	// run. Hook output is always logged to .devinit/logs in the project.
+	HookOutput io.Writer
+
+	// RetryAttempts overrides the number of attempts of network hooks (0
+	// keeps the configured policy)
+	RetryAttempts int
+
+	// Progress receives retry notices such as "attempt 2/3"; nil discards them
+	Progress io.Writer
+
+	// BranchRecorder, when set, receives the branches taken while rendering
+	// template files, e.g. to measure test coverage. Rendered files bypass
+	// the render cache while recording.
+	BranchRecorder template.BranchRecorder
+
+	// Provenance prepends a comment naming devinit and the template to the
+	// generated text files, also when the template does not ask for it
+	Provenance bool
+
+	// SmokeTest runs the template's smoke test after generation ("" skips
+	// it). It needs the project on disk and is ignored on dry runs and with
+	// a custom Output.
+	SmokeTest SmokeMode
+
+	// Scan runs vulnerability scanners against the generated project or its
+	// image ("" skips it). Like SmokeTest, it needs the project on disk.
+	Scan ScanTarget
+
+	// ScanFailOn is the severity findings must reach to fail the scan
+	// (default high; vulnscan.SeverityNone never fails)
+	ScanFailOn vulnscan.Severity
+
+	// Output receives the generated files instead of OutputDir, e.g. an
+	// archive or memory. Hooks need a directory and do not run with a custom
+	// Output; OutputDir still names the project in results.
+	Output output.Backend
 }
 
-// Generate creates a new project from a template
-func (g *Generator) Generate(opts *Options) error {
-	// Construct template name
+// ValidationLevel returns the validation level selected by the options
+func (o *Options) ValidationLevel() validator.ValidationLevel {
+	switch {
+	case o.SkipValidation:
+		return validator.ValidationNone
+	case o.StrictValidation:
+		return validator.ValidationStrict
+	default:
+		return validator.ValidationBasic
+	}
+}
+
+// forGeneration returns the generator a generation with opts uses: g, or a
+// copy of it whose renderer reports to the branch recorder of opts, so that
+// concurrent generations never share one
+func (g *Generator) forGeneration(opts *Options) *Generator {
+	if opts.BranchRecorder == nil {
+		return g
+	}
+	copied := *g
+	copied.renderer = g.renderer.WithBranchRecorder(opts.BranchRecorder)
+	return &copied
+}
+
+// resolve loads the template selected by opts and builds the rendering
+// context from the merged variables
+func (g *Generator) resolve(opts *Options) (*template.Template, *template.Context, error) {
 	templateName := fmt.Sprintf("%s/%s", opts.Language, opts.Framework)
 
-	// Load template
-	tmpl, err := g.loader.Load(templateName)
+	tmpl, err := g.loader.LoadVersion(templateName, opts.TemplateVersion)
 	if err != nil {
-		return fmt.Errorf("failed to load template: %w", err)
+		return nil, nil, i18n.Errorf("generator.load_template", err)
 	}
 
-	// Merge options with template variables
-	variables := g.mergeVariables(tmpl, opts.Variables)
+	profileVars, err := profileVariables(tmpl, opts.Profile)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	// Create context
+	// Merge options with template variables
+	variables := g.mergeVariables(tmpl, opts.Defaults, profileVars, opts.Variables)
 	outputDir := opts.OutputDir
 	if outputDir == "" {
 		outputDir = opts.ProjectName
 	}
+	if err := g.evaluateDefaults(tmpl, opts.ProjectName, outputDir, variables); err != nil {
+		return nil, nil, err
+	}
+	if _, ok := variables["Port"]; !ok && tmpl.Healthcheck != nil && tmpl.Healthcheck.Port != 0 {
+		variables["Port"] = tmpl.Healthcheck.Port
+	}
+
+	ctx := g.newContext(opts.ProjectName, outputDir, variables, tmpl)
+	ctx.OpenAPI = opts.OpenAPI
+	ctx.Proto = opts.Proto
+	ctx.DBSchema = opts.DBSchema
+	return tmpl, ctx, nil
+}
 
-	ctx := template.NewContext(opts.ProjectName, outputDir, variables, tmpl)
+// Requirements returns the system requirements of the template selected by
+// opts whose when condition holds for the options' variables
+func (g *Generator) Requirements(opts *Options) ([]validator.Requirement, error) {
+	tmpl, ctx, err := g.resolve(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var reqs []validator.Requirement
+	for _, sysReq := range tmpl.Requirements.System {
+		if sysReq.When != "" && !g.evaluateCondition(sysReq.When, ctx) {
+			continue
+		}
+		reqs = append(reqs, validator.FromTemplateRequirement(sysReq))
+	}
+
+	return reqs, nil
+}
+
+// Checks returns the doctor checks of the template selected by opts whose
+// when condition holds for the options' variables
+func (g *Generator) Checks(opts *Options) ([]validator.Check, error) {
+	tmpl, ctx, err := g.resolve(opts)
+	if err != nil {
+		return nil, err
+	}
 
-	// Create project directory
-	if !opts.DryRun {
+	var checks []validator.Check
+	for _, check := range tmpl.Requirements.Checks {
+		if check.When != "" && !g.evaluateCondition(check.When, ctx) {
+			continue
+		}
+		checks = append(checks, validator.FromTemplateCheck(check, tmpl.Path))
+	}
+
+	return checks, nil
+}
+
+// Generate creates a new project from a template
+func (g *Generator) Generate(opts *Options) (*GenerationResult, error) {
+	start := time.Now()
+	g = g.forGeneration(opts)
+
+	tmpl, ctx, err := g.resolve(opts)
+	if err != nil {
+		return nil, err
+	}
+	templateName := tmpl.ID
+	outputDir := ctx.OutputDir
+
+	deprecation := tmpl.DeprecationNotice()
+	if deprecation != "" && opts.RejectDeprecated {
+		return nil, i18n.Errorf("generator.rejected", deprecation)
+	}
+
+	if err := ValidateVariables(tmpl, ctx.Variables, opts.ValidationLevel()); err != nil {
+		return nil, err
+	}
+	if err := CheckVariableRefs(tmpl, ctx.Variables); err != nil {
+		return nil, err
+	}
+
+	result := &GenerationResult{
+		Template:        tmpl,
+		OutputDir:       outputDir,
+		DryRun:          opts.DryRun,
+		TemplateName:    templateName,
+		TemplateVersion: tmpl.Version,
+		Deprecation:     deprecation,
+		Files:           []FileResult{},
+		Hooks:           []HookResult{},
+		Warnings:        []string{},
+	}
+
+	out := opts.Output
+	switch {
+	case opts.DryRun:
+		out = output.NewRecorder()
+	case out == nil:
+		out = output.NewDir(outputDir)
+
+		// Create project directory
 		if err := os.MkdirAll(outputDir, 0755); err != nil {
-			return fmt.Errorf("failed to create project directory: %w", err)
+			return nil, i18n.Errorf("generator.create_project_dir", err)
+		}
+	}
+
+	_, toDir := out.(*output.Dir)
+	var ws *workspace.Workspace
+	if !opts.SkipWorkspace {
+		ws = g.joinWorkspace(ctx, result)
+	}
+
+	runHooks := toDir && !opts.SkipHooks && len(tmpl.Hooks.PreGenerate)+len(tmpl.Hooks.PostGenerate) > 0
+	smokeTest := toDir && opts.SmokeTest != ""
+	scan := toDir && opts.Scan != ""
+	var log *hookLog
+	if runHooks || smokeTest || scan {
+		log, err = openHookLog(outputDir, opts.HookOutput, start)
+		if err != nil {
+			return nil, i18n.Errorf("generator.hook_log", err)
+		}
+		defer log.Close()
+		result.HookLog = log.path
+	}
+	if runHooks {
+		if err := g.runHooks("pre_generate", tmpl.Hooks.PreGenerate, ctx, opts, log, result); err != nil {
+			return nil, err
 		}
 	}
 
 	// Generate files
-	filesDir := g.loader.GetFilesDir(tmpl)
+	staged := newStagedFiles(out)
+	provenance := opts.Provenance || tmpl.Provenance
 	for _, fileSpec := range tmpl.Files {
 		// Check if file should be generated based on conditions
 		if !g.shouldGenerateFile(fileSpec, ctx) {
-			if opts.DryRun {
-				fmt.Printf("Skipped: %s (conditions not met)\n", fileSpec.Destination)
+			dest, err := g.outputPath(fileSpec, ctx)
+			if err != nil {
+				return nil, err
 			}
+			result.Files = append(result.Files, FileResult{
+				Source:     fileSpec.Source,
+				Path:       filepath.Join(outputDir, dest),
+				Action:     FileActionSkipped,
+				Conditions: g.unmetConditions(fileSpec, ctx),
+			})
 			continue
 		}
 
-		if err := g.generateFile(filesDir, fileSpec, ctx, opts.DryRun); err != nil {
-			return fmt.Errorf("failed to generate file %s: %w", fileSpec.Destination, err)
+		fileResult, err := g.generateFile(tmpl, fileSpec, ctx, staged, opts.DryRun, provenance)
+		if err != nil {
+			return nil, i18n.Errorf("generator.generate_file", fileSpec.Destination, err)
 		}
+		result.Files = append(result.Files, *fileResult)
+	}
+	if err := staged.flush(); err != nil {
+		return nil, err
 	}
 
-	if !opts.DryRun {
-		// Create .devinit.yaml metadata file
-		if err := g.createMetadataFile(ctx, tmpl); err != nil {
-			return fmt.Errorf("failed to create metadata file: %w", err)
+	// Create .devinit.yaml metadata file
+	if err := g.createMetadataFile(ctx, tmpl, provenance, out); err != nil {
+		return nil, i18n.Errorf("generator.create_metadata", err)
+	}
+	if tmpl.Origin != nil {
+		if err := g.createLockFile(tmpl, out); err != nil {
+			return nil, i18n.Errorf("generator.create_metadata", err)
 		}
 	}
 
-	return nil
+	// Post-generate hooks may start the workspace, so it is wired first
+	if ws != nil && toDir {
+		result.Workspace = g.wireWorkspace(ws, ctx, result)
+	}
+
+	if runHooks {
+		if err := g.runHooks("post_generate", tmpl.Hooks.PostGenerate, ctx, opts, log, result); err != nil {
+			return nil, err
+		}
+	}
+
+	// Hooks may have installed dependencies, so lockfiles are there now
+	if toDir {
+		result.SBOM = g.writeSBOM(ctx, result)
+	}
+	if scan {
+		result.Scan = g.scan(ctx, opts.Scan, opts.ScanFailOn, log, result)
+	}
+
+	if smokeTest {
+		result.SmokeTest, err = g.smokeTest(tmpl, ctx, opts.SmokeTest, log)
+		if err != nil {
+			return nil, i18n.Errorf("generator.smoke_failed", err)
+		}
+	}
+
+	if tmpl.Healthcheck != nil {
+		healthcheck, err := g.renderHealthcheck(tmpl.Healthcheck, ctx)
+		if err != nil {
+			return nil, errcode.New(errcode.TemplateInvalid, i18n.Errorf("generator.render_healthcheck", err))
+		}
+		result.Healthcheck = healthcheck
+	}
+
+	nextSteps, err := g.renderNextSteps(tmpl, ctx)
+	if err != nil {
+		return nil, errcode.New(errcode.TemplateInvalid, i18n.Errorf("generator.render_next_steps", err))
+	}
+	result.NextSteps = nextSteps
+	result.Duration = time.Since(start)
+
+	return result, nil
+}
+
+// generateFile generates a single file from template into staged. Planned
+// files (dry runs) are reported as such; provenance adds provenance headers.
+func (g *Generator) generateFile(tmpl *template.Template, fileSpec template.FileSpec, ctx *template.Context, staged *stagedFiles, planned, provenance bool) (*FileResult, error) {
+	rendered := g.renderer.ShouldRender(fileSpec.Source)
+	_, overridden := tmpl.SourcePath(fileSpec.Source)
+
+	dest, err := g.outputPath(fileSpec, ctx)
+	if err != nil {
+		return nil, err
+	}
+	destPath := filepath.Join(ctx.OutputDir, dest)
+	name := outputName(dest)
+	if err := staged.claim(name, fileSpec); err != nil {
+		return nil, err
+	}
+
+	// Render templates also on dry runs, so errors surface early
+	content, cached, err := g.fileContent(tmpl, fileSpec, ctx)
+	if err != nil {
+		return nil, err
+	}
+	if wantsProvenance(fileSpec, provenance) {
+		content = withProvenance(content, name, ctx)
+	}
+
+	current, exists, err := staged.current(name)
+	if err != nil {
+		return nil, err
+	}
+	content, err = combine(fileSpec, name, current, exists, content)
+	if err != nil {
+		return nil, errcode.New(errcode.TemplateInvalid, err)
+	}
+	previous := staged.stage(name, content, fileSpec.GetPermissions())
+
+	result := &FileResult{
+		Source:     fileSpec.Source,
+		Path:       destPath,
+		Action:     FileActionPlanned,
+		Rendered:   rendered,
+		Cached:     cached,
+		Override:   overridden,
+		Mode:       fileSpec.Mode,
+		Conditions: fileSpec.Conditions,
+		Size:       int64(len(content) - previous),
+	}
+
+	switch {
+	case planned:
+	case exists && fileSpec.Mode != "" && fileSpec.Mode != template.WriteOverwrite:
+		result.Action = FileActionUpdated
+	default:
+		result.Action = FileActionCreated
+	}
+	return result, nil
 }
 
-// generateFile generates a single file from template
-func (g *Generator) generateFile(filesDir string, fileSpec template.FileSpec, ctx *template.Context, dryRun bool) error {
-	sourcePath := filepath.Join(filesDir, fileSpec.Source)
-	destPath := filepath.Join(ctx.OutputDir, fileSpec.Destination)
+// fileContent returns the output content of a file spec, rendering it if
+// needed, from the project's override of the file when there is one.
+// Rendered content comes from the render cache when possible; cached reports
+// whether it did.
+func (g *Generator) fileContent(tmpl *template.Template, fileSpec template.FileSpec, ctx *template.Context) (data []byte, cached bool, err error) {
+	sourcePath, _ := tmpl.SourcePath(fileSpec.Source)
+	source, err := os.ReadFile(sourcePath)
+	if err != nil {
+		return nil, false, i18n.Errorf("generator.read_file", err)
+	}
+	if !g.renderer.ShouldRender(fileSpec.Source) {
+		// Static file
+		data, err = g.applyMarkers(source, fileSpec, ctx)
+		return data, false, err
+	}
 
-	// Check if file should be rendered
-	if g.renderer.ShouldRender(fileSpec.Source) {
-		// Get actual output filename (without .tmpl)
-		actualDest := filepath.Join(ctx.OutputDir, g.renderer.GetOutputFilename(fileSpec.Destination))
+	// Coverage must see every render, so recording bypasses the cache
+	var key string
+	if g.cache != nil && g.renderer.BranchRecorder() == nil {
+		if key, err = renderKey(ctx, g.renderer.Naming(), fileSpec, source); err == nil {
+			if data, ok := g.cache.get(key); ok {
+				return data, true, nil
+			}
+		}
+	}
 
-		if dryRun {
-			fmt.Printf("Would render: %s -> %s\n", fileSpec.Source, actualDest)
-			return nil
+	out, err := g.renderer.RenderTextDelims(sourcePath, string(source), tmpl.FileDelimiters(fileSpec), ctx)
+	if err != nil {
+		// Name the file as in template.yaml rather than by its base name
+		var renderErr *template.RenderError
+		if errors.As(err, &renderErr) {
+			renderErr.File = fileSpec.Source
 		}
+		return nil, false, err
+	}
+	data, err = g.applyMarkers([]byte(out), fileSpec, ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	if key != "" {
+		g.cache.put(key, data)
+	}
+	return data, false, nil
+}
 
-		// Render template
-		if err := g.renderer.RenderToFile(sourcePath, actualDest, ctx, fileSpec.GetPermissions()); err != nil {
-			return err
+// applyMarkers processes the devinit:if markers of a file spec
+func (g *Generator) applyMarkers(data []byte, fileSpec template.FileSpec, ctx *template.Context) ([]byte, error) {
+	data, err := applyMarkers(data, fileSpec.Markers, func(condition string) bool {
+		return g.evaluateCondition(condition, ctx)
+	})
+	if err != nil {
+		return nil, errcode.New(errcode.TemplateInvalid, err)
+	}
+	return data, nil
+}
+
+// RenderFiles renders a template in memory and returns the generated files
+// keyed by their path relative to the project root. Nothing is written to
+// disk and no hooks are run.
+func (g *Generator) RenderFiles(tmpl *template.Template, projectName string, userVars map[string]interface{}) (map[string][]byte, error) {
+	return g.renderFiles(tmpl, projectName, userVars, nil)
+}
+
+// renderFiles is RenderFiles with the environment and options recorded in
+// the metadata of a generated project (nil for the current ones)
+func (g *Generator) renderFiles(tmpl *template.Template, projectName string, userVars map[string]interface{}, recorded *Metadata) (map[string][]byte, error) {
+	variables := g.mergeVariables(tmpl, nil, userVars)
+	if err := g.evaluateDefaults(tmpl, projectName, projectName, variables); err != nil {
+		return nil, err
+	}
+	ctx := g.newContext(projectName, projectName, variables, tmpl)
+	provenance := tmpl.Provenance
+	if recorded != nil {
+		if recorded.Environment != nil {
+			ctx.Environment = *recorded.Environment
 		}
+		ctx.OpenAPI, ctx.Proto, ctx.DBSchema = recorded.OpenAPI, recorded.Proto, recorded.DBSchema
+		provenance = provenance || recorded.Provenance
+	}
 
-		fmt.Printf("Created: %s\n", actualDest)
-	} else {
-		if dryRun {
-			fmt.Printf("Would copy: %s -> %s\n", fileSpec.Source, destPath)
-			return nil
+	files := output.NewMemory()
+	staged := newStagedFiles(files)
+	for _, fileSpec := range tmpl.Files {
+		if !g.shouldGenerateFile(fileSpec, ctx) {
+			continue
 		}
 
-		// Copy static file
-		if err := g.renderer.CopyFile(sourcePath, destPath, fileSpec.GetPermissions()); err != nil {
-			return err
+		dest, err := g.outputPath(fileSpec, ctx)
+		if err != nil {
+			return nil, err
+		}
+		name := outputName(dest)
+		if err := staged.claim(name, fileSpec); err != nil {
+			return nil, err
+		}
+
+		content, _, err := g.fileContent(tmpl, fileSpec, ctx)
+		if err != nil {
+			return nil, errcode.New(errcode.TemplateInvalid, i18n.Errorf("generator.render_file", fileSpec.Destination, err))
+		}
+		if wantsProvenance(fileSpec, provenance) {
+			content = withProvenance(content, name, ctx)
+		}
+		current, exists, err := staged.current(name)
+		if err == nil {
+			content, err = combine(fileSpec, name, current, exists, content)
 		}
+		if err != nil {
+			return nil, errcode.New(errcode.TemplateInvalid, i18n.Errorf("generator.render_file", fileSpec.Destination, err))
+		}
+		staged.stage(name, content, fileSpec.GetPermissions())
+	}
+	if err := staged.flush(); err != nil {
+		return nil, errcode.New(errcode.TemplateInvalid, err)
+	}
+
+	return files.Files(), nil
+}
+
+// outputName converts a destination from outputPath to the slash-separated
+// form output backends take
+func outputName(dest string) string {
+	return path.Clean(filepath.ToSlash(dest))
+}
+
+// newContext creates the rendering context. Unless an image is given, the
+// image reference is derived from the project name and configured registry.
+func (g *Generator) newContext(projectName, outputDir string, variables map[string]interface{}, tmpl *template.Template) *template.Context {
+	ctx := template.NewContext(projectName, outputDir, variables, tmpl)
+	ctx.Environment = template.CurrentEnvironment(g.version)
+	if image, _ := variables["Image"].(string); image == "" {
+		ctx.Image = g.renderer.Naming().ImageName(projectName) + ":latest"
+	}
+	return ctx
+}
 
-		fmt.Printf("Created: %s\n", destPath)
+// outputPath returns the destination of a file spec relative to the project
+// root. Destinations may contain template expressions, e.g.
+// src/{{ .ProjectNameSnake }}/__init__.py, and lose a trailing .tmpl extension.
+func (g *Generator) outputPath(fileSpec template.FileSpec, ctx *template.Context) (string, error) {
+	dest := fileSpec.Destination
+	if strings.Contains(dest, "{{") {
+		rendered, err := g.renderer.RenderString("dest", dest, ctx)
+		if err != nil {
+			return "", errcode.New(errcode.TemplateInvalid, i18n.Errorf("generator.render_destination", fileSpec.Destination, err))
+		}
+		dest = strings.TrimSpace(rendered)
 	}
 
-	return nil
+	return g.renderer.GetOutputFilename(filepath.FromSlash(dest)), nil
 }
 
 // shouldGenerateFile checks if a file should be generated based on its conditions
@@ -141,62 +660,223 @@ func (g *Generator) shouldGenerateFile(fileSpec template.FileSpec, ctx *template
 	return true
 }
 
+// unmetConditions returns the conditions of a file spec that do not hold
+func (g *Generator) unmetConditions(fileSpec template.FileSpec, ctx *template.Context) []string {
+	var unmet []string
+	for _, condition := range fileSpec.Conditions {
+		if !g.evaluateCondition(condition, ctx) {
+			unmet = append(unmet, condition)
+		}
+	}
+	return unmet
+}
+
 // evaluateCondition evaluates a single condition string
-// Supports: {{ .VariableName }}, variable names, and simple expressions
+// Supports: {{ .VariableName }}, variable names, and template expressions
+// such as {{ eq .Database "postgres" }}
 func (g *Generator) evaluateCondition(condition string, ctx *template.Context) bool {
-	condition = strings.TrimSpace(condition)
+	value, _ := g.conditionValue(condition, ctx)
+	return value
+}
 
+// conditionValue evaluates a condition like evaluateCondition, reporting
+// template expressions that fail to render or render neither true nor false
+func (g *Generator) conditionValue(condition string, ctx *template.Context) (bool, error) {
 	condition = strings.TrimSpace(condition)
 	if strings.HasPrefix(condition, "{{") && strings.HasSuffix(condition, "}}") {
 		condition = strings.TrimSpace(condition[2 : len(condition)-2])
 	}
 
+	// Anything other than a plain variable reference is a template expression
+	if !identifierPattern.MatchString(condition) {
+		out, err := g.renderer.RenderString("condition", "{{ "+condition+" }}", ctx)
+		if err != nil {
+			return false, err
+		}
+		switch out = strings.TrimSpace(out); out {
+		case "true", "false":
+			return out == "true", nil
+		default:
+			return false, i18n.Errorf("generator.condition_not_bool", condition, out)
+		}
+	}
+
 	condition = strings.TrimPrefix(condition, ".")
 
 	switch condition {
 	case "IncludeDocker":
-		return ctx.IncludeDocker
+		return ctx.IncludeDocker, nil
 	case "IncludeTests":
-		return ctx.IncludeTests
+		return ctx.IncludeTests, nil
+	case "Observability":
+		return ctx.Observability, nil
+	case "APIDocs":
+		return ctx.APIDocs, nil
+	case "OpenAPI":
+		return ctx.OpenAPI != nil, nil
+	case "Proto":
+		return ctx.Proto != nil, nil
+	case "DBSchema":
+		return ctx.DBSchema != nil, nil
 	}
 
-	return ctx.GetBool(condition)
+	return ctx.GetBool(condition), nil
+}
+
+// EvaluateConditions evaluates conditions against the variables of the
+// template selected by opts. Unlike generation, which treats them as false,
+// conditions that fail to evaluate are errors.
+func (g *Generator) EvaluateConditions(opts *Options, conditions []string) ([]bool, error) {
+	_, ctx, err := g.resolve(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]bool, len(conditions))
+	for i, condition := range conditions {
+		if values[i], err = g.conditionValue(condition, ctx); err != nil {
+			return nil, i18n.Errorf("generator.condition_invalid", condition, err)
+		}
+	}
+	return values, nil
 }
 
-// mergeVariables merges user-provided variables with template defaults
-func (g *Generator) mergeVariables(tmpl *template.Template, userVars map[string]interface{}) map[string]interface{} {
+// renderNextSteps renders the template's next_steps entries, dropping empty lines
+func (g *Generator) renderNextSteps(tmpl *template.Template, ctx *template.Context) ([]string, error) {
+	var steps []string
+	for i, step := range tmpl.NextSteps {
+		rendered, err := g.renderer.RenderString(fmt.Sprintf("next_steps[%d]", i), step, ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		rendered = strings.TrimSpace(rendered)
+		if rendered != "" {
+			steps = append(steps, rendered)
+		}
+	}
+
+	return steps, nil
+}
+
+// renderHealthcheck renders the healthcheck command for the chosen port
+func (g *Generator) renderHealthcheck(hc *template.Healthcheck, ctx *template.Context) (*template.Healthcheck, error) {
+	command, err := g.renderer.RenderString("healthcheck", hc.Command, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rendered := *hc
+	rendered.Command = command
+	if ctx.Port != 0 {
+		rendered.Port = ctx.Port
+	}
+	return &rendered, nil
+}
+
+// mergeVariables merges template defaults with the given layers. Later
+// layers override earlier ones; the first layer ranks below template defaults.
+func (g *Generator) mergeVariables(tmpl *template.Template, defaults map[string]interface{}, layers ...map[string]interface{}) map[string]interface{} {
 	variables := make(map[string]interface{})
 
-	// Start with template defaults
+	for key, value := range defaults {
+		variables[key] = value
+	}
+
+	// Template defaults
 	for key, varDef := range tmpl.Variables {
 		if varDef.Default != nil {
 			variables[key] = varDef.Default
 		}
 	}
 
-	// Override with user-provided values
-	for key, value := range userVars {
-		variables[key] = value
+	// Profile and user-provided values
+	for _, layer := range layers {
+		for key, value := range layer {
+			variables[key] = value
+		}
 	}
 
 	return variables
 }
 
-// createMetadataFile creates the .devinit.yaml file in the project
-func (g *Generator) createMetadataFile(ctx *template.Context, tmpl *template.Template) error {
-	metadata := fmt.Sprintf(`schema_version: "1.0"
-template:
-  name: %s/%s
-  version: %s
-variables:
-`, tmpl.Language, tmpl.Framework, tmpl.Version)
+// evaluateDefaults replaces the expression defaults (see
+// Variable.DefaultExpression) that no profile or option overrode among
+// variables with their values, rendered in dependency order so that they
+// can build on each other. Empty values leave the variable unset.
+func (g *Generator) evaluateDefaults(tmpl *template.Template, projectName, outputDir string, variables map[string]interface{}) error {
+	order, err := tmpl.DefaultOrder()
+	if err != nil {
+		return errcode.New(errcode.TemplateInvalid, err)
+	}
+
+	for _, name := range order {
+		variable := tmpl.Variables[name]
+		expr := variable.DefaultExpression()
+		if value, ok := variables[name].(string); !ok || value != expr {
+			continue
+		}
 
-	for key, value := range ctx.Variables {
-		metadata += fmt.Sprintf("  %s: %v\n", key, value)
+		ctx := g.newContext(projectName, outputDir, variables, tmpl)
+		rendered, err := g.renderer.RenderString("default", expr, ctx)
+		if err != nil {
+			return errcode.New(errcode.TemplateInvalid, i18n.Errorf("generator.render_default", name, err))
+		}
+		rendered = strings.TrimSpace(rendered)
+		if rendered == "" {
+			delete(variables, name)
+			continue
+		}
+		value, err := typedValue(variable.Type, rendered)
+		if err != nil {
+			return errcode.New(errcode.TemplateInvalid, i18n.Errorf("generator.default_type", name, rendered, variable.Type))
+		}
+		variables[name] = value
 	}
+	return nil
+}
 
-	metadataPath := filepath.Join(ctx.OutputDir, ".devinit.yaml")
-	return os.WriteFile(metadataPath, []byte(metadata), 0644)
+// typedValue converts a rendered value to the type of a variable
+func typedValue(typ template.VariableType, value string) (interface{}, error) {
+	switch typ {
+	case template.VariableTypeBool:
+		return strconv.ParseBool(value)
+	case template.VariableTypeInt:
+		return strconv.Atoi(value)
+	}
+	return value, nil
+}
+
+// profileVariables returns the variables preset by the named profile,
+// including the Profile variable itself. An empty name selects no profile.
+func profileVariables(tmpl *template.Template, name string) (map[string]interface{}, error) {
+	if name == "" {
+		return nil, nil
+	}
+
+	profile, ok := tmpl.Profiles[name]
+	if !ok {
+		if len(tmpl.Profiles) == 0 {
+			return nil, errcode.New(errcode.VariableInvalid, i18n.Errorf("generator.no_profiles", tmpl.Language, tmpl.Framework))
+		}
+		return nil, errcode.New(errcode.VariableInvalid, i18n.Errorf("generator.unknown_profile", name, tmpl.Language, tmpl.Framework, strings.Join(tmpl.ProfileNames(), ", ")))
+	}
+
+	variables := make(map[string]interface{}, len(profile.Variables)+1)
+	for key, value := range profile.Variables {
+		variables[key] = value
+	}
+	variables["Profile"] = name
+	return variables, nil
+}
+
+// createMetadataFile creates the .devinit.yaml file in the project
+func (g *Generator) createMetadataFile(ctx *template.Context, tmpl *template.Template, provenance bool, out output.Backend) error {
+	metadata, err := marshalMetadata(ctx, tmpl, provenance)
+	if err != nil {
+		return err
+	}
+	return out.WriteFile(MetadataFile, metadata, 0644)
 }
 
 // ListTemplates returns all available templates
@@ -204,6 +884,45 @@ func (g *Generator) ListTemplates() ([]string, error) {
 	return g.loader.List()
 }
 
+// WriteTemplateIndex writes the index file of the templates directory so
+// listing no longer walks it, returning the indexed templates
+func (g *Generator) WriteTemplateIndex() ([]string, error) {
+	return g.loader.WriteIndex()
+}
+
+// FindTemplates loads all templates matching the filter, sorted by name.
+// Templates that fail to load are skipped.
+func (g *Generator) FindTemplates(filter template.Filter) ([]*template.Template, error) {
+	names, err := g.loader.List()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	var templates []*template.Template
+	for _, name := range names {
+		tmpl, err := g.loader.Load(name)
+		if err != nil {
+			continue
+		}
+		if filter.Matches(tmpl) {
+			templates = append(templates, tmpl)
+		}
+	}
+
+	return templates, nil
+}
+
+// GetTemplateVersion returns a specific version of a template
+func (g *Generator) GetTemplateVersion(name, version string) (*template.Template, error) {
+	return g.loader.LoadVersion(name, version)
+}
+
+// ListTemplateVersions returns the available versions of a template
+func (g *Generator) ListTemplateVersions(name string) ([]string, error) {
+	return g.loader.Versions(name)
+}
+
 // GetTemplate returns a specific template
 func (g *Generator) GetTemplate(name string) (*template.Template, error) {
 	return g.loader.Load(name)
@@ -1,19 +1,45 @@
 package generator
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
 
+	"github.com/renan-dev/devinit/internal/dbschema"
+	"github.com/renan-dev/devinit/internal/errcode"
+	"github.com/renan-dev/devinit/internal/ignore"
+	"github.com/renan-dev/devinit/internal/network"
+	"github.com/renan-dev/devinit/internal/openapi"
+	"github.com/renan-dev/devinit/internal/output"
+	"github.com/renan-dev/devinit/internal/proto"
+	"github.com/renan-dev/devinit/internal/queue"
+	"github.com/renan-dev/devinit/internal/retry"
 	"github.com/renan-dev/devinit/internal/template"
+	"github.com/renan-dev/devinit/internal/vulnscan"
+	"gopkg.in/yaml.v3"
 )
 
 func TestEvaluateCondition(t *testing.T) {
-	gen := &Generator{}
+	gen := &Generator{renderer: template.NewRenderer()}
 
 	// Create test context
 	variables := map[string]interface{}{
 		"IncludeDocker": true,
 		"IncludeTests":  false,
 		"CustomFlag":    true,
+		"Database":      "postgres",
+		"Observability": true,
 	}
 	ctx := template.NewContext("test-project", "/tmp/test", variables, &template.Template{})
 
@@ -70,6 +96,12 @@ func TestEvaluateCondition(t *testing.T) {
 			want:      true,
 		},
 
+		{
+			name:      "observability field",
+			condition: "{{ .Observability }}",
+			want:      true,
+		},
+
 		// Custom variables via map
 		{
 			name:      "custom variable - true",
@@ -83,6 +115,28 @@ func TestEvaluateCondition(t *testing.T) {
 			condition: "NonExistent",
 			want:      false,
 		},
+
+		// Template expressions
+		{
+			name:      "expression - true",
+			condition: `{{ eq .Database "postgres" }}`,
+			want:      true,
+		},
+		{
+			name:      "expression - false",
+			condition: `{{ eq .Database "sqlite" }}`,
+			want:      false,
+		},
+		{
+			name:      "expression with and",
+			condition: `{{ and .IncludeDocker (ne .Database "none") }}`,
+			want:      true,
+		},
+		{
+			name:      "invalid expression",
+			condition: `{{ eq .Database }}`,
+			want:      false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -110,6 +164,7 @@ func TestShouldGenerateFile(t *testing.T) {
 		name     string
 		fileSpec template.FileSpec
 		want     bool
+		unmet    string
 	}{
 		{
 			name: "no conditions - always generate",
@@ -136,7 +191,8 @@ func TestShouldGenerateFile(t *testing.T) {
 				Destination: "tests/test_main.py",
 				Conditions:  []string{"{{ .IncludeTests }}"},
 			},
-			want: false,
+			want:  false,
+			unmet: "{{ .IncludeTests }}",
 		},
 		{
 			name: "multiple conditions - all true",
@@ -154,7 +210,8 @@ func TestShouldGenerateFile(t *testing.T) {
 				Destination: "special-file.txt",
 				Conditions:  []string{"{{ .IncludeDocker }}", "{{ .IncludeTests }}"},
 			},
-			want: false,
+			want:  false,
+			unmet: "{{ .IncludeTests }}",
 		},
 		{
 			name: "multiple conditions - all false",
@@ -163,7 +220,8 @@ func TestShouldGenerateFile(t *testing.T) {
 				Destination: "never-generated.txt",
 				Conditions:  []string{"{{ .IncludeTests }}", "NonExistent"},
 			},
-			want: false,
+			want:  false,
+			unmet: "{{ .IncludeTests }},NonExistent",
 		},
 	}
 
@@ -173,6 +231,3074 @@ func TestShouldGenerateFile(t *testing.T) {
 			if got != tt.want {
 				t.Errorf("shouldGenerateFile() = %v, want %v", got, tt.want)
 			}
+			if unmet := strings.Join(gen.unmetConditions(tt.fileSpec, ctx), ","); unmet != tt.unmet {
+				t.Errorf("unmetConditions() = %q, want %q", unmet, tt.unmet)
+			}
+		})
+	}
+}
+
+func TestOutputPath(t *testing.T) {
+	gen := &Generator{renderer: template.NewRenderer()}
+	ctx := template.NewContext("my-lib", "/tmp/test", map[string]interface{}{}, &template.Template{})
+
+	tests := []struct {
+		name    string
+		dest    string
+		want    string
+		wantErr bool
+	}{
+		{name: "static path", dest: "src/main.py", want: "src/main.py"},
+		{name: "strips tmpl extension", dest: "README.md.tmpl", want: "README.md"},
+		{name: "rendered directory", dest: "src/{{ .ProjectNameSnake }}/__init__.py", want: "src/my_lib/__init__.py"},
+		{name: "rendered with function", dest: "{{ replace .ProjectName \"-\" \"\" }}.go", want: "mylib.go"},
+		{name: "package path", dest: "src/main/java/{{ packagePath \"com.acme.billing\" }}/App.java", want: filepath.FromSlash("src/main/java/com/acme/billing/App.java")},
+		{name: "package path of a single name", dest: "{{ packagePath (snake .ProjectName) }}/__init__.py", want: filepath.FromSlash("my_lib/__init__.py")},
+		{name: "invalid expression", dest: "src/{{ .ProjectName", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := gen.outputPath(template.FileSpec{Destination: tt.dest}, ctx)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("outputPath(%q) error = %v, wantErr %v", tt.dest, err, tt.wantErr)
+			}
+			if err == nil && got != filepath.FromSlash(tt.want) {
+				t.Errorf("outputPath(%q) = %q, want %q", tt.dest, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNamingHelpers(t *testing.T) {
+	gen := &Generator{renderer: template.NewRenderer()}
+	gen.SetNaming(template.Naming{Registry: "ghcr.io/acme/", PortMin: 20000, PortMax: 20009})
+
+	tmpl := &template.Template{
+		Files: []template.FileSpec{
+			{Source: "port.tmpl", Destination: "{{ portFor \"api\" .ProjectName }}"},
+			{Source: "db.tmpl", Destination: "{{ dbName .ProjectName }}"},
+			{Source: "image.tmpl", Destination: "{{ imageName .ProjectName }}"},
+			{Source: "default-image.tmpl", Destination: "{{ replace .Image \"/\" \"_\" }}"},
+		},
+	}
+	ctx := gen.newContext("9-Lives", "9-Lives", map[string]interface{}{}, tmpl)
+
+	var got []string
+	for _, f := range tmpl.Files {
+		path, err := gen.outputPath(f, ctx)
+		if err != nil {
+			t.Fatalf("outputPath(%s) unexpected error: %v", f.Source, err)
+		}
+		got = append(got, path)
+	}
+
+	port, err := strconv.Atoi(got[0])
+	if err != nil || port < 20000 || port > 20009 {
+		t.Errorf("portFor = %q, want a port in 20000-20009", got[0])
+	}
+	again, _ := gen.outputPath(tmpl.Files[0], ctx)
+	if again != got[0] {
+		t.Errorf("portFor is not stable: %q then %q", got[0], again)
+	}
+	if got[1] != "db_9_lives" {
+		t.Errorf("dbName = %q, want %q", got[1], "db_9_lives")
+	}
+	if got[2] != "ghcr.io/acme/9-lives" {
+		t.Errorf("imageName = %q, want %q", got[2], "ghcr.io/acme/9-lives")
+	}
+	if got[3] != "ghcr.io_acme_9-lives:latest" {
+		t.Errorf("Image = %q, want %q", got[3], "ghcr.io_acme_9-lives:latest")
+	}
+}
+
+func TestCaseHelpers(t *testing.T) {
+	tests := []struct {
+		name                                  string
+		snake, kebab, camel, pascal, constant string
+	}{
+		{name: "my-project", snake: "my_project", kebab: "my-project", camel: "myProject", pascal: "MyProject", constant: "MY_PROJECT"},
+		{name: "myProject", snake: "my_project", kebab: "my-project", camel: "myProject", pascal: "MyProject", constant: "MY_PROJECT"},
+		{name: "HTTPServer", snake: "http_server", kebab: "http-server", camel: "httpServer", pascal: "HTTPServer", constant: "HTTP_SERVER"},
+		{name: "parse_HTTP_request", snake: "parse_http_request", kebab: "parse-http-request", camel: "parseHTTPRequest", pascal: "ParseHTTPRequest", constant: "PARSE_HTTP_REQUEST"},
+		{name: "userIDsForAPI", snake: "user_ids_for_api", kebab: "user-ids-for-api", camel: "userIDsForAPI", pascal: "UserIDsForAPI", constant: "USER_IDS_FOR_API"},
+		{name: "MY_PROJECT", snake: "my_project", kebab: "my-project", camel: "myProject", pascal: "MyProject", constant: "MY_PROJECT"},
+		{name: "OAuth2Client v3", snake: "o_auth2_client_v3", kebab: "o-auth2-client-v3", camel: "oAuth2ClientV3", pascal: "OAuth2ClientV3", constant: "O_AUTH2_CLIENT_V3"},
+		{name: "Café-Über", snake: "café_über", kebab: "café-über", camel: "caféÜber", pascal: "CaféÜber", constant: "CAFÉ_ÜBER"},
+		{name: "  --api__gateway--  ", snake: "api_gateway", kebab: "api-gateway", camel: "apiGateway", pascal: "ApiGateway", constant: "API_GATEWAY"},
+		{name: "日本語-app", snake: "日本語_app", kebab: "日本語-app", camel: "日本語App", pascal: "日本語App", constant: "日本語_APP"},
+	}
+
+	renderer := template.NewRenderer()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := template.NewContext(tt.name, tt.name, map[string]interface{}{}, &template.Template{})
+			fields := []string{ctx.ProjectNameSnake, ctx.ProjectNameKebab, ctx.ProjectNameCamel, ctx.ProjectNamePascal, ctx.ProjectNameConstant}
+			want := []string{tt.snake, tt.kebab, tt.camel, tt.pascal, tt.constant}
+			if !reflect.DeepEqual(fields, want) {
+				t.Errorf("context fields = %q, want %q", fields, want)
+			}
+
+			// The renderer functions agree with the fields
+			got, err := renderer.RenderString("case", "{{ snake .ProjectName }} {{ kebab .ProjectName }} {{ camel .ProjectName }} {{ pascal .ProjectName }} {{ constant .ProjectName }}", ctx)
+			if err != nil {
+				t.Fatalf("RenderString() unexpected error: %v", err)
+			}
+			if wantString := strings.Join(want, " "); got != wantString {
+				t.Errorf("RenderString() = %q, want %q", got, wantString)
+			}
+		})
+	}
+
+	long := strings.Repeat("é", 40)
+	if name := template.DefaultNaming().DBName(long); !utf8.ValidString(name) || len(name) > 63 {
+		t.Errorf("DBName(%q) = %q, want valid UTF-8 of at most 63 bytes", long, name)
+	}
+}
+
+func TestVersionHelpers(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    string
+		wantErr bool
+	}{
+		{name: "major", text: `{{ semverMajor "3.11.4" }}`, want: "3"},
+		{name: "minor", text: `{{ semverMinor "v3.11" }}`, want: "11"},
+		{name: "patch defaults to zero", text: `{{ semverPatch "3.11" }}`, want: "0"},
+		{name: "prerelease and build", text: `{{ semverPatch "1.2.3-rc.1+abc" }}`, want: "3"},
+		{name: "python tag", text: `{{ pythonTag "3.11" }}`, want: "py311"},
+		{name: "range", text: `{{ semverCompare ">=3.10, <4" "3.12.1" }}`, want: "true"},
+		{name: "range excludes", text: `{{ semverCompare ">=3.10, <4" "3.9" }}`, want: "false"},
+		{name: "exact", text: `{{ semverCompare "1.2" "1.2.0" }}`, want: "true"},
+		{name: "caret", text: `{{ semverCompare "^1.4" "1.9.0" }} {{ semverCompare "^1.4" "2.0.0" }}`, want: "true false"},
+		{name: "tilde", text: `{{ semverCompare "~1.4.2" "1.4.9" }} {{ semverCompare "~1.4.2" "1.5.0" }}`, want: "true false"},
+		{name: "prerelease before release", text: `{{ semverCompare "<1.0.0" "1.0.0-beta" }}`, want: "true"},
+		{name: "invalid version", text: `{{ semverMajor "latest" }}`, wantErr: true},
+		{name: "invalid constraint", text: `{{ semverCompare "=>1.0" "1.0" }}`, wantErr: true},
+	}
+
+	renderer := template.NewRenderer()
+	ctx := template.NewContext("demo", "demo", map[string]interface{}{}, &template.Template{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := renderer.RenderString(tt.name, tt.text, ctx)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("RenderString(%s) = %q, want error", tt.text, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RenderString(%s) unexpected error: %v", tt.text, err)
+			}
+			if got != tt.want {
+				t.Errorf("RenderString(%s) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderNextSteps(t *testing.T) {
+	gen := &Generator{renderer: template.NewRenderer()}
+
+	tmpl := &template.Template{
+		NextSteps: []string{
+			"cd {{ .ProjectName }}",
+			"{{ if .IncludeDocker }}docker compose up{{ end }}",
+			"{{ if .IncludeTests }}pytest{{ end }}",
+		},
+	}
+	variables := map[string]interface{}{
+		"IncludeDocker": true,
+		"IncludeTests":  false,
+	}
+	ctx := template.NewContext("test-project", "/tmp/test", variables, tmpl)
+
+	got, err := gen.renderNextSteps(tmpl, ctx)
+	if err != nil {
+		t.Fatalf("renderNextSteps() unexpected error: %v", err)
+	}
+
+	want := []string{"cd test-project", "docker compose up"}
+	if len(got) != len(want) {
+		t.Fatalf("renderNextSteps() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("renderNextSteps()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+// writeTestTemplate creates a minimal template under dir/test/basic
+func writeTestTemplate(t testing.TB, dir, metadata string, files map[string]string) {
+	t.Helper()
+
+	templateDir := filepath.Join(dir, "test", "basic")
+	if err := os.MkdirAll(filepath.Join(templateDir, "files"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(templateDir, "template.yaml"), []byte(metadata), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		path := filepath.Join(templateDir, "files", filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestGenerateResult(t *testing.T) {
+	templatesDir := t.TempDir()
+	writeTestTemplate(t, templatesDir, `version: "1.2.0"
+name: basic
+language: test
+framework: basic
+files:
+  - src: README.md.tmpl
+    dest: README.md
+  - src: static.txt
+    dest: static.txt
+  - src: docker.txt
+    dest: docker.txt
+    conditions: ["{{ .IncludeDocker }}"]
+hooks:
+  post_generate:
+    - run: "this-command-definitely-does-not-exist-12345"
+      error_level: warn
+`, map[string]string{
+		"README.md.tmpl": "# {{ .ProjectName }}\n",
+		"static.txt":     "static\n",
+		"docker.txt":     "docker\n",
+	})
+
+	outputDir := filepath.Join(t.TempDir(), "demo")
+	gen := NewGenerator(templatesDir)
+
+	result, err := gen.Generate(&Options{
+		ProjectName: "demo",
+		Language:    "test",
+		Framework:   "basic",
+		OutputDir:   outputDir,
+		Variables:   map[string]interface{}{"IncludeDocker": false},
+	})
+	if err != nil {
+		t.Fatalf("Generate() unexpected error: %v", err)
+	}
+
+	if result.TemplateVersion != "1.2.0" {
+		t.Errorf("TemplateVersion = %q, want %q", result.TemplateVersion, "1.2.0")
+	}
+	if got := result.FilesWritten(); got != 2 {
+		t.Errorf("FilesWritten() = %d, want 2", got)
+	}
+	if got := result.TotalSize(); got != int64(len("# demo\n")+len("static\n")) {
+		t.Errorf("TotalSize() = %d, want %d", got, len("# demo\n")+len("static\n"))
+	}
+	if len(result.Hooks) != 1 || result.Hooks[0].Error == "" {
+		t.Errorf("Hooks = %+v, want one failed hook", result.Hooks)
+	}
+	if len(result.Warnings) != 1 {
+		t.Errorf("Warnings = %v, want 1 warning", result.Warnings)
+	}
+
+	content, err := os.ReadFile(filepath.Join(outputDir, "README.md"))
+	if err != nil {
+		t.Fatalf("README.md not generated: %v", err)
+	}
+	if string(content) != "# demo\n" {
+		t.Errorf("README.md = %q, want %q", content, "# demo\n")
+	}
+}
+
+func TestGenerateOutputBackend(t *testing.T) {
+	templatesDir := t.TempDir()
+	writeTestTemplate(t, templatesDir, `version: "1.0.0"
+name: basic
+language: test
+framework: basic
+files:
+  - src: main.txt.tmpl
+    dest: "src/{{ .ProjectName }}/main.txt"
+  - src: escape.txt
+    dest: "{{ .Variables.escape }}"
+variables:
+  escape:
+    type: string
+    default: docs/escape.txt
+hooks:
+  post_generate:
+    - run: "touch hook-ran"
+`, map[string]string{
+		"main.txt.tmpl": "{{ .ProjectName }}\n",
+		"escape.txt":    "escape\n",
+	})
+
+	gen := NewGenerator(templatesDir)
+	outputDir := filepath.Join(t.TempDir(), "demo")
+
+	files := output.NewMemory()
+	result, err := gen.Generate(&Options{
+		ProjectName: "demo",
+		Language:    "test",
+		Framework:   "basic",
+		OutputDir:   outputDir,
+		Output:      files,
+	})
+	if err != nil {
+		t.Fatalf("Generate() unexpected error: %v", err)
+	}
+
+	got := files.Files()
+	if string(got["src/demo/main.txt"]) != "demo\n" {
+		t.Errorf("files = %q, want src/demo/main.txt", got)
+	}
+	if _, ok := got[".devinit.yaml"]; !ok {
+		t.Errorf("files = %q, want the .devinit.yaml metadata", got)
+	}
+	if result.FilesWritten() != 2 || len(result.Hooks) != 0 {
+		t.Errorf("result = %d files, %d hooks, want 2 files and no hooks", result.FilesWritten(), len(result.Hooks))
+	}
+	if _, err := os.Stat(outputDir); !os.IsNotExist(err) {
+		t.Errorf("output directory created with a custom Output (stat error %v)", err)
+	}
+
+	// Destinations may not leave the project
+	_, err = gen.Generate(&Options{
+		ProjectName: "demo",
+		Language:    "test",
+		Framework:   "basic",
+		OutputDir:   outputDir,
+		Variables:   map[string]interface{}{"escape": "../outside.txt"},
+		Output:      output.NewMemory(),
+	})
+	if err == nil || !strings.Contains(err.Error(), "invalid output path") {
+		t.Errorf("Generate() error = %v, want an invalid output path", err)
+	}
+}
+
+func TestGenerateFileGlobs(t *testing.T) {
+	templatesDir := t.TempDir()
+	metadata := `version: "1.0.0"
+name: basic
+language: test
+framework: basic
+files:
+  - src: "migrations/**/*.sql"
+    dest: db/migrations
+  - src: "*.md.tmpl"
+`
+	writeTestTemplate(t, templatesDir, metadata, map[string]string{
+		"migrations/001_init.sql":        "create table users;\n",
+		"migrations/002_posts.sql":       "create table posts;\n",
+		"migrations/.002_posts.sql.swp":  "swap\n",
+		"migrations/fixtures/seed.sql":   "insert into users;\n",
+		"migrations/archive/000_old.sql": "drop table users;\n",
+		"README.md.tmpl":                 "# {{ .ProjectName }}\n",
+	})
+	ignoreFile := filepath.Join(templatesDir, "test", "basic", ".devinitignore")
+	if err := os.WriteFile(ignoreFile, []byte("*.swp\nfixtures/\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	gen := NewGenerator(templatesDir)
+	tmpl, err := gen.GetTemplate("test/basic")
+	if err != nil {
+		t.Fatalf("GetTemplate() unexpected error: %v", err)
+	}
+
+	files, err := gen.RenderFiles(tmpl, "demo", nil)
+	if err != nil {
+		t.Fatalf("RenderFiles() unexpected error: %v", err)
+	}
+	want := map[string]string{
+		"db/migrations/001_init.sql":        "create table users;\n",
+		"db/migrations/002_posts.sql":       "create table posts;\n",
+		"db/migrations/archive/000_old.sql": "drop table users;\n",
+		"README.md":                         "# demo\n",
+	}
+	if len(files) != len(want) {
+		t.Errorf("RenderFiles() = %q, want %q", files, want)
+	}
+	for name, content := range want {
+		if string(files[name]) != content {
+			t.Errorf("%s = %q, want %q", name, files[name], content)
+		}
+	}
+
+	// Ignored files cannot be listed explicitly, and globs must match
+	for _, spec := range []string{"migrations/fixtures/seed.sql", "seeds/*.sql"} {
+		writeTestTemplate(t, templatesDir, metadata+"  - src: \""+spec+"\"\n    dest: extra\n", nil)
+		if _, err := NewGenerator(templatesDir).GetTemplate("test/basic"); !errors.Is(err, errcode.ErrTemplateInvalid) {
+			t.Errorf("GetTemplate() with src %s error = %v, want %v", spec, err, errcode.ErrTemplateInvalid)
+		}
+	}
+}
+
+func TestGenerateDuplicateDestinations(t *testing.T) {
+	header := `version: "1.0.0"
+name: basic
+language: test
+framework: basic
+files:
+`
+	sources := map[string]string{
+		"config/app.yaml":   "app: base\n",
+		"config/db.yaml":    "db: base\n",
+		"app.docker.yaml":   "app: docker\n",
+		"app.override.yaml": "app: override\n",
+	}
+
+	tests := []struct {
+		name  string
+		files string
+		want  []string // in the error, or nil to succeed
+		app   string
+	}{
+		{
+			name: "later file replacing an earlier one",
+			files: `  - src: config/app.yaml
+    dest: app.yaml
+  - src: app.docker.yaml
+    dest: app.yaml
+    conditions: ["IncludeDocker"]
+`,
+			want: []string{"app.yaml", "config/app.yaml", "app.docker.yaml", "override: true"},
+		},
+		{
+			name: "conditions excluding the later file",
+			files: `  - src: config/app.yaml
+    dest: app.yaml
+  - src: app.docker.yaml
+    dest: app.yaml
+    conditions: ["{{ not .IncludeDocker }}"]
+`,
+			app: "app: base\n",
+		},
+		{
+			name: "explicit override",
+			files: `  - src: config/app.yaml
+    dest: app.yaml
+  - src: app.override.yaml
+    dest: app.yaml
+    override: true
+`,
+			app: "app: override\n",
+		},
+		{
+			name: "write mode",
+			files: `  - src: config/app.yaml
+    dest: app.yaml
+  - src: app.docker.yaml
+    dest: app.yaml
+    mode: append
+`,
+			app: "app: base\napp: docker\n",
+		},
+		{
+			name: "overlapping globs",
+			files: `  - src: "config/*.yaml"
+    dest: config
+  - src: "**/app.yaml"
+`,
+			want: []string{"config/app.yaml (matched by config/*.yaml)", "config/app.yaml (matched by **/app.yaml)"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			templatesDir := t.TempDir()
+			writeTestTemplate(t, templatesDir, header+tt.files, sources)
+			gen := NewGenerator(templatesDir)
+			tmpl, err := gen.GetTemplate("test/basic")
+			if err != nil {
+				t.Fatalf("GetTemplate() unexpected error: %v", err)
+			}
+
+			// Dry runs plan the same files, so they fail the same way
+			variables := map[string]interface{}{"IncludeDocker": true}
+			_, genErr := gen.Generate(&Options{
+				ProjectName: "demo",
+				Language:    "test",
+				Framework:   "basic",
+				OutputDir:   filepath.Join(t.TempDir(), "demo"),
+				Variables:   variables,
+				DryRun:      true,
+			})
+			files, err := gen.RenderFiles(tmpl, "demo", variables)
+			if tt.want == nil {
+				if genErr != nil || err != nil {
+					t.Fatalf("Generate() error = %v, RenderFiles() error = %v", genErr, err)
+				}
+				if got := string(files["app.yaml"]); got != tt.app {
+					t.Errorf("app.yaml = %q, want %q", got, tt.app)
+				}
+				return
+			}
+
+			for _, err := range []error{genErr, err} {
+				if !errors.Is(err, errcode.ErrTemplateInvalid) {
+					t.Fatalf("error = %v, want %v", err, errcode.ErrTemplateInvalid)
+				}
+				for _, want := range tt.want {
+					if !strings.Contains(err.Error(), want) {
+						t.Errorf("error = %q, want it to name %q", err, want)
+					}
+				}
+			}
 		})
 	}
 }
+
+func TestDrift(t *testing.T) {
+	templatesDir := t.TempDir()
+	writeTestTemplate(t, templatesDir, `version: "1.0.0"
+name: basic
+language: test
+framework: basic
+files:
+  - src: README.md.tmpl
+    dest: README.md
+  - src: version.txt.tmpl
+    dest: version.txt
+  - src: notes.md
+    dest: docs/notes.md
+`, map[string]string{
+		"README.md.tmpl":   "# {{ .ProjectName }}\n",
+		"version.txt.tmpl": "{{ .Variables.go_version }}\n",
+		"notes.md":         "notes\n",
+	})
+
+	gen := NewGenerator(templatesDir)
+	outputDir := filepath.Join(t.TempDir(), "demo")
+	_, err := gen.Generate(&Options{
+		ProjectName: "demo",
+		Language:    "test",
+		Framework:   "basic",
+		OutputDir:   outputDir,
+		Variables:   map[string]interface{}{"ProjectName": "demo", "go_version": "1.20"},
+	})
+	if err != nil {
+		t.Fatalf("Generate() unexpected error: %v", err)
+	}
+
+	metadata, drift, err := gen.Drift(outputDir)
+	if err != nil {
+		t.Fatalf("Drift() unexpected error: %v", err)
+	}
+	if metadata.Template.Name != "test/basic" || len(drift) != 0 {
+		t.Errorf("Drift() of a fresh project = %s, %+v, want test/basic without drift", metadata.Template.Name, drift)
+	}
+
+	for name, content := range map[string]string{"docs/notes.md": "mine\n", ".devinitignore": "docs/\n", "version.txt": "1.21\n"} {
+		if err := os.WriteFile(filepath.Join(outputDir, filepath.FromSlash(name)), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Remove(filepath.Join(outputDir, "README.md")); err != nil {
+		t.Fatal(err)
+	}
+
+	_, drift, err = gen.Drift(outputDir)
+	if err != nil {
+		t.Fatalf("Drift() unexpected error: %v", err)
+	}
+	want := []DriftEntry{
+		{Path: "README.md", Status: DriftMissing},
+		{Path: "version.txt", Status: DriftModified},
+	}
+	if !reflect.DeepEqual(drift, want) {
+		t.Errorf("Drift() = %+v, want %+v", drift, want)
+	}
+
+	// Files left out of the managed ones are skipped like ignored ones
+	gen.SetManaged(ignore.Parse("**\n!version.txt\n"))
+	_, drift, err = gen.Drift(outputDir)
+	if err != nil {
+		t.Fatalf("Drift() with managed files unexpected error: %v", err)
+	}
+	if want := want[:1]; !reflect.DeepEqual(drift, want) {
+		t.Errorf("Drift() with managed files = %+v, want %+v", drift, want)
+	}
+}
+
+func TestLockFile(t *testing.T) {
+	templatesDir := t.TempDir()
+	writeTestTemplate(t, templatesDir, `version: "1.0.0"
+name: basic
+language: test
+framework: basic
+origin:
+  source: gh:acme/cookiecutter-basic
+  commit: 4f2c9e1
+files:
+  - src: README.md
+    dest: README.md
+`, map[string]string{"README.md": "# demo\n"})
+
+	gen := NewGenerator(templatesDir)
+	outputDir := filepath.Join(t.TempDir(), "demo")
+	if _, err := gen.Generate(&Options{ProjectName: "demo", Language: "test", Framework: "basic", OutputDir: outputDir}); err != nil {
+		t.Fatalf("Generate() unexpected error: %v", err)
+	}
+
+	lock, err := ReadLock(outputDir)
+	if err != nil || lock == nil {
+		t.Fatalf("ReadLock() = %v, %v, want the lock of the imported template", lock, err)
+	}
+	if lock.Template.Name != "test/basic" || lock.Template.Commit != "4f2c9e1" || !strings.HasPrefix(lock.Template.Digest, "sha256:") {
+		t.Errorf("lock = %+v, want test/basic pinned to 4f2c9e1 with a digest", lock.Template)
+	}
+	if _, _, err := gen.Drift(outputDir); err != nil {
+		t.Fatalf("Drift() of the locked template unexpected error: %v", err)
+	}
+
+	// The template changes in the store without a new commit
+	readme := filepath.Join(templatesDir, "test", "basic", "files", "README.md")
+	if err := os.WriteFile(readme, []byte("# changed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := gen.Drift(outputDir); errcode.Of(err) != errcode.Conflict {
+		t.Fatalf("Drift() of a changed template error = %v, want a conflict", err)
+	}
+
+	gen.SetUpdateLock(true)
+	if _, _, err := gen.Drift(outputDir); err != nil {
+		t.Fatalf("Drift() with the lock updated unexpected error: %v", err)
+	}
+	updated, err := ReadLock(outputDir)
+	if err != nil || updated.Template.Digest == lock.Template.Digest {
+		t.Errorf("lock after update = %+v, %v, want a new digest", updated, err)
+	}
+}
+
+func TestOverlay(t *testing.T) {
+	templatesDir, overlay := t.TempDir(), t.TempDir()
+	metadata := `version: "1.0.0"
+name: basic
+language: test
+framework: basic
+files:
+  - src: README.md.tmpl
+    dest: README.md
+  - src: Dockerfile
+    dest: Dockerfile
+`
+	writeTestTemplate(t, templatesDir, metadata, map[string]string{
+		"README.md.tmpl": "# {{ .ProjectName }}\n",
+		"Dockerfile":     "FROM upstream\n",
+	})
+	override := filepath.Join(overlay, "test", "basic", "files", "Dockerfile")
+	if err := os.MkdirAll(filepath.Dir(override), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(override, []byte("FROM registry.example.com/base\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeTestTemplate(t, filepath.Join(overlay, "org"), strings.Replace(metadata, "language: test", "language: org", 1), map[string]string{
+		"README.md.tmpl": "# org\n",
+		"Dockerfile":     "FROM org\n",
+	})
+
+	gen := NewGenerator(templatesDir)
+	gen.SetOverlay(overlay)
+
+	names, err := gen.ListTemplates()
+	sort.Strings(names)
+	if err != nil || !reflect.DeepEqual(names, []string{"org/test/basic", "test/basic"}) {
+		t.Errorf("ListTemplates() = %v, %v, want the overlay's template listed too", names, err)
+	}
+
+	outputDir := filepath.Join(t.TempDir(), "demo")
+	result, err := gen.Generate(&Options{ProjectName: "demo", Language: "test", Framework: "basic", OutputDir: outputDir})
+	if err != nil {
+		t.Fatalf("Generate() unexpected error: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(outputDir, "Dockerfile")); string(data) != "FROM registry.example.com/base\n" {
+		t.Errorf("Dockerfile = %q, want the override", data)
+	}
+	for _, f := range result.Files {
+		if f.Override != (f.Source == "Dockerfile") {
+			t.Errorf("%s: Override = %v", f.Source, f.Override)
+		}
+	}
+
+	recorded, drift, err := gen.Drift(outputDir)
+	if err != nil || len(drift) != 0 || !reflect.DeepEqual(recorded.Overrides, []string{"Dockerfile"}) {
+		t.Errorf("Drift() = %v, %+v, %v, want no drift and the Dockerfile override recorded", recorded.Overrides, drift, err)
+	}
+	_, drift, err = NewGenerator(templatesDir).Drift(outputDir)
+	if want := []DriftEntry{{Path: "Dockerfile", Status: DriftModified}}; err != nil || !reflect.DeepEqual(drift, want) {
+		t.Errorf("Drift() without the overlay = %+v, %v, want %+v", drift, err, want)
+	}
+
+	if err := os.WriteFile(filepath.Join(filepath.Dir(override), "Dockerfil"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gen.GetTemplate("test/basic"); err == nil || !strings.Contains(err.Error(), "overrides no file") {
+		t.Errorf("GetTemplate() with an override of a missing file = %v, want error", err)
+	}
+}
+
+func TestSiblingVariables(t *testing.T) {
+	templatesDir := t.TempDir()
+	writeTestTemplate(t, templatesDir, `version: "1.0.0"
+name: basic
+language: test
+framework: basic
+variables:
+  go_version:
+    type: string
+    default: "1.21"
+healthcheck:
+  port: 8080
+files:
+  - src: README.md.tmpl
+    dest: README.md
+`, map[string]string{"README.md.tmpl": "# {{ .ProjectName }} on {{ .Port }}\n"})
+
+	gen := NewGenerator(templatesDir)
+	outputDir := filepath.Join(t.TempDir(), "orders")
+	_, err := gen.Generate(&Options{
+		ProjectName: "orders",
+		Language:    "test",
+		Framework:   "basic",
+		OutputDir:   outputDir,
+		Variables:   map[string]interface{}{"ProjectName": "orders", "go_version": "1.22", "Image": "registry/orders:1", "Port": 9000},
+	})
+	if err != nil {
+		t.Fatalf("Generate() unexpected error: %v", err)
+	}
+
+	metadata, err := ReadMetadata(outputDir)
+	if err != nil {
+		t.Fatalf("ReadMetadata() unexpected error: %v", err)
+	}
+	want := map[string]interface{}{"go_version": "1.22"}
+	if got := metadata.SiblingVariables(); !reflect.DeepEqual(got, want) {
+		t.Errorf("SiblingVariables() = %v, want %v", got, want)
+	}
+}
+
+func TestGenerateOpenAPI(t *testing.T) {
+	templatesDir := t.TempDir()
+	writeTestTemplate(t, templatesDir, `version: "1.0.0"
+name: basic
+language: test
+framework: basic
+files:
+  - src: routes.txt.tmpl
+    dest: routes.txt
+    conditions: ["{{ .OpenAPI }}"]
+`, map[string]string{
+		"routes.txt.tmpl": "{{ range .OpenAPI.Endpoints }}{{ .Method }} {{ .Path }} {{ .Name | snake }}{{ range .PathParameters }} {{ .Name }}:{{ .Type.Go }}{{ end }}{{ if .Response }} -> {{ .Response.Python }}{{ end }}\n{{ end }}",
+	})
+
+	spec, err := openapi.Parse([]byte(`openapi: 3.0.3
+info: {title: Pets, version: 1.0.0}
+paths:
+  /pets/{petId}:
+    get:
+      operationId: showPetById
+      parameters:
+        - {name: petId, in: path, required: true, schema: {type: integer, format: int64}}
+      responses:
+        "200":
+          content:
+            application/json:
+              schema: {$ref: "#/components/schemas/Pet"}
+components:
+  schemas:
+    Pet:
+      type: object
+      properties:
+        name: {type: string}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, withSpec := range []bool{true, false} {
+		outputDir := filepath.Join(t.TempDir(), "pets")
+		opts := &Options{
+			ProjectName: "pets",
+			Language:    "test",
+			Framework:   "basic",
+			OutputDir:   outputDir,
+			Variables:   map[string]interface{}{"ProjectName": "pets"},
+		}
+		if withSpec {
+			opts.OpenAPI = spec
+		}
+		if _, err := NewGenerator(templatesDir).Generate(opts); err != nil {
+			t.Fatalf("Generate() unexpected error: %v", err)
+		}
+
+		routes, err := os.ReadFile(filepath.Join(outputDir, "routes.txt"))
+		switch {
+		case !withSpec && err == nil:
+			t.Errorf("routes.txt written without an OpenAPI document")
+		case withSpec && string(routes) != "GET /pets/{petId} show_pet_by_id petId:int64 -> Pet\n":
+			t.Errorf("routes.txt = %q, %v", routes, err)
+		}
+	}
+}
+
+func TestGenerateProto(t *testing.T) {
+	templatesDir := t.TempDir()
+	writeTestTemplate(t, templatesDir, `version: "1.0.0"
+name: basic
+language: test
+framework: basic
+files:
+  - src: sample.proto
+    dest: proto/sample.proto
+    conditions: ["{{ not .Proto }}"]
+  - src: contract.proto.tmpl
+    dest: "proto/{{ with .Proto }}{{ .Path }}{{ end }}"
+    conditions: ["{{ .Proto }}"]
+  - src: handlers.txt.tmpl
+    dest: handlers.txt
+    conditions: ["{{ .Proto }}"]
+`, map[string]string{
+		"sample.proto":        "syntax = \"proto3\";\n",
+		"contract.proto.tmpl": "{{ .Proto.Source }}",
+		"handlers.txt.tmpl":   "{{ range .Proto.Services }}{{ $service := .Name }}{{ range .Methods }}{{ $service }}.{{ .Name }}({{ .Input.Go }}) {{ .Output.Go }}\n{{ end }}{{ end }}",
+	})
+
+	source := `syntax = "proto3";
+package pets.v1;
+import "google/protobuf/empty.proto";
+service PetService {
+  rpc GetPet(GetPetRequest) returns (Pet);
+  rpc Purge(google.protobuf.Empty) returns (google.protobuf.Empty);
+}
+message GetPetRequest { string id = 1; }
+message Pet { string id = 1; }
+`
+	file, err := proto.Parse("pets.proto", []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, withProto := range []bool{true, false} {
+		outputDir := filepath.Join(t.TempDir(), "pets")
+		opts := &Options{
+			ProjectName: "pets",
+			Language:    "test",
+			Framework:   "basic",
+			OutputDir:   outputDir,
+			Variables:   map[string]interface{}{"ProjectName": "pets"},
+		}
+		if withProto {
+			opts.Proto = file
+		}
+		if _, err := NewGenerator(templatesDir).Generate(opts); err != nil {
+			t.Fatalf("Generate() unexpected error: %v", err)
+		}
+
+		_, sampleErr := os.Stat(filepath.Join(outputDir, "proto", "sample.proto"))
+		if !withProto {
+			if sampleErr != nil {
+				t.Errorf("sample.proto not written without a contract: %v", sampleErr)
+			}
+			continue
+		}
+		if sampleErr == nil {
+			t.Errorf("sample.proto written with a contract")
+		}
+		contract, _ := os.ReadFile(filepath.Join(outputDir, "proto", "pets", "v1", "pets.proto"))
+		if string(contract) != source {
+			t.Errorf("proto/pets/v1/pets.proto = %q, want the contract", contract)
+		}
+		handlers, _ := os.ReadFile(filepath.Join(outputDir, "handlers.txt"))
+		if want := "PetService.GetPet(petsv1.GetPetRequest) petsv1.Pet\nPetService.Purge(emptypb.Empty) emptypb.Empty\n"; string(handlers) != want {
+			t.Errorf("handlers.txt = %q, want %q", handlers, want)
+		}
+	}
+}
+
+func TestGenerateDBSchema(t *testing.T) {
+	templatesDir := t.TempDir()
+	writeTestTemplate(t, templatesDir, `version: "1.0.0"
+name: basic
+language: test
+framework: basic
+files:
+  - src: models.txt.tmpl
+    dest: models.txt
+    conditions: ["{{ .DBSchema }}"]
+`, map[string]string{
+		"models.txt.tmpl": "{{ range .DBSchema.Tables }}{{ .Name }}:{{ range .Columns }} {{ .Name }} {{ .Go }}{{ end }}\n{{ end }}",
+	})
+
+	schema := &dbschema.Schema{
+		Dialect:  dbschema.Postgres,
+		Database: "shop",
+		Tables: []dbschema.Table{{
+			Name: "orders",
+			Columns: []dbschema.Column{
+				{Name: "id", DataType: "bigint", Type: dbschema.TypeBigInt, PrimaryKey: true},
+				{Name: "note", DataType: "text", Type: dbschema.TypeText, Nullable: true},
+			},
+			PrimaryKey: []string{"id"},
+		}},
+	}
+
+	for _, withSchema := range []bool{true, false} {
+		outputDir := filepath.Join(t.TempDir(), "shop")
+		opts := &Options{
+			ProjectName: "shop",
+			Language:    "test",
+			Framework:   "basic",
+			OutputDir:   outputDir,
+			Variables:   map[string]interface{}{"ProjectName": "shop"},
+		}
+		if withSchema {
+			opts.DBSchema = schema
+		}
+		if _, err := NewGenerator(templatesDir).Generate(opts); err != nil {
+			t.Fatalf("Generate() unexpected error: %v", err)
+		}
+
+		models, modelsErr := os.ReadFile(filepath.Join(outputDir, "models.txt"))
+		if !withSchema {
+			if modelsErr == nil {
+				t.Errorf("models.txt written without a schema")
+			}
+			continue
+		}
+		if want := "orders: id int64 note *string\n"; string(models) != want {
+			t.Errorf("models.txt = %q, want %q", models, want)
+		}
+
+		metadata, err := ReadMetadata(outputDir)
+		if err != nil {
+			t.Fatalf("ReadMetadata() unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(metadata.DBSchema, schema) {
+			t.Errorf("recorded schema = %+v, want %+v", metadata.DBSchema, schema)
+		}
+	}
+}
+
+func TestGenerateJinja2(t *testing.T) {
+	templatesDir := t.TempDir()
+	writeTestTemplate(t, templatesDir, `version: "1.0.0"
+name: basic
+language: test
+framework: basic
+engine: jinja2
+variables:
+  use_docker:
+    type: string
+    default: "y"
+files:
+  - src: README.md.tmpl
+    dest: "{{ .ProjectNameSnake }}/README.md"
+  - src: raw.txt
+    dest: raw.txt
+`, map[string]string{
+		"README.md.tmpl": "# {{ ProjectName }}\n{% if use_docker == 'y' -%}\ndocker run {{ ProjectName | kebab }}\n{% endif -%}\n",
+		"raw.txt":        "{{ copied as is }}\n",
+	})
+
+	outputDir := filepath.Join(t.TempDir(), "my-app")
+	opts := &Options{
+		ProjectName: "my-app",
+		Language:    "test",
+		Framework:   "basic",
+		OutputDir:   outputDir,
+		Variables:   map[string]interface{}{"ProjectName": "my-app"},
+	}
+	if _, err := NewGenerator(templatesDir).Generate(opts); err != nil {
+		t.Fatalf("Generate() unexpected error: %v", err)
+	}
+
+	readme, _ := os.ReadFile(filepath.Join(outputDir, "my_app", "README.md"))
+	if want := "# my-app\ndocker run my-app\n"; string(readme) != want {
+		t.Errorf("README.md = %q, want %q", readme, want)
+	}
+	raw, _ := os.ReadFile(filepath.Join(outputDir, "raw.txt"))
+	if want := "{{ copied as is }}\n"; string(raw) != want {
+		t.Errorf("raw.txt = %q, want %q", raw, want)
+	}
+
+	writeTestTemplate(t, templatesDir, `version: "1.0.0"
+name: basic
+language: test
+framework: basic
+engine: mustache
+`, nil)
+	opts.OutputDir = filepath.Join(t.TempDir(), "other")
+	if _, err := NewGenerator(templatesDir).Generate(opts); err == nil || !strings.Contains(err.Error(), "invalid engine") {
+		t.Errorf("Generate() error = %v, want invalid engine", err)
+	}
+}
+
+func TestGenerateWorkspace(t *testing.T) {
+	templatesDir := t.TempDir()
+	writeTestTemplate(t, templatesDir, `version: "1.0.0"
+name: basic
+language: test
+framework: basic
+healthcheck:
+  port: 8080
+files:
+  - src: upstreams.txt.tmpl
+    dest: upstreams.txt
+  - src: Dockerfile
+    dest: Dockerfile
+    conditions: ["{{ .IncludeDocker }}"]
+`, map[string]string{
+		"upstreams.txt.tmpl": "{{ range .Services }}{{ .Name }}=http://{{ .Host }}:{{ .Port }}\n{{ end }}",
+		"Dockerfile":         "FROM scratch\n",
+	})
+
+	root := t.TempDir()
+	workspace := "services:\n  - name: orders\n    host: orders\n    port: 8000\n    dir: orders\n    template: python/fastapi\n"
+	if err := os.WriteFile(filepath.Join(root, "devinit-workspace.yaml"), []byte(workspace), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, skip := range []bool{false, true} {
+		outputDir := filepath.Join(root, "services", "billing")
+		os.RemoveAll(outputDir)
+		result, err := NewGenerator(templatesDir).Generate(&Options{
+			ProjectName:   "billing",
+			Language:      "test",
+			Framework:     "basic",
+			OutputDir:     outputDir,
+			Variables:     map[string]interface{}{"ProjectName": "billing", "IncludeDocker": true},
+			SkipWorkspace: skip,
+		})
+		if err != nil {
+			t.Fatalf("Generate() unexpected error: %v", err)
+		}
+
+		upstreams, _ := os.ReadFile(filepath.Join(outputDir, "upstreams.txt"))
+		if skip {
+			if result.Workspace != nil || len(upstreams) != 0 {
+				t.Errorf("Generate() with SkipWorkspace = %+v, upstreams %q, want no workspace", result.Workspace, upstreams)
+			}
+			continue
+		}
+
+		if string(upstreams) != "orders=http://orders:8000\n" {
+			t.Errorf("upstreams.txt = %q, want the orders sibling", upstreams)
+		}
+		want := &WorkspaceResult{
+			Root:    root,
+			Service: template.Service{Name: "billing", Host: "billing", Port: 8080, Dir: "services/billing", Template: "test/basic"},
+			Files:   []string{"compose.yaml", ".env", "devinit-workspace.yaml"},
+		}
+		if !reflect.DeepEqual(result.Workspace, want) || len(result.Warnings) != 0 {
+			t.Errorf("Generate() workspace = %+v, warnings %q, want %+v", result.Workspace, result.Warnings, want)
+		}
+	}
+}
+
+func TestGenerateEnvironment(t *testing.T) {
+	templatesDir := t.TempDir()
+	writeTestTemplate(t, templatesDir, `version: "1.0.0"
+name: basic
+language: test
+framework: basic
+files:
+  - src: env.txt.tmpl
+    dest: env.txt
+`, map[string]string{
+		"env.txt.tmpl": "{{ .OS }}/{{ .Arch }} {{ .Year }} {{ .DevinitVersion }} {{ .PackageManager }}\n",
+	})
+
+	gen := NewGenerator(templatesDir)
+	gen.SetVersion("1.4.0")
+	outputDir := filepath.Join(t.TempDir(), "demo")
+	_, err := gen.Generate(&Options{
+		ProjectName: "demo",
+		Language:    "test",
+		Framework:   "basic",
+		OutputDir:   outputDir,
+		Variables:   map[string]interface{}{"PackageManager": "pnpm"},
+	})
+	if err != nil {
+		t.Fatalf("Generate() unexpected error: %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(outputDir, "env.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := fmt.Sprintf("%s/%s %d 1.4.0 pnpm\n", runtime.GOOS, runtime.GOARCH, time.Now().Year())
+	if string(got) != want {
+		t.Errorf("env.txt = %q, want %q", got, want)
+	}
+
+	// Drift checks render with the recorded environment, so a project
+	// generated in another year (or on another machine) has not drifted
+	metadata, err := ReadMetadata(outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata.Environment == nil || metadata.Environment.DevinitVersion != "1.4.0" {
+		t.Fatalf("metadata environment = %+v, want the devinit version", metadata.Environment)
+	}
+	metadata.Environment.Year = 1999
+	data, err := yaml.Marshal(metadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		MetadataFile: string(data),
+		"env.txt":    strings.Replace(want, fmt.Sprint(time.Now().Year()), "1999", 1),
+	} {
+		if err := os.WriteFile(filepath.Join(outputDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, drift, err := gen.Drift(outputDir); err != nil || len(drift) != 0 {
+		t.Errorf("Drift() = %+v, %v, want no drift", drift, err)
+	}
+}
+
+func TestGenerateProvenance(t *testing.T) {
+	templatesDir := t.TempDir()
+	writeTestTemplate(t, templatesDir, `version: "1.2.0"
+name: basic
+language: test
+framework: basic
+files:
+  - src: main.py
+    dest: main.py
+  - src: Dockerfile
+    dest: Dockerfile
+  - src: package.json
+    dest: package.json
+  - src: README.md
+    dest: README.md
+  - src: post.md
+    dest: docs/post.md
+  - src: style.css
+    dest: style.css
+  - src: main.go
+    dest: main.go
+    provenance: false
+  - src: more.py
+    dest: main.py
+    mode: append
+`, map[string]string{
+		"main.py":      "#!/usr/bin/env python3\nprint('hi')\n",
+		"Dockerfile":   "# syntax=docker/dockerfile:1\nFROM scratch\n",
+		"package.json": "{}\n",
+		"README.md":    "# demo\n",
+		"post.md":      "---\ntitle: Post\n---\n",
+		"style.css":    "body {}\n",
+		"main.go":      "package main\n",
+		"more.py":      "print('more')\n",
+	})
+
+	gen := NewGenerator(templatesDir)
+	gen.SetVersion("1.4.0")
+	outputDir := filepath.Join(t.TempDir(), "demo")
+	_, err := gen.Generate(&Options{
+		ProjectName: "demo",
+		Language:    "test",
+		Framework:   "basic",
+		OutputDir:   outputDir,
+		Provenance:  true,
+	})
+	if err != nil {
+		t.Fatalf("Generate() unexpected error: %v", err)
+	}
+
+	const line = "Generated by devinit 1.4.0 from template test/basic 1.2.0."
+	const note = "Edits to this file may be overwritten when the project is regenerated."
+	want := map[string]string{
+		"main.py":      "#!/usr/bin/env python3\n# " + line + "\n# " + note + "\nprint('hi')\nprint('more')\n",
+		"Dockerfile":   "# syntax=docker/dockerfile:1\n# " + line + "\n# " + note + "\nFROM scratch\n",
+		"package.json": "{}\n",
+		"README.md":    "<!-- " + line + " -->\n<!-- " + note + " -->\n# demo\n",
+		"docs/post.md": "---\ntitle: Post\n---\n",
+		"style.css":    "/* " + line + " */\n/* " + note + " */\nbody {}\n",
+		"main.go":      "package main\n",
+	}
+	for name, content := range want {
+		got, err := os.ReadFile(filepath.Join(outputDir, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != content {
+			t.Errorf("%s = %q, want %q", name, got, content)
+		}
+	}
+
+	// The option is recorded, so drift checks expect the headers
+	if _, drift, err := gen.Drift(outputDir); err != nil || len(drift) != 0 {
+		t.Errorf("Drift() = %+v, %v, want no drift", drift, err)
+	}
+}
+
+func TestGenerateSBOM(t *testing.T) {
+	templatesDir := t.TempDir()
+	writeTestTemplate(t, templatesDir, `version: "1.0.0"
+name: basic
+language: test
+framework: basic
+files:
+  - src: requirements.txt
+    dest: requirements.txt
+`, map[string]string{
+		"requirements.txt": "fastapi==0.104.1\nuvicorn>=0.23\n",
+	})
+
+	gen := NewGenerator(templatesDir)
+	outputDir := filepath.Join(t.TempDir(), "demo")
+	result, err := gen.Generate(&Options{
+		ProjectName: "demo",
+		Language:    "test",
+		Framework:   "basic",
+		OutputDir:   outputDir,
+	})
+	if err != nil {
+		t.Fatalf("Generate() unexpected error: %v", err)
+	}
+
+	if result.SBOM == nil || result.SBOM.Components != 2 {
+		t.Fatalf("Generate() SBOM = %+v, want 2 components", result.SBOM)
+	}
+	data, err := os.ReadFile(filepath.Join(outputDir, ".devinit", "sbom.cdx.json"))
+	if err != nil {
+		t.Fatalf("bill of materials not written: %v", err)
+	}
+	if !strings.Contains(string(data), `"purl": "pkg:pypi/fastapi@0.104.1"`) {
+		t.Errorf("bill of materials missing fastapi:\n%s", data)
+	}
+}
+
+func TestGenerateWriteModes(t *testing.T) {
+	templatesDir := t.TempDir()
+	writeTestTemplate(t, templatesDir, `version: "1.0.0"
+name: basic
+language: test
+framework: basic
+files:
+  - src: Makefile
+    dest: Makefile
+  - src: Makefile.docker
+    dest: Makefile
+    mode: append
+    conditions: ["IncludeDocker"]
+  - src: Makefile.tests
+    dest: Makefile
+    mode: append
+    conditions: ["IncludeTests"]
+  - src: package.json.tmpl
+    dest: package.json
+    mode: merge-json
+  - src: compose.yaml
+    dest: compose.yaml
+  - src: compose.db.yaml
+    dest: compose.yaml
+    mode: merge-yaml
+  - src: README.md.tmpl
+    dest: README.md
+  - src: README.patch
+    dest: README.md
+    mode: patch
+  - src: pyproject.toml
+    dest: pyproject.toml
+  - src: pyproject.tests.toml
+    dest: pyproject.toml
+    mode: merge
+    merge:
+      lists: unique
+`, map[string]string{
+		"Makefile":             "build:\n\tgo build ./...\n",
+		"Makefile.docker":      "docker:\n\tdocker build .\n",
+		"Makefile.tests":       "test:\n\tgo test ./...\n",
+		"package.json.tmpl":    `{"scripts": {"test": "vitest"}}`,
+		"compose.yaml":         "services:\n  app:\n    build: .\n",
+		"compose.db.yaml":      "services:\n  app:\n    depends_on: [db]\n  db:\n    image: postgres\n",
+		"README.md.tmpl":       "# {{ .ProjectName }}\n\nA project.\n",
+		"README.patch":         "@@ -1,3 +1,5 @@\n # demo\n \n A project.\n+\n+Run with `make docker`.\n",
+		"pyproject.toml":       "[project]\ndependencies = [\"httpx\"]\n",
+		"pyproject.tests.toml": "[project]\ndependencies = [\"httpx\", \"pytest\"]\n",
+	})
+
+	// package.json exists before generation, e.g. created by a hook
+	outputDir := filepath.Join(t.TempDir(), "demo")
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(outputDir, "package.json"), []byte(`{"name": "demo", "scripts": {"start": "node ."}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	gen := NewGenerator(templatesDir)
+	result, err := gen.Generate(&Options{
+		ProjectName: "demo",
+		Language:    "test",
+		Framework:   "basic",
+		OutputDir:   outputDir,
+		Variables:   map[string]interface{}{"IncludeDocker": true, "IncludeTests": false},
+	})
+	if err != nil {
+		t.Fatalf("Generate() unexpected error: %v", err)
+	}
+
+	want := map[string]string{
+		"Makefile":       "build:\n\tgo build ./...\ndocker:\n\tdocker build .\n",
+		"package.json":   "{\n  \"name\": \"demo\",\n  \"scripts\": {\n    \"start\": \"node .\",\n    \"test\": \"vitest\"\n  }\n}\n",
+		"compose.yaml":   "services:\n  app:\n    build: .\n    depends_on: [db]\n  db:\n    image: postgres\n",
+		"README.md":      "# demo\n\nA project.\n\nRun with `make docker`.\n",
+		"pyproject.toml": "[project]\ndependencies = [\"httpx\", \"pytest\"]\n",
+	}
+	for name, content := range want {
+		got, err := os.ReadFile(filepath.Join(outputDir, name))
+		if err != nil {
+			t.Fatalf("%s not generated: %v", name, err)
+		}
+		if string(got) != content {
+			t.Errorf("%s = %q, want %q", name, got, content)
+		}
+	}
+
+	var size int64
+	for _, content := range want {
+		size += int64(len(content))
+	}
+	if got := result.TotalSize(); got != size {
+		t.Errorf("TotalSize() = %d, want %d", got, size)
+	}
+	actions := make(map[FileAction]int)
+	for _, f := range result.Files {
+		actions[f.Action]++
+	}
+	if actions[FileActionUpdated] != 5 || actions[FileActionCreated] != 4 {
+		t.Errorf("actions = %v, want 5 updated and 4 created", actions)
+	}
+
+	// Each file is written once, even to backends that cannot replace files
+	recorder := output.NewRecorder()
+	_, err = gen.Generate(&Options{
+		ProjectName: "demo",
+		Language:    "test",
+		Framework:   "basic",
+		OutputDir:   outputDir,
+		Variables:   map[string]interface{}{"IncludeDocker": true, "IncludeTests": true},
+		Output:      recorder,
+	})
+	if err != nil {
+		t.Fatalf("Generate() unexpected error: %v", err)
+	}
+	var paths []string
+	for _, entry := range recorder.Entries() {
+		paths = append(paths, entry.Path)
+	}
+	if want := []string{"Makefile", "package.json", "compose.yaml", "README.md", "pyproject.toml", ".devinit.yaml"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("written paths = %q, want %q", paths, want)
+	}
+
+	// Patches must apply
+	writeTestTemplate(t, templatesDir, `version: "1.0.0"
+name: basic
+language: test
+framework: basic
+files:
+  - src: README.patch
+    dest: README.md
+    mode: patch
+`, nil)
+	_, err = NewGenerator(templatesDir).RenderFiles(mustTemplate(t, templatesDir), "demo", nil)
+	if !errors.Is(err, errcode.ErrTemplateInvalid) {
+		t.Errorf("RenderFiles() with a failing patch error = %v, want %v", err, errcode.ErrTemplateInvalid)
+	}
+}
+
+func mustTemplate(t *testing.T, templatesDir string) *template.Template {
+	t.Helper()
+	tmpl, err := NewGenerator(templatesDir).GetTemplate("test/basic")
+	if err != nil {
+		t.Fatalf("GetTemplate() unexpected error: %v", err)
+	}
+	return tmpl
+}
+
+func TestRenderCache(t *testing.T) {
+	templatesDir := t.TempDir()
+	writeTestTemplate(t, templatesDir, `version: "1.0.0"
+name: basic
+language: test
+framework: basic
+files:
+  - src: readme.txt.tmpl
+    dest: README.txt
+  - src: static.txt
+    dest: static.txt
+`, map[string]string{"readme.txt.tmpl": "# {{ .ProjectName }} {{ .Variables.flavor }}\n", "static.txt": "static\n"})
+
+	gen := NewGenerator(templatesDir)
+	cache := NewRenderCache(t.TempDir())
+	gen.SetRenderCache(cache)
+	generate := func(flavor string) (cached bool, content string) {
+		t.Helper()
+		// The output directory is part of the context, so it stays the same
+		files := output.NewMemory()
+		result, err := gen.Generate(&Options{
+			ProjectName: "demo",
+			Language:    "test",
+			Framework:   "basic",
+			OutputDir:   "demo",
+			Variables:   map[string]interface{}{"flavor": flavor},
+			Output:      files,
+		})
+		if err != nil {
+			t.Fatalf("Generate() unexpected error: %v", err)
+		}
+		if result.Files[1].Cached {
+			t.Error("static file reported as cached")
+		}
+		return result.Files[0].Cached, string(files.Files()["README.txt"])
+	}
+
+	tests := []struct {
+		name       string
+		flavor     string
+		edit       string // new content of readme.txt.tmpl
+		wantCached bool
+		want       string
+	}{
+		{name: "first render", flavor: "plain", want: "# demo plain\n"},
+		{name: "same inputs", flavor: "plain", wantCached: true, want: "# demo plain\n"},
+		{name: "other variables", flavor: "fancy", want: "# demo fancy\n"},
+		{name: "edited source", flavor: "plain", edit: "## {{ .ProjectName }}\n", want: "## demo\n"},
+	}
+
+	for _, tt := range tests {
+		if tt.edit != "" {
+			path := filepath.Join(templatesDir, "test", "basic", "files", "readme.txt.tmpl")
+			if err := os.WriteFile(path, []byte(tt.edit), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		cached, content := generate(tt.flavor)
+		if cached != tt.wantCached || content != tt.want {
+			t.Errorf("%s: cached = %v, content = %q, want %v, %q", tt.name, cached, content, tt.wantCached, tt.want)
+		}
+	}
+
+	// Cleaning removes the entries but nothing else in a shared directory
+	other := filepath.Join(cache.Dir(), "ab", "notes.txt")
+	if err := os.MkdirAll(filepath.Dir(other), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(other, []byte("mine"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if freed, err := cache.Clean(); err != nil || freed == 0 {
+		t.Fatalf("Clean() = %d, %v, want the entries freed", freed, err)
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Errorf("Clean() removed a file it does not own: %v", err)
+	}
+	if cached, _ := generate("plain"); cached {
+		t.Error("file rendered from the cache after Clean()")
+	}
+}
+
+func TestGenerateDeprecatedTemplate(t *testing.T) {
+	templatesDir := t.TempDir()
+	writeTestTemplate(t, templatesDir, `version: "1.0.0"
+name: basic
+language: test
+framework: basic
+deprecated: true
+superseded_by: test/modern
+sunset_date: "2099-01-01"
+files:
+  - src: static.txt
+    dest: static.txt
+`, map[string]string{"static.txt": "static\n"})
+
+	gen := NewGenerator(templatesDir)
+	opts := &Options{
+		ProjectName: "demo",
+		Language:    "test",
+		Framework:   "basic",
+		OutputDir:   filepath.Join(t.TempDir(), "demo"),
+		DryRun:      true,
+	}
+
+	result, err := gen.Generate(opts)
+	if err != nil {
+		t.Fatalf("Generate() unexpected error: %v", err)
+	}
+	want := "template test/basic is deprecated and will be removed on 2099-01-01; use test/modern instead"
+	if result.Deprecation != want {
+		t.Errorf("Deprecation = %q, want %q", result.Deprecation, want)
+	}
+
+	opts.RejectDeprecated = true
+	if _, err := gen.Generate(opts); err == nil {
+		t.Error("Generate() with RejectDeprecated expected error, got nil")
+	}
+}
+
+func TestGenerateDefaultExpressions(t *testing.T) {
+	templatesDir := t.TempDir()
+	metadata := `version: "1.0.0"
+name: basic
+language: test
+framework: basic
+variables:
+  db_url:
+    type: string
+    default: "{{ .Database }}://localhost:{{ .Variables.db_port }}/{{ .ProjectNameSnake }}"
+  db_port:
+    type: int
+    default: '{{ if eq .Database "mysql" }}3306{{ else if eq .Database "postgres" }}5432{{ end }}'
+  cache:
+    type: boolean
+    default: '{{ ne .Database "sqlite" }}'
+files:
+  - src: env.tmpl
+    dest: .env
+`
+	writeTestTemplate(t, templatesDir, metadata, map[string]string{
+		"env.tmpl": "{{ .Variables.db_url }} {{ index .Variables \"db_port\" }} {{ .Variables.cache }}\n",
+	})
+	gen := NewGenerator(templatesDir)
+
+	tests := []struct {
+		name      string
+		variables map[string]interface{}
+		want      string
+	}{
+		{name: "postgres", variables: map[string]interface{}{"Database": "postgres"}, want: "postgres://localhost:5432/my_app 5432 true\n"},
+		{name: "mysql", variables: map[string]interface{}{"Database": "mysql"}, want: "mysql://localhost:3306/my_app 3306 true\n"},
+		{name: "set port", variables: map[string]interface{}{"Database": "mysql", "db_port": 3307}, want: "mysql://localhost:3307/my_app 3307 true\n"},
+		// Empty values leave variables unset
+		{name: "no port", variables: map[string]interface{}{"Database": "sqlite", "db_url": "sqlite:///app.db"}, want: "sqlite:///app.db <no value> false\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := output.NewMemory()
+			result, err := gen.Generate(&Options{
+				ProjectName: "my-app",
+				Language:    "test",
+				Framework:   "basic",
+				OutputDir:   "my-app",
+				Variables:   tt.variables,
+				Output:      files,
+			})
+			if err != nil {
+				t.Fatalf("Generate() unexpected error: %v", err)
+			}
+			if got := string(files.Files()[".env"]); got != tt.want {
+				t.Errorf(".env = %q, want %q", got, tt.want)
+			}
+			if port, ok := result.Template.Variables["db_port"]; !ok || port.DefaultExpression() == "" {
+				t.Error("Generate() changed the variables of the template")
+			}
+		})
+	}
+
+	// Cycles have no order to evaluate the defaults in
+	cyclic := strings.Replace(metadata, ".Database }}://", ".Database }}{{ .Variables.cache }}://", 1)
+	cyclic = strings.Replace(cyclic, `'{{ ne .Database "sqlite" }}'`, `'{{ ne .Variables.db_url "" }}'`, 1)
+	writeTestTemplate(t, templatesDir, cyclic, nil)
+	_, err := NewGenerator(templatesDir).GetTemplate("test/basic")
+	if !errors.Is(err, errcode.ErrTemplateInvalid) || !strings.Contains(err.Error(), "cache → db_url → cache") {
+		t.Errorf("GetTemplate() with a cycle error = %v, want the cycle", err)
+	}
+
+	// Values must fit the type of the variable
+	writeTestTemplate(t, templatesDir, strings.Replace(metadata, "3306", "mysql-port", 1), nil)
+	_, err = NewGenerator(templatesDir).Generate(&Options{
+		ProjectName: "my-app",
+		Language:    "test",
+		Framework:   "basic",
+		Variables:   map[string]interface{}{"Database": "mysql"},
+		DryRun:      true,
+	})
+	if !errors.Is(err, errcode.ErrTemplateInvalid) {
+		t.Errorf("Generate() with an int default of %q error = %v, want %v", "mysql-port", err, errcode.ErrTemplateInvalid)
+	}
+}
+
+func TestGenerateProfile(t *testing.T) {
+	templatesDir := t.TempDir()
+	writeTestTemplate(t, templatesDir, `version: "1.0.0"
+name: basic
+language: test
+framework: basic
+variables:
+  greeting:
+    type: string
+    default: hello
+profiles:
+  minimal:
+    description: No Docker
+    variables:
+      IncludeDocker: false
+      greeting: hi
+files:
+  - src: greeting.txt.tmpl
+    dest: greeting.txt
+  - src: docker.txt
+    dest: docker.txt
+    conditions: ["{{ .IncludeDocker }}"]
+  - src: minimal.txt
+    dest: minimal.txt
+    conditions: ['{{ eq .Profile "minimal" }}']
+`, map[string]string{
+		"greeting.txt.tmpl": "{{ .Variables.greeting }}",
+		"docker.txt":        "docker\n",
+		"minimal.txt":       "minimal\n",
+	})
+
+	tests := []struct {
+		name      string
+		profile   string
+		variables map[string]interface{}
+		wantFiles []string
+		wantGreet string
+		wantErr   bool
+	}{
+		{
+			name:      "no profile",
+			wantFiles: []string{"docker.txt", "greeting.txt"},
+			wantGreet: "hello",
+		},
+		{
+			name:      "profile presets variables",
+			profile:   "minimal",
+			wantFiles: []string{"greeting.txt", "minimal.txt"},
+			wantGreet: "hi",
+		},
+		{
+			name:      "explicit variables override profile",
+			profile:   "minimal",
+			variables: map[string]interface{}{"IncludeDocker": true, "greeting": "hey"},
+			wantFiles: []string{"docker.txt", "greeting.txt", "minimal.txt"},
+			wantGreet: "hey",
+		},
+		{
+			name:    "unknown profile",
+			profile: "full",
+			wantErr: true,
+		},
+	}
+
+	gen := NewGenerator(templatesDir)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outputDir := filepath.Join(t.TempDir(), "demo")
+			result, err := gen.Generate(&Options{
+				ProjectName: "demo",
+				Language:    "test",
+				Framework:   "basic",
+				OutputDir:   outputDir,
+				Variables:   tt.variables,
+				Defaults:    map[string]interface{}{"IncludeDocker": true},
+				Profile:     tt.profile,
+				SkipHooks:   true,
+			})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Generate() expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Generate() unexpected error: %v", err)
+			}
+
+			var written []string
+			for _, f := range result.Files {
+				if f.Action != FileActionSkipped {
+					written = append(written, filepath.Base(f.Path))
+				}
+			}
+			sort.Strings(written)
+			if strings.Join(written, ",") != strings.Join(tt.wantFiles, ",") {
+				t.Errorf("written files = %v, want %v", written, tt.wantFiles)
+			}
+
+			greeting, err := os.ReadFile(filepath.Join(outputDir, "greeting.txt"))
+			if err != nil {
+				t.Fatalf("greeting.txt not generated: %v", err)
+			}
+			if string(greeting) != tt.wantGreet {
+				t.Errorf("greeting.txt = %q, want %q", greeting, tt.wantGreet)
+			}
+		})
+	}
+}
+
+func TestGenerateHealthcheck(t *testing.T) {
+	templatesDir := t.TempDir()
+	writeTestTemplate(t, templatesDir, `version: "1.0.0"
+name: basic
+language: test
+framework: basic
+files:
+  - src: port.txt.tmpl
+    dest: port.txt
+healthcheck:
+  command: "curl -f http://localhost:{{ .Port }}/health"
+  port: 8000
+`, map[string]string{"port.txt.tmpl": "{{ .Port }}"})
+
+	tests := []struct {
+		name      string
+		variables map[string]interface{}
+		wantPort  int
+	}{
+		{name: "template port", wantPort: 8000},
+		{name: "chosen port", variables: map[string]interface{}{"Port": 8123}, wantPort: 8123},
+	}
+
+	gen := NewGenerator(templatesDir)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := gen.Generate(&Options{
+				ProjectName: "demo",
+				Language:    "test",
+				Framework:   "basic",
+				OutputDir:   filepath.Join(t.TempDir(), "demo"),
+				Variables:   tt.variables,
+			})
+			if err != nil {
+				t.Fatalf("Generate() unexpected error: %v", err)
+			}
+
+			want := fmt.Sprintf("curl -f http://localhost:%d/health", tt.wantPort)
+			if result.Healthcheck == nil || result.Healthcheck.Port != tt.wantPort || result.Healthcheck.Command != want {
+				t.Errorf("Healthcheck = %+v, want port %d and command %q", result.Healthcheck, tt.wantPort, want)
+			}
+
+			content, err := os.ReadFile(filepath.Join(result.OutputDir, "port.txt"))
+			if err != nil {
+				t.Fatalf("port.txt not generated: %v", err)
+			}
+			if string(content) != fmt.Sprint(tt.wantPort) {
+				t.Errorf("port.txt = %q, want %d", content, tt.wantPort)
+			}
+		})
+	}
+}
+
+func TestGenerateSmokeTest(t *testing.T) {
+	templatesDir := t.TempDir()
+	writeTestTemplate(t, templatesDir, `version: "1.0.0"
+name: basic
+language: test
+framework: basic
+files:
+  - src: readme.txt
+    dest: README.txt
+smoke_test:
+  setup:
+    - "touch setup-ran"
+  run: "{{ .Variables.run }}"
+`, map[string]string{"readme.txt": "readme\n"})
+
+	tests := []struct {
+		name        string
+		run         string
+		mode        SmokeMode
+		wantPassed  bool
+		wantSkipped bool
+		wantErr     bool
+	}{
+		{name: "passing", run: "ls setup-ran README.txt", mode: SmokeLocal, wantPassed: true},
+		{name: "failing", run: "ls missing.txt", mode: SmokeLocal},
+		{name: "no command", run: "", mode: SmokeLocal, wantSkipped: true},
+		{name: "container without image", run: "ls", mode: SmokeContainer, wantErr: true},
+	}
+
+	gen := NewGenerator(templatesDir)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := gen.Generate(&Options{
+				ProjectName: "demo",
+				Language:    "test",
+				Framework:   "basic",
+				OutputDir:   filepath.Join(t.TempDir(), "demo"),
+				Variables:   map[string]interface{}{"run": tt.run},
+				SmokeTest:   tt.mode,
+			})
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Generate() expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Generate() unexpected error: %v", err)
+			}
+
+			smoke := result.SmokeTest
+			if smoke == nil || smoke.Passed != tt.wantPassed || smoke.Skipped() != tt.wantSkipped {
+				t.Fatalf("SmokeTest = %+v, want passed %v, skipped %v", smoke, tt.wantPassed, tt.wantSkipped)
+			}
+			if !tt.wantPassed && !tt.wantSkipped && (smoke.Error == "" || len(smoke.Output) == 0) {
+				t.Errorf("SmokeTest = %+v, want the error and output of the failed step", smoke)
+			}
+
+			// The test runs in a copy; the project stays as generated
+			if _, err := os.Stat(filepath.Join(result.OutputDir, "setup-ran")); !os.IsNotExist(err) {
+				t.Errorf("smoke test setup ran in the project directory (stat error %v)", err)
+			}
+		})
+	}
+}
+
+func TestGenerateScan(t *testing.T) {
+	templatesDir := t.TempDir()
+	writeTestTemplate(t, templatesDir, `version: "1.0.0"
+name: basic
+language: test
+framework: basic
+files:
+  - src: package-lock.json
+    dest: package-lock.json
+`, map[string]string{"package-lock.json": "{}\n"})
+
+	// Fake scanners on PATH: npm reports a moderate advisory, trivy (the
+	// image scanner) is missing
+	binDir := t.TempDir()
+	npm := `#!/bin/sh
+echo '{"vulnerabilities": {"semver": {"via": [{"name": "semver", "title": "ReDoS", "url": "https://github.com/advisories/GHSA-1", "severity": "moderate"}]}}}'
+exit 1
+`
+	if err := os.WriteFile(filepath.Join(binDir, "npm"), []byte(npm), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir)
+
+	tests := []struct {
+		name        string
+		target      ScanTarget
+		failOn      vulnscan.Severity
+		wantFailed  bool
+		wantScanned bool
+	}{
+		{name: "finding below threshold", target: ScanProject, wantScanned: true},
+		{name: "finding at threshold", target: ScanProject, failOn: vulnscan.SeverityMedium, wantFailed: true, wantScanned: true},
+		{name: "scanner missing", target: ScanImage, failOn: vulnscan.SeverityLow},
+	}
+
+	gen := NewGenerator(templatesDir)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := gen.Generate(&Options{
+				ProjectName: "demo",
+				Language:    "test",
+				Framework:   "basic",
+				OutputDir:   filepath.Join(t.TempDir(), "demo"),
+				Scan:        tt.target,
+				ScanFailOn:  tt.failOn,
+			})
+			if err != nil {
+				t.Fatalf("Generate() unexpected error: %v", err)
+			}
+
+			scan := result.Scan
+			if scan == nil {
+				t.Fatal("Generate() Scan = nil")
+			}
+			if scan.Failed != tt.wantFailed || scan.Scanned() != tt.wantScanned {
+				t.Errorf("Scan failed = %v, scanned = %v, want %v and %v", scan.Failed, scan.Scanned(), tt.wantFailed, tt.wantScanned)
+			}
+			if tt.wantScanned {
+				if len(scan.Findings) != 1 || scan.Findings[0].ID != "GHSA-1" || scan.Findings[0].Severity != vulnscan.SeverityMedium {
+					t.Errorf("Scan findings = %+v, want GHSA-1 of medium severity", scan.Findings)
+				}
+			} else if len(result.Warnings) == 0 {
+				t.Error("Generate() without a scanner expected a warning")
+			}
+		})
+	}
+}
+
+func TestFileGroups(t *testing.T) {
+	templatesDir := t.TempDir()
+	writeTestTemplate(t, templatesDir, `version: "1.0.0"
+name: basic
+language: test
+framework: basic
+variables:
+  include_docker:
+    type: boolean
+    default: true
+    description: "Include Docker configuration"
+  database:
+    type: choice
+    choices: ["postgres", "none"]
+    default: "none"
+  port:
+    type: int
+    default: 8000
+files:
+  - src: main.py
+    dest: main.py
+  - src: Dockerfile
+    dest: Dockerfile
+    conditions: ["{{ .IncludeDocker }}"]
+  - src: ci.yml
+    dest: .github/workflows/ci.yml
+    conditions: ["{{ .IncludeDocker }}", '{{ eq .CIProvider "github" }}']
+  - src: db.py
+    dest: db.py
+    conditions: ["{{ ne .Variables.database \"none\" }}", "{{ gt .Variables.port 0 }}"]
+`, map[string]string{"main.py": "", "Dockerfile": "", "ci.yml": "", "db.py": ""})
+
+	gen := NewGenerator(templatesDir)
+	groups, err := gen.FileGroups(&Options{
+		ProjectName: "demo",
+		Language:    "test",
+		Framework:   "basic",
+		Variables:   map[string]interface{}{"IncludeDocker": true, "CIProvider": ""},
+	})
+	if err != nil {
+		t.Fatalf("FileGroups() unexpected error: %v", err)
+	}
+
+	want := []FileGroup{
+		{Variable: "IncludeDocker", Description: "Include Docker configuration", Bool: true, Value: true, Enabled: true, Files: []string{"Dockerfile", ".github/workflows/ci.yml"}},
+		{Variable: "CIProvider", Value: "", Files: []string{".github/workflows/ci.yml"}},
+		{Variable: "database", Value: "none", Choices: []string{"postgres", "none"}, Files: []string{"db.py"}},
+	}
+	if !reflect.DeepEqual(groups, want) {
+		t.Errorf("FileGroups() =\n%+v\nwant\n%+v", groups, want)
+	}
+}
+
+func TestGenerateErrorCodes(t *testing.T) {
+	templatesDir := t.TempDir()
+	writeTestTemplate(t, templatesDir, `version: "1.0.0"
+name: basic
+language: test
+framework: basic
+variables:
+  flavor:
+    type: choice
+    choices: [plain, fancy]
+hooks:
+  post_generate:
+    - run: "{{ .Variables.hook }}"
+files:
+  - src: readme.txt
+    dest: README.txt
+`, map[string]string{"readme.txt": "readme\n"})
+
+	tests := []struct {
+		name      string
+		framework string
+		variables map[string]interface{}
+		profile   string
+		want      error
+	}{
+		{name: "unknown template", framework: "missing", want: errcode.ErrTemplateNotFound},
+		{name: "invalid choice", variables: map[string]interface{}{"flavor": "spicy"}, want: errcode.ErrVariableInvalid},
+		{name: "unknown profile", profile: "full", want: errcode.ErrVariableInvalid},
+		{name: "failing hook", variables: map[string]interface{}{"hook": "false"}, want: errcode.ErrHookFailed},
+	}
+
+	gen := NewGenerator(templatesDir)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			framework := tt.framework
+			if framework == "" {
+				framework = "basic"
+			}
+			_, err := gen.Generate(&Options{
+				ProjectName: "demo",
+				Language:    "test",
+				Framework:   framework,
+				OutputDir:   filepath.Join(t.TempDir(), "demo"),
+				Variables:   tt.variables,
+				Profile:     tt.profile,
+			})
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Generate() error = %v (code %s), want %v", err, errcode.Of(err), tt.want)
+			}
+		})
+	}
+}
+
+func TestRequirements(t *testing.T) {
+	templatesDir := t.TempDir()
+	writeTestTemplate(t, templatesDir, `version: "1.0.0"
+name: basic
+language: test
+framework: basic
+variables:
+  build_tool:
+    type: choice
+    choices: [maven, gradle]
+    default: maven
+requirements:
+  system:
+    - command: java
+      required: true
+    - command: docker
+      when: "{{ .IncludeDocker }}"
+    - command: gradle
+      required: true
+      when: '{{ eq .Variables.build_tool "gradle" }}'
+  checks:
+    - disk_free: 1GB
+    - name: devnet
+      docker_network: devnet
+      when: "{{ .IncludeDocker }}"
+files:
+  - src: readme.txt
+    dest: README.txt
+`, map[string]string{"readme.txt": "readme\n"})
+
+	tests := []struct {
+		name       string
+		variables  map[string]interface{}
+		want       []string
+		wantChecks []string
+	}{
+		{name: "conditions not met", variables: map[string]interface{}{"IncludeDocker": false}, want: []string{"java"}, wantChecks: []string{"disk_free 1GB"}},
+		{name: "flag condition", variables: map[string]interface{}{"IncludeDocker": true}, want: []string{"java", "docker"}, wantChecks: []string{"disk_free 1GB", "devnet"}},
+		{name: "variable condition", variables: map[string]interface{}{"build_tool": "gradle"}, want: []string{"java", "gradle"}, wantChecks: []string{"disk_free 1GB"}},
+	}
+
+	gen := NewGenerator(templatesDir)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := &Options{
+				ProjectName: "demo",
+				Language:    "test",
+				Framework:   "basic",
+				Variables:   tt.variables,
+			}
+			reqs, err := gen.Requirements(opts)
+			if err != nil {
+				t.Fatalf("Requirements() unexpected error: %v", err)
+			}
+
+			var got []string
+			for _, req := range reqs {
+				got = append(got, req.Command)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Requirements() = %v, want %v", got, tt.want)
+			}
+
+			checks, err := gen.Checks(opts)
+			if err != nil {
+				t.Fatalf("Checks() unexpected error: %v", err)
+			}
+			got = nil
+			for _, check := range checks {
+				got = append(got, check.Label())
+			}
+			if strings.Join(got, ",") != strings.Join(tt.wantChecks, ",") {
+				t.Errorf("Checks() = %v, want %v", got, tt.wantChecks)
+			}
+		})
+	}
+
+	// A check verifies exactly one thing, and sizes must parse
+	for _, check := range []string{"url: https://example.com\n      disk_free: 1GB", "name: empty", "memory: lots", "script: ./check\n      required_in: [always]"} {
+		writeTestTemplate(t, templatesDir, "version: \"1.0.0\"\nrequirements:\n  checks:\n    - "+check+"\n", nil)
+		if _, err := NewGenerator(templatesDir).GetTemplate("test/basic"); !errors.Is(err, errcode.ErrTemplateInvalid) {
+			t.Errorf("GetTemplate() with check %q error = %v, want %v", check, err, errcode.ErrTemplateInvalid)
+		}
+	}
+}
+
+func TestGenerateHookLog(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing")
+	generate := func(level string, live io.Writer) (*GenerationResult, error) {
+		templatesDir := t.TempDir()
+		writeTestTemplate(t, templatesDir, `version: "1.0.0"
+name: basic
+language: test
+framework: basic
+hooks:
+  post_generate:
+    - run: "echo hello from hook"
+    - run: "ls `+missing+`"
+      error_level: `+level+`
+files:
+  - src: readme.txt
+    dest: README.txt
+`, map[string]string{"readme.txt": "readme\n"})
+
+		return NewGenerator(templatesDir).Generate(&Options{
+			ProjectName: "demo",
+			Language:    "test",
+			Framework:   "basic",
+			OutputDir:   filepath.Join(t.TempDir(), "demo"),
+			HookOutput:  live,
+		})
+	}
+
+	var live strings.Builder
+	result, err := generate("warn", &live)
+	if err != nil {
+		t.Fatalf("Generate() unexpected error: %v", err)
+	}
+	if !strings.Contains(live.String(), "hello from hook") {
+		t.Errorf("live output = %q, want hook output", live.String())
+	}
+
+	if filepath.Dir(result.HookLog) != filepath.Join(result.OutputDir, ".devinit", "logs") {
+		t.Fatalf("HookLog = %q, want a file in .devinit/logs", result.HookLog)
+	}
+	log, err := os.ReadFile(result.HookLog)
+	if err != nil {
+		t.Fatalf("hook log not written: %v", err)
+	}
+	for _, want := range []string{"==> post_generate[0]: echo hello from hook", "hello from hook", "==> post_generate[1]: ls "} {
+		if !strings.Contains(string(log), want) {
+			t.Errorf("hook log = %q, want it to contain %q", log, want)
+		}
+	}
+
+	if len(result.Hooks) != 2 || result.Hooks[0].Output != nil || len(result.Hooks[1].Output) == 0 {
+		t.Errorf("Hooks = %+v, want output only for the failed hook", result.Hooks)
+	}
+
+	// Aborting hooks include the tail of their output in the error
+	_, err = generate("error", nil)
+	if err == nil || !strings.Contains(err.Error(), "full log:") || !strings.Contains(err.Error(), "No such file") {
+		t.Errorf("Generate() error = %v, want the tail of the hook output", err)
+	}
+}
+
+func TestGenerateHookRetry(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing")
+	templatesDir := t.TempDir()
+	writeTestTemplate(t, templatesDir, `version: "1.0.0"
+name: basic
+language: test
+framework: basic
+hooks:
+  post_generate:
+    - run: "ls `+missing+`"
+      network: true
+      error_level: warn
+    - run: "devinit-no-such-command"
+      network: true
+      error_level: warn
+    - run: "ls `+missing+`"
+      error_level: warn
+`, nil)
+
+	tests := []struct {
+		name          string
+		retryAttempts int
+		want          []int
+	}{
+		{name: "configured policy", want: []int{3, 1, 1}},
+		{name: "attempts overridden", retryAttempts: 2, want: []int{2, 1, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gen := NewGenerator(templatesDir)
+			gen.SetRetryPolicy(retry.Policy{Attempts: 3})
+
+			var progress strings.Builder
+			result, err := gen.Generate(&Options{
+				ProjectName:   "demo",
+				Language:      "test",
+				Framework:     "basic",
+				OutputDir:     filepath.Join(t.TempDir(), "demo"),
+				RetryAttempts: tt.retryAttempts,
+				Progress:      &progress,
+			})
+			if err != nil {
+				t.Fatalf("Generate() unexpected error: %v", err)
+			}
+
+			var got []int
+			for _, h := range result.Hooks {
+				got = append(got, h.Attempts)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("Attempts = %v, want %v", got, tt.want)
+			}
+
+			total := tt.want[0]
+			if notices := strings.Count(progress.String(), "\n"); notices != total-1 {
+				t.Errorf("progress = %q, want %d retry notices", progress.String(), total-1)
+			}
+			if want := fmt.Sprintf("attempt %d/%d", total-1, total); !strings.Contains(progress.String(), want) {
+				t.Errorf("progress = %q, want it to contain %q", progress.String(), want)
+			}
+
+			log, err := os.ReadFile(result.HookLog)
+			if err != nil {
+				t.Fatalf("hook log not written: %v", err)
+			}
+			if want := fmt.Sprintf("attempt %d/%d)", total, total); !strings.Contains(string(log), want) {
+				t.Errorf("hook log = %q, want it to contain %q", log, want)
+			}
+		})
+	}
+}
+
+func TestGenerateHookNetworkEnv(t *testing.T) {
+	templatesDir := t.TempDir()
+	writeTestTemplate(t, templatesDir, `version: "1.0.0"
+name: basic
+language: test
+framework: basic
+hooks:
+  post_generate:
+    - run: "printenv NODE_EXTRA_CA_CERTS"
+`, nil)
+
+	gen := NewGenerator(templatesDir)
+	gen.SetNetwork(network.Settings{CABundle: "/etc/ssl/corp-ca.pem"})
+
+	var live strings.Builder
+	if _, err := gen.Generate(&Options{
+		ProjectName: "demo",
+		Language:    "test",
+		Framework:   "basic",
+		OutputDir:   filepath.Join(t.TempDir(), "demo"),
+		HookOutput:  &live,
+	}); err != nil {
+		t.Fatalf("Generate() unexpected error: %v", err)
+	}
+	if got := strings.TrimSpace(live.String()); got != "/etc/ssl/corp-ca.pem" {
+		t.Errorf("hook saw NODE_EXTRA_CA_CERTS = %q, want the CA bundle", got)
+	}
+}
+
+func TestGenerateHookEnv(t *testing.T) {
+	templatesDir := t.TempDir()
+	writeTestTemplate(t, templatesDir, `version: "1.2.0"
+name: basic
+language: test
+framework: basic
+hooks:
+  post_generate:
+    - run: "echo {{ .ProjectNameSnake }} {{ .Variables.region }}"
+    - run: "printenv DEVINIT_PROJECT_NAME DEVINIT_TEMPLATE_NAME DEVINIT_TEMPLATE_VERSION DEVINIT_HOOK_STAGE DEVINIT_PROJECT_DIR"
+    - run: "printenv DEVINIT_VARIABLES"
+`, nil)
+
+	outputDir := filepath.Join(t.TempDir(), "demo-api")
+	var live strings.Builder
+	if _, err := NewGenerator(templatesDir).Generate(&Options{
+		ProjectName: "demo-api",
+		Language:    "test",
+		Framework:   "basic",
+		OutputDir:   outputDir,
+		Variables:   map[string]interface{}{"region": "eu-west-1"},
+		HookOutput:  &live,
+	}); err != nil {
+		t.Fatalf("Generate() unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(live.String()), "\n")
+	want := []string{"demo_api eu-west-1", "demo-api", "test/basic", "1.2.0", "post_generate", outputDir}
+	if len(lines) != len(want)+1 || !reflect.DeepEqual(lines[:len(want)], want) {
+		t.Fatalf("hook output = %q, want %q and the variables", lines, want)
+	}
+	var variables map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[len(want)]), &variables); err != nil || variables["region"] != "eu-west-1" {
+		t.Errorf("DEVINIT_VARIABLES = %s (%v), want a JSON object with region", lines[len(want)], err)
+	}
+}
+
+func TestGenerateHookOrder(t *testing.T) {
+	templatesDir := t.TempDir()
+	writeTestTemplate(t, templatesDir, `version: "1.0.0"
+name: basic
+language: test
+framework: basic
+hooks:
+  post_generate:
+    - run: "echo test"
+      name: test
+      after: [install]
+      idempotent: true
+    - run: "echo docker"
+      when: IncludeDocker
+    - run: "false"
+      name: lint
+      error_level: ignore
+    - run: "echo format"
+      after: [lint]
+    - run: "echo install"
+      name: install
+      order: -1
+`, nil)
+
+	var live strings.Builder
+	result, err := NewGenerator(templatesDir).Generate(&Options{
+		ProjectName: "demo",
+		Language:    "test",
+		Framework:   "basic",
+		OutputDir:   filepath.Join(t.TempDir(), "demo"),
+		HookOutput:  &live,
+	})
+	if err != nil {
+		t.Fatalf("Generate() unexpected error: %v", err)
+	}
+
+	if got, want := strings.Fields(live.String()), []string{"install", "test"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("hook output = %q, want %q", got, want)
+	}
+
+	var got []string
+	for _, h := range result.Hooks {
+		status := "ran"
+		switch {
+		case h.When != "":
+			status = "skipped when " + h.When
+		case h.BlockedBy != "":
+			status = "blocked by " + h.BlockedBy
+		case h.Error != "":
+			status = "failed"
+		}
+		got = append(got, h.Command+": "+status)
+	}
+	want := []string{
+		"echo install: ran",
+		"echo test: ran",
+		"echo docker: skipped when IncludeDocker",
+		"false: failed",
+		"echo format: blocked by lint",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("hooks = %q, want %q", got, want)
+	}
+	if !result.Hooks[1].Idempotent || result.Hooks[1].Name != "test" {
+		t.Errorf("hook %+v, want the idempotent test hook", result.Hooks[1])
+	}
+}
+
+func TestRunProjectHooks(t *testing.T) {
+	templatesDir := t.TempDir()
+	writeTestTemplate(t, templatesDir, `version: "1.0.0"
+name: basic
+language: test
+framework: basic
+hooks:
+  post_generate:
+    - run: "echo seed {{ .Variables.db }}"
+      name: seed
+      idempotent: true
+    - run: "echo certs"
+`, nil)
+
+	outputDir := filepath.Join(t.TempDir(), "demo")
+	if _, err := NewGenerator(templatesDir).Generate(&Options{
+		ProjectName: "demo",
+		Language:    "test",
+		Framework:   "basic",
+		OutputDir:   outputDir,
+		Variables:   map[string]interface{}{"db": "demo_dev"},
+	}); err != nil {
+		t.Fatalf("Generate() unexpected error: %v", err)
+	}
+
+	// The hooks recorded in the project are run, even without its template
+	gen := NewGenerator(t.TempDir())
+	hooks, err := gen.ProjectHooks(outputDir)
+	if err != nil {
+		t.Fatalf("ProjectHooks() unexpected error: %v", err)
+	}
+	var labels []string
+	for _, h := range hooks {
+		labels = append(labels, h.Stage+" "+h.Label)
+	}
+	if want := []string{"post_generate seed", "post_generate post_generate[1]"}; !reflect.DeepEqual(labels, want) {
+		t.Fatalf("hooks = %q, want %q", labels, want)
+	}
+
+	var live strings.Builder
+	run, err := gen.RunProjectHooks(outputDir, hooks[:1], &Options{HookOutput: &live})
+	if err != nil {
+		t.Fatalf("RunProjectHooks() unexpected error: %v", err)
+	}
+	if got := strings.TrimSpace(live.String()); got != "seed demo_dev" {
+		t.Errorf("hook output = %q, want %q", got, "seed demo_dev")
+	}
+	if len(run.Hooks) != 1 || run.Hooks[0].Name != "seed" || run.Hooks[0].Error != "" || run.HookLog == "" {
+		t.Errorf("run = %+v, want seed run and logged", run)
+	}
+}
+
+func TestLoadInvalidHookOrder(t *testing.T) {
+	tests := []struct {
+		name  string
+		hooks string
+		want  string
+	}{
+		{
+			name:  "duplicate name",
+			hooks: "    - {run: a, name: x}\n    - {run: b, name: x}\n",
+			want:  `duplicate hook name "x"`,
+		},
+		{
+			name:  "unknown hook",
+			hooks: "    - {run: a, after: [x]}\n",
+			want:  `unknown hook "x"`,
+		},
+		{
+			name:  "cycle",
+			hooks: "    - {run: a, name: x, after: [y]}\n    - {run: b, name: y, after: [x]}\n",
+			want:  "hooks x, y run after each other",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			templatesDir := t.TempDir()
+			writeTestTemplate(t, templatesDir, "version: \"1.0.0\"\nname: basic\nlanguage: test\nframework: basic\nhooks:\n  post_generate:\n"+tt.hooks, nil)
+
+			_, err := template.NewLoader(templatesDir).Load("test/basic")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateTemplateVersion(t *testing.T) {
+	metadata := "version: %q\nname: basic\nlanguage: test\nframework: basic\nfiles:\n  - src: VERSION\n    dest: VERSION\n"
+	store, builtin := t.TempDir(), t.TempDir()
+	writeTestTemplate(t, store, fmt.Sprintf(metadata, "2.0.0"), map[string]string{"VERSION": "2"})
+	writeTestTemplate(t, builtin, fmt.Sprintf(metadata, "1.5.0"), map[string]string{"VERSION": "1.5"})
+	stored := filepath.Join(store, "test", "basic", "versions", "1.0.0")
+	if err := os.MkdirAll(filepath.Join(stored, "files"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(stored, "template.yaml"), []byte(fmt.Sprintf(metadata, "1.0.0")), 0644)
+	os.WriteFile(filepath.Join(stored, "files", "VERSION"), []byte("1"), 0644)
+
+	gen := NewGenerator(store)
+	gen.SetFallback(func() string { return builtin })
+
+	if versions, err := gen.ListTemplateVersions("test/basic"); err != nil || !reflect.DeepEqual(versions, []string{"2.0.0", "1.0.0", "1.5.0"}) {
+		t.Errorf("ListTemplateVersions() = %q, %v, want the store's versions then the built-in one", versions, err)
+	}
+
+	tests := []struct {
+		version string
+		want    string
+		wantErr bool
+	}{
+		{version: "", want: "2"},
+		{version: "1.0.0", want: "1"},
+		{version: "v1.0.0", want: "1"},
+		{version: "1.5.0", want: "1.5"},
+		{version: "3.0.0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			outputDir := filepath.Join(t.TempDir(), "demo")
+			_, err := gen.Generate(&Options{
+				ProjectName:     "demo",
+				Language:        "test",
+				Framework:       "basic",
+				TemplateVersion: tt.version,
+				OutputDir:       outputDir,
+			})
+			if tt.wantErr {
+				if errcode.Of(err) != errcode.TemplateNotFound {
+					t.Errorf("Generate() error = %v, want template not found", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Generate() unexpected error: %v", err)
+			}
+			if got, _ := os.ReadFile(filepath.Join(outputDir, "VERSION")); string(got) != tt.want {
+				t.Errorf("VERSION = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateDelimiters(t *testing.T) {
+	templatesDir := t.TempDir()
+	writeTestTemplate(t, templatesDir, `version: "1.0.0"
+name: basic
+language: test
+framework: basic
+delimiters: ["[[", "]]"]
+files:
+  - src: ci.yml.tmpl
+    dest: ci.yml
+  - src: README.md.tmpl
+    dest: README.md
+    delimiters: ["<%", "%>"]
+`, map[string]string{
+		"ci.yml.tmpl":    "name: [[ .ProjectName ]]\nkey: ${{ hashFiles('go.sum') }}\n",
+		"README.md.tmpl": "# <% .ProjectName %> [[ x ]]\n",
+	})
+
+	outputDir := filepath.Join(t.TempDir(), "demo")
+	if _, err := NewGenerator(templatesDir).Generate(&Options{ProjectName: "demo", Language: "test", Framework: "basic", OutputDir: outputDir}); err != nil {
+		t.Fatalf("Generate() unexpected error: %v", err)
+	}
+	for name, want := range map[string]string{
+		"ci.yml":    "name: demo\nkey: ${{ hashFiles('go.sum') }}\n",
+		"README.md": "# demo [[ x ]]\n",
+	} {
+		if got, _ := os.ReadFile(filepath.Join(outputDir, name)); string(got) != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+
+	for _, delims := range []string{`["[["]`, `["[[", ""]`} {
+		writeTestTemplate(t, templatesDir, "version: \"1.0.0\"\nname: basic\nlanguage: test\nframework: basic\ndelimiters: "+delims+"\n", nil)
+		if _, err := template.NewLoader(templatesDir).Load("test/basic"); err == nil || !strings.Contains(err.Error(), "invalid delimiters") {
+			t.Errorf("Load() with delimiters %s error = %v, want invalid delimiters", delims, err)
+		}
+	}
+}
+
+func TestGenerateEscaping(t *testing.T) {
+	templatesDir := t.TempDir()
+	writeTestTemplate(t, templatesDir, `version: "1.0.0"
+name: basic
+language: test
+framework: basic
+files:
+  - src: ci.yml.tmpl
+    dest: ci.yml
+  - src: broken.yml.tmpl
+    dest: broken.yml
+    conditions: ["false"]
+`, map[string]string{
+		"ci.yml.tmpl": "name: {{ .ProjectName }}\n" +
+			"{{/* raw */}}\nkey: ${{ hashFiles('go.sum') }}\n{{- /* endraw */}}\n" +
+			"sha: ${{ raw \"github.sha\" }}\n" +
+			"tmpl: {{ literal \"{{ name }}\" }}\n" +
+			"ref: {{ \"${{ github.ref }}\" }}\n",
+		"broken.yml.tmpl": "name: {{ .ProjectName }}\nsha: ${{ github.sha }}\n{{/* raw */}}${{ fine }}{{/* endraw */}}\nversion: ${{ matrix.go-version }}\n",
+	})
+
+	outputDir := filepath.Join(t.TempDir(), "demo")
+	if _, err := NewGenerator(templatesDir).Generate(&Options{ProjectName: "demo", Language: "test", Framework: "basic", OutputDir: outputDir}); err != nil {
+		t.Fatalf("Generate() unexpected error: %v", err)
+	}
+	want := "name: demo\n\nkey: ${{ hashFiles('go.sum') }}\nsha: ${{ github.sha }}\ntmpl: {{ name }}\nref: ${{ github.ref }}\n"
+	if got, _ := os.ReadFile(filepath.Join(outputDir, "ci.yml")); string(got) != want {
+		t.Errorf("ci.yml = %q, want %q", got, want)
+	}
+
+	tmpl, err := template.NewLoader(templatesDir).Load("test/basic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conflicts, err := tmpl.Lint()
+	if err != nil {
+		t.Fatalf("Lint() unexpected error: %v", err)
+	}
+	wantConflicts := []template.Conflict{
+		{File: "broken.yml.tmpl", Line: 2, Text: "sha: ${{ github.sha }}"},
+		{File: "broken.yml.tmpl", Line: 4, Text: "version: ${{ matrix.go-version }}"},
+	}
+	if !reflect.DeepEqual(conflicts, wantConflicts) {
+		t.Errorf("Lint() = %+v, want %+v", conflicts, wantConflicts)
+	}
+
+	if _, err := template.FindConflicts("a.tmpl", "{{/* raw */}}x", nil, ""); err == nil || !strings.Contains(err.Error(), "without endraw") {
+		t.Errorf("FindConflicts() with an open raw block error = %v", err)
+	}
+}
+
+func TestGenerateRenderErrorPosition(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    template.RenderError
+		snippet string
+	}{
+		{
+			name:    "execute",
+			content: "a: 1\nb: 2\nport: {{ .Variables.port | upper }}\nc: 3\nd: 4\ne: 5\n",
+			want:    template.RenderError{File: "conf/app.yml.tmpl", Line: 3, Column: 28, Expression: "upper"},
+			snippet: "    1 | a: 1\n    2 | b: 2\n  > 3 | port: {{ .Variables.port | upper }}\n      |                            ^\n    4 | c: 3\n    5 | d: 4",
+		},
+		{
+			name:    "parse",
+			content: "a: 1\n{{ if }}\n",
+			want:    template.RenderError{File: "conf/app.yml.tmpl", Line: 2},
+			snippet: "    1 | a: 1\n  > 2 | {{ if }}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			templatesDir := t.TempDir()
+			writeTestTemplate(t, templatesDir, `version: "1.0.0"
+name: basic
+language: test
+framework: basic
+variables:
+  port:
+    type: int
+    default: 8080
+files:
+  - src: conf/app.yml.tmpl
+    dest: app.yml
+`, map[string]string{"conf/app.yml.tmpl": tt.content})
+
+			_, err := NewGenerator(templatesDir).Generate(&Options{ProjectName: "demo", Language: "test", Framework: "basic", OutputDir: filepath.Join(t.TempDir(), "demo")})
+			var renderErr *template.RenderError
+			if !errors.As(err, &renderErr) {
+				t.Fatalf("Generate() error = %v, want a RenderError", err)
+			}
+			if renderErr.File != tt.want.File || renderErr.Line != tt.want.Line || renderErr.Column != tt.want.Column || renderErr.Expression != tt.want.Expression {
+				t.Errorf("Generate() error at %s in %q, want %s in %q", renderErr.Position(), renderErr.Expression, tt.want.Position(), tt.want.Expression)
+			}
+			if got := renderErr.Snippet(); got != tt.snippet {
+				t.Errorf("Snippet() =\n%s\nwant\n%s", got, tt.snippet)
+			}
+		})
+	}
+}
+
+func TestGenerateUndeclaredVariables(t *testing.T) {
+	templatesDir := t.TempDir()
+	writeTestTemplate(t, templatesDir, `version: "1.0.0"
+name: basic
+language: test
+framework: basic
+variables:
+  region:
+    type: string
+files:
+  - src: app.yml.tmpl
+    dest: app.yml
+  - src: extra.yml
+    dest: "{{ .Variables.extra_dir }}/extra.yml"
+    conditions: [use_extra]
+`, map[string]string{
+		"app.yml.tmpl": "region: {{ .Variables.region }}\n{{ if .IncludeDocker }}image: {{ $.Variables.imgae }}{{ end }}\n",
+		"extra.yml":    "x: 1\n",
+	})
+	gen := NewGenerator(templatesDir)
+
+	tmpl, err := gen.GetTemplate("test/basic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	refs, err := tmpl.UndeclaredVariables()
+	want := []template.VariableRef{
+		{Name: "imgae", File: "app.yml.tmpl", Line: 2},
+		{Name: "extra_dir", File: "template.yaml"},
+		{Name: "use_extra", File: "template.yaml"},
+	}
+	if err != nil || !reflect.DeepEqual(refs, want) {
+		t.Errorf("UndeclaredVariables() = %v, %v, want %v", refs, err, want)
+	}
+
+	// Typos fail even in branches not taken
+	opts := &Options{ProjectName: "demo", Language: "test", Framework: "basic", OutputDir: filepath.Join(t.TempDir(), "demo")}
+	_, err = gen.Generate(opts)
+	if errcode.Of(err) != errcode.TemplateInvalid || !strings.Contains(err.Error(), "imgae (app.yml.tmpl:2)") {
+		t.Errorf("Generate() error = %v, want the undeclared variables", err)
+	}
+
+	// Variables set with --var are not typos; declared ones without a value
+	// render as before
+	opts.Variables = map[string]interface{}{"imgae": "x", "extra_dir": "conf", "use_extra": false}
+	if _, err := gen.Generate(opts); err != nil {
+		t.Errorf("Generate() with the variables set unexpected error: %v", err)
+	}
+
+	// Rendering fails on missing keys instead of printing <no value>
+	if _, err := gen.RenderText("stdin", "{{ .Variables.nope }}", &RenderOptions{}); err == nil || !strings.Contains(err.Error(), `no entry for key "nope"`) {
+		t.Errorf("RenderText() with a missing variable error = %v", err)
+	}
+}
+
+func TestReferencesField(t *testing.T) {
+	templatesDir := t.TempDir()
+	writeTestTemplate(t, templatesDir, `version: "1.0.0"
+name: basic
+language: test
+framework: basic
+files:
+  - src: app.yml.tmpl
+    dest: app.yml
+  - src: Dockerfile.tmpl
+    dest: Dockerfile
+    conditions: [IncludeDocker]
+  - src: image.txt
+    dest: "{{ .Image }}.txt"
+`, map[string]string{
+		"app.yml.tmpl":    "port: {{ $.Port }}\nregion: {{ .Variables.region }}\n",
+		"Dockerfile.tmpl": "FROM python:3.11\n",
+		"image.txt":       "{{ .DockerBase }}\n",
+	})
+	tmpl, err := NewGenerator(templatesDir).GetTemplate("test/basic")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Files without .tmpl are copied as they are
+	for field, want := range map[string]bool{"Port": true, "IncludeDocker": true, "Image": true, "DockerBase": false, "region": false} {
+		if got, err := tmpl.ReferencesField(field); err != nil || got != want {
+			t.Errorf("ReferencesField(%s) = %v, %v, want %v", field, got, err, want)
+		}
+	}
+}
+
+func TestDescribeContext(t *testing.T) {
+	templatesDir := t.TempDir()
+	writeTestTemplate(t, templatesDir, `version: "1.0.0"
+name: basic
+language: test
+framework: basic
+variables:
+  db:
+    type: choice
+    choices: [postgres, sqlite]
+    default: sqlite
+  region:
+    type: string
+  size:
+    type: string
+    default: small
+profiles:
+  big:
+    variables:
+      size: large
+files:
+  - src: app.yml
+    dest: "{{ .ProjectNameSnake }}/app.yml"
+  - src: db.sql
+    dest: db.sql
+    conditions: ["{{ eq .Variables.db \"postgres\" }}"]
+hooks:
+  post_generate:
+    - run: "true"
+      name: migrate
+      when: "{{ eq .Variables.db \"postgres\" }}"
+`, map[string]string{"app.yml": "x: 1\n", "db.sql": "select 1;\n"})
+
+	report, err := NewGenerator(templatesDir).DescribeContext(&Options{
+		ProjectName: "billing-api",
+		Language:    "test",
+		Framework:   "basic",
+		Profile:     "big",
+		Variables:   map[string]interface{}{"db": "postgres"},
+		Defaults:    map[string]interface{}{"IncludeDocker": true},
+	})
+	if err != nil {
+		t.Fatalf("DescribeContext() unexpected error: %v", err)
+	}
+
+	if report.ProjectNameSnake != "billing_api" || !report.Fields.IncludeDocker {
+		t.Errorf("DescribeContext() names and fields = %q, %+v", report.ProjectNameSnake, report.Fields)
+	}
+	wantVariables := map[string]interface{}{"db": "postgres", "region": nil, "size": "large", "Profile": "big", "IncludeDocker": true}
+	wantSources := map[string]string{"db": SourceVar, "region": SourceUnset, "size": SourceProfile, "Profile": SourceProfile, "IncludeDocker": SourceDevinit}
+	if !reflect.DeepEqual(report.Variables, wantVariables) || !reflect.DeepEqual(report.Sources, wantSources) {
+		t.Errorf("DescribeContext() variables = %v from %v, want %v from %v", report.Variables, report.Sources, wantVariables, wantSources)
+	}
+
+	wantFiles := []FileConditions{
+		{Source: "app.yml", Destination: filepath.Join("billing_api", "app.yml"), Generated: true},
+		{Source: "db.sql", Destination: "db.sql", Generated: true, Conditions: []ConditionValue{{Condition: `{{ eq .Variables.db "postgres" }}`, Value: true}}},
+	}
+	if !reflect.DeepEqual(report.Files, wantFiles) {
+		t.Errorf("DescribeContext() files = %+v, want %+v", report.Files, wantFiles)
+	}
+	wantHooks := []HookConditionReport{{Name: "migrate", Stage: "post_generate", When: `{{ eq .Variables.db "postgres" }}`, Runs: true}}
+	if !reflect.DeepEqual(report.Hooks, wantHooks) {
+		t.Errorf("DescribeContext() hooks = %+v, want %+v", report.Hooks, wantHooks)
+	}
+}
+
+// branchCounter counts the branches reported to it
+type branchCounter struct {
+	taken, skipped int
+}
+
+func (c *branchCounter) RecordBranch(branch template.Branch, taken bool) {
+	if taken {
+		c.taken++
+	} else {
+		c.skipped++
+	}
+}
+
+func TestGenerateConcurrent(t *testing.T) {
+	templatesDir := t.TempDir()
+	writeTestTemplate(t, templatesDir, `version: "1.0.0"
+name: basic
+language: test
+framework: basic
+variables:
+  fancy:
+    type: boolean
+    default: false
+files:
+  - src: readme.txt.tmpl
+    dest: README.txt
+`, map[string]string{"readme.txt.tmpl": "# {{ .ProjectName }}{{ if .Variables.fancy }} (fancy){{ end }}\n"})
+
+	// One generator, with a render cache, generates every project
+	gen := NewGenerator(templatesDir)
+	gen.SetRenderCache(NewRenderCache(t.TempDir()))
+	outputDir := t.TempDir()
+
+	const projects = 16
+	counters := make([]*branchCounter, projects)
+	errs := make([]error, projects)
+	q := queue.New(4)
+	for i := 0; i < projects; i++ {
+		counters[i] = &branchCounter{}
+		opts := &Options{
+			ProjectName: fmt.Sprintf("app-%d", i),
+			Language:    "test",
+			Framework:   "basic",
+			OutputDir:   filepath.Join(outputDir, fmt.Sprintf("app-%d", i)),
+			Variables:   map[string]interface{}{"fancy": i%2 == 1},
+			SkipHooks:   true,
+		}
+		if i%4 == 0 {
+			opts.BranchRecorder = counters[i]
+		}
+		q.Go(func() {
+			_, errs[i] = gen.Generate(opts)
+		})
+	}
+	q.Wait()
+
+	for i := 0; i < projects; i++ {
+		if errs[i] != nil {
+			t.Fatalf("Generate(app-%d) unexpected error: %v", i, errs[i])
+		}
+		want := fmt.Sprintf("# app-%d\n", i)
+		if i%2 == 1 {
+			want = fmt.Sprintf("# app-%d (fancy)\n", i)
+		}
+		content, err := os.ReadFile(filepath.Join(outputDir, fmt.Sprintf("app-%d", i), "README.txt"))
+		if err != nil || string(content) != want {
+			t.Errorf("app-%d/README.txt = %q, %v, want %q", i, content, err, want)
+		}
+
+		// Recorders only see the generation they belong to
+		wantSkipped := 0
+		if i%4 == 0 {
+			wantSkipped = 1
+		}
+		if got := counters[i]; got.taken != 0 || got.skipped != wantSkipped {
+			t.Errorf("app-%d recorded %d taken and %d skipped branches, want 0 and %d", i, got.taken, got.skipped, wantSkipped)
+		}
+	}
+}
+
+func TestTemplateNeeds(t *testing.T) {
+	tests := []struct {
+		name    string
+		needs   string
+		extra   string // more of template.yaml
+		wantErr string
+	}{
+		{name: "supported", needs: "[hooks, globs, jinja2-engine]"},
+		{name: "missing", needs: "[hooks, remote-includes, sandbox]", wantErr: "needs remote-includes, sandbox, which this devinit does not support; upgrade devinit"},
+		// Newer syntax for existing keys fails to parse, which the missing
+		// capability explains
+		{name: "missing with new syntax", needs: "[tag-groups]", extra: "tags: {team: [billing]}\n", wantErr: "needs tag-groups, which this devinit does not support"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			templatesDir := t.TempDir()
+			// The unknown key stands for a feature of a newer devinit, which
+			// would be ignored without needs
+			writeTestTemplate(t, templatesDir, `version: "1.0.0"
+name: basic
+language: test
+framework: basic
+needs: `+tt.needs+`
+remote_includes: [https://example.com/base.yaml]
+files:
+  - src: readme.txt
+    dest: README.txt
+`+tt.extra, map[string]string{"readme.txt": "readme\n"})
+
+			tmpl, err := NewGenerator(templatesDir).GetTemplate("test/basic")
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("GetTemplate() unexpected error: %v", err)
+				}
+				if missing := tmpl.MissingCapabilities(); len(missing) != 0 {
+					t.Errorf("MissingCapabilities() = %v, want none", missing)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("GetTemplate() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+			if errcode.Of(err) != errcode.TemplateInvalid {
+				t.Errorf("error code = %s, want %s", errcode.Of(err), errcode.TemplateInvalid)
+			}
+		})
+	}
+}
+
+func TestRenderTree(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"README.md.tmpl":                     "# {{ .ProjectName }} for {{ .Variables.team }}\n",
+		"{{ .ProjectNameSnake }}/static.txt": "{{ kept }}\n",
+		".git/config":                        "ignored\n",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	gen := NewGenerator(t.TempDir())
+	opts := &RenderOptions{ProjectName: "billing-api", Variables: map[string]interface{}{"team": "core"}}
+	out := output.NewMemory()
+	if _, err := gen.RenderTree(dir, opts, out); err != nil {
+		t.Fatalf("RenderTree() unexpected error: %v", err)
+	}
+	want := map[string][]byte{
+		"README.md":              []byte("# billing-api for core\n"),
+		"billing_api/static.txt": []byte("{{ kept }}\n"),
+	}
+	if got := out.Files(); !reflect.DeepEqual(got, want) {
+		t.Errorf("RenderTree() files = %q, want %q", got, want)
+	}
+
+	got, err := gen.RenderText("stdin", "{{ team | upper }}", &RenderOptions{Variables: opts.Variables, Engine: template.EngineJinja2})
+	if err != nil || got != "CORE" {
+		t.Errorf("RenderText() = %q, %v, want CORE", got, err)
+	}
+	got, err = gen.RenderText("stdin", "[[ .Variables.team ]] ${{ github.sha }}", &RenderOptions{Variables: opts.Variables, Delimiters: []string{"[[", "]]"}})
+	if err != nil || got != "core ${{ github.sha }}" {
+		t.Errorf("RenderText() with delimiters = %q, %v", got, err)
+	}
+	if _, err := gen.RenderText("stdin", "x", &RenderOptions{Engine: "mustache"}); err == nil {
+		t.Error("RenderText() with an unknown engine succeeded")
+	}
+}
+
+func TestFindTemplates(t *testing.T) {
+	templatesDir := t.TempDir()
+	writeTestTemplate(t, templatesDir, `version: "1.0.0"
+name: basic
+description: Minimal REST service
+language: test
+framework: basic
+tags: [rest, async]
+`, nil)
+
+	gen := NewGenerator(templatesDir)
+
+	tests := []struct {
+		name   string
+		filter template.Filter
+		want   int
+	}{
+		{name: "no filter", filter: template.Filter{}, want: 1},
+		{name: "language match", filter: template.Filter{Language: "test"}, want: 1},
+		{name: "language mismatch", filter: template.Filter{Language: "python"}, want: 0},
+		{name: "default type", filter: template.Filter{Type: "api"}, want: 1},
+		{name: "all tags present", filter: template.Filter{Tags: []string{"rest", "ASYNC"}}, want: 1},
+		{name: "missing tag", filter: template.Filter{Tags: []string{"rest", "grpc"}}, want: 0},
+		{name: "query in description", filter: template.Filter{Query: "minimal service"}, want: 1},
+		{name: "query in tags", filter: template.Filter{Query: "async"}, want: 1},
+		{name: "query mismatch", filter: template.Filter{Query: "graphql"}, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := gen.FindTemplates(tt.filter)
+			if err != nil {
+				t.Fatalf("FindTemplates() unexpected error: %v", err)
+			}
+			if len(got) != tt.want {
+				t.Errorf("FindTemplates() returned %d templates, want %d", len(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestListTemplates(t *testing.T) {
+	templatesDir := t.TempDir()
+	metadata := "version: \"1.0.0\"\nname: t\nlanguage: l\nframework: f\n"
+	for _, path := range []string{
+		"go/cli/template.yaml",
+		"go/cli/files/nested/template.yaml",         // file of a template
+		"go/cli/versions/0.9.0/template.yaml",       // stored version
+		"go/cli/examples/template.yaml",             // inside a template
+		".git/hooks/template.yaml",                  // hidden directory
+		"org/team/python/fastapi/template.yaml",     // at the depth limit
+		"org/team/python/fastapi/x/y/template.yaml", // inside a template
+		"a/b/c/d/e/template.yaml",                   // too deep
+	} {
+		path = filepath.Join(templatesDir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(metadata), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	gen := NewGenerator(templatesDir)
+	want := []string{"go/cli", "org/team/python/fastapi"}
+
+	got, err := gen.ListTemplates()
+	if err != nil {
+		t.Fatalf("ListTemplates() unexpected error: %v", err)
+	}
+	sort.Strings(got)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ListTemplates() = %v, want %v", got, want)
+	}
+
+	indexed, err := gen.WriteTemplateIndex()
+	if err != nil {
+		t.Fatalf("WriteTemplateIndex() unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(indexed, want) {
+		t.Errorf("WriteTemplateIndex() = %v, want %v", indexed, want)
+	}
+
+	// The index is trusted: a template added later is not listed
+	if err := os.MkdirAll(filepath.Join(templatesDir, "rust", "axum"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(templatesDir, "rust", "axum", "template.yaml"), []byte(metadata), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := gen.ListTemplates(); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("ListTemplates() with index = %v, %v, want %v", got, err, want)
+	}
+
+	if err := os.WriteFile(filepath.Join(templatesDir, template.IndexFile), []byte("templates: [../outside]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gen.ListTemplates(); err == nil {
+		t.Error("ListTemplates() with an index entry outside the templates directory succeeded, want error")
+	}
+}
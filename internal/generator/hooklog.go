@@ -0,0 +1,99 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// hookLogDir is where hook output is logged inside the generated project
+const hookLogDir = ".devinit/logs"
+
+// hookTailLines is the number of output lines kept for failed hooks
+const hookTailLines = 20
+
+// hookTailBytes bounds the output buffered per hook to find its tail
+const hookTailBytes = 64 * 1024
+
+// hookLog writes the output of all hooks of a generation to a log file,
+// optionally echoing it to a live writer
+type hookLog struct {
+	path string
+	file *os.File
+	live io.Writer
+}
+
+// openHookLog creates .devinit/logs/<timestamp>.log in the project. The logs
+// directory ignores itself so logs are not committed by accident.
+func openHookLog(projectDir string, live io.Writer, now time.Time) (*hookLog, error) {
+	dir := filepath.Join(projectDir, filepath.FromSlash(hookLogDir))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".gitignore"), []byte("*\n"), 0644); err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(dir, now.UTC().Format("20060102T150405Z")+".log")
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &hookLog{path: path, file: file, live: live}, nil
+}
+
+// start writes the header of a hook attempt and returns the writer for its
+// output together with the buffer holding its most recent output
+func (l *hookLog) start(stage, command, workingDir string, attempt, attempts int) (io.Writer, *tailBuffer) {
+	if attempts > 1 {
+		fmt.Fprintf(l.file, "==> %s: %s (in %s, attempt %d/%d)\n", stage, command, workingDir, attempt, attempts)
+	} else {
+		fmt.Fprintf(l.file, "==> %s: %s (in %s)\n", stage, command, workingDir)
+	}
+
+	tail := &tailBuffer{}
+	if l.live == nil {
+		return io.MultiWriter(l.file, tail), tail
+	}
+	return io.MultiWriter(l.file, tail, l.live), tail
+}
+
+// note writes a line that is not hook output, such as a retry notice
+func (l *hookLog) note(line string) {
+	fmt.Fprintf(l.file, "--- %s\n", line)
+}
+
+// Close closes the log file
+func (l *hookLog) Close() error {
+	return l.file.Close()
+}
+
+// tailBuffer keeps the last hookTailBytes of written output
+type tailBuffer struct {
+	buf bytes.Buffer
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.buf.Write(p)
+	if t.buf.Len() > hookTailBytes {
+		t.buf.Next(t.buf.Len() - hookTailBytes)
+	}
+	return len(p), nil
+}
+
+// Lines returns the last hookTailLines non-empty lines
+func (t *tailBuffer) Lines() []string {
+	lines := strings.Split(strings.TrimRight(t.buf.String(), "\n"), "\n")
+	if len(lines) == 1 && lines[0] == "" {
+		return nil
+	}
+	if len(lines) > hookTailLines {
+		lines = lines[len(lines)-hookTailLines:]
+	}
+	return lines
+}
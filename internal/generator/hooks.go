@@ -0,0 +1,226 @@
+package generator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/renan-dev/devinit/internal/errcode"
+	"github.com/renan-dev/devinit/internal/i18n"
+	"github.com/renan-dev/devinit/internal/retry"
+	"github.com/renan-dev/devinit/internal/template"
+)
+
+// Environment variables describing the generation to hooks, so they need not
+// parse generated files. They are named so that devinit run by a hook does
+// not read them as flags.
+const (
+	HookEnvProjectName     = "DEVINIT_PROJECT_NAME"
+	HookEnvProjectDir      = "DEVINIT_PROJECT_DIR"
+	HookEnvTemplateName    = "DEVINIT_TEMPLATE_NAME"
+	HookEnvTemplateVersion = "DEVINIT_TEMPLATE_VERSION"
+	HookEnvVariables       = "DEVINIT_VARIABLES" // JSON object
+	HookEnvStage           = "DEVINIT_HOOK_STAGE"
+)
+
+// hookEnv returns the environment hooks of stage run in: devinit's, the
+// network settings and the HookEnv variables
+func (g *Generator) hookEnv(stage string, ctx *template.Context) ([]string, error) {
+	variables, err := json.Marshal(ctx.Variables)
+	if err != nil {
+		return nil, err
+	}
+	projectDir, err := filepath.Abs(ctx.OutputDir)
+	if err != nil {
+		return nil, err
+	}
+
+	env := append(os.Environ(), g.network.Env()...)
+	env = append(env,
+		HookEnvProjectName+"="+ctx.ProjectName,
+		HookEnvProjectDir+"="+projectDir,
+		HookEnvVariables+"="+string(variables),
+		HookEnvStage+"="+stage,
+	)
+	if tmpl := ctx.Template; tmpl != nil {
+		env = append(env, HookEnvTemplateName+"="+tmpl.ID, HookEnvTemplateVersion+"="+tmpl.Version)
+	}
+	return env, nil
+}
+
+// runHooks executes a list of lifecycle hooks and records them on the result.
+// Hooks run by order and after the hooks they name; those whose when
+// condition does not hold, or that run after a hook that failed, are
+// recorded as skipped. Hooks with error_level "error" (the default) abort
+// generation on failure; "warn" records a warning and "ignore" continues
+// silently. Hook output goes to log. Hooks marked network: true are retried
+// per the retry policy.
+func (g *Generator) runHooks(stage string, hooks []template.Hook, ctx *template.Context, opts *Options, log *hookLog, result *GenerationResult) error {
+	hooks, err := template.SortHooks(hooks)
+	if err != nil {
+		return errcode.New(errcode.TemplateInvalid, i18n.Errorf("generator.hook_run_failed", stage, err))
+	}
+	env, err := g.hookEnv(stage, ctx)
+	if err != nil {
+		return errcode.New(errcode.HookFailed, i18n.Errorf("generator.hook_run_failed", stage, err))
+	}
+
+	failed := make(map[string]bool)
+	for i, hook := range hooks {
+		if hook.Run == "" {
+			continue
+		}
+
+		skipped := HookResult{Stage: stage, Name: hook.Name, Command: hook.Run, Idempotent: hook.Idempotent, Skipped: true}
+		if hook.When != "" && !g.evaluateCondition(hook.When, ctx) {
+			skipped.When = hook.When
+			result.Hooks = append(result.Hooks, skipped)
+			continue
+		}
+		if dep := firstFailed(hook.After, failed); dep != "" {
+			skipped.BlockedBy = dep
+			result.Hooks = append(result.Hooks, skipped)
+			if hook.Name != "" {
+				failed[hook.Name] = true
+			}
+			continue
+		}
+
+		policy := retry.Policy{Attempts: 1}
+		if hook.Network {
+			policy = g.retryPolicy(opts)
+		}
+
+		hookResult, err := g.runHook(hookLabel(stage, i, hook), hook, ctx, env, log, policy, opts.Progress)
+		if err != nil {
+			return errcode.New(errcode.HookFailed, i18n.Errorf("generator.hook_run_failed", stage, err))
+		}
+		hookResult.Stage = stage
+		hookResult.Name = hook.Name
+		hookResult.Idempotent = hook.Idempotent
+		result.Hooks = append(result.Hooks, *hookResult)
+
+		if hookResult.Error == "" {
+			continue
+		}
+		if hook.Name != "" {
+			failed[hook.Name] = true
+		}
+
+		message := hookResult.Error
+		if hook.Error != "" {
+			message = hook.Error
+		}
+
+		switch hook.ErrorLevel {
+		case template.ErrorLevelIgnore:
+		case template.ErrorLevelWarn:
+			result.Warnings = append(result.Warnings, i18n.T("generator.hook_failed", stage, hookResult.Command, message))
+		default:
+			return hookFailure(stage, hookResult, message, log.path)
+		}
+	}
+
+	return nil
+}
+
+// firstFailed returns the first of names that failed, or ""
+func firstFailed(names []string, failed map[string]bool) string {
+	for _, name := range names {
+		if failed[name] {
+			return name
+		}
+	}
+	return ""
+}
+
+// hookFailure builds the error of a failed hook, ending with the tail of its
+// output
+func hookFailure(stage string, hookResult *HookResult, message, logPath string) error {
+	err := i18n.Errorf("generator.hook_failed", stage, hookResult.Command, message)
+	if len(hookResult.Output) > 0 {
+		err = fmt.Errorf("%w\n%s\n  %s", err, i18n.T("generator.hook_output", logPath), strings.Join(hookResult.Output, "\n  "))
+	}
+	return errcode.New(errcode.HookFailed, err)
+}
+
+// retryPolicy returns the policy for network hooks, applying opts.RetryAttempts
+func (g *Generator) retryPolicy(opts *Options) retry.Policy {
+	policy := g.retry
+	if opts.RetryAttempts > 0 {
+		policy.Attempts = opts.RetryAttempts
+	}
+	return policy
+}
+
+// runHook renders and executes a single hook in env, retrying it per policy.
+// Commands are executed directly (no shell) to avoid injection through
+// template variables; a command that does not exist is never retried.
+func (g *Generator) runHook(name string, hook template.Hook, ctx *template.Context, env []string, log *hookLog, policy retry.Policy, progress io.Writer) (*HookResult, error) {
+	command, err := g.renderer.RenderString(name, hook.Run, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	workingDir := ctx.OutputDir
+	if hook.WorkingDir != "" {
+		workingDir, err = g.renderer.RenderString(name+".working_dir", hook.WorkingDir, ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return nil, i18n.Errorf("generator.hook_empty", name)
+	}
+
+	result := &HookResult{
+		Command:    command,
+		WorkingDir: workingDir,
+	}
+
+	var (
+		runErr error
+		tail   *tailBuffer
+	)
+	start := time.Now()
+	policy.Do(func(attempt int) error {
+		result.Attempts = attempt
+
+		var out io.Writer
+		out, tail = log.start(name, command, workingDir, attempt, policy.Total())
+
+		cmd := exec.Command(args[0], args[1:]...)
+		cmd.Dir = workingDir
+		cmd.Env = env
+		cmd.Stdout = out
+		cmd.Stderr = out
+
+		runErr = cmd.Run()
+		if errors.Is(runErr, exec.ErrNotFound) {
+			return retry.Permanent(runErr)
+		}
+		return runErr
+	}, func(attempt int, err error, wait time.Duration) {
+		notice := i18n.T("generator.hook_retry", name, command, attempt, policy.Total(), err, wait)
+		log.note(notice)
+		if progress != nil {
+			fmt.Fprintln(progress, notice)
+		}
+	})
+	result.Duration = time.Since(start)
+
+	if runErr != nil {
+		result.Error = runErr.Error()
+		result.Output = tail.Lines()
+	}
+
+	return result, nil
+}
@@ -0,0 +1,179 @@
+package generator
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/renan-dev/devinit/internal/errcode"
+	"github.com/renan-dev/devinit/internal/i18n"
+	"github.com/renan-dev/devinit/internal/output"
+	"github.com/renan-dev/devinit/internal/template"
+	"gopkg.in/yaml.v3"
+)
+
+// LockFile is written to the root of projects generated from templates
+// imported from remote repositories. It pins the exact template used, so
+// that rendering it again (drift checks) across a team uses the same one.
+const LockFile = "devinit.lock"
+
+// lockHeader starts every lockfile
+const lockHeader = "# Pins the template of this project; commit it. Written by devinit.\n"
+
+// Lock is the content of LockFile
+type Lock struct {
+	Template struct {
+		Name    string `yaml:"name"`
+		Version string `yaml:"version"`
+		Source  string `yaml:"source,omitempty"`
+		Commit  string `yaml:"commit,omitempty"`
+		Digest  string `yaml:"digest"`
+	} `yaml:"template"`
+}
+
+// newLock pins tmpl
+func newLock(tmpl *template.Template) (*Lock, error) {
+	digest, err := TemplateDigest(tmpl)
+	if err != nil {
+		return nil, err
+	}
+
+	lock := &Lock{}
+	lock.Template.Name = tmpl.Language + "/" + tmpl.Framework
+	lock.Template.Version = tmpl.Version
+	lock.Template.Digest = digest
+	if tmpl.Origin != nil {
+		lock.Template.Source = tmpl.Origin.Source
+		lock.Template.Commit = tmpl.Origin.Commit
+	}
+	return lock, nil
+}
+
+// changes describes how the template of current differs from the one of l:
+// by commit when both are known and differ, else by digest
+func (l *Lock) changes(current *Lock) (was, now string) {
+	if l.Template.Commit != "" && current.Template.Commit != "" && l.Template.Commit != current.Template.Commit {
+		return l.Template.Commit, current.Template.Commit
+	}
+	return l.Template.Digest, current.Template.Digest
+}
+
+// marshal encodes the lock
+func (l *Lock) marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(lockHeader)
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(l); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ReadLock reads the lockfile of the project in dir; nil when it has none
+func ReadLock(dir string) (*Lock, error) {
+	data, err := os.ReadFile(filepath.Join(dir, LockFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, i18n.Errorf("generator.read_project_file", LockFile, err)
+	}
+
+	var lock Lock
+	if err := yaml.Unmarshal(data, &lock); err != nil {
+		return nil, i18n.Errorf("generator.read_project_file", LockFile, err)
+	}
+	return &lock, nil
+}
+
+// TemplateDigest returns the sha256 digest of the files of a template, its
+// other versions left out
+func TemplateDigest(tmpl *template.Template) (string, error) {
+	h := sha256.New()
+	err := filepath.WalkDir(tmpl.Path, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(tmpl.Path, path)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if rel == "versions" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		fmt.Fprintf(h, "%s\x00", filepath.ToSlash(rel))
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(h, f)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// SetUpdateLock makes commands that render the template of a project again
+// use the template as it is now and update the project's lockfile, instead
+// of failing when it no longer matches the lock
+func (g *Generator) SetUpdateLock(update bool) {
+	g.updateLock = update
+}
+
+// checkLock compares tmpl, about to be rendered again for the project in
+// dir, to the template pinned by the project's lockfile. A template that
+// changed since fails, unless the lock is updated.
+func (g *Generator) checkLock(dir string, tmpl *template.Template) error {
+	locked, err := ReadLock(dir)
+	if err != nil || locked == nil {
+		return err
+	}
+
+	current, err := newLock(tmpl)
+	if err != nil {
+		return err
+	}
+	if current.Template.Digest == locked.Template.Digest {
+		return nil
+	}
+	if !g.updateLock {
+		was, now := locked.changes(current)
+		return errcode.New(errcode.Conflict, i18n.Errorf("generator.lock_mismatch", locked.Template.Name, locked.Template.Version, LockFile, was, now))
+	}
+
+	data, err := current.marshal()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, LockFile), data, 0644)
+}
+
+// createLockFile writes the lockfile of a project generated from an
+// imported template
+func (g *Generator) createLockFile(tmpl *template.Template, out output.Backend) error {
+	lock, err := newLock(tmpl)
+	if err != nil {
+		return err
+	}
+	data, err := lock.marshal()
+	if err != nil {
+		return err
+	}
+	return out.WriteFile(LockFile, data, 0644)
+}
@@ -0,0 +1,82 @@
+package generator
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/renan-dev/devinit/internal/i18n"
+	"github.com/renan-dev/devinit/internal/template"
+)
+
+// markerPattern matches a "devinit:if <condition>", "devinit:else" or
+// "devinit:endif" marker alone on a line in a #, //, --, ;, /* */ or <!-- -->
+// comment, so files keep their syntax without being .tmpl templates
+var markerPattern = regexp.MustCompile(`^\s*(?:#|//|--|;|/\*|<!--)\s*devinit:(if|else|endif)\b\s*(.*?)\s*(?:\*/|-->)?\s*$`)
+
+// markerSection is an open devinit:if section
+type markerSection struct {
+	line      int
+	parent    bool // whether the enclosing section is kept
+	condition bool
+	inElse    bool
+}
+
+// applyMarkers keeps the sections of data whose devinit:if condition holds
+// and drops the others. Sections nest and may have a devinit:else branch.
+// Conditions are evaluated like file conditions.
+func applyMarkers(data []byte, mode template.MarkerMode, evaluate func(condition string) bool) ([]byte, error) {
+	if mode == template.MarkersOff {
+		return data, nil
+	}
+	keepMarkers := mode == template.MarkersKeep
+
+	var (
+		out    strings.Builder
+		stack  []*markerSection
+		active = true
+	)
+	for i, line := range strings.SplitAfter(string(data), "\n") {
+		match := markerPattern.FindStringSubmatch(strings.TrimRight(line, "\r\n"))
+		if match == nil {
+			if active {
+				out.WriteString(line)
+			}
+			continue
+		}
+
+		// A marker is output in keep mode when its enclosing section is kept
+		var visible bool
+		switch match[1] {
+		case "if":
+			if match[2] == "" {
+				return nil, i18n.Errorf("generator.marker_condition", i+1)
+			}
+			section := &markerSection{line: i + 1, parent: active}
+			section.condition = active && evaluate(match[2])
+			stack = append(stack, section)
+			visible, active = section.parent, section.condition
+		case "else":
+			if len(stack) == 0 || stack[len(stack)-1].inElse {
+				return nil, i18n.Errorf("generator.marker_unexpected", i+1, match[1])
+			}
+			section := stack[len(stack)-1]
+			section.inElse = true
+			visible, active = section.parent, section.parent && !section.condition
+		case "endif":
+			if len(stack) == 0 {
+				return nil, i18n.Errorf("generator.marker_unexpected", i+1, match[1])
+			}
+			visible, active = stack[len(stack)-1].parent, stack[len(stack)-1].parent
+			stack = stack[:len(stack)-1]
+		}
+
+		if keepMarkers && visible {
+			out.WriteString(line)
+		}
+	}
+
+	if len(stack) > 0 {
+		return nil, i18n.Errorf("generator.marker_unclosed", stack[len(stack)-1].line)
+	}
+	return []byte(out.String()), nil
+}
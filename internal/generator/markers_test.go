@@ -0,0 +1,102 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/renan-dev/devinit/internal/template"
+)
+
+func TestApplyMarkers(t *testing.T) {
+	const compose = `services:
+  app:
+    build: .
+  # devinit:if IncludeDocker
+  db:
+    image: postgres
+    # devinit:if Observability
+    labels: [metrics]
+    # devinit:else
+    labels: []
+    # devinit:endif
+  # devinit:endif
+`
+
+	tests := []struct {
+		name  string
+		data  string
+		mode  template.MarkerMode
+		vars  map[string]bool
+		want  string
+		error bool
+	}{
+		{
+			name: "off",
+			data: compose,
+			mode: template.MarkersOff,
+			want: compose,
+		},
+		{
+			name: "strip true",
+			data: compose,
+			mode: template.MarkersStrip,
+			vars: map[string]bool{"IncludeDocker": true, "Observability": true},
+			want: "services:\n  app:\n    build: .\n  db:\n    image: postgres\n    labels: [metrics]\n",
+		},
+		{
+			name: "strip else",
+			data: compose,
+			mode: template.MarkersStrip,
+			vars: map[string]bool{"IncludeDocker": true},
+			want: "services:\n  app:\n    build: .\n  db:\n    image: postgres\n    labels: []\n",
+		},
+		{
+			name: "strip false",
+			data: compose,
+			mode: template.MarkersStrip,
+			want: "services:\n  app:\n    build: .\n",
+		},
+		{
+			name: "keep",
+			data: compose,
+			mode: template.MarkersKeep,
+			vars: map[string]bool{"IncludeDocker": true},
+			want: "services:\n  app:\n    build: .\n  # devinit:if IncludeDocker\n  db:\n    image: postgres\n    # devinit:if Observability\n    # devinit:else\n    labels: []\n    # devinit:endif\n  # devinit:endif\n",
+		},
+		{
+			name: "comment styles",
+			data: "a\n// devinit:if IncludeDocker\nb\n/* devinit:endif */\n<!-- devinit:if IncludeDocker -->\nc\n<!-- devinit:endif -->\n",
+			mode: template.MarkersStrip,
+			want: "a\n",
+		},
+		{
+			name: "marker text in content",
+			data: "echo '# devinit:if IncludeDocker'\n",
+			mode: template.MarkersStrip,
+			want: "echo '# devinit:if IncludeDocker'\n",
+		},
+		{name: "unclosed", data: "# devinit:if IncludeDocker\n", mode: template.MarkersStrip, error: true},
+		{name: "stray endif", data: "# devinit:endif\n", mode: template.MarkersStrip, error: true},
+		{name: "double else", data: "# devinit:if X\n# devinit:else\n# devinit:else\n# devinit:endif\n", mode: template.MarkersStrip, error: true},
+		{name: "missing condition", data: "# devinit:if\n# devinit:endif\n", mode: template.MarkersStrip, error: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyMarkers([]byte(tt.data), tt.mode, func(condition string) bool {
+				return tt.vars[condition]
+			})
+			if tt.error {
+				if err == nil {
+					t.Errorf("applyMarkers() = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applyMarkers() unexpected error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("applyMarkers() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package generator
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+
+	"github.com/renan-dev/devinit/internal/diff"
+	"github.com/renan-dev/devinit/internal/errcode"
+	"github.com/renan-dev/devinit/internal/i18n"
+	"github.com/renan-dev/devinit/internal/merge"
+	"github.com/renan-dev/devinit/internal/output"
+	"github.com/renan-dev/devinit/internal/template"
+)
+
+// stagedFiles collects the files of a generation before they are written, so
+// file specs with a write mode can build on files produced by earlier specs
+// and each file reaches the backend once
+type stagedFiles struct {
+	out   output.Backend
+	order []string
+	files map[string]*stagedFile
+
+	// sources are the sources of the file specs claiming each name
+	sources map[string]string
+}
+
+type stagedFile struct {
+	data []byte
+	perm fs.FileMode
+}
+
+func newStagedFiles(out output.Backend) *stagedFiles {
+	return &stagedFiles{out: out, files: make(map[string]*stagedFile), sources: make(map[string]string)}
+}
+
+// claim records that spec generates name. It fails when an earlier spec
+// generates name too and spec would silently replace its file: specs
+// combining with it through a write mode, or marked override, may.
+func (s *stagedFiles) claim(name string, spec template.FileSpec) error {
+	source := spec.Source
+	if spec.Glob != "" {
+		source = i18n.T("generator.glob_source", spec.Source, spec.Glob)
+	}
+	earlier, claimed := s.sources[name]
+	s.sources[name] = source
+	if !claimed || spec.Override || (spec.Mode != "" && spec.Mode != template.WriteOverwrite) {
+		return nil
+	}
+	return errcode.New(errcode.TemplateInvalid, i18n.Errorf("generator.duplicate_destination", name, earlier, source))
+}
+
+// current returns the content at name staged by an earlier file spec or,
+// for backends that can read, already present, and whether there is any
+func (s *stagedFiles) current(name string) ([]byte, bool, error) {
+	if file, ok := s.files[name]; ok {
+		return file.data, true, nil
+	}
+
+	reader, ok := s.out.(output.Reader)
+	if !ok {
+		return nil, false, nil
+	}
+	data, err := reader.ReadFile(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, i18n.Errorf("generator.read_file", err)
+	}
+	return data, true, nil
+}
+
+// stage sets the content of name and returns the size it had been staged
+// with before. A file keeps the permissions it was first staged with.
+func (s *stagedFiles) stage(name string, data []byte, perm fs.FileMode) int {
+	if file, ok := s.files[name]; ok {
+		previous := len(file.data)
+		file.data = data
+		return previous
+	}
+	s.order = append(s.order, name)
+	s.files[name] = &stagedFile{data: data, perm: perm}
+	return 0
+}
+
+// flush writes the staged files in the order they were first staged
+func (s *stagedFiles) flush() error {
+	for _, name := range s.order {
+		file := s.files[name]
+		if err := s.out.WriteFile(name, file.data, file.perm); err != nil {
+			return i18n.Errorf("generator.generate_file", name, i18n.Errorf("generator.write_file", err))
+		}
+	}
+	return nil
+}
+
+// combine returns the content of a file spec combined with the current
+// content at its destination name, according to the spec's write mode
+func combine(spec template.FileSpec, name string, current []byte, exists bool, content []byte) ([]byte, error) {
+	switch spec.Mode {
+	case template.WriteAppend:
+		if len(current) > 0 && !bytes.HasSuffix(current, []byte("\n")) {
+			current = append(bytes.Clone(current), '\n')
+		}
+		return append(bytes.Clone(current), content...), nil
+	case template.WritePatch:
+		patched, err := diff.Apply(string(current), string(content))
+		if err != nil {
+			return nil, i18n.Errorf("generator.patch_failed", err)
+		}
+		return []byte(patched), nil
+	}
+
+	format := spec.Mode.MergeFormat(name)
+	if format == "" || !exists {
+		return content, nil
+	}
+	merged, err := merge.Merge(format, current, content, spec.Merge)
+	if err != nil {
+		return nil, i18n.Errorf("generator.merge_failed", err)
+	}
+	return merged, nil
+}
@@ -0,0 +1,156 @@
+package generator
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/renan-dev/devinit/internal/template"
+)
+
+// conditionVariablePattern finds the variables a condition expression
+// refers to: context fields (.IncludeDocker) and template variables
+// (.Variables.pin_image_digests)
+var conditionVariablePattern = regexp.MustCompile(`\.(Variables\.)?([A-Za-z_][A-Za-z0-9_]*)`)
+
+// FileGroup is a set of optional files switched on and off by one variable
+// their conditions refer to, such as IncludeDocker or CIProvider
+type FileGroup struct {
+	Variable    string `json:"variable"`
+	Description string `json:"description,omitempty"`
+
+	// Bool is set for boolean variables; other variables are off when empty
+	// or "none"
+	Bool    bool        `json:"bool"`
+	Value   interface{} `json:"value"`
+	Enabled bool        `json:"enabled"`
+
+	// Choices of the variable, as declared by the template
+	Choices []string `json:"choices,omitempty"`
+
+	// Files are the destinations of the file specs the variable gates
+	Files []string `json:"files"`
+}
+
+// FileGroups returns the optional file groups of the template selected by
+// opts, in the order the template first mentions them, with their state for
+// the options' variables
+func (g *Generator) FileGroups(opts *Options) ([]FileGroup, error) {
+	tmpl, ctx, err := g.resolve(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []*FileGroup
+	byVariable := make(map[string]*FileGroup)
+	for _, fileSpec := range tmpl.Files {
+		dest, err := g.outputPath(fileSpec, ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range conditionVariables(fileSpec.Conditions) {
+			group := byVariable[name]
+			if group == nil {
+				group = newFileGroup(tmpl, name, ctx.Variables[name])
+				if group == nil {
+					continue
+				}
+				byVariable[name] = group
+				groups = append(groups, group)
+			}
+			if len(group.Files) == 0 || group.Files[len(group.Files)-1] != dest {
+				group.Files = append(group.Files, dest)
+			}
+		}
+	}
+
+	result := make([]FileGroup, len(groups))
+	for i, group := range groups {
+		result[i] = *group
+	}
+	return result, nil
+}
+
+// conditionVariables returns the variables conditions refer to, in order of
+// appearance
+func conditionVariables(conditions []string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	for _, condition := range conditions {
+		condition = strings.TrimSpace(condition)
+		if identifierPattern.MatchString(condition) {
+			add(strings.TrimPrefix(condition, "."))
+			continue
+		}
+		for _, match := range conditionVariablePattern.FindAllStringSubmatch(condition, -1) {
+			if match[1] == "" && match[2] == "Variables" {
+				continue
+			}
+			add(match[2])
+		}
+	}
+	return names
+}
+
+// newFileGroup describes the group of the named variable, or returns nil
+// when the variable cannot be switched (numbers, lists)
+func newFileGroup(tmpl *template.Template, name string, value interface{}) *FileGroup {
+	group := &FileGroup{Variable: name, Value: value}
+
+	// Built-in variables (IncludeDocker) match the template variable they
+	// stand for (include_docker) when it declares a description or choices
+	definition, ok := tmpl.Variables[name]
+	if !ok {
+		for key, def := range tmpl.Variables {
+			if foldName(key) == foldName(name) {
+				definition, ok = def, true
+				break
+			}
+		}
+	}
+	if ok {
+		group.Description = definition.Description
+		group.Choices = definition.ChoiceValues()
+	}
+
+	switch v := value.(type) {
+	case bool:
+		group.Bool, group.Enabled = true, v
+	case string:
+		group.Enabled = v != "" && v != "none"
+	case nil:
+		// Unset variables are only known through the template
+		if !ok {
+			return nil
+		}
+		group.Bool = definition.Type == template.VariableTypeBool
+	default:
+		return nil
+	}
+	return group
+}
+
+// foldName reduces a variable name to compare IncludeDocker with
+// include_docker
+func foldName(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, "_", ""))
+}
+
+// PlannedFiles returns the sorted paths a dry run result would write
+func PlannedFiles(result *GenerationResult) []string {
+	var paths []string
+	for _, f := range result.Files {
+		if f.Action != FileActionSkipped {
+			paths = append(paths, f.Path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
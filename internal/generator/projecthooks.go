@@ -0,0 +1,143 @@
+package generator
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/renan-dev/devinit/internal/i18n"
+	"github.com/renan-dev/devinit/internal/template"
+)
+
+// ProjectHook is a lifecycle hook of a generated project
+type ProjectHook struct {
+	Stage string
+	Label string // its name, else stage[position]
+	template.Hook
+}
+
+// HookRun is the outcome of running hooks of a generated project again
+type HookRun struct {
+	Hooks    []HookResult  `json:"hooks"`
+	Warnings []string      `json:"warnings,omitempty"`
+	HookLog  string        `json:"hook_log,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// hookLabel names a hook of stage at position i in the order hooks run
+func hookLabel(stage string, i int, hook template.Hook) string {
+	if hook.Name != "" {
+		return hook.Name
+	}
+	return fmt.Sprintf("%s[%d]", stage, i)
+}
+
+// ProjectHooks returns the hooks of the project in dir in the order they run.
+// They are those recorded in its metadata, or those of the recorded template
+// version for projects generated before hooks were recorded.
+func (g *Generator) ProjectHooks(dir string) ([]ProjectHook, error) {
+	metadata, err := ReadMetadata(dir)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := g.projectTemplate(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	var hooks []ProjectHook
+	for _, stage := range []struct {
+		name  string
+		hooks []template.Hook
+	}{
+		{"pre_generate", tmpl.Hooks.PreGenerate},
+		{"post_generate", tmpl.Hooks.PostGenerate},
+	} {
+		sorted, err := template.SortHooks(stage.hooks)
+		if err != nil {
+			return nil, err
+		}
+		for i, hook := range sorted {
+			if hook.Run == "" {
+				continue
+			}
+			hooks = append(hooks, ProjectHook{Stage: stage.name, Label: hookLabel(stage.name, i, hook), Hook: hook})
+		}
+	}
+	return hooks, nil
+}
+
+// RunProjectHooks runs hooks from ProjectHooks of the project in dir again,
+// with its recorded variables. Hooks run on their own: the hooks they run
+// after are not run first. Their when conditions still apply. opts provides
+// HookOutput, Progress and RetryAttempts.
+func (g *Generator) RunProjectHooks(dir string, hooks []ProjectHook, opts *Options) (*HookRun, error) {
+	start := time.Now()
+
+	metadata, err := ReadMetadata(dir)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := g.projectTemplate(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	projectName, _ := metadata.Variables["ProjectName"].(string)
+	if projectName == "" {
+		projectName = filepath.Base(dir)
+	}
+	variables := g.mergeVariables(tmpl, nil, metadata.Variables)
+	if err := g.evaluateDefaults(tmpl, projectName, dir, variables); err != nil {
+		return nil, err
+	}
+	ctx := g.newContext(projectName, dir, variables, tmpl)
+	ctx.OpenAPI, ctx.Proto, ctx.DBSchema = metadata.OpenAPI, metadata.Proto, metadata.DBSchema
+
+	log, err := openHookLog(dir, opts.HookOutput, start)
+	if err != nil {
+		return nil, i18n.Errorf("generator.hook_log", err)
+	}
+	defer log.Close()
+
+	// Hooks that ran are reported along with the one that failed
+	result := &GenerationResult{HookLog: log.path}
+	for _, stage := range []string{"pre_generate", "post_generate"} {
+		var selected []template.Hook
+		for _, hook := range hooks {
+			if hook.Stage != stage {
+				continue
+			}
+			h := hook.Hook
+			h.Name, h.Order, h.After = hook.Label, 0, nil
+			selected = append(selected, h)
+		}
+		if err = g.runHooks(stage, selected, ctx, opts, log, result); err != nil {
+			break
+		}
+	}
+
+	return &HookRun{
+		Hooks:    result.Hooks,
+		Warnings: result.Warnings,
+		HookLog:  result.HookLog,
+		Duration: time.Since(start),
+	}, err
+}
+
+// projectTemplate returns the template a project was generated from, with
+// the hooks recorded in its metadata. Without the template, recorded hooks
+// are enough to run them.
+func (g *Generator) projectTemplate(metadata *Metadata) (*template.Template, error) {
+	tmpl, err := g.GetTemplateVersion(metadata.Template.Name, metadata.Template.Version)
+	if err != nil {
+		if metadata.Hooks == nil {
+			return nil, err
+		}
+		tmpl = &template.Template{ID: metadata.Template.Name, Version: metadata.Template.Version}
+	}
+	if metadata.Hooks != nil {
+		tmpl.Hooks = *metadata.Hooks
+	}
+	return tmpl, nil
+}
@@ -0,0 +1,169 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/renan-dev/devinit/internal/template"
+)
+
+// commentSyntax is how a file type writes a comment line
+type commentSyntax struct {
+	prefix, suffix string
+}
+
+var (
+	hashComment  = commentSyntax{prefix: "# "}
+	slashComment = commentSyntax{prefix: "// "}
+	dashComment  = commentSyntax{prefix: "-- "}
+	xmlComment   = commentSyntax{prefix: "<!-- ", suffix: " -->"}
+	cssComment   = commentSyntax{prefix: "/* ", suffix: " */"}
+)
+
+// commentSyntaxes maps file extensions to their comment syntax. Formats
+// without comments (JSON) or where a leading comment changes the meaning of
+// the file (PHP) are missing and never get a provenance header.
+var commentSyntaxes = map[string]commentSyntax{
+	".py": hashComment, ".sh": hashComment, ".bash": hashComment, ".zsh": hashComment,
+	".rb": hashComment, ".pl": hashComment, ".r": hashComment, ".ps1": hashComment,
+	".yaml": hashComment, ".yml": hashComment, ".toml": hashComment, ".cfg": hashComment,
+	".conf": hashComment, ".ini": hashComment, ".properties": hashComment,
+	".tf": hashComment, ".hcl": hashComment, ".env": hashComment, ".mk": hashComment,
+	".dockerfile": hashComment,
+
+	".go": slashComment, ".js": slashComment, ".mjs": slashComment, ".cjs": slashComment,
+	".ts": slashComment, ".tsx": slashComment, ".jsx": slashComment, ".java": slashComment,
+	".kt": slashComment, ".kts": slashComment, ".scala": slashComment, ".groovy": slashComment,
+	".gradle": slashComment, ".rs": slashComment, ".c": slashComment, ".h": slashComment,
+	".cc": slashComment, ".cpp": slashComment, ".hpp": slashComment, ".cs": slashComment,
+	".swift": slashComment, ".dart": slashComment, ".proto": slashComment,
+
+	".sql": dashComment, ".lua": dashComment, ".hs": dashComment,
+
+	".html": xmlComment, ".xml": xmlComment, ".md": xmlComment, ".vue": xmlComment,
+	".csproj": xmlComment,
+
+	".css": cssComment, ".scss": cssComment, ".less": cssComment,
+}
+
+// commentNames maps file names without a telling extension to their comment
+// syntax
+var commentNames = map[string]commentSyntax{
+	"Dockerfile": hashComment, "Containerfile": hashComment, "Makefile": hashComment,
+	"GNUmakefile": hashComment, "Gemfile": hashComment, "Rakefile": hashComment,
+	"Procfile": hashComment, "Justfile": hashComment, "justfile": hashComment,
+	".gitignore": hashComment, ".dockerignore": hashComment, ".gitattributes": hashComment,
+	".editorconfig": hashComment, ".env": hashComment, ".env.example": hashComment,
+}
+
+// commentSyntaxFor returns the comment syntax of the file at dest
+func commentSyntaxFor(dest string) (commentSyntax, bool) {
+	name := path.Base(dest)
+	if syntax, ok := commentNames[name]; ok {
+		return syntax, true
+	}
+	if strings.HasPrefix(name, "Dockerfile.") {
+		return hashComment, true
+	}
+	syntax, ok := commentSyntaxes[strings.ToLower(path.Ext(name))]
+	return syntax, ok
+}
+
+// wantsProvenance reports whether a file spec gets a provenance header when
+// the template or the options enable them. Files combined with existing
+// content (appended, patched, merged) never do.
+func wantsProvenance(fileSpec template.FileSpec, enabled bool) bool {
+	if fileSpec.Mode != "" && fileSpec.Mode != template.WriteOverwrite {
+		return false
+	}
+	if fileSpec.Provenance != nil {
+		return *fileSpec.Provenance
+	}
+	return enabled
+}
+
+// provenanceLines returns the text of the provenance header
+func provenanceLines(ctx *template.Context) []string {
+	devinit := "devinit"
+	if ctx.DevinitVersion != "" {
+		devinit += " " + ctx.DevinitVersion
+	}
+	return []string{
+		fmt.Sprintf("Generated by %s from template %s %s.", devinit, ctx.Template.ID, ctx.Template.Version),
+		"Edits to this file may be overwritten when the project is regenerated.",
+	}
+}
+
+// withProvenance prepends the provenance header to the content of a file
+// written to dest, unless its type has no comment syntax, the content is not
+// text or it is Markdown with front matter. Lines that must come first
+// (shebangs, XML declarations, Dockerfile parser directives) stay first.
+func withProvenance(content []byte, dest string, ctx *template.Context) []byte {
+	syntax, ok := commentSyntaxFor(dest)
+	if !ok || !utf8.Valid(content) || bytes.IndexByte(content, 0) >= 0 {
+		return content
+	}
+	if syntax == xmlComment && bytes.HasPrefix(content, []byte("---")) {
+		return content
+	}
+
+	var header strings.Builder
+	for _, line := range provenanceLines(ctx) {
+		header.WriteString(syntax.prefix + line + syntax.suffix + "\n")
+	}
+
+	offset := leadingDirectives(content, dest)
+	out := make([]byte, 0, len(content)+header.Len())
+	out = append(out, content[:offset]...)
+	out = append(out, header.String()...)
+	return append(out, content[offset:]...)
+}
+
+// leadingDirectives returns the length of the lines at the start of content
+// that must precede any comment
+func leadingDirectives(content []byte, dest string) int {
+	name := path.Base(dest)
+	dockerfile := name == "Dockerfile" || name == "Containerfile" || strings.HasPrefix(name, "Dockerfile.") ||
+		strings.HasSuffix(strings.ToLower(name), ".dockerfile")
+
+	offset := 0
+	for first := true; offset < len(content); first = false {
+		end := bytes.IndexByte(content[offset:], '\n')
+		if end < 0 {
+			end = len(content) - offset
+		} else {
+			end++
+		}
+		line := string(content[offset : offset+end])
+
+		switch {
+		case first && (strings.HasPrefix(line, "#!") || strings.HasPrefix(line, "<?xml")):
+		case dockerfile && isDockerDirective(line):
+		default:
+			return offset
+		}
+		offset += end
+	}
+	return offset
+}
+
+// isDockerDirective reports whether line is a Dockerfile parser directive
+// such as "# syntax=docker/dockerfile:1"
+func isDockerDirective(line string) bool {
+	comment, ok := strings.CutPrefix(strings.TrimSpace(line), "#")
+	if !ok {
+		return false
+	}
+	directive, _, ok := strings.Cut(comment, "=")
+	if !ok {
+		return false
+	}
+	switch strings.ToLower(strings.TrimSpace(directive)) {
+	case "syntax", "escape", "check":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package generator
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/renan-dev/devinit/internal/output"
+	"github.com/renan-dev/devinit/internal/template"
+)
+
+// RenderOptions configure rendering outside of templates (see RenderTree)
+type RenderOptions struct {
+	// ProjectName fills {{ .ProjectName }} and the names derived from it;
+	// it may be empty
+	ProjectName string
+	Variables   map[string]interface{}
+
+	// Engine is template.EngineGoTemplate (the default) or
+	// template.EngineJinja2
+	Engine string
+
+	// Delimiters replace {{ and }} in Go templates, e.g. "[[" and "]]"
+	Delimiters []string
+}
+
+// renderContext returns the context of opts, for a template of their engine
+func (g *Generator) renderContext(opts *RenderOptions) (*template.Context, error) {
+	switch opts.Engine {
+	case "", template.EngineGoTemplate, template.EngineJinja2:
+	default:
+		return nil, fmt.Errorf("unknown engine %q (use %s or %s)", opts.Engine, template.EngineGoTemplate, template.EngineJinja2)
+	}
+	if len(opts.Delimiters) > 0 {
+		if opts.Engine == template.EngineJinja2 {
+			return nil, fmt.Errorf("delimiters are not supported with engine %s", template.EngineJinja2)
+		}
+		if len(opts.Delimiters) != 2 || opts.Delimiters[0] == "" || opts.Delimiters[1] == "" {
+			return nil, fmt.Errorf("invalid delimiters %q: need a left and a right delimiter", opts.Delimiters)
+		}
+	}
+
+	variables := make(map[string]interface{}, len(opts.Variables))
+	for key, value := range opts.Variables {
+		variables[key] = value
+	}
+	tmpl := &template.Template{Engine: opts.Engine, Delimiters: opts.Delimiters}
+	return g.newContext(opts.ProjectName, ".", variables, tmpl), nil
+}
+
+// RenderText renders a single template with the helpers and context of
+// templates. name appears in errors.
+func (g *Generator) RenderText(name, text string, opts *RenderOptions) (string, error) {
+	ctx, err := g.renderContext(opts)
+	if err != nil {
+		return "", err
+	}
+	return g.renderer.RenderText(name, text, ctx)
+}
+
+// RenderTree renders the files below dir to out, without the conventions of
+// templates: no template.yaml, conditions, hooks or metadata. Files ending
+// in .tmpl are rendered and lose the suffix, others are copied; template
+// expressions in paths are rendered too. It returns the paths written.
+// Paths use the delimiters of opts as well.
+func (g *Generator) RenderTree(dir string, opts *RenderOptions, out output.Backend) ([]string, error) {
+	ctx, err := g.renderContext(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	left := "{{"
+	if len(opts.Delimiters) == 2 {
+		left = opts.Delimiters[0]
+	}
+
+	var written []string
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		dest := filepath.ToSlash(rel)
+		if strings.Contains(dest, left) {
+			if dest, err = g.renderer.RenderText(rel, dest, ctx); err != nil {
+				return fmt.Errorf("%s: %w", rel, err)
+			}
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if g.renderer.ShouldRender(dest) {
+			dest = g.renderer.GetOutputFilename(dest)
+			content, err := g.renderer.RenderText(path, string(data), ctx)
+			if err != nil {
+				return fmt.Errorf("%s: %w", rel, err)
+			}
+			data = []byte(content)
+		}
+
+		if err := out.WriteFile(dest, data, info.Mode().Perm()); err != nil {
+			return err
+		}
+		written = append(written, dest)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return written, nil
+}
@@ -0,0 +1,147 @@
+package generator
+
+import (
+	"time"
+
+	"github.com/renan-dev/devinit/internal/template"
+)
+
+// FileAction describes what happened to a template file during generation
+type FileAction string
+
+const (
+	FileActionCreated FileAction = "created"
+	FileActionUpdated FileAction = "updated" // combined with an existing file
+	FileActionPlanned FileAction = "planned" // dry run
+	FileActionSkipped FileAction = "skipped" // conditions not met
+)
+
+// FileResult describes a single generated (or skipped) file
+type FileResult struct {
+	Source   string     `json:"source"`
+	Path     string     `json:"path"`
+	Action   FileAction `json:"action"`
+	Rendered bool       `json:"rendered"`
+
+	// Cached is set when the rendered content came from the render cache
+	Cached bool `json:"cached,omitempty"`
+
+	// Override is set when the content came from the project's override of
+	// the template file
+	Override bool `json:"override,omitempty"`
+
+	// Write mode of the file spec (empty for the default overwrite)
+	Mode template.WriteMode `json:"mode,omitempty"`
+
+	// Conditions of the file spec; for skipped files only those not met
+	Conditions []string `json:"conditions,omitempty"`
+
+	// Size is the number of bytes the file spec added to its destination
+	Size int64 `json:"size"`
+}
+
+// HookResult describes a single lifecycle hook, executed or skipped
+type HookResult struct {
+	Stage      string        `json:"stage"`
+	Name       string        `json:"name,omitempty"`
+	Command    string        `json:"command"`
+	WorkingDir string        `json:"working_dir"`
+	Duration   time.Duration `json:"duration_ns"`
+	Error      string        `json:"error,omitempty"`
+
+	// Attempts is the number of times the hook ran (more than 1 only for
+	// retried network hooks)
+	Attempts int `json:"attempts"`
+
+	// Last lines of output of a failed hook
+	Output []string `json:"output,omitempty"`
+
+	// Idempotent hooks are safe to run again on the project
+	Idempotent bool `json:"idempotent,omitempty"`
+
+	// Skipped is set for hooks that did not run: their When condition, or
+	// the hook they run after that failed
+	Skipped   bool   `json:"skipped,omitempty"`
+	When      string `json:"when,omitempty"`
+	BlockedBy string `json:"blocked_by,omitempty"`
+}
+
+// Ran reports whether the hook was executed
+func (h HookResult) Ran() bool {
+	return !h.Skipped
+}
+
+// SBOMResult describes the bill of materials written for a project
+type SBOMResult struct {
+	Path       string `json:"path"`
+	Components int    `json:"components"`
+}
+
+// WorkspaceResult describes how a service was wired into its workspace
+type WorkspaceResult struct {
+	Root    string           `json:"root"`
+	Service template.Service `json:"service"`
+
+	// Files of the workspace that were changed, relative to Root
+	Files []string `json:"files"`
+}
+
+// GenerationResult describes the outcome of a project generation
+type GenerationResult struct {
+	Template  *template.Template `json:"-"`
+	OutputDir string             `json:"output_dir"`
+	DryRun    bool               `json:"dry_run"`
+
+	// Resolved template identity
+	TemplateName    string `json:"template"`
+	TemplateVersion string `json:"template_version"`
+
+	// Deprecation notice of the template (empty if not deprecated)
+	Deprecation string `json:"deprecation,omitempty"`
+
+	Files    []FileResult  `json:"files"`
+	Hooks    []HookResult  `json:"hooks"`
+	HookLog  string        `json:"hook_log,omitempty"`
+	Warnings []string      `json:"warnings"`
+	Duration time.Duration `json:"duration_ns"`
+
+	// Rendered post-generation instructions
+	NextSteps []string `json:"next_steps"`
+
+	// Bill of materials of the generated project (nil if none was written)
+	SBOM *SBOMResult `json:"sbom,omitempty"`
+
+	// Workspace the service was wired into (nil outside a workspace)
+	Workspace *WorkspaceResult `json:"workspace,omitempty"`
+
+	// Outcome of the vulnerability scan (nil if none was requested)
+	Scan *ScanResult `json:"scan,omitempty"`
+
+	// Outcome of the smoke test (nil if none was requested)
+	SmokeTest *SmokeResult `json:"smoke_test,omitempty"`
+
+	// Healthcheck of the generated service (nil if the template has none)
+	Healthcheck *template.Healthcheck `json:"healthcheck,omitempty"`
+}
+
+// FilesWritten returns the number of files created (or planned, for dry runs)
+func (r *GenerationResult) FilesWritten() int {
+	count := 0
+	for _, f := range r.Files {
+		if f.Action != FileActionSkipped {
+			count++
+		}
+	}
+	return count
+}
+
+// TotalSize returns the combined size in bytes of all written files
+func (r *GenerationResult) TotalSize() int64 {
+	var total int64
+	for _, f := range r.Files {
+		if f.Action != FileActionSkipped {
+			total += f.Size
+		}
+	}
+	return total
+}
@@ -0,0 +1,27 @@
+package generator
+
+import (
+	"path/filepath"
+
+	"github.com/renan-dev/devinit/internal/i18n"
+	"github.com/renan-dev/devinit/internal/sbom"
+	"github.com/renan-dev/devinit/internal/template"
+)
+
+// writeSBOM stores the bill of materials of the generated project under
+// .devinit/. It only informs audits, so failures become warnings.
+func (g *Generator) writeSBOM(ctx *template.Context, result *GenerationResult) *SBOMResult {
+	tool := sbom.Tool{Name: "devinit", Version: g.version}
+	components, warnings, err := sbom.Write(ctx.OutputDir, ctx.ProjectName, tool)
+	for _, warning := range warnings {
+		result.Warnings = append(result.Warnings, i18n.T("generator.sbom_skipped", warning))
+	}
+	if err != nil {
+		result.Warnings = append(result.Warnings, i18n.T("generator.sbom_failed", err))
+		return nil
+	}
+	return &SBOMResult{
+		Path:       filepath.Join(ctx.OutputDir, filepath.FromSlash(sbom.FileName)),
+		Components: len(components),
+	}
+}
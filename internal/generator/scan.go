@@ -0,0 +1,152 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/renan-dev/devinit/internal/i18n"
+	"github.com/renan-dev/devinit/internal/template"
+	"github.com/renan-dev/devinit/internal/vulnscan"
+)
+
+// ScanTarget selects what the vulnerability scan of a generated project
+// inspects
+type ScanTarget string
+
+const (
+	// ScanProject scans the project's dependencies with the scanner of each
+	// ecosystem (pip-audit, npm audit, govulncheck), or trivy when none of
+	// them applies or is installed
+	ScanProject ScanTarget = "project"
+	// ScanImage scans the image built for the project with trivy
+	ScanImage ScanTarget = "image"
+)
+
+// defaultScanTimeout bounds each scanner run
+const defaultScanTimeout = 10 * time.Minute
+
+// ScannerRun describes one scanner invocation
+type ScannerRun struct {
+	Scanner string `json:"scanner"`
+	Command string `json:"command"`
+
+	// Skipped explains why the scanner did not run, e.g. it is not installed
+	Skipped string `json:"skipped,omitempty"`
+
+	// Error describes why the scanner produced no report
+	Error string `json:"error,omitempty"`
+
+	Findings int `json:"findings"`
+}
+
+// ScanResult describes the outcome of a vulnerability scan
+type ScanResult struct {
+	Target   ScanTarget         `json:"target"`
+	Image    string             `json:"image,omitempty"`
+	FailOn   vulnscan.Severity  `json:"fail_on"`
+	Scanners []ScannerRun       `json:"scanners"`
+	Findings []vulnscan.Finding `json:"findings"`
+
+	// Failed is set when findings reach the FailOn severity
+	Failed bool `json:"failed"`
+}
+
+// Scanned reports whether any scanner produced a report
+func (r *ScanResult) Scanned() bool {
+	for _, run := range r.Scanners {
+		if run.Skipped == "" && run.Error == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// scan runs the vulnerability scanners for target against the generated
+// project in ctx.OutputDir. Scanners that are missing or fail are reported
+// on the result and as warnings; only findings fail the scan.
+func (g *Generator) scan(ctx *template.Context, target ScanTarget, failOn vulnscan.Severity, log *hookLog, result *GenerationResult) *ScanResult {
+	if failOn == "" {
+		failOn = vulnscan.SeverityHigh
+	}
+	scan := &ScanResult{Target: target, FailOn: failOn, Scanners: []ScannerRun{}, Findings: []vulnscan.Finding{}}
+
+	var jobs []vulnscan.Job
+	if target == ScanImage {
+		scan.Image = ctx.Image
+		jobs = []vulnscan.Job{vulnscan.ImageJob(ctx.Image)}
+	} else {
+		jobs = vulnscan.ProjectJobs(ctx.OutputDir)
+	}
+
+	for _, job := range jobs {
+		g.runScanner(job, ctx.OutputDir, log, scan)
+	}
+	if target == ScanProject && !scan.Scanned() {
+		g.runScanner(vulnscan.FilesystemJob(), ctx.OutputDir, log, scan)
+	}
+
+	for _, run := range scan.Scanners {
+		if run.Error != "" {
+			result.Warnings = append(result.Warnings, i18n.T("generator.scan_error", run.Scanner, run.Error))
+		}
+	}
+	if !scan.Scanned() {
+		result.Warnings = append(result.Warnings, i18n.T("generator.scan_unavailable"))
+	}
+
+	vulnscan.Sort(scan.Findings)
+	scan.Failed = len(vulnscan.Failing(scan.Findings, failOn)) > 0
+	return scan
+}
+
+// runScanner runs job in dir and adds its findings to scan. Scanners exit
+// with an error when they find vulnerabilities, so the exit status only
+// matters when there is no report to parse.
+func (g *Generator) runScanner(job vulnscan.Job, dir string, log *hookLog, scan *ScanResult) {
+	run := ScannerRun{Scanner: job.Scanner, Command: strings.Join(job.Args, " ")}
+	defer func() { scan.Scanners = append(scan.Scanners, run) }()
+
+	if _, err := exec.LookPath(job.Args[0]); err != nil {
+		run.Skipped = i18n.T("generator.scan_not_installed", job.Args[0])
+		log.note(run.Skipped)
+		return
+	}
+
+	runCtx, cancel := context.WithTimeout(context.Background(), defaultScanTimeout)
+	defer cancel()
+
+	var report bytes.Buffer
+	out, tail := log.start("scan", run.Command, dir, 1, 1)
+	cmd := exec.CommandContext(runCtx, job.Args[0], job.Args[1:]...)
+	cmd.Dir = dir
+	if env := g.network.Env(); len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	cmd.Stdout = &report
+	cmd.Stderr = out
+	runErr := cmd.Run()
+
+	findings, err := job.Parse(report.Bytes())
+	if err != nil {
+		switch {
+		case errors.Is(runCtx.Err(), context.DeadlineExceeded):
+			err = i18n.Errorf("generator.scan_timeout", defaultScanTimeout)
+		case runErr != nil:
+			err = runErr
+			if lines := tail.Lines(); len(lines) > 0 {
+				err = fmt.Errorf("%w: %s", runErr, lines[len(lines)-1])
+			}
+		}
+		run.Error = err.Error()
+		return
+	}
+
+	run.Findings = len(findings)
+	scan.Findings = append(scan.Findings, findings...)
+}
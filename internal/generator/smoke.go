@@ -0,0 +1,211 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/renan-dev/devinit/internal/i18n"
+	"github.com/renan-dev/devinit/internal/template"
+)
+
+// SmokeMode selects where the smoke test of a generated project runs
+type SmokeMode string
+
+const (
+	// SmokeLocal runs the commands on the host in a temporary copy of the
+	// project, so installed dependencies and caches do not end up in it
+	SmokeLocal SmokeMode = "local"
+	// SmokeContainer runs each command in a throwaway container of the
+	// template's smoke test image, with the temporary copy mounted
+	SmokeContainer SmokeMode = "container"
+)
+
+// defaultSmokeTimeout bounds smoke tests that do not declare a timeout
+const defaultSmokeTimeout = 10 * time.Minute
+
+// smokeWorkDir is where the project copy is mounted in containers
+const smokeWorkDir = "/project"
+
+// SmokeResult describes the outcome of a smoke test
+type SmokeResult struct {
+	Mode     SmokeMode     `json:"mode"`
+	Command  string        `json:"command"`
+	Image    string        `json:"image,omitempty"`
+	Passed   bool          `json:"passed"`
+	Duration time.Duration `json:"duration_ns"`
+
+	// Error describes the failed step; it is empty when the test passed or
+	// was skipped
+	Error string `json:"error,omitempty"`
+
+	// Last lines of output of the failed command
+	Output []string `json:"output,omitempty"`
+}
+
+// Skipped reports whether no test command was run
+func (r *SmokeResult) Skipped() bool {
+	return r.Command == ""
+}
+
+// smokeTest runs the template's smoke test against the generated project in
+// ctx.OutputDir. Failures are reported on the result; an error is returned
+// only when the smoke test cannot be prepared.
+func (g *Generator) smokeTest(tmpl *template.Template, ctx *template.Context, mode SmokeMode, log *hookLog) (*SmokeResult, error) {
+	result := &SmokeResult{Mode: mode}
+	spec := tmpl.SmokeTest
+	if spec == nil {
+		return result, nil
+	}
+
+	command, err := g.renderer.RenderString("smoke_test.run", spec.Run, ctx)
+	if err != nil {
+		return nil, err
+	}
+	result.Command = strings.TrimSpace(command)
+	if result.Command == "" {
+		return result, nil
+	}
+
+	var steps [][]string
+	for i, setup := range spec.Setup {
+		rendered, err := g.renderer.RenderString("smoke_test.setup", setup, ctx)
+		if err != nil {
+			return nil, i18n.Errorf("generator.smoke_render", i+1, err)
+		}
+		if args := strings.Fields(rendered); len(args) > 0 {
+			steps = append(steps, args)
+		}
+	}
+	steps = append(steps, strings.Fields(result.Command))
+
+	if mode == SmokeContainer {
+		if spec.Image == "" {
+			return nil, i18n.Errorf("generator.smoke_no_image", tmpl.ID)
+		}
+		image, err := g.renderer.RenderString("smoke_test.image", spec.Image, ctx)
+		if err != nil {
+			return nil, err
+		}
+		result.Image = strings.TrimSpace(image)
+	}
+
+	timeout := defaultSmokeTimeout
+	if spec.Timeout != "" {
+		timeout, _ = time.ParseDuration(spec.Timeout) // validated by the loader
+	}
+
+	workDir, err := os.MkdirTemp("", "devinit-smoke-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(workDir)
+	if err := copyProject(ctx.OutputDir, workDir); err != nil {
+		return nil, i18n.Errorf("generator.smoke_copy", err)
+	}
+
+	start := time.Now()
+	runCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	for _, args := range steps {
+		display := strings.Join(args, " ")
+		if mode == SmokeContainer {
+			args = append(containerArgs(workDir, result.Image), args...)
+		}
+
+		out, tail := log.start("smoke_test", display, workDir, 1, 1)
+		cmd := exec.CommandContext(runCtx, args[0], args[1:]...)
+		cmd.Dir = workDir
+		if env := g.network.Env(); len(env) > 0 && mode == SmokeLocal {
+			cmd.Env = append(os.Environ(), env...)
+		}
+		cmd.Stdout = out
+		cmd.Stderr = out
+
+		if err := cmd.Run(); err != nil {
+			if errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+				err = i18n.Errorf("generator.smoke_timeout", timeout)
+			}
+			result.Error = i18n.T("generator.smoke_step_failed", display, err)
+			result.Output = tail.Lines()
+			result.Duration = time.Since(start)
+			return result, nil
+		}
+	}
+
+	result.Passed = true
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// containerArgs returns the docker command running a command in image with
+// the project copy in workDir mounted. The command runs as the current user,
+// so the copy stays removable, with a writable HOME for tool caches.
+func containerArgs(workDir, image string) []string {
+	args := []string{"docker", "run", "--rm", "-v", workDir + ":" + smokeWorkDir, "-w", smokeWorkDir, "-e", "HOME=/tmp"}
+	if uid := os.Getuid(); uid >= 0 {
+		args = append(args, "--user", fmt.Sprintf("%d:%d", uid, os.Getgid()))
+	}
+	return append(args, image)
+}
+
+// copyProject copies the project in src to dst, leaving out devinit's logs
+// and the git repository
+func copyProject(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		if d.IsDir() && (rel == ".git" || rel == filepath.FromSlash(hookLogDir)) {
+			return filepath.SkipDir
+		}
+
+		target := filepath.Join(dst, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		case info.Mode()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		default:
+			return copyFile(path, target, info.Mode().Perm())
+		}
+	})
+}
+
+func copyFile(src, dst string, perm fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
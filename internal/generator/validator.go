@@ -4,6 +4,14 @@ import (
 	"fmt"
 	"os"
 	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/renan-dev/devinit/internal/errcode"
+	"github.com/renan-dev/devinit/internal/i18n"
+	"github.com/renan-dev/devinit/internal/template"
+	"github.com/renan-dev/devinit/internal/validator"
 )
 
 var projectNamePattern = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
@@ -19,27 +27,136 @@ var projectNamePattern = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
 // - Only lowercase letters, numbers, and hyphens allowed
 // - This ensures compatibility across filesystems and platforms
 func ValidateProjectName(name string) error {
+	return ValidateProjectDir(name, name)
+}
+
+// ValidateProjectDir validates a project name like ValidateProjectName, for
+// a project generated in dir rather than in a directory of the name in the
+// working directory
+func ValidateProjectDir(name, dir string) error {
 	if name == "" {
-		return fmt.Errorf("project name cannot be empty")
+		return errcode.New(errcode.VariableInvalid, i18n.Errorf("validate.name_empty"))
 	}
 
 	if name == "." || name == ".." {
-		return fmt.Errorf("invalid project name: '.' and '..' are not allowed")
+		return errcode.New(errcode.VariableInvalid, i18n.Errorf("validate.name_dots"))
 	}
 
 	for _, char := range name {
 		if char == '/' || char == '\\' {
-			return fmt.Errorf("invalid project name: path separators are not allowed")
+			return errcode.New(errcode.VariableInvalid, i18n.Errorf("validate.name_separators"))
 		}
 	}
 
 	if !projectNamePattern.MatchString(name) {
-		return fmt.Errorf("invalid project name: must start with lowercase letter and contain only lowercase letters, numbers, and hyphens")
+		return errcode.New(errcode.VariableInvalid, i18n.Errorf("validate.name_format"))
+	}
+
+	if _, err := os.Stat(dir); err == nil {
+		return errcode.New(errcode.Conflict, i18n.Errorf("validate.name_exists", dir))
+	}
+
+	return nil
+}
+
+// ValidateVariables checks variable values against the choices and patterns
+// declared by the template; ValidationStrict also checks boolean and int
+// values, ValidationNone checks nothing. Variables without a value are not
+// checked.
+func ValidateVariables(tmpl *template.Template, variables map[string]interface{}, level validator.ValidationLevel) error {
+	if level == validator.ValidationNone {
+		return nil
+	}
+
+	names := make([]string, 0, len(tmpl.Variables))
+	for name := range tmpl.Variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		value, ok := variables[name]
+		if !ok {
+			continue
+		}
+		def := tmpl.Variables[name]
+		str := fmt.Sprint(value)
+
+		if level == validator.ValidationStrict && !matchesType(def.Type, value) {
+			return errcode.New(errcode.VariableInvalid, i18n.Errorf("validate.var_type", str, name, def.Type))
+		}
+
+		if def.Type == template.VariableTypeChoice && len(def.Choices) > 0 {
+			valid := false
+			for _, choice := range def.Choices {
+				if str == choice.Value {
+					valid = true
+					break
+				}
+			}
+			if !valid {
+				return errcode.New(errcode.VariableInvalid, i18n.Errorf("validate.var_choice", str, name, strings.Join(def.ChoiceValues(), ", ")))
+			}
+		}
+
+		if def.Pattern != "" {
+			pattern, err := regexp.Compile(def.Pattern)
+			if err != nil {
+				return errcode.New(errcode.TemplateInvalid, i18n.Errorf("validate.var_pattern", name, err))
+			}
+			if !pattern.MatchString(str) {
+				return errcode.New(errcode.VariableInvalid, i18n.Errorf("validate.var_pattern_fail", str, name, def.Pattern))
+			}
+		}
 	}
 
-	if _, err := os.Stat(name); err == nil {
-		return fmt.Errorf("directory '%s' already exists", name)
+	return nil
+}
+
+// CheckVariableRefs fails when the template references variables it does
+// not declare (see Template.UndeclaredVariables) and variables does not set
+// either, which are most likely typos
+func CheckVariableRefs(tmpl *template.Template, variables map[string]interface{}) error {
+	refs, err := tmpl.UndeclaredVariables()
+	if err != nil {
+		return errcode.New(errcode.TemplateInvalid, err)
 	}
 
+	var undeclared []string
+	for _, ref := range refs {
+		if _, ok := variables[ref.Name]; !ok {
+			undeclared = append(undeclared, ref.String())
+		}
+	}
+	if len(undeclared) > 0 {
+		return errcode.New(errcode.TemplateInvalid, i18n.Errorf("generator.undeclared_variables", tmpl.ID, strings.Join(undeclared, ", ")))
+	}
 	return nil
 }
+
+// matchesType reports whether a variable value fits the declared type.
+// Strings from --var are accepted when they parse as the type.
+func matchesType(typ template.VariableType, value interface{}) bool {
+	switch typ {
+	case template.VariableTypeBool:
+		switch v := value.(type) {
+		case bool:
+			return true
+		case string:
+			_, err := strconv.ParseBool(v)
+			return err == nil
+		}
+		return false
+	case template.VariableTypeInt:
+		switch v := value.(type) {
+		case int, int64:
+			return true
+		case string:
+			_, err := strconv.Atoi(v)
+			return err == nil
+		}
+		return false
+	default:
+		return true
+	}
+}
@@ -1,9 +1,14 @@
 package generator
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/renan-dev/devinit/internal/errcode"
+	"github.com/renan-dev/devinit/internal/template"
+	"github.com/renan-dev/devinit/internal/validator"
 )
 
 func TestValidateProjectName(t *testing.T) {
@@ -193,6 +198,122 @@ func TestValidateProjectName_ExistingDirectory(t *testing.T) {
 	if !containsString(err.Error(), "already exists") {
 		t.Errorf("Error message should mention 'already exists', got: %v", err)
 	}
+	if !errors.Is(err, errcode.ErrConflict) {
+		t.Errorf("Error should be a conflict, got code %s", errcode.Of(err))
+	}
+}
+
+func TestValidateProjectDir(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(tmpDir, "existing-project"), 0755); err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+
+	// The directory given is checked, not the name in the working directory
+	if err := ValidateProjectDir("existing-project", filepath.Join(tmpDir, "existing-project")); !errors.Is(err, errcode.ErrConflict) {
+		t.Errorf("ValidateProjectDir() of an existing directory error = %v, want a conflict", err)
+	}
+	if err := ValidateProjectDir("new-project", filepath.Join(tmpDir, "new-project")); err != nil {
+		t.Errorf("ValidateProjectDir() of a free directory unexpected error: %v", err)
+	}
+	if err := ValidateProjectDir("Bad Name", filepath.Join(tmpDir, "bad")); !errors.Is(err, errcode.ErrVariableInvalid) {
+		t.Errorf("ValidateProjectDir() of an invalid name error = %v, want an invalid variable", err)
+	}
+}
+
+func TestValidateVariables(t *testing.T) {
+	tmpl := &template.Template{
+		Variables: map[string]template.Variable{
+			"build_tool": {
+				Type:    template.VariableTypeChoice,
+				Choices: []template.Choice{{Value: "maven"}, {Value: "gradle", Label: "Gradle"}},
+			},
+			"package_name": {
+				Type:    template.VariableTypeString,
+				Pattern: `^[a-z][a-z0-9_]*(\.[a-z][a-z0-9_]*)+$`,
+			},
+			"use_kafka": {Type: template.VariableTypeBool},
+			"workers":   {Type: template.VariableTypeInt},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		variables map[string]interface{}
+		level     validator.ValidationLevel
+		wantError bool
+	}{
+		{
+			name:      "valid values",
+			level:     validator.ValidationBasic,
+			variables: map[string]interface{}{"build_tool": "gradle", "package_name": "com.acme.billing"},
+		},
+		{
+			name:      "missing values are not checked",
+			level:     validator.ValidationBasic,
+			variables: map[string]interface{}{},
+		},
+		{
+			name:      "invalid choice",
+			level:     validator.ValidationBasic,
+			variables: map[string]interface{}{"build_tool": "ant"},
+			wantError: true,
+		},
+		{
+			// Labels are for prompts; templates only ever get values
+			name:      "choice label",
+			level:     validator.ValidationBasic,
+			variables: map[string]interface{}{"build_tool": "Gradle"},
+			wantError: true,
+		},
+		{
+			name:      "pattern mismatch",
+			level:     validator.ValidationBasic,
+			variables: map[string]interface{}{"package_name": "Com.Acme"},
+			wantError: true,
+		},
+		{
+			name:      "undeclared variables are ignored",
+			level:     validator.ValidationBasic,
+			variables: map[string]interface{}{"IncludeDocker": true},
+		},
+		{
+			name:      "types are not checked by default",
+			level:     validator.ValidationBasic,
+			variables: map[string]interface{}{"use_kafka": "yes", "workers": "many"},
+		},
+		{
+			name:      "strict accepts typed and parseable values",
+			level:     validator.ValidationStrict,
+			variables: map[string]interface{}{"use_kafka": true, "workers": "4"},
+		},
+		{
+			name:      "strict rejects non-boolean",
+			level:     validator.ValidationStrict,
+			variables: map[string]interface{}{"use_kafka": "yes"},
+			wantError: true,
+		},
+		{
+			name:      "strict rejects non-integer",
+			level:     validator.ValidationStrict,
+			variables: map[string]interface{}{"workers": "many"},
+			wantError: true,
+		},
+		{
+			name:      "disabled validation checks nothing",
+			level:     validator.ValidationNone,
+			variables: map[string]interface{}{"build_tool": "ant"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateVariables(tmpl, tt.variables, tt.level)
+			if (err != nil) != tt.wantError {
+				t.Errorf("ValidateVariables() error = %v, wantError %v", err, tt.wantError)
+			}
+		})
+	}
 }
 
 // Helper function to check if a string contains a substring
@@ -0,0 +1,51 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/renan-dev/devinit/internal/i18n"
+	"github.com/renan-dev/devinit/internal/template"
+	"github.com/renan-dev/devinit/internal/workspace"
+)
+
+// joinWorkspace returns the devinit workspace the project in ctx is
+// generated into, or nil, and exposes the other services of the workspace to
+// the project's templates
+func (g *Generator) joinWorkspace(ctx *template.Context, result *GenerationResult) *workspace.Workspace {
+	ws, err := workspace.Find(filepath.Dir(ctx.OutputDir))
+	if err != nil {
+		result.Warnings = append(result.Warnings, i18n.T("generator.workspace_failed", err))
+		return nil
+	}
+	if ws == nil {
+		return nil
+	}
+	ctx.Services = ws.Siblings(ctx.ProjectName)
+	return ws
+}
+
+// wireWorkspace adds the generated service to the compose file, shared env
+// files and CI matrix of the workspace. The project is usable without them,
+// so failures become warnings.
+func (g *Generator) wireWorkspace(ws *workspace.Workspace, ctx *template.Context, result *GenerationResult) *WorkspaceResult {
+	dir, err := ws.ServiceDir(ctx.OutputDir)
+	if err != nil {
+		result.Warnings = append(result.Warnings, i18n.T("generator.workspace_failed", err))
+		return nil
+	}
+	svc := template.Service{
+		Name:     ctx.ProjectName,
+		Host:     ctx.ProjectName,
+		Port:     ctx.Port,
+		Dir:      dir,
+		Template: ctx.Template.ID,
+	}
+
+	_, statErr := os.Stat(filepath.Join(ctx.OutputDir, "Dockerfile"))
+	files, err := ws.Wire(svc, ctx.IncludeDocker && statErr == nil)
+	if err != nil {
+		result.Warnings = append(result.Warnings, i18n.T("generator.workspace_failed", err))
+	}
+	return &WorkspaceResult{Root: ws.Root, Service: svc, Files: files}
+}
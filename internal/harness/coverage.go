@@ -0,0 +1,164 @@
+package harness
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/renan-dev/devinit/internal/template"
+)
+
+// CoverageKind tells conditions and template branches apart
+type CoverageKind string
+
+const (
+	// CoverageCondition is a condition of a file spec
+	CoverageCondition CoverageKind = "condition"
+	// CoverageBranch is an {{ if }}, {{ with }} or {{ range }} of a file
+	CoverageBranch CoverageKind = "branch"
+)
+
+// CoverageItem counts how often a condition held (True) or did not (False)
+// across test cases; for branches, how often the body or the else part ran.
+// Branches of files a case did not generate are not counted.
+type CoverageItem struct {
+	Kind  CoverageKind `json:"kind"`
+	File  string       `json:"file"`           // source below files/
+	Line  int          `json:"line,omitempty"` // branches only
+	Text  string       `json:"text"`           // condition or branch action
+	True  int          `json:"true"`
+	False int          `json:"false"`
+}
+
+// Covered reports whether test cases exercised both outcomes
+func (i CoverageItem) Covered() bool {
+	return i.True > 0 && i.False > 0
+}
+
+// Coverage records which file conditions and template branches of a
+// template the test cases exercise. Pass it in Options.Coverage; cases
+// running concurrently may share it.
+type Coverage struct {
+	mu       sync.Mutex
+	filesDir string
+	items    []*CoverageItem
+	branches map[template.Branch]*CoverageItem
+}
+
+// NewCoverage lists the conditions of the template's file specs and the
+// branches of its rendered files
+func NewCoverage(tmpl *template.Template) (*Coverage, error) {
+	c := &Coverage{
+		filesDir: filepath.Join(tmpl.Path, "files"),
+		branches: make(map[template.Branch]*CoverageItem),
+	}
+
+	renderer := template.NewRenderer()
+	seen := make(map[string]bool)
+	for _, file := range tmpl.Files {
+		for _, condition := range file.Conditions {
+			c.items = append(c.items, &CoverageItem{Kind: CoverageCondition, File: file.Source, Text: condition})
+		}
+
+		// Branches are only tracked in Go templates
+		if !renderer.ShouldRender(file.Source) || seen[file.Source] || tmpl.Engine == template.EngineJinja2 {
+			continue
+		}
+		seen[file.Source] = true
+
+		content, err := os.ReadFile(filepath.Join(c.filesDir, file.Source))
+		if err != nil {
+			return nil, err
+		}
+		branches, err := template.FindBranches(file.Source, string(content), tmpl.FileDelimiters(file))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file.Source, err)
+		}
+		for _, branch := range branches {
+			if c.branches[branch] != nil {
+				continue
+			}
+			item := &CoverageItem{Kind: CoverageBranch, File: branch.File, Line: branch.Line, Text: branch.Action}
+			c.branches[branch] = item
+			c.items = append(c.items, item)
+		}
+	}
+	return c, nil
+}
+
+// RecordBranch implements template.BranchRecorder. The renderer reports
+// branches with the full path of their file.
+func (c *Coverage) RecordBranch(branch template.Branch, taken bool) {
+	if rel, err := filepath.Rel(c.filesDir, branch.File); err == nil {
+		branch.File = filepath.ToSlash(rel)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if item := c.branches[branch]; item != nil {
+		item.record(taken)
+	}
+}
+
+// recordConditions counts the values of the file conditions, in the order of
+// fileConditions
+func (c *Coverage) recordConditions(values []bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, item := range c.items {
+		if item.Kind != CoverageCondition || len(values) == 0 {
+			continue
+		}
+		item.record(values[0])
+		values = values[1:]
+	}
+}
+
+func (i *CoverageItem) record(value bool) {
+	if value {
+		i.True++
+	} else {
+		i.False++
+	}
+}
+
+// Items returns the conditions and branches in file spec order
+func (c *Coverage) Items() []CoverageItem {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	items := make([]CoverageItem, 0, len(c.items))
+	for _, item := range c.items {
+		items = append(items, *item)
+	}
+	return items
+}
+
+// Untested returns the items not exercised both ways
+func (c *Coverage) Untested() []CoverageItem {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var untested []CoverageItem
+	for _, item := range c.items {
+		if !item.Covered() {
+			untested = append(untested, *item)
+		}
+	}
+	return untested
+}
+
+// Summary returns the number of outcomes (two per item) the test cases
+// exercised, and the total
+func (c *Coverage) Summary() (exercised, total int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, item := range c.items {
+		total += 2
+		if item.True > 0 {
+			exercised++
+		}
+		if item.False > 0 {
+			exercised++
+		}
+	}
+	return exercised, total
+}
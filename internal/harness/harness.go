@@ -0,0 +1,520 @@
+// Package harness runs the test fixtures of templates for devinit templates
+// test. Every test case (or combination of its matrix) generates a project
+// in a temporary directory and checks that the template's conditions
+// evaluate and produce the expected files; container verification then builds the generated Dockerfile, starts the
+// services, waits for the healthcheck and runs the smoke test, so template CI
+// can show that templates produce runnable projects.
+package harness
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/renan-dev/devinit/internal/generator"
+	"github.com/renan-dev/devinit/internal/queue"
+	"github.com/renan-dev/devinit/internal/template"
+)
+
+// ProjectName is the name of the projects generated by test cases
+const ProjectName = "devinit-test"
+
+// DefaultCase names the test case of templates without fixtures
+const DefaultCase = "default"
+
+// DefaultStartTimeout bounds how long started services may take to pass the
+// healthcheck
+const DefaultStartTimeout = 2 * time.Minute
+
+// outputLines is the number of output lines kept for a failed step
+const outputLines = 20
+
+// Options for running test cases
+type Options struct {
+	// Container verifies generated projects with Docker: build, start,
+	// healthcheck and smoke test. Without it cases only generate.
+	Container bool
+
+	// StartTimeout overrides DefaultStartTimeout
+	StartTimeout time.Duration
+
+	// Log receives the output of hooks and verification commands; nil
+	// discards it
+	Log io.Writer
+
+	// Coverage, when set, records the conditions and branches the cases
+	// exercise. It must belong to the tested template.
+	Coverage *Coverage
+
+	// Jobs is the number of cases Run and RunJobs run at a time; below 2
+	// they run one after the other. The Log output of parallel cases is
+	// written line by line, each line prefixed with its template and case.
+	Jobs int
+}
+
+// Step names a stage of a test case
+type Step string
+
+const (
+	StepGenerate    Step = "generate"
+	StepConditions  Step = "conditions"
+	StepSmokeTest   Step = "smoke_test"
+	StepBuild       Step = "build"
+	StepStart       Step = "start"
+	StepHealthcheck Step = "healthcheck"
+)
+
+// StepResult describes a stage of a test case
+type StepResult struct {
+	Step     Step          `json:"step"`
+	Passed   bool          `json:"passed"`
+	Duration time.Duration `json:"duration_ns"`
+	Error    string        `json:"error,omitempty"`
+
+	// Last lines of output of a failed step
+	Output []string `json:"output,omitempty"`
+}
+
+// CaseResult describes a test case. Steps stop at the first failure.
+type CaseResult struct {
+	Template  string                 `json:"template"`
+	Case      string                 `json:"case"`
+	Profile   string                 `json:"profile,omitempty"`
+	Variables map[string]interface{} `json:"variables,omitempty"`
+	Passed    bool                   `json:"passed"`
+	Files     int                    `json:"files"`
+	Steps     []StepResult           `json:"steps"`
+	Duration  time.Duration          `json:"duration_ns"`
+}
+
+// Failed returns the step that failed, or nil
+func (r *CaseResult) Failed() *StepResult {
+	for i := range r.Steps {
+		if !r.Steps[i].Passed {
+			return &r.Steps[i]
+		}
+	}
+	return nil
+}
+
+// Cases returns the test cases of a template with their matrices expanded.
+// Templates without fixtures are tested once with their defaults.
+func Cases(tmpl *template.Template) []template.TestCase {
+	if len(tmpl.Tests) == 0 {
+		return []template.TestCase{{Name: DefaultCase}}
+	}
+
+	var cases []template.TestCase
+	for _, tc := range tmpl.Tests {
+		cases = append(cases, expandMatrix(tc)...)
+	}
+	return cases
+}
+
+// expandMatrix returns one case per combination of the matrix values, named
+// after the combination (e.g. "docker/Database=postgres,IncludeDocker=true").
+// Matrix values override the case's variables.
+func expandMatrix(tc template.TestCase) []template.TestCase {
+	if len(tc.Matrix) == 0 {
+		return []template.TestCase{tc}
+	}
+
+	keys := make([]string, 0, len(tc.Matrix))
+	for key := range tc.Matrix {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	combinations := []map[string]interface{}{{}}
+	for _, key := range keys {
+		var next []map[string]interface{}
+		for _, combination := range combinations {
+			for _, value := range tc.Matrix[key] {
+				extended := copyVariables(combination)
+				extended[key] = value
+				next = append(next, extended)
+			}
+		}
+		combinations = next
+	}
+
+	cases := make([]template.TestCase, 0, len(combinations))
+	for _, combination := range combinations {
+		variables := copyVariables(tc.Variables)
+		pairs := make([]string, 0, len(keys))
+		for _, key := range keys {
+			variables[key] = combination[key]
+			pairs = append(pairs, fmt.Sprintf("%s=%v", key, combination[key]))
+		}
+		cases = append(cases, template.TestCase{
+			Name:      tc.Name + "/" + strings.Join(pairs, ","),
+			Profile:   tc.Profile,
+			Variables: variables,
+			Expect:    tc.Expect,
+		})
+	}
+	return cases
+}
+
+// Run runs every test case of a template
+func Run(gen *generator.Generator, tmpl *template.Template, opts Options) []CaseResult {
+	var jobs []Job
+	for _, tc := range Cases(tmpl) {
+		jobs = append(jobs, Job{Template: tmpl, Case: tc, Coverage: opts.Coverage})
+	}
+	results := make([]CaseResult, len(jobs))
+	RunJobs(gen, jobs, opts, func(i int, result CaseResult) {
+		results[i] = result
+	})
+	return results
+}
+
+// Job is a test case of a template for RunJobs
+type Job struct {
+	Template *template.Template
+	Case     template.TestCase
+
+	// Coverage replaces Options.Coverage for the case
+	Coverage *Coverage
+}
+
+// RunJobs runs jobs, opts.Jobs at a time, possibly of several templates. It
+// calls report with the result of every job in the order of jobs, as soon
+// as the job and those before it are done, so results stream in the same
+// order whatever the number of jobs.
+func RunJobs(gen *generator.Generator, jobs []Job, opts Options, report func(i int, result CaseResult)) {
+	q := queue.New(max(opts.Jobs, 1))
+	var log *queue.Output
+	if opts.Log != nil && q.Limit() > 1 {
+		log = queue.NewOutput(opts.Log)
+	}
+
+	done := make([]chan CaseResult, len(jobs))
+	for i, job := range jobs {
+		done[i] = make(chan CaseResult, 1)
+		q.Go(func() {
+			caseOpts := opts
+			caseOpts.Coverage = job.Coverage
+			if log == nil {
+				done[i] <- RunCase(gen, job.Template, job.Case, caseOpts)
+				return
+			}
+			w := log.Writer(fmt.Sprintf("[%s %s] ", job.Template.ID, job.Case.Name))
+			caseOpts.Log = w
+			result := RunCase(gen, job.Template, job.Case, caseOpts)
+			w.Close()
+			done[i] <- result
+		})
+	}
+	for i := range jobs {
+		report(i, <-done[i])
+	}
+	q.Wait()
+}
+
+// RunCase generates the project of a test case in a temporary directory and
+// verifies it
+func RunCase(gen *generator.Generator, tmpl *template.Template, tc template.TestCase, opts Options) (result CaseResult) {
+	start := time.Now()
+	result = CaseResult{Template: tmpl.ID, Case: tc.Name, Profile: tc.Profile, Variables: tc.Variables}
+	defer func() {
+		result.Passed = result.Failed() == nil
+		result.Duration = time.Since(start)
+	}()
+
+	dir, err := os.MkdirTemp("", "devinit-test-")
+	if err != nil {
+		result.Steps = append(result.Steps, StepResult{Step: StepGenerate, Error: err.Error()})
+		return result
+	}
+	defer os.RemoveAll(dir)
+
+	genOpts := &generator.Options{
+		ProjectName: ProjectName,
+		Language:    tmpl.Language,
+		Framework:   tmpl.Framework,
+		OutputDir:   filepath.Join(dir, ProjectName),
+		Variables:   copyVariables(tc.Variables),
+		Profile:     tc.Profile,
+		// Hooks install dependencies; only container verification needs
+		// the project as users get it
+		SkipHooks:  !opts.Container,
+		HookOutput: opts.Log,
+	}
+	if opts.Coverage != nil {
+		genOpts.BranchRecorder = opts.Coverage
+	}
+	if opts.Container {
+		genOpts.SmokeTest = generator.SmokeLocal
+		if tmpl.SmokeTest != nil && tmpl.SmokeTest.Image != "" {
+			genOpts.SmokeTest = generator.SmokeContainer
+		}
+	}
+
+	stepStart := time.Now()
+	generated, err := gen.Generate(genOpts)
+	step := StepResult{Step: StepGenerate, Passed: err == nil, Duration: time.Since(stepStart)}
+	if err != nil {
+		step.Error = err.Error()
+	}
+	result.Steps = append(result.Steps, step)
+	if err != nil {
+		return result
+	}
+	result.Files = generated.FilesWritten()
+
+	if conditions := fileConditions(tmpl); len(conditions) > 0 || len(tc.Expect) > 0 {
+		stepStart := time.Now()
+		err := checkConditions(gen, genOpts, conditions, tc.Expect, generated.OutputDir, opts.Coverage)
+		step := StepResult{Step: StepConditions, Passed: err == nil, Duration: time.Since(stepStart)}
+		if err != nil {
+			step.Error = err.Error()
+		}
+		result.Steps = append(result.Steps, step)
+		if err != nil {
+			return result
+		}
+	}
+
+	if !opts.Container {
+		return result
+	}
+
+	if smoke := generated.SmokeTest; smoke != nil && !smoke.Skipped() {
+		result.Steps = append(result.Steps, StepResult{
+			Step:     StepSmokeTest,
+			Passed:   smoke.Passed,
+			Duration: smoke.Duration,
+			Error:    smoke.Error,
+			Output:   smoke.Output,
+		})
+		if !smoke.Passed {
+			return result
+		}
+	}
+
+	v := &verifier{
+		dir:          generated.OutputDir,
+		name:         filepath.Base(dir),
+		log:          opts.Log,
+		healthcheck:  generated.Healthcheck,
+		startTimeout: opts.StartTimeout,
+	}
+	if v.startTimeout <= 0 {
+		v.startTimeout = DefaultStartTimeout
+	}
+	defer v.cleanup()
+	result.Steps = append(result.Steps, v.verify()...)
+	return result
+}
+
+func fileConditions(tmpl *template.Template) []string {
+	var conditions []string
+	for _, file := range tmpl.Files {
+		conditions = append(conditions, file.Conditions...)
+	}
+	return conditions
+}
+
+// checkConditions evaluates the template's file conditions, which must all
+// render true or false, and compares the generated files in dir with the
+// expected ones. The values of the conditions are counted in coverage, if
+// not nil.
+func checkConditions(gen *generator.Generator, opts *generator.Options, conditions []string, expected []template.ExpectedFile, dir string, coverage *Coverage) error {
+	n := len(conditions)
+	for _, file := range expected {
+		if file.When != "" {
+			conditions = append(conditions, file.When)
+		}
+	}
+	values, err := gen.EvaluateConditions(opts, conditions)
+	if err != nil {
+		return err
+	}
+	if coverage != nil {
+		coverage.recordConditions(values[:n])
+	}
+	values = values[n:]
+
+	var problems []string
+	for _, file := range expected {
+		want := true
+		if file.When != "" {
+			want, values = values[0], values[1:]
+		}
+		_, err := os.Stat(filepath.Join(dir, filepath.FromSlash(file.Path)))
+		switch exists := err == nil; {
+		case want && !exists:
+			problems = append(problems, fmt.Sprintf("%s was not generated", file.Path))
+		case !want && exists:
+			problems = append(problems, fmt.Sprintf("%s was generated although %s is false", file.Path, file.When))
+		}
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func copyVariables(variables map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(variables))
+	for key, value := range variables {
+		copied[key] = value
+	}
+	return copied
+}
+
+// composeFiles are the names docker compose looks for, in its order
+var composeFiles = []string{"compose.yaml", "compose.yml", "docker-compose.yaml", "docker-compose.yml"}
+
+// verifier runs the container steps of a test case and undoes them in
+// cleanup
+type verifier struct {
+	dir          string // generated project
+	name         string // image, container and compose project name
+	log          io.Writer
+	healthcheck  *template.Healthcheck
+	startTimeout time.Duration
+
+	cleanups [][]string
+}
+
+// verify builds and starts the project and waits for its healthcheck. Steps
+// that do not apply (no Dockerfile, nothing to start) are left out.
+func (v *verifier) verify() []StepResult {
+	var steps []StepResult
+	run := func(step Step, fn func() ([]string, error)) bool {
+		start := time.Now()
+		output, err := fn()
+		result := StepResult{Step: step, Passed: err == nil, Duration: time.Since(start)}
+		if err != nil {
+			result.Error = err.Error()
+			result.Output = output
+		}
+		steps = append(steps, result)
+		return err == nil
+	}
+
+	dockerfile := v.exists("Dockerfile")
+	if dockerfile {
+		v.cleanups = append(v.cleanups, []string{"docker", "image", "rm", "-f", v.name})
+		if !run(StepBuild, func() ([]string, error) {
+			return v.command(context.Background(), "docker", "build", "-t", v.name, ".")
+		}) {
+			return steps
+		}
+	}
+
+	started := false
+	switch compose := v.composeFile(); {
+	case compose != "":
+		v.cleanups = append(v.cleanups, []string{"docker", "compose", "-p", v.name, "down", "-v", "--remove-orphans"})
+		started = run(StepStart, func() ([]string, error) {
+			return v.command(context.Background(), "docker", "compose", "-p", v.name, "up", "-d", "--build")
+		})
+	case dockerfile && v.healthcheck != nil:
+		v.cleanups = append(v.cleanups, []string{"docker", "rm", "-f", v.name})
+		port := fmt.Sprintf("%d:%d", v.healthcheck.Port, v.healthcheck.Port)
+		started = run(StepStart, func() ([]string, error) {
+			return v.command(context.Background(), "docker", "run", "-d", "--name", v.name, "-p", port, v.name)
+		})
+	default:
+		return steps
+	}
+
+	if started && v.healthcheck != nil {
+		run(StepHealthcheck, v.waitHealthy)
+	}
+	return steps
+}
+
+// waitHealthy runs the healthcheck command until it passes or the start
+// timeout expires
+func (v *verifier) waitHealthy() ([]string, error) {
+	args := strings.Fields(v.healthcheck.Command)
+	if len(args) == 0 {
+		return nil, errors.New("healthcheck command is empty")
+	}
+	try := 5 * time.Second
+	if v.healthcheck.Timeout != "" {
+		if d, err := time.ParseDuration(v.healthcheck.Timeout); err == nil {
+			try = d
+		}
+	}
+
+	deadline := time.Now().Add(v.startTimeout)
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), try)
+		output, err := v.command(ctx, args...)
+		cancel()
+		if err == nil {
+			return nil, nil
+		}
+		if time.Now().After(deadline) {
+			return output, fmt.Errorf("%s did not pass within %s: %w", v.healthcheck.Command, v.startTimeout, err)
+		}
+		time.Sleep(2 * time.Second)
+	}
+}
+
+// cleanup stops what verify started, most recent first. Failures are logged
+// only: they do not change the outcome of the test.
+func (v *verifier) cleanup() {
+	for i := len(v.cleanups) - 1; i >= 0; i-- {
+		v.command(context.Background(), v.cleanups[i]...)
+	}
+}
+
+func (v *verifier) exists(name string) bool {
+	_, err := os.Stat(filepath.Join(v.dir, name))
+	return err == nil
+}
+
+func (v *verifier) composeFile() string {
+	for _, name := range composeFiles {
+		if v.exists(name) {
+			return name
+		}
+	}
+	return ""
+}
+
+// command runs a command in the project directory, logging its output. On
+// failure it returns the last lines of output.
+func (v *verifier) command(ctx context.Context, args ...string) ([]string, error) {
+	var output bytes.Buffer
+	out := io.Writer(&output)
+	if v.log != nil {
+		fmt.Fprintf(v.log, "==> %s\n", strings.Join(args, " "))
+		out = io.MultiWriter(&output, v.log)
+	}
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = v.dir
+	cmd.Stdout = out
+	cmd.Stderr = out
+	if err := cmd.Run(); err != nil {
+		return tail(output.String(), outputLines), err
+	}
+	return nil, nil
+}
+
+// tail returns the last n lines of s
+func tail(s string, n int) []string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	if len(lines) == 1 && lines[0] == "" {
+		return nil
+	}
+	return lines
+}
@@ -0,0 +1,284 @@
+package harness
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/renan-dev/devinit/internal/generator"
+)
+
+// writeTestTemplate writes the template test/basic to dir
+func writeTestTemplate(t *testing.T, dir, metadata string, files map[string]string) {
+	t.Helper()
+
+	templateDir := filepath.Join(dir, "test", "basic")
+	if err := os.MkdirAll(filepath.Join(templateDir, "files"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(templateDir, "template.yaml"), []byte(metadata), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(templateDir, "files", name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	templatesDir := t.TempDir()
+	writeTestTemplate(t, templatesDir, `version: "1.0.0"
+name: basic
+language: test
+framework: basic
+variables:
+  flavor:
+    type: choice
+    choices: [plain, fancy]
+    default: plain
+files:
+  - src: readme.txt
+    dest: README.txt
+  - src: fancy.txt
+    dest: FANCY.txt
+    conditions: ['{{ eq .Variables.flavor "fancy" }}']
+hooks:
+  post_generate:
+    - run: "false"
+tests:
+  - name: plain
+  - name: fancy
+    variables:
+      flavor: fancy
+  - name: spicy
+    variables:
+      flavor: spicy
+`, map[string]string{"readme.txt": "readme\n", "fancy.txt": "fancy\n"})
+
+	gen := generator.NewGenerator(templatesDir)
+	tmpl, err := gen.GetTemplate("test/basic")
+	if err != nil {
+		t.Fatalf("GetTemplate() unexpected error: %v", err)
+	}
+
+	want := []struct {
+		name   string
+		passed bool
+		files  int
+	}{
+		{name: "plain", passed: true, files: 1},
+		{name: "fancy", passed: true, files: 2},
+		{name: "spicy", passed: false},
+	}
+
+	// Cases running in parallel report in the same order
+	for _, jobs := range []int{1, 3} {
+		// Hooks only run with container verification, so the failing hook
+		// does not fail the cases
+		results := Run(gen, tmpl, Options{Jobs: jobs})
+
+		if len(results) != len(want) {
+			t.Fatalf("Run() with %d jobs returned %d results, want %d", jobs, len(results), len(want))
+		}
+		for i, w := range want {
+			got := results[i]
+			if got.Case != w.name || got.Passed != w.passed || got.Files != w.files {
+				t.Errorf("%d jobs: results[%d] = %+v, want case %s, passed %v, %d files", jobs, i, got, w.name, w.passed, w.files)
+			}
+		}
+
+		failed := results[2].Failed()
+		if failed == nil || failed.Step != StepGenerate || failed.Error == "" {
+			t.Errorf("%d jobs: Failed() = %+v, want the generate step with its error", jobs, failed)
+		}
+	}
+}
+
+func TestCases(t *testing.T) {
+	templatesDir := t.TempDir()
+	writeTestTemplate(t, templatesDir, `version: "1.0.0"
+name: basic
+language: test
+framework: basic
+files:
+  - src: readme.txt
+    dest: README.txt
+`, map[string]string{"readme.txt": "readme\n"})
+
+	tmpl, err := generator.NewGenerator(templatesDir).GetTemplate("test/basic")
+	if err != nil {
+		t.Fatalf("GetTemplate() unexpected error: %v", err)
+	}
+	if cases := Cases(tmpl); len(cases) != 1 || cases[0].Name != DefaultCase {
+		t.Errorf("Cases() = %+v, want a single %s case", cases, DefaultCase)
+	}
+}
+
+func TestExpandMatrix(t *testing.T) {
+	templatesDir := t.TempDir()
+	writeTestTemplate(t, templatesDir, `version: "1.0.0"
+name: basic
+language: test
+framework: basic
+files:
+  - src: readme.txt
+    dest: README.txt
+tests:
+  - name: options
+    variables:
+      flavor: plain
+      size: small
+    matrix:
+      size: [small, large]
+      IncludeDocker: [true, false]
+`, map[string]string{"readme.txt": "readme\n"})
+
+	tmpl, err := generator.NewGenerator(templatesDir).GetTemplate("test/basic")
+	if err != nil {
+		t.Fatalf("GetTemplate() unexpected error: %v", err)
+	}
+
+	cases := Cases(tmpl)
+	want := []string{
+		"options/IncludeDocker=true,size=small",
+		"options/IncludeDocker=true,size=large",
+		"options/IncludeDocker=false,size=small",
+		"options/IncludeDocker=false,size=large",
+	}
+	if len(cases) != len(want) {
+		t.Fatalf("Cases() returned %d cases, want %d", len(cases), len(want))
+	}
+	for i, name := range want {
+		if cases[i].Name != name {
+			t.Errorf("cases[%d].Name = %q, want %q", i, cases[i].Name, name)
+		}
+		if cases[i].Variables["flavor"] != "plain" {
+			t.Errorf("cases[%d] lost the case variables: %v", i, cases[i].Variables)
+		}
+	}
+	if cases[1].Variables["size"] != "large" || cases[1].Variables["IncludeDocker"] != true {
+		t.Errorf("cases[1].Variables = %v, want the matrix values", cases[1].Variables)
+	}
+}
+
+func TestRunConditions(t *testing.T) {
+	templatesDir := t.TempDir()
+	writeTestTemplate(t, templatesDir, `version: "1.0.0"
+name: basic
+language: test
+framework: basic
+variables:
+  docs:
+    type: boolean
+    default: false
+files:
+  - src: readme.txt
+    dest: README.txt
+  - src: docs.txt
+    dest: docs/index.txt
+    conditions: ["{{ .Variables.docs }}"]
+tests:
+  - name: docs
+    matrix:
+      docs: [true, false]
+    expect:
+      - path: README.txt
+      - path: docs/index.txt
+        when: "{{ .Variables.docs }}"
+  - name: wrong
+    expect:
+      - path: docs/index.txt
+  - name: typo
+    expect:
+      - path: README.txt
+        when: "{{ .Variables.dcos }}"
+`, map[string]string{"readme.txt": "readme\n", "docs.txt": "docs\n"})
+
+	gen := generator.NewGenerator(templatesDir)
+	tmpl, err := gen.GetTemplate("test/basic")
+	if err != nil {
+		t.Fatalf("GetTemplate() unexpected error: %v", err)
+	}
+
+	want := map[string]bool{
+		"docs/docs=true":  true,
+		"docs/docs=false": true,
+		"wrong":           false,
+		"typo":            false,
+	}
+	for _, result := range Run(gen, tmpl, Options{}) {
+		if result.Passed != want[result.Case] {
+			t.Errorf("case %s passed = %v, want %v (steps %+v)", result.Case, result.Passed, want[result.Case], result.Steps)
+		}
+		if failed := result.Failed(); failed != nil && failed.Step != StepConditions {
+			t.Errorf("case %s failed at %s, want %s", result.Case, failed.Step, StepConditions)
+		}
+	}
+}
+
+func TestCoverage(t *testing.T) {
+	templatesDir := t.TempDir()
+	writeTestTemplate(t, templatesDir, `version: "1.0.0"
+name: basic
+language: test
+framework: basic
+variables:
+  docs:
+    type: boolean
+    default: false
+  db:
+    type: choice
+    choices: [postgres, sqlite]
+    default: sqlite
+  tags:
+    type: string
+  title:
+    type: string
+files:
+  - src: readme.txt.tmpl
+    dest: README.txt
+  - src: docs.txt.tmpl
+    dest: docs/index.txt
+    conditions: ["{{ .Variables.docs }}"]
+tests:
+  - name: docs
+    matrix:
+      docs: [true, false]
+`, map[string]string{
+		"readme.txt.tmpl": "{{ if eq .Variables.db \"postgres\" }}postgres{{ else }}sqlite{{ end }}\n{{ range .Variables.tags }}{{ . }}{{ end }}\n",
+		"docs.txt.tmpl":   "{{ with .Variables.title }}{{ . }}{{ end }}\n",
+	})
+
+	gen := generator.NewGenerator(templatesDir)
+	tmpl, err := gen.GetTemplate("test/basic")
+	if err != nil {
+		t.Fatalf("GetTemplate() unexpected error: %v", err)
+	}
+	coverage, err := NewCoverage(tmpl)
+	if err != nil {
+		t.Fatalf("NewCoverage() unexpected error: %v", err)
+	}
+	for _, result := range Run(gen, tmpl, Options{Coverage: coverage, Jobs: 2}) {
+		if !result.Passed {
+			t.Fatalf("case %s failed: %+v", result.Case, result.Steps)
+		}
+	}
+
+	want := []CoverageItem{
+		{Kind: CoverageBranch, File: "readme.txt.tmpl", Line: 1, Text: `if eq .Variables.db "postgres"`, False: 2},
+		{Kind: CoverageBranch, File: "readme.txt.tmpl", Line: 2, Text: "range .Variables.tags", False: 2},
+		{Kind: CoverageCondition, File: "docs.txt.tmpl", Text: "{{ .Variables.docs }}", True: 1, False: 1},
+		{Kind: CoverageBranch, File: "docs.txt.tmpl", Line: 1, Text: "with .Variables.title", False: 1},
+	}
+	if got := coverage.Items(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Items() = %+v, want %+v", got, want)
+	}
+	if untested := coverage.Untested(); len(untested) != 3 {
+		t.Errorf("Untested() = %+v, want the three branches", untested)
+	}
+	if exercised, total := coverage.Summary(); exercised != 5 || total != 8 {
+		t.Errorf("Summary() = %d, %d, want 5, 8", exercised, total)
+	}
+}
@@ -0,0 +1,108 @@
+// Package i18n holds the user-facing messages of devinit in per-locale
+// catalogs and selects the locale they are printed in.
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// EnvLocale overrides the locale from the config file and the environment
+const EnvLocale = "DEVINIT_LOCALE"
+
+// DefaultLocale is used when no supported locale is configured. Its catalog
+// must contain every message.
+const DefaultLocale = "en"
+
+// catalogs maps a locale to its messages, keyed by message ID. Messages are
+// fmt format strings; errors may use %w.
+var catalogs = map[string]map[string]string{
+	"en": english,
+	"pt": portuguese,
+}
+
+// current is the active locale. It is set once at startup.
+var current = DefaultLocale
+
+// Locales returns the supported locales, sorted
+func Locales() []string {
+	locales := make([]string, 0, len(catalogs))
+	for locale := range catalogs {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Supported reports whether a locale has a catalog. Region and encoding
+// suffixes are ignored (pt_BR.UTF-8 is pt).
+func Supported(locale string) bool {
+	_, ok := catalogs[normalize(locale)]
+	return ok
+}
+
+// SetLocale makes locale the active one
+func SetLocale(locale string) error {
+	normalized := normalize(locale)
+	if _, ok := catalogs[normalized]; !ok {
+		return fmt.Errorf("unsupported locale %q (available: %s)", locale, strings.Join(Locales(), ", "))
+	}
+	current = normalized
+	return nil
+}
+
+// Locale returns the active locale
+func Locale() string {
+	return current
+}
+
+// Detect picks the locale to use: DEVINIT_LOCALE, then the configured locale,
+// then the standard LC_ALL, LC_MESSAGES and LANG variables. Unsupported
+// values are skipped; DefaultLocale is used if none is supported.
+func Detect(configured string) string {
+	candidates := []string{os.Getenv(EnvLocale), configured, os.Getenv("LC_ALL"), os.Getenv("LC_MESSAGES"), os.Getenv("LANG")}
+	for _, candidate := range candidates {
+		if locale := normalize(candidate); catalogs[locale] != nil {
+			return locale
+		}
+	}
+	return DefaultLocale
+}
+
+// T returns the message for key in the active locale, formatted with args.
+// Messages missing from the active catalog fall back to DefaultLocale, and
+// unknown keys are returned as is.
+func T(key string, args ...interface{}) string {
+	if len(args) == 0 {
+		return lookup(key)
+	}
+	return fmt.Sprintf(lookup(key), args...)
+}
+
+// Errorf is like fmt.Errorf with the message for key as the format, so
+// %w wraps errors in every locale
+func Errorf(key string, args ...interface{}) error {
+	return fmt.Errorf(lookup(key), args...)
+}
+
+// lookup returns the unformatted message for key
+func lookup(key string) string {
+	if message, ok := catalogs[current][key]; ok {
+		return message
+	}
+	if message, ok := catalogs[DefaultLocale][key]; ok {
+		return message
+	}
+	return key
+}
+
+// normalize reduces a locale such as pt_BR.UTF-8 or pt-BR to its language
+func normalize(locale string) string {
+	locale = strings.ToLower(strings.TrimSpace(locale))
+	if i := strings.IndexAny(locale, "_-.@"); i >= 0 {
+		locale = locale[:i]
+	}
+	return locale
+}
@@ -0,0 +1,97 @@
+package i18n
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		configured string
+		want       string
+	}{
+		{name: "nothing set", want: "en"},
+		{name: "LANG with region and encoding", env: map[string]string{"LANG": "pt_BR.UTF-8"}, want: "pt"},
+		{name: "LC_ALL beats LANG", env: map[string]string{"LC_ALL": "en_US.UTF-8", "LANG": "pt_BR.UTF-8"}, want: "en"},
+		{name: "config beats environment", env: map[string]string{"LANG": "en_US.UTF-8"}, configured: "pt", want: "pt"},
+		{name: "DEVINIT_LOCALE beats config", env: map[string]string{EnvLocale: "en"}, configured: "pt", want: "en"},
+		{name: "unsupported values are skipped", env: map[string]string{EnvLocale: "fr", "LANG": "pt-BR"}, configured: "C", want: "pt"},
+		{name: "nothing supported", env: map[string]string{"LANG": "C.UTF-8"}, want: "en"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{EnvLocale, "LC_ALL", "LC_MESSAGES", "LANG"} {
+				t.Setenv(key, tt.env[key])
+			}
+			if got := Detect(tt.configured); got != tt.want {
+				t.Errorf("Detect(%q) = %q, want %q", tt.configured, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestT(t *testing.T) {
+	defer SetLocale(DefaultLocale)
+
+	if err := SetLocale("pt_BR"); err != nil {
+		t.Fatalf("SetLocale() unexpected error: %v", err)
+	}
+	if got, want := T("summary.hooks", 2), "Hooks executados: 2"; got != want {
+		t.Errorf("T() = %q, want %q", got, want)
+	}
+	if got := T("no.such.key"); got != "no.such.key" {
+		t.Errorf("T() of unknown key = %q, want the key", got)
+	}
+
+	// Messages missing from a catalog fall back to English
+	english["test.only_english"] = "only %s"
+	defer delete(english, "test.only_english")
+	if got, want := T("test.only_english", "english"), "only english"; got != want {
+		t.Errorf("T() fallback = %q, want %q", got, want)
+	}
+
+	if err := SetLocale("fr"); err == nil {
+		t.Error("SetLocale(fr) expected error")
+	}
+	if Locale() != "pt" {
+		t.Errorf("Locale() after failed SetLocale = %q, want pt", Locale())
+	}
+}
+
+func TestErrorfWraps(t *testing.T) {
+	cause := errors.New("boom")
+	err := Errorf("new.failed", cause)
+	if !errors.Is(err, cause) {
+		t.Errorf("Errorf() = %v, does not wrap the cause", err)
+	}
+}
+
+var verbPattern = regexp.MustCompile(`%[-+# 0]*[0-9]*(\.[0-9]+)?[a-zA-Z%]`)
+
+// TestCatalogs checks every catalog translates every English message with
+// the same format verbs in the same order
+func TestCatalogs(t *testing.T) {
+	for locale, catalog := range catalogs {
+		for key, message := range english {
+			translated, ok := catalog[key]
+			if !ok {
+				t.Errorf("%s: missing message %q", locale, key)
+				continue
+			}
+			want := verbPattern.FindAllString(message, -1)
+			if got := verbPattern.FindAllString(translated, -1); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: message %q has verbs %v, want %v", locale, key, got, want)
+			}
+		}
+		for key := range catalog {
+			if _, ok := english[key]; !ok {
+				t.Errorf("%s: message %q is not in the English catalog", locale, key)
+			}
+		}
+	}
+}
@@ -0,0 +1,301 @@
+package i18n
+
+// english is the catalog of DefaultLocale
+var english = map[string]string{
+	// Shared
+	"error":      "Error: %v",
+	"error_code": "Error [%s]: %v",
+	"next_steps": "Next steps:",
+
+	// Usage errors
+	"usage.unknown_command":    "unknown command %q for %q",
+	"usage.did_you_mean":       "Did you mean this?",
+	"usage.hint":               "Run '%s --help' for usage.",
+	"usage.porcelain_conflict": "--porcelain and --output cannot be used together",
+	"usage.env_invalid":        "%s has an invalid value %q: %v",
+
+	// devinit validate
+	"validate.running":        "Validating project...",
+	"validate.drift_missing":  "missing: %s",
+	"validate.drift_modified": "modified: %s",
+	"validate.drift_found":    "%d generated files differ from %s %s",
+	"validate.no_drift":       "Project matches %s %s",
+
+	// devinit new
+	"new.name_required":          "project name is required",
+	"new.lang_required":          "--lang flag is required",
+	"new.framework_required":     "--framework flag is required",
+	"new.validate_conflict":      "--no-validate and --strict cannot be used together",
+	"new.plan_conflict":          "--plan is interactive and cannot be combined with --dry-run, --output json or --porcelain",
+	"new.tree_conflict":          "--tree shows the files of a dry run and needs --dry-run without --output json or --porcelain",
+	"new.invalid_from_project":   "invalid --from-project: %w",
+	"new.from_project_conflict":  "--from-project %s was generated from %s; leave out --lang and --framework or match them",
+	"new.from_project_version":   "warning: %s was generated from %s %s; generating with the current version %s (--framework %s@%s keeps the old one)",
+	"new.invalid_openapi":        "invalid --from-openapi: %v",
+	"new.invalid_proto":          "invalid --from-proto: %v",
+	"new.invalid_from_db":        "invalid --from-db: %v",
+	"new.from_db_failed":         "cannot read the schema of --from-db: %w",
+	"new.invalid_port":           "invalid --port %d (expected 1-65535)",
+	"new.invalid_docker_base":    "invalid --docker-base %q (expected one of: %s)",
+	"new.flag_unused":            "%s has no effect on %s, whose files do not use it",
+	"new.invalid_var":            "invalid --var %q (expected KEY=VALUE)",
+	"new.var_env_unset":          "--var %s: environment variable %s is not set",
+	"new.var_file_failed":        "--var %s: %w",
+	"new.invalid_retry_attempts": "invalid --retry-attempts %d (expected at least 1)",
+	"new.invalid_smoke_test":     "invalid --smoke-test %q: must be local or container",
+	"new.smoke_test_failed":      "smoke test failed: %s (log: %s)",
+	"new.invalid_scan":           "invalid --scan %q: must be project or image",
+	"new.invalid_scan_fail_on":   "invalid --scan-fail-on: %v",
+	"new.scan_failed":            "vulnerability scan found %d issue(s) of severity %s or higher (log: %s)",
+	"new.invalid_output":         "invalid output format %q (expected %s or %s)",
+	"new.creating":               "Creating %s/%s project: %s",
+	"new.dry_run":                "(dry run - no files will be created)",
+	"new.port_taken":             "Port %d is taken; using %d (set --port to override)",
+	"new.checking_requirements":  "Checking requirements...",
+	"new.requirements_unmet":     "requirements not met: %s (install them or use --no-validate)",
+	"new.failed":                 "failed to generate project: %w",
+	"new.record_failed":          "warning: failed to record generation: %v",
+	"new.repeating":              "Repeating: %s",
+	"new.repeat_last_none":       "no generation to repeat: --repeat-last repeats the last project devinit new generated",
+	"new.repeat_last_args":       "--repeat-last takes at most the new project name",
+	"new.repeat_last_invalid":    "invalid last generation in %s: %v",
+	"new.record_last_failed":     "warning: cannot record the generation for --repeat-last: %v",
+	"new.invalid_owner":          "invalid --chown: %v",
+	"new.owner_failed":           "failed to give the project to %s: %v",
+
+	// devinit new --plan
+	"plan.files":       "Files to generate: %d (%s)",
+	"plan.groups":      "Optional file groups:",
+	"plan.no_groups":   "The template has no optional file groups.",
+	"plan.group_files": "%d file(s)",
+	"plan.prompt":      "Toggle groups by number (e.g. 1 3), g to generate, q to cancel: ",
+	"plan.invalid":     "invalid choice %q",
+	"plan.choose":      "Value for %s: ",
+	"plan.choose_from": "Value for %s (%s): ",
+	"plan.cancelled":   "Generation cancelled; no files were written.",
+
+	// devinit browse
+	"browse.search":       "Search: %s",
+	"browse.keys":         " ↑/↓ select · enter create a project · pgup/pgdn scroll · ctrl-u clear · esc quit",
+	"browse.not_terminal": "browse needs an interactive terminal; use 'devinit templates search' in scripts",
+	"browse.template":     "Creating a project from %s (Ctrl-D to cancel)",
+	"browse.name":         "Project name: ",
+	"browse.profile":      "Profile (%s; Enter for none): ",
+	"browse.variable":     "%s [%s]: ",
+	"browse.required":     "%s is required",
+	"browse.invalid":      "invalid value %q: %s",
+	"browse.running":      "Running: %s",
+
+	// Generation summary
+	"summary.created":       "Created: %s",
+	"summary.updated":       "Updated: %s (%s)",
+	"summary.smoke_passed":  "Smoke test passed: %s (%s)",
+	"summary.smoke_failed":  "Smoke test failed: %s",
+	"summary.smoke_skipped": "Smoke test skipped: the template has no test command for these options",
+	"summary.would_copy":    "Would copy: %s -> %s",
+	"summary.would_render":  "Would render: %s -> %s",
+	"summary.skipped":       "Skipped: %s (conditions not met)",
+	"tree.included":         "if %s",
+	"tree.skipped":          "skipped: %s not met",
+	"tree.override":         "project override",
+	"summary.title":         "Summary:",
+	"summary.template":      "Template: %s@%s",
+	"summary.files_created": "Files created: %d (%s)",
+	"summary.files_planned": "Files planned: %d (%s)",
+	"summary.overrides":     "Project overrides: %s",
+	"summary.hooks":         "Hooks executed: %d",
+	"summary.hook_attempts": "%d attempts",
+	"summary.hook_when":     "skipped: when %s is false",
+	"summary.hook_blocked":  "skipped: %s failed",
+	"summary.hook_log":      "Hook log: %s",
+	"summary.sbom":          "Bill of materials: %d dependencies in %s",
+	"summary.workspace":     "Wired into workspace %s: %s",
+	"summary.scan":          "Vulnerability scan: %d finding(s) %s, failing at %s",
+	"summary.scan_clean":    "Vulnerability scan: no findings",
+	"summary.scan_skipped":  "%s skipped: %s",
+	"summary.scan_error":    "%s failed: %s",
+	"summary.scan_more":     "... and %d more (see --output json)",
+	"summary.scan_fixed":    "fixed in %s",
+
+	"summary.port":       "Port: %d (health check: %s)",
+	"summary.duration":   "Duration: %s",
+	"summary.warnings":   "Warnings (%d):",
+	"summary.success":    "✓ Project created successfully at: ./%s",
+	"summary.deprecated": "DEPRECATED: %s",
+
+	// info
+	"info.template":     "Template: %s@%s",
+	"info.generated":    "Generated by devinit %s on %s/%s by %s in %d",
+	"info.provenance":   "Generated files carry provenance headers",
+	"info.sbom":         "Bill of materials: %s",
+	"info.overrides":    "Files from project overrides (.devinit/templates):",
+	"info.variables":    "Variables:",
+	"info.sbom_scanned": "No %s in the project; scanning its dependencies now",
+
+	// devinit doctor
+	"doctor.checking":        "Checking system requirements...",
+	"doctor.no_requirements": "(no system requirements)",
+	"doctor.when":            "when %s",
+	"doctor.required_at":     "required at %s validation",
+	"doctor.optional_at":     "optional at %s validation",
+	"doctor.cached":          "cached %s ago",
+	"doctor.fix":             "Fix: %s",
+	"doctor.failed":          "%d requirement(s) not met",
+	"doctor.ok":              "All required dependencies are installed!",
+
+	// devinit templates
+	"templates.dir_failed":                "cannot use the built-in templates, falling back to ./templates: %v",
+	"templates.none":                      "No templates found",
+	"templates.no_matches":                "No matching templates",
+	"templates.query_required":            "a query or at least one --tag is required",
+	"templates.table_header":              "NAME\tTYPE\tVERSION\tSTATUS\tTAGS\tDESCRIPTION",
+	"templates.status_active":             "active",
+	"templates.status_deprecated":         "deprecated",
+	"templates.status_use":                "use %s",
+	"templates.name":                      "Name: %s",
+	"templates.version":                   "Version: %s",
+	"templates.versions":                  "Versions: %s",
+	"templates.description":               "Description: %s",
+	"templates.language":                  "Language: %s",
+	"templates.framework":                 "Framework: %s",
+	"templates.type":                      "Type: %s",
+	"templates.tags":                      "Tags: %s",
+	"templates.needs":                     "Needs: %s",
+	"templates.deprecated":                "Deprecated: %s",
+	"templates.variables":                 "Variables:",
+	"templates.profiles":                  "Profiles:",
+	"templates.validating":                "Validating templates...",
+	"templates.validate_failed":           "%d template(s) failed validation",
+	"templates.all_valid":                 "All templates valid!",
+	"templates.conflict":                  "%s:%d: would be rendered as a template action, use a raw block or the raw or literal helper: %s",
+	"templates.indexed":                   "Indexed %d templates in %s",
+	"templates.imported":                  "Imported %s (%d files, %d variables) into %s",
+	"templates.import_warnings":           "Warnings:",
+	"templates.import_exists":             "%w; use --force to replace it",
+	"templates.import_failed":             "cannot import the template: %w",
+	"templates.import_versions":           "Version %s, with older versions %s",
+	"templates.pushed":                    "Pushed %s %s to %s (%s)",
+	"templates.push_failed":               "cannot push the template: %w",
+	"templates.push_digest":               "cannot push to %s: push to a tag, the digest follows from the content",
+	"templates.logout_none":               "No token stored for %s",
+	"templates.logged_out":                "Removed the token of %s",
+	"templates.logged_in":                 "Stored the token of %s in the %s",
+	"templates.login_failed":              "cannot access the keychain: %w",
+	"templates.login_no_keychain":         "cannot store the token: %w; set $%s or add the host to ~/.netrc instead",
+	"templates.login_empty":               "no token on standard input",
+	"templates.login_host":                "invalid host %q",
+	"templates.login_prompt":              "Token for %s: ",
+	"templates.update_none":               "No imported templates to update",
+	"templates.update_available":          "%s has an update: %s → %s",
+	"templates.update_failed":             "cannot check %s for updates: %v",
+	"templates.update_incomplete":         "%d templates could not be checked for updates",
+	"templates.update_not_imported":       "%s was not imported from a remote source",
+	"templates.update_notice_one":         "1 template has updates (run devinit templates update)",
+	"templates.update_notice":             "%d templates have updates (run devinit templates update)",
+	"templates.up_to_date":                "%s is up to date",
+	"templates.testing":                   "Testing templates...",
+	"templates.test_files":                "(%d files)",
+	"templates.tests_failed":              "%d of %d template test case(s) failed",
+	"templates.tests_passed":              "All %d template test case(s) passed!",
+	"templates.coverage":                  "Coverage of %s: %d of %d condition and branch outcomes exercised",
+	"templates.coverage_condition_unused": "never evaluated",
+	"templates.coverage_never_true":       "never true",
+	"templates.coverage_never_false":      "never false",
+	"templates.coverage_branch_unused":    "file never rendered",
+	"templates.coverage_never_taken":      "body never ran",
+	"templates.coverage_never_skipped":    "else never ran",
+	"templates.render_failed":             "failed to render %s@%s: %w",
+	"templates.no_differences":            "No differences between %s@%s and %s@%s",
+
+	// Generator
+	"generator.load_template":         "failed to load template: %w",
+	"generator.rejected":              "%s (rejected by policy)",
+	"generator.create_project_dir":    "failed to create project directory: %w",
+	"generator.generate_file":         "failed to generate file %s: %w",
+	"generator.create_metadata":       "failed to create metadata file: %w",
+	"generator.render_healthcheck":    "failed to render healthcheck: %w",
+	"generator.render_next_steps":     "failed to render next steps: %w",
+	"generator.write_file":            "failed to write file: %w",
+	"generator.read_file":             "failed to read file: %w",
+	"generator.read_project_file":     "failed to read %s: %w",
+	"generator.lock_mismatch":         "template %s %s changed since %s pinned it (%s, now %s); pass --update to use it and update the lock",
+	"generator.render_file":           "failed to render file %s: %w",
+	"generator.render_destination":    "failed to render destination %s: %w",
+	"generator.render_default":        "failed to render the default of variable %s: %w",
+	"generator.default_type":          "the default of variable %s is %q, which is not a valid %s",
+	"generator.undeclared_variables":  "template %s references variables it does not declare: %s",
+	"generator.marker_condition":      "line %d: devinit:if needs a condition",
+	"generator.marker_unexpected":     "line %d: unexpected devinit:%s",
+	"generator.marker_unclosed":       "line %d: devinit:if is not closed",
+	"generator.patch_failed":          "failed to apply patch: %w",
+	"generator.merge_failed":          "failed to merge: %w",
+	"generator.duplicate_destination": "%s is generated by both %s and %s: set override: true on the later file entry to replace the earlier file, or a mode to combine them",
+	"generator.glob_source":           "%s (matched by %s)",
+	"generator.condition_not_bool":    "%s rendered %q, not true or false",
+	"generator.condition_invalid":     "condition %s: %w",
+	"generator.smoke_render":          "failed to render smoke test setup step %d: %w",
+	"generator.smoke_no_image":        "template %s declares no smoke test image for --smoke-test=container",
+	"generator.smoke_copy":            "failed to copy the project for the smoke test: %w",
+	"generator.smoke_timeout":         "timed out after %s",
+	"generator.smoke_step_failed":     "%s failed: %v",
+	"generator.smoke_failed":          "smoke test: %w",
+	"generator.sbom_failed":           "failed to write the bill of materials: %v",
+	"generator.sbom_skipped":          "bill of materials: skipped %s",
+	"generator.workspace_failed":      "failed to wire the service into the workspace: %v",
+	"generator.scan_not_installed":    "%s is not installed",
+	"generator.scan_timeout":          "timed out after %s",
+	"generator.scan_error":            "vulnerability scan: %s failed: %s",
+	"generator.scan_unavailable":      "vulnerability scan: no scanner could run; install pip-audit, npm, govulncheck or trivy",
+	"generator.no_profiles":           "template %s/%s does not define any profiles",
+	"generator.unknown_profile":       "unknown profile %q for template %s/%s (available: %s)",
+	"generator.hook_run_failed":       "failed to run %s hook: %w",
+	"generator.hook_failed":           "%s hook %q failed: %s",
+	"generator.hook_log":              "failed to create hook log: %w",
+	"generator.hook_output":           "Last lines of output (full log: %s):",
+	"generator.hook_empty":            "hook %s rendered to an empty command",
+	"generator.hook_retry":            "%s: %q failed (attempt %d/%d): %v; retrying in %s",
+
+	// Project name and variable validation
+	"validate.name_empty":       "project name cannot be empty",
+	"validate.name_dots":        "invalid project name: '.' and '..' are not allowed",
+	"validate.name_separators":  "invalid project name: path separators are not allowed",
+	"validate.name_format":      "invalid project name: must start with lowercase letter and contain only lowercase letters, numbers, and hyphens",
+	"validate.name_exists":      "directory '%s' already exists",
+	"validate.var_choice":       "invalid value %q for variable %s: must be one of %s",
+	"validate.var_pattern":      "invalid pattern for variable %s: %w",
+	"validate.var_pattern_fail": "invalid value %q for variable %s: must match %s",
+	"validate.var_type":         "invalid value %q for variable %s: must be of type %s",
+
+	// devinit cache
+	"cache.table_header": "NAME\tPATH\tSIZE\tFILES",
+	"cache.disabled":     "(disabled)",
+	"cache.unknown":      "unknown cache %q (use %s, %s, %s or %s)",
+	"cache.clean_failed": "failed to clean %s: %w",
+	"cache.cleaned":      "Cleaned %s: %s",
+	"cache.freed":        "Freed %s",
+
+	// devinit hooks
+	"hooks.table_header":   "NAME\tSTAGE\tIDEMPOTENT\tWHEN\tCOMMAND",
+	"hooks.none":           "The project in %s has no hooks",
+	"hooks.unknown":        "unknown hook %q (see devinit hooks list)",
+	"hooks.not_idempotent": "hook %s is not marked idempotent and may not be safe to run again; use --force to run it anyway",
+	"hooks.nothing_to_run": "name the hooks to run, or use --idempotent",
+
+	// devinit render
+	"render.dir_and_file":   "--template-dir renders a directory; pass either it or a file",
+	"render.out_required":   "--template-dir needs --out, the directory to render into",
+	"render.invalid_engine": "invalid --engine %q (use %s or %s)",
+	"render.invalid_delims": "invalid --delims %q: need a left and a right delimiter, e.g. '[[,]]', and the gotemplate engine",
+	"render.read_failed":    "failed to read the template: %w",
+	"render.failed":         "failed to render: %w",
+	"render.written":        "Rendered %d files to %s",
+
+	// devinit gen-docs
+	"gendocs.dir_required":     "pass --man, --markdown or both with the directory to write to",
+	"gendocs.failed":           "failed to generate documentation: %w",
+	"gendocs.man_written":      "Wrote man pages to %s",
+	"gendocs.markdown_written": "Wrote Markdown pages to %s",
+
+	"pprof.cpu_failed":  "failed to write CPU profile: %w",
+	"pprof.heap_failed": "failed to write heap profile: %v",
+}
@@ -0,0 +1,301 @@
+package i18n
+
+// portuguese is the Brazilian Portuguese catalog
+var portuguese = map[string]string{
+	// Shared
+	"error":      "Erro: %v",
+	"error_code": "Erro [%s]: %v",
+	"next_steps": "Próximos passos:",
+
+	// Usage errors
+	"usage.unknown_command":    "comando %q desconhecido para %q",
+	"usage.did_you_mean":       "Você quis dizer?",
+	"usage.hint":               "Execute '%s --help' para ver o uso.",
+	"usage.porcelain_conflict": "--porcelain e --output não podem ser usados juntos",
+	"usage.env_invalid":        "%s tem um valor inválido %q: %v",
+
+	// devinit validate
+	"validate.running":        "Validando projeto...",
+	"validate.drift_missing":  "ausente: %s",
+	"validate.drift_modified": "modificado: %s",
+	"validate.drift_found":    "%d arquivos gerados diferem de %s %s",
+	"validate.no_drift":       "O projeto corresponde a %s %s",
+
+	// devinit new
+	"new.name_required":          "o nome do projeto é obrigatório",
+	"new.lang_required":          "a flag --lang é obrigatória",
+	"new.framework_required":     "a flag --framework é obrigatória",
+	"new.validate_conflict":      "--no-validate e --strict não podem ser usadas juntas",
+	"new.plan_conflict":          "--plan é interativo e não pode ser combinado com --dry-run, --output json ou --porcelain",
+	"new.tree_conflict":          "--tree mostra os arquivos de uma simulação e precisa de --dry-run sem --output json ou --porcelain",
+	"new.invalid_from_project":   "--from-project inválido: %w",
+	"new.from_project_conflict":  "--from-project %s foi gerado a partir de %s; omita --lang e --framework ou use os mesmos valores",
+	"new.from_project_version":   "aviso: %s foi gerado a partir de %s %s; gerando com a versão atual %s (--framework %s@%s mantém a antiga)",
+	"new.invalid_openapi":        "--from-openapi inválido: %v",
+	"new.invalid_proto":          "--from-proto inválido: %v",
+	"new.invalid_from_db":        "--from-db inválido: %v",
+	"new.from_db_failed":         "não foi possível ler o schema de --from-db: %w",
+	"new.invalid_port":           "--port %d inválida (esperado 1-65535)",
+	"new.invalid_docker_base":    "--docker-base %q inválida (esperado um de: %s)",
+	"new.flag_unused":            "%s não tem efeito em %s, cujos arquivos não a usam",
+	"new.invalid_var":            "--var %q inválida (esperado CHAVE=VALOR)",
+	"new.var_env_unset":          "--var %s: a variável de ambiente %s não está definida",
+	"new.var_file_failed":        "--var %s: %w",
+	"new.invalid_retry_attempts": "--retry-attempts %d inválida (esperado pelo menos 1)",
+	"new.invalid_smoke_test":     "--smoke-test inválido %q: deve ser local ou container",
+	"new.smoke_test_failed":      "teste de fumaça falhou: %s (log: %s)",
+	"new.invalid_scan":           "--scan inválido %q: deve ser project ou image",
+	"new.invalid_scan_fail_on":   "--scan-fail-on inválido: %v",
+	"new.scan_failed":            "a análise de vulnerabilidades encontrou %d problema(s) de severidade %s ou maior (log: %s)",
+	"new.invalid_output":         "formato de saída %q inválido (esperado %s ou %s)",
+	"new.creating":               "Criando projeto %s/%s: %s",
+	"new.dry_run":                "(simulação - nenhum arquivo será criado)",
+	"new.port_taken":             "A porta %d está ocupada; usando %d (use --port para escolher outra)",
+	"new.checking_requirements":  "Verificando requisitos...",
+	"new.requirements_unmet":     "requisitos não atendidos: %s (instale-os ou use --no-validate)",
+	"new.failed":                 "falha ao gerar o projeto: %w",
+	"new.record_failed":          "aviso: falha ao registrar a geração: %v",
+	"new.repeating":              "Repetindo: %s",
+	"new.repeat_last_none":       "nenhuma geração para repetir: --repeat-last repete o último projeto gerado pelo devinit new",
+	"new.repeat_last_args":       "--repeat-last aceita no máximo o nome do novo projeto",
+	"new.repeat_last_invalid":    "última geração inválida em %s: %v",
+	"new.record_last_failed":     "aviso: não foi possível registrar a geração para --repeat-last: %v",
+	"new.invalid_owner":          "--chown inválido: %v",
+	"new.owner_failed":           "falha ao passar o projeto para %s: %v",
+
+	// devinit new --plan
+	"plan.files":       "Arquivos a gerar: %d (%s)",
+	"plan.groups":      "Grupos opcionais de arquivos:",
+	"plan.no_groups":   "O template não tem grupos opcionais de arquivos.",
+	"plan.group_files": "%d arquivo(s)",
+	"plan.prompt":      "Alterne grupos pelo número (ex.: 1 3), g para gerar, q para cancelar: ",
+	"plan.invalid":     "escolha inválida %q",
+	"plan.choose":      "Valor para %s: ",
+	"plan.choose_from": "Valor para %s (%s): ",
+	"plan.cancelled":   "Geração cancelada; nenhum arquivo foi gravado.",
+
+	// devinit browse
+	"browse.search":       "Buscar: %s",
+	"browse.keys":         " ↑/↓ selecionar · enter criar projeto · pgup/pgdn rolar · ctrl-u limpar · esc sair",
+	"browse.not_terminal": "browse precisa de um terminal interativo; use 'devinit templates search' em scripts",
+	"browse.template":     "Criando um projeto a partir de %s (Ctrl-D para cancelar)",
+	"browse.name":         "Nome do projeto: ",
+	"browse.profile":      "Perfil (%s; Enter para nenhum): ",
+	"browse.variable":     "%s [%s]: ",
+	"browse.required":     "%s é obrigatório",
+	"browse.invalid":      "valor inválido %q: %s",
+	"browse.running":      "Executando: %s",
+
+	// Generation summary
+	"summary.created":       "Criado: %s",
+	"summary.updated":       "Atualizado: %s (%s)",
+	"summary.smoke_passed":  "Teste de fumaça aprovado: %s (%s)",
+	"summary.smoke_failed":  "Teste de fumaça falhou: %s",
+	"summary.smoke_skipped": "Teste de fumaça ignorado: o template não tem comando de teste para estas opções",
+	"summary.would_copy":    "Copiaria: %s -> %s",
+	"summary.would_render":  "Renderizaria: %s -> %s",
+	"summary.skipped":       "Ignorado: %s (condições não atendidas)",
+	"tree.included":         "se %s",
+	"tree.skipped":          "ignorado: %s não atendido",
+	"tree.override":         "sobrescrito pelo projeto",
+	"summary.title":         "Resumo:",
+	"summary.template":      "Template: %s@%s",
+	"summary.files_created": "Arquivos criados: %d (%s)",
+	"summary.files_planned": "Arquivos planejados: %d (%s)",
+	"summary.overrides":     "Sobrescritos pelo projeto: %s",
+	"summary.hooks":         "Hooks executados: %d",
+	"summary.hook_attempts": "%d tentativas",
+	"summary.hook_when":     "ignorado: %s é falso",
+	"summary.hook_blocked":  "ignorado: %s falhou",
+	"summary.hook_log":      "Log dos hooks: %s",
+	"summary.sbom":          "Lista de materiais (SBOM): %d dependências em %s",
+	"summary.workspace":     "Integrado ao workspace %s: %s",
+	"summary.scan":          "Análise de vulnerabilidades: %d achado(s) %s, falhando a partir de %s",
+	"summary.scan_clean":    "Análise de vulnerabilidades: nenhum achado",
+	"summary.scan_skipped":  "%s ignorado: %s",
+	"summary.scan_error":    "%s falhou: %s",
+	"summary.scan_more":     "... e mais %d (veja --output json)",
+	"summary.scan_fixed":    "corrigido em %s",
+
+	"summary.port":       "Porta: %d (health check: %s)",
+	"summary.duration":   "Duração: %s",
+	"summary.warnings":   "Avisos (%d):",
+	"summary.success":    "✓ Projeto criado com sucesso em: ./%s",
+	"summary.deprecated": "DESCONTINUADO: %s",
+
+	// info
+	"info.template":     "Template: %s@%s",
+	"info.generated":    "Gerado pelo devinit %s em %s/%s por %s em %d",
+	"info.provenance":   "Os arquivos gerados têm cabeçalhos de proveniência",
+	"info.sbom":         "Lista de materiais (SBOM): %s",
+	"info.overrides":    "Arquivos sobrescritos pelo projeto (.devinit/templates):",
+	"info.variables":    "Variáveis:",
+	"info.sbom_scanned": "Sem %s no projeto; analisando as dependências agora",
+
+	// devinit doctor
+	"doctor.checking":        "Verificando requisitos do sistema...",
+	"doctor.no_requirements": "(sem requisitos de sistema)",
+	"doctor.when":            "quando %s",
+	"doctor.required_at":     "obrigatório na validação %s",
+	"doctor.optional_at":     "opcional na validação %s",
+	"doctor.cached":          "em cache há %s",
+	"doctor.fix":             "Correção: %s",
+	"doctor.failed":          "%d requisito(s) não atendido(s)",
+	"doctor.ok":              "Todas as dependências obrigatórias estão instaladas!",
+
+	// devinit templates
+	"templates.dir_failed":                "não é possível usar os templates embutidos, usando ./templates: %v",
+	"templates.none":                      "Nenhum template encontrado",
+	"templates.no_matches":                "Nenhum template corresponde à busca",
+	"templates.query_required":            "informe uma busca ou pelo menos uma --tag",
+	"templates.table_header":              "NOME\tTIPO\tVERSÃO\tSTATUS\tTAGS\tDESCRIÇÃO",
+	"templates.status_active":             "ativo",
+	"templates.status_deprecated":         "descontinuado",
+	"templates.status_use":                "use %s",
+	"templates.name":                      "Nome: %s",
+	"templates.version":                   "Versão: %s",
+	"templates.versions":                  "Versões: %s",
+	"templates.description":               "Descrição: %s",
+	"templates.language":                  "Linguagem: %s",
+	"templates.framework":                 "Framework: %s",
+	"templates.type":                      "Tipo: %s",
+	"templates.tags":                      "Tags: %s",
+	"templates.needs":                     "Requer: %s",
+	"templates.deprecated":                "Descontinuado: %s",
+	"templates.variables":                 "Variáveis:",
+	"templates.profiles":                  "Perfis:",
+	"templates.validating":                "Validando templates...",
+	"templates.validate_failed":           "%d template(s) com falha na validação",
+	"templates.all_valid":                 "Todos os templates são válidos!",
+	"templates.conflict":                  "%s:%d: seria renderizado como ação de template, use um bloco raw ou o helper raw ou literal: %s",
+	"templates.indexed":                   "%d templates indexados em %s",
+	"templates.imported":                  "%s importado (%d arquivos, %d variáveis) em %s",
+	"templates.import_warnings":           "Avisos:",
+	"templates.import_exists":             "%w; use --force para substituí-lo",
+	"templates.import_failed":             "não foi possível importar o template: %w",
+	"templates.import_versions":           "Versão %s, com as versões anteriores %s",
+	"templates.pushed":                    "%s %s enviado para %s (%s)",
+	"templates.push_failed":               "não foi possível enviar o template: %w",
+	"templates.push_digest":               "não é possível enviar para %s: envie para uma tag, o digest decorre do conteúdo",
+	"templates.logout_none":               "Nenhum token armazenado para %s",
+	"templates.logged_out":                "Token de %s removido",
+	"templates.logged_in":                 "Token de %s armazenado em %s",
+	"templates.login_failed":              "não foi possível acessar o chaveiro: %w",
+	"templates.login_no_keychain":         "não foi possível armazenar o token: %w; defina $%s ou adicione o host ao ~/.netrc",
+	"templates.login_empty":               "nenhum token na entrada padrão",
+	"templates.login_host":                "host inválido %q",
+	"templates.login_prompt":              "Token para %s: ",
+	"templates.update_none":               "Nenhum template importado para atualizar",
+	"templates.update_available":          "%s tem uma atualização: %s → %s",
+	"templates.update_failed":             "não foi possível verificar atualizações de %s: %v",
+	"templates.update_incomplete":         "%d templates não puderam ser verificados",
+	"templates.update_not_imported":       "%s não foi importado de uma origem remota",
+	"templates.update_notice_one":         "1 template tem atualizações (execute devinit templates update)",
+	"templates.update_notice":             "%d templates têm atualizações (execute devinit templates update)",
+	"templates.up_to_date":                "%s está atualizado",
+	"templates.testing":                   "Testando templates...",
+	"templates.test_files":                "(%d arquivos)",
+	"templates.tests_failed":              "%d de %d caso(s) de teste de template falharam",
+	"templates.tests_passed":              "Todos os %d caso(s) de teste de template passaram!",
+	"templates.coverage":                  "Cobertura de %s: %d de %d resultados de condições e ramos exercitados",
+	"templates.coverage_condition_unused": "nunca avaliada",
+	"templates.coverage_never_true":       "nunca verdadeira",
+	"templates.coverage_never_false":      "nunca falsa",
+	"templates.coverage_branch_unused":    "arquivo nunca renderizado",
+	"templates.coverage_never_taken":      "corpo nunca executado",
+	"templates.coverage_never_skipped":    "else nunca executado",
+	"templates.render_failed":             "falha ao renderizar %s@%s: %w",
+	"templates.no_differences":            "Nenhuma diferença entre %s@%s e %s@%s",
+
+	// Generator
+	"generator.load_template":         "falha ao carregar o template: %w",
+	"generator.rejected":              "%s (rejeitado pela política)",
+	"generator.create_project_dir":    "falha ao criar o diretório do projeto: %w",
+	"generator.generate_file":         "falha ao gerar o arquivo %s: %w",
+	"generator.create_metadata":       "falha ao criar o arquivo de metadados: %w",
+	"generator.render_healthcheck":    "falha ao renderizar o health check: %w",
+	"generator.render_next_steps":     "falha ao renderizar os próximos passos: %w",
+	"generator.write_file":            "falha ao gravar o arquivo: %w",
+	"generator.read_file":             "falha ao ler o arquivo: %w",
+	"generator.read_project_file":     "falha ao ler %s: %w",
+	"generator.lock_mismatch":         "o template %s %s mudou desde que %s o fixou (%s, agora %s); use --update para usá-lo e atualizar o lock",
+	"generator.render_file":           "falha ao renderizar o arquivo %s: %w",
+	"generator.render_destination":    "falha ao renderizar o destino %s: %w",
+	"generator.render_default":        "falha ao renderizar o valor padrão da variável %s: %w",
+	"generator.default_type":          "o valor padrão da variável %s é %q, que não é um %s válido",
+	"generator.undeclared_variables":  "o template %s referencia variáveis que não declara: %s",
+	"generator.marker_condition":      "linha %d: devinit:if precisa de uma condição",
+	"generator.marker_unexpected":     "linha %d: devinit:%s inesperado",
+	"generator.marker_unclosed":       "linha %d: devinit:if não foi fechado",
+	"generator.patch_failed":          "falha ao aplicar o patch: %w",
+	"generator.merge_failed":          "falha ao mesclar: %w",
+	"generator.duplicate_destination": "%s é gerado por %s e por %s: defina override: true na entrada de arquivo posterior para substituir o arquivo anterior, ou um mode para combiná-los",
+	"generator.glob_source":           "%s (casado por %s)",
+	"generator.condition_not_bool":    "%s resultou em %q, não true ou false",
+	"generator.condition_invalid":     "condição %s: %w",
+	"generator.smoke_render":          "falha ao renderizar o passo %d de preparação do teste de fumaça: %w",
+	"generator.smoke_no_image":        "o template %s não declara uma imagem de teste de fumaça para --smoke-test=container",
+	"generator.smoke_copy":            "falha ao copiar o projeto para o teste de fumaça: %w",
+	"generator.smoke_timeout":         "tempo esgotado após %s",
+	"generator.smoke_step_failed":     "%s falhou: %v",
+	"generator.smoke_failed":          "teste de fumaça: %w",
+	"generator.sbom_failed":           "falha ao gravar a lista de materiais (SBOM): %v",
+	"generator.sbom_skipped":          "lista de materiais (SBOM): %s ignorado",
+	"generator.workspace_failed":      "falha ao integrar o serviço ao workspace: %v",
+	"generator.scan_not_installed":    "%s não está instalado",
+	"generator.scan_timeout":          "tempo esgotado após %s",
+	"generator.scan_error":            "análise de vulnerabilidades: %s falhou: %s",
+	"generator.scan_unavailable":      "análise de vulnerabilidades: nenhum scanner pôde ser executado; instale pip-audit, npm, govulncheck ou trivy",
+	"generator.no_profiles":           "o template %s/%s não define perfis",
+	"generator.unknown_profile":       "perfil %q desconhecido para o template %s/%s (disponíveis: %s)",
+	"generator.hook_run_failed":       "falha ao executar o hook %s: %w",
+	"generator.hook_failed":           "o hook %s %q falhou: %s",
+	"generator.hook_log":              "falha ao criar o log dos hooks: %w",
+	"generator.hook_output":           "Últimas linhas da saída (log completo: %s):",
+	"generator.hook_empty":            "o hook %s foi renderizado como um comando vazio",
+	"generator.hook_retry":            "%s: %q falhou (tentativa %d/%d): %v; nova tentativa em %s",
+
+	// Project name and variable validation
+	"validate.name_empty":       "o nome do projeto não pode ser vazio",
+	"validate.name_dots":        "nome de projeto inválido: '.' e '..' não são permitidos",
+	"validate.name_separators":  "nome de projeto inválido: separadores de caminho não são permitidos",
+	"validate.name_format":      "nome de projeto inválido: deve começar com letra minúscula e conter apenas letras minúsculas, números e hífens",
+	"validate.name_exists":      "o diretório '%s' já existe",
+	"validate.var_choice":       "valor %q inválido para a variável %s: deve ser um de %s",
+	"validate.var_pattern":      "padrão inválido para a variável %s: %w",
+	"validate.var_pattern_fail": "valor %q inválido para a variável %s: deve corresponder a %s",
+	"validate.var_type":         "valor %q inválido para a variável %s: deve ser do tipo %s",
+
+	// devinit cache
+	"cache.table_header": "NOME\tCAMINHO\tTAMANHO\tARQUIVOS",
+	"cache.disabled":     "(desativado)",
+	"cache.unknown":      "cache %q desconhecido (use %s, %s, %s ou %s)",
+	"cache.clean_failed": "falha ao limpar %s: %w",
+	"cache.cleaned":      "%s limpo: %s",
+	"cache.freed":        "%s liberados",
+
+	// devinit hooks
+	"hooks.table_header":   "NOME\tETAPA\tIDEMPOTENTE\tQUANDO\tCOMANDO",
+	"hooks.none":           "O projeto em %s não tem hooks",
+	"hooks.unknown":        "hook desconhecido %q (veja devinit hooks list)",
+	"hooks.not_idempotent": "o hook %s não está marcado como idempotente e pode não ser seguro executá-lo de novo; use --force para executá-lo mesmo assim",
+	"hooks.nothing_to_run": "informe os hooks a executar ou use --idempotent",
+
+	// devinit render
+	"render.dir_and_file":   "--template-dir renderiza um diretório; informe ele ou um arquivo",
+	"render.out_required":   "--template-dir exige --out, o diretório onde renderizar",
+	"render.invalid_engine": "--engine inválido %q (use %s ou %s)",
+	"render.invalid_delims": "--delims inválido %q: informe um delimitador esquerdo e um direito, ex. '[[,]]', e o engine gotemplate",
+	"render.read_failed":    "falha ao ler o template: %w",
+	"render.failed":         "falha ao renderizar: %w",
+	"render.written":        "%d arquivos renderizados em %s",
+
+	// devinit gen-docs
+	"gendocs.dir_required":     "informe --man, --markdown ou ambos com o diretório de destino",
+	"gendocs.failed":           "falha ao gerar a documentação: %w",
+	"gendocs.man_written":      "Páginas de manual gravadas em %s",
+	"gendocs.markdown_written": "Páginas Markdown gravadas em %s",
+
+	"pprof.cpu_failed":  "falha ao gravar o perfil de CPU: %w",
+	"pprof.heap_failed": "falha ao gravar o perfil de heap: %v",
+}
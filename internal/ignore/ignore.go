@@ -0,0 +1,133 @@
+// Package ignore implements .devinitignore files: gitignore-style patterns
+// naming files that devinit must leave alone, in templates (files that are
+// never generated) and in generated projects (user-owned areas skipped by
+// drift checks).
+package ignore
+
+import (
+	"os"
+	"path"
+	"strings"
+)
+
+// FileName is the name of ignore files in templates and projects
+const FileName = ".devinitignore"
+
+// Matcher decides whether a path is ignored
+type Matcher struct {
+	rules []rule
+}
+
+type rule struct {
+	segments []string // pattern split at "/"; "**" matches any number of segments
+	negate   bool     // "!pattern" re-includes paths
+	dirOnly  bool     // "pattern/" only matches directories
+}
+
+// Parse reads patterns, one per line. Blank lines and lines starting with #
+// are skipped. Patterns follow .gitignore: "*" and "?" match within a path
+// segment, "**" matches across segments, a leading or inner "/" anchors the
+// pattern to the root, a trailing "/" matches directories only and "!"
+// re-includes what an earlier pattern excluded.
+func Parse(data string) *Matcher {
+	m := &Matcher{}
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var r rule
+		if strings.HasPrefix(line, "!") {
+			r.negate = true
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			r.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+
+		anchored := strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+		if line == "" {
+			continue
+		}
+
+		r.segments = strings.Split(line, "/")
+		if !anchored {
+			r.segments = append([]string{"**"}, r.segments...)
+		}
+		m.rules = append(m.rules, r)
+	}
+	return m
+}
+
+// Load reads an ignore file. A missing file ignores nothing.
+func Load(filename string) (*Matcher, error) {
+	data, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return &Matcher{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return Parse(string(data)), nil
+}
+
+// Match reports whether name, a slash-separated path relative to the root of
+// the ignore file, is ignored. Paths inside an ignored directory are ignored.
+func (m *Matcher) Match(name string, isDir bool) bool {
+	if m == nil || len(m.rules) == 0 {
+		return false
+	}
+
+	segments := strings.Split(path.Clean(name), "/")
+	ignored := false
+	for _, r := range m.rules {
+		for i := 1; i <= len(segments); i++ {
+			// Parents of name are directories
+			dir := isDir || i < len(segments)
+			if r.dirOnly && !dir {
+				continue
+			}
+			if matchSegments(r.segments, segments[:i]) {
+				ignored = !r.negate
+				break
+			}
+		}
+	}
+	return ignored
+}
+
+// MatchGlob reports whether name matches a single pattern with the same
+// syntax as a .devinitignore line (without "!" or a trailing "/"), anchored
+// to the root
+func MatchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(strings.TrimPrefix(pattern, "/"), "/"), strings.Split(path.Clean(name), "/"))
+}
+
+// IsGlob reports whether s contains glob metacharacters
+func IsGlob(s string) bool {
+	return strings.ContainsAny(s, "*?[")
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], name[0]); err != nil || !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
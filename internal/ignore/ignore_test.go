@@ -0,0 +1,90 @@
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	m := Parse(`# editor files
+*.swp
+.DS_Store
+
+/fixtures/
+!fixtures/keep.json
+docs/**/*.draft.md
+build/
+`)
+
+	tests := []struct {
+		name  string
+		isDir bool
+		want  bool
+	}{
+		{name: "main.go.tmpl", want: false},
+		{name: "main.go.tmpl.swp", want: true},
+		{name: "src/.main.py.swp", want: true},
+		{name: "src/.DS_Store", want: true},
+		{name: "fixtures", isDir: true, want: true},
+		{name: "fixtures/users.json", want: true},
+		{name: "fixtures/keep.json", want: false},
+		{name: "src/fixtures/users.json", want: false},
+		{name: "docs/api/v1/intro.draft.md", want: true},
+		{name: "docs/intro.draft.md", want: true},
+		{name: "docs/intro.md", want: false},
+		{name: "build", want: false},
+		{name: "build", isDir: true, want: true},
+		{name: "app/build/out.bin", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.Match(tt.name, tt.isDir); got != tt.want {
+				t.Errorf("Match(%q, %v) = %v, want %v", tt.name, tt.isDir, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{pattern: "migrations/*.sql", name: "migrations/001_init.sql", want: true},
+		{pattern: "migrations/*.sql", name: "migrations/old/001_init.sql", want: false},
+		{pattern: "migrations/**/*.sql", name: "migrations/old/001_init.sql", want: true},
+		{pattern: "*.sql", name: "migrations/001_init.sql", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := MatchGlob(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("MatchGlob(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	m, err := Load(filepath.Join(dir, FileName))
+	if err != nil {
+		t.Fatalf("Load() of a missing file unexpected error: %v", err)
+	}
+	if m.Match("anything", false) {
+		t.Error("missing ignore file ignores files")
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, FileName), []byte("local/\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m, err = Load(filepath.Join(dir, FileName))
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+	if !m.Match("local/notes.md", false) {
+		t.Error("Load() did not read the patterns")
+	}
+}
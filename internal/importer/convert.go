@@ -0,0 +1,640 @@
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/renan-dev/devinit/internal/template"
+)
+
+// varKind is how a variable of the source is converted
+type varKind int
+
+const (
+	// kindPlain variables are declared with their default
+	kindPlain varKind = iota
+	// kindDerived variables have a default rendered from other variables;
+	// they are declared without one and references fall back to it
+	kindDerived
+	// kindComputed variables are never asked for (cookiecutter's __name,
+	// copier's when: false); references are replaced by their value
+	kindComputed
+	// kindRoot is the variable naming the project directory, which is
+	// the project name
+	kindRoot
+)
+
+// variable is a variable of the source template
+type variable struct {
+	name     string
+	kind     varKind
+	value    string // default of derived and computed variables
+	variable template.Variable
+}
+
+// converter holds the state of the conversion of a template
+type converter struct {
+	source   string
+	filesDir string
+
+	vars   []*variable
+	byName map[string]*variable
+
+	// readRef reads the variable reference at the start of a Jinja2
+	// expression, returning its name and length
+	readRef func(s string) (name string, n int, ok bool)
+
+	// jinjaRefs rewrites the variable references of a Jinja2 expression
+	// taken from the source to the imported template
+	jinjaRefs func(file, expr string) string
+
+	// root is the cookiecutter {{ cookiecutter.x }} directory
+	root string
+
+	// sources are the names given to files under files/
+	sources map[string]bool
+
+	// jinjaExprs and goExprs cache the Jinja2 and Go expressions of derived
+	// and computed variables
+	jinjaExprs map[string]string
+	goExprs    map[string]string
+
+	warnings     []string
+	files, hooks int
+}
+
+func newConverter(source, filesDir string) *converter {
+	return &converter{
+		source:     source,
+		filesDir:   filesDir,
+		byName:     make(map[string]*variable),
+		jinjaRefs:  func(file, expr string) string { return expr },
+		sources:    make(map[string]bool),
+		jinjaExprs: make(map[string]string),
+		goExprs:    make(map[string]string),
+	}
+}
+
+func (c *converter) warnf(format string, args ...any) {
+	c.warnings = append(c.warnings, fmt.Sprintf(format, args...))
+}
+
+func (c *converter) add(v *variable) {
+	c.vars = append(c.vars, v)
+	c.byName[v.name] = v
+}
+
+// declare adds the variables asked for to template.yaml
+func (c *converter) declare(m *manifest) {
+	for _, v := range c.vars {
+		switch v.kind {
+		case kindPlain:
+			if m.Variables == nil {
+				m.Variables = make(map[string]template.Variable)
+			}
+			m.Variables[v.name] = v.variable
+		case kindDerived:
+			if m.Variables == nil {
+				m.Variables = make(map[string]template.Variable)
+			}
+			m.Variables[v.name] = template.Variable{Type: template.VariableTypeString, Description: v.variable.Description}
+		}
+	}
+}
+
+// result returns the Result of the conversion
+func (c *converter) result(format Format, language, framework, dir, version string) *Result {
+	result := &Result{Format: format, Language: language, Framework: framework, Dir: dir, Version: version, Files: c.files, Hooks: c.hooks, Warnings: c.warnings}
+	for _, v := range c.vars {
+		if v.kind == kindPlain || v.kind == kindDerived {
+			result.Variables = append(result.Variables, v.name)
+		}
+	}
+	return result
+}
+
+// constant makes a computed variable of a value that is not a template
+func constant(v *variable, value any) {
+	literal := v.value
+	switch value.(type) {
+	case bool:
+		literal = strings.ToLower(v.value)
+	case string, []any:
+		literal = jinjaLiteral(v.value)
+	}
+	v.value = "{{ " + literal + " }}"
+}
+
+// copyFile is a file of the source as a converter wants it copied
+type copyFile struct {
+	// dest is the path it is generated at, with the Jinja2 expressions and
+	// {% if %} blocks of the source
+	dest string
+
+	// data is its content, rendered when render is set
+	data   []byte
+	render bool
+}
+
+// copyFiles copies the files below root to files/ and adds their file
+// specs. convert decides how a file, given by its slash-separated path
+// relative to root, is copied, or skips it by returning nil; skipDir skips
+// directories.
+func (c *converter) copyFiles(root string, m *manifest, skipDir func(rel string) bool, convert func(rel string, data []byte) *copyFile) error {
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			if rel != "." && (d.Name() == ".git" || skipDir(rel)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			c.warnf("%s is not a regular file; it is skipped", rel)
+			return nil
+		}
+
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		file := convert(rel, data)
+		if file == nil {
+			return nil
+		}
+		dest, conditions, err := c.destination(file.dest)
+		if err != nil {
+			c.warnf("%s: %v; it is skipped", rel, err)
+			return nil
+		}
+
+		src := c.sourceName(file.dest)
+		if file.render {
+			src += ".tmpl"
+		} else if strings.HasSuffix(src, ".tmpl") {
+			c.warnf("%s ends in .tmpl, which devinit renders and strips from its destination", rel)
+		}
+		if err := c.writeFile(src, file.data, info.Mode().Perm()); err != nil {
+			return err
+		}
+
+		spec := template.FileSpec{Source: src, Destination: dest, Conditions: conditions}
+		if info.Mode().Perm()&0111 != 0 {
+			spec.Permissions = "0755"
+		}
+		m.Files = append(m.Files, spec)
+		return nil
+	})
+}
+
+// writeFile writes a file under files/
+func (c *converter) writeFile(src string, data []byte, perm fs.FileMode) error {
+	target := filepath.Join(c.filesDir, filepath.FromSlash(src))
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(target, data, perm)
+}
+
+// addHook generates a script run after generation: it is written to
+// .devinit/hooks of the project, rendered like the source renders it, and
+// run from the project directory by interpreter
+func (c *converter) addHook(m *manifest, name, interpreter, description string, data []byte) error {
+	dest := path.Join(".devinit", "hooks", name)
+	src := dest
+	if isTemplated(string(data)) {
+		src += ".tmpl"
+	}
+	if err := c.writeFile(src, data, 0644); err != nil {
+		return err
+	}
+
+	m.Files = append(m.Files, template.FileSpec{Source: src, Destination: dest})
+	if m.Hooks == nil {
+		m.Hooks = &template.Hooks{}
+	}
+	m.Hooks.PostGenerate = append(m.Hooks.PostGenerate, template.Hook{
+		Run:   interpreter + " " + dest,
+		Error: description + " failed",
+	})
+	return nil
+}
+
+var (
+	// conditionalPattern matches the {% if ... %}name{% endif %} paths
+	// cookiecutter and copier skip when the condition is false
+	conditionalPattern = regexp.MustCompile(`\{%-?\s*if\s+(.+?)\s*-?%\}(.*?)\{%-?\s*endif\s*-?%\}`)
+
+	// identifierPattern matches a variable name
+	identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+	// unsafeName matches the characters kept out of source names
+	unsafeName = regexp.MustCompile(`[^A-Za-z0-9._/-]+`)
+)
+
+// isTemplated reports whether s contains Jinja2 markup
+func isTemplated(s string) bool {
+	return strings.Contains(s, "{{") || strings.Contains(s, "{%")
+}
+
+// isBinary reports whether data looks like a binary file
+func isBinary(data []byte) bool {
+	if len(data) > 8000 {
+		data = data[:8000]
+	}
+	return bytes.IndexByte(data, 0) >= 0
+}
+
+// jinjaLiteral quotes s as a Jinja2 string
+func jinjaLiteral(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\n", `\n`).Replace(s)
+	return "'" + s + "'"
+}
+
+// jinjaDefault returns the value of a derived or computed variable as a
+// Jinja2 expression
+func (c *converter) jinjaDefault(v *variable) string {
+	if expr, ok := c.jinjaExprs[v.name]; ok {
+		return expr
+	}
+	// Guards against variables whose defaults refer to each other
+	c.jinjaExprs[v.name] = "''"
+
+	parts, err := splitExpressions(v.value)
+	if err != nil {
+		c.warnf("default of %s: %v; set it with --var", v.name, err)
+		return "''"
+	}
+	var exprs []string
+	for _, part := range parts {
+		if part.expr {
+			exprs = append(exprs, "("+c.jinjaRefs(v.name, part.text)+")")
+		} else {
+			exprs = append(exprs, jinjaLiteral(part.text))
+		}
+	}
+
+	expr := strings.Join(exprs, " ~ ")
+	if len(exprs) == 1 {
+		expr = strings.TrimSuffix(strings.TrimPrefix(expr, "("), ")")
+	}
+	c.jinjaExprs[v.name] = expr
+	return expr
+}
+
+// part is a piece of text or the inside of a {{ }} expression
+type part struct {
+	text string
+	expr bool
+}
+
+// splitExpressions splits s into text and {{ }} expressions; other Jinja2
+// tags are an error
+func splitExpressions(s string) ([]part, error) {
+	var parts []part
+	for s != "" {
+		if strings.Contains(s, "{%") {
+			return nil, fmt.Errorf("%q uses {%% %%} tags", s)
+		}
+		i := strings.Index(s, "{{")
+		if i < 0 {
+			parts = append(parts, part{text: s})
+			break
+		}
+		j := strings.Index(s[i:], "}}")
+		if j < 0 {
+			return nil, fmt.Errorf("%q has an unclosed {{", s)
+		}
+		if i > 0 {
+			parts = append(parts, part{text: s[:i]})
+		}
+		expr := strings.TrimSpace(strings.Trim(s[i+2:i+j], "-"))
+		parts = append(parts, part{text: expr, expr: true})
+		s = s[i+j+2:]
+	}
+	return parts, nil
+}
+
+// destination converts the path of a file into a destination, which is a
+// Go template, and the conditions of the {% if %} blocks the source uses
+// to skip files
+func (c *converter) destination(rel string) (string, []string, error) {
+	var conditions []string
+	var condErr error
+	rel = conditionalPattern.ReplaceAllStringFunc(rel, func(block string) string {
+		match := conditionalPattern.FindStringSubmatch(block)
+		cond, err := c.goCondition(match[1])
+		if err != nil {
+			condErr = err
+		}
+		conditions = append(conditions, "{{ "+cond+" }}")
+		return match[2]
+	})
+	if condErr != nil {
+		return "", nil, condErr
+	}
+
+	dest, err := c.goText(rel)
+	return dest, conditions, err
+}
+
+// goText translates text with {{ }} expressions to a Go template
+func (c *converter) goText(s string) (string, error) {
+	parts, err := splitExpressions(s)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for _, part := range parts {
+		if !part.expr {
+			b.WriteString(part.text)
+			continue
+		}
+		expr, err := c.goExpr(part.text)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString("{{ " + strings.TrimSuffix(strings.TrimPrefix(expr, "("), ")") + " }}")
+	}
+	return b.String(), nil
+}
+
+// sourceName returns the name under files/ of a file generated at rel: the
+// path with the conditions dropped and each expression replaced by the
+// variable it refers to, e.g. package/__init__.py for
+// {{ cookiecutter.package }}/__init__.py
+func (c *converter) sourceName(rel string) string {
+	name := conditionalPattern.ReplaceAllString(rel, "$2")
+	for {
+		i := strings.Index(name, "{{")
+		j := strings.Index(name, "}}")
+		if i < 0 || j < i {
+			break
+		}
+		replacement := "_"
+		expr := strings.TrimSpace(strings.Trim(name[i+2:j], "-"))
+		if ref, _, ok := c.readRef(expr); ok {
+			replacement = ref
+		}
+		name = name[:i] + replacement + name[j+2:]
+	}
+	name = unsafeName.ReplaceAllString(name, "_")
+
+	unique := name
+	for n := 2; c.sources[unique]; n++ {
+		ext := path.Ext(name)
+		unique = fmt.Sprintf("%s_%d%s", strings.TrimSuffix(name, ext), n, ext)
+	}
+	c.sources[unique] = true
+	return unique
+}
+
+// goFilters maps the Jinja2 filters and Python string methods paths use to
+// template functions
+var goFilters = map[string]string{
+	"lower":  "lower",
+	"upper":  "upper",
+	"title":  "title",
+	"strip":  "trim",
+	"trim":   "trim",
+	"string": "print",
+}
+
+// goExpr translates a Jinja2 expression to a Go template expression. It
+// supports what paths typically hold: a variable or string, followed by
+// methods and filters like lower, upper, title, strip and replace.
+func (c *converter) goExpr(expr string) (string, error) {
+	s := strings.TrimSpace(expr)
+	unsupported := fmt.Errorf("cannot translate %q to a Go template", expr)
+
+	var out string
+	if name, n, ok := c.readRef(s); ok {
+		ref, err := c.goRef(name)
+		if err != nil {
+			return "", err
+		}
+		out, s = ref, s[n:]
+	} else if literal, rest, ok := cutString(s); ok {
+		out, s = strconv.Quote(literal), rest
+	} else {
+		return "", unsupported
+	}
+
+	for {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			return out, nil
+		}
+		if s[0] != '.' && s[0] != '|' {
+			return "", unsupported
+		}
+		method := s[0] == '.'
+		s = strings.TrimSpace(s[1:])
+		end := 0
+		for end < len(s) && (s[end] == '_' || s[end] >= 'a' && s[end] <= 'z' || s[end] >= 'A' && s[end] <= 'Z') {
+			end++
+		}
+		name := s[:end]
+		s = strings.TrimSpace(s[end:])
+
+		var args []string
+		if strings.HasPrefix(s, "(") {
+			s = strings.TrimSpace(s[1:])
+			for !strings.HasPrefix(s, ")") {
+				literal, rest, ok := cutString(s)
+				if !ok {
+					return "", unsupported
+				}
+				args = append(args, strconv.Quote(literal))
+				s = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(rest), ","))
+				if s == "" {
+					return "", unsupported
+				}
+			}
+			s = s[1:]
+		} else if method {
+			return "", unsupported
+		}
+
+		switch {
+		case name == "replace" && len(args) == 2:
+			out = fmt.Sprintf("(replace %s %s %s)", out, args[0], args[1])
+		case goFilters[name] != "" && len(args) == 0:
+			out = fmt.Sprintf("(%s %s)", goFilters[name], out)
+		default:
+			return "", fmt.Errorf("cannot translate %s in %q to a Go template", name, expr)
+		}
+	}
+}
+
+// cutString reads a quoted Python string at the start of s
+func cutString(s string) (literal, rest string, ok bool) {
+	if s == "" || (s[0] != '\'' && s[0] != '"') {
+		return "", "", false
+	}
+	quote := s[0]
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				b.WriteByte(s[i])
+			}
+		case quote:
+			return b.String(), s[i+1:], true
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", "", false
+}
+
+// goRef returns the Go template expression of a variable
+func (c *converter) goRef(name string) (string, error) {
+	v := c.byName[name]
+	if v == nil {
+		return "", fmt.Errorf("%s is not a variable of the template", name)
+	}
+
+	ref := ".Variables." + name
+	if !identifierPattern.MatchString(name) {
+		ref = fmt.Sprintf("(index .Variables %q)", name)
+	}
+	switch v.kind {
+	case kindRoot:
+		return ".ProjectName", nil
+	case kindDerived, kindComputed:
+		value, err := c.goDefault(v)
+		if err != nil {
+			return "", err
+		}
+		if v.kind == kindComputed {
+			return value, nil
+		}
+		return fmt.Sprintf("(or %s %s)", ref, value), nil
+	}
+	return ref, nil
+}
+
+// goDefault returns the value of a derived or computed variable as a Go
+// template expression
+func (c *converter) goDefault(v *variable) (string, error) {
+	if expr, ok := c.goExprs[v.name]; ok {
+		return expr, nil
+	}
+	c.goExprs[v.name] = `""`
+
+	parts, err := splitExpressions(v.value)
+	if err != nil {
+		return "", fmt.Errorf("default of %s: %w", v.name, err)
+	}
+	var exprs []string
+	for _, part := range parts {
+		if !part.expr {
+			exprs = append(exprs, strconv.Quote(part.text))
+			continue
+		}
+		expr, err := c.goExpr(part.text)
+		if err != nil {
+			return "", fmt.Errorf("default of %s: %w", v.name, err)
+		}
+		exprs = append(exprs, expr)
+	}
+
+	expr := exprs[0]
+	if len(exprs) > 1 {
+		expr = "(print " + strings.Join(exprs, " ") + ")"
+	}
+	c.goExprs[v.name] = expr
+	return expr, nil
+}
+
+// goCondition translates the condition of a conditional path: a variable,
+// optionally negated or compared with == or != to a string
+func (c *converter) goCondition(cond string) (string, error) {
+	cond = strings.TrimSpace(cond)
+	for _, op := range []struct{ token, fn string }{{"==", "eq"}, {"!=", "ne"}} {
+		if left, right, ok := strings.Cut(cond, op.token); ok {
+			l, err := c.goExpr(left)
+			if err != nil {
+				return "", err
+			}
+			r, err := c.goExpr(right)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%s %s %s", op.fn, l, r), nil
+		}
+	}
+
+	negate := false
+	if rest, ok := strings.CutPrefix(cond, "not "); ok {
+		negate, cond = true, strings.TrimSpace(rest)
+	}
+	expr, err := c.goExpr(cond)
+	if err != nil {
+		return "", err
+	}
+	if name, _, ok := c.readRef(cond); !ok || c.byName[name].variable.Type != template.VariableTypeBool {
+		// Strings and lists are true when not empty, as in Jinja2
+		expr = "(not " + expr + ")"
+		negate = !negate
+	}
+	if negate {
+		return "not " + expr, nil
+	}
+	return expr, nil
+}
+
+// fnmatch matches a name against a pattern where * matches any characters
+// including slashes, like Python's fnmatch
+func fnmatch(pattern, name string) bool {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch ch := pattern[i]; ch {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		case '[':
+			end := strings.IndexByte(pattern[i:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end
+		default:
+			b.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+	b.WriteString("$")
+	re, err := regexp.Compile(b.String())
+	return err == nil && re.MatchString(name)
+}
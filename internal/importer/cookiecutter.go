@@ -0,0 +1,363 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/renan-dev/devinit/internal/template"
+)
+
+// cookiecutterFile declares the variables of a cookiecutter template
+const cookiecutterFile = "cookiecutter.json"
+
+var (
+	// cookiecutterRef matches cookiecutter.name and cookiecutter['name']
+	cookiecutterRef = regexp.MustCompile(`\bcookiecutter(?:\.(\w+)|\[\s*['"](\w+)['"]\s*\])`)
+
+	// singleCookiecutterRef matches a {{ cookiecutter.name }} expression
+	// alone
+	singleCookiecutterRef = regexp.MustCompile(`^\{\{-?\s*cookiecutter(?:\.(\w+)|\[\s*['"](\w+)['"]\s*\])\s*-?\}\}$`)
+
+	// bareCookiecutter matches a use of the cookiecutter object left after
+	// rewriting its references
+	bareCookiecutter = regexp.MustCompile(`\bcookiecutter\b`)
+
+	// rawPattern and endRawPattern match the tags of a {% raw %} block
+	rawPattern    = regexp.MustCompile(`^\{%-?\s*raw\s*-?%\}$`)
+	endRawPattern = regexp.MustCompile(`\{%-?\s*endraw\s*-?%\}`)
+)
+
+// cookiecutterRefName returns the variable name of a cookiecutterRef match
+func cookiecutterRefName(match []string) string {
+	if match[1] != "" {
+		return match[1]
+	}
+	return match[2]
+}
+
+// cookiecutter converts a cookiecutter template. The variables of
+// cookiecutter.json are referenced as cookiecutter.name; the files are
+// below the one {{ cookiecutter.x }} directory, named after the project.
+func (c *converter) cookiecutter(m *manifest) error {
+	c.readRef = func(s string) (string, int, bool) {
+		loc := cookiecutterRef.FindStringSubmatchIndex(s)
+		if loc == nil || loc[0] != 0 {
+			return "", 0, false
+		}
+		return cookiecutterRefName(cookiecutterRef.FindStringSubmatch(s[:loc[1]])), loc[1], true
+	}
+	c.jinjaRefs = c.rewriteCookiecutterRefs
+
+	data, err := os.ReadFile(filepath.Join(c.source, cookiecutterFile))
+	if err != nil {
+		return err
+	}
+	copyWithoutRender, err := c.parseCookiecutterContext(data)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", cookiecutterFile, err)
+	}
+
+	entries, err := os.ReadDir(c.source)
+	if err != nil {
+		return err
+	}
+	var roots []string
+	for _, entry := range entries {
+		if entry.IsDir() && strings.Contains(entry.Name(), "{{") && strings.Contains(entry.Name(), "cookiecutter") {
+			roots = append(roots, entry.Name())
+		}
+	}
+	switch len(roots) {
+	case 0:
+		return fmt.Errorf("no {{ cookiecutter.* }} directory next to %s", cookiecutterFile)
+	case 1:
+		c.root = roots[0]
+	default:
+		return fmt.Errorf("several {{ cookiecutter.* }} directories next to %s: %s", cookiecutterFile, strings.Join(roots, ", "))
+	}
+
+	// A directory named after a single variable is the project directory,
+	// so that variable is the project name
+	if match := singleCookiecutterRef.FindStringSubmatch(c.root); match != nil {
+		if v := c.byName[cookiecutterRefName(match)]; v != nil {
+			v.kind = kindRoot
+		}
+	}
+
+	err = c.copyFiles(filepath.Join(c.source, c.root), m, func(string) bool { return false }, func(rel string, data []byte) *copyFile {
+		file := &copyFile{dest: rel, data: data}
+		if matchesAny(copyWithoutRender, rel) || isBinary(data) || !isTemplated(string(data)) {
+			return file
+		}
+		file.data, file.render = c.rewriteCookiecutterContent(rel, data), true
+		return file
+	})
+	if err != nil {
+		return err
+	}
+	return c.cookiecutterHooks(m)
+}
+
+// matchesAny reports whether a _copy_without_render pattern matches rel
+func matchesAny(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		if fnmatch(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseCookiecutterContext reads the variables of cookiecutter.json in
+// their order and returns its _copy_without_render patterns
+func (c *converter) parseCookiecutterContext(data []byte) ([]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, errors.New("expected an object")
+	}
+
+	var copyWithoutRender []string
+	prompts := map[string]any{}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		name := tok.(string)
+		var value any
+		if err := dec.Decode(&value); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		switch {
+		case name == "__prompts__":
+			prompts, _ = value.(map[string]any)
+			continue
+		case name == "_copy_without_render":
+			list, _ := value.([]any)
+			for _, pattern := range list {
+				copyWithoutRender = append(copyWithoutRender, fmt.Sprint(pattern))
+			}
+			continue
+		case name == "_extensions":
+			c.warnf("Jinja2 extensions %v are not supported; filters they add must be replaced by hand", value)
+			continue
+		case strings.HasPrefix(name, "_") && !strings.HasPrefix(name, "__"):
+			continue
+		}
+
+		if v := c.cookiecutterVariable(name, value); v != nil {
+			c.add(v)
+		}
+	}
+
+	for name, prompt := range prompts {
+		v := c.byName[name]
+		if v == nil {
+			continue
+		}
+		switch prompt := prompt.(type) {
+		case string:
+			v.variable.Description = prompt
+		case map[string]any:
+			if text, ok := prompt["__prompt__"].(string); ok {
+				v.variable.Description = text
+			}
+		}
+	}
+	return copyWithoutRender, nil
+}
+
+// cookiecutterVariable converts a value of cookiecutter.json, or returns
+// nil for values that have no devinit equivalent
+func (c *converter) cookiecutterVariable(name string, value any) *variable {
+	v := &variable{name: name}
+	if strings.HasPrefix(name, "__") {
+		v.kind = kindComputed
+	}
+
+	switch value := value.(type) {
+	case string:
+		v.value = value
+		if isTemplated(value) {
+			if v.kind != kindComputed {
+				v.kind = kindDerived
+			}
+			return v
+		}
+		v.variable = template.Variable{Type: template.VariableTypeString, Default: value}
+	case bool:
+		v.value = fmt.Sprint(value)
+		v.variable = template.Variable{Type: template.VariableTypeBool, Default: value}
+	case json.Number:
+		v.value = value.String()
+		if n, err := value.Int64(); err == nil {
+			v.variable = template.Variable{Type: template.VariableTypeInt, Default: int(n)}
+		} else {
+			v.variable = template.Variable{Type: template.VariableTypeString, Default: value.String()}
+		}
+	case []any:
+		if len(value) == 0 {
+			c.warnf("variable %s is an empty list; it is skipped", name)
+			return nil
+		}
+		choices := make([]template.Choice, len(value))
+		for i, choice := range value {
+			choices[i].Value = fmt.Sprint(choice)
+			if isTemplated(choices[i].Value) {
+				c.warnf("choice %q of %s is rendered by cookiecutter; it is kept as text", choices[i].Value, name)
+			}
+		}
+		v.value = choices[0].Value
+		v.variable = template.Variable{Type: template.VariableTypeChoice, Default: choices[0].Value, Choices: choices}
+	default:
+		kind := "null"
+		if _, ok := value.(map[string]any); ok {
+			kind = "dictionary"
+		}
+		c.warnf("variable %s is a %s, which devinit variables cannot hold; it is skipped", name, kind)
+		return nil
+	}
+
+	if v.kind == kindComputed {
+		constant(v, value)
+	}
+	return v
+}
+
+// rewriteCookiecutterContent rewrites the cookiecutter.name references
+// inside the {{ }} and {% %} tags of a file, leaving text, comments and raw
+// blocks alone
+func (c *converter) rewriteCookiecutterContent(file string, data []byte) []byte {
+	text := string(data)
+	var b strings.Builder
+	for {
+		i := tagStart(text)
+		if i < 0 {
+			b.WriteString(text)
+			break
+		}
+		b.WriteString(text[:i])
+		text = text[i:]
+
+		closer := map[byte]string{'{': "}}", '%': "%}", '#': "#}"}[text[1]]
+		j := strings.Index(text[2:], closer)
+		if j < 0 {
+			// Left for the renderer to report
+			b.WriteString(text)
+			break
+		}
+		tag := text[:j+4]
+		text = text[j+4:]
+
+		switch {
+		case tag[1] == '#':
+			b.WriteString(tag)
+		case rawPattern.MatchString(tag):
+			b.WriteString(tag)
+			if loc := endRawPattern.FindStringIndex(text); loc != nil {
+				b.WriteString(text[:loc[1]])
+				text = text[loc[1]:]
+			}
+		default:
+			b.WriteString(c.rewriteCookiecutterRefs(file, tag))
+		}
+	}
+	return []byte(b.String())
+}
+
+// tagStart returns the index of the first {{, {% or {# in text, or -1
+func tagStart(text string) int {
+	start := -1
+	for _, open := range []string{"{{", "{%", "{#"} {
+		if i := strings.Index(text, open); i >= 0 && (start < 0 || i < start) {
+			start = i
+		}
+	}
+	return start
+}
+
+// rewriteCookiecutterRefs rewrites the cookiecutter.name references of a
+// Jinja2 expression or tag to the names the imported template uses
+func (c *converter) rewriteCookiecutterRefs(file, expr string) string {
+	out := cookiecutterRef.ReplaceAllStringFunc(expr, func(ref string) string {
+		return c.cookiecutterJinjaRef(file, cookiecutterRefName(cookiecutterRef.FindStringSubmatch(ref)))
+	})
+	if bareCookiecutter.MatchString(out) {
+		c.warnf("%s: %s uses the cookiecutter object itself, which has no equivalent", file, strings.TrimSpace(expr))
+	}
+	return out
+}
+
+// cookiecutterJinjaRef returns the Jinja2 expression a reference to a
+// variable becomes
+func (c *converter) cookiecutterJinjaRef(file, name string) string {
+	v := c.byName[name]
+	if v == nil {
+		c.warnf("%s: cookiecutter.%s is not declared in %s", file, name, cookiecutterFile)
+		return name
+	}
+	switch v.kind {
+	case kindRoot:
+		return "ProjectName"
+	case kindDerived:
+		return fmt.Sprintf("(%s|default(%s))", name, c.jinjaDefault(v))
+	case kindComputed:
+		return "(" + c.jinjaDefault(v) + ")"
+	}
+	return name
+}
+
+// cookiecutterHookFiles are the hooks converted, by the interpreter
+// running them
+var cookiecutterHookFiles = []struct {
+	name        string
+	interpreter string
+}{
+	{"post_gen_project.py", "python3"},
+	{"post_gen_project.sh", "sh"},
+}
+
+// cookiecutterHooks turns post_gen_project hooks into post_generate hooks
+func (c *converter) cookiecutterHooks(m *manifest) error {
+	hooksDir := filepath.Join(c.source, "hooks")
+	entries, err := os.ReadDir(hooksDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, "pre_gen_project.") || strings.HasPrefix(name, "pre_prompt.") {
+			c.warnf("hook %s is not converted; validate variables with a pattern in template.yaml instead", name)
+		}
+	}
+
+	for _, hook := range cookiecutterHookFiles {
+		data, err := os.ReadFile(filepath.Join(hooksDir, hook.name))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if isTemplated(string(data)) {
+			data = c.rewriteCookiecutterContent("hooks/"+hook.name, data)
+		}
+		if err := c.addHook(m, hook.name, hook.interpreter, "cookiecutter hook "+hook.name, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/renan-dev/devinit/internal/generator"
+)
+
+var cookiecutterFixture = map[string]string{
+	"cookiecutter.json": `{
+  "project_name": "My Project",
+  "project_slug": "{{ cookiecutter.project_name.lower().replace(' ', '-') }}",
+  "package": "{{ cookiecutter.project_slug.replace('-', '_') }}",
+  "license": ["MIT", "BSD-3-Clause"],
+  "use_docker": "y",
+  "async": true,
+  "workers": 4,
+  "settings": {"debug": false},
+  "__year": "2026",
+  "__author": "{{ cookiecutter.project_name }} team",
+  "_copy_without_render": ["*.html"],
+  "_extensions": ["jinja2_time.TimeExtension"],
+  "__prompts__": {"project_name": "Project name", "license": {"__prompt__": "License", "MIT": "MIT License"}}
+}`,
+	"{{cookiecutter.project_slug}}/README.md":                                                    "# {{ cookiecutter.project_name }}\n{{ cookiecutter.package }} by {{ cookiecutter.__author }}, {{ cookiecutter['__year'] }} {# note #}\n{% if cookiecutter.async %}async {% endif %}{{ cookiecutter.license }}\n{% raw %}{{ cookiecutter.kept }}{% endraw %}\n",
+	"{{cookiecutter.project_slug}}/{{cookiecutter.package}}/__init__.py":                         "__version__ = '0.1.0'\n",
+	"{{cookiecutter.project_slug}}/{{cookiecutter.package|upper}}.txt":                           "{{ cookiecutter.workers * 2 }}\n",
+	"{{cookiecutter.project_slug}}/{% if cookiecutter.use_docker == 'y' %}Dockerfile{% endif %}": "FROM python\n",
+	"{{cookiecutter.project_slug}}/{% if cookiecutter.async %}worker.py{% endif %}":              "import asyncio\n",
+	"{{cookiecutter.project_slug}}/templates/index.html":                                         "{{ not rendered }}\n",
+	"hooks/post_gen_project.py":                                                                  "print('{{ cookiecutter.package }}')\n",
+	"hooks/pre_gen_project.py":                                                                   "import sys\n",
+	"README.md":                                                                                  "The template itself\n",
+}
+
+func TestImportCookiecutter(t *testing.T) {
+	source := t.TempDir()
+	writeSource(t, source, cookiecutterFixture)
+
+	templatesDir := t.TempDir()
+	dir := filepath.Join(templatesDir, "cookiecutter", "demo")
+	result, err := Import(context.Background(), Options{Source: source, Dir: dir, Language: "cookiecutter", Framework: "demo"})
+	if err != nil {
+		t.Fatalf("Import() unexpected error: %v", err)
+	}
+
+	if got, want := strings.Join(result.Variables, ","), "project_name,package,license,use_docker,async,workers"; got != want {
+		t.Errorf("Variables = %s, want %s", got, want)
+	}
+	if result.Format != FormatCookiecutter || result.Files != 7 || result.Hooks != 1 {
+		t.Errorf("Format, Files, Hooks = %s, %d, %d, want cookiecutter, 7, 1", result.Format, result.Files, result.Hooks)
+	}
+	for _, want := range []string{"jinja2_time.TimeExtension", "settings is a dictionary", "pre_gen_project.py is not converted"} {
+		if !strings.Contains(strings.Join(result.Warnings, "\n"), want) {
+			t.Errorf("Warnings = %q, want one containing %q", result.Warnings, want)
+		}
+	}
+
+	for _, name := range []string{"package/__init__.py", "package.txt.tmpl", "Dockerfile", "README.md.tmpl", "templates/index.html"} {
+		if _, err := os.Stat(filepath.Join(dir, "files", name)); err != nil {
+			t.Errorf("files/%s not written: %v", name, err)
+		}
+	}
+
+	if _, err := Import(context.Background(), Options{Source: source, Dir: dir}); !errors.Is(err, ErrExists) {
+		t.Errorf("Import() into an existing directory error = %v, want ErrExists", err)
+	}
+
+	gen := generator.NewGenerator(templatesDir)
+	tmpl, err := gen.GetTemplate("cookiecutter/demo")
+	if err != nil {
+		t.Fatalf("GetTemplate() unexpected error: %v", err)
+	}
+	if tmpl.Variables["license"].Description != "License" || tmpl.Variables["license"].Default != "MIT" {
+		t.Errorf("license = %+v, want a choice described as License defaulting to MIT", tmpl.Variables["license"])
+	}
+
+	tests := []struct {
+		name      string
+		variables map[string]interface{}
+		want      map[string]string
+		absent    []string
+	}{
+		{
+			name: "defaults",
+			want: map[string]string{
+				"README.md":                          "# My Project\nmy_app by My Project team, 2026 \nasync MIT\n{{ cookiecutter.kept }}\n",
+				"my_app/__init__.py":                 "__version__ = '0.1.0'\n",
+				"MY_APP.txt":                         "8\n",
+				"Dockerfile":                         "FROM python\n",
+				"worker.py":                          "import asyncio\n",
+				"templates/index.html":               "{{ not rendered }}\n",
+				".devinit/hooks/post_gen_project.py": "print('my_app')\n",
+			},
+		},
+		{
+			name:      "overridden",
+			variables: map[string]interface{}{"package": "core", "use_docker": "n", "async": false, "project_name": "Other"},
+			want: map[string]string{
+				"README.md":        "# Other\ncore by Other team, 2026 \nMIT\n{{ cookiecutter.kept }}\n",
+				"CORE.txt":         "8\n",
+				"core/__init__.py": "__version__ = '0.1.0'\n",
+			},
+			absent: []string{"Dockerfile", "worker.py"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := gen.RenderFiles(tmpl, "my-app", tt.variables)
+			if err != nil {
+				t.Fatalf("RenderFiles() unexpected error: %v", err)
+			}
+			for name, want := range tt.want {
+				if got := string(files[name]); got != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+			for _, name := range tt.absent {
+				if _, ok := files[name]; ok {
+					t.Errorf("%s generated, want it skipped", name)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,436 @@
+package importer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/renan-dev/devinit/internal/ignore"
+	"github.com/renan-dev/devinit/internal/template"
+)
+
+// copierFiles are the names of the copier configuration
+var copierFiles = []string{"copier.yml", "copier.yaml"}
+
+// copierDefaultExclude is what copier leaves out of projects when the
+// template does not set _exclude
+var copierDefaultExclude = []string{"copier.yaml", "copier.yml", "~*", "*.py[co]", "__pycache__", ".git", ".DS_Store", ".svn"}
+
+// copierSettings are the settings of copier.yml, whose keys start with _
+type copierSettings struct {
+	Subdirectory     string      `yaml:"_subdirectory"`
+	TemplatesSuffix  *string     `yaml:"_templates_suffix"`
+	Exclude          []string    `yaml:"_exclude"`
+	SkipIfExists     []string    `yaml:"_skip_if_exists"`
+	Tasks            []yaml.Node `yaml:"_tasks"`
+	Migrations       []yaml.Node `yaml:"_migrations"`
+	MessageAfterCopy string      `yaml:"_message_after_copy"`
+	JinjaExtensions  []string    `yaml:"_jinja_extensions"`
+	Envops           yaml.Node   `yaml:"_envops"`
+}
+
+// copierQuestion is the long form of a question
+type copierQuestion struct {
+	Type        string    `yaml:"type"`
+	Help        string    `yaml:"help"`
+	Default     yaml.Node `yaml:"default"`
+	Choices     yaml.Node `yaml:"choices"`
+	When        yaml.Node `yaml:"when"`
+	Validator   string    `yaml:"validator"`
+	Multiselect bool      `yaml:"multiselect"`
+}
+
+// copierQuestionKeys are the keys telling the long form of a question from
+// a dictionary default
+var copierQuestionKeys = map[string]bool{
+	"type": true, "help": true, "default": true, "choices": true, "when": true, "validator": true,
+	"secret": true, "placeholder": true, "multiselect": true, "multiline": true, "qmark": true,
+}
+
+// copierIdentifier matches the variable name at the start of an expression
+var copierIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*`)
+
+// copier converts a copier template. Questions of copier.yml are
+// referenced by name; files ending in the templates suffix (.jinja) are
+// rendered and lose it; _tasks run after generation.
+func (c *converter) copier(m *manifest) error {
+	c.readRef = func(s string) (string, int, bool) {
+		name := copierIdentifier.FindString(s)
+		if name == "" || c.byName[name] == nil {
+			return "", 0, false
+		}
+		return name, len(name), true
+	}
+
+	var config yaml.Node
+	var settings copierSettings
+	for _, name := range copierFiles {
+		data, err := os.ReadFile(filepath.Join(c.source, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := yaml.Unmarshal(data, &config); err != nil {
+			return fmt.Errorf("invalid %s: %w", name, err)
+		}
+		if err := config.Decode(&settings); err != nil {
+			return fmt.Errorf("invalid %s: %w", name, err)
+		}
+		break
+	}
+
+	// The project directory, named by _folder_name in copier
+	c.add(&variable{name: "_folder_name", kind: kindRoot})
+	if len(config.Content) > 0 && config.Content[0].Kind == yaml.MappingNode {
+		fields := config.Content[0].Content
+		for i := 0; i+1 < len(fields); i += 2 {
+			name, value := fields[i].Value, fields[i+1]
+			if strings.HasPrefix(name, "_") || name == "<<" {
+				continue
+			}
+			if v := c.copierVariable(name, value); v != nil {
+				c.add(v)
+			}
+		}
+	}
+	c.copierSettingsWarnings(settings)
+
+	if isTemplated(settings.Subdirectory) {
+		return fmt.Errorf("_subdirectory %q is rendered by copier, which is not supported", settings.Subdirectory)
+	}
+	suffix := ".jinja"
+	if settings.TemplatesSuffix != nil {
+		suffix = *settings.TemplatesSuffix
+	}
+	exclude := settings.Exclude
+	if exclude == nil {
+		exclude = copierDefaultExclude
+	}
+	excluded := ignore.Parse(strings.Join(append([]string{"copier.yml", "copier.yaml"}, exclude...), "\n"))
+
+	root := filepath.Join(c.source, filepath.FromSlash(settings.Subdirectory))
+	skipDir := func(rel string) bool { return excluded.Match(rel, true) }
+	err := c.copyFiles(root, m, skipDir, func(rel string, data []byte) *copyFile {
+		if excluded.Match(rel, false) {
+			return nil
+		}
+		if strings.Contains(rel, "_copier_conf") {
+			c.warnf("%s: the copier answers file is not converted; devinit records the answers in .devinit.yaml", rel)
+			return nil
+		}
+
+		file := &copyFile{dest: rel, data: data}
+		if suffix != "" && !strings.HasSuffix(rel, suffix) {
+			return file
+		}
+		file.dest = strings.TrimSuffix(rel, suffix)
+		if isBinary(data) {
+			return file
+		}
+		if strings.Contains(string(data), "_copier_conf") {
+			c.warnf("%s uses _copier_conf, which has no equivalent", rel)
+		}
+		file.data, file.render = []byte(c.copierPreamble(string(data))+string(data)), true
+		return file
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, line := range strings.Split(settings.MessageAfterCopy, "\n") {
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		step, err := c.goText(line)
+		if err != nil {
+			c.warnf("_message_after_copy line %q: %v; it is skipped", line, err)
+			continue
+		}
+		m.NextSteps = append(m.NextSteps, step)
+	}
+
+	return c.copierTasks(m, settings.Tasks)
+}
+
+// copierSettingsWarnings reports the settings that have no equivalent
+func (c *converter) copierSettingsWarnings(settings copierSettings) {
+	if len(settings.SkipIfExists) > 0 {
+		c.warnf("_skip_if_exists %v is not converted; devinit generates new projects only", settings.SkipIfExists)
+	}
+	if len(settings.JinjaExtensions) > 0 {
+		c.warnf("Jinja2 extensions %v are not supported; filters they add must be replaced by hand", settings.JinjaExtensions)
+	}
+	if settings.Envops.Kind != 0 {
+		c.warnf("_envops is not supported; templates must use the default Jinja2 delimiters")
+	}
+
+	var versions []string
+	for _, migration := range settings.Migrations {
+		var entry struct {
+			Version string `yaml:"version"`
+		}
+		if migration.Decode(&entry) == nil && entry.Version != "" {
+			versions = append(versions, entry.Version)
+		}
+	}
+	if len(settings.Migrations) > 0 {
+		c.warnf("migrations %s are not converted: devinit does not update generated projects; compare template versions with devinit templates diff", strings.Join(versions, ", "))
+	}
+}
+
+// copierVariable converts a question of copier.yml, or returns nil for
+// questions that have no devinit equivalent
+func (c *converter) copierVariable(name string, node *yaml.Node) *variable {
+	if node.Tag == "!include" {
+		c.warnf("%s is included from %s, which is not supported; it is skipped", name, node.Value)
+		return nil
+	}
+
+	var q copierQuestion
+	def := node
+	if isCopierQuestion(node) {
+		if err := node.Decode(&q); err != nil {
+			c.warnf("question %s: %v; it is skipped", name, err)
+			return nil
+		}
+		def = &q.Default
+	}
+
+	v := &variable{name: name, variable: template.Variable{Description: q.Help}}
+	if q.When.Kind != 0 {
+		var when any
+		_ = q.When.Decode(&when)
+		switch when {
+		case false:
+			v.kind = kindComputed
+		case true:
+		default:
+			c.warnf("%s is asked only when %s; devinit always asks it", name, q.When.Value)
+		}
+	}
+	if q.Validator != "" {
+		c.warnf("the validator of %s is not converted; add a pattern to its variable", name)
+	}
+	if q.Multiselect {
+		c.warnf("%s is a multiselect question; it is converted to a single choice", name)
+	}
+
+	var value any
+	if def.Kind != 0 {
+		if err := def.Decode(&value); err != nil {
+			c.warnf("default of %s: %v; it is skipped", name, err)
+			return nil
+		}
+	}
+	if s, ok := value.(string); ok && isTemplated(s) {
+		v.value = s
+		if v.kind != kindComputed {
+			v.kind = kindDerived
+		}
+		return v
+	}
+
+	typ := q.Type
+	if typ == "" {
+		switch value.(type) {
+		case bool:
+			typ = "bool"
+		case int:
+			typ = "int"
+		case []any, map[string]any:
+			typ = "yaml"
+		default:
+			typ = "str"
+		}
+	}
+	switch typ {
+	case "bool":
+		v.variable.Type = template.VariableTypeBool
+	case "int":
+		v.variable.Type = template.VariableTypeInt
+	case "str", "float":
+		v.variable.Type = template.VariableTypeString
+		if value != nil {
+			value = fmt.Sprint(value)
+		}
+	default:
+		c.warnf("%s is a %s question, which devinit variables cannot hold; it is skipped", name, typ)
+		return nil
+	}
+	if q.Choices.Kind != 0 {
+		v.variable.Type = template.VariableTypeChoice
+		v.variable.Choices = copierChoices(&q.Choices)
+		if value != nil {
+			value = fmt.Sprint(value)
+		}
+	}
+
+	if value == nil {
+		if v.kind == kindComputed {
+			c.warnf("%s is never asked and has no default; it is skipped", name)
+			return nil
+		}
+		v.variable.Required = true
+		return v
+	}
+	v.value = fmt.Sprint(value)
+	v.variable.Default = value
+	if v.kind == kindComputed {
+		constant(v, value)
+	}
+	return v
+}
+
+// isCopierQuestion reports whether node is the long form of a question
+func isCopierQuestion(node *yaml.Node) bool {
+	if node.Kind != yaml.MappingNode {
+		return false
+	}
+	for i := 0; i < len(node.Content); i += 2 {
+		if !copierQuestionKeys[node.Content[i].Value] {
+			return false
+		}
+	}
+	return true
+}
+
+// copierChoices returns the choices given as a list of values or [label,
+// value] pairs, or as a mapping of labels to values, with their labels
+func copierChoices(node *yaml.Node) []template.Choice {
+	var choices []template.Choice
+	switch node.Kind {
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			if item.Kind == yaml.SequenceNode && len(item.Content) == 2 {
+				choices = append(choices, template.Choice{Value: item.Content[1].Value, Label: item.Content[0].Value})
+				continue
+			}
+			choices = append(choices, template.Choice{Value: item.Value})
+		}
+	case yaml.MappingNode:
+		for i := 1; i < len(node.Content); i += 2 {
+			label, item := node.Content[i-1].Value, node.Content[i]
+			if item.Kind == yaml.MappingNode {
+				var choice struct {
+					Value string `yaml:"value"`
+					Help  string `yaml:"help"`
+				}
+				_ = item.Decode(&choice)
+				choices = append(choices, template.Choice{Value: choice.Value, Label: label, Description: choice.Help})
+				continue
+			}
+			choices = append(choices, template.Choice{Value: item.Value, Label: label})
+		}
+	}
+	return choices
+}
+
+// copierPreamble returns the {% set %} tags giving the derived and computed
+// variables text refers to their values, which copier computes before
+// rendering
+func (c *converter) copierPreamble(text string) string {
+	needed := make(map[string]bool)
+	var visit func(s string)
+	visit = func(s string) {
+		for _, v := range c.vars {
+			if v.kind == kindPlain || needed[v.name] || !regexp.MustCompile(`\b`+regexp.QuoteMeta(v.name)+`\b`).MatchString(s) {
+				continue
+			}
+			needed[v.name] = true
+			visit(v.value)
+		}
+	}
+	visit(text)
+
+	var b strings.Builder
+	for _, v := range c.vars {
+		if !needed[v.name] {
+			continue
+		}
+		switch v.kind {
+		case kindRoot:
+			fmt.Fprintf(&b, "{%% set %s = ProjectName %%}", v.name)
+		case kindDerived:
+			fmt.Fprintf(&b, "{%% set %s = %s|default(%s) %%}", v.name, v.name, c.jinjaDefault(v))
+		case kindComputed:
+			fmt.Fprintf(&b, "{%% set %s = %s %%}", v.name, c.jinjaDefault(v))
+		}
+	}
+	return b.String()
+}
+
+// copierTask is the long form of a task
+type copierTask struct {
+	Command          yaml.Node `yaml:"command"`
+	When             yaml.Node `yaml:"when"`
+	WorkingDirectory string    `yaml:"working_directory"`
+}
+
+// copierTasks turns _tasks into a shell script run after generation.
+// Commands and conditions are rendered like copier renders them.
+func (c *converter) copierTasks(m *manifest, tasks []yaml.Node) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	var b strings.Builder
+	b.WriteString("#!/bin/sh\n# Tasks of the copier template\nset -e\n")
+	for _, node := range tasks {
+		task := copierTask{Command: node}
+		if node.Kind == yaml.MappingNode {
+			if err := node.Decode(&task); err != nil {
+				c.warnf("task at line %d: %v; it is skipped", node.Line, err)
+				continue
+			}
+		}
+
+		command := task.Command.Value
+		if task.Command.Kind == yaml.SequenceNode {
+			var args []string
+			for _, arg := range task.Command.Content {
+				args = append(args, shellQuote(arg.Value))
+			}
+			command = strings.Join(args, " ")
+		}
+		if task.WorkingDirectory != "" && task.WorkingDirectory != "." {
+			command = fmt.Sprintf("(cd %s && %s)", task.WorkingDirectory, command)
+		}
+
+		var when any = true
+		if task.When.Kind != 0 {
+			_ = task.When.Decode(&when)
+		}
+		switch when := when.(type) {
+		case bool:
+			if when {
+				b.WriteString(command + "\n")
+			}
+		case string:
+			parts, err := splitExpressions(when)
+			if err != nil || len(parts) != 1 || !parts[0].expr {
+				c.warnf("task %q runs when %s, which cannot be converted; it is skipped", command, when)
+				continue
+			}
+			fmt.Fprintf(&b, "{%% if %s -%%}\n%s\n{%%- endif %%}\n", parts[0].text, command)
+		default:
+			c.warnf("task %q runs when %v, which cannot be converted; it is skipped", command, when)
+		}
+	}
+
+	script := b.String()
+	return c.addHook(m, "copier_tasks.sh", "sh", "copier tasks", []byte(c.copierPreamble(script)+script))
+}
+
+// shellQuote quotes an argument for sh when needed
+func shellQuote(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\n'\"\\$`|&;<>()*?[]#~{}") {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
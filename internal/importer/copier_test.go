@@ -0,0 +1,173 @@
+package importer
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/renan-dev/devinit/internal/generator"
+)
+
+var copierFixture = map[string]string{
+	"copier.yml": `_subdirectory: template
+_exclude: ["*.bak"]
+_skip_if_exists: [.env]
+_tasks:
+  - git init --quiet
+  - command: [echo, "{{ package }} ready"]
+    when: "{{ use_ci }}"
+  - command: make setup
+    when: false
+_migrations:
+  - version: v2.0.0
+    command: rm old.txt
+_message_after_copy: |
+  cd {{ _folder_name }}
+  Import {{ package }}
+
+project_name:
+  type: str
+  help: Project name
+  default: My Project
+package:
+  type: str
+  default: "{{ project_name.lower().replace(' ', '_') }}"
+license:
+  help: License
+  choices:
+    MIT License: MIT
+    Apache License 2.0: Apache-2.0
+  default: MIT
+use_ci: true
+python:
+  type: str
+  choices: [["Python 3.12", "3.12"], ["Python 3.11", "3.11"]]
+  default: "3.12"
+email:
+  type: str
+  validator: "{% if '@' not in email %}invalid{% endif %}"
+author:
+  default: Jane
+  when: "{{ license == 'MIT' }}"
+year:
+  default: 2026
+  when: false
+slug:
+  default: "{{ package|replace('_', '-') }}"
+  when: false
+extra:
+  type: yaml
+  default: {}
+`,
+	"README.md":                          "The template itself\n",
+	"template/README.md.jinja":           "# {{ project_name }}\n{{ package }} ({{ slug }}, {{ year }}) in {{ _folder_name }}\n{% if use_ci %}CI{% endif %}\n",
+	"template/{{ package }}/__init__.py": "{{ kept }}\n",
+	"template/{% if use_ci %}.github{% endif %}/workflows/ci.yml.jinja": "python: {{ python }}\n",
+	"template/LICENSE.jinja":                       "{{ license }} {{ author }}\n",
+	"template/notes.bak":                           "excluded\n",
+	"template/{{_copier_conf.answers_file}}.jinja": "{{ _copier_answers|to_nice_yaml }}",
+}
+
+func TestImportCopier(t *testing.T) {
+	source := t.TempDir()
+	writeSource(t, source, copierFixture)
+
+	templatesDir := t.TempDir()
+	result, err := Import(context.Background(), Options{Source: source, Dir: templatesDir + "/copier/demo", Language: "copier", Framework: "demo"})
+	if err != nil {
+		t.Fatalf("Import() unexpected error: %v", err)
+	}
+
+	if got, want := strings.Join(result.Variables, ","), "project_name,package,license,use_ci,python,email,author"; got != want {
+		t.Errorf("Variables = %s, want %s", got, want)
+	}
+	if result.Format != FormatCopier || result.Files != 5 || result.Hooks != 1 {
+		t.Errorf("Format, Files, Hooks = %s, %d, %d, want copier, 5, 1", result.Format, result.Files, result.Hooks)
+	}
+	warnings := strings.Join(result.Warnings, "\n")
+	for _, want := range []string{
+		"_skip_if_exists [.env]",
+		"migrations v2.0.0 are not converted",
+		"the validator of email",
+		"author is asked only when {{ license == 'MIT' }}",
+		"extra is a yaml question",
+		"the copier answers file is not converted",
+	} {
+		if !strings.Contains(warnings, want) {
+			t.Errorf("Warnings = %q, want one containing %q", result.Warnings, want)
+		}
+	}
+
+	gen := generator.NewGenerator(templatesDir)
+	tmpl, err := gen.GetTemplate("copier/demo")
+	if err != nil {
+		t.Fatalf("GetTemplate() unexpected error: %v", err)
+	}
+	if license := tmpl.Variables["license"]; strings.Join(license.ChoiceValues(), ",") != "MIT,Apache-2.0" || license.Default != "MIT" {
+		t.Errorf("license = %+v, want choices MIT and Apache-2.0 defaulting to MIT", license)
+	}
+	if python := tmpl.Variables["python"]; strings.Join(python.ChoiceValues(), ",") != "3.12,3.11" {
+		t.Errorf("python choices = %v, want 3.12, 3.11", python.Choices)
+	}
+	// The labels of copier choices are kept for prompts
+	if license := tmpl.Variables["license"]; license.Choices[1].Label != "Apache License 2.0" {
+		t.Errorf("license choices = %+v, want the labels of copier", license.Choices)
+	}
+	if !tmpl.Variables["email"].Required {
+		t.Errorf("email without a default is not required")
+	}
+	if got, want := strings.Join(tmpl.NextSteps, "\n"), "cd {{ .ProjectName }}\nImport {{ or .Variables.package (replace (lower .Variables.project_name) \" \" \"_\") }}"; got != want {
+		t.Errorf("NextSteps = %q, want %q", got, want)
+	}
+
+	tests := []struct {
+		name      string
+		variables map[string]interface{}
+		want      map[string]string
+		absent    []string
+	}{
+		{
+			name:      "defaults",
+			variables: map[string]interface{}{"email": "dev@example.com"},
+			want: map[string]string{
+				"README.md":                "# My Project\nmy_project (my-project, 2026) in my-app\nCI\n",
+				"my_project/__init__.py":   "{{ kept }}\n",
+				".github/workflows/ci.yml": "python: 3.12\n",
+				"LICENSE":                  "MIT Jane\n",
+				".devinit/hooks/copier_tasks.sh": "#!/bin/sh\n# Tasks of the copier template\nset -e\n" +
+					"git init --quiet\necho 'my_project ready'\n",
+			},
+			absent: []string{"notes.bak", "template/README.md"},
+		},
+		{
+			name:      "overridden",
+			variables: map[string]interface{}{"email": "dev@example.com", "package": "core", "use_ci": false},
+			want: map[string]string{
+				"README.md":        "# My Project\ncore (core, 2026) in my-app\n\n",
+				"core/__init__.py": "{{ kept }}\n",
+				".devinit/hooks/copier_tasks.sh": "#!/bin/sh\n# Tasks of the copier template\nset -e\n" +
+					"git init --quiet\n\n",
+			},
+			absent: []string{".github/workflows/ci.yml"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := gen.RenderFiles(tmpl, "my-app", tt.variables)
+			if err != nil {
+				t.Fatalf("RenderFiles() unexpected error: %v", err)
+			}
+			for name, want := range tt.want {
+				if got := string(files[name]); got != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+			for _, name := range tt.absent {
+				if _, ok := files[name]; ok {
+					t.Errorf("%s generated, want it skipped", name)
+				}
+			}
+		})
+	}
+}
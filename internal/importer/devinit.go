@@ -0,0 +1,155 @@
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/renan-dev/devinit/internal/template"
+)
+
+// devinitFile describes a devinit template
+const devinitFile = "template.yaml"
+
+// devinitTemplate returns the template.yaml of the devinit template in dir;
+// errors are left to the import, which reads it again
+func devinitTemplate(dir string) template.Template {
+	var tmpl template.Template
+	if data, err := os.ReadFile(filepath.Join(dir, devinitFile)); err == nil {
+		yaml.Unmarshal(data, &tmpl)
+	}
+	return tmpl
+}
+
+// devinit copies a devinit template to dir as it is, but for its older
+// versions, which belong to the source, and with its version and origin
+// set
+func (c *converter) devinit(dir, version string, origin *template.Origin) error {
+	data, err := os.ReadFile(filepath.Join(c.source, devinitFile))
+	if err != nil {
+		return err
+	}
+	var tmpl template.Template
+	if err := yaml.Unmarshal(data, &tmpl); err != nil {
+		return fmt.Errorf("invalid %s: %w", devinitFile, err)
+	}
+
+	err = filepath.WalkDir(c.source, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(c.source, p)
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if rel != "." && (d.Name() == ".git" || rel == "versions") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if rel == devinitFile {
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			c.warnf("%s is not a regular file; it is skipped", filepath.ToSlash(rel))
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dir, rel)
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		return os.WriteFile(target, content, info.Mode().Perm())
+	})
+	if err != nil {
+		return err
+	}
+
+	if tmpl.Version != version {
+		if data, err = setField(data, "version", version); err != nil {
+			return fmt.Errorf("invalid %s: %w", devinitFile, err)
+		}
+	}
+	if origin != nil {
+		if data, err = setField(data, "origin", origin); err != nil {
+			return fmt.Errorf("invalid %s: %w", devinitFile, err)
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, devinitFile), data, 0644); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(tmpl.Variables))
+	for name := range tmpl.Variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		c.add(&variable{name: name, kind: kindPlain, variable: tmpl.Variables[name]})
+	}
+	c.files = len(tmpl.Files)
+	c.hooks = len(tmpl.Hooks.PreGenerate) + len(tmpl.Hooks.PostGenerate)
+	return nil
+}
+
+// setField sets a top-level field of template.yaml. A new field is
+// appended, which keeps the file as its author wrote it.
+func setField(data []byte, key string, value any) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("expected a mapping")
+	}
+
+	root := doc.Content[0]
+	for i := 0; i < len(root.Content)-1; i += 2 {
+		if root.Content[i].Value == key {
+			if err := root.Content[i+1].Encode(value); err != nil {
+				return nil, err
+			}
+			return encodeYAML(&doc)
+		}
+	}
+
+	appended, err := encodeYAML(map[string]any{key: value})
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > 0 && !bytes.HasSuffix(data, []byte("\n")) {
+		data = append(data, '\n')
+	}
+	return append(data, appended...), nil
+}
+
+// encodeYAML encodes v indented like the template.yaml files of devinit
+func encodeYAML(v any) ([]byte, error) {
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,265 @@
+package importer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/renan-dev/devinit/internal/archive"
+	"github.com/renan-dev/devinit/internal/credentials"
+)
+
+// ErrAWSMissing is returned when an s3:// source is imported without the
+// AWS CLI
+var ErrAWSMissing = errors.New("the AWS CLI (aws) is not installed")
+
+// archiveSuffixes end the paths of archive sources
+var archiveSuffixes = []string{".tar.gz", ".tgz"}
+
+// checksumPattern is the format of the checksum pinning an archive
+var checksumPattern = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+
+// IsArchive reports whether source names a template archive: an https://,
+// http:// or s3:// URL of a .tar.gz or .tgz file. parseArchiveSource
+// refuses http:// URLs not pinned to a checksum.
+func IsArchive(source string) bool {
+	scheme, rest, ok := strings.Cut(source, "://")
+	if !ok || (scheme != "https" && scheme != "http" && scheme != "s3") {
+		return false
+	}
+	rest, _, _ = strings.Cut(rest, "?")
+	rest, _, _ = strings.Cut(rest, "//")
+	for _, suffix := range archiveSuffixes {
+		if strings.HasSuffix(rest, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// archiveSource is an archive source split into the URL to download, the
+// subdirectory of the template in the archive and the checksum pinning it
+type archiveSource struct {
+	url      string
+	subdir   string
+	checksum string
+}
+
+// parseArchiveSource parses url[//subdir][?checksum=sha256:<hex>]. Other
+// query parameters, such as the signature of a presigned URL, stay in the
+// URL.
+func parseArchiveSource(source string) (archiveSource, error) {
+	var src archiveSource
+	rest, query, _ := strings.Cut(source, "?")
+
+	var kept []string
+	for _, param := range strings.Split(query, "&") {
+		value, ok := strings.CutPrefix(param, "checksum=")
+		if !ok {
+			if param != "" {
+				kept = append(kept, param)
+			}
+			continue
+		}
+		checksum, err := url.QueryUnescape(value)
+		if err != nil || !checksumPattern.MatchString(checksum) {
+			return src, fmt.Errorf("invalid source %q: checksum must be sha256:<64 hex digits>", source)
+		}
+		src.checksum = checksum
+	}
+
+	scheme, rest, _ := strings.Cut(rest, "://")
+	// Nothing vouches for what a plain HTTP server sends but the checksum
+	if scheme == "http" && src.checksum == "" {
+		return src, fmt.Errorf("invalid source %q: http:// archives must be pinned with ?checksum=sha256:<hex>; use https:// otherwise", source)
+	}
+	if location, subdir, ok := strings.Cut(rest, "//"); ok {
+		src.subdir = path.Clean("/" + subdir)[1:]
+		if src.subdir == "" {
+			return src, fmt.Errorf("invalid source %q: empty subdirectory", source)
+		}
+		rest = location
+	}
+	src.url = scheme + "://" + rest
+	if len(kept) > 0 {
+		src.url += "?" + strings.Join(kept, "&")
+	}
+	return src, nil
+}
+
+// runAWS runs the AWS CLI; tests replace it
+var runAWS = func(ctx context.Context, args ...string) error {
+	out, err := exec.CommandContext(ctx, "aws", args...).CombinedOutput()
+	if err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return ErrAWSMissing
+		}
+		return fmt.Errorf("aws %s: %w: %s", strings.Join(args[:2], " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// download downloads and unpacks the archive of source into a temporary
+// directory of opts.CloneDir, pinned to the checksum of the archive, which
+// must be the one in the source when it has one. An archive holding a
+// single directory, such as the archives of git hosts, unpacks to that
+// directory.
+func download(ctx context.Context, opts Options) (*fetched, error) {
+	if opts.Versions {
+		return nil, fmt.Errorf("%s is an archive: import the version tags of git repositories only", opts.Source)
+	}
+	src, err := parseArchiveSource(opts.Source)
+	if err != nil {
+		return nil, err
+	}
+
+	tmp, err := tempDir(opts.CloneDir)
+	if err != nil {
+		return nil, err
+	}
+	f := &fetched{subdir: src.subdir, cleanup: func() { os.RemoveAll(tmp) }}
+	if err := f.unpack(ctx, opts.HTTPClient, src, tmp); err != nil {
+		f.cleanup()
+		return nil, err
+	}
+	return f, nil
+}
+
+// unpack downloads the archive of src to tmp and unpacks it there
+func (f *fetched) unpack(ctx context.Context, client *http.Client, src archiveSource, tmp string) error {
+	file := filepath.Join(tmp, "template.tar.gz")
+	var err error
+	if strings.HasPrefix(src.url, "s3://") {
+		err = runAWS(ctx, "s3", "cp", "--only-show-errors", src.url, file)
+	} else {
+		err = fetchURL(ctx, client, src.url, file)
+	}
+	if err != nil {
+		return err
+	}
+
+	if f.digest, err = fileChecksum(file); err != nil {
+		return err
+	}
+	switch {
+	case src.checksum == "":
+		f.warnings = append(f.warnings, fmt.Sprintf("the archive is not pinned; add ?checksum=%s to the source to refuse any other", f.digest))
+	case f.digest != src.checksum:
+		return fmt.Errorf("checksum of %s is %s, not the pinned %s", redact(src.url), f.digest, src.checksum)
+	}
+
+	f.repo = filepath.Join(tmp, "template")
+	r, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	err = archive.Unpack(r, f.repo, 0)
+	r.Close()
+	if err != nil {
+		return fmt.Errorf("%s: %w", redact(src.url), err)
+	}
+	if entries, err := os.ReadDir(f.repo); err == nil && len(entries) == 1 && entries[0].IsDir() {
+		f.repo = filepath.Join(f.repo, entries[0].Name())
+	}
+
+	if info, err := os.Stat(filepath.Join(f.repo, f.subdir)); err != nil || !info.IsDir() {
+		return fmt.Errorf("%s has no directory %s", redact(src.url), f.subdir)
+	}
+	return nil
+}
+
+// fetchURL downloads url to file, with the credentials of its host over
+// HTTPS unless the URL has its own. Tokens are never sent in clear text,
+// not even after a redirect.
+func fetchURL(ctx context.Context, client *http.Client, url, file string) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	// The client drops the header on redirects to other hosts, but not on
+	// redirects to plain HTTP on the same one
+	if req.URL.User == nil && req.URL.Scheme == "https" {
+		creds, err := credentials.Lookup(req.URL.Host)
+		if err != nil {
+			return err
+		}
+		if creds != nil {
+			req.Header.Set("Authorization", creds.Header())
+			secure := *client
+			secure.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+				if req.URL.Scheme != "https" {
+					return fmt.Errorf("refusing to send the credentials of %s to %s", via[0].URL.Host, redact(req.URL.String()))
+				}
+				if client.CheckRedirect != nil {
+					return client.CheckRedirect(req, via)
+				}
+				if len(via) >= 10 {
+					return errors.New("stopped after 10 redirects")
+				}
+				return nil
+			}
+			client = &secure
+		}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download %s: %s", redact(url), resp.Status)
+	}
+
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(f, io.LimitReader(resp.Body, archive.MaxSize+1))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && n > archive.MaxSize {
+		err = fmt.Errorf("%s is larger than %d bytes", redact(url), archive.MaxSize)
+	}
+	return err
+}
+
+// redact leaves the query, which may hold the signature of a presigned URL,
+// and any password out of URLs in messages
+func redact(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		rawURL, _, _ = strings.Cut(rawURL, "?")
+		return rawURL
+	}
+	u.RawQuery, u.User = "", nil
+	return u.String()
+}
+
+// fileChecksum returns the sha256 checksum of a file
+func fileChecksum(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil)), nil
+}
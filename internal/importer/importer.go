@@ -0,0 +1,659 @@
+// Package importer converts templates written for other scaffolding tools
+// into devinit templates rendered with the jinja2 engine.
+//
+// Each format has a converter: cookiecutter (cookiecutter.json) and copier
+// (copier.yml). A converter declares the variables of the template, copies
+// its files to files/ with their paths translated into destinations and
+// conditions, and turns hooks or tasks into post_generate hooks. What
+// cannot be converted is reported as a warning rather than an error, so a
+// mostly convertible template can be imported and finished by hand. Yeoman
+// generators are JavaScript programs rather than templates and are refused.
+// Templates that already are devinit templates are copied as they are.
+//
+// A git source may name a subdirectory of the repository and a ref, as in
+// github.com/org/platform//templates/python/fastapi?ref=v3; only that
+// subdirectory is checked out, so importing one template of a large
+// monorepo does not download the files of the others.
+//
+// Templates pushed to a container registry with devinit templates push are
+// pulled from oci:// sources, pinned to the digest of their manifest, and
+// templates published as archives to an artifact store are downloaded from
+// https:// or s3:// URLs, pinned to their checksum (which http:// URLs
+// require). Private sources are
+// reached with the credentials of package credentials.
+//
+// The version tags of a git repository can be imported too, each as a
+// version of the template under versions/, so devinit templates diff can
+// compare them.
+package importer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/renan-dev/devinit/internal/template"
+)
+
+// Format is a kind of template Import converts
+type Format string
+
+const (
+	FormatCookiecutter Format = "cookiecutter"
+	FormatCopier       Format = "copier"
+	FormatDevinit      Format = "devinit"
+)
+
+// Formats lists the formats Import converts
+var Formats = []Format{FormatCookiecutter, FormatCopier, FormatDevinit}
+
+var (
+	// ErrGitMissing is returned when a git source is imported without git
+	ErrGitMissing = errors.New("git is not installed")
+
+	// ErrExists is returned when the destination of an import already
+	// exists and Force is not set
+	ErrExists = errors.New("destination already exists")
+
+	// ErrUnsupported is returned for sources no converter handles
+	ErrUnsupported = errors.New("unsupported template")
+)
+
+// Options configures an import
+type Options struct {
+	// Source is a local directory or a git repository: a URL, a git@
+	// address, a gh:, gl: or bb: abbreviation as cookiecutter and copier
+	// accept, or a github.com/, gitlab.com/ or bitbucket.org/ path. A git
+	// source may end in //subdir for a template in a subdirectory of the
+	// repository and in ?ref= for the branch, tag or commit to import.
+	// oci://registry/repository:tag names a template pushed to a registry,
+	// and an https:// or s3:// URL of a .tar.gz archive, which may end in
+	// //subdir too and in ?checksum=sha256:<hex> pinning it, a template
+	// published to an artifact store.
+	Source string
+
+	// Format of the source; empty detects it
+	Format Format
+
+	// Dir is the directory the devinit template is written to. When empty
+	// it is <TemplatesDir>/<Language>/<Framework>.
+	Dir          string
+	TemplatesDir string
+
+	// Language and Framework identify the template in template.yaml; an
+	// empty Language is the name of the format, or the language of a
+	// devinit template
+	Language  string
+	Framework string
+
+	// Versions imports the version tags (1.2.0, v1.2.0) of a git source:
+	// the newest becomes the template and the others its versions/
+	Versions bool
+
+	// Force replaces an existing Dir
+	Force bool
+
+	// CloneDir is where git sources are cloned, and other remote sources
+	// downloaded, while they are imported (default: the system temporary
+	// directory)
+	CloneDir string
+
+	// HTTPClient downloads OCI sources and archives (default:
+	// http.DefaultClient)
+	HTTPClient *http.Client
+
+	// GitCredentialHelper is a git credential helper (see
+	// gitcredentials(7)) asked for the credentials of git sources after
+	// the helpers of the git configuration, such as devinit itself
+	GitCredentialHelper string
+}
+
+// Result describes an imported template
+type Result struct {
+	Format    Format
+	Language  string
+	Framework string
+	Dir       string
+	Version   string
+
+	// Versions are the older versions imported under versions/
+	Versions []string
+
+	// Variables are the declared variables, in the order of the source
+	Variables []string
+	Files     int
+	Hooks     int
+
+	// Warnings are the parts of the template that were not converted
+	Warnings []string
+}
+
+// DefaultVersion is the version of templates converted without tags
+const DefaultVersion = "1.0.0"
+
+// manifest is the template.yaml written by an import. It mirrors
+// template.Template with only the fields an import fills in.
+type manifest struct {
+	Version     string                       `yaml:"version"`
+	Name        string                       `yaml:"name"`
+	Description string                       `yaml:"description"`
+	Language    string                       `yaml:"language"`
+	Framework   string                       `yaml:"framework"`
+	Engine      string                       `yaml:"engine"`
+	Variables   map[string]template.Variable `yaml:"variables,omitempty"`
+	Files       []template.FileSpec          `yaml:"files"`
+	Hooks       *template.Hooks              `yaml:"hooks,omitempty"`
+	NextSteps   []string                     `yaml:"next_steps,omitempty"`
+	Origin      *template.Origin             `yaml:"origin,omitempty"`
+}
+
+// gitEnvKey is the context key of the environment git runs with
+type gitEnvKey struct{}
+
+// withGitEnv returns a context running git with env added to its
+// environment
+func withGitEnv(ctx context.Context, env ...string) context.Context {
+	return context.WithValue(ctx, gitEnvKey{}, env)
+}
+
+// credentialHelperEnv returns the environment configuring helper as a
+// git credential helper, asked after the ones of the git configuration
+func credentialHelperEnv(helper string) []string {
+	n, _ := strconv.Atoi(os.Getenv("GIT_CONFIG_COUNT"))
+	return []string{
+		fmt.Sprintf("GIT_CONFIG_COUNT=%d", n+1),
+		fmt.Sprintf("GIT_CONFIG_KEY_%d=credential.helper", n),
+		fmt.Sprintf("GIT_CONFIG_VALUE_%d=%s", n, helper),
+	}
+}
+
+// runGit runs git and returns its output; tests replace it
+var runGit = func(ctx context.Context, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	if env, _ := ctx.Value(gitEnvKey{}).([]string); len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, ErrGitMissing
+		}
+		return nil, fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+	return out, nil
+}
+
+// Import converts the template at opts.Source into a devinit template in
+// opts.Dir
+func Import(ctx context.Context, opts Options) (*Result, error) {
+	if opts.Format != "" && !knownFormat(opts.Format) {
+		return nil, fmt.Errorf("%w: unknown format %q", ErrUnsupported, opts.Format)
+	}
+	if opts.GitCredentialHelper != "" {
+		ctx = withGitEnv(ctx, credentialHelperEnv(opts.GitCredentialHelper)...)
+	}
+
+	var (
+		f   *fetched
+		err error
+	)
+	switch {
+	case IsOCI(opts.Source):
+		f, err = pull(ctx, opts)
+	case IsArchive(opts.Source):
+		f, err = download(ctx, opts)
+	default:
+		f, err = fetch(ctx, opts.Source, opts.Versions, opts.CloneDir)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.cleanup()
+	repo, subdir := f.repo, f.subdir
+	source := filepath.Join(repo, subdir)
+
+	format := opts.Format
+	if format == "" {
+		if format, err = Detect(source); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Language == "" {
+		opts.Language = string(format)
+		if language := devinitTemplate(source).Language; format == FormatDevinit && language != "" {
+			opts.Language = language
+		}
+	}
+	if opts.Framework == "" && format == FormatDevinit {
+		opts.Framework = devinitTemplate(source).Framework
+	}
+	if opts.Dir == "" {
+		if opts.Framework == "" {
+			return nil, fmt.Errorf("no framework to import %s as", opts.Source)
+		}
+		opts.Dir = filepath.Join(opts.TemplatesDir, opts.Language, opts.Framework)
+	}
+
+	if _, err := os.Stat(opts.Dir); err == nil {
+		if !opts.Force {
+			return nil, fmt.Errorf("%s: %w", opts.Dir, ErrExists)
+		}
+		if err := os.RemoveAll(opts.Dir); err != nil {
+			return nil, err
+		}
+	}
+
+	var tags []versionTag
+	if opts.Versions {
+		if tags, err = versionTags(ctx, repo); err != nil {
+			return nil, err
+		}
+	}
+	if len(tags) == 0 {
+		version := DefaultVersion
+		if v := devinitTemplate(source).Version; format == FormatDevinit && v != "" {
+			version = v
+		}
+		c, err := convert(source, format, opts, opts.Dir, version, origin(ctx, opts.Source, repo, f.digest))
+		if err != nil {
+			return nil, err
+		}
+		result := c.result(format, opts.Language, opts.Framework, opts.Dir, version)
+		result.Warnings = append(result.Warnings, f.warnings...)
+		if opts.Versions {
+			result.Warnings = append(result.Warnings, "no version tags found; the current files were imported")
+		}
+		return result, nil
+	}
+
+	// The newest tag is the template; older ones are its versions
+	var (
+		result  *Result
+		skipped []string
+	)
+	for i := len(tags) - 1; i >= 0; i-- {
+		tag := tags[i]
+		dir := opts.Dir
+		if result != nil {
+			dir = filepath.Join(opts.Dir, "versions", tag.version)
+		}
+
+		tree, done, err := checkout(ctx, repo, tag.name, IsRemote(opts.Source), opts.CloneDir)
+		if err != nil {
+			os.RemoveAll(opts.Dir)
+			return nil, err
+		}
+		// Tags from before the template moved into its subdirectory
+		if !exists(filepath.Join(tree, subdir)) {
+			done()
+			skipped = append(skipped, fmt.Sprintf("version %s has no %s; it is skipped", tag.name, subdir))
+			continue
+		}
+		c, err := convert(filepath.Join(tree, subdir), format, opts, dir, tag.version, origin(ctx, opts.Source, tree, ""))
+		done()
+		if err != nil {
+			os.RemoveAll(opts.Dir)
+			return nil, fmt.Errorf("version %s: %w", tag.name, err)
+		}
+
+		if result == nil {
+			result = c.result(format, opts.Language, opts.Framework, opts.Dir, tag.version)
+			continue
+		}
+		result.Versions = append(result.Versions, tag.version)
+	}
+	if result == nil {
+		return nil, fmt.Errorf("no version tag has %s", subdir)
+	}
+	result.Warnings = append(result.Warnings, skipped...)
+	return result, nil
+}
+
+// Detect returns the format of the template in dir
+func Detect(dir string) (Format, error) {
+	if exists(filepath.Join(dir, devinitFile)) {
+		return FormatDevinit, nil
+	}
+	if exists(filepath.Join(dir, cookiecutterFile)) {
+		return FormatCookiecutter, nil
+	}
+	for _, name := range copierFiles {
+		if exists(filepath.Join(dir, name)) {
+			return FormatCopier, nil
+		}
+	}
+
+	if data, err := os.ReadFile(filepath.Join(dir, "package.json")); err == nil && bytes.Contains(data, []byte(`"yeoman-generator"`)) {
+		return "", fmt.Errorf("%w: Yeoman generators are JavaScript programs whose prompts and files are computed at run time, so they cannot be converted; port the templates of generators/*/templates by hand", ErrUnsupported)
+	}
+	return "", fmt.Errorf("%w: %s has none of %s, %s and %s", ErrUnsupported, dir, cookiecutterFile, copierFiles[0], devinitFile)
+}
+
+func knownFormat(format Format) bool {
+	for _, f := range Formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// convert converts the template in source into dir
+func convert(source string, format Format, opts Options, dir, version string, origin *template.Origin) (*converter, error) {
+	if format == FormatDevinit {
+		c := newConverter(source, filepath.Join(dir, "files"))
+		if err := c.devinit(dir, version, origin); err != nil {
+			os.RemoveAll(dir)
+			return nil, err
+		}
+		return c, nil
+	}
+
+	m := &manifest{
+		Version:     version,
+		Name:        opts.Framework,
+		Description: fmt.Sprintf("Imported from %s template %s", format, opts.Source),
+		Language:    opts.Language,
+		Framework:   opts.Framework,
+		Engine:      template.EngineJinja2,
+		Origin:      origin,
+	}
+
+	c := newConverter(source, filepath.Join(dir, "files"))
+	var err error
+	switch format {
+	case FormatCookiecutter:
+		err = c.cookiecutter(m)
+	case FormatCopier:
+		err = c.copier(m)
+	}
+	if err == nil {
+		c.declare(m)
+		err = writeManifest(filepath.Join(dir, "template.yaml"), opts.Source, m)
+	}
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	c.files = len(m.Files)
+	if m.Hooks != nil {
+		c.hooks = len(m.Hooks.PostGenerate)
+	}
+	return c, nil
+}
+
+// writeManifest writes template.yaml
+func writeManifest(path, source string, m *manifest) error {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# Imported from %s by devinit templates import\n", source)
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(m); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, b.Bytes(), 0644)
+}
+
+// hostPrefixes are the hosts whose repositories may be named without a
+// scheme, as in github.com/org/repo
+var hostPrefixes = []string{"github.com/", "gitlab.com/", "bitbucket.org/"}
+
+// IsRemote reports whether source names a git repository rather than a
+// local directory
+func IsRemote(source string) bool {
+	for _, prefix := range append([]string{"gh:", "gl:", "bb:", "git@", "git+"}, hostPrefixes...) {
+		if strings.HasPrefix(source, prefix) {
+			return true
+		}
+	}
+	return strings.Contains(source, "://")
+}
+
+// gitSource is a remote source split into the repository to clone, the
+// subdirectory of the template in it and the ref to check out
+type gitSource struct {
+	repo   string
+	subdir string
+	ref    string
+}
+
+// parseGitSource parses repo[//subdir][?ref=ref]
+func parseGitSource(source string) (gitSource, error) {
+	var src gitSource
+	if rest, query, ok := strings.Cut(source, "?"); ok {
+		values, err := url.ParseQuery(query)
+		if err != nil {
+			return src, fmt.Errorf("invalid source %q: %w", source, err)
+		}
+		for key := range values {
+			if key != "ref" {
+				return src, fmt.Errorf("invalid source %q: unknown parameter %s, want ref", source, key)
+			}
+		}
+		if src.ref = values.Get("ref"); src.ref == "" {
+			return src, fmt.Errorf("invalid source %q: empty ref", source)
+		}
+		source = rest
+	}
+
+	// The // of the subdirectory is the first after the scheme
+	start := 0
+	if i := strings.Index(source, "://"); i >= 0 {
+		start = i + len("://")
+	}
+	if i := strings.Index(source[start:], "//"); i >= 0 {
+		subdir := path.Clean("/" + source[start+i+2:])[1:]
+		if subdir == "" {
+			return src, fmt.Errorf("invalid source %q: empty subdirectory", source)
+		}
+		src.subdir = subdir
+		source = source[:start+i]
+	}
+	src.repo = expandAbbreviation(source)
+	return src, nil
+}
+
+// fetched is a source ready to import
+type fetched struct {
+	// repo is the git repository, or the directory a template was pulled
+	// or unpacked to
+	repo string
+	// subdir is the directory of the template in repo
+	subdir string
+	// digest pins pulled artifacts and downloaded archives
+	digest string
+	// warnings are about the source rather than the template
+	warnings []string
+	// cleanup removes what was cloned or downloaded
+	cleanup func()
+}
+
+// fetch returns the repository of source and the subdirectory of the
+// template in it. Remote repositories are cloned into a temporary
+// directory of cloneDir: only the latest commit unless history is needed
+// for the version tags, and only the subdirectory, with a sparse checkout
+// of a partial clone, when there is one.
+func fetch(ctx context.Context, source string, history bool, cloneDir string) (*fetched, error) {
+	if !IsRemote(source) {
+		info, err := os.Stat(source)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("%s is not a directory", source)
+		}
+		return &fetched{repo: source, cleanup: func() {}}, nil
+	}
+
+	src, err := parseGitSource(source)
+	if err != nil {
+		return nil, err
+	}
+	if history && src.ref != "" {
+		return nil, fmt.Errorf("%s imports the ref %s only; drop ?ref= to import every version tag", source, src.ref)
+	}
+
+	tmp, err := tempDir(cloneDir)
+	if err != nil {
+		return nil, err
+	}
+	cleanup := func() { os.RemoveAll(tmp) }
+
+	// Commits are checked out after the clone, which only takes branches
+	// and tags
+	commit := commitPattern.MatchString(src.ref)
+	args := []string{"clone", "--quiet"}
+	if !history && !commit {
+		args = append(args, "--depth", "1")
+	}
+	if src.subdir != "" {
+		args = append(args, "--filter=blob:none", "--sparse")
+	}
+	if src.ref != "" && !commit {
+		args = append(args, "--branch", src.ref)
+	}
+	steps := [][]string{append(args, src.repo, tmp)}
+	if src.subdir != "" {
+		steps = append(steps, []string{"-C", tmp, "sparse-checkout", "set", "--", src.subdir})
+	}
+	if commit {
+		steps = append(steps, []string{"-C", tmp, "checkout", "--quiet", src.ref})
+	}
+	for _, step := range steps {
+		if _, err := runGit(ctx, step...); err != nil {
+			cleanup()
+			return nil, err
+		}
+	}
+
+	if info, err := os.Stat(filepath.Join(tmp, src.subdir)); err != nil || !info.IsDir() {
+		cleanup()
+		return nil, fmt.Errorf("%s has no directory %s", src.repo, src.subdir)
+	}
+	return &fetched{repo: tmp, subdir: src.subdir, cleanup: cleanup}, nil
+}
+
+// commitPattern matches refs that are commit hashes
+var commitPattern = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+
+// origin returns the origin of a template imported from a remote source,
+// cloned into dir, with the commit checked out, or pinned to the digest of
+// a pulled artifact or downloaded archive; nil for local sources
+func origin(ctx context.Context, source, dir, digest string) *template.Origin {
+	if !IsRemote(source) {
+		return nil
+	}
+	o := &template.Origin{Source: source}
+	if digest != "" {
+		o.Commit = digest
+	} else if out, err := runGit(ctx, "-C", dir, "rev-parse", "HEAD"); err == nil {
+		o.Commit = strings.TrimSpace(string(out))
+	}
+	return o
+}
+
+// expandAbbreviation expands the gh:, gl: and bb: abbreviations and the
+// paths of known hosts, and strips the git+ prefix of sources
+func expandAbbreviation(source string) string {
+	for prefix, host := range map[string]string{"gh:": "https://github.com/", "gl:": "https://gitlab.com/", "bb:": "https://bitbucket.org/"} {
+		if strings.HasPrefix(source, prefix) {
+			return host + strings.TrimPrefix(source, prefix)
+		}
+	}
+	for _, prefix := range hostPrefixes {
+		if strings.HasPrefix(source, prefix) {
+			return "https://" + source
+		}
+	}
+	return strings.TrimPrefix(source, "git+")
+}
+
+// versionTag is a git tag naming a version
+type versionTag struct {
+	name    string // e.g. v1.2.0
+	version string // e.g. 1.2.0
+}
+
+// versionTags returns the tags of the repository in dir that are
+// versions, oldest first
+func versionTags(ctx context.Context, dir string) ([]versionTag, error) {
+	out, err := runGit(ctx, "-C", dir, "tag", "--list")
+	if err != nil {
+		return nil, err
+	}
+	return parseVersionTags(strings.Fields(string(out))), nil
+}
+
+// parseVersionTags returns the tags of names that are versions, oldest
+// first
+func parseVersionTags(names []string) []versionTag {
+	var tags []versionTag
+	for _, name := range names {
+		version := strings.TrimPrefix(name, "v")
+		if _, err := template.CompareVersions(version, version); err == nil && strings.Count(version, ".") == 2 {
+			tags = append(tags, versionTag{name: name, version: version})
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool {
+		cmp, _ := template.CompareVersions(tags[i].version, tags[j].version)
+		return cmp < 0
+	})
+	return tags
+}
+
+// checkout checks out a tag of the repository in dir and returns the
+// tree, and done to call once the tree is imported. A clone of the import
+// is checked out in place, which keeps its sparse checkout; any other
+// repository is cloned into a temporary directory of cloneDir, removed by
+// done.
+func checkout(ctx context.Context, dir, tag string, clone bool, cloneDir string) (tree string, done func(), err error) {
+	if clone {
+		if _, err := runGit(ctx, "-C", dir, "checkout", "--quiet", tag); err != nil {
+			return "", nil, err
+		}
+		return dir, func() {}, nil
+	}
+
+	tmp, err := tempDir(cloneDir)
+	if err != nil {
+		return "", nil, err
+	}
+	if _, err := runGit(ctx, "clone", "--quiet", "--branch", tag, dir, tmp); err != nil {
+		os.RemoveAll(tmp)
+		return "", nil, err
+	}
+	return tmp, func() { os.RemoveAll(tmp) }, nil
+}
+
+// tempDir creates a directory for a clone in cloneDir, or in the system
+// temporary directory when cloneDir is empty
+func tempDir(cloneDir string) (string, error) {
+	if cloneDir != "" {
+		if err := os.MkdirAll(cloneDir, 0755); err != nil {
+			return "", err
+		}
+	}
+	return os.MkdirTemp(cloneDir, "devinit-import-")
+}
@@ -0,0 +1,445 @@
+package importer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/renan-dev/devinit/internal/archive"
+	"github.com/renan-dev/devinit/internal/credentials"
+	"github.com/renan-dev/devinit/internal/oci"
+	"github.com/renan-dev/devinit/internal/template"
+)
+
+// writeSource writes the files of a template to import to dir
+func writeSource(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		wantErr string
+	}{
+		{name: "unknown format", files: map[string]string{"README.md": "x"}, wantErr: "has none of cookiecutter.json, copier.yml and template.yaml"},
+		{name: "yeoman", files: map[string]string{"package.json": `{"dependencies": {"yeoman-generator": "^7.0.0"}}`}, wantErr: "Yeoman generators are JavaScript programs"},
+		{name: "invalid json", files: map[string]string{"cookiecutter.json": "[1]"}, wantErr: "expected an object"},
+		{name: "no template directory", files: map[string]string{"cookiecutter.json": "{}", "src/a.py": "x"}, wantErr: "no {{ cookiecutter.* }} directory"},
+		{
+			name:    "several template directories",
+			files:   map[string]string{"cookiecutter.json": `{"a": "x"}`, "{{cookiecutter.a}}/a": "x", "{{cookiecutter.a}}-b/b": "x"},
+			wantErr: "several {{ cookiecutter.* }} directories",
+		},
+		{name: "templated copier subdirectory", files: map[string]string{"copier.yml": "_subdirectory: \"{{ kind }}\"\nkind: api\n"}, wantErr: "_subdirectory"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := t.TempDir()
+			writeSource(t, source, tt.files)
+			_, err := Import(context.Background(), Options{Source: source, Dir: filepath.Join(t.TempDir(), "out")})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Import() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFetchRemote(t *testing.T) {
+	var cloned string
+	original := runGit
+	t.Cleanup(func() { runGit = original })
+	runGit = func(ctx context.Context, args ...string) ([]byte, error) {
+		if args[0] == "-C" {
+			return []byte("4f2c9e1\n"), nil
+		}
+		cloned = args[len(args)-2]
+		writeSource(t, args[len(args)-1], map[string]string{"cookiecutter.json": `{"name": "x"}`, "{{cookiecutter.name}}/a.txt": "a\n"})
+		return nil, nil
+	}
+
+	dir := filepath.Join(t.TempDir(), "out")
+	if _, err := Import(context.Background(), Options{Source: "gh:acme/cookiecutter-api", Dir: dir}); err != nil {
+		t.Fatalf("Import() unexpected error: %v", err)
+	}
+	if cloned != "https://github.com/acme/cookiecutter-api" {
+		t.Errorf("cloned %s, want https://github.com/acme/cookiecutter-api", cloned)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "template.yaml"))
+	if err != nil || !strings.Contains(string(data), "origin:\n  source: gh:acme/cookiecutter-api\n  commit: 4f2c9e1\n") {
+		t.Errorf("template.yaml = %s, %v, want the origin pinned to the commit", data, err)
+	}
+}
+
+func TestParseGitSource(t *testing.T) {
+	tests := []struct {
+		source  string
+		want    gitSource
+		wantErr bool
+	}{
+		{source: "gh:acme/api", want: gitSource{repo: "https://github.com/acme/api"}},
+		{source: "github.com/acme/platform//templates/python/fastapi?ref=v3", want: gitSource{repo: "https://github.com/acme/platform", subdir: "templates/python/fastapi", ref: "v3"}},
+		{source: "https://gitlab.com/acme/platform.git//templates/go/", want: gitSource{repo: "https://gitlab.com/acme/platform.git", subdir: "templates/go"}},
+		{source: "git@github.com:acme/platform.git//api?ref=4f2c9e1", want: gitSource{repo: "git@github.com:acme/platform.git", subdir: "api", ref: "4f2c9e1"}},
+		{source: "gh:acme/platform//../../etc", want: gitSource{repo: "https://github.com/acme/platform", subdir: "etc"}},
+		{source: "gh:acme/platform//", wantErr: true},
+		{source: "gh:acme/platform?ref=", wantErr: true},
+		{source: "gh:acme/platform?depth=1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseGitSource(tt.source)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseGitSource(%q) error = %v, wantErr %v", tt.source, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("parseGitSource(%q) = %+v, want %+v", tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestCredentialHelperEnv(t *testing.T) {
+	t.Setenv("GIT_CONFIG_COUNT", "1")
+	got := strings.Join(credentialHelperEnv("!devinit templates git-credential"), "\n")
+	want := "GIT_CONFIG_COUNT=2\nGIT_CONFIG_KEY_1=credential.helper\nGIT_CONFIG_VALUE_1=!devinit templates git-credential"
+	if got != want {
+		t.Errorf("credentialHelperEnv() = %s, want the helper after the configured entry", got)
+	}
+}
+
+func TestFetchSubdirectory(t *testing.T) {
+	var calls []string
+	original := runGit
+	t.Cleanup(func() { runGit = original })
+	runGit = func(ctx context.Context, args ...string) ([]byte, error) {
+		switch args[0] {
+		case "clone":
+			writeSource(t, args[len(args)-1], map[string]string{"README.md": "monorepo\n"})
+			args = args[:len(args)-1]
+		case "-C":
+			if args[2] == "rev-parse" {
+				calls = append(calls, "rev-parse HEAD")
+				return []byte("4f2c9e1\n"), nil
+			}
+			// Only the subdirectory is checked out
+			writeSource(t, args[1], map[string]string{
+				"templates/python/fastapi/template.yaml":      "# FastAPI service\nversion: 3.0.0\nlanguage: python\nframework: fastapi\nvariables:\n  port:\n    type: int\nfiles:\n  - src: main.py.tmpl\n    dest: main.py\n",
+				"templates/python/fastapi/files/main.py.tmpl": "app = {{ .ProjectName }}\n",
+				"templates/python/fastapi/versions/2.0.0/x":   "older\n",
+			})
+			args = args[2:]
+		}
+		calls = append(calls, strings.Join(args, " "))
+		return nil, nil
+	}
+
+	dir := filepath.Join(t.TempDir(), "out")
+	source := "github.com/acme/platform//templates/python/fastapi?ref=v3"
+	result, err := Import(context.Background(), Options{Source: source, Dir: dir})
+	if err != nil {
+		t.Fatalf("Import() unexpected error: %v", err)
+	}
+
+	want := []string{
+		"clone --quiet --depth 1 --filter=blob:none --sparse --branch v3 https://github.com/acme/platform",
+		"sparse-checkout set -- templates/python/fastapi",
+		"rev-parse HEAD",
+	}
+	if strings.Join(calls, "\n") != strings.Join(want, "\n") {
+		t.Errorf("git calls:\n%s\nwant:\n%s", strings.Join(calls, "\n"), strings.Join(want, "\n"))
+	}
+	if result.Format != FormatDevinit || result.Language != "python" || result.Version != "3.0.0" || result.Files != 1 {
+		t.Errorf("Result = %+v, want devinit template python 3.0.0 with 1 file", result)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "template.yaml"))
+	wantManifest := "# FastAPI service\nversion: 3.0.0\n"
+	wantOrigin := "origin:\n  source: " + source + "\n  commit: 4f2c9e1\n"
+	if err != nil || !strings.HasPrefix(string(data), wantManifest) || !strings.HasSuffix(string(data), wantOrigin) {
+		t.Errorf("template.yaml = %s, %v, want it kept with the origin appended", data, err)
+	}
+	if !exists(filepath.Join(dir, "files", "main.py.tmpl")) || exists(filepath.Join(dir, "versions")) || exists(filepath.Join(dir, "README.md")) {
+		t.Errorf("want the template copied without its versions nor the rest of the repository")
+	}
+}
+
+func TestImportOCI(t *testing.T) {
+	var pulled string
+	original := pullArtifact
+	t.Cleanup(func() { pullArtifact = original })
+	pullArtifact = func(ctx context.Context, client *http.Client, ref oci.Reference, dest string) (string, error) {
+		pulled = ref.String()
+		writeSource(t, dest, map[string]string{
+			"template.yaml":      "version: 1.2.0\nlanguage: go\nframework: cli\nfiles:\n  - src: main.go.tmpl\n    dest: main.go\n",
+			"files/main.go.tmpl": "package main\n",
+		})
+		return "sha256:4f2c", nil
+	}
+
+	templatesDir := t.TempDir()
+	source := "oci://ghcr.io/acme/templates/go-cli:1.2.0"
+	result, err := Import(context.Background(), Options{Source: source, TemplatesDir: templatesDir})
+	if err != nil {
+		t.Fatalf("Import() unexpected error: %v", err)
+	}
+	if pulled != "ghcr.io/acme/templates/go-cli:1.2.0" {
+		t.Errorf("pulled %s, want ghcr.io/acme/templates/go-cli:1.2.0", pulled)
+	}
+	if want := filepath.Join(templatesDir, "go", "cli"); result.Dir != want || result.Version != "1.2.0" {
+		t.Errorf("Dir, Version = %s, %s, want the template's own %s, 1.2.0", result.Dir, result.Version, want)
+	}
+	data, err := os.ReadFile(filepath.Join(result.Dir, "template.yaml"))
+	if err != nil || !strings.HasSuffix(string(data), "origin:\n  source: "+source+"\n  commit: sha256:4f2c\n") {
+		t.Errorf("template.yaml = %s, %v, want the origin pinned to the digest", data, err)
+	}
+
+	if _, err := Import(context.Background(), Options{Source: source, TemplatesDir: templatesDir, Versions: true}); err == nil {
+		t.Errorf("Import() of the versions of an OCI source succeeded")
+	}
+}
+
+func TestParseArchiveSource(t *testing.T) {
+	checksum := "sha256:" + strings.Repeat("ab", 32)
+	tests := []struct {
+		source  string
+		want    archiveSource
+		wantErr bool
+	}{
+		{source: "https://artifacts.acme.dev/fastapi-3.1.0.tar.gz", want: archiveSource{url: "https://artifacts.acme.dev/fastapi-3.1.0.tar.gz"}},
+		{source: "s3://acme-templates/platform.tgz//python/fastapi?checksum=" + checksum, want: archiveSource{url: "s3://acme-templates/platform.tgz", subdir: "python/fastapi", checksum: checksum}},
+		{source: "https://acme.s3.amazonaws.com/api.tar.gz?X-Amz-Signature=4f2c&checksum=" + checksum, want: archiveSource{url: "https://acme.s3.amazonaws.com/api.tar.gz?X-Amz-Signature=4f2c", checksum: checksum}},
+		{source: "https://artifacts.acme.dev/api.tar.gz?checksum=md5:4f2c", wantErr: true},
+		{source: "https://artifacts.acme.dev/api.tar.gz//", wantErr: true},
+		{source: "http://mirror.acme.dev/api.tar.gz?checksum=" + checksum, want: archiveSource{url: "http://mirror.acme.dev/api.tar.gz", checksum: checksum}},
+		{source: "http://mirror.acme.dev/api.tar.gz", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		if !IsArchive(tt.source) {
+			t.Errorf("IsArchive(%q) = false", tt.source)
+		}
+		got, err := parseArchiveSource(tt.source)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseArchiveSource(%q) error = %v, wantErr %v", tt.source, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("parseArchiveSource(%q) = %+v, want %+v", tt.source, got, tt.want)
+		}
+	}
+
+	for _, source := range []string{"https://github.com/acme/api", "gh:acme/api.tar.gz", "./api.tar.gz"} {
+		if IsArchive(source) {
+			t.Errorf("IsArchive(%q) = true", source)
+		}
+	}
+}
+
+func TestImportArchive(t *testing.T) {
+	// Archives of git hosts hold a single directory named after the version
+	tree := t.TempDir()
+	writeSource(t, tree, map[string]string{
+		"platform-3.1.0/go/cli/template.yaml":      "version: 3.1.0\nlanguage: go\nframework: cli\nfiles:\n  - src: main.go.tmpl\n    dest: main.go\n",
+		"platform-3.1.0/go/cli/files/main.go.tmpl": "package main\n",
+	})
+	bundle, err := archive.Pack(tree, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checksum := oci.Digest(bundle)
+
+	var auth string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		if r.URL.Path != "/platform-3.1.0.tar.gz" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(bundle)
+	}))
+	defer server.Close()
+	t.Setenv(credentials.EnvVar(credentials.Host(server.URL)), "s3cret")
+
+	templatesDir := t.TempDir()
+	source := server.URL + "/platform-3.1.0.tar.gz//go/cli?checksum=" + checksum
+	result, err := Import(context.Background(), Options{Source: source, TemplatesDir: templatesDir, HTTPClient: server.Client()})
+	if err != nil {
+		t.Fatalf("Import() unexpected error: %v", err)
+	}
+	if want := filepath.Join(templatesDir, "go", "cli"); result.Dir != want || result.Version != "3.1.0" || len(result.Warnings) != 0 {
+		t.Errorf("Dir, Version, Warnings = %s, %s, %v, want %s, 3.1.0 and no warnings", result.Dir, result.Version, result.Warnings, want)
+	}
+	if auth != "Bearer s3cret" {
+		t.Errorf("Authorization = %q, want the token of the host", auth)
+	}
+	data, err := os.ReadFile(filepath.Join(result.Dir, "template.yaml"))
+	if err != nil || !strings.HasSuffix(string(data), "  commit: "+checksum+"\n") {
+		t.Errorf("template.yaml = %s, %v, want the origin pinned to the checksum", data, err)
+	}
+
+	// Unpinned archives are imported with a warning naming their checksum
+	result, err = Import(context.Background(), Options{Source: server.URL + "/platform-3.1.0.tar.gz//go/cli", TemplatesDir: t.TempDir(), HTTPClient: server.Client()})
+	if err != nil || len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], checksum) {
+		t.Errorf("Import() of an unpinned archive = %+v, %v, want a warning with its checksum", result, err)
+	}
+
+	other := "sha256:" + strings.Repeat("0", 64)
+	for _, source := range []string{
+		server.URL + "/platform-3.1.0.tar.gz//go/cli?checksum=" + other,
+		server.URL + "/platform-3.1.0.tar.gz//go/api",
+		server.URL + "/missing.tar.gz",
+	} {
+		if _, err := Import(context.Background(), Options{Source: source, TemplatesDir: t.TempDir(), HTTPClient: server.Client()}); err == nil {
+			t.Errorf("Import(%s) succeeded", source)
+		}
+	}
+
+	// Plain HTTP archives are downloaded pinned, without credentials, and
+	// credentials are not sent after a redirect to plain HTTP either
+	auth = ""
+	plain := httptest.NewServer(server.Config.Handler)
+	defer plain.Close()
+	t.Setenv(credentials.EnvVar(credentials.Host(plain.URL)), "s3cret")
+	if _, err := Import(context.Background(), Options{Source: plain.URL + "/platform-3.1.0.tar.gz//go/cli?checksum=" + checksum, TemplatesDir: t.TempDir()}); err != nil {
+		t.Fatalf("Import() of a pinned http archive unexpected error: %v", err)
+	}
+	if auth != "" {
+		t.Errorf("Authorization over http = %q, want none", auth)
+	}
+	if _, err := Import(context.Background(), Options{Source: plain.URL + "/platform-3.1.0.tar.gz//go/cli", TemplatesDir: t.TempDir()}); err == nil {
+		t.Errorf("Import() of an unpinned http archive succeeded")
+	}
+	redirect := httptest.NewTLSServer(http.RedirectHandler(plain.URL+"/platform-3.1.0.tar.gz", http.StatusFound))
+	defer redirect.Close()
+	t.Setenv(credentials.EnvVar(credentials.Host(redirect.URL)), "s3cret")
+	if _, err := Import(context.Background(), Options{Source: redirect.URL + "/platform-3.1.0.tar.gz//go/cli?checksum=" + checksum, TemplatesDir: t.TempDir(), HTTPClient: redirect.Client()}); err == nil || auth != "" {
+		t.Errorf("Import() redirected to http = %v, Authorization %q, want an error and no credentials sent", err, auth)
+	}
+
+	// s3:// archives are downloaded with the AWS CLI
+	var copied []string
+	original := runAWS
+	t.Cleanup(func() { runAWS = original })
+	runAWS = func(ctx context.Context, args ...string) error {
+		copied = args
+		return os.WriteFile(args[len(args)-1], bundle, 0644)
+	}
+	if _, err := Import(context.Background(), Options{Source: "s3://acme-templates/platform-3.1.0.tar.gz//go/cli?checksum=" + checksum, TemplatesDir: t.TempDir()}); err != nil {
+		t.Fatalf("Import() of an s3 archive unexpected error: %v", err)
+	}
+	if len(copied) < 4 || copied[0] != "s3" || copied[1] != "cp" || copied[3] != "s3://acme-templates/platform-3.1.0.tar.gz" {
+		t.Errorf("aws %v, want s3 cp of the archive", copied)
+	}
+}
+
+func TestCheckUpdate(t *testing.T) {
+	var calls int
+	original := runGit
+	t.Cleanup(func() { runGit = original })
+	runGit = func(ctx context.Context, args ...string) ([]byte, error) {
+		calls++
+		switch {
+		case args[1] == "--tags":
+			return []byte("aaa\trefs/tags/v1.2.0\nbbb\trefs/tags/v1.10.0\nccc\trefs/tags/latest\n"), nil
+		case args[2] == "v3":
+			return []byte("1111111\trefs/tags/v3\n2222222\trefs/tags/v3^{}\n"), nil
+		}
+		return []byte("4f2c9e1aa0b3c\tHEAD\n"), nil
+	}
+	originalResolve := resolveArtifact
+	t.Cleanup(func() { resolveArtifact = originalResolve })
+	resolveArtifact = func(ctx context.Context, client *http.Client, ref oci.Reference) (string, error) {
+		return "sha256:new", nil
+	}
+
+	versioned := t.TempDir()
+	writeSource(t, versioned, map[string]string{"versions/1.1.0/template.yaml": "version: 1.1.0\n"})
+	tests := []struct {
+		name    string
+		tmpl    template.Template
+		want    *Update
+		wantGit bool
+	}{
+		{name: "branch", tmpl: template.Template{Origin: &template.Origin{Source: "gh:acme/api", Commit: "0000000"}}, want: &Update{Current: "0000000", Latest: "4f2c9e1aa0b3"}, wantGit: true},
+		{name: "up to date", tmpl: template.Template{Origin: &template.Origin{Source: "gh:acme/api", Commit: "4f2c9e1aa0b3c"}}, wantGit: true},
+		{name: "annotated tag", tmpl: template.Template{Origin: &template.Origin{Source: "gh:acme/api?ref=v3", Commit: "2222222"}}, wantGit: true},
+		{name: "commit", tmpl: template.Template{Origin: &template.Origin{Source: "gh:acme/api?ref=4f2c9e1", Commit: "4f2c9e1"}}},
+		{name: "version tags", tmpl: template.Template{Path: versioned, Version: "1.2.0", Origin: &template.Origin{Source: "gh:acme/api", Commit: "aaa"}}, want: &Update{Current: "1.2.0", Latest: "1.10.0"}, wantGit: true},
+		{name: "oci", tmpl: template.Template{Origin: &template.Origin{Source: "oci://ghcr.io/acme/api:1", Commit: "sha256:old"}}, want: &Update{Current: "sha256:old", Latest: "sha256:new"}},
+		{name: "oci digest", tmpl: template.Template{Origin: &template.Origin{Source: "oci://ghcr.io/acme/api@sha256:" + strings.Repeat("ab", 32), Commit: "sha256:old"}}},
+		{name: "archive", tmpl: template.Template{Origin: &template.Origin{Source: "https://artifacts.acme.dev/api.tar.gz", Commit: "sha256:old"}}},
+		{name: "local", tmpl: template.Template{}},
+	}
+	for _, tt := range tests {
+		calls = 0
+		got, err := CheckUpdate(context.Background(), &tt.tmpl, Options{})
+		if err != nil {
+			t.Errorf("%s: CheckUpdate() unexpected error: %v", tt.name, err)
+			continue
+		}
+		if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+			t.Errorf("%s: CheckUpdate() = %+v, want %+v", tt.name, got, tt.want)
+		}
+		if (calls > 0) != tt.wantGit {
+			t.Errorf("%s: git run %d times", tt.name, calls)
+		}
+	}
+}
+
+func TestImportVersions(t *testing.T) {
+	source := t.TempDir()
+	writeSource(t, source, map[string]string{"copier.yml": "name: x\n"})
+
+	var checkouts []string
+	original := runGit
+	t.Cleanup(func() { runGit = original })
+	runGit = func(ctx context.Context, args ...string) ([]byte, error) {
+		if args[0] == "-C" {
+			return []byte("v1.10.0\nlatest\nv1.2.0\n0.9.0\n"), nil
+		}
+		tag := args[3]
+		checkouts = append(checkouts, tag)
+		writeSource(t, args[len(args)-1], map[string]string{"copier.yml": "name: x\n", "VERSION.jinja": tag + " {{ name }}\n"})
+		return nil, nil
+	}
+
+	dir := filepath.Join(t.TempDir(), "out")
+	result, err := Import(context.Background(), Options{Source: source, Dir: dir, Versions: true})
+	if err != nil {
+		t.Fatalf("Import() unexpected error: %v", err)
+	}
+	if got := strings.Join(checkouts, ","); got != "v1.10.0,v1.2.0,0.9.0" {
+		t.Errorf("checked out %s, want v1.10.0,v1.2.0,0.9.0", got)
+	}
+	if result.Version != "1.10.0" || strings.Join(result.Versions, ",") != "1.2.0,0.9.0" {
+		t.Errorf("Version, Versions = %s, %v, want 1.10.0, [1.2.0 0.9.0]", result.Version, result.Versions)
+	}
+	for path, want := range map[string]string{
+		"files/VERSION.tmpl":                "v1.10.0 {{ name }}\n",
+		"versions/1.2.0/files/VERSION.tmpl": "v1.2.0 {{ name }}\n",
+		"versions/0.9.0/template.yaml":      "version: 0.9.0",
+	} {
+		data, err := os.ReadFile(filepath.Join(dir, path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), want) {
+			t.Errorf("%s = %q, want it to contain %q", path, data, want)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/renan-dev/devinit/internal/oci"
+)
+
+// IsOCI reports whether source names an OCI artifact (oci://registry/...)
+func IsOCI(source string) bool {
+	return strings.HasPrefix(source, oci.Prefix)
+}
+
+// pullArtifact pulls the template of ref into dest and returns the digest
+// of its manifest; tests replace it
+var pullArtifact = func(ctx context.Context, client *http.Client, ref oci.Reference, dest string) (string, error) {
+	digest, _, err := oci.NewClient(client).Pull(ctx, ref, dest)
+	return digest, err
+}
+
+// pull pulls the template of an oci:// source into a temporary directory
+// of opts.CloneDir, pinned to the digest of its manifest
+func pull(ctx context.Context, opts Options) (*fetched, error) {
+	if opts.Versions {
+		return nil, fmt.Errorf("%s is an OCI artifact: import the version tags of git repositories only", opts.Source)
+	}
+	ref, err := oci.ParseReference(opts.Source)
+	if err != nil {
+		return nil, err
+	}
+
+	tmp, err := tempDir(opts.CloneDir)
+	if err != nil {
+		return nil, err
+	}
+	client := opts.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	digest, err := pullArtifact(ctx, client, ref, tmp)
+	if err != nil {
+		os.RemoveAll(tmp)
+		return nil, err
+	}
+	return &fetched{repo: tmp, digest: digest, cleanup: func() { os.RemoveAll(tmp) }}, nil
+}
@@ -0,0 +1,123 @@
+package importer
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/renan-dev/devinit/internal/oci"
+	"github.com/renan-dev/devinit/internal/template"
+)
+
+// Update is a newer revision of the source of an imported template
+type Update struct {
+	// Current is the commit, version or digest imported, Latest the one
+	// of the source now
+	Current string
+	Latest  string
+}
+
+// resolveArtifact returns the digest an OCI reference points to; tests
+// replace it
+var resolveArtifact = func(ctx context.Context, client *http.Client, ref oci.Reference) (string, error) {
+	return oci.NewClient(client).Resolve(ctx, ref)
+}
+
+// CheckUpdate compares an imported template with its source, without
+// downloading it, and returns the newer revision of the source; nil when
+// the template is up to date or cannot change: local sources, commits and
+// digests, and archives, which are pinned by checksum. Templates imported
+// with their version tags are compared with the newest version tag, others
+// with the commit of their branch or tag, or the digest of their tag.
+// opts.HTTPClient and opts.GitCredentialHelper reach the source.
+func CheckUpdate(ctx context.Context, tmpl *template.Template, opts Options) (*Update, error) {
+	o := tmpl.Origin
+	if o == nil || o.Commit == "" || !IsRemote(o.Source) || IsArchive(o.Source) {
+		return nil, nil
+	}
+	// Checks run in the background, where git must not prompt
+	env := []string{"GIT_TERMINAL_PROMPT=0"}
+	if opts.GitCredentialHelper != "" {
+		env = append(env, credentialHelperEnv(opts.GitCredentialHelper)...)
+	}
+	ctx = withGitEnv(ctx, env...)
+
+	if IsOCI(o.Source) {
+		ref, err := oci.ParseReference(o.Source)
+		if err != nil || ref.Digest != "" {
+			return nil, err
+		}
+		client := opts.HTTPClient
+		if client == nil {
+			client = http.DefaultClient
+		}
+		digest, err := resolveArtifact(ctx, client, ref)
+		if err != nil || digest == o.Commit {
+			return nil, err
+		}
+		return &Update{Current: o.Commit, Latest: digest}, nil
+	}
+
+	src, err := parseGitSource(o.Source)
+	if err != nil || commitPattern.MatchString(src.ref) {
+		return nil, err
+	}
+	if info, err := os.Stat(filepath.Join(tmpl.Path, "versions")); err == nil && info.IsDir() {
+		return newerTag(ctx, src.repo, tmpl.Version)
+	}
+
+	ref := src.ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+	// Annotated tags are followed to their commit, listed as tag^{}
+	out, err := runGit(ctx, "ls-remote", src.repo, ref, ref+"^{}")
+	if err != nil {
+		return nil, err
+	}
+	var commit string
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		hash, name, _ := strings.Cut(line, "\t")
+		if commit == "" || strings.HasSuffix(name, "^{}") {
+			commit = hash
+		}
+	}
+	if commit == "" || commit == o.Commit {
+		return nil, nil
+	}
+	return &Update{Current: shortCommit(o.Commit), Latest: shortCommit(commit)}, nil
+}
+
+// newerTag returns the newest version tag of repo when it is newer than
+// version
+func newerTag(ctx context.Context, repo, version string) (*Update, error) {
+	out, err := runGit(ctx, "ls-remote", "--tags", "--refs", repo)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if _, name, ok := strings.Cut(line, "\trefs/tags/"); ok {
+			names = append(names, name)
+		}
+	}
+	tags := parseVersionTags(names)
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	latest := tags[len(tags)-1].version
+	if cmp, err := template.CompareVersions(latest, version); err != nil || cmp <= 0 {
+		return nil, nil
+	}
+	return &Update{Current: version, Latest: latest}, nil
+}
+
+// shortCommit abbreviates a commit hash for messages
+func shortCommit(commit string) string {
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return commit
+}
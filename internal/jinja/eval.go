@@ -0,0 +1,938 @@
+package jinja
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// undefined is the value of a name or attribute that does not exist. It is
+// false in conditions, empty when iterated, replaced by the default filter
+// and an error when printed.
+type undefined struct{ name string }
+
+// tuple is the value of a tuple literal. It behaves as a list, except that
+// % formatting takes its items as the arguments.
+type tuple []any
+
+// method is a method bound to its receiver, such as "abc".upper
+type method struct {
+	receiver any
+	name     string
+}
+
+func (s *state) eval(e expr) (any, error) {
+	switch e := e.(type) {
+	case literal:
+		return e.value, nil
+	case name:
+		return s.lookup(e.name), nil
+	case listExpr:
+		items := make([]any, len(e.items))
+		for i, item := range e.items {
+			value, err := s.eval(item)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = value
+		}
+		if e.tuple {
+			return tuple(items), nil
+		}
+		return items, nil
+	case dictExpr:
+		dict := make(map[string]any, len(e.keys))
+		for i := range e.keys {
+			key, err := s.eval(e.keys[i])
+			if err != nil {
+				return nil, err
+			}
+			value, err := s.eval(e.values[i])
+			if err != nil {
+				return nil, err
+			}
+			dict[toString(key)] = value
+		}
+		return dict, nil
+	case attr:
+		target, err := s.eval(e.target)
+		if err != nil {
+			return nil, err
+		}
+		return attribute(target, e.name, describeExpr(e)), nil
+	case index:
+		target, err := s.eval(e.target)
+		if err != nil {
+			return nil, err
+		}
+		key, err := s.eval(e.key)
+		if err != nil {
+			return nil, err
+		}
+		return item(target, key, describeExpr(e))
+	case sliceExpr:
+		target, err := s.eval(e.target)
+		if err != nil {
+			return nil, err
+		}
+		bounds := make([]any, 3)
+		for i, b := range []expr{e.start, e.stop, e.step} {
+			if b == nil {
+				continue
+			}
+			if bounds[i], err = s.eval(b); err != nil {
+				return nil, err
+			}
+		}
+		return slice(target, bounds[0], bounds[1], bounds[2], describeExpr(e))
+	case call:
+		if len(e.kwargs) > 0 {
+			return nil, fmt.Errorf("%s does not take keyword arguments", describeExpr(e.target))
+		}
+		target, err := s.eval(e.target)
+		if err != nil {
+			return nil, err
+		}
+		args, err := s.evalAll(e.args)
+		if err != nil {
+			return nil, err
+		}
+		return callValue(target, args, describeExpr(e.target))
+	case filter:
+		target, err := s.eval(e.target)
+		if err != nil {
+			return nil, err
+		}
+		args, err := s.evalAll(e.args)
+		if err != nil {
+			return nil, err
+		}
+		kwargs := make(map[string]any, len(e.kwargs))
+		for _, kw := range e.kwargs {
+			if kwargs[kw.name], err = s.eval(kw.value); err != nil {
+				return nil, err
+			}
+		}
+		return s.applyFilter(e.name, target, args, kwargs)
+	case test:
+		if len(e.kwargs) > 0 {
+			return nil, fmt.Errorf("test %s does not take keyword arguments", e.name)
+		}
+		target, err := s.eval(e.target)
+		if err != nil {
+			return nil, err
+		}
+		args, err := s.evalAll(e.args)
+		if err != nil {
+			return nil, err
+		}
+		ok, err := applyTest(e.name, target, args)
+		return ok != e.negate, err
+	case unary:
+		operand, err := s.eval(e.operand)
+		if err != nil {
+			return nil, err
+		}
+		if e.op == "not" {
+			return !truthy(operand), nil
+		}
+		if n, ok := toNumber(operand); ok {
+			return negate(n), nil
+		}
+		return nil, fmt.Errorf("cannot negate %s", toString(operand))
+	case binary:
+		return s.evalBinary(e)
+	case conditional:
+		cond, err := s.eval(e.cond)
+		if err != nil {
+			return nil, err
+		}
+		if truthy(cond) {
+			return s.eval(e.then)
+		}
+		return s.eval(e.otherwise)
+	}
+	return nil, fmt.Errorf("unknown expression %T", e)
+}
+
+func (s *state) evalAll(exprs []expr) ([]any, error) {
+	values := make([]any, len(exprs))
+	for i, e := range exprs {
+		value, err := s.eval(e)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+func (s *state) evalBinary(e binary) (any, error) {
+	left, err := s.eval(e.left)
+	if err != nil {
+		return nil, err
+	}
+	// and and or return an operand, evaluating the right one only if needed
+	switch e.op {
+	case "and":
+		if !truthy(left) {
+			return left, nil
+		}
+		return s.eval(e.right)
+	case "or":
+		if truthy(left) {
+			return left, nil
+		}
+		return s.eval(e.right)
+	}
+
+	right, err := s.eval(e.right)
+	if err != nil {
+		return nil, err
+	}
+	switch e.op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	case "<", "<=", ">", ">=":
+		c, err := compare(left, right)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]bool{"<": c < 0, "<=": c <= 0, ">": c > 0, ">=": c >= 0}[e.op], nil
+	case "in":
+		return contains(right, left)
+	case "not in":
+		ok, err := contains(right, left)
+		return !ok, err
+	case "~":
+		return toString(left) + toString(right), nil
+	}
+	return arithmetic(e.op, left, right)
+}
+
+// describeExpr renders an expression for error messages: user.name
+func describeExpr(e expr) string {
+	switch e := e.(type) {
+	case name:
+		return e.name
+	case attr:
+		return describeExpr(e.target) + "." + e.name
+	case index:
+		return describeExpr(e.target) + "[...]"
+	case sliceExpr:
+		return describeExpr(e.target) + "[...]"
+	case call:
+		return describeExpr(e.target) + "()"
+	}
+	return "expression"
+}
+
+// attribute looks name up on target: a method of strings and dicts, a key
+// of a map, or a field or method of a Go value
+func attribute(target any, n, path string) any {
+	if _, ok := target.(undefined); ok {
+		return undefined{path}
+	}
+	if value, ok := getAttr(target, n); ok {
+		return value
+	}
+	if isBuiltinMethod(target, n) {
+		return method{target, n}
+	}
+	if i, err := strconv.Atoi(n); err == nil {
+		if value, err := item(target, i, path); err == nil {
+			return value
+		}
+	}
+	return undefined{path}
+}
+
+// getAttr returns the map key, field or method without arguments of value
+// named n. Methods are called, as Go templates do.
+func getAttr(value any, n string) (any, bool) {
+	v := reflect.ValueOf(value)
+	if !v.IsValid() {
+		return nil, false
+	}
+	if m := v.MethodByName(n); m.IsValid() && m.Type().NumIn() == 0 && m.Type().NumOut() >= 1 {
+		out := m.Call(nil)
+		if len(out) == 2 && !out[1].IsNil() {
+			return nil, false
+		}
+		return out[0].Interface(), true
+	}
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, false
+		}
+		v = v.Elem()
+		if m := v.MethodByName(n); m.IsValid() && m.Type().NumIn() == 0 && m.Type().NumOut() >= 1 {
+			return m.Call(nil)[0].Interface(), true
+		}
+	}
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, false
+		}
+		if elem := v.MapIndex(reflect.ValueOf(n).Convert(v.Type().Key())); elem.IsValid() {
+			return elem.Interface(), true
+		}
+	case reflect.Struct:
+		if f, ok := v.Type().FieldByName(n); ok && f.IsExported() {
+			return v.FieldByIndex(f.Index).Interface(), true
+		}
+	}
+	return nil, false
+}
+
+// item returns target[key]: an element of a list or string, negative
+// indexes counting from the end, or the value of a map key
+func item(target, key any, path string) (any, error) {
+	if _, ok := target.(undefined); ok {
+		return undefined{path}, nil
+	}
+	v := indirect(reflect.ValueOf(target))
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array, reflect.String:
+		n, ok := toNumber(key)
+		i, isInt := n.(int)
+		if !ok || !isInt {
+			return nil, fmt.Errorf("%s: index %s is not an integer", path, toString(key))
+		}
+		if i < 0 {
+			i += v.Len()
+		}
+		if i < 0 || i >= v.Len() {
+			return undefined{path}, nil
+		}
+		if v.Kind() == reflect.String {
+			return string(v.String()[i]), nil
+		}
+		return v.Index(i).Interface(), nil
+	case reflect.Map:
+		if value, ok := getAttr(target, toString(key)); ok {
+			return value, nil
+		}
+		return undefined{path}, nil
+	case reflect.Struct:
+		if value, ok := getAttr(target, toString(key)); ok {
+			return value, nil
+		}
+	}
+	return undefined{path}, nil
+}
+
+// slice returns target[start:stop:step] of a list or string, with the
+// bounds of Python: negative ones count from the end, out of range ones are
+// clipped and missing ones (nil) take in everything in the step's direction
+func slice(target, start, stop, step any, path string) (any, error) {
+	if _, ok := target.(undefined); ok {
+		return undefined{path}, nil
+	}
+	var items []any
+	s, isString := asString(target)
+	switch {
+	case isString:
+		for _, r := range s {
+			items = append(items, string(r))
+		}
+	case isList(target):
+		items, _ = iterate(target)
+	default:
+		return nil, fmt.Errorf("%s cannot be sliced", path)
+	}
+
+	bound := func(value any, fallback int) (int, error) {
+		if value == nil {
+			return fallback, nil
+		}
+		n, ok := toNumber(value)
+		i, isInt := n.(int)
+		if !ok || !isInt {
+			return 0, fmt.Errorf("%s: slice index %s is not an integer", path, toString(value))
+		}
+		return i, nil
+	}
+	by, err := bound(step, 1)
+	if err != nil {
+		return nil, err
+	}
+	if by == 0 {
+		return nil, fmt.Errorf("%s: slice step cannot be zero", path)
+	}
+	lower, upper := 0, len(items)
+	if by < 0 {
+		lower, upper = -1, len(items)-1
+	}
+	clip := func(i int) int {
+		if i < 0 {
+			i += len(items)
+		}
+		return min(max(i, lower), upper)
+	}
+	from, to := lower, upper
+	if by < 0 {
+		from, to = upper, lower
+	}
+	if from, err = bound(start, from); err != nil {
+		return nil, err
+	}
+	if to, err = bound(stop, to); err != nil {
+		return nil, err
+	}
+	if start != nil {
+		from = clip(from)
+	}
+	if stop != nil {
+		to = clip(to)
+	}
+
+	var picked []any
+	for i := from; by > 0 && i < to || by < 0 && i > to; i += by {
+		picked = append(picked, items[i])
+	}
+	switch {
+	case isString:
+		var b strings.Builder
+		for _, c := range picked {
+			b.WriteString(c.(string))
+		}
+		return b.String(), nil
+	case picked == nil:
+		picked = []any{}
+	}
+	if _, ok := target.(tuple); ok {
+		return tuple(picked), nil
+	}
+	return picked, nil
+}
+
+// indirect dereferences pointers and interfaces
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// iterate returns the elements of a list, the characters of a string, or
+// the sorted keys of a map; undefined and none iterate as empty
+func iterate(value any) ([]any, error) {
+	switch value.(type) {
+	case undefined, nil:
+		return nil, nil
+	}
+	v := indirect(reflect.ValueOf(value))
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		items := make([]any, v.Len())
+		for i := range items {
+			items[i] = v.Index(i).Interface()
+		}
+		return items, nil
+	case reflect.String:
+		var items []any
+		for _, r := range v.String() {
+			items = append(items, string(r))
+		}
+		return items, nil
+	case reflect.Map:
+		keys := sortedKeys(v)
+		items := make([]any, len(keys))
+		for i, key := range keys {
+			items[i] = key.Interface()
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("%s is not iterable", toString(value))
+}
+
+// sortedKeys returns the keys of a map in a stable order
+func sortedKeys(v reflect.Value) []reflect.Value {
+	keys := v.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+	})
+	return keys
+}
+
+// truthy reports whether a value counts as true, as in Python
+func truthy(value any) bool {
+	switch value := value.(type) {
+	case nil, undefined:
+		return false
+	case bool:
+		return value
+	}
+	v := indirect(reflect.ValueOf(value))
+	switch v.Kind() {
+	case reflect.Invalid:
+		return false
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		return v.Len() > 0
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() != 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint() != 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() != 0
+	case reflect.Bool:
+		return v.Bool()
+	}
+	return true
+}
+
+// toString formats a value as Python's str would
+func toString(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return "None"
+	case undefined:
+		return ""
+	case string:
+		return value
+	case bool:
+		if value {
+			return "True"
+		}
+		return "False"
+	case float64:
+		return formatFloat(value)
+	case float32:
+		return formatFloat(float64(value))
+	case fmt.Stringer:
+		return value.String()
+	case tuple:
+		parts := make([]string, len(value))
+		for i, item := range value {
+			parts[i] = repr(item)
+		}
+		if len(parts) == 1 {
+			return "(" + parts[0] + ",)"
+		}
+		return "(" + strings.Join(parts, ", ") + ")"
+	}
+	v := indirect(reflect.ValueOf(value))
+	switch v.Kind() {
+	case reflect.Invalid:
+		return "None"
+	case reflect.String:
+		return v.String()
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			break
+		}
+		parts := make([]string, v.Len())
+		for i := range parts {
+			parts[i] = repr(v.Index(i).Interface())
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	case reflect.Map:
+		var parts []string
+		for _, key := range sortedKeys(v) {
+			parts = append(parts, repr(key.Interface())+": "+repr(v.MapIndex(key).Interface()))
+		}
+		return "{" + strings.Join(parts, ", ") + "}"
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// repr formats a value as Python's repr would, quoting strings
+func repr(value any) string {
+	if s, ok := asString(value); ok {
+		return "'" + strings.ReplaceAll(strings.ReplaceAll(s, `\`, `\\`), "'", `\'`) + "'"
+	}
+	return toString(value)
+}
+
+func formatFloat(f float64) string {
+	s := strconv.FormatFloat(f, 'f', -1, 64)
+	if !strings.ContainsAny(s, ".eEnN") {
+		s += ".0"
+	}
+	return s
+}
+
+// toNumber returns value as an int or a float64
+func toNumber(value any) (any, bool) {
+	if _, ok := value.(bool); ok {
+		return nil, false
+	}
+	v := indirect(reflect.ValueOf(value))
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return nil, false
+}
+
+func toFloat(n any) float64 {
+	if i, ok := n.(int); ok {
+		return float64(i)
+	}
+	return n.(float64)
+}
+
+func negate(n any) any {
+	if i, ok := n.(int); ok {
+		return -i
+	}
+	return -n.(float64)
+}
+
+// equal compares values as Python would: numbers by value, others deeply
+func equal(a, b any) bool {
+	if x, ok := toNumber(a); ok {
+		y, ok := toNumber(b)
+		return ok && toFloat(x) == toFloat(y)
+	}
+	if x, ok := asString(a); ok {
+		y, ok := asString(b)
+		return ok && x == y
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// asString returns value as a string if it is one, of any string type
+func asString(value any) (string, bool) {
+	if v := indirect(reflect.ValueOf(value)); v.Kind() == reflect.String {
+		return v.String(), true
+	}
+	return "", false
+}
+
+// compare orders two numbers or two strings
+func compare(a, b any) (int, error) {
+	if x, ok := toNumber(a); ok {
+		if y, ok := toNumber(b); ok {
+			switch fx, fy := toFloat(x), toFloat(y); {
+			case fx < fy:
+				return -1, nil
+			case fx > fy:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	}
+	x, xok := asString(a)
+	y, yok := asString(b)
+	if xok && yok {
+		return strings.Compare(x, y), nil
+	}
+	return 0, fmt.Errorf("cannot compare %s and %s", repr(a), repr(b))
+}
+
+// contains implements "needle in haystack" for strings, lists and maps
+func contains(haystack, needle any) (bool, error) {
+	if _, ok := haystack.(undefined); ok {
+		return false, nil
+	}
+	v := indirect(reflect.ValueOf(haystack))
+	switch v.Kind() {
+	case reflect.String:
+		return strings.Contains(v.String(), toString(needle)), nil
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if equal(v.Index(i).Interface(), needle) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case reflect.Map:
+		_, ok := getAttr(haystack, toString(needle))
+		return ok, nil
+	}
+	return false, fmt.Errorf("cannot look for %s in %s", repr(needle), toString(haystack))
+}
+
+// arithmetic applies + - * / // % ** to numbers; + also joins strings and
+// lists, * repeats a string and % formats one
+func arithmetic(op string, left, right any) (any, error) {
+	x, xok := toNumber(left)
+	y, yok := toNumber(right)
+	if xok && yok {
+		xi, xint := x.(int)
+		yi, yint := y.(int)
+		ints := xint && yint
+		switch op {
+		case "+":
+			if ints {
+				return xi + yi, nil
+			}
+			return toFloat(x) + toFloat(y), nil
+		case "-":
+			if ints {
+				return xi - yi, nil
+			}
+			return toFloat(x) - toFloat(y), nil
+		case "*":
+			if ints {
+				return xi * yi, nil
+			}
+			return toFloat(x) * toFloat(y), nil
+		case "/":
+			if toFloat(y) == 0 {
+				return nil, fmt.Errorf("division by zero")
+			}
+			return toFloat(x) / toFloat(y), nil
+		case "//", "%":
+			if toFloat(y) == 0 {
+				return nil, fmt.Errorf("division by zero")
+			}
+			if ints {
+				q := int(math.Floor(float64(xi) / float64(yi)))
+				if op == "//" {
+					return q, nil
+				}
+				return xi - q*yi, nil
+			}
+			q := math.Floor(toFloat(x) / toFloat(y))
+			if op == "//" {
+				return q, nil
+			}
+			return toFloat(x) - q*toFloat(y), nil
+		case "**":
+			if ints && yi >= 0 {
+				n := 1
+				for i := 0; i < yi; i++ {
+					n *= xi
+				}
+				return n, nil
+			}
+			return math.Pow(toFloat(x), toFloat(y)), nil
+		}
+	}
+
+	switch op {
+	case "+":
+		if l, ok := asString(left); ok {
+			if r, ok := asString(right); ok {
+				return l + r, nil
+			}
+		}
+		if l, err := iterate(left); err == nil && isList(left) && isList(right) {
+			r, _ := iterate(right)
+			return append(l, r...), nil
+		}
+	case "*":
+		if s, ok := asString(left); ok && yok {
+			if n, ok := y.(int); ok && n >= 0 {
+				return strings.Repeat(s, n), nil
+			}
+		}
+	case "%":
+		if s, ok := asString(left); ok {
+			return formatPercent(s, right)
+		}
+	}
+	return nil, fmt.Errorf("unsupported operation %s %s %s", repr(left), op, repr(right))
+}
+
+func isList(value any) bool {
+	kind := indirect(reflect.ValueOf(value)).Kind()
+	return kind == reflect.Slice || kind == reflect.Array
+}
+
+func isMapping(value any) bool {
+	return indirect(reflect.ValueOf(value)).Kind() == reflect.Map
+}
+
+// callValue calls a bound method or a Go function
+func callValue(target any, args []any, path string) (any, error) {
+	switch target := target.(type) {
+	case method:
+		return callMethod(target, args)
+	case undefined:
+		return nil, fmt.Errorf("%s is undefined", target.name)
+	}
+	if fn := reflect.ValueOf(target); fn.Kind() == reflect.Func {
+		return callFunc(fn, args)
+	}
+	return nil, fmt.Errorf("%s is not callable", path)
+}
+
+// callFunc calls a Go function, converting the arguments to its parameter
+// types. A function may return a value and an error.
+func callFunc(fn reflect.Value, args []any) (any, error) {
+	t := fn.Type()
+	if t.IsVariadic() && len(args) < t.NumIn()-1 || !t.IsVariadic() && len(args) != t.NumIn() {
+		return nil, fmt.Errorf("wrong number of arguments: got %d, want %d", len(args), t.NumIn())
+	}
+	in := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		var want reflect.Type
+		if t.IsVariadic() && i >= t.NumIn()-1 {
+			want = t.In(t.NumIn() - 1).Elem()
+		} else {
+			want = t.In(i)
+		}
+		v, err := convert(arg, want)
+		if err != nil {
+			return nil, fmt.Errorf("argument %d: %w", i+1, err)
+		}
+		in[i] = v
+	}
+
+	out := fn.Call(in)
+	switch len(out) {
+	case 0:
+		return nil, nil
+	case 2:
+		if err, _ := out[1].Interface().(error); err != nil {
+			return nil, err
+		}
+	}
+	return out[0].Interface(), nil
+}
+
+// convert makes value a reflect.Value of type want
+func convert(value any, want reflect.Type) (reflect.Value, error) {
+	if value == nil {
+		return reflect.Zero(want), nil
+	}
+	if u, ok := value.(undefined); ok {
+		return reflect.Value{}, fmt.Errorf("%s is undefined", u.name)
+	}
+	v := reflect.ValueOf(value)
+	switch {
+	case v.Type().AssignableTo(want):
+		return v, nil
+	case want.Kind() == reflect.String:
+		return reflect.ValueOf(toString(value)).Convert(want), nil
+	case want.Kind() == reflect.Slice && isList(value):
+		items, _ := iterate(value)
+		slice := reflect.MakeSlice(want, len(items), len(items))
+		for i, item := range items {
+			elem, err := convert(item, want.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			slice.Index(i).Set(elem)
+		}
+		return slice, nil
+	}
+	if n, ok := toNumber(value); ok {
+		switch want.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return reflect.ValueOf(int64(toFloat(n))).Convert(want), nil
+		case reflect.Float32, reflect.Float64:
+			return reflect.ValueOf(toFloat(n)).Convert(want), nil
+		}
+	}
+	if v.Type().ConvertibleTo(want) && v.Kind() == want.Kind() {
+		return v.Convert(want), nil
+	}
+	return reflect.Value{}, fmt.Errorf("cannot use %s as %s", repr(value), want)
+}
+
+// formatPercent implements Python's printf-style formatting, format % args:
+// a tuple gives the values of the conversions in order, a mapping those
+// naming a key, as in %(name)s, and any other value the only conversion.
+// Conversions take the flags, width and precision of Python, which Go's fmt
+// shares.
+func formatPercent(format string, args any) (string, error) {
+	values := []any{args}
+	if t, ok := args.(tuple); ok {
+		values = t
+	}
+	used := 0
+
+	var b strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			b.WriteByte(format[i])
+			continue
+		}
+		i++
+		if i < len(format) && format[i] == '%' {
+			b.WriteByte('%')
+			continue
+		}
+
+		var value any
+		named := false
+		if i < len(format) && format[i] == '(' {
+			end := strings.IndexByte(format[i:], ')')
+			if end < 0 {
+				return "", fmt.Errorf("incomplete format key")
+			}
+			if !isMapping(args) {
+				return "", fmt.Errorf("format requires a mapping")
+			}
+			key := format[i+1 : i+end]
+			v, ok := getAttr(args, key)
+			if !ok {
+				return "", fmt.Errorf("format key %q not found", key)
+			}
+			value, named = v, true
+			i += end + 1
+		}
+		spec := i
+		for i < len(format) && strings.IndexByte("-+ 0#", format[i]) >= 0 {
+			i++
+		}
+		for i < len(format) && (format[i] >= '0' && format[i] <= '9' || format[i] == '.') {
+			i++
+		}
+		if i >= len(format) {
+			return "", fmt.Errorf("incomplete format")
+		}
+		verb := format[i]
+		if !named {
+			if used >= len(values) {
+				return "", fmt.Errorf("not enough arguments for format string")
+			}
+			value = values[used]
+			used++
+		}
+
+		directive := "%" + format[spec:i]
+		switch verb {
+		case 's':
+			fmt.Fprintf(&b, directive+"s", toString(value))
+		case 'r':
+			fmt.Fprintf(&b, directive+"s", repr(value))
+		case 'd', 'i', 'u', 'x', 'X', 'o', 'c':
+			n, ok := toNumber(value)
+			if !ok {
+				return "", fmt.Errorf("%%%c format: a number is required, not %s", verb, repr(value))
+			}
+			switch verb {
+			case 'd', 'i', 'u':
+				fmt.Fprintf(&b, directive+"d", int(toFloat(n)))
+			case 'c':
+				fmt.Fprintf(&b, directive+"c", rune(toFloat(n)))
+			default:
+				fmt.Fprintf(&b, directive+string(verb), int(toFloat(n)))
+			}
+		case 'f', 'F', 'e', 'E', 'g', 'G':
+			n, ok := toNumber(value)
+			if !ok {
+				return "", fmt.Errorf("%%%c format: a number is required, not %s", verb, repr(value))
+			}
+			if verb == 'F' {
+				verb = 'f'
+			}
+			fmt.Fprintf(&b, directive+string(verb), toFloat(n))
+		default:
+			return "", fmt.Errorf("unsupported format character %q", verb)
+		}
+	}
+	if _, isTuple := args.(tuple); isTuple && used < len(values) || !isTuple && used == 0 && !isMapping(args) {
+		return "", fmt.Errorf("not all arguments converted during string formatting")
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,597 @@
+package jinja
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// builtinFilters are the Jinja2 filters templates get without Env.Filters.
+// They receive the filtered value and the arguments of the filter.
+var builtinFilters = map[string]func(value any, args []any) (any, error){
+	"default":    filterDefault,
+	"d":          filterDefault,
+	"lower":      stringFilter(strings.ToLower),
+	"upper":      stringFilter(strings.ToUpper),
+	"title":      stringFilter(title),
+	"capitalize": stringFilter(capitalize),
+	"trim":       stringFilter(strings.TrimSpace),
+	"string":     func(value any, _ []any) (any, error) { return toString(value), nil },
+	"length":     filterLength,
+	"count":      filterLength,
+	"first": func(value any, _ []any) (any, error) {
+		items, err := iterate(value)
+		if err != nil || len(items) == 0 {
+			return undefined{"first"}, err
+		}
+		return items[0], nil
+	},
+	"last": func(value any, _ []any) (any, error) {
+		items, err := iterate(value)
+		if err != nil || len(items) == 0 {
+			return undefined{"last"}, err
+		}
+		return items[len(items)-1], nil
+	},
+	"join": func(value any, args []any) (any, error) {
+		items, err := iterate(value)
+		if err != nil {
+			return nil, err
+		}
+		attr := arg(args, 1, nil)
+		parts := make([]string, len(items))
+		for i, item := range items {
+			if attr != nil {
+				item = attributeOf(item, toString(attr))
+			}
+			parts[i] = toString(item)
+		}
+		return strings.Join(parts, toString(arg(args, 0, ""))), nil
+	},
+	"replace": func(value any, args []any) (any, error) {
+		old, repl := arg(args, 0, nil), arg(args, 1, nil)
+		if old == nil || repl == nil {
+			return nil, fmt.Errorf("replace needs the old and new strings")
+		}
+		count, err := intArg(args, 2, -1)
+		if err != nil {
+			return nil, err
+		}
+		return strings.Replace(toString(value), toString(old), toString(repl), count), nil
+	},
+	"int": func(value any, args []any) (any, error) {
+		if n, ok := toNumber(value); ok {
+			return int(toFloat(n)), nil
+		}
+		base, err := intArg(args, 1, 10)
+		if err != nil {
+			return nil, err
+		}
+		s := strings.TrimSpace(toString(value))
+		if prefix, ok := basePrefixes[base]; ok && len(s) > 2 && strings.EqualFold(s[:2], prefix) {
+			s = s[2:]
+		}
+		if i, err := strconv.ParseInt(s, base, 64); err == nil {
+			return int(i), nil
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil && base == 10 {
+			return int(f), nil
+		}
+		return arg(args, 0, 0), nil
+	},
+	"float": func(value any, args []any) (any, error) {
+		if n, ok := toNumber(value); ok {
+			return toFloat(n), nil
+		}
+		if f, err := strconv.ParseFloat(strings.TrimSpace(toString(value)), 64); err == nil {
+			return f, nil
+		}
+		return arg(args, 0, 0.0), nil
+	},
+	"list": func(value any, _ []any) (any, error) {
+		return iterate(value)
+	},
+	"sort": func(value any, args []any) (any, error) {
+		items, err := iterate(value)
+		if err != nil {
+			return nil, err
+		}
+		// Items are compared by their attribute and, unless case
+		// sensitive, with strings in lower case
+		reverse, caseSensitive, attr := truthy(arg(args, 0, false)), truthy(arg(args, 1, false)), arg(args, 2, nil)
+		key := func(item any) any {
+			if attr != nil {
+				item = attributeOf(item, toString(attr))
+			}
+			if s, ok := asString(item); ok && !caseSensitive {
+				return strings.ToLower(s)
+			}
+			return item
+		}
+		var sortErr error
+		sort.SliceStable(items, func(i, j int) bool {
+			c, err := compare(key(items[i]), key(items[j]))
+			if err != nil {
+				sortErr = err
+			}
+			if reverse {
+				return c > 0
+			}
+			return c < 0
+		})
+		return items, sortErr
+	},
+	"reverse": func(value any, _ []any) (any, error) {
+		if s, ok := asString(value); ok {
+			r := []rune(s)
+			for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+				r[i], r[j] = r[j], r[i]
+			}
+			return string(r), nil
+		}
+		items, err := iterate(value)
+		if err != nil {
+			return nil, err
+		}
+		reversed := make([]any, len(items))
+		for i, item := range items {
+			reversed[len(items)-1-i] = item
+		}
+		return reversed, nil
+	},
+	"indent": func(value any, args []any) (any, error) {
+		width := 4
+		if n, ok := toNumber(arg(args, 0, 4)); ok {
+			width = int(toFloat(n))
+		}
+		prefix := strings.Repeat(" ", width)
+		if s, ok := asString(arg(args, 0, nil)); ok {
+			prefix = s
+		}
+		first, blank := truthy(arg(args, 1, false)), truthy(arg(args, 2, false))
+		lines := strings.Split(toString(value), "\n")
+		for i := range lines {
+			if i > 0 && (lines[i] != "" || blank) || i == 0 && first {
+				lines[i] = prefix + lines[i]
+			}
+		}
+		return strings.Join(lines, "\n"), nil
+	},
+	"truncate": func(value any, args []any) (any, error) {
+		s := []rune(toString(value))
+		length, err := intArg(args, 0, 255)
+		if err != nil {
+			return nil, err
+		}
+		leeway, err := intArg(args, 3, 5)
+		if err != nil {
+			return nil, err
+		}
+		end := []rune(toString(arg(args, 2, "...")))
+		switch {
+		case length < len(end):
+			return nil, fmt.Errorf("truncate length %d is shorter than the end %q", length, string(end))
+		case leeway < 0:
+			return nil, fmt.Errorf("truncate leeway cannot be negative")
+		case len(s) <= length+leeway:
+			return string(s), nil
+		}
+		kept := string(s[:length-len(end)])
+		if !truthy(arg(args, 1, false)) {
+			// Whole words only, as Python's rsplit(" ", 1)[0]
+			if i := strings.LastIndex(kept, " "); i >= 0 {
+				kept = kept[:i]
+			}
+		}
+		return kept + string(end), nil
+	},
+	"tojson": func(value any, args []any) (any, error) {
+		indent, err := intArg(args, 0, 0)
+		if err != nil {
+			return nil, err
+		}
+		var data []byte
+		if indent > 0 {
+			data, err = json.MarshalIndent(value, "", strings.Repeat(" ", indent))
+		} else {
+			data, err = json.Marshal(value)
+		}
+		return string(data), err
+	},
+}
+
+// filterParams name the arguments of the builtin filters in order, so they
+// can be given by keyword as in Jinja2: sort(attribute='name')
+var filterParams = map[string][]string{
+	"default":  {"default_value", "boolean"},
+	"d":        {"default_value", "boolean"},
+	"join":     {"d", "attribute"},
+	"replace":  {"old", "new", "count"},
+	"int":      {"default", "base"},
+	"float":    {"default"},
+	"sort":     {"reverse", "case_sensitive", "attribute"},
+	"indent":   {"width", "first", "blank"},
+	"truncate": {"length", "killwords", "end", "leeway"},
+	"tojson":   {"indent"},
+}
+
+// keywordFilters are the builtin filters taking any keyword arguments
+var keywordFilters = map[string]func(value any, args []any, kwargs map[string]any) (any, error){
+	"format": filterFormat,
+}
+
+// basePrefixes are the prefixes int accepts in numbers of their base, as
+// Python's int does: '0x1F'|int(base=16)
+var basePrefixes = map[int]string{2: "0b", 8: "0o", 16: "0x"}
+
+// filterFormat applies Python's % formatting, with the positional arguments
+// as a tuple or the keyword ones as a mapping: '%s-%s'|format(a, b)
+func filterFormat(value any, args []any, kwargs map[string]any) (any, error) {
+	switch {
+	case len(args) > 0 && len(kwargs) > 0:
+		return nil, fmt.Errorf("format takes positional or keyword arguments, not both")
+	case len(kwargs) > 0:
+		return formatPercent(toString(value), kwargs)
+	}
+	return formatPercent(toString(value), tuple(args))
+}
+
+// bindArgs places the keyword arguments of builtin filter n at the position
+// of their parameter among the positional arguments
+func bindArgs(n string, args []any, kwargs map[string]any) ([]any, error) {
+	if len(kwargs) == 0 {
+		return args, nil
+	}
+	names := make([]string, 0, len(kwargs))
+	for name := range kwargs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	bound := append([]any(nil), args...)
+	for _, name := range names {
+		i := slices.Index(filterParams[n], name)
+		switch {
+		case i < 0:
+			return nil, fmt.Errorf("filter %s has no argument %s", n, name)
+		case i < len(args):
+			return nil, fmt.Errorf("filter %s got argument %s twice", n, name)
+		}
+		for len(bound) <= i {
+			bound = append(bound, noArg{})
+		}
+		bound[i] = kwargs[name]
+	}
+	return bound, nil
+}
+
+// filterDefault returns its argument when the value is undefined, or, with
+// a true second argument, when it is false
+func filterDefault(value any, args []any) (any, error) {
+	if _, ok := value.(undefined); ok || truthy(arg(args, 1, false)) && !truthy(value) {
+		return arg(args, 0, ""), nil
+	}
+	return value, nil
+}
+
+func filterLength(value any, _ []any) (any, error) {
+	if s, ok := asString(value); ok {
+		return len([]rune(s)), nil
+	}
+	items, err := iterate(value)
+	return len(items), err
+}
+
+func stringFilter(fn func(string) string) func(any, []any) (any, error) {
+	return func(value any, _ []any) (any, error) {
+		return fn(toString(value)), nil
+	}
+}
+
+// noArg stands for the arguments left out before a keyword argument
+type noArg struct{}
+
+// arg returns args[i], or fallback when there are fewer arguments or it was
+// left out
+func arg(args []any, i int, fallback any) any {
+	if i < len(args) && args[i] != (noArg{}) {
+		return args[i]
+	}
+	return fallback
+}
+
+// intArg returns args[i] as an int, or fallback when it is missing or none
+func intArg(args []any, i, fallback int) (int, error) {
+	value := arg(args, i, nil)
+	if value == nil {
+		return fallback, nil
+	}
+	n, ok := toNumber(value)
+	if !ok {
+		return 0, fmt.Errorf("%s is not a number", toString(value))
+	}
+	return int(toFloat(n)), nil
+}
+
+// attributeOf returns the attribute of item at path, dotted for nested
+// ones, as the attribute arguments of filters name them
+func attributeOf(item any, path string) any {
+	for _, n := range strings.Split(path, ".") {
+		item = attribute(item, n, path)
+	}
+	return item
+}
+
+// capitalize upper-cases the first letter of s and lower-cases the rest
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + strings.ToLower(s[1:])
+}
+
+// title capitalizes the words of s, as Python's str.title does
+func title(s string) string {
+	var b strings.Builder
+	start := true
+	for _, r := range s {
+		if unicode.IsLetter(r) {
+			if start {
+				r = unicode.ToUpper(r)
+			} else {
+				r = unicode.ToLower(r)
+			}
+			start = false
+		} else {
+			start = true
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func (s *state) applyFilter(n string, value any, args []any, kwargs map[string]any) (any, error) {
+	if f, ok := keywordFilters[n]; ok {
+		if u, isUndefined := value.(undefined); isUndefined {
+			return nil, fmt.Errorf("%s is undefined", u.name)
+		}
+		return f(value, args, kwargs)
+	}
+	if f, ok := builtinFilters[n]; ok {
+		if _, isUndefined := value.(undefined); isUndefined && n != "default" && n != "d" {
+			return nil, fmt.Errorf("%s is undefined", value.(undefined).name)
+		}
+		args, err := bindArgs(n, args, kwargs)
+		if err != nil {
+			return nil, err
+		}
+		return f(value, args)
+	}
+	if f, ok := s.env.Filters[n]; ok {
+		if u, isUndefined := value.(undefined); isUndefined {
+			return nil, fmt.Errorf("%s is undefined", u.name)
+		}
+		if len(kwargs) > 0 {
+			return nil, fmt.Errorf("filter %s does not take keyword arguments", n)
+		}
+		result, err := callFunc(reflect.ValueOf(f), append([]any{value}, args...))
+		if err != nil {
+			return nil, fmt.Errorf("filter %s: %w", n, err)
+		}
+		return result, nil
+	}
+	return nil, fmt.Errorf("unknown filter %s", n)
+}
+
+// applyTest implements value is name(args)
+func applyTest(n string, value any, args []any) (bool, error) {
+	_, isUndefined := value.(undefined)
+	switch n {
+	case "defined":
+		return !isUndefined, nil
+	case "undefined":
+		return isUndefined, nil
+	case "none":
+		return value == nil || !isUndefined && !indirect(reflect.ValueOf(value)).IsValid(), nil
+	case "string":
+		_, ok := asString(value)
+		return ok, nil
+	case "number":
+		_, ok := toNumber(value)
+		return ok, nil
+	case "boolean":
+		_, ok := value.(bool)
+		return ok, nil
+	case "sequence", "iterable":
+		_, err := iterate(value)
+		return !isUndefined && value != nil && err == nil, nil
+	case "mapping":
+		return indirect(reflect.ValueOf(value)).Kind() == reflect.Map, nil
+	case "even", "odd", "divisibleby":
+		num, ok := toNumber(value)
+		i, isInt := num.(int)
+		if !ok || !isInt {
+			return false, fmt.Errorf("%s is not an integer", toString(value))
+		}
+		switch n {
+		case "even":
+			return i%2 == 0, nil
+		case "odd":
+			return i%2 != 0, nil
+		}
+		d, ok := toNumber(arg(args, 0, nil))
+		if !ok || toFloat(d) == 0 {
+			return false, fmt.Errorf("divisibleby needs a non-zero number")
+		}
+		return i%int(toFloat(d)) == 0, nil
+	case "eq", "equalto", "sameas":
+		return equal(value, arg(args, 0, nil)), nil
+	case "ne":
+		return !equal(value, arg(args, 0, nil)), nil
+	case "in":
+		return contains(arg(args, 0, nil), value)
+	}
+	return false, fmt.Errorf("unknown test %s", n)
+}
+
+// maxRange bounds the lists range builds, as Jinja2 does
+const maxRange = 100000
+
+// builtinGlobals are the functions templates can call without Env.Globals
+var builtinGlobals = map[string]any{
+	// range(stop), range(start, stop) and range(start, stop, step), as in
+	// Python: {% for i in range(3) %}
+	"range": func(args ...int) ([]any, error) {
+		start, stop, step := 0, 0, 1
+		switch len(args) {
+		case 1:
+			stop = args[0]
+		case 2:
+			start, stop = args[0], args[1]
+		case 3:
+			start, stop, step = args[0], args[1], args[2]
+		default:
+			return nil, fmt.Errorf("range expects 1 to 3 arguments, got %d", len(args))
+		}
+		if step == 0 {
+			return nil, fmt.Errorf("range step cannot be zero")
+		}
+		items := []any{}
+		for i := start; step > 0 && i < stop || step < 0 && i > stop; i += step {
+			if len(items) == maxRange {
+				return nil, fmt.Errorf("range is too big, at most %d items", maxRange)
+			}
+			items = append(items, i)
+		}
+		return items, nil
+	},
+}
+
+// stringMethods are the methods of Python strings templates can call, e.g.
+// {{ name.lower().replace(' ', '_') }}
+var stringMethods = map[string]func(s string, args []any) (any, error){
+	"lower":      func(s string, _ []any) (any, error) { return strings.ToLower(s), nil },
+	"upper":      func(s string, _ []any) (any, error) { return strings.ToUpper(s), nil },
+	"title":      func(s string, _ []any) (any, error) { return title(s), nil },
+	"capitalize": func(s string, _ []any) (any, error) { return capitalize(s), nil },
+	"strip": func(s string, args []any) (any, error) {
+		if chars, ok := asString(arg(args, 0, nil)); ok {
+			return strings.Trim(s, chars), nil
+		}
+		return strings.TrimSpace(s), nil
+	},
+	"lstrip": func(s string, args []any) (any, error) {
+		if chars, ok := asString(arg(args, 0, nil)); ok {
+			return strings.TrimLeft(s, chars), nil
+		}
+		return strings.TrimLeftFunc(s, unicode.IsSpace), nil
+	},
+	"rstrip": func(s string, args []any) (any, error) {
+		if chars, ok := asString(arg(args, 0, nil)); ok {
+			return strings.TrimRight(s, chars), nil
+		}
+		return strings.TrimRightFunc(s, unicode.IsSpace), nil
+	},
+	"replace": func(s string, args []any) (any, error) {
+		if len(args) < 2 {
+			return nil, fmt.Errorf("replace needs the old and new strings")
+		}
+		return strings.ReplaceAll(s, toString(args[0]), toString(args[1])), nil
+	},
+	"split": func(s string, args []any) (any, error) {
+		var parts []string
+		if sep, ok := asString(arg(args, 0, nil)); ok {
+			parts = strings.Split(s, sep)
+		} else {
+			parts = strings.Fields(s)
+		}
+		items := make([]any, len(parts))
+		for i, part := range parts {
+			items[i] = part
+		}
+		return items, nil
+	},
+	"startswith": func(s string, args []any) (any, error) {
+		return strings.HasPrefix(s, toString(arg(args, 0, ""))), nil
+	},
+	"endswith": func(s string, args []any) (any, error) {
+		return strings.HasSuffix(s, toString(arg(args, 0, ""))), nil
+	},
+	"join": func(s string, args []any) (any, error) {
+		items, err := iterate(arg(args, 0, nil))
+		if err != nil {
+			return nil, err
+		}
+		parts := make([]string, len(items))
+		for i, item := range items {
+			parts[i] = toString(item)
+		}
+		return strings.Join(parts, s), nil
+	},
+	"format": func(s string, args []any) (any, error) {
+		for _, a := range args {
+			s = strings.Replace(s, "{}", toString(a), 1)
+		}
+		return s, nil
+	},
+	"isdigit": func(s string, _ []any) (any, error) {
+		return s != "" && strings.IndexFunc(s, func(r rune) bool { return !unicode.IsDigit(r) }) < 0, nil
+	},
+}
+
+// mapMethods are the methods of Python dicts templates can call, e.g.
+// {% for key, value in options.items() %}
+var mapMethods = map[string]func(m reflect.Value, args []any) (any, error){
+	"items": func(m reflect.Value, _ []any) (any, error) {
+		var items []any
+		for _, key := range sortedKeys(m) {
+			items = append(items, []any{key.Interface(), m.MapIndex(key).Interface()})
+		}
+		return items, nil
+	},
+	"keys": func(m reflect.Value, _ []any) (any, error) {
+		var keys []any
+		for _, key := range sortedKeys(m) {
+			keys = append(keys, key.Interface())
+		}
+		return keys, nil
+	},
+	"values": func(m reflect.Value, _ []any) (any, error) {
+		var values []any
+		for _, key := range sortedKeys(m) {
+			values = append(values, m.MapIndex(key).Interface())
+		}
+		return values, nil
+	},
+	"get": func(m reflect.Value, args []any) (any, error) {
+		if value, ok := getAttr(m.Interface(), toString(arg(args, 0, ""))); ok {
+			return value, nil
+		}
+		return arg(args, 1, nil), nil
+	},
+}
+
+// isBuiltinMethod reports whether target has a Python method named n
+func isBuiltinMethod(target any, n string) bool {
+	if _, ok := asString(target); ok {
+		_, ok := stringMethods[n]
+		return ok
+	}
+	if indirect(reflect.ValueOf(target)).Kind() == reflect.Map {
+		_, ok := mapMethods[n]
+		return ok
+	}
+	return false
+}
+
+func callMethod(m method, args []any) (any, error) {
+	if s, ok := asString(m.receiver); ok {
+		return stringMethods[m.name](s, args)
+	}
+	return mapMethods[m.name](indirect(reflect.ValueOf(m.receiver)), args)
+}
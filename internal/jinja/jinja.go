@@ -0,0 +1,223 @@
+// Package jinja renders templates written in the Jinja2 syntax cookiecutter
+// templates use, so template libraries written for Jinja2 can be rendered by
+// devinit without rewriting them as Go templates.
+//
+// It covers the subset of Jinja2 such templates rely on: {{ }} expressions
+// with filters (keyword arguments included), tests, attribute and item
+// lookups, slices, % formatting, range and the string methods of Python
+// (lower, replace, split, ...);
+// {% if %}, {% for %} (with loop and else), {% set %} and {% raw %}; {# #}
+// comments; and "-" whitespace control. Macros, includes and template
+// inheritance are not supported.
+// As in cookiecutter, a trailing newline is kept and printing an undefined
+// name is an error.
+package jinja
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Template is a parsed Jinja2 template
+type Template struct {
+	name  string
+	nodes []node
+}
+
+// Env is what a template is executed against
+type Env struct {
+	// Globals are the names templates see, e.g. the template variables
+	Globals map[string]any
+
+	// Data resolves the names Globals lacks as its fields, map keys or
+	// methods without arguments, like the dot of a Go template
+	Data any
+
+	// Filters are functions applied with value|name(args), taking the
+	// filtered value first; builtin filters of the same name take
+	// precedence
+	Filters map[string]any
+}
+
+// Parse parses a template named name, which prefixes its errors
+func Parse(name, text string) (*Template, error) {
+	segments, err := split(text)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	nodes, err := parseTemplate(segments)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return &Template{name: name, nodes: nodes}, nil
+}
+
+// Execute renders the template to w
+func (t *Template) Execute(w io.Writer, env Env) error {
+	s := &state{env: env, scopes: []map[string]any{{}}}
+	var b strings.Builder
+	if err := s.run(&b, t.nodes); err != nil {
+		return fmt.Errorf("%s: %w", t.name, err)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Render parses and executes a template
+func Render(name, text string, env Env) (string, error) {
+	t, err := Parse(name, text)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, env); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// state is the state of an execution: the environment and the scopes of
+// the variables set by {% set %} and {% for %}, innermost last
+type state struct {
+	env    Env
+	scopes []map[string]any
+}
+
+func (s *state) run(b *strings.Builder, nodes []node) error {
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case textNode:
+			b.WriteString(n.text)
+		case printNode:
+			value, err := s.eval(n.expr)
+			if err != nil {
+				return fmt.Errorf("line %d: %w", n.line, err)
+			}
+			if u, ok := value.(undefined); ok {
+				return fmt.Errorf("line %d: %s is undefined", n.line, u.name)
+			}
+			b.WriteString(toString(value))
+		case ifNode:
+			for i, cond := range n.conds {
+				value, err := s.eval(cond)
+				if err != nil {
+					return err
+				}
+				if truthy(value) {
+					if err := s.run(b, n.bodies[i]); err != nil {
+						return err
+					}
+					break
+				}
+				if i == len(n.conds)-1 && len(n.bodies) > len(n.conds) {
+					if err := s.run(b, n.bodies[len(n.conds)]); err != nil {
+						return err
+					}
+				}
+			}
+		case forNode:
+			if err := s.runFor(b, n); err != nil {
+				return err
+			}
+		case setNode:
+			value, err := s.eval(n.expr)
+			if err != nil {
+				return err
+			}
+			s.scopes[len(s.scopes)-1][n.name] = value
+		}
+	}
+	return nil
+}
+
+func (s *state) runFor(b *strings.Builder, n forNode) error {
+	value, err := s.eval(n.iter)
+	if err != nil {
+		return fmt.Errorf("line %d: %w", n.line, err)
+	}
+	items, err := iterate(value)
+	if err != nil {
+		return fmt.Errorf("line %d: %w", n.line, err)
+	}
+
+	scope := make(map[string]any)
+	s.scopes = append(s.scopes, scope)
+	defer func() { s.scopes = s.scopes[:len(s.scopes)-1] }()
+
+	bind := func(item any) error {
+		if len(n.targets) == 1 {
+			scope[n.targets[0]] = item
+			return nil
+		}
+		values, err := iterate(item)
+		if err != nil || len(values) != len(n.targets) {
+			return fmt.Errorf("line %d: cannot unpack %s into %d loop variables", n.line, toString(item), len(n.targets))
+		}
+		for i, target := range n.targets {
+			scope[target] = values[i]
+		}
+		return nil
+	}
+
+	if n.filter != nil {
+		var kept []any
+		for _, item := range items {
+			if err := bind(item); err != nil {
+				return err
+			}
+			ok, err := s.eval(n.filter)
+			if err != nil {
+				return fmt.Errorf("line %d: %w", n.line, err)
+			}
+			if truthy(ok) {
+				kept = append(kept, item)
+			}
+		}
+		items = kept
+	}
+
+	if len(items) == 0 {
+		return s.run(b, n.empty)
+	}
+	for i, item := range items {
+		if err := bind(item); err != nil {
+			return err
+		}
+		scope["loop"] = map[string]any{
+			"index":     i + 1,
+			"index0":    i,
+			"revindex":  len(items) - i,
+			"revindex0": len(items) - i - 1,
+			"first":     i == 0,
+			"last":      i == len(items)-1,
+			"length":    len(items),
+		}
+		if err := s.run(b, n.body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lookup resolves a name in the scopes, then the globals, then the data,
+// then the builtin functions
+func (s *state) lookup(n string) any {
+	for i := len(s.scopes) - 1; i >= 0; i-- {
+		if value, ok := s.scopes[i][n]; ok {
+			return value
+		}
+	}
+	if value, ok := s.env.Globals[n]; ok {
+		return value
+	}
+	if s.env.Data != nil {
+		if value, ok := getAttr(s.env.Data, n); ok {
+			return value
+		}
+	}
+	if fn, ok := builtinGlobals[n]; ok {
+		return fn
+	}
+	return undefined{n}
+}
@@ -0,0 +1,132 @@
+package jinja
+
+import (
+	"strings"
+	"testing"
+)
+
+type project struct {
+	Name  string
+	Tags  []string
+	owner string
+}
+
+func (p *project) Slug() string {
+	return strings.ToLower(p.Name)
+}
+
+func TestRender(t *testing.T) {
+	env := Env{
+		Globals: map[string]any{
+			"project_name": "My Project",
+			"use_docker":   "y",
+			"python":       "3.12",
+			"licenses":     []string{"MIT", "BSD"},
+			"options":      map[string]any{"b": 2, "a": 1},
+			"count":        3,
+			"empty":        "",
+		},
+		Data:    &project{Name: "Demo", Tags: []string{"api", "grpc"}, owner: "hidden"},
+		Filters: map[string]any{"snake": func(s string) string { return strings.ReplaceAll(strings.ToLower(s), " ", "_") }},
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{name: "text", template: "plain text\n", want: "plain text\n"},
+		{name: "variable", template: "{{ project_name }}", want: "My Project"},
+		{name: "string methods", template: "{{ project_name.lower().replace(' ', '_') }}", want: "my_project"},
+		{name: "filters", template: "{{ project_name|lower|replace(' ', '-') }} {{ licenses|join(', ') }} {{ licenses|length }}", want: "my-project MIT, BSD 2"},
+		{name: "env filter", template: "{{ project_name | snake }}", want: "my_project"},
+		{name: "default", template: "{{ missing|default('none') }} {{ empty|default('blank', true) }} {{ missing.attr|d('x') }}", want: "none blank x"},
+		{name: "data fields and methods", template: "{{ Name }} {{ Slug }} {{ Tags[1] }} {{ Tags[-1] }}", want: "Demo demo grpc grpc"},
+		{name: "if", template: "{% if use_docker == 'y' %}docker{% elif use_docker == 'n' %}none{% else %}?{% endif %}", want: "docker"},
+		{name: "elif", template: "{% if count > 5 %}big{% elif count > 2 %}medium{% else %}small{% endif %}", want: "medium"},
+		{name: "else", template: "{% if missing %}yes{% else %}no{% endif %}", want: "no"},
+		{name: "not in", template: "{% if 'GPL' not in licenses and 'MIT' in licenses %}ok{% endif %}", want: "ok"},
+		{name: "for", template: "{% for l in licenses %}{{ loop.index }}.{{ l }}{% if not loop.last %},{% endif %}{% endfor %}", want: "1.MIT,2.BSD"},
+		{name: "for items", template: "{% for k, v in options.items() %}{{ k }}={{ v }} {% endfor %}", want: "a=1 b=2 "},
+		{name: "for filter and else", template: "{% for l in licenses if l == 'GPL' %}{{ l }}{% else %}no GPL{% endfor %}", want: "no GPL"},
+		{name: "set", template: "{% set slug = project_name|lower %}{{ slug ~ '-' ~ count }}", want: "my project-3"},
+		{name: "arithmetic", template: "{{ count * 2 + 1 }} {{ 7 // 2 }} {{ 7 / 2 }} {{ -count }}", want: "7 3 3.5 -3"},
+		{name: "conditional expression", template: "{{ 'yes' if count is odd else 'no' }}", want: "yes"},
+		{name: "tests", template: "{{ missing is defined }} {{ count is number }} {{ python is string }} {{ options is mapping }}", want: "False True True True"},
+		{name: "split", template: "{{ python.split('.')[0] }}", want: "3"},
+		{name: "comment", template: "a{# note #}b", want: "ab"},
+		{name: "whitespace control", template: "a\n  {%- if true -%}\n  b\n  {%- endif %}\nc", want: "ab\nc"},
+		{name: "raw", template: "{% raw %}{{ not rendered }}{% endraw %}", want: "{{ not rendered }}"},
+		{name: "literals", template: "{{ [1, 'a', true, none] }} {{ {'k': 1}.k }}", want: "[1, 'a', True, None] 1"},
+		{name: "trailing newline kept", template: "{{ count }}\n", want: "3\n"},
+		{name: "unexported field", template: "{{ owner|default('-') }}", want: "-"},
+		{name: "slices", template: "{{ project_name[:2] }} {{ project_name[3:] }} {{ python[::-1] }} {{ licenses[-1:] }} {{ licenses[5:] }}", want: "My Project 21.3 ['BSD'] []"},
+		{name: "percent formatting", template: "{{ '%s-%d' % (project_name, count) }} {{ '%05.1f%%' % 2.25 }} {{ '%(a)s/%(b)s' % options }} {{ '%r' % python }}", want: "My Project-3 002.2% 1/2 '3.12'"},
+		{name: "tuples", template: "{{ (1, 'a') }} {{ (count,) }} {{ (1, 2, 3)[1:] }}", want: "(1, 'a') (3,) (2, 3)"},
+		{name: "range", template: "{% for i in range(3) %}{{ i }}{% endfor %} {{ range(1, 10, 4) }} {{ range(3, 0, -1)|join(',') }}", want: "012 [1, 5, 9] 3,2,1"},
+		{name: "test arguments", template: "{{ count is divisibleby 3 }} {{ count is divisibleby(2) }} {{ 'yes' if count is eq 3 else 'no' }} {{ count is sameas 3 and true }}", want: "True False yes True"},
+		{name: "keyword arguments", template: "{{ [{'n': 'b'}, {'n': 'a'}]|sort(attribute='n')|join(',', attribute='n') }} {{ licenses|sort(reverse=true)|join }} {{ empty|default(boolean=true, default_value='-') }} {{ licenses|join(d='/') }}", want: "a,b MITBSD - MIT/BSD"},
+		{name: "sort", template: "{{ ['b', 'A', 'c']|sort|join }} {{ ['b', 'A', 'c']|sort(case_sensitive=true)|join }} {{ [3, 1, 2]|sort(true)|join }}", want: "Abc Abc 321"},
+		{name: "attribute", template: "{{ [{'n': {'v': 2} }, {'n': {'v': 1} }]|sort(attribute='n.v')|join(',', attribute='n.v') }}", want: "1,2"},
+		{name: "indent", template: "{{ 'a\n\nb'|indent(2) }}|{{ 'a\nb'|indent(first=true, width='> ') }}|{{ 'a\n\nb'|indent(2, blank=true) }}", want: "a\n\n  b|> a\n> b|a\n  \n  b"},
+		{name: "int and replace", template: "{{ '0x1F'|int(base=16) }} {{ 'x'|int(default=7) }} {{ 'aaa'|replace('a', 'b', count=2) }} {{ options|tojson(indent=1) }}", want: "31 7 bba {\n \"a\": 1,\n \"b\": 2\n}"},
+		{name: "format", template: "{{ '%s-%03d'|format(project_name, count) }} {{ '%(a)s+%(b)s'|format(a=1, b='x') }} {{ 'plain'|format }}", want: "My Project-003 1+x plain"},
+		{name: "truncate", template: "{{ 'hello big world'|truncate(9, leeway=0) }}|{{ 'hello big world'|truncate(9, true, leeway=0) }}|{{ 'hello big world'|truncate(12) }}|{{ 'hello big world'|truncate(length=10, end='~', leeway=0) }}", want: "hello...|hello ...|hello big world|hello~"},
+		{name: "power", template: "{{ 2 ** 3 }} {{ 2 ** 3 ** 2 }} {{ 2 ** -1 }} {{ -2 ** 2 }} {{ 2 * 3 ** 2 }}", want: "8 64 0.5 4 18"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render("test", tt.template, env)
+			if err != nil {
+				t.Fatalf("Render() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		wantErr  string
+	}{
+		{name: "undefined", template: "line 1\n{{ missing }}", wantErr: "test: line 2: missing is undefined"},
+		{name: "undefined attribute", template: "{{ cookiecutter.name }}", wantErr: "cookiecutter.name is undefined"},
+		{name: "unclosed print", template: "{{ name", wantErr: "unclosed {{"},
+		{name: "unclosed if", template: "{% if x %}yes", wantErr: "expected endif, found end of template"},
+		{name: "stray endfor", template: "{% endfor %}", wantErr: "unexpected endfor"},
+		{name: "unsupported tag", template: "{% macro m() %}{% endmacro %}", wantErr: `unsupported tag "macro"`},
+		{name: "unknown filter", template: "{{ 'a'|shout }}", wantErr: "unknown filter shout"},
+		{name: "syntax", template: "{{ a + }}", wantErr: "unexpected end of expression"},
+		{name: "unclosed raw", template: "{% raw %}x", wantErr: "raw block is not closed"},
+		{name: "slice step zero", template: "{{ 'abc'[::0] }}", wantErr: "slice step cannot be zero"},
+		{name: "too few format arguments", template: "{{ '%s %s' % ('a',) }}", wantErr: "not enough arguments"},
+		{name: "too many format arguments", template: "{{ '%s' % ('a', 'b') }}", wantErr: "not all arguments converted"},
+		{name: "format needs a number", template: "{{ '%d' % 'a' }}", wantErr: "a number is required"},
+		{name: "range too big", template: "{{ range(1000000) }}", wantErr: "range is too big"},
+		{name: "unknown keyword argument", template: "{{ [1]|sort(key='x') }}", wantErr: "filter sort has no argument key"},
+		{name: "keyword argument twice", template: "{{ [1]|sort(true, reverse=false) }}", wantErr: "filter sort got argument reverse twice"},
+		{name: "keyword argument repeated", template: "{{ [1]|sort(reverse=true, reverse=false) }}", wantErr: "keyword argument reverse repeated"},
+		{name: "positional after keyword", template: "{{ [1]|join(d=',', 'x') }}", wantErr: "positional argument follows keyword argument"},
+		{name: "keyword argument of a filter without arguments", template: "{{ 'a'|upper(first=true) }}", wantErr: "filter upper has no argument first"},
+		{name: "keyword argument of a call", template: "{{ range(stop=3) }}", wantErr: "range does not take keyword arguments"},
+		{name: "keyword argument of a test", template: "{{ 3 is divisibleby(num=3) }}", wantErr: "test divisibleby does not take keyword arguments"},
+		{name: "format with both arguments", template: "{{ '%s'|format(1, a=2) }}", wantErr: "not both"},
+		{name: "truncate shorter than its end", template: "{{ 'abc'|truncate(2) }}", wantErr: "shorter than the end"},
+		{name: "replace without new", template: "{{ 'abc'|replace(old='a') }}", wantErr: "replace needs the old and new strings"},
+		{name: "empty subscript", template: "{{ 'abc'[] }}", wantErr: `unexpected "]"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Render("test", tt.template, Env{})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Render() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package jinja
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// segmentKind is the kind of a piece of template text
+type segmentKind int
+
+const (
+	segmentText  segmentKind = iota
+	segmentPrint             // {{ expression }}
+	segmentTag               // {% statement %}
+)
+
+// segment is a piece of template text: literal text, or the inside of a
+// print or tag delimiter
+type segment struct {
+	kind segmentKind
+	text string
+	line int
+}
+
+// endRaw matches the tag closing a raw block
+var endRaw = regexp.MustCompile(`\{%-?\s*endraw\s*-?%\}`)
+
+// split cuts a template into text, print and tag segments. Comments are
+// dropped, raw blocks become text, and "-" next to a delimiter strips the
+// whitespace on that side.
+func split(text string) ([]segment, error) {
+	var segments []segment
+	line := 1
+	trimNext := false
+
+	// emit adds literal text, stripped as the delimiters around it ask
+	emit := func(s string, trimRight bool) {
+		lines := strings.Count(s, "\n")
+		if trimNext {
+			trimmed := strings.TrimLeftFunc(s, unicode.IsSpace)
+			line += lines - strings.Count(trimmed, "\n")
+			lines, s = strings.Count(trimmed, "\n"), trimmed
+		}
+		if trimRight {
+			s = strings.TrimRightFunc(s, unicode.IsSpace)
+		}
+		if s != "" {
+			segments = append(segments, segment{kind: segmentText, text: s, line: line})
+		}
+		line += lines
+		trimNext = false
+	}
+
+	for len(text) > 0 {
+		start := nextDelimiter(text)
+		if start < 0 {
+			emit(text, false)
+			break
+		}
+
+		open := text[start : start+2]
+		rest := text[start+2:]
+		trimLeft := strings.HasPrefix(rest, "-")
+		if trimLeft {
+			rest = rest[1:]
+		}
+		emit(text[:start], trimLeft)
+
+		closer := map[string]string{"{{": "}}", "{%": "%}", "{#": "#}"}[open]
+		end := strings.Index(rest, closer)
+		if end < 0 {
+			return nil, fmt.Errorf("line %d: unclosed %s", line, open)
+		}
+		inner := rest[:end]
+		text = rest[end+2:]
+		if strings.HasSuffix(inner, "-") {
+			inner = inner[:len(inner)-1]
+			trimNext = true
+		}
+
+		switch {
+		case open == "{{":
+			segments = append(segments, segment{kind: segmentPrint, text: inner, line: line})
+		case open == "{%" && strings.TrimSpace(inner) == "raw":
+			loc := endRaw.FindStringIndex(text)
+			if loc == nil {
+				return nil, fmt.Errorf("line %d: raw block is not closed with endraw", line)
+			}
+			raw, closing := text[:loc[0]], text[loc[0]:loc[1]]
+			text = text[loc[1]:]
+			line += strings.Count(inner, "\n")
+			emit(raw, strings.HasPrefix(closing, "{%-"))
+			line += strings.Count(closing, "\n")
+			trimNext = strings.HasSuffix(closing, "-%}")
+			continue
+		case open == "{%":
+			segments = append(segments, segment{kind: segmentTag, text: inner, line: line})
+		}
+		line += strings.Count(inner, "\n")
+	}
+	return segments, nil
+}
+
+// nextDelimiter returns the index of the next {{, {% or {#, or -1
+func nextDelimiter(text string) int {
+	for i := 0; i+1 < len(text); i++ {
+		if text[i] == '{' && (text[i+1] == '{' || text[i+1] == '%' || text[i+1] == '#') {
+			return i
+		}
+	}
+	return -1
+}
+
+// tokenKind is the kind of an expression token
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenName
+	tokenString
+	tokenNumber
+	tokenOperator
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// operators are the expression operators, longest first
+var operators = []string{"==", "!=", "<=", ">=", "//", "**", "<", ">", "+", "-", "*", "/", "%", "~", "|", ".", ",", "(", ")", "[", "]", "{", "}", ":", "="}
+
+// tokenize splits the expression of a print or tag segment into tokens
+func tokenize(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '_' || unicode.IsLetter(rune(c)):
+			j := i
+			for j < len(src) && (src[j] == '_' || unicode.IsLetter(rune(src[j])) || unicode.IsDigit(rune(src[j]))) {
+				j++
+			}
+			tokens = append(tokens, token{tokenName, src[i:j]})
+			i = j
+		case unicode.IsDigit(rune(c)):
+			j := i
+			for j < len(src) && (unicode.IsDigit(rune(src[j])) || src[j] == '_' ||
+				src[j] == '.' && j+1 < len(src) && unicode.IsDigit(rune(src[j+1]))) {
+				j++
+			}
+			tokens = append(tokens, token{tokenNumber, strings.ReplaceAll(src[i:j], "_", "")})
+			i = j
+		case c == '"' || c == '\'':
+			s, n, err := unquote(src[i:])
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{tokenString, s})
+			i += n
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(src[i:], op) {
+					tokens = append(tokens, token{tokenOperator, op})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected character %q", c)
+			}
+		}
+	}
+	return append(tokens, token{kind: tokenEOF}), nil
+}
+
+// unquote reads the string literal at the start of src, returning its value
+// and length
+func unquote(src string) (string, int, error) {
+	quote := src[0]
+	var b strings.Builder
+	for i := 1; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case c == quote:
+			return b.String(), i + 1, nil
+		case c == '\\' && i+1 < len(src):
+			i++
+			switch src[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			default:
+				b.WriteByte(src[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
@@ -0,0 +1,755 @@
+package jinja
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// node is a statement of a parsed template
+type node interface{}
+
+type textNode struct{ text string }
+
+type printNode struct {
+	expr expr
+	line int
+}
+
+type ifNode struct {
+	conds  []expr
+	bodies [][]node // one per condition, plus the else body if any
+}
+
+type forNode struct {
+	targets []string
+	iter    expr
+	filter  expr // for x in xs if filter
+	body    []node
+	empty   []node // else body, run when nothing was iterated
+	line    int
+}
+
+type setNode struct {
+	name string
+	expr expr
+}
+
+// expr is an expression
+type expr interface{}
+
+type literal struct{ value any }
+
+type name struct{ name string }
+
+// listExpr is a list literal, or a tuple: (a, b)
+type listExpr struct {
+	items []expr
+	tuple bool
+}
+
+type dictExpr struct{ keys, values []expr }
+
+type attr struct {
+	target expr
+	name   string
+}
+
+type index struct{ target, key expr }
+
+// sliceExpr is target[start:stop:step]; missing bounds are nil
+type sliceExpr struct{ target, start, stop, step expr }
+
+type call struct {
+	target expr
+	args   []expr
+	kwargs []kwarg
+}
+
+type filter struct {
+	target expr
+	name   string
+	args   []expr
+	kwargs []kwarg
+}
+
+type test struct {
+	target expr
+	name   string
+	args   []expr
+	kwargs []kwarg
+	negate bool
+}
+
+// kwarg is a keyword argument, name=value
+type kwarg struct {
+	name  string
+	value expr
+}
+
+type unary struct {
+	op      string // not, -
+	operand expr
+}
+
+type binary struct {
+	op          string
+	left, right expr
+}
+
+type conditional struct{ cond, then, otherwise expr }
+
+// parseTemplate turns the segments of a template into statements
+func parseTemplate(segments []segment) ([]node, error) {
+	p := &templateParser{segments: segments}
+	nodes, end, err := p.parseBody()
+	if err != nil {
+		return nil, err
+	}
+	if end != "" {
+		return nil, fmt.Errorf("line %d: unexpected %s", p.segments[p.pos-1].line, end)
+	}
+	return nodes, nil
+}
+
+type templateParser struct {
+	segments []segment
+	pos      int
+}
+
+// parseBody reads statements up to a tag it does not open (elif, else,
+// endif, endfor), which it returns with its expression tokens, or to the end
+// of the template
+func (p *templateParser) parseBody() ([]node, string, error) {
+	var nodes []node
+	for p.pos < len(p.segments) {
+		seg := p.segments[p.pos]
+		p.pos++
+		switch seg.kind {
+		case segmentText:
+			nodes = append(nodes, textNode{seg.text})
+		case segmentPrint:
+			e, err := parseExpression(seg.text)
+			if err != nil {
+				return nil, "", fmt.Errorf("line %d: %w", seg.line, err)
+			}
+			nodes = append(nodes, printNode{e, seg.line})
+		case segmentTag:
+			keyword, rest, _ := strings.Cut(strings.TrimSpace(seg.text), " ")
+			var n node
+			var err error
+			switch keyword {
+			case "if":
+				n, err = p.parseIf(rest, seg.line)
+			case "for":
+				n, err = p.parseFor(rest, seg.line)
+			case "set":
+				n, err = parseSet(rest)
+			case "elif", "else", "endif", "endfor":
+				return nodes, keyword, nil
+			case "":
+				err = fmt.Errorf("empty tag")
+			default:
+				err = fmt.Errorf("unsupported tag %q", keyword)
+			}
+			if err != nil {
+				return nil, "", fmt.Errorf("line %d: %w", seg.line, err)
+			}
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes, "", nil
+}
+
+// tagArgs returns the text after the keyword of the tag that ended a body
+func (p *templateParser) tagArgs() string {
+	_, rest, _ := strings.Cut(strings.TrimSpace(p.segments[p.pos-1].text), " ")
+	return rest
+}
+
+func (p *templateParser) parseIf(cond string, line int) (node, error) {
+	var n ifNode
+	for {
+		e, err := parseExpression(cond)
+		if err != nil {
+			return nil, err
+		}
+		body, end, err := p.parseBody()
+		if err != nil {
+			return nil, err
+		}
+		n.conds = append(n.conds, e)
+		n.bodies = append(n.bodies, body)
+
+		switch end {
+		case "elif":
+			cond = p.tagArgs()
+		case "else":
+			body, end, err := p.parseBody()
+			if err != nil {
+				return nil, err
+			}
+			if end != "endif" {
+				return nil, fmt.Errorf("if at line %d: expected endif, found %s", line, orEnd(end))
+			}
+			n.bodies = append(n.bodies, body)
+			return n, nil
+		case "endif":
+			return n, nil
+		default:
+			return nil, fmt.Errorf("if at line %d: expected endif, found %s", line, orEnd(end))
+		}
+	}
+}
+
+func (p *templateParser) parseFor(src string, line int) (node, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	ep := &exprParser{tokens: tokens}
+
+	n := forNode{line: line}
+	for {
+		tok := ep.next()
+		if tok.kind != tokenName {
+			return nil, fmt.Errorf("expected a loop variable, found %s", describe(tok))
+		}
+		n.targets = append(n.targets, tok.text)
+		if !ep.accept(",") {
+			break
+		}
+	}
+	if !ep.acceptName("in") {
+		return nil, fmt.Errorf("expected in, found %s", describe(ep.peek()))
+	}
+	// Parse at the or level so a trailing "if" filters instead of starting
+	// a conditional expression
+	if n.iter, err = ep.parseOr(); err != nil {
+		return nil, err
+	}
+	if ep.acceptName("if") {
+		if n.filter, err = ep.parseOr(); err != nil {
+			return nil, err
+		}
+	}
+	if err := ep.end(); err != nil {
+		return nil, err
+	}
+
+	body, end, err := p.parseBody()
+	if err != nil {
+		return nil, err
+	}
+	n.body = body
+	if end == "else" {
+		if n.empty, end, err = p.parseBody(); err != nil {
+			return nil, err
+		}
+	}
+	if end != "endfor" {
+		return nil, fmt.Errorf("for at line %d: expected endfor, found %s", line, orEnd(end))
+	}
+	return n, nil
+}
+
+func parseSet(src string) (node, error) {
+	target, value, ok := strings.Cut(src, "=")
+	target = strings.TrimSpace(target)
+	if !ok || !isName(target) {
+		return nil, fmt.Errorf("expected set name = value")
+	}
+	e, err := parseExpression(value)
+	if err != nil {
+		return nil, err
+	}
+	return setNode{target, e}, nil
+}
+
+func orEnd(tag string) string {
+	if tag == "" {
+		return "end of template"
+	}
+	return tag
+}
+
+func isName(s string) bool {
+	tokens, err := tokenize(s)
+	return err == nil && len(tokens) == 2 && tokens[0].kind == tokenName
+}
+
+// parseExpression parses the expression of a print or tag segment
+func parseExpression(src string) (expr, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{tokens: tokens}
+	e, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	return e, p.end()
+}
+
+type exprParser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *exprParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// accept consumes the next token if it is the operator op
+func (p *exprParser) accept(op string) bool {
+	if tok := p.peek(); tok.kind == tokenOperator && tok.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// acceptName consumes the next token if it is the keyword word
+func (p *exprParser) acceptName(word string) bool {
+	if tok := p.peek(); tok.kind == tokenName && tok.text == word {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) expect(op string) error {
+	if !p.accept(op) {
+		return fmt.Errorf("expected %q, found %s", op, describe(p.peek()))
+	}
+	return nil
+}
+
+func (p *exprParser) end() error {
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return fmt.Errorf("unexpected %s", describe(tok))
+	}
+	return nil
+}
+
+func describe(tok token) string {
+	switch tok.kind {
+	case tokenEOF:
+		return "end of expression"
+	case tokenString:
+		return strconv.Quote(tok.text)
+	}
+	return fmt.Sprintf("%q", tok.text)
+}
+
+// parseExpr reads a conditional expression: a if cond else b
+func (p *exprParser) parseExpr() (expr, error) {
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.acceptName("if") {
+		return e, nil
+	}
+	cond, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	var otherwise expr = literal{nil}
+	if p.acceptName("else") {
+		if otherwise, err = p.parseExpr(); err != nil {
+			return nil, err
+		}
+	}
+	return conditional{cond, e, otherwise}, nil
+}
+
+func (p *exprParser) parseOr() (expr, error) {
+	left, err := p.parseAnd()
+	for err == nil && p.acceptName("or") {
+		var right expr
+		if right, err = p.parseAnd(); err == nil {
+			left = binary{"or", left, right}
+		}
+	}
+	return left, err
+}
+
+func (p *exprParser) parseAnd() (expr, error) {
+	left, err := p.parseNot()
+	for err == nil && p.acceptName("and") {
+		var right expr
+		if right, err = p.parseNot(); err == nil {
+			left = binary{"and", left, right}
+		}
+	}
+	return left, err
+}
+
+func (p *exprParser) parseNot() (expr, error) {
+	if p.acceptName("not") {
+		operand, err := p.parseNot()
+		return unary{"not", operand}, err
+	}
+	return p.parseCompare()
+}
+
+func (p *exprParser) parseCompare() (expr, error) {
+	left, err := p.parseConcat()
+	for err == nil {
+		var op string
+		tok := p.peek()
+		switch {
+		case tok.kind == tokenOperator && (tok.text == "==" || tok.text == "!=" || tok.text == "<" || tok.text == "<=" || tok.text == ">" || tok.text == ">="):
+			op = tok.text
+			p.next()
+		case p.acceptName("in"):
+			op = "in"
+		case tok.kind == tokenName && tok.text == "not" && p.tokens[p.pos+1].kind == tokenName && p.tokens[p.pos+1].text == "in":
+			p.pos += 2
+			op = "not in"
+		default:
+			return left, nil
+		}
+		var right expr
+		if right, err = p.parseConcat(); err == nil {
+			left = binary{op, left, right}
+		}
+	}
+	return left, err
+}
+
+func (p *exprParser) parseConcat() (expr, error) {
+	left, err := p.parseAdd()
+	for err == nil && p.accept("~") {
+		var right expr
+		if right, err = p.parseAdd(); err == nil {
+			left = binary{"~", left, right}
+		}
+	}
+	return left, err
+}
+
+func (p *exprParser) parseAdd() (expr, error) {
+	left, err := p.parseMul()
+	for err == nil {
+		tok := p.peek()
+		if tok.kind != tokenOperator || tok.text != "+" && tok.text != "-" {
+			break
+		}
+		p.next()
+		var right expr
+		if right, err = p.parseMul(); err == nil {
+			left = binary{tok.text, left, right}
+		}
+	}
+	return left, err
+}
+
+func (p *exprParser) parseMul() (expr, error) {
+	left, err := p.parsePow()
+	for err == nil {
+		tok := p.peek()
+		if tok.kind != tokenOperator || tok.text != "*" && tok.text != "/" && tok.text != "//" && tok.text != "%" {
+			break
+		}
+		p.next()
+		var right expr
+		if right, err = p.parsePow(); err == nil {
+			left = binary{tok.text, left, right}
+		}
+	}
+	return left, err
+}
+
+// parsePow reads a ** b. As in Jinja2, and unlike Python, it binds looser
+// than unary minus and groups from the left.
+func (p *exprParser) parsePow() (expr, error) {
+	left, err := p.parseUnary()
+	for err == nil && p.accept("**") {
+		var right expr
+		if right, err = p.parseUnary(); err == nil {
+			left = binary{"**", left, right}
+		}
+	}
+	return left, err
+}
+
+func (p *exprParser) parseUnary() (expr, error) {
+	if p.accept("-") {
+		operand, err := p.parseUnary()
+		return unary{"-", operand}, err
+	}
+	return p.parsePostfix()
+}
+
+// parsePostfix reads a primary expression followed by attribute lookups,
+// subscripts, calls, filters and tests
+func (p *exprParser) parsePostfix() (expr, error) {
+	e, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	return p.parseSuffixes(e, true)
+}
+
+// parseSuffixes reads the attribute lookups, subscripts and calls following
+// e, and with filters also its filters and tests
+func (p *exprParser) parseSuffixes(e expr, filters bool) (expr, error) {
+	var err error
+	for {
+		switch {
+		case p.accept("."):
+			tok := p.next()
+			if tok.kind != tokenName && tok.kind != tokenNumber {
+				return nil, fmt.Errorf("expected an attribute name, found %s", describe(tok))
+			}
+			e = attr{e, tok.text}
+		case p.accept("["):
+			if e, err = p.parseSubscript(e); err != nil {
+				return nil, err
+			}
+		case p.accept("("):
+			c := call{target: e}
+			if c.args, c.kwargs, err = p.parseCallArgs(); err != nil {
+				return nil, err
+			}
+			e = c
+		case !filters:
+			return e, nil
+		case p.accept("|"):
+			tok := p.next()
+			if tok.kind != tokenName {
+				return nil, fmt.Errorf("expected a filter name, found %s", describe(tok))
+			}
+			f := filter{target: e, name: tok.text}
+			if p.accept("(") {
+				if f.args, f.kwargs, err = p.parseCallArgs(); err != nil {
+					return nil, err
+				}
+			}
+			e = f
+		case p.acceptName("is"):
+			t := test{target: e, negate: p.acceptName("not")}
+			tok := p.next()
+			if tok.kind != tokenName {
+				return nil, fmt.Errorf("expected a test name, found %s", describe(tok))
+			}
+			t.name = tok.text
+			if p.accept("(") {
+				if t.args, t.kwargs, err = p.parseCallArgs(); err != nil {
+					return nil, err
+				}
+			} else if p.startsTestArg() {
+				// A single argument may follow without parentheses, as in
+				// n is divisibleby 3
+				arg, err := p.parsePrimary()
+				if err == nil {
+					arg, err = p.parseSuffixes(arg, false)
+				}
+				if err != nil {
+					return nil, err
+				}
+				t.args = []expr{arg}
+			}
+			e = t
+		default:
+			return e, nil
+		}
+	}
+}
+
+// startsTestArg reports whether the next token starts the argument of a
+// test given without parentheses; keywords that may follow a test do not
+func (p *exprParser) startsTestArg() bool {
+	tok := p.peek()
+	switch tok.kind {
+	case tokenString, tokenNumber:
+		return true
+	case tokenName:
+		switch tok.text {
+		case "and", "or", "else", "if", "in", "not", "is":
+			return false
+		}
+		return true
+	case tokenOperator:
+		return tok.text == "[" || tok.text == "{"
+	}
+	return false
+}
+
+// parseSubscript reads the inside of target[...] after the "[": a key, or a
+// slice start:stop:step whose parts are all optional
+func (p *exprParser) parseSubscript(target expr) (expr, error) {
+	// bound reads a slice bound, missing before ":" or "]"
+	bound := func() (expr, error) {
+		if tok := p.peek(); tok.kind == tokenOperator && (tok.text == ":" || tok.text == "]") {
+			return nil, nil
+		}
+		return p.parseExpr()
+	}
+
+	start, err := bound()
+	if err != nil {
+		return nil, err
+	}
+	if !p.accept(":") {
+		if start == nil {
+			return nil, fmt.Errorf("unexpected %s", describe(p.peek()))
+		}
+		return index{target, start}, p.expect("]")
+	}
+	s := sliceExpr{target: target, start: start}
+	if s.stop, err = bound(); err != nil {
+		return nil, err
+	}
+	if p.accept(":") {
+		if s.step, err = bound(); err != nil {
+			return nil, err
+		}
+	}
+	return s, p.expect("]")
+}
+
+// parseArgs reads comma separated expressions up to closer
+func (p *exprParser) parseArgs(closer string) ([]expr, error) {
+	var args []expr
+	for !p.accept(closer) {
+		if len(args) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+			if p.accept(closer) {
+				break
+			}
+		}
+		arg, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	return args, nil
+}
+
+// parseCallArgs reads the arguments of a call, filter or test up to the
+// ")" after the "(": positional ones, then name=value keyword ones
+func (p *exprParser) parseCallArgs() ([]expr, []kwarg, error) {
+	var args []expr
+	var kwargs []kwarg
+	for !p.accept(")") {
+		if len(args)+len(kwargs) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, nil, err
+			}
+			if p.accept(")") {
+				break
+			}
+		}
+		if tok := p.peek(); tok.kind == tokenName && p.pos+1 < len(p.tokens) && p.tokens[p.pos+1].kind == tokenOperator && p.tokens[p.pos+1].text == "=" {
+			p.pos += 2
+			for _, kw := range kwargs {
+				if kw.name == tok.text {
+					return nil, nil, fmt.Errorf("keyword argument %s repeated", tok.text)
+				}
+			}
+			value, err := p.parseExpr()
+			if err != nil {
+				return nil, nil, err
+			}
+			kwargs = append(kwargs, kwarg{tok.text, value})
+			continue
+		}
+		if len(kwargs) > 0 {
+			return nil, nil, fmt.Errorf("positional argument follows keyword argument")
+		}
+		arg, err := p.parseExpr()
+		if err != nil {
+			return nil, nil, err
+		}
+		args = append(args, arg)
+	}
+	return args, kwargs, nil
+}
+
+func (p *exprParser) parsePrimary() (expr, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenString:
+		// Adjacent literals are joined, as in Python
+		s := tok.text
+		for p.peek().kind == tokenString {
+			s += p.next().text
+		}
+		return literal{s}, nil
+	case tokenNumber:
+		if strings.Contains(tok.text, ".") {
+			f, err := strconv.ParseFloat(tok.text, 64)
+			return literal{f}, err
+		}
+		n, err := strconv.Atoi(tok.text)
+		return literal{n}, err
+	case tokenName:
+		switch tok.text {
+		case "true", "True":
+			return literal{true}, nil
+		case "false", "False":
+			return literal{false}, nil
+		case "none", "None":
+			return literal{nil}, nil
+		}
+		return name{tok.text}, nil
+	case tokenOperator:
+		switch tok.text {
+		case "(":
+			e, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if p.accept(",") {
+				// A tuple, which behaves as a list but for % formatting
+				items, err := p.parseArgs(")")
+				return listExpr{items: append([]expr{e}, items...), tuple: true}, err
+			}
+			return e, p.expect(")")
+		case "[":
+			items, err := p.parseArgs("]")
+			return listExpr{items: items}, err
+		case "{":
+			var d dictExpr
+			for !p.accept("}") {
+				if len(d.keys) > 0 {
+					if err := p.expect(","); err != nil {
+						return nil, err
+					}
+					if p.accept("}") {
+						break
+					}
+				}
+				key, err := p.parseExpr()
+				if err != nil {
+					return nil, err
+				}
+				if err := p.expect(":"); err != nil {
+					return nil, err
+				}
+				value, err := p.parseExpr()
+				if err != nil {
+					return nil, err
+				}
+				d.keys, d.values = append(d.keys, key), append(d.values, value)
+			}
+			return d, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected %s", describe(tok))
+}
@@ -0,0 +1,280 @@
+// Package jsonrpc is a JSON-RPC 2.0 server over a byte stream framed like
+// the Language Server Protocol: each message is preceded by a
+// Content-Length header and a blank line. It is the transport of devinit
+// ide-server, which editor plugins speak over stdio with the JSON-RPC
+// libraries they already use for language servers.
+package jsonrpc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Version is the JSON-RPC version of every message
+const Version = "2.0"
+
+// Error codes of JSON-RPC 2.0 and of the Language Server Protocol
+const (
+	CodeParseError       = -32700
+	CodeInvalidRequest   = -32600
+	CodeMethodNotFound   = -32601
+	CodeInvalidParams    = -32602
+	CodeInternalError    = -32603
+	CodeRequestFailed    = -32803
+	CodeRequestCancelled = -32800
+)
+
+// maxMessageSize bounds the messages read, so a broken client cannot make
+// the server allocate without limit
+const maxMessageSize = 64 << 20
+
+// Error is the error of a response
+type Error struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Notifier sends a notification to the client
+type Notifier func(method string, params interface{})
+
+// Handler answers a request with its result, which is encoded as JSON.
+// Handlers of notifications have their result dropped. ctx is cancelled
+// when the client cancels the request or the connection ends.
+type Handler func(ctx context.Context, params json.RawMessage, notify Notifier) (interface{}, error)
+
+// Server dispatches the requests of a connection to the handlers of their
+// method. Requests run concurrently, so a long one such as a generation
+// does not hold up the others.
+//
+// Besides the registered methods it implements those of the LSP lifecycle:
+// $/cancelRequest cancels a request by id, shutdown makes the server refuse
+// further requests and exit ends Serve.
+type Server struct {
+	handlers map[string]Handler
+
+	// ErrorData, when set, returns the data attached to the error response
+	// of a handler error that is not an *Error
+	ErrorData func(err error) interface{}
+}
+
+// NewServer returns a server without methods
+func NewServer() *Server {
+	return &Server{handlers: make(map[string]Handler)}
+}
+
+// Register sets the handler of a method
+func (s *Server) Register(method string, handler Handler) {
+	s.handlers[method] = handler
+}
+
+// request is a request or notification; notifications have no id
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// response answers a request with a result or an error
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result"`
+}
+
+type errorResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Error   *Error          `json:"error"`
+}
+
+type notification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+// conn is a connection being served
+type conn struct {
+	mu  sync.Mutex // serializes writes
+	out io.Writer
+
+	pending  sync.Map // id → context.CancelFunc of running requests
+	running  sync.WaitGroup
+	shutdown bool
+}
+
+// Serve answers the messages read from r on w until r ends, then waits for
+// the running requests, or until the client sends exit, which cancels them.
+// It returns the error that ended reading, nil at the end of r or on exit.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	c := &conn{out: w}
+	defer c.running.Wait()
+	// Running requests are cancelled before they are waited for
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	in := bufio.NewReader(r)
+	for {
+		data, err := readMessage(in)
+		if err == io.EOF {
+			// Clients piping requests in close the input before the
+			// responses are written
+			c.running.Wait()
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var req request
+		if err := json.Unmarshal(data, &req); err != nil {
+			c.replyError(nil, &Error{Code: CodeParseError, Message: err.Error()})
+			continue
+		}
+		if req.JSONRPC != Version || req.Method == "" {
+			c.replyError(req.ID, &Error{Code: CodeInvalidRequest, Message: "not a JSON-RPC 2.0 request"})
+			continue
+		}
+
+		switch req.Method {
+		case "exit":
+			return nil
+		case "shutdown":
+			c.shutdown = true
+			c.reply(req.ID, nil)
+			continue
+		case "$/cancelRequest":
+			var params struct {
+				ID json.RawMessage `json:"id"`
+			}
+			if json.Unmarshal(req.Params, &params) == nil {
+				if cancel, ok := c.pending.Load(string(params.ID)); ok {
+					cancel.(context.CancelFunc)()
+				}
+			}
+			continue
+		}
+
+		handler, ok := s.handlers[req.Method]
+		switch {
+		case req.ID == nil && (!ok || c.shutdown):
+			// Notifications are never answered, not even with errors
+			continue
+		case c.shutdown:
+			c.replyError(req.ID, &Error{Code: CodeInvalidRequest, Message: "the server is shutting down"})
+			continue
+		case !ok:
+			c.replyError(req.ID, &Error{Code: CodeMethodNotFound, Message: "method not found: " + req.Method})
+			continue
+		}
+
+		reqCtx, cancelReq := context.WithCancel(ctx)
+		if req.ID != nil {
+			c.pending.Store(string(req.ID), cancelReq)
+		}
+		c.running.Add(1)
+		go func() {
+			defer c.running.Done()
+			defer cancelReq()
+			result, err := handler(reqCtx, req.Params, c.notify)
+			if req.ID == nil {
+				return
+			}
+			c.pending.Delete(string(req.ID))
+			if err != nil {
+				c.replyError(req.ID, s.responseError(reqCtx, err))
+				return
+			}
+			c.reply(req.ID, result)
+		}()
+	}
+}
+
+// responseError returns the error response of a handler error
+func (s *Server) responseError(ctx context.Context, err error) *Error {
+	var rpcErr *Error
+	switch {
+	case errors.As(err, &rpcErr):
+		return rpcErr
+	case ctx.Err() != nil && errors.Is(err, context.Canceled):
+		return &Error{Code: CodeRequestCancelled, Message: err.Error()}
+	}
+	rpcErr = &Error{Code: CodeRequestFailed, Message: err.Error()}
+	if s.ErrorData != nil {
+		rpcErr.Data = s.ErrorData(err)
+	}
+	return rpcErr
+}
+
+func (c *conn) reply(id json.RawMessage, result interface{}) {
+	c.write(response{JSONRPC: Version, ID: id, Result: result})
+}
+
+func (c *conn) replyError(id json.RawMessage, err *Error) {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	c.write(errorResponse{JSONRPC: Version, ID: id, Error: err})
+}
+
+func (c *conn) notify(method string, params interface{}) {
+	c.write(notification{JSONRPC: Version, Method: method, Params: params})
+}
+
+// write sends a message. Write errors are dropped, as the client they
+// would be reported to is gone.
+func (c *conn) write(msg interface{}) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		data, _ = json.Marshal(errorResponse{JSONRPC: Version, ID: json.RawMessage("null"), Error: &Error{Code: CodeInternalError, Message: err.Error()}})
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fmt.Fprintf(c.out, "Content-Length: %d\r\n\r\n%s", len(data), data)
+}
+
+// readMessage reads the content of the next message
+func readMessage(in *bufio.Reader) ([]byte, error) {
+	headers, err := textproto.NewReader(in).ReadMIMEHeader()
+	if err != nil {
+		if err == io.EOF && len(headers) == 0 {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("reading message headers: %w", err)
+	}
+	length, err := strconv.Atoi(strings.TrimSpace(headers.Get("Content-Length")))
+	if err != nil || length < 0 || length > maxMessageSize {
+		return nil, fmt.Errorf("invalid Content-Length %q", headers.Get("Content-Length"))
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(in, data); err != nil {
+		return nil, fmt.Errorf("reading message: %w", err)
+	}
+	return data, nil
+}
+
+// DecodeParams decodes the parameters of a request into v, failing with
+// CodeInvalidParams. Missing parameters leave v unchanged.
+func DecodeParams(params json.RawMessage, v interface{}) error {
+	if len(params) == 0 || string(params) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return &Error{Code: CodeInvalidParams, Message: "invalid params: " + err.Error()}
+	}
+	return nil
+}
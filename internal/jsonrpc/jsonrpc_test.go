@@ -0,0 +1,170 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// frame frames messages for Serve
+func frame(messages ...string) string {
+	var b strings.Builder
+	for _, msg := range messages {
+		fmt.Fprintf(&b, "Content-Length: %d\r\n\r\n%s", len(msg), msg)
+	}
+	return b.String()
+}
+
+// readAll decodes the messages Serve wrote
+func readAll(t *testing.T, out string) []map[string]interface{} {
+	t.Helper()
+	in := bufio.NewReader(strings.NewReader(out))
+	var messages []map[string]interface{}
+	for {
+		data, err := readMessage(in)
+		if err == io.EOF {
+			return messages
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		var msg map[string]interface{}
+		if err := json.Unmarshal(data, &msg); err != nil {
+			t.Fatal(err)
+		}
+		messages = append(messages, msg)
+	}
+}
+
+// byID indexes the responses by id
+func byID(messages []map[string]interface{}) map[string]map[string]interface{} {
+	responses := make(map[string]map[string]interface{})
+	for _, msg := range messages {
+		if id, ok := msg["id"]; ok {
+			responses[fmt.Sprint(id)] = msg
+		}
+	}
+	return responses
+}
+
+func newTestServer() *Server {
+	s := NewServer()
+	s.Register("echo", func(ctx context.Context, params json.RawMessage, notify Notifier) (interface{}, error) {
+		var p struct {
+			Text string `json:"text"`
+		}
+		if err := DecodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		notify("progress", map[string]string{"message": "echoing"})
+		return p.Text, nil
+	})
+	s.Register("fail", func(ctx context.Context, params json.RawMessage, notify Notifier) (interface{}, error) {
+		return nil, errors.New("boom")
+	})
+	s.ErrorData = func(err error) interface{} { return map[string]string{"code": "UNKNOWN"} }
+	return s
+}
+
+func TestServe(t *testing.T) {
+	var out strings.Builder
+	in := frame(
+		`{"jsonrpc":"2.0","id":1,"method":"echo","params":{"text":"hi"}}`,
+		`{"jsonrpc":"2.0","id":"two","method":"missing"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"echo","params":{"text":1}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"fail"}`,
+		`{"jsonrpc":"2.0","method":"missing"}`,
+		`not json`,
+		`{"id":5,"method":"echo"}`,
+	)
+	if err := newTestServer().Serve(context.Background(), strings.NewReader(in), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	messages := readAll(t, out.String())
+	responses := byID(messages)
+	if got := responses["1"]; got["result"] != "hi" {
+		t.Errorf("echo = %v, want hi", got)
+	}
+	code := func(id string) float64 {
+		e, _ := responses[id]["error"].(map[string]interface{})
+		c, _ := e["code"].(float64)
+		return c
+	}
+	for id, want := range map[string]float64{
+		"two":   CodeMethodNotFound,
+		"3":     CodeInvalidParams,
+		"4":     CodeRequestFailed,
+		"<nil>": CodeParseError,
+		"5":     CodeInvalidRequest,
+	} {
+		if got := code(id); got != want {
+			t.Errorf("error code of %s = %v, want %v", id, got, want)
+		}
+	}
+	if data := responses["4"]["error"].(map[string]interface{})["data"]; fmt.Sprint(data) != "map[code:UNKNOWN]" {
+		t.Errorf("error data = %v", data)
+	}
+
+	var notifications int
+	for _, msg := range messages {
+		if msg["method"] == "progress" {
+			notifications++
+		}
+	}
+	// The notification without a handler is not answered
+	if notifications != 1 || len(messages) != 7 {
+		t.Errorf("got %d messages with %d notifications, want 7 with 1:\n%s", len(messages), notifications, out.String())
+	}
+}
+
+func TestCancelAndShutdown(t *testing.T) {
+	s := NewServer()
+	started := make(chan struct{})
+	s.Register("wait", func(ctx context.Context, params json.RawMessage, notify Notifier) (interface{}, error) {
+		close(started)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(5 * time.Second):
+			return "timeout", nil
+		}
+	})
+	s.Register("echo", func(ctx context.Context, params json.RawMessage, notify Notifier) (interface{}, error) {
+		return "echo", nil
+	})
+
+	r, w := io.Pipe()
+	var out strings.Builder
+	done := make(chan error)
+	go func() { done <- s.Serve(context.Background(), r, &out) }()
+
+	io.WriteString(w, frame(`{"jsonrpc":"2.0","id":1,"method":"wait"}`))
+	<-started
+	io.WriteString(w, frame(
+		`{"jsonrpc":"2.0","method":"$/cancelRequest","params":{"id":1}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"shutdown"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"echo"}`,
+		`{"jsonrpc":"2.0","method":"exit"}`,
+	))
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	responses := byID(readAll(t, out.String()))
+	if e, _ := responses["1"]["error"].(map[string]interface{}); e == nil || e["code"] != float64(CodeRequestCancelled) {
+		t.Errorf("cancelled request = %v, want RequestCancelled", responses["1"])
+	}
+	if got, ok := responses["2"]; !ok || got["result"] != nil {
+		t.Errorf("shutdown = %v, want a null result", got)
+	}
+	if e, _ := responses["3"]["error"].(map[string]interface{}); e == nil || e["code"] != float64(CodeInvalidRequest) {
+		t.Errorf("request after shutdown = %v, want InvalidRequest", responses["3"])
+	}
+}
@@ -0,0 +1,100 @@
+// Package merge combines structured configuration files (YAML, JSON and
+// TOML), so a template can add keys to a file such as package.json,
+// compose.yaml or pyproject.toml without replacing what another template (or
+// the user) put there.
+package merge
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Format is the syntax of a mergeable file
+type Format string
+
+const (
+	FormatYAML Format = "yaml"
+	FormatJSON Format = "json"
+	FormatTOML Format = "toml"
+)
+
+// FormatOf returns the format of a file from its extension, or "" if it is
+// not mergeable
+func FormatOf(filename string) Format {
+	switch strings.ToLower(path.Ext(strings.TrimSuffix(filename, ".tmpl"))) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".json":
+		return FormatJSON
+	case ".toml":
+		return FormatTOML
+	}
+	return ""
+}
+
+// MapStrategy selects how a mapping (object, table) present in both files is
+// combined
+type MapStrategy string
+
+const (
+	// MapsDeep merges nested mappings key by key (default)
+	MapsDeep MapStrategy = "deep"
+	// MapsReplace replaces nested mappings of the base with those of the
+	// patch; only top-level keys are merged
+	MapsReplace MapStrategy = "replace"
+)
+
+// ListStrategy selects how a list (sequence, array) present in both files is
+// combined
+type ListStrategy string
+
+const (
+	// ListsReplace uses the list of the patch (default)
+	ListsReplace ListStrategy = "replace"
+	// ListsAppend adds the items of the patch after those of the base
+	ListsAppend ListStrategy = "append"
+	// ListsUnique appends the items of the patch the base does not have yet
+	ListsUnique ListStrategy = "unique"
+)
+
+// Options configure a merge. The zero value merges mappings deeply and
+// replaces lists.
+type Options struct {
+	Maps  MapStrategy  `yaml:"maps,omitempty" json:"maps,omitempty"`
+	Lists ListStrategy `yaml:"lists,omitempty" json:"lists,omitempty"`
+}
+
+// Validate reports unknown strategies
+func (o Options) Validate() error {
+	switch o.Maps {
+	case "", MapsDeep, MapsReplace:
+	default:
+		return fmt.Errorf("invalid maps strategy %q: must be deep or replace", o.Maps)
+	}
+	switch o.Lists {
+	case "", ListsReplace, ListsAppend, ListsUnique:
+	default:
+		return fmt.Errorf("invalid lists strategy %q: must be replace, append or unique", o.Lists)
+	}
+	return nil
+}
+
+// Merge merges the document patch into base. Mappings of base keep their key
+// order (and, for YAML and TOML, their comments); new keys are appended.
+// Scalars in patch replace those in base. An empty base yields patch.
+func Merge(format Format, base, patch []byte, opts Options) ([]byte, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case FormatYAML:
+		return mergeYAML(base, patch, opts)
+	case FormatJSON:
+		return mergeJSON(base, patch, opts)
+	case FormatTOML:
+		return mergeTOML(base, patch, opts)
+	}
+	return nil, fmt.Errorf("unsupported format %q", format)
+}
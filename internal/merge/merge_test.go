@@ -0,0 +1,288 @@
+package merge
+
+import "testing"
+
+func TestYAML(t *testing.T) {
+	tests := []struct {
+		name  string
+		base  string
+		patch string
+		opts  Options
+		want  string
+	}{
+		{
+			name: "nested keys",
+			base: `# Local services
+services:
+  app:
+    build: .  # the application
+    ports: ["8000:8000"]
+`,
+			patch: `services:
+  app:
+    depends_on: [db]
+  db:
+    image: postgres:16
+volumes:
+  data: {}
+`,
+			want: `# Local services
+services:
+  app:
+    build: . # the application
+    ports: ["8000:8000"]
+    depends_on: [db]
+  db:
+    image: postgres:16
+volumes:
+  data: {}
+`,
+		},
+		{
+			name:  "lists are replaced",
+			base:  "tags: [a, b]\n",
+			patch: "tags: [c]\n",
+			want:  "tags: [c]\n",
+		},
+		{
+			name:  "lists appended",
+			base:  "tags: [a, b]\n",
+			patch: "tags: [b, c]\n",
+			opts:  Options{Lists: ListsAppend},
+			want:  "tags: [a, b, b, c]\n",
+		},
+		{
+			name:  "unique list items",
+			base:  "steps:\n  - run: make\n  - uses: actions/checkout@v4\n",
+			patch: "steps:\n  - uses: actions/checkout@v4\n  - run: make test\n",
+			opts:  Options{Lists: ListsUnique},
+			want:  "steps:\n  - run: make\n  - uses: actions/checkout@v4\n  - run: make test\n",
+		},
+		{
+			name:  "maps replaced",
+			base:  "app:\n  build: .\n  ports: [8000]\nname: demo\n",
+			patch: "app:\n  image: demo\nversion: 2\n",
+			opts:  Options{Maps: MapsReplace},
+			want:  "app:\n  image: demo\nname: demo\nversion: 2\n",
+		},
+		{
+			name:  "empty base",
+			base:  "",
+			patch: "a: 1\n",
+			want:  "a: 1\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Merge(FormatYAML, []byte(tt.base), []byte(tt.patch), tt.opts)
+			if err != nil {
+				t.Fatalf("YAML() unexpected error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("YAML() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := Merge(FormatYAML, []byte("a: [\n"), []byte("a: 1\n"), Options{}); err == nil {
+		t.Error("YAML() of an invalid document expected error")
+	}
+}
+
+func TestJSON(t *testing.T) {
+	base := `{
+  "name": "demo",
+  "version": "1.0.0",
+  "scripts": {"start": "node index.js"},
+  "private": true
+}
+`
+	patch := `{"scripts": {"test": "vitest", "lint": "eslint ."}, "devDependencies": {"vitest": "^2.0.0"}, "engines": {"node": ">=20"}, "workspaces": [], "port": 8080, "nullable": null}`
+	want := `{
+  "name": "demo",
+  "version": "1.0.0",
+  "scripts": {
+    "start": "node index.js",
+    "test": "vitest",
+    "lint": "eslint ."
+  },
+  "private": true,
+  "devDependencies": {
+    "vitest": "^2.0.0"
+  },
+  "engines": {
+    "node": ">=20"
+  },
+  "workspaces": [],
+  "port": 8080,
+  "nullable": null
+}
+`
+
+	got, err := Merge(FormatJSON, []byte(base), []byte(patch), Options{})
+	if err != nil {
+		t.Fatalf("JSON() unexpected error: %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("JSON() = %s, want %s", got, want)
+	}
+
+	if _, err := Merge(FormatJSON, []byte(base), []byte("scripts: {}\n"), Options{}); err == nil {
+		t.Error("JSON() of a YAML patch expected error")
+	}
+}
+
+func TestTOML(t *testing.T) {
+	const pyproject = `# Project metadata
+[project]
+name = "demo"
+version = "0.1.0"
+dependencies = [
+    "fastapi>=0.110",  # web framework
+    "uvicorn>=0.29",
+]
+
+[tool.ruff]
+line-length = 100
+
+[[tool.mypy.overrides]]
+module = "tests.*"
+disallow_untyped_defs = false
+`
+
+	tests := []struct {
+		name  string
+		patch string
+		opts  Options
+		want  string
+	}{
+		{
+			name: "keys and tables",
+			patch: `[project]
+version = "0.2.0"
+requires-python = ">=3.11"
+
+[tool.pytest.ini_options]
+testpaths = ["tests"]
+`,
+			want: `# Project metadata
+[project]
+name = "demo"
+version = "0.2.0"
+dependencies = [
+    "fastapi>=0.110",  # web framework
+    "uvicorn>=0.29",
+]
+requires-python = ">=3.11"
+
+[tool.ruff]
+line-length = 100
+
+[[tool.mypy.overrides]]
+module = "tests.*"
+disallow_untyped_defs = false
+
+[tool.pytest.ini_options]
+testpaths = ["tests"]
+`,
+		},
+		{
+			name:  "unique array items",
+			patch: "[project]\ndependencies = [\"uvicorn>=0.29\", \"sqlalchemy>=2.0\"]\n",
+			opts:  Options{Lists: ListsUnique},
+			want: `# Project metadata
+[project]
+name = "demo"
+version = "0.1.0"
+dependencies = [
+    "fastapi>=0.110",
+    "uvicorn>=0.29",
+    "sqlalchemy>=2.0",
+]
+
+[tool.ruff]
+line-length = 100
+
+[[tool.mypy.overrides]]
+module = "tests.*"
+disallow_untyped_defs = false
+`,
+		},
+		{
+			name:  "array tables appended",
+			patch: "[[tool.mypy.overrides]]\nmodule = \"migrations.*\"\nignore_errors = true\n",
+			opts:  Options{Lists: ListsAppend},
+			want: pyproject + `
+[[tool.mypy.overrides]]
+module = "migrations.*"
+ignore_errors = true
+`,
+		},
+		{
+			name:  "array tables replaced",
+			patch: "[[tool.mypy.overrides]]\nmodule = \"migrations.*\"\n",
+			want: `# Project metadata
+[project]
+name = "demo"
+version = "0.1.0"
+dependencies = [
+    "fastapi>=0.110",  # web framework
+    "uvicorn>=0.29",
+]
+
+[tool.ruff]
+line-length = 100
+
+[[tool.mypy.overrides]]
+module = "migrations.*"
+`,
+		},
+		{
+			name:  "tables replaced",
+			patch: "[tool.ruff]\ntarget-version = \"py311\"\n",
+			opts:  Options{Maps: MapsReplace},
+			want: `# Project metadata
+[project]
+name = "demo"
+version = "0.1.0"
+dependencies = [
+    "fastapi>=0.110",  # web framework
+    "uvicorn>=0.29",
+]
+
+[tool.ruff]
+target-version = "py311"
+
+[[tool.mypy.overrides]]
+module = "tests.*"
+disallow_untyped_defs = false
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Merge(FormatTOML, []byte(pyproject), []byte(tt.patch), tt.opts)
+			if err != nil {
+				t.Fatalf("Merge() unexpected error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Merge() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := Merge(FormatTOML, []byte("deps = [\n  \"a\",\n"), []byte("x = 1\n"), Options{}); err == nil {
+		t.Error("Merge() of an unclosed array expected error")
+	}
+}
+
+func TestOptions(t *testing.T) {
+	if _, err := Merge(FormatYAML, nil, nil, Options{Lists: "sorted"}); err == nil {
+		t.Error("Merge() with an unknown list strategy expected error")
+	}
+	if got := FormatOf("package.json.tmpl"); got != FormatJSON {
+		t.Errorf("FormatOf() = %q, want %q", got, FormatJSON)
+	}
+}
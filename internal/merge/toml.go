@@ -0,0 +1,449 @@
+package merge
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TOML files are merged line by line rather than decoded, so comments and
+// formatting of the base survive. Tables are matched by name and keys by
+// their literal text; dotted keys are not expanded.
+
+// tomlTable is a table with its key/value entries in file order
+type tomlTable struct {
+	header string // raw header line, "" for the root table
+	name   string // normalized name, e.g. "tool.poetry"
+	array  bool   // [[name]]
+	items  []tomlItem
+}
+
+// tomlItem is a key/value entry, or a comment or blank line (empty key)
+type tomlItem struct {
+	key   string
+	lines []string // raw lines; a value may span several
+}
+
+// value returns the text after "=" of a key/value item
+func (i tomlItem) value() string {
+	text := strings.Join(i.lines, "\n")
+	_, value, _ := strings.Cut(text, "=")
+	return strings.TrimSpace(value)
+}
+
+func mergeTOML(base, patch []byte, opts Options) ([]byte, error) {
+	baseTables, err := parseTOML(string(base))
+	if err != nil {
+		return nil, fmt.Errorf("base: %w", err)
+	}
+	patchTables, err := parseTOML(string(patch))
+	if err != nil {
+		return nil, fmt.Errorf("patch: %w", err)
+	}
+	if len(baseTables) == 1 && len(baseTables[0].items) == 0 {
+		return patch, nil
+	}
+
+	// Array tables of the patch are added as a group, after existing ones
+	var arrays []string
+	arrayTables := make(map[string][]*tomlTable)
+	for _, table := range patchTables {
+		if table.array {
+			if _, ok := arrayTables[table.name]; !ok {
+				arrays = append(arrays, table.name)
+			}
+			arrayTables[table.name] = append(arrayTables[table.name], table)
+			continue
+		}
+
+		target := findTable(baseTables, table.name)
+		switch {
+		case target == nil:
+			baseTables = append(baseTables, table)
+		case table.name != "" && opts.Maps == MapsReplace:
+			target.items = table.items
+		default:
+			mergeTableItems(target, table, opts)
+		}
+	}
+	for _, name := range arrays {
+		baseTables = mergeArrayTables(baseTables, name, arrayTables[name], opts)
+	}
+
+	return []byte(formatTOML(baseTables)), nil
+}
+
+// parseTOML splits a document into tables. The root table comes first and
+// always exists.
+func parseTOML(doc string) ([]*tomlTable, error) {
+	tables := []*tomlTable{{}}
+	current := tables[0]
+	var open *tomlItem // item whose value continues on the next line
+
+	lines := strings.Split(strings.TrimSuffix(doc, "\n"), "\n")
+	if doc == "" {
+		lines = nil
+	}
+	for n, line := range lines {
+		if open != nil {
+			open.lines = append(open.lines, line)
+			if valueComplete(open.value()) {
+				open = nil
+			}
+			continue
+		}
+
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "" || strings.HasPrefix(trimmed, "#"):
+			current.items = append(current.items, tomlItem{lines: []string{line}})
+		case strings.HasPrefix(trimmed, "["):
+			array := strings.HasPrefix(trimmed, "[[")
+			name, ok := tableName(trimmed, array)
+			if !ok {
+				return nil, fmt.Errorf("line %d: invalid table header %q", n+1, trimmed)
+			}
+			current = &tomlTable{header: line, name: name, array: array}
+			tables = append(tables, current)
+		default:
+			key, _, ok := strings.Cut(trimmed, "=")
+			if !ok {
+				return nil, fmt.Errorf("line %d: expected key = value", n+1)
+			}
+			current.items = append(current.items, tomlItem{key: strings.TrimSpace(key), lines: []string{line}})
+			if item := &current.items[len(current.items)-1]; !valueComplete(item.value()) {
+				open = item
+			}
+		}
+	}
+
+	if open != nil {
+		return nil, fmt.Errorf("value of %s is not closed", open.key)
+	}
+	return tables, nil
+}
+
+// tableName returns the normalized name of a [table] or [[array]] header
+func tableName(header string, array bool) (string, bool) {
+	open, close := "[", "]"
+	if array {
+		open, close = "[[", "]]"
+	}
+	end := strings.Index(header, close)
+	if end < 0 {
+		return "", false
+	}
+
+	parts := strings.Split(header[len(open):end], ".")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+		if parts[i] == "" {
+			return "", false
+		}
+	}
+	return strings.Join(parts, "."), true
+}
+
+// valueComplete reports whether a value has no open string, array or inline
+// table
+func valueComplete(value string) bool {
+	depth := 0
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case '"', '\'':
+			quote := string(c)
+			if strings.HasPrefix(value[i:], strings.Repeat(quote, 3)) {
+				end := strings.Index(value[i+3:], strings.Repeat(quote, 3))
+				if end < 0 {
+					return false
+				}
+				i += 3 + end + 2
+				continue
+			}
+			for i++; i < len(value) && value[i] != c; i++ {
+				if c == '"' && value[i] == '\\' {
+					i++
+				}
+			}
+		case '[', '{':
+			depth++
+		case ']', '}':
+			depth--
+		case '#':
+			// A comment runs to the end of its line
+			end := strings.IndexByte(value[i:], '\n')
+			if end < 0 {
+				return depth <= 0
+			}
+			i += end
+		}
+	}
+	return depth <= 0
+}
+
+func findTable(tables []*tomlTable, name string) *tomlTable {
+	for _, table := range tables {
+		if !table.array && table.name == name {
+			return table
+		}
+	}
+	return nil
+}
+
+// mergeTableItems merges the keys of patch into target. New keys follow the
+// last key of target.
+func mergeTableItems(target, patch *tomlTable, opts Options) {
+	for _, item := range patch.items {
+		if item.key == "" {
+			continue
+		}
+
+		i := findItem(target.items, item.key)
+		if i < 0 {
+			at := 0
+			for j, existing := range target.items {
+				if existing.key != "" {
+					at = j + 1
+				}
+			}
+			target.items = append(target.items[:at], append([]tomlItem{item}, target.items[at:]...)...)
+			continue
+		}
+
+		if combined, ok := combineArrays(target.items[i], item, opts.Lists); ok {
+			target.items[i] = combined
+		} else {
+			target.items[i] = item
+		}
+	}
+}
+
+func findItem(items []tomlItem, key string) int {
+	for i, item := range items {
+		if item.key != "" && item.key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// combineArrays appends the elements of an array value in patch to the array
+// in base with the append and unique strategies. It reports false when the
+// values are not both arrays or the patch replaces the array.
+func combineArrays(base, patch tomlItem, strategy ListStrategy) (tomlItem, bool) {
+	if strategy != ListsAppend && strategy != ListsUnique {
+		return tomlItem{}, false
+	}
+	baseElems, ok := arrayElements(base.value())
+	if !ok {
+		return tomlItem{}, false
+	}
+	patchElems, ok := arrayElements(patch.value())
+	if !ok {
+		return tomlItem{}, false
+	}
+
+	elems := baseElems
+	for _, elem := range patchElems {
+		if strategy == ListsUnique && contains(elems, elem) {
+			continue
+		}
+		elems = append(elems, elem)
+	}
+
+	// Keep the layout of the base: one line, or one element per line
+	if len(base.lines) == 1 {
+		return tomlItem{key: base.key, lines: []string{base.key + " = [" + strings.Join(elems, ", ") + "]"}}, true
+	}
+	indent := "    "
+	if second := base.lines[1]; strings.TrimSpace(second) != "" {
+		indent = second[:len(second)-len(strings.TrimLeft(second, " \t"))]
+	}
+	lines := []string{base.key + " = ["}
+	for _, elem := range elems {
+		lines = append(lines, indent+elem+",")
+	}
+	return tomlItem{key: base.key, lines: append(lines, "]")}, true
+}
+
+// arrayElements splits an array value into its elements, dropping comments
+func arrayElements(value string) ([]string, bool) {
+	if !strings.HasPrefix(value, "[") {
+		return nil, false
+	}
+
+	var (
+		elems []string
+		elem  strings.Builder
+		depth int
+	)
+	flush := func() {
+		if text := strings.TrimSpace(elem.String()); text != "" {
+			elems = append(elems, text)
+		}
+		elem.Reset()
+	}
+	for i := 1; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c == '"' || c == '\'':
+			end := i + 1
+			for ; end < len(value) && value[end] != c; end++ {
+				if c == '"' && value[end] == '\\' {
+					end++
+				}
+			}
+			elem.WriteString(value[i:min(end+1, len(value))])
+			i = end
+		case c == '#':
+			for i < len(value) && value[i] != '\n' {
+				i++
+			}
+		case c == '[' || c == '{':
+			depth++
+			elem.WriteByte(c)
+		case c == ']' && depth == 0:
+			flush()
+			return elems, true
+		case c == ']' || c == '}':
+			depth--
+			elem.WriteByte(c)
+		case c == ',' && depth == 0:
+			flush()
+		default:
+			elem.WriteByte(c)
+		}
+	}
+	return nil, false
+}
+
+func contains(elems []string, elem string) bool {
+	for _, e := range elems {
+		if e == elem {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeArrayTables adds the [[name]] tables of the patch after the existing
+// ones, or in their place with the replace strategy
+func mergeArrayTables(tables []*tomlTable, name string, patch []*tomlTable, opts Options) []*tomlTable {
+	var existing []*tomlTable
+	last := -1
+	for i, table := range tables {
+		if table.array && table.name == name {
+			existing = append(existing, table)
+			last = i
+		}
+	}
+	if last < 0 {
+		return append(tables, patch...)
+	}
+
+	var added []*tomlTable
+	for _, table := range patch {
+		if opts.Lists == ListsUnique && containsTable(existing, table) {
+			continue
+		}
+		added = append(added, table)
+	}
+
+	if opts.Lists == "" || opts.Lists == ListsReplace {
+		var kept []*tomlTable
+		for i, table := range tables {
+			if i == last {
+				kept = append(kept, added...)
+			}
+			if !(table.array && table.name == name) {
+				kept = append(kept, table)
+			}
+		}
+		return kept
+	}
+
+	result := append([]*tomlTable{}, tables[:last+1]...)
+	result = append(result, added...)
+	return append(result, tables[last+1:]...)
+}
+
+// containsTable reports whether tables has one with the same keys and values
+func containsTable(tables []*tomlTable, table *tomlTable) bool {
+	for _, t := range tables {
+		if tableKeys(t) == tableKeys(table) {
+			return true
+		}
+	}
+	return false
+}
+
+func tableKeys(table *tomlTable) string {
+	var b strings.Builder
+	for _, item := range table.items {
+		if item.key != "" {
+			fmt.Fprintf(&b, "%s=%s\n", item.key, item.value())
+		}
+	}
+	return b.String()
+}
+
+// formatTOML writes tables back. Tables that follow a key/value line, e.g.
+// appended ones, are separated from it by a blank line.
+func formatTOML(tables []*tomlTable) string {
+	var (
+		b    strings.Builder
+		last string // last line written
+	)
+	for _, table := range tables {
+		if table.header != "" {
+			if trimmed := strings.TrimSpace(last); trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+				b.WriteString("\n")
+			}
+			b.WriteString(table.header + "\n")
+			last = table.header
+		}
+		for _, item := range table.items {
+			for _, line := range item.lines {
+				b.WriteString(line + "\n")
+				last = line
+			}
+		}
+	}
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+// TOMLTable is a table of a TOML document as read by ReadTOML. Values keep
+// their TOML text, e.g. `"^1.2"` or `{ version = "1.0", optional = true }`.
+type TOMLTable struct {
+	Name   string // e.g. "tool.poetry.dependencies", "" for the root table
+	Array  bool   // [[name]]
+	Keys   []string
+	Values map[string]string
+}
+
+// ReadTOML returns the tables of a document in file order, for reading
+// documents with the same line-based parser merges use
+func ReadTOML(doc []byte) ([]TOMLTable, error) {
+	tables, err := parseTOML(string(doc))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]TOMLTable, 0, len(tables))
+	for _, table := range tables {
+		read := TOMLTable{Name: table.name, Array: table.array, Values: make(map[string]string)}
+		for _, item := range table.items {
+			if item.key == "" {
+				continue
+			}
+			read.Keys = append(read.Keys, item.key)
+			read.Values[item.key] = item.value()
+		}
+		result = append(result, read)
+	}
+	return result, nil
+}
+
+// TOMLArray returns the elements of an array value, without comments
+func TOMLArray(value string) ([]string, bool) {
+	return arrayElements(value)
+}
@@ -0,0 +1,199 @@
+package merge
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// mergeYAML merges YAML documents, keeping the comments of base
+func mergeYAML(base, patch []byte, opts Options) ([]byte, error) {
+	root, err := mergeDocuments(base, patch, opts)
+	if err != nil || root == nil {
+		return patch, err
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(root); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// mergeJSON merges JSON documents. The result is indented with two spaces.
+func mergeJSON(base, patch []byte, opts Options) ([]byte, error) {
+	for _, doc := range [][]byte{base, patch} {
+		if len(bytes.TrimSpace(doc)) > 0 && !json.Valid(doc) {
+			return nil, fmt.Errorf("invalid JSON")
+		}
+	}
+
+	root, err := mergeDocuments(base, patch, opts)
+	if err != nil || root == nil {
+		return patch, err
+	}
+
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, root.Content[0], ""); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// mergeDocuments parses and merges two YAML (or JSON) documents. It returns
+// nil when base is empty.
+func mergeDocuments(base, patch []byte, opts Options) (*yaml.Node, error) {
+	var baseDoc, patchDoc yaml.Node
+	if err := yaml.Unmarshal(base, &baseDoc); err != nil {
+		return nil, fmt.Errorf("base: %w", err)
+	}
+	if err := yaml.Unmarshal(patch, &patchDoc); err != nil {
+		return nil, fmt.Errorf("patch: %w", err)
+	}
+	if len(baseDoc.Content) == 0 {
+		return nil, nil
+	}
+	if len(patchDoc.Content) == 0 {
+		return &baseDoc, nil
+	}
+
+	baseDoc.Content[0] = mergeNodes(baseDoc.Content[0], patchDoc.Content[0], opts, true)
+	return &baseDoc, nil
+}
+
+// mergeNodes merges patch into base and returns the result. Nested mappings
+// are only merged with the deep strategy.
+func mergeNodes(base, patch *yaml.Node, opts Options, root bool) *yaml.Node {
+	switch {
+	case base.Kind == yaml.MappingNode && patch.Kind == yaml.MappingNode:
+		if !root && opts.Maps == MapsReplace {
+			return patch
+		}
+		for i := 0; i+1 < len(patch.Content); i += 2 {
+			key, value := patch.Content[i], patch.Content[i+1]
+			if j := findKey(base, key.Value); j >= 0 {
+				base.Content[j+1] = mergeNodes(base.Content[j+1], value, opts, false)
+			} else {
+				base.Content = append(base.Content, key, value)
+			}
+		}
+		return base
+	case base.Kind == yaml.SequenceNode && patch.Kind == yaml.SequenceNode:
+		switch opts.Lists {
+		case ListsAppend:
+			base.Content = append(base.Content, patch.Content...)
+			return base
+		case ListsUnique:
+			for _, item := range patch.Content {
+				if !containsNode(base.Content, item) {
+					base.Content = append(base.Content, item)
+				}
+			}
+			return base
+		}
+	}
+	return patch
+}
+
+// containsNode reports whether nodes has a node equal to node
+func containsNode(nodes []*yaml.Node, node *yaml.Node) bool {
+	for _, n := range nodes {
+		if equalNodes(n, node) {
+			return true
+		}
+	}
+	return false
+}
+
+// equalNodes compares the values of two nodes, ignoring style and comments
+func equalNodes(a, b *yaml.Node) bool {
+	if a.Kind != b.Kind || a.Value != b.Value || a.ShortTag() != b.ShortTag() || len(a.Content) != len(b.Content) {
+		return false
+	}
+	for i := range a.Content {
+		if !equalNodes(a.Content[i], b.Content[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// findKey returns the index of key in the mapping node, or -1
+func findKey(mapping *yaml.Node, key string) int {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// writeJSON encodes a node parsed from JSON back to JSON, keeping key order
+func writeJSON(buf *bytes.Buffer, node *yaml.Node, indent string) error {
+	switch node.Kind {
+	case yaml.MappingNode:
+		if len(node.Content) == 0 {
+			buf.WriteString("{}")
+			return nil
+		}
+		buf.WriteString("{\n")
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			buf.WriteString(indent + "  ")
+			writeString(buf, node.Content[i].Value)
+			buf.WriteString(": ")
+			if err := writeJSON(buf, node.Content[i+1], indent+"  "); err != nil {
+				return err
+			}
+			if i+2 < len(node.Content) {
+				buf.WriteByte(',')
+			}
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(indent + "}")
+	case yaml.SequenceNode:
+		if len(node.Content) == 0 {
+			buf.WriteString("[]")
+			return nil
+		}
+		buf.WriteString("[\n")
+		for i, item := range node.Content {
+			buf.WriteString(indent + "  ")
+			if err := writeJSON(buf, item, indent+"  "); err != nil {
+				return err
+			}
+			if i+1 < len(node.Content) {
+				buf.WriteByte(',')
+			}
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(indent + "]")
+	case yaml.ScalarNode:
+		switch node.ShortTag() {
+		case "!!int", "!!float", "!!bool", "!!null":
+			buf.WriteString(strings.ToLower(node.Value))
+		default:
+			writeString(buf, node.Value)
+		}
+	default:
+		return fmt.Errorf("unsupported value at line %d", node.Line)
+	}
+	return nil
+}
+
+// writeString writes s as a JSON string without escaping HTML characters
+func writeString(buf *bytes.Buffer, s string) {
+	var quoted bytes.Buffer
+	enc := json.NewEncoder(&quoted)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	buf.Write(bytes.TrimSuffix(quoted.Bytes(), []byte("\n")))
+}
@@ -0,0 +1,110 @@
+// Package network holds the settings shared by everything devinit (or a
+// template hook) downloads: the standard proxy variables and a custom CA
+// bundle for corporate TLS interception.
+package network
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Settings configure outgoing connections. Proxies always come from
+// HTTP_PROXY, HTTPS_PROXY and NO_PROXY.
+type Settings struct {
+	// CABundle is a PEM file of extra certificate authorities to trust
+	CABundle string
+
+	// Insecure disables TLS certificate verification
+	Insecure bool
+}
+
+// caEnv lists the variables that point common tools at a CA bundle
+var caEnv = []string{
+	"SSL_CERT_FILE",       // OpenSSL, Go, Ruby
+	"REQUESTS_CA_BUNDLE",  // Python requests (poetry)
+	"PIP_CERT",            // pip
+	"CURL_CA_BUNDLE",      // curl
+	"NODE_EXTRA_CA_CERTS", // Node.js (npm)
+	"GIT_SSL_CAINFO",      // git
+	"CARGO_HTTP_CAINFO",   // cargo
+}
+
+// insecureEnv turns off certificate verification in tools that allow it
+var insecureEnv = []string{
+	"NODE_TLS_REJECT_UNAUTHORIZED=0",
+	"GIT_SSL_NO_VERIFY=true",
+}
+
+// Validate checks that CABundle can be read and holds certificates
+func (s Settings) Validate() error {
+	_, err := s.rootCAs()
+	return err
+}
+
+// rootCAs returns the system pool extended with CABundle, or nil (the
+// system pool) if no bundle is set
+func (s Settings) rootCAs() (*x509.CertPool, error) {
+	if s.CABundle == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(s.CABundle)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("CA bundle %s contains no PEM certificates", s.CABundle)
+	}
+	return pool, nil
+}
+
+// TLSConfig returns the TLS configuration for outgoing connections
+func (s Settings) TLSConfig() (*tls.Config, error) {
+	pool, err := s.rootCAs()
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		RootCAs:            pool,
+		InsecureSkipVerify: s.Insecure,
+	}, nil
+}
+
+// Client returns an HTTP client that uses the proxy environment variables
+// and the TLS settings
+func (s Settings) Client(timeout time.Duration) (*http.Client, error) {
+	tlsConfig, err := s.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	transport.TLSClientConfig = tlsConfig
+
+	return &http.Client{Transport: transport, Timeout: timeout}, nil
+}
+
+// Env returns the environment variables (KEY=VALUE) that pass the settings
+// on to hook commands. Proxy variables are inherited as they are.
+func (s Settings) Env() []string {
+	var env []string
+	if s.CABundle != "" {
+		for _, key := range caEnv {
+			env = append(env, key+"="+s.CABundle)
+		}
+	}
+	if s.Insecure {
+		env = append(env, insecureEnv...)
+	}
+	return env
+}
@@ -0,0 +1,73 @@
+package network
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClient(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(bundle, cert, 0644); err != nil {
+		t.Fatal(err)
+	}
+	notPEM := filepath.Join(t.TempDir(), "ca.txt")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		settings   Settings
+		wantErr    bool // building the client fails
+		wantGetErr bool
+	}{
+		{name: "untrusted server", settings: Settings{}, wantGetErr: true},
+		{name: "custom CA bundle", settings: Settings{CABundle: bundle}},
+		{name: "insecure", settings: Settings{Insecure: true}},
+		{name: "missing bundle", settings: Settings{CABundle: filepath.Join(t.TempDir(), "missing.pem")}, wantErr: true},
+		{name: "bundle without certificates", settings: Settings{CABundle: notPEM}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := tt.settings.Client(5 * time.Second)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Client() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			resp, err := client.Get(server.URL)
+			if (err != nil) != tt.wantGetErr {
+				t.Fatalf("Get() error = %v, wantGetErr %v", err, tt.wantGetErr)
+			}
+			if resp != nil {
+				resp.Body.Close()
+			}
+		})
+	}
+}
+
+func TestEnv(t *testing.T) {
+	if env := (Settings{}).Env(); len(env) != 0 {
+		t.Errorf("Env() = %v, want nothing by default", env)
+	}
+
+	env := strings.Join(Settings{CABundle: "/etc/corp.pem", Insecure: true}.Env(), "\n")
+	for _, want := range []string{"SSL_CERT_FILE=/etc/corp.pem", "NODE_EXTRA_CA_CERTS=/etc/corp.pem", "NODE_TLS_REJECT_UNAUTHORIZED=0"} {
+		if !strings.Contains(env, want) {
+			t.Errorf("Env() = %q, want it to contain %q", env, want)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package oci
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/renan-dev/devinit/internal/credentials"
+)
+
+// Credentials log in to a registry
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// authFile is the part of a Docker config.json, or of the auth.json of
+// Podman, Buildah and Skopeo, that holds credentials
+type authFile struct {
+	Auths map[string]struct {
+		Auth     string `json:"auth"`
+		Username string `json:"username"`
+		Password string `json:"password"`
+	} `json:"auths"`
+	CredHelpers map[string]string `json:"credHelpers"`
+	CredsStore  string            `json:"credsStore"`
+}
+
+// authFiles returns the files docker login and podman login store
+// credentials in, in the order they are read
+func authFiles() []string {
+	home, _ := os.UserHomeDir()
+	var files []string
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		files = append(files, filepath.Join(dir, "config.json"))
+	} else if home != "" {
+		files = append(files, filepath.Join(home, ".docker", "config.json"))
+	}
+	if file := os.Getenv("REGISTRY_AUTH_FILE"); file != "" {
+		files = append(files, file)
+	}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		files = append(files, filepath.Join(dir, "containers", "auth.json"))
+	}
+	if home != "" {
+		files = append(files, filepath.Join(home, ".config", "containers", "auth.json"))
+	}
+	return files
+}
+
+// authKeys returns the keys credentials of a registry are stored under
+func authKeys(registry string) []string {
+	if registry == "docker.io" {
+		return []string{"https://index.docker.io/v1/", "docker.io", "index.docker.io"}
+	}
+	return []string{registry, "https://" + registry, "http://" + registry}
+}
+
+// LookupCredentials returns the credentials docker login or podman login
+// stored for registry, from their files or the credential helpers these
+// name, and otherwise the token of the registry devinit has (see package
+// credentials); nil when there are none, for anonymous access
+func LookupCredentials(registry string) (*Credentials, error) {
+	for _, path := range authFiles() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var file authFile
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", path, err)
+		}
+
+		for _, key := range authKeys(registry) {
+			if helper := file.CredHelpers[key]; helper != "" {
+				return helperCredentials(helper, key)
+			}
+			entry, ok := file.Auths[key]
+			if !ok {
+				continue
+			}
+			if entry.Auth != "" {
+				decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+				if err != nil {
+					return nil, fmt.Errorf("invalid credentials of %s in %s: %w", registry, path, err)
+				}
+				username, password, _ := strings.Cut(string(decoded), ":")
+				return &Credentials{Username: username, Password: password}, nil
+			}
+			if entry.Username != "" {
+				return &Credentials{Username: entry.Username, Password: entry.Password}, nil
+			}
+		}
+		if file.CredsStore != "" {
+			for _, key := range authKeys(registry) {
+				if creds, err := helperCredentials(file.CredsStore, key); creds != nil || err != nil {
+					return creds, err
+				}
+			}
+		}
+	}
+
+	creds, err := credentials.Lookup(registry)
+	if err != nil || creds == nil {
+		return nil, err
+	}
+	return &Credentials{Username: creds.BasicUsername(registry), Password: creds.Secret}, nil
+}
+
+// runHelper runs docker-credential-<helper> get for a server and returns
+// its output; tests replace it
+var runHelper = func(helper, server string) ([]byte, error) {
+	cmd := exec.Command("docker-credential-"+helper, "get")
+	cmd.Stdin = strings.NewReader(server)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		// Helpers report unknown servers on stdout
+		if bytes.Contains(out, []byte("credentials not found")) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("docker-credential-%s: %w: %s", helper, err, strings.TrimSpace(stderr.String()+string(out)))
+	}
+	return out, nil
+}
+
+// helperCredentials asks a credential helper for the credentials of server
+func helperCredentials(helper, server string) (*Credentials, error) {
+	out, err := runHelper(helper, server)
+	if err != nil || out == nil {
+		return nil, err
+	}
+	var creds struct {
+		Username string
+		Secret   string
+	}
+	if err := json.Unmarshal(out, &creds); err != nil {
+		return nil, fmt.Errorf("docker-credential-%s: invalid output: %w", helper, err)
+	}
+	return &Credentials{Username: creds.Username, Password: creds.Secret}, nil
+}
+
+// challenge is the WWW-Authenticate header of a registry
+type challenge struct {
+	scheme string // bearer or basic
+	params map[string]string
+}
+
+// parseChallenge parses a WWW-Authenticate header such as
+// Bearer realm="https://ghcr.io/token",service="ghcr.io",scope="..."
+func parseChallenge(header string) challenge {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+	c := challenge{scheme: strings.ToLower(scheme), params: make(map[string]string)}
+	for rest = strings.TrimSpace(rest); rest != ""; {
+		key, value, ok := strings.Cut(rest, "=")
+		if !ok {
+			break
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		if strings.HasPrefix(value, `"`) {
+			end := strings.Index(value[1:], `"`)
+			if end < 0 {
+				end = len(value) - 1
+			}
+			c.params[key], rest = value[1:end+1], value[end+1:]
+			if len(rest) > 0 {
+				rest = rest[1:]
+			}
+		} else {
+			value, rest, _ = strings.Cut(value, ",")
+			c.params[key] = strings.TrimSpace(value)
+		}
+		rest = strings.TrimLeft(rest, ", ")
+	}
+	return c
+}
+
+// authorize answers the challenge of a registry for scope and returns the
+// Authorization header to send
+func (c *Client) authorize(ctx context.Context, registry string, ch challenge, scope string) (string, error) {
+	creds, err := c.credentials(registry)
+	if err != nil {
+		return "", err
+	}
+
+	switch ch.scheme {
+	case "basic":
+		if creds == nil {
+			return "", fmt.Errorf("%s requires a login: run docker login %s", registry, registry)
+		}
+		return "Basic " + basicAuth(creds), nil
+	case "bearer":
+	default:
+		return "", fmt.Errorf("%s asks for unsupported authentication %q", registry, ch.scheme)
+	}
+
+	realm, err := url.Parse(ch.params["realm"])
+	if err != nil || realm.Host == "" {
+		return "", fmt.Errorf("%s asks for a token from an invalid realm %q", registry, ch.params["realm"])
+	}
+	query := realm.Query()
+	if service := ch.params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	query.Set("scope", scope)
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if creds != nil {
+		req.Header.Set("Authorization", "Basic "+basicAuth(creds))
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		if creds == nil {
+			return "", fmt.Errorf("%s refused anonymous access (%s): run docker login %s", registry, resp.Status, registry)
+		}
+		return "", fmt.Errorf("%s refused the credentials of %s (%s)", registry, creds.Username, resp.Status)
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("invalid token from %s: %w", realm.Host, err)
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	if token.Token == "" {
+		return "", errors.New("no token from " + realm.Host)
+	}
+	return "Bearer " + token.Token, nil
+}
+
+// credentials returns the credentials of registry, looked up once
+func (c *Client) credentials(registry string) (*Credentials, error) {
+	if creds, ok := c.creds[registry]; ok {
+		return creds, nil
+	}
+	creds, err := c.lookup(registry)
+	if err != nil {
+		return nil, err
+	}
+	c.creds[registry] = creds
+	return creds, nil
+}
+
+func basicAuth(creds *Credentials) string {
+	return base64.StdEncoding.EncodeToString([]byte(creds.Username + ":" + creds.Password))
+}
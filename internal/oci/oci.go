@@ -0,0 +1,377 @@
+// Package oci distributes templates as OCI artifacts: a template is pushed
+// to a container registry (ghcr.io, ECR, Harbor, a local registry:2) as a
+// manifest with a config blob describing it and one layer, the template
+// packed by package archive, and pulled back from there.
+//
+// Registries are spoken to over the distribution API with the credentials
+// docker login or podman login stored, from their config files or the
+// credential helpers these name, so pushing and pulling templates needs no
+// login of its own; registries these have no credentials for get the token
+// of devinit templates login or the environment.
+package oci
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/renan-dev/devinit/internal/archive"
+)
+
+// Media types of template artifacts
+const (
+	ManifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+	ArtifactType      = "application/vnd.devinit.template.v1"
+	ConfigMediaType   = "application/vnd.devinit.template.config.v1+json"
+	LayerMediaType    = "application/vnd.devinit.template.layer.v1.tar+gzip"
+)
+
+// maxManifestSize bounds the manifests read from registries
+const maxManifestSize = 4 << 20
+
+// Descriptor points to a blob
+type Descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// Manifest is an OCI image manifest holding a template
+type Manifest struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType"`
+	ArtifactType  string            `json:"artifactType,omitempty"`
+	Config        Descriptor        `json:"config"`
+	Layers        []Descriptor      `json:"layers"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+// Config describes the template of an artifact, so registries and their
+// users can tell what it is without unpacking it
+type Config struct {
+	Name        string `json:"name"`
+	Language    string `json:"language"`
+	Framework   string `json:"framework"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Client pushes and pulls template artifacts. It is not safe for concurrent
+// use.
+type Client struct {
+	http *http.Client
+
+	// lookup finds the credentials of a registry; tests replace it
+	lookup func(registry string) (*Credentials, error)
+	creds  map[string]*Credentials
+
+	// auth holds the Authorization header that last worked for each
+	// registry and scope
+	auth map[string]string
+}
+
+// NewClient returns a client sending requests with httpClient, which
+// carries the proxy and TLS settings
+func NewClient(httpClient *http.Client) *Client {
+	return &Client{
+		http:   httpClient,
+		lookup: LookupCredentials,
+		creds:  make(map[string]*Credentials),
+		auth:   make(map[string]string),
+	}
+}
+
+// Digest returns the sha256 digest of data
+func Digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// Push pushes a template bundle, as packed by archive.Pack, with its config
+// to ref and returns the digest of the manifest. Blobs the repository
+// already has are not uploaded again.
+func (c *Client) Push(ctx context.Context, ref Reference, config Config, bundle []byte) (string, error) {
+	if ref.Digest != "" {
+		return "", fmt.Errorf("cannot push to %s: push to a tag, the digest follows from the content", ref)
+	}
+	configData, err := json.Marshal(config)
+	if err != nil {
+		return "", err
+	}
+
+	manifest := Manifest{
+		SchemaVersion: 2,
+		MediaType:     ManifestMediaType,
+		ArtifactType:  ArtifactType,
+		Config:        Descriptor{MediaType: ConfigMediaType, Digest: Digest(configData), Size: int64(len(configData))},
+		Layers: []Descriptor{{
+			MediaType:   LayerMediaType,
+			Digest:      Digest(bundle),
+			Size:        int64(len(bundle)),
+			Annotations: map[string]string{"org.opencontainers.image.title": "template.tar.gz"},
+		}},
+		Annotations: map[string]string{
+			"org.opencontainers.image.title":       config.Name,
+			"org.opencontainers.image.version":     config.Version,
+			"org.opencontainers.image.description": config.Description,
+		},
+	}
+	for key, value := range manifest.Annotations {
+		if value == "" {
+			delete(manifest.Annotations, key)
+		}
+	}
+	for _, blob := range []struct {
+		desc Descriptor
+		data []byte
+	}{{manifest.Config, configData}, {manifest.Layers[0], bundle}} {
+		if err := c.pushBlob(ctx, ref, blob.desc, blob.data); err != nil {
+			return "", err
+		}
+	}
+
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return "", err
+	}
+	header := http.Header{"Content-Type": {ManifestMediaType}}
+	resp, err := c.do(ctx, ref, true, http.MethodPut, c.url(ref, "manifests/"+ref.manifestRef()), data, header)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return "", registryError(resp, "push the manifest of "+ref.String())
+	}
+	return Digest(data), nil
+}
+
+// pushBlob uploads a blob in one request, unless the repository has it
+func (c *Client) pushBlob(ctx context.Context, ref Reference, desc Descriptor, data []byte) error {
+	resp, err := c.do(ctx, ref, true, http.MethodHead, c.url(ref, "blobs/"+desc.Digest), nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	resp, err = c.do(ctx, ref, true, http.MethodPost, c.url(ref, "blobs/uploads/"), nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		return registryError(resp, "start the upload of "+desc.Digest)
+	}
+	location, err := resp.Request.URL.Parse(resp.Header.Get("Location"))
+	if err != nil || resp.Header.Get("Location") == "" {
+		return fmt.Errorf("%s started an upload without a valid location", ref.Registry)
+	}
+	query := location.Query()
+	query.Set("digest", desc.Digest)
+	location.RawQuery = query.Encode()
+
+	header := http.Header{"Content-Type": {"application/octet-stream"}}
+	resp, err = c.do(ctx, ref, true, http.MethodPut, location.String(), data, header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return registryError(resp, "upload "+desc.Digest)
+	}
+	return nil
+}
+
+// Pull pulls the template of ref and unpacks it into dest. It returns the
+// digest of the manifest, which pins the artifact, and its config. Blobs
+// are checked against their digests.
+func (c *Client) Pull(ctx context.Context, ref Reference, dest string) (string, *Config, error) {
+	header := http.Header{"Accept": {ManifestMediaType}}
+	resp, err := c.do(ctx, ref, false, http.MethodGet, c.url(ref, "manifests/"+ref.manifestRef()), nil, header)
+	if err != nil {
+		return "", nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", nil, registryError(resp, "pull "+ref.String())
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestSize))
+	if err != nil {
+		return "", nil, err
+	}
+	digest := Digest(data)
+	if ref.Digest != "" && digest != ref.Digest {
+		return "", nil, fmt.Errorf("manifest of %s has digest %s", ref, digest)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return "", nil, fmt.Errorf("invalid manifest of %s: %w", ref, err)
+	}
+	if manifest.Config.MediaType != ConfigMediaType || len(manifest.Layers) != 1 || manifest.Layers[0].MediaType != LayerMediaType {
+		return "", nil, fmt.Errorf("%s is not a devinit template (config %s)", ref, manifest.Config.MediaType)
+	}
+
+	var configData bytes.Buffer
+	if err := c.pullBlob(ctx, ref, manifest.Config, &configData); err != nil {
+		return "", nil, err
+	}
+	var config Config
+	if err := json.Unmarshal(configData.Bytes(), &config); err != nil {
+		return "", nil, fmt.Errorf("invalid config of %s: %w", ref, err)
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(c.pullBlob(ctx, ref, manifest.Layers[0], pw))
+	}()
+	// The rest of the blob is read too, for the digest to be checked
+	err = archive.Unpack(pr, dest, 0)
+	if err == nil {
+		_, err = io.Copy(io.Discard, pr)
+	}
+	pr.CloseWithError(err)
+	if err != nil {
+		return "", nil, fmt.Errorf("template of %s: %w", ref, err)
+	}
+	return digest, &config, nil
+}
+
+// Resolve returns the digest of the manifest ref points to, without
+// pulling the template
+func (c *Client) Resolve(ctx context.Context, ref Reference) (string, error) {
+	if ref.Digest != "" {
+		return ref.Digest, nil
+	}
+	header := http.Header{"Accept": {ManifestMediaType}}
+	resp, err := c.do(ctx, ref, false, http.MethodHead, c.url(ref, "manifests/"+ref.manifestRef()), nil, header)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", registryError(resp, "resolve "+ref.String())
+	}
+	if digest := resp.Header.Get("Docker-Content-Digest"); digest != "" {
+		return digest, nil
+	}
+
+	// Registries need not send the digest, which the manifest then gives
+	resp, err = c.do(ctx, ref, false, http.MethodGet, c.url(ref, "manifests/"+ref.manifestRef()), nil, header)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", registryError(resp, "resolve "+ref.String())
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestSize))
+	if err != nil {
+		return "", err
+	}
+	return Digest(data), nil
+}
+
+// pullBlob writes a blob to w and checks its digest and size
+func (c *Client) pullBlob(ctx context.Context, ref Reference, desc Descriptor, w io.Writer) error {
+	resp, err := c.do(ctx, ref, false, http.MethodGet, c.url(ref, "blobs/"+desc.Digest), nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return registryError(resp, "pull "+desc.Digest)
+	}
+
+	hash := sha256.New()
+	n, err := io.Copy(io.MultiWriter(w, hash), io.LimitReader(resp.Body, desc.Size+1))
+	if err != nil {
+		return err
+	}
+	if got := "sha256:" + hex.EncodeToString(hash.Sum(nil)); n != desc.Size || got != desc.Digest {
+		return fmt.Errorf("blob %s of %s does not match its digest", desc.Digest, ref)
+	}
+	return nil
+}
+
+// url returns the URL of a path of the repository of ref
+func (c *Client) url(ref Reference, path string) string {
+	return fmt.Sprintf("%s://%s/v2/%s/%s", ref.scheme(), ref.apiHost(), ref.Repository, path)
+}
+
+// do sends a request to the registry of ref. When the registry asks for
+// authentication, it is answered with the stored credentials, for pulling
+// or for pushing too, and the request is sent again.
+func (c *Client) do(ctx context.Context, ref Reference, push bool, method, target string, body []byte, header http.Header) (*http.Response, error) {
+	scope := "repository:" + ref.Repository + ":pull"
+	if push {
+		scope += ",push"
+	}
+	key := ref.Registry + " " + scope
+
+	send := func() (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		// Only the registry gets the credentials, not the storage it may
+		// redirect blobs to
+		if auth := c.auth[key]; auth != "" && sameHost(req.URL, ref.apiHost()) {
+			req.Header.Set("Authorization", auth)
+		}
+		return c.http.Do(req)
+	}
+
+	resp, err := send()
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	resp.Body.Close()
+	auth, err := c.authorize(ctx, ref.Registry, parseChallenge(resp.Header.Get("WWW-Authenticate")), scope)
+	if err != nil {
+		return nil, err
+	}
+	c.auth[key] = auth
+	return send()
+}
+
+// sameHost reports whether u is on host
+func sameHost(u *url.URL, host string) bool {
+	return strings.EqualFold(u.Host, host)
+}
+
+// registryError describes a failed request, with the errors the registry
+// gave
+func registryError(resp *http.Response, action string) error {
+	var body struct {
+		Errors []struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	var details []string
+	if json.Unmarshal(data, &body) == nil {
+		for _, e := range body.Errors {
+			details = append(details, strings.TrimSpace(e.Code+": "+e.Message))
+		}
+	}
+	if len(details) == 0 {
+		return fmt.Errorf("failed to %s: %s", action, resp.Status)
+	}
+	return fmt.Errorf("failed to %s: %s (%s)", action, resp.Status, strings.Join(details, "; "))
+}
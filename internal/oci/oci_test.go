@@ -0,0 +1,292 @@
+package oci
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/renan-dev/devinit/internal/archive"
+)
+
+func TestParseReference(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("ab", 32)
+	tests := []struct {
+		ref     string
+		want    Reference
+		wantErr bool
+	}{
+		{ref: "ghcr.io/org/templates/python-fastapi:1.2.0", want: Reference{Registry: "ghcr.io", Repository: "org/templates/python-fastapi", Tag: "1.2.0"}},
+		{ref: "oci://localhost:5000/python-fastapi", want: Reference{Registry: "localhost:5000", Repository: "python-fastapi"}},
+		{ref: "registry.acme.io:443/t/api:v1@" + digest, want: Reference{Registry: "registry.acme.io:443", Repository: "t/api", Tag: "v1", Digest: digest}},
+		{ref: "org/templates/python-fastapi:1.2.0", wantErr: true},
+		{ref: "ghcr.io/Org/api", wantErr: true},
+		{ref: "ghcr.io/org/api:", wantErr: true},
+		{ref: "ghcr.io/org/api@sha256:abc", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseReference(tt.ref)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseReference(%q) error = %v, wantErr %v", tt.ref, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("ParseReference(%q) = %+v, want %+v", tt.ref, got, tt.want)
+		}
+	}
+}
+
+func TestParseChallenge(t *testing.T) {
+	c := parseChallenge(`Bearer realm="https://ghcr.io/token",service="ghcr.io",scope="repository:org/api:pull,push"`)
+	if c.scheme != "bearer" || c.params["realm"] != "https://ghcr.io/token" || c.params["service"] != "ghcr.io" || c.params["scope"] != "repository:org/api:pull,push" {
+		t.Errorf("parseChallenge() = %+v", c)
+	}
+	if c := parseChallenge(`Basic realm=registry`); c.scheme != "basic" || c.params["realm"] != "registry" {
+		t.Errorf("parseChallenge(basic) = %+v", c)
+	}
+}
+
+// registry is an in-memory registry asking for a bearer token, given for
+// the credentials user:secret
+type registry struct {
+	mu        sync.Mutex
+	blobs     map[string][]byte
+	manifests map[string][]byte
+	uploads   int
+}
+
+func newRegistry(t *testing.T) (*registry, *httptest.Server) {
+	r := &registry{blobs: make(map[string][]byte), manifests: make(map[string][]byte)}
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		if req.URL.Path == "/token" {
+			if user, password, _ := req.BasicAuth(); user != "user" || password != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			io.WriteString(w, `{"token": "t0k"}`)
+			return
+		}
+		if req.Header.Get("Authorization") != "Bearer t0k" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+server.URL+`/token",service="test"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		body, _ := io.ReadAll(req.Body)
+		switch path := req.URL.Path; {
+		case req.Method == http.MethodPost && strings.HasSuffix(path, "/blobs/uploads/"):
+			w.Header().Set("Location", "/upload/1?state=x")
+			w.WriteHeader(http.StatusAccepted)
+		case req.Method == http.MethodPut && strings.HasPrefix(path, "/upload/"):
+			digest := req.URL.Query().Get("digest")
+			if req.URL.Query().Get("state") != "x" || Digest(body) != digest {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			r.blobs[digest] = body
+			r.uploads++
+			w.WriteHeader(http.StatusCreated)
+		case strings.Contains(path, "/blobs/"):
+			_, digest, _ := strings.Cut(path, "/blobs/")
+			data, ok := r.blobs[digest]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write(data)
+		case strings.Contains(path, "/manifests/"):
+			repo, ref, _ := strings.Cut(strings.TrimPrefix(path, "/v2/"), "/manifests/")
+			if req.Method == http.MethodPut {
+				r.manifests[repo+":"+ref] = body
+				r.manifests[repo+":"+Digest(body)] = body
+				w.WriteHeader(http.StatusCreated)
+				return
+			}
+			data, ok := r.manifests[repo+":"+ref]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				io.WriteString(w, `{"errors": [{"code": "MANIFEST_UNKNOWN", "message": "manifest unknown"}]}`)
+				return
+			}
+			w.Write(data)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return r, server
+}
+
+// writeTemplate writes a template to dir
+func writeTemplate(t *testing.T, dir string) {
+	t.Helper()
+	for name, content := range map[string]string{
+		"template.yaml":           "version: 1.2.0\nlanguage: python\nframework: fastapi\n",
+		"files/main.py.tmpl":      "app = {{ .ProjectName }}\n",
+		"files/scripts/run.sh":    "#!/bin/sh\n",
+		"versions/1.0.0/old.yaml": "old\n",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestPushPull(t *testing.T) {
+	reg, server := newRegistry(t)
+	ref, err := ParseReference(strings.TrimPrefix(server.URL, "http://") + "/org/templates/python-fastapi:1.2.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source := t.TempDir()
+	writeTemplate(t, source)
+	bundle, err := archive.Pack(source, func(rel string) bool { return rel == "versions" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := Config{Name: "FastAPI", Language: "python", Framework: "fastapi", Version: "1.2.0"}
+
+	client := NewClient(server.Client())
+	client.lookup = func(string) (*Credentials, error) { return &Credentials{Username: "user", Password: "secret"}, nil }
+	digest, err := client.Push(context.Background(), ref, config, bundle)
+	if err != nil {
+		t.Fatalf("Push() unexpected error: %v", err)
+	}
+	if reg.uploads != 2 {
+		t.Errorf("Push() uploaded %d blobs, want the config and the template", reg.uploads)
+	}
+
+	// Blobs the registry has are not uploaded again
+	if again, err := client.Push(context.Background(), ref, config, bundle); err != nil || again != digest || reg.uploads != 2 {
+		t.Errorf("Push() again = %s, %v with %d uploads, want %s without uploads", again, err, reg.uploads, digest)
+	}
+	if got, err := NewClient(server.Client()).withCredentials("user", "secret").Resolve(context.Background(), ref); err != nil || got != digest {
+		t.Errorf("Resolve() = %s, %v, want %s", got, err, digest)
+	}
+
+	for _, pull := range []Reference{ref, {Registry: ref.Registry, Repository: ref.Repository, Digest: digest}} {
+		dest := t.TempDir()
+		got, gotConfig, err := NewClient(server.Client()).withCredentials("user", "secret").Pull(context.Background(), pull, dest)
+		if err != nil {
+			t.Fatalf("Pull(%s) unexpected error: %v", pull, err)
+		}
+		if got != digest || *gotConfig != config {
+			t.Errorf("Pull(%s) = %s, %+v, want %s, %+v", pull, got, gotConfig, digest, config)
+		}
+		data, err := os.ReadFile(filepath.Join(dest, "files", "main.py.tmpl"))
+		if err != nil || string(data) != "app = {{ .ProjectName }}\n" {
+			t.Errorf("pulled files/main.py.tmpl = %q, %v", data, err)
+		}
+		if _, err := os.Stat(filepath.Join(dest, "versions")); !os.IsNotExist(err) {
+			t.Errorf("pulled versions/, want it left out of the bundle")
+		}
+	}
+
+	if _, _, err := NewClient(server.Client()).withCredentials("user", "wrong").Pull(context.Background(), ref, t.TempDir()); err == nil || !strings.Contains(err.Error(), "refused the credentials of user") {
+		t.Errorf("Pull() with wrong credentials error = %v", err)
+	}
+	missing := ref
+	missing.Tag = "9.9.9"
+	if _, _, err := NewClient(server.Client()).withCredentials("user", "secret").Pull(context.Background(), missing, t.TempDir()); err == nil || !strings.Contains(err.Error(), "MANIFEST_UNKNOWN") {
+		t.Errorf("Pull() of a missing tag error = %v", err)
+	}
+
+	// A blob that does not match its digest is refused
+	for d := range reg.blobs {
+		if d != Digest([]byte(`{"name":"FastAPI","language":"python","framework":"fastapi","version":"1.2.0"}`)) {
+			reg.blobs[d] = append(reg.blobs[d], 0)
+		}
+	}
+	if _, _, err := NewClient(server.Client()).withCredentials("user", "secret").Pull(context.Background(), ref, t.TempDir()); err == nil || !strings.Contains(err.Error(), "does not match its digest") {
+		t.Errorf("Pull() of a corrupted blob error = %v", err)
+	}
+}
+
+// withCredentials makes the client log in with user and password
+func (c *Client) withCredentials(user, password string) *Client {
+	c.lookup = func(string) (*Credentials, error) { return &Credentials{Username: user, Password: password}, nil }
+	return c
+}
+
+func TestLookupCredentials(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("DOCKER_CONFIG", filepath.Join(home, "docker"))
+	t.Setenv("REGISTRY_AUTH_FILE", "")
+	t.Setenv("XDG_RUNTIME_DIR", filepath.Join(home, "run"))
+	t.Setenv("NETRC", filepath.Join(home, "netrc"))
+	t.Setenv("DEVINIT_TOKEN_HARBOR_ACME_IO", "harbor-token")
+
+	auth := base64.StdEncoding.EncodeToString([]byte("octo:ghp_x"))
+	writeFile(t, filepath.Join(home, "docker", "config.json"), `{
+		"auths": {"ghcr.io": {"auth": "`+auth+`"}, "https://index.docker.io/v1/": {}},
+		"credHelpers": {"123.dkr.ecr.eu-west-1.amazonaws.com": "ecr-login"},
+		"credsStore": "desktop"
+	}`)
+	writeFile(t, filepath.Join(home, "run", "containers", "auth.json"), `{"auths": {"quay.io": {"auth": "`+base64.StdEncoding.EncodeToString([]byte("robot:pw"))+`"}}}`)
+
+	var asked []string
+	original := runHelper
+	t.Cleanup(func() { runHelper = original })
+	runHelper = func(helper, server string) ([]byte, error) {
+		asked = append(asked, helper+" "+server)
+		if server == "docker.io" || server == "https://index.docker.io/v1/" {
+			return []byte(`{"Username": "hub", "Secret": "dckr_pat"}`), nil
+		}
+		if helper == "ecr-login" {
+			return []byte(`{"Username": "AWS", "Secret": "ecr-token"}`), nil
+		}
+		return nil, nil
+	}
+
+	tests := []struct {
+		registry string
+		want     *Credentials
+	}{
+		{registry: "ghcr.io", want: &Credentials{Username: "octo", Password: "ghp_x"}},
+		{registry: "123.dkr.ecr.eu-west-1.amazonaws.com", want: &Credentials{Username: "AWS", Password: "ecr-token"}},
+		{registry: "docker.io", want: &Credentials{Username: "hub", Password: "dckr_pat"}},
+		{registry: "quay.io", want: &Credentials{Username: "robot", Password: "pw"}},
+		{registry: "harbor.acme.io", want: &Credentials{Username: "oauth2", Password: "harbor-token"}},
+		{registry: "registry.acme.io", want: nil},
+	}
+	for _, tt := range tests {
+		got, err := LookupCredentials(tt.registry)
+		if err != nil {
+			t.Errorf("LookupCredentials(%s) unexpected error: %v", tt.registry, err)
+			continue
+		}
+		if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+			t.Errorf("LookupCredentials(%s) = %+v, want %+v", tt.registry, got, tt.want)
+		}
+	}
+	if len(asked) == 0 || asked[0] != "ecr-login 123.dkr.ecr.eu-west-1.amazonaws.com" {
+		t.Errorf("credential helpers asked: %v", asked)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
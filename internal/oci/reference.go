@@ -0,0 +1,102 @@
+package oci
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Prefix marks OCI references among other template sources
+const Prefix = "oci://"
+
+// Patterns of the parts of a reference, from the distribution spec
+var (
+	repositoryPattern = regexp.MustCompile(`^[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*$`)
+	tagPattern        = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$`)
+	digestPattern     = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+)
+
+// Reference names an artifact in a registry:
+// registry/repository[:tag][@digest]
+type Reference struct {
+	Registry   string // e.g. ghcr.io, localhost:5000
+	Repository string // e.g. org/templates/python-fastapi
+	Tag        string
+	Digest     string // sha256:...
+}
+
+// ParseReference parses a reference, with or without the oci:// prefix.
+// The registry is required: devinit does not assume Docker Hub.
+func ParseReference(s string) (Reference, error) {
+	var ref Reference
+	name := strings.TrimPrefix(s, Prefix)
+	if rest, digest, ok := strings.Cut(name, "@"); ok {
+		if !digestPattern.MatchString(digest) {
+			return ref, fmt.Errorf("invalid reference %q: digest must be sha256:<64 hex digits>", s)
+		}
+		name, ref.Digest = rest, digest
+	}
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, ref.Tag = name[:i], name[i+1:]
+		if !tagPattern.MatchString(ref.Tag) {
+			return ref, fmt.Errorf("invalid reference %q: invalid tag %q", s, ref.Tag)
+		}
+	}
+
+	registry, repository, ok := strings.Cut(name, "/")
+	if !ok || !(strings.ContainsAny(registry, ".:") || registry == "localhost") {
+		return ref, fmt.Errorf("invalid reference %q: must start with the registry, e.g. ghcr.io/org/templates/python-fastapi:1.2.0", s)
+	}
+	if !repositoryPattern.MatchString(repository) {
+		return ref, fmt.Errorf("invalid reference %q: invalid repository %q (lowercase letters, digits and separators)", s, repository)
+	}
+	ref.Registry, ref.Repository = registry, repository
+	return ref, nil
+}
+
+// String returns the reference as parsed
+func (r Reference) String() string {
+	s := r.Registry + "/" + r.Repository
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
+
+// manifestRef is the tag or digest the manifest is addressed by; the digest
+// wins when both are set
+func (r Reference) manifestRef() string {
+	if r.Digest != "" {
+		return r.Digest
+	}
+	if r.Tag != "" {
+		return r.Tag
+	}
+	return "latest"
+}
+
+// apiHost is the host serving the registry API; Docker Hub serves it from
+// another host than its name
+func (r Reference) apiHost() string {
+	if r.Registry == "docker.io" {
+		return "registry-1.docker.io"
+	}
+	return r.Registry
+}
+
+// scheme is http for registries on the local machine, which usually serve
+// no TLS, and https elsewhere
+func (r Reference) scheme() string {
+	host := r.Registry
+	if i := strings.LastIndex(host, ":"); i >= 0 && !strings.HasSuffix(host, "]") {
+		host = host[:i]
+	}
+	switch strings.Trim(host, "[]") {
+	case "localhost", "127.0.0.1", "::1":
+		return "http"
+	}
+	return "https"
+}
@@ -0,0 +1,166 @@
+package openapi
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// document is the subset of an OpenAPI 3 document devinit reads
+type document struct {
+	OpenAPI string `yaml:"openapi"`
+	Swagger string `yaml:"swagger"`
+	Info    struct {
+		Title       string `yaml:"title"`
+		Version     string `yaml:"version"`
+		Description string `yaml:"description"`
+	} `yaml:"info"`
+	Paths      ordered[*pathItem] `yaml:"paths"`
+	Components struct {
+		Schemas    ordered[*schema]    `yaml:"schemas"`
+		Parameters ordered[*parameter] `yaml:"parameters"`
+	} `yaml:"components"`
+}
+
+type pathItem struct {
+	Parameters []*parameter `yaml:"parameters"`
+	Get        *operation   `yaml:"get"`
+	Post       *operation   `yaml:"post"`
+	Put        *operation   `yaml:"put"`
+	Patch      *operation   `yaml:"patch"`
+	Delete     *operation   `yaml:"delete"`
+	Head       *operation   `yaml:"head"`
+	Options    *operation   `yaml:"options"`
+	Trace      *operation   `yaml:"trace"`
+}
+
+type methodOperation struct {
+	method    string
+	operation *operation
+}
+
+// operations returns the operations of the path item in methods order
+func (p *pathItem) operations() []methodOperation {
+	var ops []methodOperation
+	for i, op := range []*operation{p.Get, p.Post, p.Put, p.Patch, p.Delete, p.Head, p.Options, p.Trace} {
+		if op != nil {
+			ops = append(ops, methodOperation{methods[i], op})
+		}
+	}
+	return ops
+}
+
+type operation struct {
+	OperationID string         `yaml:"operationId"`
+	Summary     string         `yaml:"summary"`
+	Description string         `yaml:"description"`
+	Tags        []string       `yaml:"tags"`
+	Deprecated  bool           `yaml:"deprecated"`
+	Parameters  []*parameter   `yaml:"parameters"`
+	RequestBody *body          `yaml:"requestBody"`
+	Responses   ordered[*body] `yaml:"responses"`
+}
+
+type parameter struct {
+	Ref         string  `yaml:"$ref"`
+	Name        string  `yaml:"name"`
+	In          string  `yaml:"in"`
+	Required    bool    `yaml:"required"`
+	Description string  `yaml:"description"`
+	Schema      *schema `yaml:"schema"`
+}
+
+// body is a request body or a response
+type body struct {
+	Content ordered[*mediaType] `yaml:"content"`
+}
+
+type mediaType struct {
+	Schema *schema `yaml:"schema"`
+}
+
+type schema struct {
+	Ref                  string           `yaml:"$ref"`
+	Type                 schemaType       `yaml:"type"`
+	Format               string           `yaml:"format"`
+	Description          string           `yaml:"description"`
+	Nullable             bool             `yaml:"nullable"`
+	Items                *schema          `yaml:"items"`
+	Properties           ordered[*schema] `yaml:"properties"`
+	AdditionalProperties valueSchema      `yaml:"additionalProperties"`
+	Required             []string         `yaml:"required"`
+	Enum                 []interface{}    `yaml:"enum"`
+	AllOf                []*schema        `yaml:"allOf"`
+	OneOf                []*schema        `yaml:"oneOf"`
+	AnyOf                []*schema        `yaml:"anyOf"`
+}
+
+// flatten returns the properties and required properties of the schema,
+// including those of the inline schemas it composes with allOf, and the
+// schemas it extends by reference
+func (s *schema) flatten() (props ordered[*schema], required []string, extends []string) {
+	props, required = s.Properties, s.Required
+	for _, part := range s.AllOf {
+		if part.Ref != "" {
+			extends = append(extends, refName(part.Ref))
+			continue
+		}
+		p, r, e := part.flatten()
+		props, required, extends = append(props, p...), append(required, r...), append(extends, e...)
+	}
+	return props, required, extends
+}
+
+// schemaType is the type of a schema: one name, or in OpenAPI 3.1 a list
+// such as [string, "null"]
+type schemaType []string
+
+func (t *schemaType) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*t = schemaType{node.Value}
+		return nil
+	}
+	var names []string
+	if err := node.Decode(&names); err != nil {
+		return err
+	}
+	*t = names
+	return nil
+}
+
+// valueSchema is the additionalProperties of a schema: the schema of the
+// values of a map, or a boolean that leaves them untyped (nil)
+type valueSchema struct {
+	*schema
+}
+
+func (v *valueSchema) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return nil
+	}
+	v.schema = new(schema)
+	return node.Decode(v.schema)
+}
+
+// entry is a key of a mapping with its value
+type entry[T any] struct {
+	Key   string
+	Value T
+}
+
+// ordered is a mapping decoded in document order
+type ordered[T any] []entry[T]
+
+func (o *ordered[T]) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: expected a mapping", node.Line)
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		var value T
+		if err := node.Content[i+1].Decode(&value); err != nil {
+			return err
+		}
+		*o = append(*o, entry[T]{Key: node.Content[i].Value, Value: value})
+	}
+	return nil
+}
@@ -0,0 +1,353 @@
+// Package openapi reads the operations and models of an OpenAPI 3 document
+// into a structure templates can range over, so framework templates can
+// generate route stubs and model classes from a contract.
+package openapi
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Spec is what templates see of an OpenAPI document as {{ .OpenAPI }}
+type Spec struct {
+	Title       string `yaml:"title,omitempty"`
+	Version     string `yaml:"version,omitempty"`
+	Description string `yaml:"description,omitempty"`
+
+	// Endpoints are sorted by path, then method
+	Endpoints []Endpoint `yaml:"endpoints,omitempty"`
+
+	// Schemas are the component schemas in document order, except that
+	// schemas come after those they extend, so classes can subclass them
+	Schemas []Schema `yaml:"schemas,omitempty"`
+}
+
+// Endpoint is one operation of the API
+type Endpoint struct {
+	Method string `yaml:"method,omitempty"` // upper case, e.g. GET
+	Path   string `yaml:"path,omitempty"`   // e.g. /pets/{petId}
+
+	// Name is the operationId, or one derived from the method and path
+	// (get_pets_pet_id) when the operation has none
+	Name        string   `yaml:"name,omitempty"`
+	OperationID string   `yaml:"operation_id,omitempty"`
+	Summary     string   `yaml:"summary,omitempty"`
+	Description string   `yaml:"description,omitempty"`
+	Tags        []string `yaml:"tags,omitempty"`
+	Deprecated  bool     `yaml:"deprecated,omitempty"`
+
+	// Parameters include those declared for the whole path
+	Parameters []Parameter `yaml:"parameters,omitempty"`
+
+	// RequestBody is the JSON request body (nil if none)
+	RequestBody *Type `yaml:"request_body,omitempty"`
+
+	// Status is the first 2xx response and Response its JSON body (nil for
+	// responses without content)
+	Status   int   `yaml:"status,omitempty"`
+	Response *Type `yaml:"response,omitempty"`
+}
+
+// PathParameters returns the parameters of the endpoint found in its path
+func (e Endpoint) PathParameters() []Parameter {
+	return e.parametersIn("path")
+}
+
+// QueryParameters returns the query string parameters of the endpoint
+func (e Endpoint) QueryParameters() []Parameter {
+	return e.parametersIn("query")
+}
+
+func (e Endpoint) parametersIn(in string) []Parameter {
+	var params []Parameter
+	for _, p := range e.Parameters {
+		if p.In == in {
+			params = append(params, p)
+		}
+	}
+	return params
+}
+
+// Parameter is a path, query, header or cookie parameter of an endpoint
+type Parameter struct {
+	Name        string `yaml:"name,omitempty"`
+	In          string `yaml:"in,omitempty"`
+	Required    bool   `yaml:"required,omitempty"`
+	Description string `yaml:"description,omitempty"`
+	Type        Type   `yaml:"type,omitempty"`
+}
+
+// Schema is a named model of the components section
+type Schema struct {
+	Name        string `yaml:"name,omitempty"`
+	Description string `yaml:"description,omitempty"`
+
+	// Fields are the properties of an object schema, in document order
+	Fields []Field `yaml:"fields,omitempty"`
+
+	// Extends lists the schemas an allOf composition refers to, whose
+	// fields the schema also has
+	Extends []string `yaml:"extends,omitempty"`
+
+	// Enum lists the values of an enumeration schema
+	Enum []string `yaml:"enum,omitempty"`
+
+	// Type is the schema itself, for schemas that are not objects
+	Type Type `yaml:"type,omitempty"`
+}
+
+// Field is a property of an object schema
+type Field struct {
+	Name        string `yaml:"name,omitempty"`
+	Description string `yaml:"description,omitempty"`
+	Required    bool   `yaml:"required,omitempty"`
+	Type        Type   `yaml:"type,omitempty"`
+}
+
+// Load reads the OpenAPI document (YAML or JSON) at path
+func Load(path string) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	spec, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return spec, nil
+}
+
+// Parse reads an OpenAPI 3 document in YAML or JSON
+func Parse(data []byte) (*Spec, error) {
+	var doc document
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		if doc.Swagger != "" {
+			return nil, fmt.Errorf("swagger %s documents are not supported: convert them to OpenAPI 3", doc.Swagger)
+		}
+		return nil, fmt.Errorf("not an OpenAPI 3 document (missing openapi: 3.x)")
+	}
+
+	spec := &Spec{
+		Title:       doc.Info.Title,
+		Version:     doc.Info.Version,
+		Description: doc.Info.Description,
+		Endpoints:   []Endpoint{},
+		Schemas:     []Schema{},
+	}
+
+	for _, path := range doc.Paths {
+		for _, op := range path.Value.operations() {
+			endpoint, err := doc.endpoint(path.Key, op.method, op.operation, path.Value.Parameters)
+			if err != nil {
+				return nil, err
+			}
+			spec.Endpoints = append(spec.Endpoints, endpoint)
+		}
+	}
+	sort.SliceStable(spec.Endpoints, func(i, j int) bool {
+		a, b := spec.Endpoints[i], spec.Endpoints[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return methodRank(a.Method) < methodRank(b.Method)
+	})
+
+	for _, entry := range doc.Components.Schemas {
+		spec.Schemas = append(spec.Schemas, newSchema(entry.Key, entry.Value))
+	}
+	spec.Schemas = baseFirst(spec.Schemas)
+	return spec, nil
+}
+
+// baseFirst orders schemas after the schemas they extend, keeping the order
+// otherwise
+func baseFirst(schemas []Schema) []Schema {
+	byName := make(map[string]Schema, len(schemas))
+	for _, s := range schemas {
+		byName[s.Name] = s
+	}
+
+	ordered := make([]Schema, 0, len(schemas))
+	visited := make(map[string]bool, len(schemas))
+	var visit func(s Schema)
+	visit = func(s Schema) {
+		if visited[s.Name] {
+			return
+		}
+		visited[s.Name] = true
+		for _, base := range s.Extends {
+			if b, ok := byName[base]; ok {
+				visit(b)
+			}
+		}
+		ordered = append(ordered, s)
+	}
+	for _, s := range schemas {
+		visit(s)
+	}
+	return ordered
+}
+
+// endpoint converts an operation, resolving parameter references
+func (doc *document) endpoint(path, method string, op *operation, shared []*parameter) (Endpoint, error) {
+	endpoint := Endpoint{
+		Method:      strings.ToUpper(method),
+		Path:        path,
+		Name:        op.OperationID,
+		OperationID: op.OperationID,
+		Summary:     op.Summary,
+		Description: op.Description,
+		Tags:        op.Tags,
+		Deprecated:  op.Deprecated,
+		Parameters:  []Parameter{},
+	}
+	if endpoint.Name == "" {
+		endpoint.Name = deriveName(method, path)
+	}
+
+	seen := make(map[string]bool)
+	for _, params := range [][]*parameter{op.Parameters, shared} {
+		for _, p := range params {
+			p, err := doc.resolveParameter(p)
+			if err != nil {
+				return Endpoint{}, fmt.Errorf("%s %s: %w", endpoint.Method, path, err)
+			}
+			// Operation parameters override those of the path
+			if seen[p.In+" "+p.Name] {
+				continue
+			}
+			seen[p.In+" "+p.Name] = true
+			endpoint.Parameters = append(endpoint.Parameters, Parameter{
+				Name:        p.Name,
+				In:          p.In,
+				Required:    p.Required || p.In == "path",
+				Description: p.Description,
+				Type:        newType(p.Schema),
+			})
+		}
+	}
+
+	if op.RequestBody != nil {
+		if s := jsonSchema(op.RequestBody.Content); s != nil {
+			t := newType(s)
+			endpoint.RequestBody = &t
+		}
+	}
+
+	for _, entry := range op.Responses {
+		status, err := strconv.Atoi(entry.Key)
+		if err != nil || status < 200 || status > 299 {
+			continue
+		}
+		if endpoint.Status == 0 || status < endpoint.Status {
+			endpoint.Status = status
+			endpoint.Response = nil
+			if s := jsonSchema(entry.Value.Content); s != nil {
+				t := newType(s)
+				endpoint.Response = &t
+			}
+		}
+	}
+	return endpoint, nil
+}
+
+// resolveParameter follows a reference to a component parameter
+func (doc *document) resolveParameter(p *parameter) (*parameter, error) {
+	if p.Ref == "" {
+		return p, nil
+	}
+	name := refName(p.Ref)
+	for _, entry := range doc.Components.Parameters {
+		if entry.Key == name {
+			return entry.Value, nil
+		}
+	}
+	return nil, fmt.Errorf("unresolved parameter reference %s", p.Ref)
+}
+
+// jsonSchema returns the schema of the JSON media type of a content map
+func jsonSchema(content ordered[*mediaType]) *schema {
+	for _, entry := range content {
+		if entry.Key == "application/json" || strings.HasSuffix(entry.Key, "+json") {
+			return entry.Value.Schema
+		}
+	}
+	return nil
+}
+
+func newSchema(name string, s *schema) Schema {
+	result := Schema{Name: name, Description: s.Description, Fields: []Field{}, Type: newType(s)}
+	for _, value := range s.Enum {
+		result.Enum = append(result.Enum, fmt.Sprint(value))
+	}
+
+	props, requiredFields, extends := s.flatten()
+	result.Extends = extends
+	required := make(map[string]bool, len(requiredFields))
+	for _, field := range requiredFields {
+		required[field] = true
+	}
+	for _, entry := range props {
+		result.Fields = append(result.Fields, Field{
+			Name:        entry.Key,
+			Description: entry.Value.Description,
+			Required:    required[entry.Key],
+			Type:        newType(entry.Value),
+		})
+	}
+	return result
+}
+
+// deriveName names an operation without operationId from its method and
+// path, e.g. get_pets_pet_id for GET /pets/{petId}
+func deriveName(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	underscore := true
+	for _, r := range path {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if unicode.IsUpper(r) {
+				if !underscore {
+					b.WriteByte('_')
+				}
+				r = unicode.ToLower(r)
+			} else if underscore {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r)
+			underscore = false
+		default:
+			underscore = true
+		}
+	}
+	return b.String()
+}
+
+// methods are the operations of a path item, in the order endpoints of the
+// same path are listed
+var methods = []string{"get", "post", "put", "patch", "delete", "head", "options", "trace"}
+
+func methodRank(method string) int {
+	for i, m := range methods {
+		if strings.EqualFold(m, method) {
+			return i
+		}
+	}
+	return len(methods)
+}
+
+// refName returns the last segment of a reference such as
+// #/components/schemas/Pet
+func refName(ref string) string {
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
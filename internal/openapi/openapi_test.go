@@ -0,0 +1,197 @@
+package openapi
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const petstore = `openapi: 3.0.3
+info:
+  title: Petstore
+  version: 1.2.0
+paths:
+  /pets/{petId}:
+    parameters:
+      - $ref: "#/components/parameters/PetId"
+      - name: X-Request-Id
+        in: header
+        schema: {type: string}
+    get:
+      operationId: showPetById
+      tags: [pets]
+      responses:
+        "200":
+          description: A pet
+          content:
+            application/json:
+              schema: {$ref: "#/components/schemas/Pet"}
+        default:
+          description: Error
+    delete:
+      responses:
+        "204": {description: Deleted}
+  /pets:
+    post:
+      summary: Create a pet
+      requestBody:
+        content:
+          application/json:
+            schema: {$ref: "#/components/schemas/NewPet"}
+      responses:
+        "201":
+          content:
+            application/json:
+              schema: {$ref: "#/components/schemas/Pet"}
+    get:
+      operationId: listPets
+      parameters:
+        - name: limit
+          in: query
+          schema: {type: integer, format: int32}
+      responses:
+        "200":
+          content:
+            application/json:
+              schema:
+                type: array
+                items: {$ref: "#/components/schemas/Pet"}
+components:
+  parameters:
+    PetId:
+      name: petId
+      in: path
+      schema: {type: string, format: uuid}
+  schemas:
+    Pet:
+      allOf:
+        - $ref: "#/components/schemas/NewPet"
+        - type: object
+          required: [id]
+          properties:
+            id: {type: integer}
+    NewPet:
+      type: object
+      required: [name]
+      properties:
+        name: {type: string}
+        tag: {type: string, nullable: true}
+        born: {type: string, format: date}
+        labels:
+          type: object
+          additionalProperties: {type: string}
+    Status:
+      type: string
+      enum: [available, sold]
+`
+
+func TestParse(t *testing.T) {
+	spec, err := Parse([]byte(petstore))
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+	if spec.Title != "Petstore" || spec.Version != "1.2.0" {
+		t.Errorf("Parse() info = %q %q, want Petstore 1.2.0", spec.Title, spec.Version)
+	}
+
+	var endpoints []string
+	for _, e := range spec.Endpoints {
+		var params []string
+		for _, p := range e.Parameters {
+			params = append(params, p.In+":"+p.Name+":"+p.Type.Python())
+		}
+		body, response := "-", "-"
+		if e.RequestBody != nil {
+			body = e.RequestBody.Python()
+		}
+		if e.Response != nil {
+			response = e.Response.Python()
+		}
+		endpoints = append(endpoints, strings.Join([]string{e.Method, e.Path, e.Name, strings.Join(params, ","), body, response}, " "))
+	}
+	wantEndpoints := []string{
+		"GET /pets listPets query:limit:int - list[Pet]",
+		"POST /pets post_pets  NewPet Pet",
+		"GET /pets/{petId} showPetById path:petId:str,header:X-Request-Id:str - Pet",
+		"DELETE /pets/{petId} delete_pets_pet_id path:petId:str,header:X-Request-Id:str - -",
+	}
+	if !reflect.DeepEqual(endpoints, wantEndpoints) {
+		t.Errorf("Parse() endpoints =\n%s\nwant\n%s", strings.Join(endpoints, "\n"), strings.Join(wantEndpoints, "\n"))
+	}
+	if got := spec.Endpoints[2]; got.Status != 200 || len(got.PathParameters()) != 1 || !got.PathParameters()[0].Required {
+		t.Errorf("GET /pets/{petId} = %+v, want status 200 and a required path parameter", got)
+	}
+	if got := spec.Endpoints[3].Status; got != 204 {
+		t.Errorf("DELETE status = %d, want 204", got)
+	}
+
+	var schemas []string
+	for _, s := range spec.Schemas {
+		var fields []string
+		for _, f := range s.Fields {
+			field := f.Name + ":" + f.Type.Python()
+			if f.Required {
+				field += "!"
+			}
+			fields = append(fields, field)
+		}
+		schemas = append(schemas, s.Name+" "+strings.Join(s.Extends, ",")+" "+strings.Join(fields, ",")+" "+strings.Join(s.Enum, "|"))
+	}
+	wantSchemas := []string{
+		"NewPet  name:str!,tag:str | None,born:date,labels:dict[str, str] ",
+		"Pet NewPet id:int! ",
+		"Status   available|sold",
+	}
+	if !reflect.DeepEqual(schemas, wantSchemas) {
+		t.Errorf("Parse() schemas =\n%s\nwant\n%s", strings.Join(schemas, "\n"), strings.Join(wantSchemas, "\n"))
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, doc := range []string{
+		`swagger: "2.0"`,
+		`info: {title: x}`,
+		`openapi: 3.1.0
+paths:
+  /a:
+    get:
+      parameters: [{$ref: "#/components/parameters/Missing"}]`,
+		`openapi: [`,
+	} {
+		if _, err := Parse([]byte(doc)); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", doc)
+		}
+	}
+}
+
+func TestTypes(t *testing.T) {
+	str := Type{Name: "string"}
+	pet := Type{Ref: "Pet"}
+	tests := []struct {
+		typ                        Type
+		python, typescript, golang string
+	}{
+		{typ: str, python: "str", typescript: "string", golang: "string"},
+		{typ: Type{Name: "string", Nullable: true}, python: "str | None", typescript: "string | null", golang: "*string"},
+		{typ: Type{Name: "string", Format: "date-time"}, python: "datetime", typescript: "string", golang: "time.Time"},
+		{typ: Type{Name: "integer", Format: "int32"}, python: "int", typescript: "number", golang: "int32"},
+		{typ: Type{Name: "number"}, python: "float", typescript: "number", golang: "float64"},
+		{typ: Type{Name: "array", Items: &pet}, python: "list[Pet]", typescript: "Pet[]", golang: "[]Pet"},
+		{typ: Type{Name: "array", Items: &Type{Ref: "Pet", Nullable: true}}, python: "list[Pet | None]", typescript: "(Pet | null)[]", golang: "[]*Pet"},
+		{typ: Type{Name: "object", Values: &str}, python: "dict[str, str]", typescript: "Record<string, string>", golang: "map[string]string"},
+		{typ: Type{Name: "object"}, python: "dict[str, Any]", typescript: "Record<string, unknown>", golang: "map[string]any"},
+		{typ: Type{}, python: "Any", typescript: "unknown", golang: "any"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.typ.Python(); got != tt.python {
+			t.Errorf("%+v.Python() = %q, want %q", tt.typ, got, tt.python)
+		}
+		if got := tt.typ.TypeScript(); got != tt.typescript {
+			t.Errorf("%+v.TypeScript() = %q, want %q", tt.typ, got, tt.typescript)
+		}
+		if got := tt.typ.Go(); got != tt.golang {
+			t.Errorf("%+v.Go() = %q, want %q", tt.typ, got, tt.golang)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package openapi
+
+// Type is the type of a parameter, field or body. Templates turn it into a
+// language type with {{ .Type.Python }}, {{ .Type.TypeScript }} or
+// {{ .Type.Go }}.
+type Type struct {
+	// Name is string, integer, number, boolean, array or object; empty
+	// means any value
+	Name   string `yaml:"name,omitempty"`
+	Format string `yaml:"format,omitempty"`
+
+	// Ref is the name of the component schema the type refers to
+	Ref string `yaml:"ref,omitempty"`
+
+	// Items is the element type of arrays and Values the value type of
+	// objects used as maps (additionalProperties)
+	Items  *Type `yaml:"items,omitempty"`
+	Values *Type `yaml:"values,omitempty"`
+
+	Nullable bool `yaml:"nullable,omitempty"`
+}
+
+// newType converts a schema; nil is any value
+func newType(s *schema) Type {
+	if s == nil {
+		return Type{}
+	}
+	if s.Ref != "" {
+		return Type{Ref: refName(s.Ref), Nullable: s.Nullable}
+	}
+	// allOf with a single schema is how references get a description
+	if len(s.AllOf) == 1 && len(s.Properties) == 0 {
+		t := newType(s.AllOf[0])
+		t.Nullable = t.Nullable || s.Nullable
+		return t
+	}
+
+	t := Type{Format: s.Format, Nullable: s.Nullable}
+	for _, name := range s.Type {
+		if name == "null" {
+			t.Nullable = true
+		} else if t.Name == "" {
+			t.Name = name
+		}
+	}
+	if t.Name == "" && (len(s.Properties) > 0 || len(s.AllOf) > 0) {
+		t.Name = "object"
+	}
+
+	switch t.Name {
+	case "array":
+		items := newType(s.Items)
+		t.Items = &items
+	case "object":
+		if s.AdditionalProperties.schema != nil && len(s.Properties) == 0 {
+			values := newType(s.AdditionalProperties.schema)
+			t.Values = &values
+		}
+	}
+	return t
+}
+
+// Python returns the type annotation, e.g. list[Pet] or str | None
+func (t Type) Python() string {
+	var name string
+	switch {
+	case t.Ref != "":
+		name = t.Ref
+	case t.Name == "string":
+		switch t.Format {
+		case "date-time":
+			name = "datetime"
+		case "date":
+			name = "date"
+		case "binary":
+			name = "bytes"
+		default:
+			name = "str"
+		}
+	case t.Name == "integer":
+		name = "int"
+	case t.Name == "number":
+		name = "float"
+	case t.Name == "boolean":
+		name = "bool"
+	case t.Name == "array":
+		name = "list[" + t.Items.Python() + "]"
+	case t.Name == "object" && t.Values != nil:
+		name = "dict[str, " + t.Values.Python() + "]"
+	case t.Name == "object":
+		name = "dict[str, Any]"
+	default:
+		return "Any"
+	}
+	if t.Nullable {
+		name += " | None"
+	}
+	return name
+}
+
+// TypeScript returns the type, e.g. Pet[] or string | null
+func (t Type) TypeScript() string {
+	var name string
+	switch {
+	case t.Ref != "":
+		name = t.Ref
+	case t.Name == "string":
+		name = "string"
+	case t.Name == "integer", t.Name == "number":
+		name = "number"
+	case t.Name == "boolean":
+		name = "boolean"
+	case t.Name == "array":
+		items := t.Items.TypeScript()
+		if t.Items.Nullable {
+			items = "(" + items + ")"
+		}
+		name = items + "[]"
+	case t.Name == "object" && t.Values != nil:
+		name = "Record<string, " + t.Values.TypeScript() + ">"
+	case t.Name == "object":
+		name = "Record<string, unknown>"
+	default:
+		return "unknown"
+	}
+	if t.Nullable {
+		name += " | null"
+	}
+	return name
+}
+
+// Go returns the type, e.g. []Pet or *string for nullable values
+func (t Type) Go() string {
+	var name string
+	switch {
+	case t.Ref != "":
+		name = t.Ref
+	case t.Name == "string" && (t.Format == "date-time" || t.Format == "date"):
+		name = "time.Time"
+	case t.Name == "string" && t.Format == "binary":
+		return "[]byte"
+	case t.Name == "string":
+		name = "string"
+	case t.Name == "integer" && t.Format == "int32":
+		name = "int32"
+	case t.Name == "integer":
+		name = "int64"
+	case t.Name == "number" && t.Format == "float":
+		name = "float32"
+	case t.Name == "number":
+		name = "float64"
+	case t.Name == "boolean":
+		name = "bool"
+	case t.Name == "array":
+		return "[]" + t.Items.Go()
+	case t.Name == "object" && t.Values != nil:
+		return "map[string]" + t.Values.Go()
+	case t.Name == "object":
+		return "map[string]any"
+	default:
+		return "any"
+	}
+	if t.Nullable {
+		name = "*" + name
+	}
+	return name
+}
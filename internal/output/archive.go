@@ -0,0 +1,95 @@
+package output
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"path"
+	"time"
+)
+
+// TarGz streams files into a gzip-compressed tar archive. Call Close to
+// finish the archive.
+type TarGz struct {
+	gz      *gzip.Writer
+	tw      *tar.Writer
+	prefix  string
+	modTime time.Time
+}
+
+// NewTarGz returns a backend writing a .tar.gz archive to w. Entries are
+// placed below prefix (e.g. the project name); an empty prefix puts them at
+// the root of the archive.
+func NewTarGz(w io.Writer, prefix string) *TarGz {
+	gz := gzip.NewWriter(w)
+	return &TarGz{gz: gz, tw: tar.NewWriter(gz), prefix: prefix, modTime: time.Now()}
+}
+
+// WriteFile adds a file to the archive
+func (t *TarGz) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	if err := checkPath(name); err != nil {
+		return err
+	}
+
+	header := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     path.Join(t.prefix, name),
+		Mode:     int64(perm.Perm()),
+		Size:     int64(len(data)),
+		ModTime:  t.modTime,
+	}
+	if err := t.tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := t.tw.Write(data)
+	return err
+}
+
+// Close finishes the archive. It does not close the underlying writer.
+func (t *TarGz) Close() error {
+	if err := t.tw.Close(); err != nil {
+		return err
+	}
+	return t.gz.Close()
+}
+
+// Zip streams files into a zip archive. Call Close to finish the archive.
+type Zip struct {
+	zw      *zip.Writer
+	prefix  string
+	modTime time.Time
+}
+
+// NewZip returns a backend writing a zip archive to w, with entries below
+// prefix like NewTarGz
+func NewZip(w io.Writer, prefix string) *Zip {
+	return &Zip{zw: zip.NewWriter(w), prefix: prefix, modTime: time.Now()}
+}
+
+// WriteFile adds a file to the archive
+func (z *Zip) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	if err := checkPath(name); err != nil {
+		return err
+	}
+
+	header := &zip.FileHeader{
+		Name:     path.Join(z.prefix, name),
+		Method:   zip.Deflate,
+		Modified: z.modTime,
+	}
+	header.SetMode(perm)
+
+	w, err := z.zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// Close finishes the archive. It does not close the underlying writer.
+func (z *Zip) Close() error {
+	return z.zw.Close()
+}
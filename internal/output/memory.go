@@ -0,0 +1,162 @@
+package output
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Memory keeps files in memory. It implements fs.FS, so a generated project
+// can be inspected, served or compared without touching the disk.
+type Memory struct {
+	files map[string]*memFile
+}
+
+type memFile struct {
+	data []byte
+	mode fs.FileMode
+}
+
+// NewMemory returns an empty in-memory backend
+func NewMemory() *Memory {
+	return &Memory{files: make(map[string]*memFile)}
+}
+
+// WriteFile stores a copy of data under name, replacing any previous file
+func (m *Memory) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	if err := checkPath(name); err != nil {
+		return err
+	}
+
+	m.files[name] = &memFile{data: bytes.Clone(data), mode: perm}
+	return nil
+}
+
+// ReadFile returns a copy of the content of a file. It implements
+// fs.ReadFileFS.
+func (m *Memory) ReadFile(name string) ([]byte, error) {
+	file, ok := m.files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrNotExist}
+	}
+	return bytes.Clone(file.data), nil
+}
+
+// Files returns the content of every file keyed by path
+func (m *Memory) Files() map[string][]byte {
+	files := make(map[string][]byte, len(m.files))
+	for name, file := range m.files {
+		files[name] = file.data
+	}
+	return files
+}
+
+// Open implements fs.FS
+func (m *Memory) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	if file, ok := m.files[name]; ok {
+		info := &memInfo{name: path.Base(name), size: int64(len(file.data)), mode: file.mode}
+		return &memOpenFile{Reader: bytes.NewReader(file.data), info: info}, nil
+	}
+
+	entries, ok := m.readDir(name)
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return &memOpenDir{info: dirInfo(path.Base(name)), entries: entries}, nil
+}
+
+// readDir returns the entries of directory name, and whether it exists.
+// Directories exist implicitly when files are stored below them.
+func (m *Memory) readDir(name string) ([]fs.DirEntry, bool) {
+	prefix := name + "/"
+	if name == "." {
+		prefix = ""
+	}
+
+	seen := make(map[string]fs.DirEntry)
+	for filePath, file := range m.files {
+		rest, ok := strings.CutPrefix(filePath, prefix)
+		if !ok {
+			continue
+		}
+		if child, _, isDir := strings.Cut(rest, "/"); isDir {
+			seen[child] = fs.FileInfoToDirEntry(dirInfo(child))
+		} else {
+			seen[rest] = fs.FileInfoToDirEntry(&memInfo{name: rest, size: int64(len(file.data)), mode: file.mode})
+		}
+	}
+	if len(seen) == 0 && name != "." {
+		return nil, false
+	}
+
+	entries := make([]fs.DirEntry, 0, len(seen))
+	for _, entry := range seen {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, true
+}
+
+// memInfo implements fs.FileInfo for files and directories
+type memInfo struct {
+	name string
+	size int64
+	mode fs.FileMode
+}
+
+func dirInfo(name string) *memInfo {
+	return &memInfo{name: name, mode: fs.ModeDir | 0755}
+}
+
+func (i *memInfo) Name() string       { return i.name }
+func (i *memInfo) Size() int64        { return i.size }
+func (i *memInfo) Mode() fs.FileMode  { return i.mode }
+func (i *memInfo) ModTime() time.Time { return time.Time{} }
+func (i *memInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i *memInfo) Sys() any           { return nil }
+
+type memOpenFile struct {
+	*bytes.Reader
+	info *memInfo
+}
+
+func (f *memOpenFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *memOpenFile) Close() error               { return nil }
+
+type memOpenDir struct {
+	info    *memInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *memOpenDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *memOpenDir) Close() error               { return nil }
+
+func (d *memOpenDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: fs.ErrInvalid}
+}
+
+// ReadDir implements fs.ReadDirFile
+func (d *memOpenDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	remaining := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return remaining, nil
+	}
+	if len(remaining) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(remaining) {
+		n = len(remaining)
+	}
+	d.offset += n
+	return remaining[:n], nil
+}
@@ -0,0 +1,108 @@
+// Package output abstracts where generated project files go: a directory on
+// disk, memory, a tar or zip stream, or a recorder for dry runs. Backends
+// take slash-separated paths relative to the project root.
+package output
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Backend receives the files of a generated project
+type Backend interface {
+	// WriteFile stores a file at name, a slash-separated path relative to
+	// the project root, creating parent directories as needed
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+}
+
+// Reader is implemented by backends that can read files back, so new files
+// can be combined with files already present
+type Reader interface {
+	// ReadFile returns the content of the file at name, or an error
+	// matching fs.ErrNotExist if there is none
+	ReadFile(name string) ([]byte, error)
+}
+
+// checkPath rejects paths that are absolute or escape the project root
+func checkPath(name string) error {
+	if !fs.ValidPath(name) || name == "." {
+		return fmt.Errorf("invalid output path %q (must be relative and stay inside the project)", name)
+	}
+	return nil
+}
+
+// Dir writes files below a directory on disk
+type Dir struct {
+	root string
+}
+
+// NewDir returns a backend writing below root
+func NewDir(root string) *Dir {
+	return &Dir{root: root}
+}
+
+// Root returns the directory files are written to
+func (d *Dir) Root() string {
+	return d.root
+}
+
+// WriteFile writes a file below the root directory
+func (d *Dir) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	if err := checkPath(name); err != nil {
+		return err
+	}
+
+	path := filepath.Join(d.root, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, perm)
+}
+
+// ReadFile reads a file below the root directory
+func (d *Dir) ReadFile(name string) ([]byte, error) {
+	if err := checkPath(name); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(filepath.Join(d.root, filepath.FromSlash(name)))
+}
+
+// FS returns the written tree as a file system
+func (d *Dir) FS() fs.FS {
+	return os.DirFS(d.root)
+}
+
+// Entry describes a file written to a Recorder
+type Entry struct {
+	Path string
+	Size int64
+	Mode fs.FileMode
+}
+
+// Recorder remembers what would be written without storing content, for
+// dry runs and manifests
+type Recorder struct {
+	entries []Entry
+}
+
+// NewRecorder returns an empty recorder
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// WriteFile records the path, size and mode of a file
+func (r *Recorder) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	if err := checkPath(name); err != nil {
+		return err
+	}
+
+	r.entries = append(r.entries, Entry{Path: name, Size: int64(len(data)), Mode: perm})
+	return nil
+}
+
+// Entries returns the recorded files in the order they were written
+func (r *Recorder) Entries() []Entry {
+	return r.entries
+}
@@ -0,0 +1,204 @@
+package output
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+var testFiles = []struct {
+	name string
+	data string
+	perm fs.FileMode
+}{
+	{name: "README.md", data: "# demo\n", perm: 0644},
+	{name: "src/app/main.py", data: "print('hi')\n", perm: 0644},
+	{name: "scripts/run.sh", data: "#!/bin/sh\n", perm: 0755},
+}
+
+func writeTestFiles(t *testing.T, b Backend) {
+	t.Helper()
+	for _, f := range testFiles {
+		if err := b.WriteFile(f.name, []byte(f.data), f.perm); err != nil {
+			t.Fatalf("WriteFile(%q) unexpected error: %v", f.name, err)
+		}
+	}
+}
+
+func wantFiles() map[string][]byte {
+	files := make(map[string][]byte)
+	for _, f := range testFiles {
+		files[f.name] = []byte(f.data)
+	}
+	return files
+}
+
+// readFS returns every regular file of fsys keyed by path
+func readFS(t *testing.T, fsys fs.FS) map[string][]byte {
+	t.Helper()
+	files := make(map[string][]byte)
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		files[name], err = fs.ReadFile(fsys, name)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("walking output: %v", err)
+	}
+	return files
+}
+
+func TestBackends(t *testing.T) {
+	tests := []struct {
+		name    string
+		backend func(t *testing.T) (Backend, func() map[string][]byte)
+	}{
+		{
+			name: "dir",
+			backend: func(t *testing.T) (Backend, func() map[string][]byte) {
+				d := NewDir(t.TempDir())
+				return d, func() map[string][]byte { return readFS(t, d.FS()) }
+			},
+		},
+		{
+			name: "memory",
+			backend: func(t *testing.T) (Backend, func() map[string][]byte) {
+				m := NewMemory()
+				return m, func() map[string][]byte { return readFS(t, m) }
+			},
+		},
+		{
+			name: "tar.gz",
+			backend: func(t *testing.T) (Backend, func() map[string][]byte) {
+				var buf bytes.Buffer
+				archive := NewTarGz(&buf, "demo")
+				return archive, func() map[string][]byte {
+					if err := archive.Close(); err != nil {
+						t.Fatalf("Close() unexpected error: %v", err)
+					}
+					return readTarGz(t, &buf)
+				}
+			},
+		},
+		{
+			name: "zip",
+			backend: func(t *testing.T) (Backend, func() map[string][]byte) {
+				var buf bytes.Buffer
+				archive := NewZip(&buf, "demo")
+				return archive, func() map[string][]byte {
+					if err := archive.Close(); err != nil {
+						t.Fatalf("Close() unexpected error: %v", err)
+					}
+					return readZip(t, buf.Bytes())
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend, read := tt.backend(t)
+			writeTestFiles(t, backend)
+
+			for _, name := range []string{"../escape.txt", "/etc/passwd", ".", ""} {
+				if err := backend.WriteFile(name, nil, 0644); err == nil {
+					t.Errorf("WriteFile(%q) expected error", name)
+				}
+			}
+
+			if reader, ok := backend.(Reader); ok {
+				if data, err := reader.ReadFile("src/app/main.py"); err != nil || string(data) != "print('hi')\n" {
+					t.Errorf("ReadFile() = %q, %v", data, err)
+				}
+				if _, err := reader.ReadFile("missing.txt"); !errors.Is(err, fs.ErrNotExist) {
+					t.Errorf("ReadFile() of a missing file error = %v, want fs.ErrNotExist", err)
+				}
+			}
+
+			if got := read(); !reflect.DeepEqual(got, wantFiles()) {
+				t.Errorf("written files = %q, want %q", got, wantFiles())
+			}
+		})
+	}
+}
+
+func readTarGz(t *testing.T, r io.Reader) map[string][]byte {
+	t.Helper()
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string][]byte)
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		name, ok := bytes.CutPrefix([]byte(header.Name), []byte("demo/"))
+		if !ok {
+			t.Errorf("entry %q is not below the prefix", header.Name)
+		}
+		files[string(name)] = data
+	}
+}
+
+func readZip(t *testing.T, data []byte) map[string][]byte {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub, err := fs.Sub(zr, "demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return readFS(t, sub)
+}
+
+func TestMemoryFS(t *testing.T) {
+	m := NewMemory()
+	writeTestFiles(t, m)
+
+	if err := fstest.TestFS(m, "README.md", "src/app/main.py", "scripts/run.sh"); err != nil {
+		t.Error(err)
+	}
+
+	info, err := fs.Stat(m, "scripts/run.sh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode() != 0755 {
+		t.Errorf("Mode() = %v, want 0755", info.Mode())
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	r := NewRecorder()
+	writeTestFiles(t, r)
+
+	want := []Entry{
+		{Path: "README.md", Size: 7, Mode: 0644},
+		{Path: "src/app/main.py", Size: 12, Mode: 0644},
+		{Path: "scripts/run.sh", Size: 10, Mode: 0755},
+	}
+	if got := r.Entries(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Entries() = %+v, want %+v", got, want)
+	}
+}
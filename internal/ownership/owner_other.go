@@ -0,0 +1,8 @@
+//go:build !unix
+
+package ownership
+
+// ownerOf is not implemented on this platform
+func ownerOf(path string) (*Owner, error) {
+	return nil, errUnsupported
+}
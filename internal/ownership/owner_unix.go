@@ -0,0 +1,22 @@
+//go:build unix
+
+package ownership
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// ownerOf returns the owner of path
+func ownerOf(path string) (*Owner, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil, errors.New("no owner information")
+	}
+	return &Owner{UID: int(stat.Uid), GID: int(stat.Gid)}, nil
+}
@@ -0,0 +1,175 @@
+// Package ownership gives generated projects to the user meant to edit them
+// when devinit runs as root: in a container, such as its official image,
+// the owner of the mounted volume they are written to; under sudo, the user
+// who ran sudo.
+package ownership
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// EnvOwner is the environment variable of --chown; the official image sets
+// it to auto
+const EnvOwner = "DEVINIT_OWNER"
+
+// Auto selects the owner of the directory generated files are written to,
+// e.g. the mounted volume
+const Auto = "auto"
+
+// Environment variables sudo sets to the user who ran it
+const (
+	EnvSudoUID = "SUDO_UID"
+	EnvSudoGID = "SUDO_GID"
+)
+
+// errUnsupported is returned where file ownership is not available
+var errUnsupported = errors.New("file ownership is not supported on this platform")
+
+// Owner is the user and group generated files are given
+type Owner struct {
+	UID int
+	GID int
+}
+
+// String returns uid:gid
+func (o Owner) String() string {
+	return fmt.Sprintf("%d:%d", o.UID, o.GID)
+}
+
+// Parse parses an owner: user, user:group (names or ids), or auto for the
+// owner of dir or of its closest parent that exists. A user without a group
+// comes with its primary group, or the group of the same id for ids unknown
+// to the system.
+//
+// An empty spec is the user who ran sudo, when devinit runs as root under
+// sudo, else nil. Auto is nil as well when the owner is already the current
+// user or devinit runs unprivileged and could not change it anyway.
+func Parse(spec, dir string) (*Owner, error) {
+	switch spec {
+	case "":
+		return Sudo(), nil
+	case Auto:
+		owner, err := ownerOf(existingParent(dir))
+		if err != nil {
+			return nil, fmt.Errorf("failed to determine the owner of %s: %w", dir, err)
+		}
+		if os.Geteuid() != 0 || owner.UID == os.Geteuid() {
+			return nil, nil
+		}
+		return owner, nil
+	}
+
+	name, group, hasGroup := strings.Cut(spec, ":")
+	if name == "" || (hasGroup && group == "") {
+		return nil, fmt.Errorf("invalid owner %q: must be user, user:group or %s", spec, Auto)
+	}
+	owner, err := lookupUser(name)
+	if err != nil {
+		return nil, err
+	}
+	if hasGroup {
+		if owner.GID, err = lookupGroup(group); err != nil {
+			return nil, err
+		}
+	}
+	return owner, nil
+}
+
+// Sudo returns the user who ran sudo, from SUDO_UID and SUDO_GID, when
+// devinit runs as root; nil otherwise
+func Sudo() *Owner {
+	if os.Geteuid() != 0 {
+		return nil
+	}
+	uid, err := strconv.Atoi(os.Getenv(EnvSudoUID))
+	if err != nil || uid <= 0 {
+		return nil
+	}
+	gid, err := strconv.Atoi(os.Getenv(EnvSudoGID))
+	if err != nil || gid < 0 {
+		gid = uid
+	}
+	return &Owner{UID: uid, GID: gid}
+}
+
+// lookupUser returns the user named or numbered name, with its primary group
+func lookupUser(name string) (*Owner, error) {
+	if uid, err := strconv.Atoi(name); err == nil {
+		if uid < 0 {
+			return nil, fmt.Errorf("invalid user id %d", uid)
+		}
+		owner := &Owner{UID: uid, GID: uid}
+		if u, err := user.LookupId(name); err == nil {
+			owner.GID, _ = strconv.Atoi(u.Gid)
+		}
+		return owner, nil
+	}
+
+	u, err := user.Lookup(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown user %q", name)
+	}
+	uid, err := strconv.Atoi(u.Uid)
+	if err != nil {
+		return nil, fmt.Errorf("user %q has no numeric id", name)
+	}
+	gid, _ := strconv.Atoi(u.Gid)
+	return &Owner{UID: uid, GID: gid}, nil
+}
+
+// lookupGroup returns the id of the group named or numbered name
+func lookupGroup(name string) (int, error) {
+	if gid, err := strconv.Atoi(name); err == nil {
+		if gid < 0 {
+			return 0, fmt.Errorf("invalid group id %d", gid)
+		}
+		return gid, nil
+	}
+	g, err := user.LookupGroup(name)
+	if err != nil {
+		return 0, fmt.Errorf("unknown group %q", name)
+	}
+	gid, err := strconv.Atoi(g.Gid)
+	if err != nil {
+		return 0, fmt.Errorf("group %q has no numeric id", name)
+	}
+	return gid, nil
+}
+
+// Apply gives dir and everything below it to the owner. Symbolic links
+// themselves are changed, not what they point to.
+func (o Owner) Apply(dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := os.Lchown(path, o.UID, o.GID); err != nil {
+			return fmt.Errorf("failed to change the owner of %s: %w", path, err)
+		}
+		return nil
+	})
+}
+
+// existingParent returns dir, or its closest parent that exists
+func existingParent(dir string) string {
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,101 @@
+package ownership
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("users and groups are looked up the Unix way")
+	}
+	t.Setenv(EnvSudoUID, "")
+
+	tests := []struct {
+		spec    string
+		want    *Owner
+		wantErr bool
+	}{
+		{spec: "", want: nil},
+		{spec: "54321", want: &Owner{UID: 54321, GID: 54321}},
+		{spec: "54321:100", want: &Owner{UID: 54321, GID: 100}},
+		{spec: "0:0", want: &Owner{}},
+		{spec: "root", want: &Owner{}},
+		{spec: "root:0", want: &Owner{}},
+		{spec: "no-such-user-devinit", wantErr: true},
+		{spec: "54321:no-such-group-devinit", wantErr: true},
+		{spec: "54321:", wantErr: true},
+		{spec: ":100", wantErr: true},
+		{spec: "-1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := Parse(tt.spec, t.TempDir())
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Parse(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestSudo(t *testing.T) {
+	t.Setenv(EnvSudoUID, "1000")
+	t.Setenv(EnvSudoGID, "1001")
+
+	// Only root changes owners; sudo -E keeps SUDO_UID for other users too
+	var want *Owner
+	if os.Geteuid() == 0 {
+		want = &Owner{UID: 1000, GID: 1001}
+	}
+	if got, err := Parse("", t.TempDir()); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse(\"\") under sudo = %v, %v, want %v", got, err, want)
+	}
+}
+
+func TestAuto(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file ownership is not supported on Windows")
+	}
+
+	// The volume is owned by the current user, so there is nothing to change
+	dir := t.TempDir()
+	got, err := Parse(Auto, filepath.Join(dir, "api", "src"))
+	if err != nil || got != nil {
+		t.Errorf("Parse(auto) = %v, %v, want no owner to apply", got, err)
+	}
+}
+
+func TestApply(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file ownership is not supported on Windows")
+	}
+
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "src"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "src", "main.go"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("missing", filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Giving files to their own owner needs no privileges; dangling links
+	// are changed themselves
+	owner := Owner{UID: os.Getuid(), GID: os.Getgid()}
+	if err := owner.Apply(dir); err != nil {
+		t.Errorf("Apply() unexpected error: %v", err)
+	}
+	for _, name := range []string{".", "src", "src/main.go"} {
+		if got, err := ownerOf(filepath.Join(dir, name)); err != nil || *got != owner {
+			t.Errorf("%s owned by %v, %v, want %v", name, got, err, owner)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+// Package paths locates the files devinit keeps outside projects, following
+// the XDG base directory specification:
+//
+//   - config ($XDG_CONFIG_HOME/devinit): config.yaml
+//   - cache ($XDG_CACHE_HOME/devinit): rendered files, probed tool versions
+//     and clones of remote templates, all safe to delete
+//   - data ($XDG_DATA_HOME/devinit): user-installed templates
+//   - state ($XDG_STATE_HOME/devinit): the history of generated projects
+//
+// Unset or relative XDG variables fall back to the platform defaults:
+// ~/.config, ~/.cache, ~/.local/share and ~/.local/state on Unix, and the
+// user config and cache directories of macOS and Windows elsewhere.
+//
+// DEVINIT_CACHE_DIR and DEVINIT_STATE_DIR name the cache and state
+// directories themselves. Without them, a cache or state directory that
+// cannot be written, as in containers with a read-only file system, falls
+// back to devinit-<uid>/cache or devinit-<uid>/state in the temporary
+// directory (a tmpfs such containers usually mount at /tmp).
+package paths
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// App is the directory devinit uses inside each base directory
+const App = "devinit"
+
+// Environment variables of the XDG base directories
+const (
+	EnvConfigHome = "XDG_CONFIG_HOME"
+	EnvCacheHome  = "XDG_CACHE_HOME"
+	EnvDataHome   = "XDG_DATA_HOME"
+	EnvStateHome  = "XDG_STATE_HOME"
+)
+
+// Environment variables naming the cache and state directories, used as
+// they are
+const (
+	EnvCacheDir = "DEVINIT_CACHE_DIR"
+	EnvStateDir = "DEVINIT_STATE_DIR"
+)
+
+// ConfigDir returns the directory of the config file
+func ConfigDir() (string, error) {
+	return dir(EnvConfigHome, os.UserConfigDir, "config")
+}
+
+// CacheDir returns the directory of the caches
+func CacheDir() (string, error) {
+	return writableDir(EnvCacheDir, EnvCacheHome, os.UserCacheDir, "cache")
+}
+
+// DataDir returns the directory of user-installed templates
+func DataDir() (string, error) {
+	return dir(EnvDataHome, unixDefault(".local", "share"), "data")
+}
+
+// StateDir returns the directory of the generation history
+func StateDir() (string, error) {
+	return writableDir(EnvStateDir, EnvStateHome, unixDefault(".local", "state"), "state")
+}
+
+// dir returns the devinit directory of a base directory: the absolute path
+// in env, or the fallback
+func dir(env string, fallback func() (string, error), kind string) (string, error) {
+	if base := os.Getenv(env); filepath.IsAbs(base) {
+		return filepath.Join(base, App), nil
+	}
+	base, err := fallback()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine %s directory: %w", kind, err)
+	}
+	return filepath.Join(base, App), nil
+}
+
+// writableDir returns the directory in override, else the devinit
+// directory of a base directory, or TempDir when that cannot be written
+func writableDir(override, env string, fallback func() (string, error), kind string) (string, error) {
+	if dir := os.Getenv(override); filepath.IsAbs(dir) {
+		return dir, nil
+	}
+	dir, err := dir(env, fallback, kind)
+	if err != nil || !writable(dir) {
+		return TempDir(kind), nil
+	}
+	return dir, nil
+}
+
+// TempDir returns the directory of the given kind (cache or state) under the
+// temporary directory, which directories that cannot be written fall back to
+func TempDir(kind string) string {
+	user := App
+	if uid := os.Getuid(); uid >= 0 {
+		user = fmt.Sprintf("%s-%d", App, uid)
+	}
+	return filepath.Join(os.TempDir(), user, kind)
+}
+
+// writable is defaultWritable, replaced in tests
+var writable = defaultWritable
+
+// defaultWritable reports whether dir can be created and written: it tries
+// to create a file in dir, or in the closest parent that exists
+func defaultWritable(dir string) bool {
+	for {
+		info, err := os.Stat(dir)
+		if err == nil {
+			if !info.IsDir() {
+				return false
+			}
+			f, err := os.CreateTemp(dir, ".devinit-")
+			if err != nil {
+				return false
+			}
+			f.Close()
+			os.Remove(f.Name())
+			return true
+		}
+		parent := filepath.Dir(dir)
+		if !os.IsNotExist(err) || parent == dir {
+			return false
+		}
+		dir = parent
+	}
+}
+
+// unixDefault returns the fallback of the data and state directories:
+// ~/<elem...> on Unix, the user config directory on macOS and Windows,
+// which have no separate place for them
+func unixDefault(elem ...string) func() (string, error) {
+	return func() (string, error) {
+		if runtime.GOOS == "darwin" || runtime.GOOS == "windows" {
+			return os.UserConfigDir()
+		}
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(append([]string{home}, elem...)...), nil
+	}
+}
+
+// Migrated returns path, unless only legacy exists: files written before
+// devinit followed the XDG layout stay where they are
+func Migrated(path, legacy string) string {
+	if _, err := os.Stat(path); err == nil {
+		return path
+	}
+	if _, err := os.Stat(legacy); err == nil {
+		return legacy
+	}
+	return path
+}
+
+// Size returns the total size in bytes and the number of files below path,
+// which may be a file. A missing path has size 0.
+func Size(path string) (size int64, files int, err error) {
+	err = filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+			files++
+		}
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return 0, 0, nil
+	}
+	return size, files, err
+}
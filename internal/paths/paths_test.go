@@ -0,0 +1,163 @@
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestDirs(t *testing.T) {
+	if runtime.GOOS == "darwin" || runtime.GOOS == "windows" {
+		t.Skip("the fallbacks tested are the Unix ones")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(EnvCacheDir, "")
+	t.Setenv(EnvStateDir, "")
+	writable = func(string) bool { return true }
+	defer func() { writable = defaultWritable }()
+
+	tests := []struct {
+		name string
+		env  string
+		dir  func() (string, error)
+		set  string
+		want string
+	}{
+		{name: "config from XDG", env: EnvConfigHome, dir: ConfigDir, set: "/xdg/config", want: "/xdg/config/devinit"},
+		{name: "cache from XDG", env: EnvCacheHome, dir: CacheDir, set: "/xdg/cache", want: "/xdg/cache/devinit"},
+		{name: "data from XDG", env: EnvDataHome, dir: DataDir, set: "/xdg/data", want: "/xdg/data/devinit"},
+		{name: "state from XDG", env: EnvStateHome, dir: StateDir, set: "/xdg/state", want: "/xdg/state/devinit"},
+		{name: "config default", env: EnvConfigHome, dir: ConfigDir, want: filepath.Join(home, ".config", "devinit")},
+		{name: "cache default", env: EnvCacheHome, dir: CacheDir, want: filepath.Join(home, ".cache", "devinit")},
+		{name: "data default", env: EnvDataHome, dir: DataDir, want: filepath.Join(home, ".local", "share", "devinit")},
+		{name: "state default", env: EnvStateHome, dir: StateDir, want: filepath.Join(home, ".local", "state", "devinit")},
+		{name: "relative path ignored", env: EnvStateHome, dir: StateDir, set: "state", want: filepath.Join(home, ".local", "state", "devinit")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, env := range []string{EnvConfigHome, EnvCacheHome, EnvDataHome, EnvStateHome} {
+				t.Setenv(env, "")
+			}
+			t.Setenv(tt.env, tt.set)
+
+			got, err := tt.dir()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadOnlyDirs(t *testing.T) {
+	base := t.TempDir()
+	t.Setenv(EnvCacheHome, filepath.Join(base, "cache"))
+	t.Setenv(EnvStateHome, filepath.Join(base, "state"))
+	t.Setenv(EnvCacheDir, "")
+	t.Setenv(EnvStateDir, "")
+
+	// Writable directories need not exist yet
+	for name, dir := range map[string]func() (string, error){"cache": CacheDir, "state": StateDir} {
+		got, err := dir()
+		if err != nil || got != filepath.Join(base, name, App) {
+			t.Errorf("%s dir = %s, %v, want %s", name, got, err, filepath.Join(base, name, App))
+		}
+	}
+
+	// Read-only ones fall back to the temporary directory
+	writable = func(string) bool { return false }
+	defer func() { writable = defaultWritable }()
+	for name, dir := range map[string]func() (string, error){"cache": CacheDir, "state": StateDir} {
+		got, err := dir()
+		if err != nil || got != TempDir(name) {
+			t.Errorf("read-only %s dir = %s, %v, want %s", name, got, err, TempDir(name))
+		}
+	}
+
+	// Configured directories are used as they are
+	t.Setenv(EnvCacheDir, "/volume/cache")
+	t.Setenv(EnvStateDir, "/volume/state")
+	if got, _ := CacheDir(); got != "/volume/cache" {
+		t.Errorf("CacheDir() = %s, want %s from %s", got, "/volume/cache", EnvCacheDir)
+	}
+	if got, _ := StateDir(); got != "/volume/state" {
+		t.Errorf("StateDir() = %s, want %s from %s", got, "/volume/state", EnvStateDir)
+	}
+}
+
+func TestWritable(t *testing.T) {
+	dir := t.TempDir()
+	if !defaultWritable(filepath.Join(dir, "missing", "devinit")) {
+		t.Error("missing directory below a writable one reported read-only")
+	}
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if defaultWritable(filepath.Join(file, "devinit")) {
+		t.Error("directory below a file reported writable")
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("writability probe left files behind: %v", entries)
+	}
+}
+
+func TestMigrated(t *testing.T) {
+	dir := t.TempDir()
+	path, legacy := filepath.Join(dir, "new.log"), filepath.Join(dir, "old.log")
+
+	if got := Migrated(path, legacy); got != path {
+		t.Errorf("neither exists: got %s, want %s", got, path)
+	}
+	if err := os.WriteFile(legacy, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := Migrated(path, legacy); got != legacy {
+		t.Errorf("only legacy exists: got %s, want %s", got, legacy)
+	}
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := Migrated(path, legacy); got != path {
+		t.Errorf("both exist: got %s, want %s", got, path)
+	}
+}
+
+func TestSize(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{"a.txt": "abc", "sub/b.txt": "de"} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		path      string
+		wantSize  int64
+		wantFiles int
+	}{
+		{name: "directory", path: dir, wantSize: 5, wantFiles: 2},
+		{name: "file", path: filepath.Join(dir, "a.txt"), wantSize: 3, wantFiles: 1},
+		{name: "missing", path: filepath.Join(dir, "missing")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			size, files, err := Size(tt.path)
+			if err != nil || size != tt.wantSize || files != tt.wantFiles {
+				t.Errorf("Size() = %d, %d, %v, want %d, %d", size, files, err, tt.wantSize, tt.wantFiles)
+			}
+		})
+	}
+}
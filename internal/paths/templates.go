@@ -0,0 +1,161 @@
+package paths
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ProjectTemplates is the templates directory a project can keep for
+// itself, layered over the resolved one
+const ProjectTemplates = ".devinit/templates"
+
+// TemplatesSource says where the templates directory was found
+type TemplatesSource string
+
+// Sources of the templates directory, in the order they are tried
+const (
+	TemplatesExplicit TemplatesSource = "explicit" // --templates-dir or DEVINIT_TEMPLATES_DIR
+	TemplatesUser     TemplatesSource = "user"     // $XDG_DATA_HOME/devinit/templates
+	TemplatesEmbedded TemplatesSource = "embedded" // shipped in the binary
+)
+
+// TemplatesDir resolves the templates directory: explicit when given, else
+// the user store when it exists. It returns TemplatesEmbedded with an empty
+// directory otherwise; the caller extracts the embedded templates.
+func TemplatesDir(explicit string) (string, TemplatesSource, error) {
+	if explicit != "" {
+		return explicit, TemplatesExplicit, nil
+	}
+
+	data, err := DataDir()
+	if err != nil {
+		return "", "", err
+	}
+	if user := filepath.Join(data, "templates"); isDir(user) {
+		return user, TemplatesUser, nil
+	}
+
+	return "", TemplatesEmbedded, nil
+}
+
+// ProjectTemplatesDir returns the closest ProjectTemplates of dir or its
+// parents, or "" when there is none
+func ProjectTemplatesDir(dir string) string {
+	if dir == "" {
+		return ""
+	}
+	for dir = filepath.Clean(dir); ; dir = filepath.Dir(dir) {
+		candidate := filepath.Join(dir, filepath.FromSlash(ProjectTemplates))
+		if isDir(candidate) {
+			return candidate
+		}
+		if filepath.Dir(dir) == dir {
+			return ""
+		}
+	}
+}
+
+// UserTemplatesDir returns the directory of user-installed templates, where
+// imported templates go when there is no other writable store
+func UserTemplatesDir() (string, error) {
+	data, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(data, "templates"), nil
+}
+
+// ExtractTemplates writes the directories of fsys below root/<hash of their
+// content> and returns that directory. Extracted content is reused, so only
+// the first run of a build pays for it. Go files at the top of fsys, which
+// embed it, are skipped.
+func ExtractTemplates(fsys fs.FS, root string) (string, error) {
+	hash, err := hashTree(fsys)
+	if err != nil {
+		return "", fmt.Errorf("failed to read embedded templates: %w", err)
+	}
+	dir := filepath.Join(root, hash)
+	if isDir(dir) {
+		return dir, nil
+	}
+
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return "", fmt.Errorf("failed to extract embedded templates: %w", err)
+	}
+	tmp, err := os.MkdirTemp(root, ".tmp-")
+	if err != nil {
+		return "", fmt.Errorf("failed to extract embedded templates: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	err = walkTree(fsys, func(name string, d fs.DirEntry) error {
+		target := filepath.Join(tmp, filepath.FromSlash(name))
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, data, 0644)
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to extract embedded templates: %w", err)
+	}
+
+	// Another devinit may have extracted the same templates meanwhile
+	if err := os.Rename(tmp, dir); err != nil && !isDir(dir) {
+		return "", fmt.Errorf("failed to extract embedded templates: %w", err)
+	}
+	return dir, nil
+}
+
+// hashTree returns a short hash of the names and content of the templates
+// in fsys
+func hashTree(fsys fs.FS) (string, error) {
+	h := sha256.New()
+	err := walkTree(fsys, func(name string, d fs.DirEntry) error {
+		fmt.Fprintf(h, "%s\x00", name)
+		if d.IsDir() {
+			return nil
+		}
+		f, err := fsys.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(h, f)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16], nil
+}
+
+// walkTree calls fn for everything in fsys but the Go files at its top, in
+// lexical order
+func walkTree(fsys fs.FS, fn func(name string, d fs.DirEntry) error) error {
+	return fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name == "." || (!d.IsDir() && !strings.Contains(name, "/") && path.Ext(name) == ".go") {
+			return nil
+		}
+		return fn(path.Clean(name), d)
+	})
+}
+
+// isDir reports whether dir is an existing directory
+func isDir(dir string) bool {
+	info, err := os.Stat(dir)
+	return err == nil && info.IsDir()
+}
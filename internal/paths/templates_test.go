@@ -0,0 +1,123 @@
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestTemplatesDir(t *testing.T) {
+	data := t.TempDir()
+	user := filepath.Join(data, App, "templates")
+
+	tests := []struct {
+		name       string
+		explicit   string
+		userStore  bool
+		wantDir    string
+		wantSource TemplatesSource
+	}{
+		{name: "explicit wins", explicit: "/opt/templates", userStore: true, wantDir: "/opt/templates", wantSource: TemplatesExplicit},
+		{name: "user store", userStore: true, wantDir: user, wantSource: TemplatesUser},
+		{name: "embedded without a user store", wantSource: TemplatesEmbedded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(EnvDataHome, data)
+			os.RemoveAll(user)
+			if tt.userStore {
+				if err := os.MkdirAll(user, 0755); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			dir, source, err := TemplatesDir(tt.explicit)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if dir != tt.wantDir || source != tt.wantSource {
+				t.Errorf("got %q (%s), want %q (%s)", dir, source, tt.wantDir, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestProjectTemplatesDir(t *testing.T) {
+	root := t.TempDir()
+	project := filepath.Join(root, "repo")
+	projectTemplates := filepath.Join(project, filepath.FromSlash(ProjectTemplates))
+	sub := filepath.Join(project, "services", "api")
+	for _, dir := range []string{projectTemplates, sub, filepath.Join(root, "elsewhere")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		dir  string
+		want string
+	}{
+		{name: "project root", dir: project, want: projectTemplates},
+		{name: "subdirectory", dir: sub, want: projectTemplates},
+		{name: "outside projects", dir: filepath.Join(root, "elsewhere")},
+		{name: "no directory"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ProjectTemplatesDir(tt.dir); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractTemplates(t *testing.T) {
+	fsys := fstest.MapFS{
+		"templates.go":                                {Data: []byte("package templates")},
+		"python/fastapi/template.yaml":                {Data: []byte("name: fastapi")},
+		"python/fastapi/files/.gitignore":             {Data: []byte("*.pyc")},
+		"python/fastapi/files/app/main.py.tmpl":       {Data: []byte("app = {{ .ProjectName }}")},
+		"python/fastapi/versions/1.0.0/template.yaml": {Data: []byte("name: fastapi")},
+	}
+	root := t.TempDir()
+
+	dir, err := ExtractTemplates(fsys, root)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if filepath.Dir(dir) != root {
+		t.Errorf("extracted to %s, want a directory of %s", dir, root)
+	}
+	for name, file := range fsys {
+		got, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if name == "templates.go" {
+			if err == nil {
+				t.Errorf("%s extracted, want it skipped", name)
+			}
+			continue
+		}
+		if err != nil || string(got) != string(file.Data) {
+			t.Errorf("%s = %q, %v, want %q", name, got, err, file.Data)
+		}
+	}
+
+	again, err := ExtractTemplates(fsys, root)
+	if err != nil || again != dir {
+		t.Errorf("second extraction = %s, %v, want %s reused", again, err, dir)
+	}
+
+	fsys["python/fastapi/template.yaml"] = &fstest.MapFile{Data: []byte("name: fastapi\nversion: 2.0.0")}
+	changed, err := ExtractTemplates(fsys, root)
+	if err != nil || changed == dir {
+		t.Errorf("extraction of changed templates = %s, %v, want a new directory", changed, err)
+	}
+
+	entries, err := os.ReadDir(root)
+	if err != nil || len(entries) != 2 {
+		t.Errorf("%s has %d entries, %v, want the 2 extractions only", root, len(entries), err)
+	}
+}
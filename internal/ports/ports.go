@@ -0,0 +1,34 @@
+// Package ports picks TCP ports for generated projects that do not collide
+// with running services or previously generated projects.
+package ports
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// searchRange is how many ports above the preferred one Allocate tries
+const searchRange = 100
+
+// Available reports whether the TCP port can be bound on this machine
+func Available(port int) bool {
+	l, err := net.Listen("tcp", net.JoinHostPort("", strconv.Itoa(port)))
+	if err != nil {
+		return false
+	}
+	l.Close()
+	return true
+}
+
+// Allocate returns the first port, starting at preferred, that is neither
+// reserved nor reported unavailable by available
+func Allocate(preferred int, reserved map[int]bool, available func(int) bool) (int, error) {
+	for port := preferred; port < preferred+searchRange && port <= 65535; port++ {
+		if reserved[port] || !available(port) {
+			continue
+		}
+		return port, nil
+	}
+	return 0, fmt.Errorf("no free port in %d-%d", preferred, preferred+searchRange-1)
+}
@@ -0,0 +1,48 @@
+package ports
+
+import (
+	"net"
+	"testing"
+)
+
+func TestAllocate(t *testing.T) {
+	busy := map[int]bool{8001: true}
+	available := func(port int) bool { return !busy[port] }
+
+	tests := []struct {
+		name      string
+		preferred int
+		reserved  map[int]bool
+		want      int
+		wantErr   bool
+	}{
+		{name: "preferred is free", preferred: 8000, want: 8000},
+		{name: "skips reserved", preferred: 8000, reserved: map[int]bool{8000: true}, want: 8002},
+		{name: "skips busy", preferred: 8001, want: 8002},
+		{name: "upper bound", preferred: 65535, reserved: map[int]bool{65535: true}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Allocate(tt.preferred, tt.reserved, available)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Allocate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Allocate() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAvailable(t *testing.T) {
+	l, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Skipf("cannot listen: %v", err)
+	}
+	defer l.Close()
+
+	if Available(l.Addr().(*net.TCPAddr).Port) {
+		t.Error("Available() = true for a bound port")
+	}
+}
@@ -0,0 +1,138 @@
+package proto
+
+import (
+	"fmt"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokenIdent tokenKind = iota
+	tokenNumber
+	tokenString
+	tokenSymbol
+	tokenEOF
+)
+
+// token is a word of a proto file. Comment holds the comment lines right
+// above it, which document the declaration it starts.
+type token struct {
+	kind    tokenKind
+	text    string
+	line    int
+	comment string
+}
+
+// tokenize splits a proto file into tokens. Identifiers keep their dots
+// (google.protobuf.Empty, .pets.v1.Pet) and strings are unquoted.
+func tokenize(src string) ([]token, error) {
+	var (
+		tokens  []token
+		comment []string
+		// commentEnd is the line the pending comment ends on; a blank line
+		// or a token in between detaches it
+		commentEnd int
+		line       = 1
+		lastLine   int
+	)
+
+	emit := func(kind tokenKind, text string) {
+		tok := token{kind: kind, text: text, line: line}
+		if len(comment) > 0 && commentEnd >= line-1 {
+			tok.comment = strings.Join(comment, "\n")
+		}
+		comment = nil
+		tokens = append(tokens, tok)
+		lastLine = line
+	}
+	addComment := func(text string, start, end int) {
+		// A comment after a token on the same line describes that token
+		if start == lastLine {
+			return
+		}
+		if len(comment) > 0 && commentEnd < start-1 {
+			comment = nil
+		}
+		for _, l := range strings.Split(text, "\n") {
+			l = strings.TrimSpace(l)
+			l = strings.TrimSpace(strings.TrimPrefix(l, "*"))
+			if l != "" {
+				comment = append(comment, l)
+			}
+		}
+		commentEnd = end
+	}
+
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case strings.HasPrefix(src[i:], "//"):
+			end := strings.IndexByte(src[i:], '\n')
+			if end < 0 {
+				end = len(src) - i
+			}
+			addComment(strings.TrimPrefix(src[i:i+end], "//"), line, line)
+			i += end
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated comment", line)
+			}
+			text := src[i+2 : i+2+end]
+			start := line
+			line += strings.Count(text, "\n")
+			addComment(text, start, line)
+			i += end + 4
+		case c == '"' || c == '\'':
+			j := i + 1
+			for j < len(src) && src[j] != c {
+				if src[j] == '\\' {
+					j++
+				}
+				if j < len(src) && src[j] == '\n' {
+					return nil, fmt.Errorf("line %d: unterminated string", line)
+				}
+				j++
+			}
+			if j >= len(src) {
+				return nil, fmt.Errorf("line %d: unterminated string", line)
+			}
+			emit(tokenString, src[i+1:j])
+			i = j + 1
+		case isIdentStart(c) || c == '.' && i+1 < len(src) && isIdentStart(src[i+1]):
+			j := i + 1
+			for j < len(src) && (isIdentStart(src[j]) || isDigit(src[j]) || src[j] == '.') {
+				j++
+			}
+			emit(tokenIdent, src[i:j])
+			i = j
+		case isDigit(c):
+			j := i + 1
+			for j < len(src) && (isIdentStart(src[j]) || isDigit(src[j]) || src[j] == '.') {
+				j++
+			}
+			emit(tokenNumber, src[i:j])
+			i = j
+		default:
+			emit(tokenSymbol, string(c))
+			i++
+		}
+	}
+
+	tokens = append(tokens, token{kind: tokenEOF, line: line})
+	return tokens, nil
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
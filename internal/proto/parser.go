@@ -0,0 +1,420 @@
+package proto
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// parser reads the declarations of a proto file. Options, reservations and
+// extensions are skipped: templates only need the shape of the API.
+type parser struct {
+	tokens []token
+	pos    int
+	file   *File
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// accept consumes the next token if its text is text
+func (p *parser) accept(text string) bool {
+	if tok := p.peek(); tok.kind != tokenString && tok.text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(text string) error {
+	if !p.accept(text) {
+		return p.unexpected(fmt.Sprintf("%q", text))
+	}
+	return nil
+}
+
+func (p *parser) ident() (string, error) {
+	if p.peek().kind != tokenIdent {
+		return "", p.unexpected("a name")
+	}
+	return p.next().text, nil
+}
+
+func (p *parser) number() (int64, error) {
+	negative := p.accept("-")
+	tok := p.peek()
+	if tok.kind != tokenNumber {
+		return 0, p.unexpected("a number")
+	}
+	p.next()
+	n, err := strconv.ParseInt(tok.text, 0, 64)
+	if err != nil {
+		return 0, fmt.Errorf("line %d: invalid number %s", tok.line, tok.text)
+	}
+	if negative {
+		n = -n
+	}
+	return n, nil
+}
+
+func (p *parser) unexpected(want string) error {
+	tok := p.peek()
+	if tok.kind == tokenEOF {
+		return fmt.Errorf("line %d: expected %s, found end of file", tok.line, want)
+	}
+	return fmt.Errorf("line %d: expected %s, found %q", tok.line, want, tok.text)
+}
+
+// skipStatement skips to the end of a statement such as an option, with
+// its braced values: option (x) = { a: 1 };
+func (p *parser) skipStatement() error {
+	depth := 0
+	for {
+		tok := p.next()
+		switch {
+		case tok.kind == tokenEOF:
+			return p.unexpected(`";"`)
+		case tok.kind != tokenSymbol:
+		case tok.text == "{" || tok.text == "[" || tok.text == "(":
+			depth++
+		case tok.text == "}" || tok.text == "]" || tok.text == ")":
+			depth--
+		case tok.text == ";" && depth == 0:
+			return nil
+		}
+	}
+}
+
+// skipBlock skips a braced block such as an extend declaration or the
+// options of an rpc
+func (p *parser) skipBlock() error {
+	for p.peek().text != "{" {
+		if p.peek().kind == tokenEOF {
+			return p.unexpected(`"{"`)
+		}
+		p.next()
+	}
+	depth := 0
+	for {
+		tok := p.next()
+		switch {
+		case tok.kind == tokenEOF:
+			return p.unexpected(`"}"`)
+		case tok.kind != tokenSymbol:
+		case tok.text == "{":
+			depth++
+		case tok.text == "}":
+			if depth--; depth == 0 {
+				return nil
+			}
+		}
+	}
+}
+
+func (p *parser) parseFile() error {
+	for p.peek().kind != tokenEOF {
+		tok := p.next()
+		var err error
+		switch tok.text {
+		case "syntax":
+			err = p.parseSyntax()
+		case "edition":
+			err = fmt.Errorf("line %d: editions are not supported, use syntax = \"proto3\"", tok.line)
+		case "package":
+			if p.file.Package, err = p.ident(); err == nil {
+				err = p.expect(";")
+			}
+		case "import":
+			err = p.parseImport()
+		case "option":
+			err = p.skipStatement()
+		case "extend":
+			err = p.skipBlock()
+		case "message":
+			err = p.parseMessage("", tok.comment)
+		case "enum":
+			err = p.parseEnum("", tok.comment)
+		case "service":
+			err = p.parseService(tok.comment)
+		case ";":
+		default:
+			p.pos--
+			err = p.unexpected("a declaration")
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *parser) parseSyntax() error {
+	if err := p.expect("="); err != nil {
+		return err
+	}
+	tok := p.next()
+	if tok.kind != tokenString || tok.text != "proto2" && tok.text != "proto3" {
+		return fmt.Errorf("line %d: unsupported syntax %q", tok.line, tok.text)
+	}
+	p.file.Syntax = tok.text
+	return p.expect(";")
+}
+
+func (p *parser) parseImport() error {
+	if !p.accept("public") {
+		p.accept("weak")
+	}
+	tok := p.next()
+	if tok.kind != tokenString {
+		p.pos--
+		return p.unexpected("an import path")
+	}
+	p.file.Imports = append(p.file.Imports, tok.text)
+	return p.expect(";")
+}
+
+// parseMessage reads a message; nested messages and enums are added to the
+// file after it, named within it (Outer.Inner)
+func (p *parser) parseMessage(scope, comment string) error {
+	name, err := p.ident()
+	if err != nil {
+		return err
+	}
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+
+	index := len(p.file.Messages)
+	p.file.Messages = append(p.file.Messages, Message{})
+	message := Message{Name: scope + name, Description: comment}
+	for !p.accept("}") {
+		tok := p.peek()
+		switch tok.text {
+		case "message":
+			p.next()
+			err = p.parseMessage(message.Name+".", tok.comment)
+		case "enum":
+			p.next()
+			err = p.parseEnum(message.Name+".", tok.comment)
+		case "oneof":
+			p.next()
+			err = p.parseOneof(&message)
+		case "option", "reserved", "extensions":
+			err = p.skipStatement()
+		case "extend":
+			err = p.skipBlock()
+		case ";":
+			p.next()
+		default:
+			var field Field
+			if field, err = p.parseField(); err == nil {
+				message.Fields = append(message.Fields, field)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	p.file.Messages[index] = message
+	return nil
+}
+
+func (p *parser) parseOneof(message *Message) error {
+	name, err := p.ident()
+	if err != nil {
+		return err
+	}
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	for !p.accept("}") {
+		if p.peek().text == "option" {
+			if err := p.skipStatement(); err != nil {
+				return err
+			}
+			continue
+		}
+		field, err := p.parseField()
+		if err != nil {
+			return err
+		}
+		field.Oneof = name
+		message.Fields = append(message.Fields, field)
+	}
+	return nil
+}
+
+// parseField reads [label] type name = number [options]; or
+// map<key, value> name = number;
+func (p *parser) parseField() (Field, error) {
+	field := Field{Description: p.peek().comment}
+	switch {
+	case p.accept("repeated"):
+		field.Repeated = true
+	case p.accept("optional"):
+		field.Optional = true
+	case p.accept("required"):
+	}
+
+	var err error
+	if p.peek().text == "map" && p.tokens[p.pos+1].text == "<" {
+		p.pos += 2
+		if field.Key, err = p.ident(); err != nil {
+			return field, err
+		}
+		if err := p.expect(","); err != nil {
+			return field, err
+		}
+		if field.Type, err = p.ident(); err != nil {
+			return field, err
+		}
+		if err := p.expect(">"); err != nil {
+			return field, err
+		}
+	} else if field.Type, err = p.ident(); err != nil {
+		return field, err
+	}
+
+	if field.Name, err = p.ident(); err != nil {
+		return field, err
+	}
+	if err := p.expect("="); err != nil {
+		return field, err
+	}
+	number, err := p.number()
+	if err != nil {
+		return field, err
+	}
+	field.Number = int(number)
+	return field, p.skipStatement()
+}
+
+func (p *parser) parseEnum(scope, comment string) error {
+	name, err := p.ident()
+	if err != nil {
+		return err
+	}
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+
+	enum := Enum{Name: scope + name, Description: comment}
+	for !p.accept("}") {
+		switch p.peek().text {
+		case "option", "reserved":
+			if err := p.skipStatement(); err != nil {
+				return err
+			}
+			continue
+		case ";":
+			p.next()
+			continue
+		}
+
+		value := EnumValue{Description: p.peek().comment}
+		if value.Name, err = p.ident(); err != nil {
+			return err
+		}
+		if err := p.expect("="); err != nil {
+			return err
+		}
+		number, err := p.number()
+		if err != nil {
+			return err
+		}
+		value.Number = int(number)
+		if err := p.skipStatement(); err != nil {
+			return err
+		}
+		enum.Values = append(enum.Values, value)
+	}
+	p.file.Enums = append(p.file.Enums, enum)
+	return nil
+}
+
+func (p *parser) parseService(comment string) error {
+	name, err := p.ident()
+	if err != nil {
+		return err
+	}
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+
+	service := Service{Name: name, Description: comment}
+	for !p.accept("}") {
+		tok := p.peek()
+		switch tok.text {
+		case "rpc":
+			p.next()
+			var method Method
+			if method, err = p.parseMethod(tok.comment); err == nil {
+				service.Methods = append(service.Methods, method)
+			}
+		case "option":
+			err = p.skipStatement()
+		case ";":
+			p.next()
+		default:
+			err = p.unexpected(`"rpc"`)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	p.file.Services = append(p.file.Services, service)
+	return nil
+}
+
+// parseMethod reads Name (stream Input) returns (stream Output), ended by
+// ";" or a block of options
+func (p *parser) parseMethod(comment string) (Method, error) {
+	method := Method{Description: comment}
+	var err error
+	if method.Name, err = p.ident(); err != nil {
+		return method, err
+	}
+
+	messageType := func() (TypeRef, bool, error) {
+		if err := p.expect("("); err != nil {
+			return TypeRef{}, false, err
+		}
+		// "stream" is also a valid message name: stream Foo vs Foo
+		stream := p.peek().text == "stream" && p.tokens[p.pos+1].kind == tokenIdent
+		if stream {
+			p.next()
+		}
+		name, err := p.ident()
+		if err != nil {
+			return TypeRef{}, false, err
+		}
+		return TypeRef{Name: name}, stream, p.expect(")")
+	}
+
+	if method.Input, method.ClientStreaming, err = messageType(); err != nil {
+		return method, err
+	}
+	if err := p.expect("returns"); err != nil {
+		return method, err
+	}
+	if method.Output, method.ServerStreaming, err = messageType(); err != nil {
+		return method, err
+	}
+
+	if p.peek().text == "{" {
+		if err := p.skipBlock(); err != nil {
+			return method, err
+		}
+		p.accept(";")
+		return method, nil
+	}
+	return method, p.expect(";")
+}
@@ -0,0 +1,335 @@
+// Package proto reads the services, methods and messages of a protobuf file
+// into a structure templates can range over, so gRPC templates can generate
+// handler stubs, client examples and buf configuration from a contract.
+package proto
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// File is what templates see of a proto file as {{ .Proto }}
+type File struct {
+	// Path is where the file goes below the proto root, following its
+	// package as buf lint expects: pets/v1/pets.proto
+	Path    string   `yaml:"path"`
+	Syntax  string   `yaml:"syntax,omitempty"`
+	Package string   `yaml:"package"`
+	Imports []string `yaml:"imports,omitempty"`
+
+	Services []Service `yaml:"services,omitempty"`
+	Messages []Message `yaml:"messages,omitempty"`
+	Enums    []Enum    `yaml:"enums,omitempty"`
+
+	// Source is the file as written, for templates to copy into the project
+	Source string `yaml:"source"`
+}
+
+// Service is a gRPC service of the file
+type Service struct {
+	Name        string   `yaml:"name"`
+	Description string   `yaml:"description,omitempty"`
+	Methods     []Method `yaml:"methods,omitempty"`
+}
+
+// Method is an rpc of a service
+type Method struct {
+	Name            string  `yaml:"name"`
+	Description     string  `yaml:"description,omitempty"`
+	Input           TypeRef `yaml:"input"`
+	Output          TypeRef `yaml:"output"`
+	ClientStreaming bool    `yaml:"client_streaming,omitempty"`
+	ServerStreaming bool    `yaml:"server_streaming,omitempty"`
+}
+
+// Unary reports whether the method streams in neither direction
+func (m Method) Unary() bool {
+	return !m.ClientStreaming && !m.ServerStreaming
+}
+
+// Message is a message of the file; nested messages are listed after their
+// parent, named within it (Outer.Inner)
+type Message struct {
+	Name        string  `yaml:"name"`
+	Description string  `yaml:"description,omitempty"`
+	Fields      []Field `yaml:"fields,omitempty"`
+}
+
+// Field is a field of a message
+type Field struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description,omitempty"`
+	Number      int    `yaml:"number"`
+
+	// Type is the type as written (string, Pet, google.protobuf.Timestamp);
+	// for maps it is the value type and Key the key type
+	Type string `yaml:"type"`
+	Key  string `yaml:"key,omitempty"`
+
+	Repeated bool `yaml:"repeated,omitempty"`
+	Optional bool `yaml:"optional,omitempty"`
+
+	// Oneof names the oneof the field belongs to
+	Oneof string `yaml:"oneof,omitempty"`
+}
+
+// Enum is an enum of the file, named like messages
+type Enum struct {
+	Name        string      `yaml:"name"`
+	Description string      `yaml:"description,omitempty"`
+	Values      []EnumValue `yaml:"values,omitempty"`
+}
+
+// EnumValue is a value of an enum
+type EnumValue struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description,omitempty"`
+	Number      int    `yaml:"number"`
+}
+
+// TypeRef is the message a method takes or returns. Templates turn it into
+// the type of the generated code with {{ .Input.Go }} or
+// {{ .Input.Python }}.
+type TypeRef struct {
+	// Name is the message name within its package (Outer.Inner) and
+	// Package the proto package it belongs to
+	Name    string `yaml:"name"`
+	Package string `yaml:"package"`
+
+	// GoPackage and PythonModule qualify the type in generated code:
+	// petsv1 and pets_pb2 for messages of the file, emptypb and empty_pb2
+	// for google.protobuf.Empty
+	GoPackage    string `yaml:"go_package"`
+	PythonModule string `yaml:"python_module"`
+}
+
+// Go returns the Go type of the message, e.g. petsv1.Pet_Owner
+func (t TypeRef) Go() string {
+	return t.GoPackage + "." + strings.ReplaceAll(t.Name, ".", "_")
+}
+
+// Python returns the Python type of the message, e.g. pets_pb2.Pet.Owner
+func (t TypeRef) Python() string {
+	return t.PythonModule + "." + t.Name
+}
+
+// wellKnownPackage is the proto package of the well-known types
+const wellKnownPackage = "google.protobuf"
+
+// wellKnownTypes maps the well-known messages methods can take or return to
+// their Go package and Python module
+var wellKnownTypes = map[string][2]string{
+	"Empty":       {"emptypb", "empty_pb2"},
+	"Timestamp":   {"timestamppb", "timestamp_pb2"},
+	"Duration":    {"durationpb", "duration_pb2"},
+	"Any":         {"anypb", "any_pb2"},
+	"Struct":      {"structpb", "struct_pb2"},
+	"Value":       {"structpb", "struct_pb2"},
+	"ListValue":   {"structpb", "struct_pb2"},
+	"FieldMask":   {"fieldmaskpb", "field_mask_pb2"},
+	"DoubleValue": {"wrapperspb", "wrappers_pb2"},
+	"FloatValue":  {"wrapperspb", "wrappers_pb2"},
+	"Int64Value":  {"wrapperspb", "wrappers_pb2"},
+	"UInt64Value": {"wrapperspb", "wrappers_pb2"},
+	"Int32Value":  {"wrapperspb", "wrappers_pb2"},
+	"UInt32Value": {"wrapperspb", "wrappers_pb2"},
+	"BoolValue":   {"wrapperspb", "wrappers_pb2"},
+	"StringValue": {"wrapperspb", "wrappers_pb2"},
+	"BytesValue":  {"wrapperspb", "wrappers_pb2"},
+}
+
+// bufDependencies maps import prefixes to the Buf Schema Registry modules
+// providing them; google/protobuf imports ship with buf itself
+var bufDependencies = []struct{ prefix, module string }{
+	{"google/protobuf/", ""},
+	{"google/api/", "buf.build/googleapis/googleapis"},
+	{"google/rpc/", "buf.build/googleapis/googleapis"},
+	{"google/type/", "buf.build/googleapis/googleapis"},
+	{"buf/validate/", "buf.build/bufbuild/protovalidate"},
+	{"validate/", "buf.build/envoyproxy/protoc-gen-validate"},
+	{"protoc-gen-openapiv2/", "buf.build/grpc-ecosystem/grpc-gateway"},
+}
+
+// Deps returns the Buf Schema Registry modules the imports of the file come
+// from, for the deps of buf.yaml
+func (f *File) Deps() []string {
+	var deps []string
+	for _, imp := range f.Imports {
+		if module := dependency(imp); module != "" && !contains(deps, module) {
+			deps = append(deps, module)
+		}
+	}
+	sort.Strings(deps)
+	return deps
+}
+
+// Dir is the directory of the file below the proto root: pets/v1
+func (f *File) Dir() string {
+	return path.Dir(f.Path)
+}
+
+// Module is the base name of the file, which names the generated Python
+// modules (pets_pb2, pets_pb2_grpc)
+func (f *File) Module() string {
+	return strings.TrimSuffix(path.Base(f.Path), ".proto")
+}
+
+// versionPattern matches package versions such as v1 or v2beta1
+var versionPattern = regexp.MustCompile(`^v\d+((alpha|beta)\d*)?$`)
+
+// GoPackage is the name buf managed mode gives the Go package generated for
+// the file: the last element of its package, prefixed by the one before it
+// when that is a version (pets.v1 is petsv1)
+func (f *File) GoPackage() string {
+	parts := strings.Split(f.Package, ".")
+	name := parts[len(parts)-1]
+	if len(parts) > 1 && versionPattern.MatchString(name) {
+		name = parts[len(parts)-2] + name
+	}
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, strings.ToLower(name))
+}
+
+// GoImports returns the import paths of the well-known types the methods
+// take or return, e.g. google.golang.org/protobuf/types/known/emptypb
+func (f *File) GoImports() []string {
+	var imports []string
+	for _, ref := range f.wellKnownRefs() {
+		imp := "google.golang.org/protobuf/types/known/" + ref.GoPackage
+		if !contains(imports, imp) {
+			imports = append(imports, imp)
+		}
+	}
+	sort.Strings(imports)
+	return imports
+}
+
+// PythonImports returns the google.protobuf modules of the well-known types
+// the methods take or return, e.g. empty_pb2
+func (f *File) PythonImports() []string {
+	var imports []string
+	for _, ref := range f.wellKnownRefs() {
+		if !contains(imports, ref.PythonModule) {
+			imports = append(imports, ref.PythonModule)
+		}
+	}
+	sort.Strings(imports)
+	return imports
+}
+
+func (f *File) wellKnownRefs() []TypeRef {
+	var refs []TypeRef
+	for _, service := range f.Services {
+		for _, method := range service.Methods {
+			for _, ref := range []TypeRef{method.Input, method.Output} {
+				if ref.Package == wellKnownPackage {
+					refs = append(refs, ref)
+				}
+			}
+		}
+	}
+	return refs
+}
+
+// Load reads the proto file at filename
+func Load(filename string) (*File, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	file, err := Parse(filepath.Base(filename), data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return file, nil
+}
+
+// Parse reads a proto file named name. The file must declare a package and
+// may only import the well-known types and modules of the Buf Schema
+// Registry, since it is copied into the project on its own.
+func Parse(name string, data []byte) (*File, error) {
+	tokens, err := tokenize(string(data))
+	if err != nil {
+		return nil, err
+	}
+	file := &File{Syntax: "proto2", Source: string(data)}
+	p := &parser{tokens: tokens, file: file}
+	if err := p.parseFile(); err != nil {
+		return nil, err
+	}
+
+	if file.Package == "" {
+		return nil, fmt.Errorf("no package declared: generated code is placed by package")
+	}
+	for _, imp := range file.Imports {
+		if !strings.HasPrefix(imp, "google/protobuf/") && dependency(imp) == "" {
+			return nil, fmt.Errorf("import %q is neither a well-known type nor a Buf Schema Registry module: only self-contained files are supported", imp)
+		}
+	}
+	if len(file.Services) == 0 {
+		return nil, fmt.Errorf("no service declared")
+	}
+	file.Path = strings.ReplaceAll(file.Package, ".", "/") + "/" + name
+
+	messages := make(map[string]bool, len(file.Messages))
+	for _, message := range file.Messages {
+		messages[message.Name] = true
+	}
+	for i := range file.Services {
+		service := &file.Services[i]
+		for j := range service.Methods {
+			method := &service.Methods[j]
+			for _, ref := range []*TypeRef{&method.Input, &method.Output} {
+				if err := file.resolve(ref, messages); err != nil {
+					return nil, fmt.Errorf("rpc %s.%s: %w", service.Name, method.Name, err)
+				}
+			}
+		}
+	}
+	return file, nil
+}
+
+// resolve qualifies the message name a method refers to, which is either a
+// message of the file or a well-known type
+func (f *File) resolve(ref *TypeRef, messages map[string]bool) error {
+	name := strings.TrimPrefix(ref.Name, ".")
+	if local := strings.TrimPrefix(name, f.Package+"."); messages[local] {
+		ref.Name, ref.Package = local, f.Package
+		ref.GoPackage, ref.PythonModule = f.GoPackage(), f.Module()+"_pb2"
+		return nil
+	}
+	if known, ok := wellKnownTypes[strings.TrimPrefix(name, wellKnownPackage+".")]; ok && strings.HasPrefix(name, wellKnownPackage+".") {
+		ref.Name, ref.Package = strings.TrimPrefix(name, wellKnownPackage+"."), wellKnownPackage
+		ref.GoPackage, ref.PythonModule = known[0], known[1]
+		return nil
+	}
+	return fmt.Errorf("unknown message %s", ref.Name)
+}
+
+// dependency returns the Buf Schema Registry module providing an import
+func dependency(imp string) string {
+	for _, dep := range bufDependencies {
+		if strings.HasPrefix(imp, dep.prefix) {
+			return dep.module
+		}
+	}
+	return ""
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
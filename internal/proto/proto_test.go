@@ -0,0 +1,175 @@
+package proto
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const pets = `// Pets API
+syntax = "proto3";
+
+package pets.v1;
+
+import "google/protobuf/empty.proto";
+import "google/api/annotations.proto";
+
+option go_package = "example.com/pets/gen/pets/v1;petsv1";
+
+// PetService manages pets.
+service PetService {
+  option deprecated = false;
+
+  // GetPet returns one pet.
+  rpc GetPet(GetPetRequest) returns (Pet) {
+    option (google.api.http) = { get: "/v1/pets/{id}" };
+  }
+  rpc ListPets(google.protobuf.Empty) returns (stream Pet);
+  /* Upload sends
+   * many pets. */
+  rpc Upload(stream .pets.v1.Pet) returns (google.protobuf.Empty);
+  rpc Chat(stream Pet.Note) returns (stream Pet.Note);
+}
+
+message GetPetRequest {
+  string id = 1; // trailing comments describe nothing
+}
+
+// A pet.
+message Pet {
+  // Note is a message about a pet.
+  message Note {
+    string text = 1;
+  }
+  enum Kind {
+    KIND_UNSPECIFIED = 0;
+    KIND_DOG = 1 [deprecated = true];
+  }
+
+  string id = 1;
+  repeated string tags = 2;
+  map<string, Note> notes = 3;
+  optional Kind kind = 4;
+  oneof owner {
+    string person = 5;
+    string shelter = 6;
+  }
+  reserved 7, 8;
+}
+`
+
+func TestParse(t *testing.T) {
+	file, err := Parse("pets.proto", []byte(pets))
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+
+	if file.Path != "pets/v1/pets.proto" || file.Syntax != "proto3" || file.GoPackage() != "petsv1" || file.Module() != "pets" {
+		t.Errorf("Parse() path %q, syntax %q, Go package %q, module %q", file.Path, file.Syntax, file.GoPackage(), file.Module())
+	}
+
+	local := func(name string) TypeRef {
+		return TypeRef{Name: name, Package: "pets.v1", GoPackage: "petsv1", PythonModule: "pets_pb2"}
+	}
+	empty := TypeRef{Name: "Empty", Package: "google.protobuf", GoPackage: "emptypb", PythonModule: "empty_pb2"}
+	wantServices := []Service{{
+		Name:        "PetService",
+		Description: "PetService manages pets.",
+		Methods: []Method{
+			{Name: "GetPet", Description: "GetPet returns one pet.", Input: local("GetPetRequest"), Output: local("Pet")},
+			{Name: "ListPets", Input: empty, Output: local("Pet"), ServerStreaming: true},
+			{Name: "Upload", Description: "Upload sends\nmany pets.", Input: local("Pet"), Output: empty, ClientStreaming: true},
+			{Name: "Chat", Input: local("Pet.Note"), Output: local("Pet.Note"), ClientStreaming: true, ServerStreaming: true},
+		},
+	}}
+	if !reflect.DeepEqual(file.Services, wantServices) {
+		t.Errorf("Parse() services =\n%+v\nwant\n%+v", file.Services, wantServices)
+	}
+
+	wantMessages := []Message{
+		{Name: "GetPetRequest", Fields: []Field{{Name: "id", Number: 1, Type: "string"}}},
+		{Name: "Pet", Description: "A pet.", Fields: []Field{
+			{Name: "id", Number: 1, Type: "string"},
+			{Name: "tags", Number: 2, Type: "string", Repeated: true},
+			{Name: "notes", Number: 3, Type: "Note", Key: "string"},
+			{Name: "kind", Number: 4, Type: "Kind", Optional: true},
+			{Name: "person", Number: 5, Type: "string", Oneof: "owner"},
+			{Name: "shelter", Number: 6, Type: "string", Oneof: "owner"},
+		}},
+		{Name: "Pet.Note", Description: "Note is a message about a pet.", Fields: []Field{{Name: "text", Number: 1, Type: "string"}}},
+	}
+	if !reflect.DeepEqual(file.Messages, wantMessages) {
+		t.Errorf("Parse() messages =\n%+v\nwant\n%+v", file.Messages, wantMessages)
+	}
+
+	wantEnums := []Enum{{Name: "Pet.Kind", Values: []EnumValue{{Name: "KIND_UNSPECIFIED"}, {Name: "KIND_DOG", Number: 1}}}}
+	if !reflect.DeepEqual(file.Enums, wantEnums) {
+		t.Errorf("Parse() enums = %+v, want %+v", file.Enums, wantEnums)
+	}
+
+	if got, want := file.Deps(), []string{"buf.build/googleapis/googleapis"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Deps() = %q, want %q", got, want)
+	}
+	if got, want := file.GoImports(), []string{"google.golang.org/protobuf/types/known/emptypb"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GoImports() = %q, want %q", got, want)
+	}
+	if got, want := file.PythonImports(), []string{"empty_pb2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("PythonImports() = %q, want %q", got, want)
+	}
+	if got := wantServices[0].Methods[3].Input; got.Go() != "petsv1.Pet_Note" || got.Python() != "pets_pb2.Pet.Note" {
+		t.Errorf("Go() = %q, Python() = %q", got.Go(), got.Python())
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		wantErr string
+	}{
+		{
+			name:    "no package",
+			source:  `syntax = "proto3"; service S { rpc M(A) returns (A); } message A {}`,
+			wantErr: "no package",
+		},
+		{
+			name:    "no service",
+			source:  `syntax = "proto3"; package a.v1; message A {}`,
+			wantErr: "no service",
+		},
+		{
+			name:    "local import",
+			source:  `syntax = "proto3"; package a.v1; import "a/v1/common.proto";`,
+			wantErr: `import "a/v1/common.proto"`,
+		},
+		{
+			name:    "unknown message",
+			source:  `syntax = "proto3"; package a.v1; service S { rpc M(A) returns (B); } message A {}`,
+			wantErr: "rpc S.M: unknown message B",
+		},
+		{
+			name:    "syntax error",
+			source:  "syntax = \"proto3\";\npackage a.v1;\nmessage A {\n  string = 1;\n}",
+			wantErr: "line 4",
+		},
+		{
+			name:    "unterminated",
+			source:  `syntax = "proto3"; package a.v1; message A {`,
+			wantErr: "end of file",
+		},
+		{
+			name:    "editions",
+			source:  `edition = "2023";`,
+			wantErr: "editions are not supported",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse("a.proto", []byte(tt.source))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Parse() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
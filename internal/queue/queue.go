@@ -0,0 +1,149 @@
+// Package queue runs jobs, such as generating several projects, concurrently
+// up to a limit, and keeps the output of the jobs readable: every job writes
+// through its own prefixed writer, and only whole lines reach the shared
+// output.
+package queue
+
+import (
+	"bytes"
+	"io"
+	"runtime"
+	"sync"
+)
+
+// Queue runs jobs, in the order they are queued, with at most a given
+// number of them at a time
+type Queue struct {
+	limit int
+	wg    sync.WaitGroup
+
+	mu      sync.Mutex
+	pending []func()
+	running int
+}
+
+// New returns a queue running up to limit jobs at a time; limits below 1
+// mean one per CPU
+func New(limit int) *Queue {
+	if limit < 1 {
+		limit = runtime.NumCPU()
+	}
+	return &Queue{limit: limit}
+}
+
+// Limit returns the number of jobs the queue runs at a time
+func (q *Queue) Limit() int {
+	return q.limit
+}
+
+// Go queues job. It does not block; the job starts once the jobs queued
+// before it have started and fewer than Limit are running.
+func (q *Queue) Go(job func()) {
+	q.wg.Add(1)
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.pending = append(q.pending, job)
+	if q.running < q.limit {
+		q.running++
+		go q.work()
+	}
+}
+
+// work runs pending jobs until there are none left
+func (q *Queue) work() {
+	for {
+		q.mu.Lock()
+		if len(q.pending) == 0 {
+			q.running--
+			q.mu.Unlock()
+			return
+		}
+		job := q.pending[0]
+		q.pending = q.pending[1:]
+		q.mu.Unlock()
+
+		job()
+		q.wg.Done()
+	}
+}
+
+// Wait waits for every queued job to return
+func (q *Queue) Wait() {
+	q.wg.Wait()
+}
+
+// Output is an output shared by jobs. Its writers pass on whole lines only,
+// prefixed with the name of their job, so lines of parallel jobs never mix.
+type Output struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewOutput returns an output writing to w
+func NewOutput(w io.Writer) *Output {
+	return &Output{w: w}
+}
+
+// Writer returns a writer for a job: every line written to it goes to the
+// output with prefix in front. Call Close to write the last, unterminated
+// line.
+func (o *Output) Writer(prefix string) *LineWriter {
+	return &LineWriter{out: o, prefix: prefix}
+}
+
+// LineWriter is the writer of a job of an Output
+type LineWriter struct {
+	out    *Output
+	prefix string
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+// Write buffers p and writes the lines it completes
+func (w *LineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	end := bytes.LastIndexByte(w.buf, '\n')
+	if end < 0 {
+		return len(p), nil
+	}
+	lines := w.buf[:end+1]
+	if err := w.flush(lines); err != nil {
+		return 0, err
+	}
+	w.buf = append(w.buf[:0], w.buf[end+1:]...)
+	return len(p), nil
+}
+
+// Close writes the buffered line, if any, with a newline
+func (w *LineWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buf) == 0 {
+		return nil
+	}
+	err := w.flush(append(w.buf, '\n'))
+	w.buf = nil
+	return err
+}
+
+// flush writes whole lines, each prefixed, in one go
+func (w *LineWriter) flush(lines []byte) error {
+	var b bytes.Buffer
+	for _, line := range bytes.SplitAfter(lines, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		b.WriteString(w.prefix)
+		b.Write(line)
+	}
+
+	w.out.mu.Lock()
+	defer w.out.mu.Unlock()
+	_, err := w.out.w.Write(b.Bytes())
+	return err
+}
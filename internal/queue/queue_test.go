@@ -0,0 +1,97 @@
+package queue
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestQueueLimit(t *testing.T) {
+	q := New(2)
+	if q.Limit() != 2 {
+		t.Fatalf("Limit() = %d, want 2", q.Limit())
+	}
+	if New(0).Limit() < 1 {
+		t.Errorf("New(0).Limit() = %d, want one per CPU", New(0).Limit())
+	}
+
+	var running, peak, done int32
+	for i := 0; i < 8; i++ {
+		q.Go(func() {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			atomic.AddInt32(&done, 1)
+		})
+	}
+	q.Wait()
+
+	if done != 8 {
+		t.Errorf("%d jobs ran, want 8", done)
+	}
+	if peak > 2 {
+		t.Errorf("%d jobs ran at a time, want at most 2", peak)
+	}
+}
+
+func TestQueueOrder(t *testing.T) {
+	q := New(1)
+	var order []int
+	for i := 0; i < 5; i++ {
+		q.Go(func() { order = append(order, i) })
+	}
+	q.Wait()
+
+	if fmt.Sprint(order) != "[0 1 2 3 4]" {
+		t.Errorf("jobs ran in order %v, want the order they were queued", order)
+	}
+}
+
+func TestOutputLines(t *testing.T) {
+	var buf bytes.Buffer
+	out := NewOutput(&buf)
+
+	var wg sync.WaitGroup
+	for _, name := range []string{"a", "b", "c"} {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			w := out.Writer("[" + name + "] ")
+			for i := 0; i < 50; i++ {
+				// Lines arrive in pieces
+				fmt.Fprintf(w, "line %d ", i)
+				fmt.Fprintf(w, "of %s\n", name)
+			}
+			fmt.Fprintf(w, "last of %s", name)
+			w.Close()
+		}(name)
+	}
+	wg.Wait()
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 153 {
+		t.Fatalf("got %d lines, want 153:\n%s", len(lines), buf.String())
+	}
+	for _, line := range lines {
+		name := line[1:2]
+		if !strings.HasPrefix(line, "["+name+"] ") || !strings.HasSuffix(line, "of "+name) {
+			t.Errorf("line mixes jobs: %q", line)
+		}
+	}
+
+	sort.Strings(lines)
+	if lines[0] != "[a] last of a" {
+		t.Errorf("unterminated line = %q, want it written on Close", lines[0])
+	}
+}
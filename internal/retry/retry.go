@@ -0,0 +1,94 @@
+// Package retry runs operations that may fail transiently (network
+// downloads, dependency installs) several times with exponential backoff.
+package retry
+
+import "time"
+
+// Policy describes how often and how patiently an operation is retried
+type Policy struct {
+	// Attempts is the total number of tries; values below 1 mean 1
+	Attempts int
+
+	// Backoff is the wait after the first failure. It doubles after each
+	// further failure, up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// Default returns the policy used when nothing is configured
+func Default() Policy {
+	return Policy{
+		Attempts:   3,
+		Backoff:    2 * time.Second,
+		MaxBackoff: 30 * time.Second,
+	}
+}
+
+// sleep is replaced in tests
+var sleep = time.Sleep
+
+// Total returns the number of attempts Do makes at most
+func (p Policy) Total() int {
+	if p.Attempts < 1 {
+		return 1
+	}
+	return p.Attempts
+}
+
+// Wait returns the delay after the given failed attempt (1-based)
+func (p Policy) Wait(attempt int) time.Duration {
+	wait := p.Backoff
+	for i := 1; i < attempt && wait > 0; i++ {
+		wait *= 2
+		if p.MaxBackoff > 0 && wait >= p.MaxBackoff {
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && wait > p.MaxBackoff {
+		wait = p.MaxBackoff
+	}
+	return wait
+}
+
+// Do calls fn until it succeeds, returns a permanent error or the attempts
+// run out, and returns the last error. Before each retry, onRetry (if not
+// nil) is told which attempt failed and how long Do waits.
+func (p Policy) Do(fn func(attempt int) error, onRetry func(attempt int, err error, wait time.Duration)) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn(attempt)
+		if err == nil || attempt >= p.Total() || IsPermanent(err) {
+			return err
+		}
+
+		wait := p.Wait(attempt)
+		if onRetry != nil {
+			onRetry(attempt, err, wait)
+		}
+		if wait > 0 {
+			sleep(wait)
+		}
+	}
+}
+
+// permanentError marks an error that retrying cannot fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so Do returns it without retrying
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was wrapped with Permanent
+func IsPermanent(err error) bool {
+	_, ok := err.(*permanentError)
+	return ok
+}
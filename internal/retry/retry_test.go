@@ -0,0 +1,127 @@
+package retry
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWait(t *testing.T) {
+	policy := Policy{Attempts: 6, Backoff: time.Second, MaxBackoff: 5 * time.Second}
+
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{attempt: 1, want: time.Second},
+		{attempt: 2, want: 2 * time.Second},
+		{attempt: 3, want: 4 * time.Second},
+		{attempt: 4, want: 5 * time.Second},
+		{attempt: 50, want: 5 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := policy.Wait(tt.attempt); got != tt.want {
+			t.Errorf("Wait(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+
+	if got := (Policy{}).Wait(3); got != 0 {
+		t.Errorf("Wait() without backoff = %v, want 0", got)
+	}
+}
+
+func TestDo(t *testing.T) {
+	var slept []time.Duration
+	sleep = func(d time.Duration) { slept = append(slept, d) }
+	defer func() { sleep = time.Sleep }()
+
+	transient := errors.New("connection reset")
+
+	tests := []struct {
+		name      string
+		policy    Policy
+		failures  int
+		permanent bool
+		wantCalls int
+		wantErr   bool
+		wantSlept []time.Duration
+	}{
+		{
+			name:      "succeeds first time",
+			policy:    Policy{Attempts: 3, Backoff: time.Second},
+			wantCalls: 1,
+		},
+		{
+			name:      "succeeds after retries",
+			policy:    Policy{Attempts: 3, Backoff: time.Second},
+			failures:  2,
+			wantCalls: 3,
+			wantSlept: []time.Duration{time.Second, 2 * time.Second},
+		},
+		{
+			name:      "gives up after all attempts",
+			policy:    Policy{Attempts: 2, Backoff: time.Second},
+			failures:  5,
+			wantCalls: 2,
+			wantErr:   true,
+			wantSlept: []time.Duration{time.Second},
+		},
+		{
+			name:      "zero attempts still runs once",
+			policy:    Policy{},
+			failures:  5,
+			wantCalls: 1,
+			wantErr:   true,
+		},
+		{
+			name:      "permanent errors are not retried",
+			policy:    Policy{Attempts: 3, Backoff: time.Second},
+			failures:  5,
+			permanent: true,
+			wantCalls: 1,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slept = nil
+			calls := 0
+			var retried []int
+
+			err := tt.policy.Do(func(attempt int) error {
+				calls++
+				if attempt != calls {
+					t.Errorf("attempt = %d, want %d", attempt, calls)
+				}
+				if calls > tt.failures {
+					return nil
+				}
+				if tt.permanent {
+					return Permanent(transient)
+				}
+				return transient
+			}, func(attempt int, err error, wait time.Duration) {
+				retried = append(retried, attempt)
+			})
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Do() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, transient) {
+				t.Errorf("Do() error = %v, want the last error", err)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+			if !reflect.DeepEqual(slept, tt.wantSlept) {
+				t.Errorf("slept = %v, want %v", slept, tt.wantSlept)
+			}
+			if len(retried) != calls-1 {
+				t.Errorf("onRetry called %d times, want %d", len(retried), calls-1)
+			}
+		})
+	}
+}
@@ -0,0 +1,578 @@
+package sbom
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/renan-dev/devinit/internal/merge"
+)
+
+// parser extracts the components of a manifest or lockfile
+type parser func(data []byte) ([]Component, error)
+
+// parserFor returns the parser of a file name, and whether the file is a
+// lockfile, or nil for files that declare no dependencies
+func parserFor(name string) (p parser, lock bool) {
+	switch {
+	case name == "package.json":
+		return parsePackageJSON, false
+	case name == "package-lock.json":
+		return parsePackageLock, true
+	case name == "pyproject.toml":
+		return parsePyproject, false
+	case name == "requirements.txt", strings.HasPrefix(name, "requirements") && strings.HasSuffix(name, ".txt"):
+		return parseRequirements(strings.TrimSuffix(name, ".txt") != "requirements"), false
+	case name == "poetry.lock", name == "uv.lock":
+		return parseTOMLLock(PyPI), true
+	case name == "go.mod":
+		return parseGoMod, false
+	case name == "Cargo.toml":
+		return parseCargoToml, false
+	case name == "Cargo.lock":
+		return parseTOMLLock(Cargo), true
+	case name == "pom.xml":
+		return parsePom, false
+	case strings.HasSuffix(name, ".csproj"):
+		return parseCsproj, false
+	case name == "composer.json":
+		return parseComposerJSON, false
+	case name == "composer.lock":
+		return parseComposerLock, true
+	case name == "Gemfile":
+		return parseGemfile, false
+	}
+	return nil, false
+}
+
+// scopeOf returns the scope of optional (dev, test) or required dependencies
+func scopeOf(optional bool) string {
+	if optional {
+		return ScopeOptional
+	}
+	return ScopeRequired
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// exactVersion returns the version a constraint pins, if it pins one
+// ("1.2.3", "=1.2.3", "==1.2.3")
+func exactVersion(constraint string) string {
+	version := strings.TrimLeft(strings.TrimSpace(constraint), "=")
+	if version == "" || strings.ContainsAny(version, "^~<>*, |!") || !strings.ContainsAny(version[:1], "0123456789") {
+		return ""
+	}
+	return version
+}
+
+func parsePackageJSON(data []byte) ([]Component, error) {
+	var manifest struct {
+		Dependencies         map[string]string `json:"dependencies"`
+		DevDependencies      map[string]string `json:"devDependencies"`
+		OptionalDependencies map[string]string `json:"optionalDependencies"`
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, err
+	}
+
+	var components []Component
+	for _, group := range []struct {
+		deps     map[string]string
+		optional bool
+	}{
+		{manifest.Dependencies, false},
+		{manifest.DevDependencies, true},
+		{manifest.OptionalDependencies, true},
+	} {
+		for _, name := range sortedKeys(group.deps) {
+			constraint := group.deps[name]
+			components = append(components, Component{
+				Ecosystem:  Npm,
+				Name:       name,
+				Version:    exactVersion(constraint),
+				Constraint: constraint,
+				Scope:      scopeOf(group.optional),
+			})
+		}
+	}
+	return components, nil
+}
+
+func parsePackageLock(data []byte) ([]Component, error) {
+	var lock struct {
+		Packages map[string]struct {
+			Version  string `json:"version"`
+			Dev      bool   `json:"dev"`
+			Optional bool   `json:"optional"`
+		} `json:"packages"`
+	}
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(lock.Packages))
+	for path := range lock.Packages {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var components []Component
+	for _, path := range paths {
+		// "" is the project itself; entries are node_modules/<name>, nested
+		// for conflicting versions
+		i := strings.LastIndex(path, "node_modules/")
+		if i < 0 {
+			continue
+		}
+		pkg := lock.Packages[path]
+		components = append(components, Component{
+			Ecosystem: Npm,
+			Name:      path[i+len("node_modules/"):],
+			Version:   pkg.Version,
+			Scope:     scopeOf(pkg.Dev || pkg.Optional),
+		})
+	}
+	return components, nil
+}
+
+// tomlString returns the string a TOML value holds, without quotes or a
+// trailing comment
+func tomlString(value string) string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return ""
+	}
+	if quote := value[0]; quote == '"' || quote == '\'' {
+		if end := strings.IndexByte(value[1:], quote); end >= 0 {
+			return value[1 : end+1]
+		}
+		return strings.Trim(value, string(quote))
+	}
+	value, _, _ = strings.Cut(value, "#")
+	return strings.TrimSpace(value)
+}
+
+// inlineTable returns the keys of an inline table value ({ version = "1" })
+func inlineTable(value string) map[string]string {
+	value = strings.TrimSpace(value)
+	if !strings.HasPrefix(value, "{") {
+		return nil
+	}
+	value = strings.TrimSuffix(strings.TrimPrefix(value, "{"), "}")
+	elems, _ := merge.TOMLArray("[" + value + "]")
+
+	table := make(map[string]string)
+	for _, elem := range elems {
+		if key, val, ok := strings.Cut(elem, "="); ok {
+			table[strings.Trim(strings.TrimSpace(key), `"'`)] = strings.TrimSpace(val)
+		}
+	}
+	return table
+}
+
+// tomlDependency returns the constraint of a Poetry or Cargo dependency,
+// given as a string or an inline table with a version, and whether it is
+// optional
+func tomlDependency(value string) (constraint string, optional bool) {
+	if table := inlineTable(value); table != nil {
+		return tomlString(table["version"]), tomlString(table["optional"]) == "true"
+	}
+	return tomlString(value), false
+}
+
+// pep508Pattern splits a PEP 508 requirement into name and version
+// specifier, e.g. "uvicorn[standard]>=0.23; python_version >= '3.8'"
+var pep508Pattern = regexp.MustCompile(`^\s*([A-Za-z0-9][A-Za-z0-9._-]*)\s*(?:\[[^\]]*\])?\s*\(?\s*([^;()]*)`)
+
+// pep508 returns the component of a PEP 508 requirement
+func pep508(requirement string, optional bool) (Component, bool) {
+	match := pep508Pattern.FindStringSubmatch(requirement)
+	if match == nil {
+		return Component{}, false
+	}
+	constraint := strings.TrimSpace(match[2])
+	return Component{
+		Ecosystem:  PyPI,
+		Name:       normalizePyPI(match[1]),
+		Version:    exactVersion(constraint),
+		Constraint: constraint,
+		Scope:      scopeOf(optional),
+	}, true
+}
+
+// pypiSeparators are the runs of characters PEP 503 normalizes to "-"
+var pypiSeparators = regexp.MustCompile(`[-_.]+`)
+
+// normalizePyPI normalizes a Python project name as PyPI does (PEP 503)
+func normalizePyPI(name string) string {
+	return strings.ToLower(pypiSeparators.ReplaceAllString(name, "-"))
+}
+
+func parsePyproject(data []byte) ([]Component, error) {
+	tables, err := merge.ReadTOML(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var components []Component
+	addArray := func(value string, optional bool) {
+		elems, _ := merge.TOMLArray(value)
+		for _, elem := range elems {
+			if c, ok := pep508(tomlString(elem), optional); ok {
+				components = append(components, c)
+			}
+		}
+	}
+	for _, table := range tables {
+		switch {
+		case table.Name == "project":
+			addArray(table.Values["dependencies"], false)
+		case table.Name == "project.optional-dependencies", table.Name == "dependency-groups":
+			for _, key := range table.Keys {
+				addArray(table.Values[key], true)
+			}
+		case table.Name == "tool.poetry.dependencies", table.Name == "tool.poetry.dev-dependencies",
+			strings.HasPrefix(table.Name, "tool.poetry.group.") && strings.HasSuffix(table.Name, ".dependencies"):
+			for _, key := range table.Keys {
+				if strings.EqualFold(key, "python") {
+					continue
+				}
+				constraint, optional := tomlDependency(table.Values[key])
+				components = append(components, Component{
+					Ecosystem:  PyPI,
+					Name:       normalizePyPI(strings.Trim(key, `"'`)),
+					Version:    exactVersion(constraint),
+					Constraint: constraint,
+					Scope:      scopeOf(optional || table.Name != "tool.poetry.dependencies"),
+				})
+			}
+		}
+	}
+	return components, nil
+}
+
+// parseRequirements parses a pip requirements file; optional marks files
+// such as requirements-dev.txt
+func parseRequirements(optional bool) parser {
+	return func(data []byte) ([]Component, error) {
+		var components []Component
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			line, _, _ := strings.Cut(scanner.Text(), "#")
+			line = strings.TrimSpace(line)
+			// Options (-r, -e, --index-url) and URLs name no package
+			if line == "" || strings.HasPrefix(line, "-") || strings.Contains(line, "://") {
+				continue
+			}
+			if c, ok := pep508(line, optional); ok {
+				components = append(components, c)
+			}
+		}
+		return components, scanner.Err()
+	}
+}
+
+// parseTOMLLock parses lockfiles listing [[package]] tables with a name and
+// version (poetry.lock, uv.lock, Cargo.lock)
+func parseTOMLLock(ecosystem string) parser {
+	return func(data []byte) ([]Component, error) {
+		tables, err := merge.ReadTOML(data)
+		if err != nil {
+			return nil, err
+		}
+
+		var components []Component
+		for _, table := range tables {
+			if !table.Array || table.Name != "package" {
+				continue
+			}
+			name := tomlString(table.Values["name"])
+			// The project itself has no source in Cargo.lock, and an
+			// editable or virtual one in uv.lock
+			source := table.Values["source"]
+			if name == "" || ecosystem == Cargo && source == "" || strings.Contains(source, "editable") || strings.Contains(source, "virtual") {
+				continue
+			}
+			if ecosystem == PyPI {
+				name = normalizePyPI(name)
+			}
+			category := tomlString(table.Values["category"])
+			components = append(components, Component{
+				Ecosystem: ecosystem,
+				Name:      name,
+				Version:   tomlString(table.Values["version"]),
+				Scope:     scopeOf(category == "dev"),
+			})
+		}
+		return components, nil
+	}
+}
+
+func parseGoMod(data []byte) ([]Component, error) {
+	var components []Component
+	inBlock := false
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "require (":
+			inBlock = true
+			continue
+		case inBlock && line == ")":
+			inBlock = false
+			continue
+		case strings.HasPrefix(line, "require "):
+			line = strings.TrimSpace(strings.TrimPrefix(line, "require"))
+		case !inBlock:
+			continue
+		}
+
+		line, _, _ = strings.Cut(line, "//")
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		components = append(components, Component{
+			Ecosystem: Golang,
+			Name:      fields[0],
+			Version:   fields[1],
+			Scope:     ScopeRequired,
+		})
+	}
+	return components, scanner.Err()
+}
+
+func parseCargoToml(data []byte) ([]Component, error) {
+	tables, err := merge.ReadTOML(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var components []Component
+	for _, table := range tables {
+		var dev bool
+		switch table.Name {
+		case "dependencies", "build-dependencies":
+		case "dev-dependencies":
+			dev = true
+		default:
+			continue
+		}
+		for _, key := range table.Keys {
+			constraint, optional := tomlDependency(table.Values[key])
+			components = append(components, Component{
+				Ecosystem:  Cargo,
+				Name:       strings.Trim(key, `"'`),
+				Constraint: constraint,
+				Scope:      scopeOf(dev || optional),
+			})
+		}
+	}
+	return components, nil
+}
+
+func parsePom(data []byte) ([]Component, error) {
+	var pom struct {
+		Dependencies []struct {
+			GroupID    string `xml:"groupId"`
+			ArtifactID string `xml:"artifactId"`
+			Version    string `xml:"version"`
+			Scope      string `xml:"scope"`
+			Optional   bool   `xml:"optional"`
+		} `xml:"dependencies>dependency"`
+	}
+	if err := xml.Unmarshal(data, &pom); err != nil {
+		return nil, err
+	}
+
+	var components []Component
+	for _, dep := range pom.Dependencies {
+		version := dep.Version
+		// Versions from properties or a parent BOM are resolved by Maven
+		if strings.Contains(version, "${") {
+			version = ""
+		}
+		components = append(components, Component{
+			Ecosystem: Maven,
+			Group:     dep.GroupID,
+			Name:      dep.ArtifactID,
+			Version:   version,
+			Scope:     scopeOf(dep.Optional || dep.Scope == "test" || dep.Scope == "provided"),
+		})
+	}
+	return components, nil
+}
+
+func parseCsproj(data []byte) ([]Component, error) {
+	var project struct {
+		ItemGroups []struct {
+			References []struct {
+				Include       string `xml:"Include,attr"`
+				Version       string `xml:"Version,attr"`
+				VersionElem   string `xml:"Version"`
+				PrivateAssets string `xml:"PrivateAssets,attr"`
+			} `xml:"PackageReference"`
+		} `xml:"ItemGroup"`
+	}
+	if err := xml.Unmarshal(data, &project); err != nil {
+		return nil, err
+	}
+
+	var components []Component
+	for _, group := range project.ItemGroups {
+		for _, ref := range group.References {
+			version := ref.Version
+			if version == "" {
+				version = ref.VersionElem
+			}
+			components = append(components, Component{
+				Ecosystem:  NuGet,
+				Name:       ref.Include,
+				Version:    exactVersion(version),
+				Constraint: version,
+				// Analyzers and test tooling do not flow to consumers
+				Scope: scopeOf(strings.EqualFold(ref.PrivateAssets, "all")),
+			})
+		}
+	}
+	return components, nil
+}
+
+// composerName splits vendor/package
+func composerName(name string) (group, pkg string) {
+	if vendor, pkg, ok := strings.Cut(name, "/"); ok {
+		return vendor, pkg
+	}
+	return "", name
+}
+
+func parseComposerJSON(data []byte) ([]Component, error) {
+	var manifest struct {
+		Require    map[string]string `json:"require"`
+		RequireDev map[string]string `json:"require-dev"`
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, err
+	}
+
+	var components []Component
+	for _, group := range []struct {
+		deps map[string]string
+		dev  bool
+	}{{manifest.Require, false}, {manifest.RequireDev, true}} {
+		for _, name := range sortedKeys(group.deps) {
+			// The PHP version and extensions are platform requirements
+			if name == "php" || strings.HasPrefix(name, "ext-") {
+				continue
+			}
+			vendor, pkg := composerName(name)
+			components = append(components, Component{
+				Ecosystem:  Composer,
+				Group:      vendor,
+				Name:       pkg,
+				Version:    exactVersion(group.deps[name]),
+				Constraint: group.deps[name],
+				Scope:      scopeOf(group.dev),
+			})
+		}
+	}
+	return components, nil
+}
+
+func parseComposerLock(data []byte) ([]Component, error) {
+	type lockPackage struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+	var lock struct {
+		Packages    []lockPackage `json:"packages"`
+		PackagesDev []lockPackage `json:"packages-dev"`
+	}
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return nil, err
+	}
+
+	var components []Component
+	for _, group := range []struct {
+		packages []lockPackage
+		dev      bool
+	}{{lock.Packages, false}, {lock.PackagesDev, true}} {
+		for _, pkg := range group.packages {
+			vendor, name := composerName(pkg.Name)
+			components = append(components, Component{
+				Ecosystem: Composer,
+				Group:     vendor,
+				Name:      name,
+				Version:   strings.TrimPrefix(pkg.Version, "v"),
+				Scope:     scopeOf(group.dev),
+			})
+		}
+	}
+	return components, nil
+}
+
+// gemPattern matches gem "name", "constraint", ... lines of a Gemfile
+var gemPattern = regexp.MustCompile(`^\s*gem\s+["']([^"']+)["']((?:\s*,\s*["'][^"']*["'])*)`)
+
+// groupPattern matches the start of a group block and its group names
+var groupPattern = regexp.MustCompile(`^\s*group\s+(.*?)\s+do\s*$`)
+
+func parseGemfile(data []byte) ([]Component, error) {
+	var components []Component
+	depth := 0 // nesting of blocks inside a dev/test group
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case depth > 0 && trimmed == "end":
+			depth--
+			continue
+		case depth > 0 && strings.HasSuffix(trimmed, " do"):
+			depth++
+			continue
+		}
+		if match := groupPattern.FindStringSubmatch(line); match != nil && depth == 0 {
+			if !strings.Contains(match[1], "production") && !strings.Contains(match[1], "default") {
+				depth = 1
+			}
+			continue
+		}
+
+		match := gemPattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		var constraints []string
+		for _, part := range strings.Split(match[2], ",") {
+			if part = strings.Trim(strings.TrimSpace(part), `"'`); part != "" {
+				constraints = append(constraints, part)
+			}
+		}
+		constraint := strings.Join(constraints, ", ")
+		components = append(components, Component{
+			Ecosystem:  Gem,
+			Name:       match[1],
+			Version:    exactVersion(constraint),
+			Constraint: constraint,
+			Scope:      scopeOf(depth > 0),
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read Gemfile: %w", err)
+	}
+	return components, nil
+}
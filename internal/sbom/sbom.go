@@ -0,0 +1,282 @@
+// Package sbom lists the dependencies a generated project declares, from its
+// manifests and lockfiles, and encodes them as a CycloneDX bill of
+// materials, so security teams can audit what a template introduced.
+package sbom
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FileName is where generation stores the bill of materials, relative to the
+// project root
+const FileName = ".devinit/sbom.cdx.json"
+
+// Ecosystems, named after their package URL types
+const (
+	Npm      = "npm"
+	PyPI     = "pypi"
+	Golang   = "golang"
+	Cargo    = "cargo"
+	Maven    = "maven"
+	NuGet    = "nuget"
+	Composer = "composer"
+	Gem      = "gem"
+)
+
+// Scopes of a component, as in CycloneDX: required at runtime, or optional
+// (development, test and optional dependencies)
+const (
+	ScopeRequired = "required"
+	ScopeOptional = "optional"
+)
+
+// Component is a dependency of the project
+type Component struct {
+	Ecosystem string `json:"ecosystem"`
+	Group     string `json:"group,omitempty"` // Maven group, Composer vendor
+	Name      string `json:"name"`
+
+	// Version is the exact version, from a lockfile or a pinned
+	// requirement; Constraint is the declared range (^1.2, >=0.100)
+	Version    string `json:"version,omitempty"`
+	Constraint string `json:"constraint,omitempty"`
+
+	Scope  string `json:"scope"`
+	Source string `json:"source"` // manifest or lockfile, relative to the project
+}
+
+// PURL returns the package URL of the component
+func (c Component) PURL() string {
+	name := url.PathEscape(c.Name)
+	if c.Ecosystem == Npm || c.Ecosystem == Golang {
+		// Scoped npm packages and Go modules keep their slashes
+		parts := strings.Split(c.Name, "/")
+		for i := range parts {
+			parts[i] = url.PathEscape(parts[i])
+		}
+		name = strings.Join(parts, "/")
+		if c.Ecosystem == Npm {
+			// The @ of an npm scope is encoded in package URLs
+			name = strings.Replace(name, "@", "%40", 1)
+		}
+	}
+	if c.Group != "" {
+		name = url.PathEscape(c.Group) + "/" + name
+	}
+
+	purl := "pkg:" + c.Ecosystem + "/" + name
+	if c.Version != "" {
+		purl += "@" + url.PathEscape(c.Version)
+	}
+	return purl
+}
+
+// key identifies a component across manifests and lockfiles
+func (c Component) key() string {
+	return c.Ecosystem + "|" + c.Group + "|" + strings.ToLower(c.Name)
+}
+
+// skipDirs are never searched for manifests: dependencies, build output and
+// version control
+var skipDirs = map[string]bool{
+	".git": true, ".devinit": true, "node_modules": true, "vendor": true, ".venv": true,
+	"venv": true, "target": true, "bin": true, "obj": true, "dist": true, "build": true,
+	"__pycache__": true,
+}
+
+// Scan lists the dependencies declared by the manifests and lockfiles in dir.
+// Versions from lockfiles complete the components of manifests; packages
+// only found in lockfiles (transitive dependencies) are included too. Files
+// that fail to parse are reported in warnings and skipped.
+func Scan(dir string) (components []Component, warnings []string, err error) {
+	var manifests, lockfiles []Component
+	err = filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path != dir && skipDirs[entry.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		parser, lock := parserFor(entry.Name())
+		if parser == nil {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		found, err := parser(data)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("%s: %v", rel, err))
+			return nil
+		}
+		for i := range found {
+			found[i].Source = rel
+		}
+		if lock {
+			lockfiles = append(lockfiles, found...)
+		} else {
+			manifests = append(manifests, found...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, warnings, err
+	}
+
+	return combine(manifests, lockfiles), warnings, nil
+}
+
+// combine merges the components of manifests and lockfiles, sorted by
+// ecosystem and name
+func combine(manifests, lockfiles []Component) []Component {
+	byKey := make(map[string]*Component)
+	var result []*Component
+	add := func(c Component) {
+		if existing := byKey[c.key()]; existing != nil {
+			if existing.Version == "" {
+				existing.Version = c.Version
+			}
+			if existing.Constraint == "" {
+				existing.Constraint = c.Constraint
+			}
+			if c.Scope == ScopeRequired {
+				existing.Scope = ScopeRequired
+			}
+			return
+		}
+		component := c
+		byKey[c.key()] = &component
+		result = append(result, &component)
+	}
+	for _, c := range manifests {
+		add(c)
+	}
+	for _, c := range lockfiles {
+		add(c)
+	}
+
+	components := make([]Component, 0, len(result))
+	for _, c := range result {
+		components = append(components, *c)
+	}
+	sort.SliceStable(components, func(i, j int) bool {
+		if components[i].Ecosystem != components[j].Ecosystem {
+			return components[i].Ecosystem < components[j].Ecosystem
+		}
+		return components[i].key() < components[j].key()
+	})
+	return components
+}
+
+// Tool identifies the program writing a bill of materials
+type Tool struct {
+	Name    string
+	Version string
+}
+
+// cycloneDX is a CycloneDX 1.5 JSON document, reduced to the fields devinit
+// writes
+type cycloneDX struct {
+	BOMFormat   string `json:"bomFormat"`
+	SpecVersion string `json:"specVersion"`
+	Version     int    `json:"version"`
+	Metadata    struct {
+		Timestamp string `json:"timestamp"`
+		Tools     struct {
+			Components []cdxComponent `json:"components"`
+		} `json:"tools"`
+		Component cdxComponent `json:"component"`
+	} `json:"metadata"`
+	Components []cdxComponent `json:"components"`
+}
+
+type cdxComponent struct {
+	Type       string        `json:"type"`
+	BOMRef     string        `json:"bom-ref,omitempty"`
+	Group      string        `json:"group,omitempty"`
+	Name       string        `json:"name"`
+	Version    string        `json:"version,omitempty"`
+	Scope      string        `json:"scope,omitempty"`
+	PURL       string        `json:"purl,omitempty"`
+	Properties []cdxProperty `json:"properties,omitempty"`
+}
+
+type cdxProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// CycloneDX encodes components as a CycloneDX JSON bill of materials for the
+// named project. Declared ranges and source files are recorded as
+// devinit:constraint and devinit:source properties.
+func CycloneDX(project string, tool Tool, components []Component, now time.Time) ([]byte, error) {
+	doc := cycloneDX{BOMFormat: "CycloneDX", SpecVersion: "1.5", Version: 1}
+	doc.Metadata.Timestamp = now.UTC().Format(time.RFC3339)
+	doc.Metadata.Tools.Components = []cdxComponent{{Type: "application", Name: tool.Name, Version: tool.Version}}
+	doc.Metadata.Component = cdxComponent{Type: "application", Name: project}
+	doc.Components = make([]cdxComponent, 0, len(components))
+
+	for _, c := range components {
+		component := cdxComponent{
+			Type:    "library",
+			BOMRef:  c.PURL(),
+			Group:   c.Group,
+			Name:    c.Name,
+			Version: c.Version,
+			Scope:   c.Scope,
+			PURL:    c.PURL(),
+		}
+		if c.Constraint != "" {
+			component.Properties = append(component.Properties, cdxProperty{Name: "devinit:constraint", Value: c.Constraint})
+		}
+		component.Properties = append(component.Properties, cdxProperty{Name: "devinit:source", Value: c.Source})
+		doc.Components = append(doc.Components, component)
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// Write scans the project in dir and stores its bill of materials at
+// FileName, returning the components
+func Write(dir, project string, tool Tool) (components []Component, warnings []string, err error) {
+	components, warnings, err = Scan(dir)
+	if err != nil {
+		return nil, warnings, err
+	}
+
+	data, err := CycloneDX(project, tool, components, time.Now())
+	if err != nil {
+		return nil, warnings, err
+	}
+	path := filepath.Join(dir, filepath.FromSlash(FileName))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, warnings, err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return nil, warnings, err
+	}
+	return components, warnings, nil
+}
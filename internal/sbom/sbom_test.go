@@ -0,0 +1,232 @@
+package sbom
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeProject writes files (slash-separated paths) to a new directory
+func writeProject(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  []Component
+	}{
+		{
+			name: "npm with lockfile",
+			files: map[string]string{
+				"package.json": `{"dependencies": {"express": "^4.18.2", "@types/node": "20.1.0"}, "devDependencies": {"jest": "^29.0.0"}}`,
+				"package-lock.json": `{"packages": {
+					"": {"name": "demo"},
+					"node_modules/express": {"version": "4.18.2"},
+					"node_modules/jest": {"version": "29.7.0", "dev": true},
+					"node_modules/accepts": {"version": "1.3.8"}
+				}}`,
+				"node_modules/express/package.json": `{"dependencies": {"ignored": "1.0.0"}}`,
+			},
+			want: []Component{
+				{Ecosystem: Npm, Name: "@types/node", Version: "20.1.0", Constraint: "20.1.0", Scope: ScopeRequired, Source: "package.json"},
+				{Ecosystem: Npm, Name: "accepts", Version: "1.3.8", Scope: ScopeRequired, Source: "package-lock.json"},
+				{Ecosystem: Npm, Name: "express", Version: "4.18.2", Constraint: "^4.18.2", Scope: ScopeRequired, Source: "package.json"},
+				{Ecosystem: Npm, Name: "jest", Version: "29.7.0", Constraint: "^29.0.0", Scope: ScopeOptional, Source: "package.json"},
+			},
+		},
+		{
+			name: "poetry and requirements",
+			files: map[string]string{
+				"pyproject.toml": `[tool.poetry.dependencies]
+python = "^3.11"
+fastapi = "^0.104.0"
+SQLAlchemy = { version = "2.0.23", optional = true }
+
+[tool.poetry.group.dev.dependencies]
+pytest = "^7.4"
+`,
+				"requirements-dev.txt": "-r requirements.txt\nruff==0.1.6  # linter\nhttps://example.com/pkg.tar.gz\n",
+			},
+			want: []Component{
+				{Ecosystem: PyPI, Name: "fastapi", Constraint: "^0.104.0", Scope: ScopeRequired, Source: "pyproject.toml"},
+				{Ecosystem: PyPI, Name: "pytest", Constraint: "^7.4", Scope: ScopeOptional, Source: "pyproject.toml"},
+				{Ecosystem: PyPI, Name: "ruff", Version: "0.1.6", Constraint: "==0.1.6", Scope: ScopeOptional, Source: "requirements-dev.txt"},
+				{Ecosystem: PyPI, Name: "sqlalchemy", Version: "2.0.23", Constraint: "2.0.23", Scope: ScopeOptional, Source: "pyproject.toml"},
+			},
+		},
+		{
+			name: "PEP 621 with uv lockfile",
+			files: map[string]string{
+				"pyproject.toml": `[project]
+name = "demo"
+dependencies = [
+    "uvicorn[standard]>=0.23; python_version >= '3.8'",
+    "Typer",
+]
+
+[project.optional-dependencies]
+test = ["pytest>=7"]
+`,
+				"uv.lock": `version = 1
+
+[[package]]
+name = "demo"
+version = "0.1.0"
+source = { editable = "." }
+
+[[package]]
+name = "typer"
+version = "0.9.0"
+source = { registry = "https://pypi.org/simple" }
+`,
+			},
+			want: []Component{
+				{Ecosystem: PyPI, Name: "pytest", Constraint: ">=7", Scope: ScopeOptional, Source: "pyproject.toml"},
+				{Ecosystem: PyPI, Name: "typer", Version: "0.9.0", Scope: ScopeRequired, Source: "pyproject.toml"},
+				{Ecosystem: PyPI, Name: "uvicorn", Constraint: ">=0.23", Scope: ScopeRequired, Source: "pyproject.toml"},
+			},
+		},
+		{
+			name: "go, cargo, maven, nuget, composer and gems",
+			files: map[string]string{
+				"go.mod": "module example.com/demo\n\ngo 1.22\n\nrequire github.com/spf13/cobra v1.8.0\n\nrequire (\n\tgolang.org/x/sys v0.15.0 // indirect\n)\n",
+				"Cargo.toml": `[package]
+name = "demo"
+
+[dependencies]
+axum = "0.7"
+tokio = { version = "1", features = ["full"] }
+
+[dev-dependencies]
+"tower" = "0.4"
+`,
+				"Cargo.lock": "[[package]]\nname = \"demo\"\nversion = \"0.1.0\"\n\n[[package]]\nname = \"axum\"\nversion = \"0.7.4\"\nsource = \"registry+https://github.com/rust-lang/crates.io-index\"\n",
+				"pom.xml": `<project><dependencies>
+  <dependency><groupId>org.springframework.boot</groupId><artifactId>spring-boot-starter-web</artifactId></dependency>
+  <dependency><groupId>org.junit</groupId><artifactId>junit</artifactId><version>5.10.0</version><scope>test</scope></dependency>
+</dependencies></project>`,
+				"src/Api/Api.csproj": `<Project Sdk="Microsoft.NET.Sdk.Web"><ItemGroup>
+  <PackageReference Include="Serilog" Version="3.1.1" />
+  <PackageReference Include="StyleCop.Analyzers" Version="1.1.118" PrivateAssets="all" />
+</ItemGroup></Project>`,
+				"composer.json": `{"require": {"php": "^8.2", "ext-json": "*", "laravel/framework": "^11.0"}}`,
+				"Gemfile":       "source \"https://rubygems.org\"\ngem \"rails\", \"~> 7.1.0\"\ngroup :development, :test do\n  gem 'rspec-rails'\nend\n",
+			},
+			want: []Component{
+				{Ecosystem: Cargo, Name: "axum", Version: "0.7.4", Constraint: "0.7", Scope: ScopeRequired, Source: "Cargo.toml"},
+				{Ecosystem: Cargo, Name: "tokio", Constraint: "1", Scope: ScopeRequired, Source: "Cargo.toml"},
+				{Ecosystem: Cargo, Name: "tower", Constraint: "0.4", Scope: ScopeOptional, Source: "Cargo.toml"},
+				{Ecosystem: Composer, Group: "laravel", Name: "framework", Constraint: "^11.0", Scope: ScopeRequired, Source: "composer.json"},
+				{Ecosystem: Gem, Name: "rails", Constraint: "~> 7.1.0", Scope: ScopeRequired, Source: "Gemfile"},
+				{Ecosystem: Gem, Name: "rspec-rails", Scope: ScopeOptional, Source: "Gemfile"},
+				{Ecosystem: Golang, Name: "github.com/spf13/cobra", Version: "v1.8.0", Scope: ScopeRequired, Source: "go.mod"},
+				{Ecosystem: Golang, Name: "golang.org/x/sys", Version: "v0.15.0", Scope: ScopeRequired, Source: "go.mod"},
+				{Ecosystem: Maven, Group: "org.junit", Name: "junit", Version: "5.10.0", Scope: ScopeOptional, Source: "pom.xml"},
+				{Ecosystem: Maven, Group: "org.springframework.boot", Name: "spring-boot-starter-web", Scope: ScopeRequired, Source: "pom.xml"},
+				{Ecosystem: NuGet, Name: "Serilog", Version: "3.1.1", Constraint: "3.1.1", Scope: ScopeRequired, Source: "src/Api/Api.csproj"},
+				{Ecosystem: NuGet, Name: "StyleCop.Analyzers", Version: "1.1.118", Constraint: "1.1.118", Scope: ScopeOptional, Source: "src/Api/Api.csproj"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, warnings, err := Scan(writeProject(t, tt.files))
+			if err != nil {
+				t.Fatalf("Scan() unexpected error: %v", err)
+			}
+			if len(warnings) > 0 {
+				t.Errorf("Scan() warnings = %q, want none", warnings)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Scan() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanInvalidManifest(t *testing.T) {
+	dir := writeProject(t, map[string]string{
+		"package.json": "{",
+		"go.mod":       "module demo\n\nrequire example.com/x v1.0.0\n",
+	})
+
+	got, warnings, err := Scan(dir)
+	if err != nil {
+		t.Fatalf("Scan() unexpected error: %v", err)
+	}
+	if len(warnings) != 1 || !strings.HasPrefix(warnings[0], "package.json: ") {
+		t.Errorf("Scan() warnings = %q, want one for package.json", warnings)
+	}
+	if len(got) != 1 || got[0].Name != "example.com/x" {
+		t.Errorf("Scan() = %+v, want the go.mod dependency", got)
+	}
+}
+
+func TestCycloneDX(t *testing.T) {
+	components := []Component{
+		{Ecosystem: Npm, Name: "@types/node", Version: "20.1.0", Scope: ScopeRequired, Source: "package.json"},
+		{Ecosystem: Maven, Group: "org.junit", Name: "junit", Constraint: "[5,6)", Scope: ScopeOptional, Source: "pom.xml"},
+	}
+	data, err := CycloneDX("demo", Tool{Name: "devinit", Version: "1.0.0"}, components, time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("CycloneDX() unexpected error: %v", err)
+	}
+
+	var doc struct {
+		BOMFormat   string `json:"bomFormat"`
+		SpecVersion string `json:"specVersion"`
+		Metadata    struct {
+			Timestamp string `json:"timestamp"`
+			Component struct {
+				Name string `json:"name"`
+			} `json:"component"`
+		} `json:"metadata"`
+		Components []struct {
+			Name       string `json:"name"`
+			PURL       string `json:"purl"`
+			Scope      string `json:"scope"`
+			Properties []struct {
+				Name  string `json:"name"`
+				Value string `json:"value"`
+			} `json:"properties"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("CycloneDX() is not JSON: %v", err)
+	}
+
+	if doc.BOMFormat != "CycloneDX" || doc.SpecVersion != "1.5" || doc.Metadata.Component.Name != "demo" || doc.Metadata.Timestamp != "2026-01-02T03:04:05Z" {
+		t.Errorf("document header = %+v", doc)
+	}
+	if len(doc.Components) != 2 {
+		t.Fatalf("CycloneDX() has %d components, want 2", len(doc.Components))
+	}
+	if purl := doc.Components[0].PURL; purl != "pkg:npm/%40types/node@20.1.0" {
+		t.Errorf("npm purl = %q, want %q", purl, "pkg:npm/%40types/node@20.1.0")
+	}
+	junit := doc.Components[1]
+	if junit.PURL != "pkg:maven/org.junit/junit" || junit.Scope != ScopeOptional {
+		t.Errorf("maven component = %+v, want an optional pkg:maven/org.junit/junit", junit)
+	}
+	if len(junit.Properties) != 2 || junit.Properties[0].Value != "[5,6)" || junit.Properties[1].Value != "pom.xml" {
+		t.Errorf("maven properties = %+v, want the constraint and source", junit.Properties)
+	}
+}
@@ -0,0 +1,130 @@
+package template
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// Branch identifies an {{ if }}, {{ with }} or {{ range }} action of a
+// template file
+type Branch struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Action string `json:"action"` // e.g. `if eq .Database "postgres"`
+}
+
+// BranchRecorder is told which way the branches of rendered template files
+// go: taken is true when the body of an if or with runs or a range has
+// elements, false when the else part (possibly empty) runs
+type BranchRecorder interface {
+	RecordBranch(branch Branch, taken bool)
+}
+
+// branchFunc is the function instrumented templates call to record branches
+const branchFunc = "devinitRecordBranch"
+
+// FindBranches returns the branches of a template file written with delims
+// (see Template.FileDelimiters), in the order they appear. Functions are not
+// checked, so files can be inspected without a renderer.
+func FindBranches(file, text string, delims []string) ([]Branch, error) {
+	tree := parse.New(file)
+	tree.Mode = parse.SkipFuncCheck
+	trees := make(map[string]*parse.Tree)
+	left, right := splitDelims(delims)
+	text, err := expandRawBlocks(text, defaultDelim(left, "{{"), defaultDelim(right, "}}"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+	if _, err := tree.Parse(text, left, right, trees); err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	var branches []Branch
+	for _, t := range trees {
+		walkBranches(t.Root, func(node *parse.BranchNode, kind string) {
+			branches = append(branches, newBranch(file, text, node, kind))
+		})
+	}
+	sort.SliceStable(branches, func(i, j int) bool { return branches[i].Line < branches[j].Line })
+	return branches, nil
+}
+
+func newBranch(file, text string, node *parse.BranchNode, kind string) Branch {
+	line := 1 + strings.Count(text[:min(int(node.Pos), len(text))], "\n")
+	return Branch{File: file, Line: line, Action: kind + " " + node.Pipe.String()}
+}
+
+// walkBranches calls fn for every branch node below node
+func walkBranches(node parse.Node, fn func(node *parse.BranchNode, kind string)) {
+	var branch *parse.BranchNode
+	var kind string
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			walkBranches(child, fn)
+		}
+		return
+	case *parse.IfNode:
+		branch, kind = &n.BranchNode, "if"
+	case *parse.WithNode:
+		branch, kind = &n.BranchNode, "with"
+	case *parse.RangeNode:
+		branch, kind = &n.BranchNode, "range"
+	default:
+		return
+	}
+
+	fn(branch, kind)
+	walkBranches(branch.List, fn)
+	walkBranches(branch.ElseList, fn)
+}
+
+// instrumentBranches makes every branch body and else part (added when
+// missing) of tmpl start with a call to branchFunc, passing the index of the
+// branch in the returned slice. branchFunc must be among the template's
+// functions before it is parsed.
+func instrumentBranches(tmpl *template.Template, file, text string) []Branch {
+	var branches []Branch
+	for _, t := range tmpl.Templates() {
+		if t.Tree == nil {
+			continue
+		}
+		walkBranches(t.Tree.Root, func(node *parse.BranchNode, kind string) {
+			id := len(branches)
+			branches = append(branches, newBranch(file, text, node, kind))
+			if node.ElseList == nil {
+				node.ElseList = &parse.ListNode{NodeType: parse.NodeList, Pos: node.Pos}
+			}
+			node.List.Nodes = append([]parse.Node{branchAction(node.Pos, id, true)}, node.List.Nodes...)
+			node.ElseList.Nodes = append([]parse.Node{branchAction(node.Pos, id, false)}, node.ElseList.Nodes...)
+		})
+	}
+	return branches
+}
+
+// branchAction returns the node of {{ devinitRecordBranch id taken }}
+func branchAction(pos parse.Pos, id int, taken bool) parse.Node {
+	return &parse.ActionNode{
+		NodeType: parse.NodeAction,
+		Pos:      pos,
+		Pipe: &parse.PipeNode{
+			NodeType: parse.NodePipe,
+			Pos:      pos,
+			Cmds: []*parse.CommandNode{{
+				NodeType: parse.NodeCommand,
+				Pos:      pos,
+				Args: []parse.Node{
+					parse.NewIdentifier(branchFunc).SetPos(pos),
+					&parse.NumberNode{NodeType: parse.NodeNumber, Pos: pos, IsInt: true, Int64: int64(id), Text: fmt.Sprint(id)},
+					&parse.BoolNode{NodeType: parse.NodeBool, Pos: pos, True: taken},
+				},
+			}},
+		},
+	}
+}
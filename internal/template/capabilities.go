@@ -0,0 +1,65 @@
+package template
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Capabilities are the features of devinit templates may list under needs:
+// in template.yaml, with what they cover. Older devinit binaries ignore the
+// template.yaml keys of features they lack, so templates relying on a
+// feature name it to fail with an explanation instead of generating
+// projects that are silently wrong. Names are only ever added.
+var Capabilities = map[string]string{
+	"hooks":               "pre_generate and post_generate hooks, with name, after, when and network",
+	"globs":               "file sources with glob patterns, e.g. migrations/*.sql",
+	"jinja2-engine":       "engine: jinja2",
+	"delimiters":          "delimiters of the template and of files",
+	"raw-blocks":          "{{/* raw */}} blocks and the raw and literal helpers",
+	"strict-variables":    "rendering fails on references to undeclared variables",
+	"profiles":            "profiles presetting variables",
+	"markers":             "devinit:if comment markers in files",
+	"write-modes":         "file modes append, patch and merge",
+	"provenance":          "provenance headers",
+	"checks":              "requirements.checks: disk_free, memory, url, docker_network and script",
+	"smoke-test":          "smoke_test",
+	"tests":               "tests with matrix and expect for devinit templates test",
+	"openapi":             "the OpenAPI contract of --from-openapi",
+	"proto":               "the protobuf contract of --from-proto",
+	"dbschema":            "the database schema of --from-db",
+	"default-expressions": "variable defaults computed from other variables",
+	"choice-labels":       "choices with a label and a description",
+}
+
+// SupportedCapabilities returns the names of Capabilities, sorted
+func SupportedCapabilities() []string {
+	names := make([]string, 0, len(Capabilities))
+	for name := range Capabilities {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// MissingCapabilities returns the capabilities the template needs that this
+// devinit lacks, in the order they are listed
+func (t *Template) MissingCapabilities() []string {
+	var missing []string
+	for _, need := range t.Needs {
+		if _, ok := Capabilities[need]; !ok {
+			missing = append(missing, need)
+		}
+	}
+	return missing
+}
+
+// checkCapabilities fails for templates needing capabilities this devinit
+// lacks, telling to upgrade
+func checkCapabilities(tmpl *Template) error {
+	missing := tmpl.MissingCapabilities()
+	if len(missing) == 0 {
+		return nil
+	}
+	return fmt.Errorf("template %s needs %s, which this devinit does not support; upgrade devinit", tmpl.ID, strings.Join(missing, ", "))
+}
@@ -0,0 +1,133 @@
+package template
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/template/parse"
+)
+
+// DefaultExpression returns the expression of a default computed from other
+// variables: a string default with actions, such as
+// '{{ if eq .Database "mysql" }}3306{{ else }}5432{{ end }}', or ""
+func (v Variable) DefaultExpression() string {
+	if s, ok := v.Default.(string); ok && strings.Contains(s, "{{") {
+		return s
+	}
+	return ""
+}
+
+// DefaultDependencies returns the variables the expression default of a
+// variable refers to, sorted: template variables (.Variables.name) and
+// common variables (.Database)
+func (v Variable) DefaultDependencies() ([]string, error) {
+	expr := v.DefaultExpression()
+	if expr == "" {
+		return nil, nil
+	}
+	tree := parse.New("default")
+	tree.Mode = parse.SkipFuncCheck
+	trees := make(map[string]*parse.Tree)
+	if _, err := tree.Parse(expr, "", "", trees); err != nil {
+		return nil, err
+	}
+
+	common := make(map[string]bool, len(CommonVariables))
+	for _, name := range CommonVariables {
+		common[name] = true
+	}
+	seen := make(map[string]bool)
+	add := func(ident []string) {
+		switch {
+		case len(ident) >= 2 && ident[0] == "Variables":
+			seen[ident[1]] = true
+		case len(ident) >= 1 && common[ident[0]]:
+			seen[ident[0]] = true
+		}
+	}
+	for _, t := range trees {
+		walkNodes(t.Root, func(node parse.Node) {
+			switch n := node.(type) {
+			case *parse.FieldNode:
+				add(n.Ident)
+			case *parse.VariableNode:
+				if len(n.Ident) > 0 && n.Ident[0] == "$" {
+					add(n.Ident[1:])
+				}
+			}
+		})
+	}
+
+	deps := make([]string, 0, len(seen))
+	for name := range seen {
+		deps = append(deps, name)
+	}
+	sort.Strings(deps)
+	return deps, nil
+}
+
+// DefaultOrder returns the variables with expression defaults in the order
+// they are evaluated: each after the expression defaults it refers to. It
+// fails on expressions that do not parse and on defaults referring to each
+// other in a cycle, which has no order.
+func (t *Template) DefaultOrder() ([]string, error) {
+	deps := make(map[string][]string)
+	for name, variable := range t.Variables {
+		if variable.DefaultExpression() == "" {
+			continue
+		}
+		refs, err := variable.DefaultDependencies()
+		if err != nil {
+			return nil, fmt.Errorf("variable %s has an invalid default: %w", name, err)
+		}
+		deps[name] = refs
+	}
+
+	names := make([]string, 0, len(deps))
+	for name := range deps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[string]int, len(deps))
+	var order, path []string
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case done:
+			return nil
+		case visiting:
+			start := 0
+			for path[start] != name {
+				start++
+			}
+			cycle := append(append([]string(nil), path[start:]...), name)
+			return fmt.Errorf("variable defaults refer to each other in a cycle: %s", strings.Join(cycle, " → "))
+		}
+		state[name] = visiting
+		path = append(path, name)
+		for _, dep := range deps[name] {
+			// Other variables have their values before any expression
+			if _, ok := deps[dep]; !ok {
+				continue
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = done
+		order = append(order, name)
+		return nil
+	}
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
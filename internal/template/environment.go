@@ -0,0 +1,50 @@
+package template
+
+import (
+	"os"
+	"os/user"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Environment describes where, when and by whom a project is generated, so
+// templates can tailor platform-specific snippets and stamp provenance
+// comments. Its fields are available directly on the context ({{ .OS }}).
+type Environment struct {
+	OS             string `json:"os" yaml:"os"`     // runtime.GOOS, e.g. linux, darwin, windows
+	Arch           string `json:"arch" yaml:"arch"` // runtime.GOARCH, e.g. amd64, arm64
+	User           string `json:"user,omitempty" yaml:"user,omitempty"`
+	Year           int    `json:"year" yaml:"year"`
+	DevinitVersion string `json:"devinit_version,omitempty" yaml:"devinit_version,omitempty"`
+}
+
+// CurrentEnvironment returns the environment of this process
+func CurrentEnvironment(devinitVersion string) Environment {
+	return Environment{
+		OS:             runtime.GOOS,
+		Arch:           runtime.GOARCH,
+		User:           currentUser(),
+		Year:           time.Now().Year(),
+		DevinitVersion: devinitVersion,
+	}
+}
+
+// currentUser returns the login name of the current user, without the
+// domain on Windows, or empty when it cannot be determined
+func currentUser() string {
+	name := ""
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	if name == "" {
+		name = os.Getenv("USER")
+	}
+	if name == "" {
+		name = os.Getenv("USERNAME")
+	}
+	if i := strings.LastIndex(name, `\`); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
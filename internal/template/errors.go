@@ -0,0 +1,116 @@
+package template
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// snippetLines is the number of lines shown around the line of an error
+const snippetLines = 2
+
+// RenderError is an error of a template file at a position, shown with the
+// lines around it
+type RenderError struct {
+	File       string
+	Line       int
+	Column     int    // from 1; 0 when unknown
+	Expression string // the action that failed, when known
+	Message    string
+
+	text string
+}
+
+var (
+	// goTemplateError matches the errors of text/template, e.g.
+	// `template: a.tmpl:3:14: executing "a.tmpl" at <.Foo>: message`
+	goTemplateError = regexp.MustCompile(`(?s)^template: [^:]*:(\d+)(?::(\d+))?: (?:executing "[^"]*" at <(.*?)>: )?(.*)$`)
+	// lineError matches the errors of Jinja2 templates and raw blocks, e.g.
+	// `a.tmpl: line 3: message`
+	lineError = regexp.MustCompile(`(?s)(?:^|: )line (\d+): (.*)$`)
+)
+
+// positioned returns err, of rendering text from file, as a RenderError when
+// it tells where it occurred, else wrapped with what failed
+func positioned(file, text, failed string, err error) error {
+	e := &RenderError{File: file, text: text}
+	msg := err.Error()
+	if m := goTemplateError.FindStringSubmatch(msg); m != nil {
+		e.Line, _ = strconv.Atoi(m[1])
+		if m[2] != "" {
+			e.Column, _ = strconv.Atoi(m[2])
+			e.Column++
+		}
+		if m[3] != "" {
+			e.Expression = m[3]
+		}
+		e.Message = m[4]
+	} else if m := lineError.FindStringSubmatch(msg); m != nil {
+		e.Line, _ = strconv.Atoi(m[1])
+		e.Message = m[2]
+	} else {
+		return fmt.Errorf("failed to %s template: %w", failed, err)
+	}
+	return e
+}
+
+// Error returns the position and message, then the snippet
+func (e *RenderError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Position())
+	b.WriteString(": ")
+	b.WriteString(e.Message)
+	if e.Expression != "" {
+		fmt.Fprintf(&b, " (in `%s`)", e.Expression)
+	}
+	if snippet := e.Snippet(); snippet != "" {
+		b.WriteString("\n")
+		b.WriteString(snippet)
+	}
+	return b.String()
+}
+
+// Position returns file:line, or file:line:column when the column is known
+func (e *RenderError) Position() string {
+	if e.Column > 0 {
+		return fmt.Sprintf("%s:%d:%d", e.File, e.Line, e.Column)
+	}
+	return fmt.Sprintf("%s:%d", e.File, e.Line)
+}
+
+// Snippet returns the lines around the line of the error, numbered, the line
+// itself marked with > and the column with ^
+func (e *RenderError) Snippet() string {
+	lines := strings.Split(strings.TrimSuffix(e.text, "\n"), "\n")
+	if e.Line < 1 || e.Line > len(lines) {
+		return ""
+	}
+
+	first, last := max(1, e.Line-snippetLines), min(len(lines), e.Line+snippetLines)
+	width := len(strconv.Itoa(last))
+	var b strings.Builder
+	for n := first; n <= last; n++ {
+		marker := " "
+		if n == e.Line {
+			marker = ">"
+		}
+		fmt.Fprintf(&b, "  %s %*d | %s\n", marker, width, n, strings.TrimRight(lines[n-1], "\r"))
+		if n == e.Line && e.Column > 0 {
+			fmt.Fprintf(&b, "    %*s | %s^\n", width, "", caretIndent(lines[n-1], e.Column))
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// caretIndent returns the whitespace putting a caret under column (from 1,
+// in bytes) of line, keeping its tabs
+func caretIndent(line string, column int) string {
+	prefix := line[:min(column-1, len(line))]
+	return strings.Map(func(r rune) rune {
+		if r == '\t' {
+			return '\t'
+		}
+		return ' '
+	}, prefix)
+}
@@ -0,0 +1,248 @@
+package template
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template/parse"
+)
+
+// Go template files output text meant for other template engines, such as
+// ${{ github.sha }} in GitHub Actions workflows or {{ name }} in generated
+// Jinja2 templates, in one of these ways:
+//
+//   - between {{/* raw */}} and {{/* endraw */}} comments, output as is
+//   - with the literal helper, {{ literal "{{ name }}" }}
+//   - with the raw helper, ${{ raw "github.sha" }}, which wraps an expression
+//     in {{ and }}
+//   - with other delimiters for the whole file (Template.Delimiters)
+//
+// Jinja2 templates have {% raw %} blocks of their own.
+
+// wrapExpression is the raw helper: the expression between {{ and }}
+func wrapExpression(expr string) string {
+	return "{{ " + expr + " }}"
+}
+
+// rawMarker matches the {{/* raw */}} and {{/* endraw */}} comments around
+// raw blocks written with the given delimiters; the first group is set for
+// endraw
+func rawMarker(left, right string) *regexp.Regexp {
+	return regexp.MustCompile(regexp.QuoteMeta(left) + `(?:- )?\s*/\*\s*(end)?raw\s*\*/\s*(?: -)?` + regexp.QuoteMeta(right))
+}
+
+// rawBlocks returns the start and end offsets of the content of the raw
+// blocks of a Go template. Markers within a raw block are part of it.
+func rawBlocks(text, left, right string) ([][2]int, error) {
+	if !strings.Contains(text, "raw") {
+		return nil, nil
+	}
+
+	var blocks [][2]int
+	start := -1
+	for _, loc := range rawMarker(left, right).FindAllStringSubmatchIndex(text, -1) {
+		end := loc[2] >= 0
+		switch {
+		case !end && start < 0:
+			start = loc[1]
+		case end && start >= 0:
+			blocks = append(blocks, [2]int{start, loc[0]})
+			start = -1
+		case end:
+			return nil, fmt.Errorf("line %d: endraw without raw", lineAt(text, loc[0]))
+		}
+	}
+	if start >= 0 {
+		return nil, fmt.Errorf("line %d: raw block without endraw", lineAt(text, start))
+	}
+	return blocks, nil
+}
+
+// expandRawBlocks rewrites the left delimiters in the raw blocks of a Go
+// template as actions printing them, so the blocks render as they are
+// written. Lines stay where they are.
+func expandRawBlocks(text, left, right string) (string, error) {
+	blocks, err := rawBlocks(text, left, right)
+	if err != nil || len(blocks) == 0 {
+		return text, err
+	}
+
+	escaped := left + " " + strconv.Quote(left) + " " + right
+	var b strings.Builder
+	last := 0
+	for _, block := range blocks {
+		b.WriteString(text[last:block[0]])
+		b.WriteString(strings.ReplaceAll(text[block[0]:block[1]], left, escaped))
+		last = block[1]
+	}
+	b.WriteString(text[last:])
+	return b.String(), nil
+}
+
+// jinjaRawBlock matches the {% raw %} blocks of Jinja2 templates
+var jinjaRawBlock = regexp.MustCompile(`(?s)\{%-?\s*raw\s*-?%\}.*?\{%-?\s*endraw\s*-?%\}`)
+
+// helperCall matches the start of actions calling the raw or literal helper
+var helperCall = regexp.MustCompile(`^-?\s*(raw|literal)\s`)
+
+// Conflict is a sequence of a template file that looks meant for the
+// generated file, such as ${{ of GitHub Actions, but would be rendered by
+// the template engine
+type Conflict struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+	Text string `json:"text"` // the line, trimmed
+}
+
+// FindConflicts returns the conflicts of a template file written with delims
+// (see Template.FileDelimiters) for the given engine: actions right after a
+// $, outside raw blocks, that neither print a string nor call the raw or
+// literal helper. Go templates that fail to parse are scanned as text.
+func FindConflicts(file, text string, delims []string, engine string) ([]Conflict, error) {
+	left, right := splitDelims(delims)
+	if engine == EngineJinja2 {
+		return scanConflicts(file, text, "{{", jinjaRawBlock.FindAllStringIndex(text, -1)), nil
+	}
+
+	expanded, err := expandRawBlocks(text, defaultDelim(left, "{{"), defaultDelim(right, "}}"))
+	if err != nil {
+		return nil, err
+	}
+	tree := parse.New(file)
+	tree.Mode = parse.SkipFuncCheck
+	trees := make(map[string]*parse.Tree)
+	if _, err := tree.Parse(expanded, left, right, trees); err != nil {
+		blocks, _ := rawBlocks(text, defaultDelim(left, "{{"), defaultDelim(right, "}}"))
+		var ranges [][]int
+		for _, block := range blocks {
+			ranges = append(ranges, []int{block[0], block[1]})
+		}
+		if conflicts := scanConflicts(file, text, defaultDelim(left, "{{"), ranges); len(conflicts) > 0 {
+			return conflicts, nil
+		}
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	var conflicts []Conflict
+	for _, t := range trees {
+		walkLists(t.Root, func(list *parse.ListNode) {
+			for i, node := range list.Nodes[1:] {
+				prev, ok := list.Nodes[i].(*parse.TextNode)
+				action, isAction := node.(*parse.ActionNode)
+				if !ok || !isAction || !bytes.HasSuffix(prev.Text, []byte("$")) || escapes(action) {
+					continue
+				}
+				conflicts = append(conflicts, newConflict(file, text, lineAt(expanded, int(action.Pos))))
+			}
+		})
+	}
+	sort.SliceStable(conflicts, func(i, j int) bool { return conflicts[i].Line < conflicts[j].Line })
+	return conflicts, nil
+}
+
+// escapes reports whether an action prints a string constant or calls the
+// raw or literal helper
+func escapes(action *parse.ActionNode) bool {
+	if len(action.Pipe.Cmds) == 0 || len(action.Pipe.Cmds[0].Args) == 0 {
+		return false
+	}
+	switch arg := action.Pipe.Cmds[0].Args[0].(type) {
+	case *parse.StringNode:
+		return true
+	case *parse.IdentifierNode:
+		return arg.Ident == "raw" || arg.Ident == "literal"
+	}
+	return false
+}
+
+// walkLists calls fn for node and every list below it
+func walkLists(node parse.Node, fn func(list *parse.ListNode)) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		fn(n)
+		for _, child := range n.Nodes {
+			walkLists(child, fn)
+		}
+	case *parse.IfNode:
+		walkLists(n.List, fn)
+		walkLists(n.ElseList, fn)
+	case *parse.RangeNode:
+		walkLists(n.List, fn)
+		walkLists(n.ElseList, fn)
+	case *parse.WithNode:
+		walkLists(n.List, fn)
+		walkLists(n.ElseList, fn)
+	}
+}
+
+// scanConflicts finds the conflicts of text as a whole, the raw blocks in
+// skip left out: $ right before the left delimiter, not quoted and not
+// followed by a helper call
+func scanConflicts(file, text, left string, skip [][]int) []Conflict {
+	masked := []byte(text)
+	for _, loc := range skip {
+		for i := loc[0]; i < loc[1]; i++ {
+			if masked[i] != '\n' {
+				masked[i] = ' '
+			}
+		}
+	}
+
+	var conflicts []Conflict
+	seq := []byte("$" + left)
+	for offset := 0; ; {
+		i := bytes.Index(masked[offset:], seq)
+		if i < 0 {
+			break
+		}
+		i += offset
+		offset = i + len(seq)
+		if i > 0 && (masked[i-1] == '"' || masked[i-1] == '\'') || helperCall.Match(masked[offset:]) {
+			continue
+		}
+		conflicts = append(conflicts, newConflict(file, text, lineAt(text, i)))
+	}
+	return conflicts
+}
+
+// newConflict returns the conflict at line of text
+func newConflict(file, text string, line int) Conflict {
+	lines := strings.Split(text, "\n")
+	return Conflict{File: file, Line: line, Text: strings.TrimSpace(lines[min(line, len(lines))-1])}
+}
+
+// Lint returns the conflicts of the files of the template that are rendered
+func (t *Template) Lint() ([]Conflict, error) {
+	var conflicts []Conflict
+	seen := make(map[string]bool)
+	for _, file := range t.Files {
+		if !strings.HasSuffix(file.Source, ".tmpl") || seen[file.Source] {
+			continue
+		}
+		seen[file.Source] = true
+
+		path, _ := t.SourcePath(file.Source)
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		found, err := FindConflicts(file.Source, string(content), t.FileDelimiters(file), t.Engine)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file.Source, err)
+		}
+		conflicts = append(conflicts, found...)
+	}
+	return conflicts, nil
+}
+
+// lineAt returns the line of text at offset i, counting from 1
+func lineAt(text string, i int) int {
+	return 1 + strings.Count(text[:min(i, len(text))], "\n")
+}
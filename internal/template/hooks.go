@@ -0,0 +1,82 @@
+package template
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SortHooks returns the hooks of a stage in the order they run: by Order,
+// then by position, with every hook after those it names in After
+func SortHooks(hooks []Hook) ([]Hook, error) {
+	names := make(map[string]bool, len(hooks))
+	for _, hook := range hooks {
+		if hook.Name == "" {
+			continue
+		}
+		if names[hook.Name] {
+			return nil, fmt.Errorf("duplicate hook name %q", hook.Name)
+		}
+		names[hook.Name] = true
+	}
+	for _, hook := range hooks {
+		for _, dep := range hook.After {
+			if !names[dep] {
+				return nil, fmt.Errorf("hook %s runs after unknown hook %q", hookLabel(hook), dep)
+			}
+			if dep == hook.Name {
+				return nil, fmt.Errorf("hook %s runs after itself", hook.Name)
+			}
+		}
+	}
+
+	pending := make([]Hook, len(hooks))
+	copy(pending, hooks)
+	sort.SliceStable(pending, func(i, j int) bool { return pending[i].Order < pending[j].Order })
+
+	sorted := make([]Hook, 0, len(hooks))
+	done := make(map[string]bool, len(hooks))
+	for len(pending) > 0 {
+		next := -1
+		for i, hook := range pending {
+			if allDone(hook.After, done) {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			var cycle []string
+			for _, hook := range pending {
+				cycle = append(cycle, hookLabel(hook))
+			}
+			return nil, fmt.Errorf("hooks %s run after each other", strings.Join(cycle, ", "))
+		}
+
+		hook := pending[next]
+		sorted = append(sorted, hook)
+		if hook.Name != "" {
+			done[hook.Name] = true
+		}
+		pending = append(pending[:next], pending[next+1:]...)
+	}
+
+	return sorted, nil
+}
+
+// allDone reports whether all names are in done
+func allDone(names []string, done map[string]bool) bool {
+	for _, name := range names {
+		if !done[name] {
+			return false
+		}
+	}
+	return true
+}
+
+// hookLabel names a hook in errors: its name, else its command
+func hookLabel(hook Hook) string {
+	if hook.Name != "" {
+		return hook.Name
+	}
+	return fmt.Sprintf("%q", hook.Run)
+}
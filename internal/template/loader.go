@@ -2,15 +2,48 @@ package template
 
 import (
 	"fmt"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/renan-dev/devinit/internal/errcode"
+	"github.com/renan-dev/devinit/internal/ignore"
+	"github.com/renan-dev/devinit/internal/merge"
 	"gopkg.in/yaml.v3"
 )
 
+// versionsDir is the directory inside a template holding other versions
+const versionsDir = "versions"
+
+// IndexFile is the optional file at the root of a templates directory listing
+// its templates, so remote and registry stores need not be walked
+const IndexFile = "index.yaml"
+
+// maxTemplateDepth is how many directories deep below the templates directory
+// Scan looks for templates (language/framework is two)
+const maxTemplateDepth = 4
+
+// Index is the content of IndexFile
+type Index struct {
+	Templates []string `yaml:"templates"`
+}
+
+// tagPattern is the allowed format of template tags
+var tagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// profilePattern is the allowed format of profile names
+var profilePattern = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
 // Loader loads templates from the filesystem
 type Loader struct {
 	templatesDir string
+	overlay      string
+	fallback     func() string
 }
 
 // NewLoader creates a new template loader
@@ -20,60 +53,305 @@ func NewLoader(templatesDir string) *Loader {
 	}
 }
 
+// SetOverlay layers a project's templates directory over the templates
+// directory. A template there with its own template.yaml replaces the one of
+// the same name; one without only overrides single files: files/<src> in it
+// replaces the file the template's src points to (see Template.Overrides).
+func (l *Loader) SetOverlay(dir string) {
+	l.overlay = dir
+}
+
+// SetFallback sets the store searched for versions of templates that the
+// templates directory lacks, such as the built-in templates behind the user
+// store, so that versions of both can be selected. It is only called when a
+// version is missing and returns "" when there is no such store.
+func (l *Loader) SetFallback(fallback func() string) {
+	l.fallback = fallback
+}
+
+// fallbackLoader returns a loader of the fallback store, or nil
+func (l *Loader) fallbackLoader() *Loader {
+	if l.fallback == nil {
+		return nil
+	}
+	dir := l.fallback()
+	if dir == "" || dir == l.templatesDir {
+		return nil
+	}
+	return NewLoader(dir)
+}
+
+// root returns the directory a template is loaded from: the overlay when it
+// holds a full template of that name
+func (l *Loader) root(name string) string {
+	if l.overlay != "" {
+		if _, err := os.Stat(filepath.Join(l.overlay, name, "template.yaml")); err == nil {
+			return l.overlay
+		}
+	}
+	return l.templatesDir
+}
+
 // Load loads a template by name (e.g., "python/fastapi")
 func (l *Loader) Load(name string) (*Template, error) {
-	templatePath := filepath.Join(l.templatesDir, name)
+	tmpl, err := l.load(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := l.applyOverrides(tmpl, name, true); err != nil {
+		return nil, errcode.New(errcode.TemplateInvalid, fmt.Errorf("invalid overrides: %w", err))
+	}
+	return tmpl, nil
+}
+
+// load loads a template by name without file overrides
+func (l *Loader) load(name string) (*Template, error) {
+	templatePath := filepath.Join(l.root(name), name)
 
 	// Check if template directory exists
 	if _, err := os.Stat(templatePath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("template not found: %s", name)
+		return nil, errcode.New(errcode.TemplateNotFound, fmt.Errorf("template not found: %s", name))
 	}
 
 	// Load template.yaml
 	metadataPath := filepath.Join(templatePath, "template.yaml")
 	data, err := os.ReadFile(metadataPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read template.yaml: %w", err)
+		return nil, errcode.New(errcode.TemplateInvalid, fmt.Errorf("failed to read template.yaml: %w", err))
 	}
 
 	// Parse YAML
 	var tmpl Template
 	if err := yaml.Unmarshal(data, &tmpl); err != nil {
-		return nil, fmt.Errorf("failed to parse template.yaml: %w", err)
+		// The keys of capabilities this devinit lacks may not parse
+		var needs struct {
+			Needs []string `yaml:"needs"`
+		}
+		if yaml.Unmarshal(data, &needs) == nil {
+			if err := checkCapabilities(&Template{ID: name, Needs: needs.Needs}); err != nil {
+				return nil, errcode.New(errcode.TemplateInvalid, err)
+			}
+		}
+		return nil, errcode.New(errcode.TemplateInvalid, fmt.Errorf("failed to parse template.yaml: %w", err))
 	}
 
-	// Store template path
+	// Store template reference and path
+	tmpl.ID = name
 	tmpl.Path = templatePath
 
+	// Before anything else, which may fail for want of the capability
+	if err := checkCapabilities(&tmpl); err != nil {
+		return nil, errcode.New(errcode.TemplateInvalid, err)
+	}
+
+	if err := l.expandFiles(&tmpl); err != nil {
+		return nil, errcode.New(errcode.TemplateInvalid, fmt.Errorf("invalid template: %w", err))
+	}
+
 	// Validate template
 	if err := l.validate(&tmpl); err != nil {
-		return nil, fmt.Errorf("invalid template: %w", err)
+		return nil, errcode.New(errcode.TemplateInvalid, fmt.Errorf("invalid template: %w", err))
 	}
 
 	return &tmpl, nil
 }
 
-// List returns all available templates
+// LoadVersion loads a specific version of a template. The current version
+// lives at the template root; older (or newer) versions are stored as full
+// templates under <template>/versions/<version>/. Versions missing there are
+// looked up in the fallback store.
+func (l *Loader) LoadVersion(name, version string) (*Template, error) {
+	tmpl, err := l.Load(name)
+	if err != nil {
+		if fallback := l.fallbackLoader(); fallback != nil && version != "" && errcode.Of(err) == errcode.TemplateNotFound {
+			return fallback.LoadVersion(name, version)
+		}
+		return nil, err
+	}
+
+	version = strings.TrimPrefix(version, "v")
+	if version == "" || version == strings.TrimPrefix(tmpl.Version, "v") {
+		return tmpl, nil
+	}
+
+	versionPath := filepath.Join(name, versionsDir, version)
+	if _, err := os.Stat(filepath.Join(l.root(name), versionPath)); os.IsNotExist(err) {
+		if fallback := l.fallbackLoader(); fallback != nil {
+			if versioned, err := fallback.LoadVersion(name, version); err == nil {
+				return versioned, nil
+			}
+		}
+		return nil, errcode.New(errcode.TemplateNotFound, fmt.Errorf("version %s of template %s not found", version, name))
+	}
+
+	versioned, err := l.load(versionPath)
+	if err != nil {
+		return nil, err
+	}
+	versioned.ID = name
+
+	// Overrides follow the template from version to version; those of files
+	// a version lacks do not apply to it
+	if err := l.applyOverrides(versioned, name, false); err != nil {
+		return nil, errcode.New(errcode.TemplateInvalid, fmt.Errorf("invalid overrides: %w", err))
+	}
+
+	return versioned, nil
+}
+
+// Versions returns all versions available for a template, current first,
+// followed by those only the fallback store has
+func (l *Loader) Versions(name string) ([]string, error) {
+	tmpl, err := l.Load(name)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := []string{tmpl.Version}
+
+	entries, err := os.ReadDir(filepath.Join(tmpl.Path, versionsDir))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read versions: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && entry.Name() != tmpl.Version {
+			versions = append(versions, entry.Name())
+		}
+	}
+
+	if fallback := l.fallbackLoader(); fallback != nil {
+		others, _ := fallback.Versions(name)
+		for _, version := range others {
+			if !containsVersion(versions, version) {
+				versions = append(versions, version)
+			}
+		}
+	}
+
+	return versions, nil
+}
+
+// containsVersion reports whether versions holds version, with or without
+// a leading v
+func containsVersion(versions []string, version string) bool {
+	for _, v := range versions {
+		if strings.TrimPrefix(v, "v") == strings.TrimPrefix(version, "v") {
+			return true
+		}
+	}
+	return false
+}
+
+// List returns all available templates, including the full templates of
+// the overlay. A store with an index file is not walked: the index is
+// trusted to be complete.
 func (l *Loader) List() ([]string, error) {
 	var templates []string
+	index, err := l.ReadIndex()
+	switch {
+	case err != nil:
+		return nil, err
+	case index != nil:
+		templates = index.Templates
+	default:
+		if templates, err = l.Scan(); err != nil {
+			return nil, err
+		}
+	}
+	if l.overlay == "" {
+		return templates, nil
+	}
+
+	overlaid, err := NewLoader(l.overlay).Scan()
+	if err != nil {
+		return nil, err
+	}
+	listed := make(map[string]bool, len(templates))
+	for _, name := range templates {
+		listed[name] = true
+	}
+	for _, name := range overlaid {
+		if !listed[name] {
+			templates = append(templates, name)
+		}
+	}
+	return templates, nil
+}
 
-	// Walk through templates directory
-	err := filepath.Walk(l.templatesDir, func(path string, info os.FileInfo, err error) error {
+// applyOverrides fills in the overrides the overlay holds for the template
+// name, unless tmpl comes from the overlay itself. Strict loads reject
+// overrides of files the template does not have, which are usually typos.
+func (l *Loader) applyOverrides(tmpl *Template, name string, strict bool) error {
+	if l.overlay == "" || l.root(name) == l.overlay {
+		return nil
+	}
+	dir := filepath.Join(l.overlay, name, "files")
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil
+	}
+
+	sources := make(map[string]bool, len(tmpl.Files))
+	for _, file := range tmpl.Files {
+		sources[file.Source] = true
+	}
+
+	overrides := make(map[string]string)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
 		if err != nil {
 			return err
 		}
+		source := filepath.ToSlash(rel)
+		switch {
+		case sources[source]:
+			overrides[source] = path
+		case strict:
+			return fmt.Errorf("%s overrides no file of %s", filepath.Join(name, "files", rel), name)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(overrides) > 0 {
+		tmpl.Overrides = overrides
+	}
+	return nil
+}
 
-		// Check if this is a template.yaml file
-		if !info.IsDir() && info.Name() == "template.yaml" {
-			// Get relative path from templates dir
-			relPath, err := filepath.Rel(l.templatesDir, filepath.Dir(path))
-			if err != nil {
-				return err
-			}
+// Scan walks the templates directory for templates, ignoring any index. It
+// does not descend into templates (their files, hooks and stored versions),
+// hidden directories or below maxTemplateDepth, so its cost depends on the
+// number of templates rather than the size of their files.
+func (l *Loader) Scan() ([]string, error) {
+	var templates []string
 
-			templates = append(templates, relPath)
+	err := filepath.WalkDir(l.templatesDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() || path == l.templatesDir {
+			return nil
 		}
 
+		relPath, err := filepath.Rel(l.templatesDir, path)
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(entry.Name(), ".") || entry.Name() == "files" || entry.Name() == versionsDir {
+			return filepath.SkipDir
+		}
+
+		if _, err := os.Stat(filepath.Join(path, "template.yaml")); err == nil {
+			templates = append(templates, filepath.ToSlash(relPath))
+			return filepath.SkipDir
+		}
+		if strings.Count(filepath.ToSlash(relPath), "/")+1 >= maxTemplateDepth {
+			return filepath.SkipDir
+		}
 		return nil
 	})
 
@@ -84,6 +362,50 @@ func (l *Loader) List() ([]string, error) {
 	return templates, nil
 }
 
+// ReadIndex reads the index file of the templates directory, if there is one
+func (l *Loader) ReadIndex() (*Index, error) {
+	data, err := os.ReadFile(filepath.Join(l.templatesDir, IndexFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template index: %w", err)
+	}
+
+	var index Index
+	if err := yaml.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("failed to parse template index: %w", err)
+	}
+	for _, name := range index.Templates {
+		if name == "" || filepath.IsAbs(name) || path.Clean(name) != name || strings.HasPrefix(name, "../") || name == ".." {
+			return nil, fmt.Errorf("invalid template index entry %q", name)
+		}
+	}
+
+	return &index, nil
+}
+
+// WriteIndex scans the templates directory and writes its index file,
+// returning the indexed templates
+func (l *Loader) WriteIndex() ([]string, error) {
+	templates, err := l.Scan()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(templates)
+
+	data, err := yaml.Marshal(Index{Templates: templates})
+	if err != nil {
+		return nil, err
+	}
+	data = append([]byte("# Generated by devinit templates index; regenerate when adding or removing templates.\n"), data...)
+	if err := os.WriteFile(filepath.Join(l.templatesDir, IndexFile), data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write template index: %w", err)
+	}
+
+	return templates, nil
+}
+
 // validate performs basic validation on a template
 func (l *Loader) validate(tmpl *Template) error {
 	if tmpl.Version == "" {
@@ -98,6 +420,124 @@ func (l *Loader) validate(tmpl *Template) error {
 		return fmt.Errorf("language is required")
 	}
 
+	switch tmpl.Engine {
+	case "", EngineGoTemplate, EngineJinja2:
+	default:
+		return fmt.Errorf("invalid engine %q: must be gotemplate or jinja2", tmpl.Engine)
+	}
+	if err := validateDelimiters(tmpl, tmpl.Delimiters); err != nil {
+		return err
+	}
+
+	for _, tag := range tmpl.Tags {
+		if !tagPattern.MatchString(tag) {
+			return fmt.Errorf("invalid tag %q: must be lowercase letters, numbers, and hyphens", tag)
+		}
+	}
+
+	for name, variable := range tmpl.Variables {
+		for _, choice := range variable.Choices {
+			if choice.Value == "" {
+				return fmt.Errorf("variable %s has a choice without a value", name)
+			}
+		}
+		if variable.Pattern == "" {
+			continue
+		}
+		if _, err := regexp.Compile(variable.Pattern); err != nil {
+			return fmt.Errorf("variable %s has invalid pattern: %w", name, err)
+		}
+	}
+	if _, err := tmpl.DefaultOrder(); err != nil {
+		return err
+	}
+
+	for name, profile := range tmpl.Profiles {
+		if !profilePattern.MatchString(name) {
+			return fmt.Errorf("invalid profile name %q: must be lowercase letters, numbers, and hyphens", name)
+		}
+		for key := range profile.Variables {
+			if key == "ProjectName" || key == "Profile" {
+				return fmt.Errorf("profile %s cannot set %s", name, key)
+			}
+		}
+	}
+
+	if tmpl.SunsetDate != "" {
+		if _, err := time.Parse(SunsetDateLayout, tmpl.SunsetDate); err != nil {
+			return fmt.Errorf("sunset_date must be formatted as YYYY-MM-DD: %s", tmpl.SunsetDate)
+		}
+	}
+
+	if (tmpl.SupersededBy != "" || tmpl.SunsetDate != "") && !tmpl.Deprecated {
+		return fmt.Errorf("superseded_by and sunset_date require deprecated: true")
+	}
+
+	if smoke := tmpl.SmokeTest; smoke != nil {
+		if smoke.Run == "" {
+			return fmt.Errorf("smoke_test.run is required")
+		}
+		if smoke.Timeout != "" {
+			if _, err := time.ParseDuration(smoke.Timeout); err != nil {
+				return fmt.Errorf("smoke_test.timeout: %w", err)
+			}
+		}
+	}
+
+	names := make(map[string]bool)
+	for i, test := range tmpl.Tests {
+		if test.Name == "" {
+			return fmt.Errorf("tests[%d]: name is required", i)
+		}
+		if names[test.Name] {
+			return fmt.Errorf("duplicate test %q", test.Name)
+		}
+		names[test.Name] = true
+		if _, ok := tmpl.Profiles[test.Profile]; test.Profile != "" && !ok {
+			return fmt.Errorf("test %s uses unknown profile %q", test.Name, test.Profile)
+		}
+		for key, values := range test.Matrix {
+			if len(values) == 0 {
+				return fmt.Errorf("test %s: matrix %s has no values", test.Name, key)
+			}
+		}
+		for _, expected := range test.Expect {
+			if expected.Path == "" {
+				return fmt.Errorf("test %s: expect entries need a path", test.Name)
+			}
+		}
+	}
+
+	if _, err := SortHooks(tmpl.Hooks.PreGenerate); err != nil {
+		return fmt.Errorf("pre_generate: %w", err)
+	}
+	if _, err := SortHooks(tmpl.Hooks.PostGenerate); err != nil {
+		return fmt.Errorf("post_generate: %w", err)
+	}
+
+	for _, req := range tmpl.Requirements.System {
+		if err := validateLevels(req.RequiredIn); err != nil {
+			return fmt.Errorf("requirement %s: %w", req.Command, err)
+		}
+	}
+	for i, check := range tmpl.Requirements.Checks {
+		name := check.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		}
+		switch check.Kind() {
+		case "":
+			return fmt.Errorf("check %s needs exactly one of disk_free, memory, url, docker_network or script", name)
+		case "disk_free", "memory":
+			if _, err := check.Bytes(); err != nil {
+				return fmt.Errorf("check %s: %w", name, err)
+			}
+		}
+		if err := validateLevels(check.RequiredIn); err != nil {
+			return fmt.Errorf("check %s: %w", name, err)
+		}
+	}
+
 	// Validate that all file sources exist
 	filesDir := filepath.Join(tmpl.Path, "files")
 	for _, file := range tmpl.Files {
@@ -105,11 +545,151 @@ func (l *Loader) validate(tmpl *Template) error {
 		if _, err := os.Stat(filePath); os.IsNotExist(err) {
 			return fmt.Errorf("file not found: %s", file.Source)
 		}
+
+		switch file.Markers {
+		case MarkersOff, MarkersStrip, MarkersKeep:
+		default:
+			return fmt.Errorf("file %s has invalid markers %q: must be strip or keep", file.Source, file.Markers)
+		}
+		if err := validateDelimiters(tmpl, file.Delimiters); err != nil {
+			return fmt.Errorf("file %s: %w", file.Source, err)
+		}
+
+		switch file.Mode {
+		case "", WriteOverwrite, WriteAppend, WritePatch, WriteMergeYAML, WriteMergeJSON, WriteMergeTOML:
+		case WriteMerge:
+			if merge.FormatOf(file.Destination) == "" {
+				return fmt.Errorf("file %s: mode merge needs a .yaml, .yml, .json or .toml destination, or an explicit merge-yaml, merge-json or merge-toml", file.Source)
+			}
+		default:
+			return fmt.Errorf("file %s has invalid mode %q: must be overwrite, append, patch, merge, merge-yaml, merge-json or merge-toml", file.Source, file.Mode)
+		}
+		if err := file.Merge.Validate(); err != nil {
+			return fmt.Errorf("file %s: %w", file.Source, err)
+		}
+	}
+
+	return nil
+}
+
+// validateDelimiters checks a pair of action delimiters of tmpl, if any
+func validateDelimiters(tmpl *Template, delims []string) error {
+	if len(delims) == 0 {
+		return nil
+	}
+	if tmpl.Engine == EngineJinja2 {
+		return fmt.Errorf("delimiters are not supported with engine jinja2")
+	}
+	if len(delims) != 2 || strings.TrimSpace(delims[0]) == "" || strings.TrimSpace(delims[1]) == "" {
+		return fmt.Errorf("invalid delimiters %q: must be a left and a right delimiter, e.g. [\"[[\", \"]]\"]", delims)
+	}
+	return nil
+}
+
+// validateLevels checks the validation levels of a required_in list
+func validateLevels(levels []string) error {
+	for _, level := range levels {
+		if level != "basic" && level != "strict" {
+			return fmt.Errorf("invalid required_in level %q: must be basic or strict", level)
+		}
+	}
+	return nil
+}
+
+// expandFiles replaces file specs whose src is a glob (e.g.
+// "migrations/*.sql") with one spec per matching file under files/. A match
+// keeps its path below the leading directories of the glob, placed under
+// dest. Files excluded by the template's .devinitignore are never matched,
+// and naming one explicitly is an error.
+func (l *Loader) expandFiles(tmpl *Template) error {
+	ignored, err := ignore.Load(filepath.Join(tmpl.Path, ignore.FileName))
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", ignore.FileName, err)
+	}
+
+	filesDir := filepath.Join(tmpl.Path, "files")
+	var files []FileSpec
+	for _, spec := range tmpl.Files {
+		if !ignore.IsGlob(spec.Source) {
+			if ignored.Match(spec.Source, false) {
+				return fmt.Errorf("file %s is excluded by %s", spec.Source, ignore.FileName)
+			}
+			files = append(files, spec)
+			continue
+		}
+
+		matches, err := globFiles(filesDir, spec.Source, ignored)
+		if err != nil {
+			return err
+		}
+		if len(matches) == 0 {
+			return fmt.Errorf("no files match %s", spec.Source)
+		}
+
+		base := globBase(spec.Source)
+		for _, match := range matches {
+			expanded := spec
+			expanded.Source = match
+			expanded.Glob = spec.Source
+			expanded.Destination = strings.TrimPrefix(match, base)
+			if spec.Destination != "" {
+				expanded.Destination = strings.TrimSuffix(spec.Destination, "/") + "/" + expanded.Destination
+			}
+			files = append(files, expanded)
+		}
 	}
 
+	tmpl.Files = files
 	return nil
 }
 
+// globFiles returns the files below dir matching pattern that are not
+// ignored, as slash-separated paths relative to dir in lexical order
+func globFiles(dir, pattern string, ignored *ignore.Matcher) ([]string, error) {
+	var matches []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if ignored.Match(rel, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.IsDir() && ignore.MatchGlob(pattern, rel) {
+			matches = append(matches, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand %s: %w", pattern, err)
+	}
+	return matches, nil
+}
+
+// globBase returns the leading directories of pattern without glob
+// characters, with a trailing slash (e.g. "migrations/" for
+// "migrations/*.sql")
+func globBase(pattern string) string {
+	segments := strings.Split(pattern, "/")
+	base := ""
+	for _, segment := range segments[:len(segments)-1] {
+		if ignore.IsGlob(segment) {
+			break
+		}
+		base += segment + "/"
+	}
+	return base
+}
+
 // GetFilesDir returns the files directory for a template
 func (l *Loader) GetFilesDir(tmpl *Template) string {
 	return filepath.Join(tmpl.Path, "files")
@@ -0,0 +1,74 @@
+package template
+
+import (
+	"hash/fnv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Default range of ports handed out by portFor
+const (
+	DefaultPortMin = 10000
+	DefaultPortMax = 19999
+)
+
+// Naming configures the helpers that derive stable values (ports, database
+// and image names) from a project name
+type Naming struct {
+	// Registry is prepended to image names (e.g. ghcr.io/acme)
+	Registry string
+
+	// PortMin and PortMax bound the ports returned by PortFor (inclusive)
+	PortMin int
+	PortMax int
+}
+
+// DefaultNaming returns the naming used when nothing is configured
+func DefaultNaming() Naming {
+	return Naming{PortMin: DefaultPortMin, PortMax: DefaultPortMax}
+}
+
+// PortFor hashes a service and project name into the configured port range,
+// so the same project always gets the same port and different projects
+// rarely collide
+func (n Naming) PortFor(service, project string) int {
+	min, max := n.PortMin, n.PortMax
+	if min <= 0 || max < min {
+		min, max = DefaultPortMin, DefaultPortMax
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(project + "/" + service))
+	return min + int(h.Sum32()%uint32(max-min+1))
+}
+
+// maxDBNameLength is the PostgreSQL identifier limit
+const maxDBNameLength = 63
+
+// DBName returns a database name for the project: snake_case, starting with
+// a letter and at most 63 characters
+func (n Naming) DBName(project string) string {
+	name := strings.Trim(toSnakeCase(project), "_")
+	if name == "" || name[0] < 'a' || name[0] > 'z' {
+		name = "db_" + name
+	}
+	if len(name) > maxDBNameLength {
+		// The limit is in bytes; never cut a character in half
+		cut := maxDBNameLength
+		for !utf8.RuneStart(name[cut]) {
+			cut--
+		}
+		name = strings.TrimRight(name[:cut], "_")
+	}
+	return name
+}
+
+// ImageName returns the image repository for the project, prefixed with the
+// configured registry
+func (n Naming) ImageName(project string) string {
+	name := toKebabCase(project)
+	if n.Registry == "" {
+		return name
+	}
+	return strings.TrimSuffix(n.Registry, "/") + "/" + name
+}
@@ -0,0 +1,287 @@
+package template
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template/parse"
+)
+
+// CommonVariables are the variables devinit itself sets for every template
+// (see NewContext), which templates need not declare
+var CommonVariables = []string{
+	"ProjectName", "PythonVersion", "IncludeDocker", "Database", "IncludeTests",
+	"CIProvider", "Observability", "APIDocs", "Profile", "DockerBase", "Image",
+	"Port", "PackageManager",
+}
+
+// conditionName matches conditions naming a variable, e.g. use_docker
+var conditionName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// conditionFields are the context fields and constants conditions may name
+// besides variables
+var conditionFields = map[string]bool{"OpenAPI": true, "Proto": true, "DBSchema": true, "true": true, "false": true}
+
+// VariableRef is a reference to a variable, {{ .Variables.name }}, in a
+// template file or in template.yaml (Line 0)
+type VariableRef struct {
+	Name string `json:"name"`
+	File string `json:"file"`
+	Line int    `json:"line,omitempty"`
+}
+
+// String returns the name and where it is referenced
+func (r VariableRef) String() string {
+	if r.Line == 0 {
+		return fmt.Sprintf("%s (%s)", r.Name, r.File)
+	}
+	return fmt.Sprintf("%s (%s:%d)", r.Name, r.File, r.Line)
+}
+
+// FindVariableRefs returns the references to variables, .Variables.name or
+// $.Variables.name, of a Go template written with delims, in the order they
+// appear. References through with, index or template variables are not
+// followed.
+func FindVariableRefs(file, text string, delims []string) ([]VariableRef, error) {
+	return findRefs(file, text, delims, func(ident []string) string {
+		if len(ident) >= 2 && ident[0] == "Variables" {
+			return ident[1]
+		}
+		return ""
+	})
+}
+
+// FindFieldRefs returns the references to the fields of the context other
+// than Variables, .Port or $.Port, of a Go template written with delims, in
+// the order they appear
+func FindFieldRefs(file, text string, delims []string) ([]VariableRef, error) {
+	return findRefs(file, text, delims, func(ident []string) string {
+		if len(ident) >= 1 && ident[0] != "Variables" {
+			return ident[0]
+		}
+		return ""
+	})
+}
+
+// findRefs returns the references of a Go template written with delims
+// that name returns a name for, given the identifiers of a field
+func findRefs(file, text string, delims []string, name func(ident []string) string) ([]VariableRef, error) {
+	left, right := splitDelims(delims)
+	expanded, err := expandRawBlocks(text, defaultDelim(left, "{{"), defaultDelim(right, "}}"))
+	if err != nil {
+		return nil, positioned(file, text, "parse", err)
+	}
+	tree := parse.New(file)
+	tree.Mode = parse.SkipFuncCheck
+	trees := make(map[string]*parse.Tree)
+	if _, err := tree.Parse(expanded, left, right, trees); err != nil {
+		return nil, positioned(file, text, "parse", err)
+	}
+
+	var refs []VariableRef
+	add := func(pos parse.Pos, ident []string) {
+		if n := name(ident); n != "" {
+			refs = append(refs, VariableRef{Name: n, File: file, Line: lineAt(expanded, int(pos))})
+		}
+	}
+	for _, t := range trees {
+		walkNodes(t.Root, func(node parse.Node) {
+			switch n := node.(type) {
+			case *parse.FieldNode:
+				add(n.Pos, n.Ident)
+			case *parse.VariableNode:
+				if len(n.Ident) > 0 && n.Ident[0] == "$" {
+					add(n.Pos, n.Ident[1:])
+				}
+			}
+		})
+	}
+	sort.SliceStable(refs, func(i, j int) bool { return refs[i].Line < refs[j].Line })
+	return refs, nil
+}
+
+// walkNodes calls fn for node and every node below it
+func walkNodes(node parse.Node, fn func(node parse.Node)) {
+	if node == nil {
+		return
+	}
+	fn(node)
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			walkNodes(child, fn)
+		}
+	case *parse.ActionNode:
+		walkNodes(n.Pipe, fn)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			walkNodes(cmd, fn)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			walkNodes(arg, fn)
+		}
+	case *parse.ChainNode:
+		walkNodes(n.Node, fn)
+	case *parse.IfNode:
+		walkBranch(&n.BranchNode, fn)
+	case *parse.RangeNode:
+		walkBranch(&n.BranchNode, fn)
+	case *parse.WithNode:
+		walkBranch(&n.BranchNode, fn)
+	case *parse.TemplateNode:
+		walkNodes(n.Pipe, fn)
+	}
+}
+
+// walkBranch walks the pipeline and lists of an if, range or with
+func walkBranch(n *parse.BranchNode, fn func(node parse.Node)) {
+	walkNodes(n.Pipe, fn)
+	walkNodes(n.List, fn)
+	if n.ElseList != nil {
+		walkNodes(n.ElseList, fn)
+	}
+}
+
+// UndeclaredVariables returns the references of the Go template files,
+// destinations, conditions and expression defaults of the template to variables it neither
+// declares nor presets in a profile, other than CommonVariables. They are
+// most likely typos; rendering fails on them unless --var sets them.
+func (t *Template) UndeclaredVariables() ([]VariableRef, error) {
+	known := make(map[string]bool)
+	for _, name := range CommonVariables {
+		known[name] = true
+	}
+	for name := range t.Variables {
+		known[name] = true
+	}
+	for _, profile := range t.Profiles {
+		for name := range profile.Variables {
+			known[name] = true
+		}
+	}
+
+	var refs []VariableRef
+	names := make([]string, 0, len(t.Variables))
+	for name := range t.Variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		// template.yaml: expression defaults
+		if expr := t.Variables[name].DefaultExpression(); expr != "" {
+			found, _ := FindVariableRefs("template.yaml", expr, nil)
+			for _, ref := range found {
+				ref.Line = 0
+				refs = append(refs, ref)
+			}
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, file := range t.Files {
+		// template.yaml: destinations and conditions
+		for i, expr := range append([]string{file.Destination}, file.Conditions...) {
+			if i > 0 && !strings.Contains(expr, "{{") {
+				// Conditions name a variable or are expressions
+				name := strings.TrimPrefix(strings.TrimSpace(expr), ".")
+				if conditionName.MatchString(name) {
+					if !conditionFields[name] {
+						refs = append(refs, VariableRef{Name: name, File: "template.yaml"})
+					}
+					continue
+				}
+				expr = "{{ " + expr + " }}"
+			}
+			found, err := FindVariableRefs("template.yaml", expr, nil)
+			if err != nil {
+				continue // reported when rendered
+			}
+			for _, ref := range found {
+				ref.Line = 0
+				refs = append(refs, ref)
+			}
+		}
+
+		if !strings.HasSuffix(file.Source, ".tmpl") || seen[file.Source] || t.Engine == EngineJinja2 {
+			continue
+		}
+		seen[file.Source] = true
+		path, _ := t.SourcePath(file.Source)
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		found, err := FindVariableRefs(file.Source, string(content), t.FileDelimiters(file))
+		if err != nil {
+			continue // reported when rendered
+		}
+		refs = append(refs, found...)
+	}
+
+	var undeclared []VariableRef
+	reported := make(map[VariableRef]bool)
+	for _, ref := range refs {
+		if !known[ref.Name] && !reported[ref] {
+			reported[ref] = true
+			undeclared = append(undeclared, ref)
+		}
+	}
+	return undeclared, nil
+}
+
+// ReferencesField reports whether the files of the template reference a
+// field of the context, such as Port, in their content, destination or
+// conditions; flags setting a field the template ignores have no effect.
+// Jinja2 templates are not checked and reference every field.
+func (t *Template) ReferencesField(field string) (bool, error) {
+	if t.Engine == EngineJinja2 {
+		return true, nil
+	}
+	seen := make(map[string]bool)
+	for _, file := range t.Files {
+		for i, expr := range append([]string{file.Destination}, file.Conditions...) {
+			if i > 0 && !strings.Contains(expr, "{{") {
+				if strings.TrimPrefix(strings.TrimSpace(expr), ".") == field {
+					return true, nil
+				}
+				expr = "{{ " + expr + " }}"
+			}
+			if found, err := FindFieldRefs("template.yaml", expr, nil); err == nil && containsRef(found, field) {
+				return true, nil
+			}
+		}
+
+		if !strings.HasSuffix(file.Source, ".tmpl") || seen[file.Source] {
+			continue
+		}
+		seen[file.Source] = true
+		path, _ := t.SourcePath(file.Source)
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return false, err
+		}
+		if found, err := FindFieldRefs(file.Source, string(content), t.FileDelimiters(file)); err == nil && containsRef(found, field) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// containsRef reports whether refs has a reference to name
+func containsRef(refs []VariableRef, name string) bool {
+	for _, ref := range refs {
+		if ref.Name == name {
+			return true
+		}
+	}
+	return false
+}
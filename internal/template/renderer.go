@@ -7,24 +7,32 @@ import (
 	"path/filepath"
 	"strings"
 	"text/template"
+
+	"github.com/renan-dev/devinit/internal/jinja"
 )
 
-// Renderer renders template files
+// Renderer renders template files. It is safe for concurrent use once
+// configured with SetNaming.
 type Renderer struct {
-	funcMap template.FuncMap
+	funcMap  template.FuncMap
+	naming   Naming
+	branches BranchRecorder
 }
 
 // NewRenderer creates a new template renderer
 func NewRenderer() *Renderer {
-	funcMap := template.FuncMap{
+	r := &Renderer{naming: DefaultNaming()}
+
+	r.funcMap = template.FuncMap{
 		// String manipulation
-		"lower":   strings.ToLower,
-		"upper":   strings.ToUpper,
-		"title":   strings.Title,
-		"snake":   toSnakeCase,
-		"camel":   toCamelCase,
-		"pascal":  toPascalCase,
-		"kebab":   toKebabCase,
+		"lower":    strings.ToLower,
+		"upper":    strings.ToUpper,
+		"title":    strings.Title,
+		"snake":    toSnakeCase,
+		"camel":    toCamelCase,
+		"pascal":   toPascalCase,
+		"kebab":    toKebabCase,
+		"constant": toConstantCase,
 
 		// String operations
 		"contains": strings.Contains,
@@ -33,14 +41,56 @@ func NewRenderer() *Renderer {
 		"split":    strings.Split,
 		"join":     strings.Join,
 
+		// Paths
+		"packagePath": packagePath,
+
+		// Escaping text meant for other template engines (see escape.go)
+		"literal": func(s string) string { return s },
+		"raw":     wrapExpression,
+
 		// Comparison
 		"eq": func(a, b interface{}) bool { return a == b },
 		"ne": func(a, b interface{}) bool { return a != b },
-	}
 
-	return &Renderer{
-		funcMap: funcMap,
+		// Versions
+		"semverMajor":   semverMajor,
+		"semverMinor":   semverMinor,
+		"semverPatch":   semverPatch,
+		"semverCompare": semverCompare,
+		"pythonTag":     pythonTag,
+
+		// Stable values derived from names
+		"portFor":   func(service, project string) int { return r.naming.PortFor(service, project) },
+		"dbName":    func(project string) string { return r.naming.DBName(project) },
+		"imageName": func(project string) string { return r.naming.ImageName(project) },
 	}
+
+	return r
+}
+
+// SetNaming configures the portFor, dbName and imageName helpers
+func (r *Renderer) SetNaming(naming Naming) {
+	r.naming = naming
+}
+
+// WithBranchRecorder returns a copy of the renderer that reports the
+// branches taken while rendering template files to rec (nil for none). The
+// renderer itself is left as it is, so renderings in progress keep their
+// recorder.
+func (r *Renderer) WithBranchRecorder(rec BranchRecorder) *Renderer {
+	copied := *r
+	copied.branches = rec
+	return &copied
+}
+
+// BranchRecorder returns the recorder of WithBranchRecorder, if any
+func (r *Renderer) BranchRecorder() BranchRecorder {
+	return r.branches
+}
+
+// Naming returns the naming configuration of the helpers
+func (r *Renderer) Naming() Naming {
+	return r.naming
 }
 
 // Render renders a single template file
@@ -51,23 +101,137 @@ func (r *Renderer) Render(templatePath string, ctx *Context) (string, error) {
 		return "", fmt.Errorf("failed to read template: %w", err)
 	}
 
-	// Create template
-	tmpl, err := template.New(filepath.Base(templatePath)).
-		Funcs(r.funcMap).
-		Parse(string(content))
+	return r.RenderText(templatePath, string(content), ctx)
+}
+
+// RenderText renders text, the content of the template file at templatePath
+// read by the caller, with the engine and delimiters of the template
+func (r *Renderer) RenderText(templatePath, text string, ctx *Context) (string, error) {
+	var delims []string
+	if ctx != nil && ctx.Template != nil {
+		delims = ctx.Template.Delimiters
+	}
+	return r.RenderTextDelims(templatePath, text, delims, ctx)
+}
+
+// RenderTextDelims is RenderText with the action delimiters of the file, a
+// left and right pair such as "[[" and "]]" (none for {{ and }})
+func (r *Renderer) RenderTextDelims(templatePath, text string, delims []string, ctx *Context) (string, error) {
+	if ctx != nil && ctx.Template != nil && ctx.Template.Engine == EngineJinja2 {
+		return r.renderJinja(filepath.Base(templatePath), text, ctx)
+	}
+	return r.render(filepath.Base(templatePath), templatePath, text, delims, ctx)
+}
+
+// RenderString renders an in-memory template string
+func (r *Renderer) RenderString(name, text string, ctx *Context) (string, error) {
+	return r.render(name, "", text, nil, ctx)
+}
+
+// render renders text. The branches of template files (file is their path)
+// are reported to the branch recorder.
+func (r *Renderer) render(name, file, text string, delims []string, ctx *Context) (string, error) {
+	record := r.branches != nil && file != ""
+
+	// Create template; references to undeclared variables are errors
+	var branches []Branch
+	left, right := splitDelims(delims)
+	source := text
+	if file != "" {
+		var err error
+		if text, err = expandRawBlocks(text, defaultDelim(left, "{{"), defaultDelim(right, "}}")); err != nil {
+			return "", positioned(name, source, "parse", err)
+		}
+	}
+	tmpl := template.New(name).Delims(left, right).Option("missingkey=error").Funcs(r.funcMap)
+	if record {
+		rec := r.branches
+		tmpl.Funcs(template.FuncMap{branchFunc: func(id int, taken bool) string {
+			rec.RecordBranch(branches[id], taken)
+			return ""
+		}})
+	}
+	tmpl, err := tmpl.Parse(text)
 	if err != nil {
-		return "", fmt.Errorf("failed to parse template: %w", err)
+		return "", positioned(name, source, "parse", err)
+	}
+	if record {
+		branches = instrumentBranches(tmpl, file, text)
 	}
 
 	// Execute template
 	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, ctx); err != nil {
-		return "", fmt.Errorf("failed to execute template: %w", err)
+	if err := tmpl.Execute(&buf, strictContext(ctx)); err != nil {
+		return "", positioned(name, source, "execute", err)
 	}
 
 	return buf.String(), nil
 }
 
+// strictContext returns ctx with the variables its template declares but
+// leaves unset, and the common ones, set to nil. Templates render with
+// missingkey=error, so only references to undeclared variables fail.
+func strictContext(ctx *Context) *Context {
+	if ctx == nil {
+		return nil
+	}
+	var missing []string
+	for _, name := range CommonVariables {
+		if _, ok := ctx.Variables[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if ctx.Template != nil {
+		for name := range ctx.Template.Variables {
+			if _, ok := ctx.Variables[name]; !ok {
+				missing = append(missing, name)
+			}
+		}
+	}
+	if len(missing) == 0 {
+		return ctx
+	}
+
+	strict := *ctx
+	strict.Variables = make(map[string]interface{}, len(ctx.Variables)+len(missing))
+	for key, value := range ctx.Variables {
+		strict.Variables[key] = value
+	}
+	for _, name := range missing {
+		strict.Variables[name] = nil
+	}
+	return &strict
+}
+
+// splitDelims returns the left and right delimiters of a pair, empty (the
+// defaults of text/template) when there is none
+func splitDelims(delims []string) (left, right string) {
+	if len(delims) != 2 {
+		return "", ""
+	}
+	return delims[0], delims[1]
+}
+
+// defaultDelim returns delim, else def
+func defaultDelim(delim, def string) string {
+	if delim == "" {
+		return def
+	}
+	return delim
+}
+
+// renderJinja renders a Jinja2 template file. Templates see the variables by
+// name ({{ project_name }}) and the fields of the context ({{ ProjectName }});
+// the helpers of Go templates are filters ({{ project_name | snake }}).
+// Branches are not recorded.
+func (r *Renderer) renderJinja(name, text string, ctx *Context) (string, error) {
+	out, err := jinja.Render(name, text, jinja.Env{Globals: ctx.Variables, Data: ctx, Filters: r.funcMap})
+	if err != nil {
+		return "", positioned(name, text, "render", err)
+	}
+	return out, nil
+}
+
 // RenderToFile renders a template and writes it to a file
 func (r *Renderer) RenderToFile(templatePath, outputPath string, ctx *Context, perm os.FileMode) error {
 	// Render template
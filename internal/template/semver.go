@@ -0,0 +1,150 @@
+package template
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// version is a parsed semantic version. Missing minor and patch numbers are
+// zero, so "3.11" is 3.11.0; build metadata is dropped.
+type version struct {
+	parts      [3]int
+	prerelease string
+}
+
+// parseVersion parses versions like "1.2.3", "v1.2", "3" or "1.0.0-rc.1+build"
+func parseVersion(s string) (version, error) {
+	var v version
+
+	text := strings.TrimPrefix(strings.TrimSpace(s), "v")
+	text, _, _ = strings.Cut(text, "+")
+	text, v.prerelease, _ = strings.Cut(text, "-")
+
+	fields := strings.Split(text, ".")
+	if len(fields) > 3 {
+		return v, fmt.Errorf("invalid version %q", s)
+	}
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("invalid version %q", s)
+		}
+		v.parts[i] = n
+	}
+
+	return v, nil
+}
+
+// compare returns -1, 0 or 1 as v is older than, equal to or newer than o. A
+// prerelease is older than its release; prereleases compare as strings.
+func (v version) compare(o version) int {
+	for i := range v.parts {
+		if v.parts[i] != o.parts[i] {
+			if v.parts[i] < o.parts[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	switch {
+	case v.prerelease == o.prerelease:
+		return 0
+	case v.prerelease == "":
+		return 1
+	case o.prerelease == "":
+		return -1
+	}
+	return strings.Compare(v.prerelease, o.prerelease)
+}
+
+// CompareVersions returns -1, 0 or 1 as version a is older than, equal to
+// or newer than b
+func CompareVersions(a, b string) (int, error) {
+	va, err := parseVersion(a)
+	if err != nil {
+		return 0, err
+	}
+	vb, err := parseVersion(b)
+	if err != nil {
+		return 0, err
+	}
+	return va.compare(vb), nil
+}
+
+// semverMajor returns the major number of a version ("3.11.4" → 3)
+func semverMajor(s string) (int, error) {
+	v, err := parseVersion(s)
+	return v.parts[0], err
+}
+
+// semverMinor returns the minor number of a version ("3.11.4" → 11)
+func semverMinor(s string) (int, error) {
+	v, err := parseVersion(s)
+	return v.parts[1], err
+}
+
+// semverPatch returns the patch number of a version ("3.11.4" → 4)
+func semverPatch(s string) (int, error) {
+	v, err := parseVersion(s)
+	return v.parts[2], err
+}
+
+// semverCompare reports whether version satisfies constraint: one or more
+// comma-separated comparisons (=, !=, >, >=, <, <=, ^ same major, ~ same
+// minor), e.g. `semverCompare ">=3.10, <4" .PythonVersion`
+func semverCompare(constraint, s string) (bool, error) {
+	v, err := parseVersion(s)
+	if err != nil {
+		return false, err
+	}
+
+	for _, clause := range strings.Split(constraint, ",") {
+		clause = strings.TrimSpace(clause)
+		rest := strings.TrimLeft(clause, "=!<>^~")
+		op := clause[:len(clause)-len(rest)]
+		want, err := parseVersion(rest)
+		if err != nil {
+			return false, fmt.Errorf("invalid constraint %q: %w", constraint, err)
+		}
+
+		cmp := v.compare(want)
+		var ok bool
+		switch op {
+		case "", "=", "==":
+			ok = cmp == 0
+		case "!=":
+			ok = cmp != 0
+		case ">":
+			ok = cmp > 0
+		case ">=":
+			ok = cmp >= 0
+		case "<":
+			ok = cmp < 0
+		case "<=":
+			ok = cmp <= 0
+		case "^":
+			ok = cmp >= 0 && v.parts[0] == want.parts[0]
+		case "~":
+			ok = cmp >= 0 && v.parts[0] == want.parts[0] && v.parts[1] == want.parts[1]
+		default:
+			return false, fmt.Errorf("invalid constraint %q: unknown operator %q", constraint, op)
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// pythonTag returns the interpreter tag of a Python version, as used by
+// wheels, black and ruff ("3.11" → "py311")
+func pythonTag(s string) (string, error) {
+	v, err := parseVersion(s)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("py%d%d", v.parts[0], v.parts[1]), nil
+}
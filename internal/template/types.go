@@ -1,23 +1,63 @@
 package template
 
-import "os"
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/renan-dev/devinit/internal/dbschema"
+	"github.com/renan-dev/devinit/internal/merge"
+	"github.com/renan-dev/devinit/internal/openapi"
+	"github.com/renan-dev/devinit/internal/proto"
+	"gopkg.in/yaml.v3"
+)
 
 // Template represents a project template
 type Template struct {
 	// Metadata
-	Version     string `yaml:"version"`
-	Name        string `yaml:"name"`
-	Description string `yaml:"description"`
-	Language    string `yaml:"language"`
-	Framework   string `yaml:"framework"`
+	Version       string `yaml:"version"`
+	Name          string `yaml:"name"`
+	Description   string `yaml:"description"`
+	Language      string `yaml:"language"`
+	Framework     string `yaml:"framework"`
+	Type          string `yaml:"type,omitempty"` // Project type (api, worker, lib, ...)
 	MinCLIVersion string `yaml:"min_cli_version"`
 
+	// Needs lists the capabilities of devinit the template relies on (see
+	// Capabilities); loading fails where one is missing
+	Needs []string `yaml:"needs,omitempty"`
+
+	// Capability tags (grpc, graphql, async, ...)
+	Tags []string `yaml:"tags,omitempty"`
+
+	// Engine is the syntax of the template files: gotemplate (default) or
+	// jinja2. Destinations, conditions and hooks in template.yaml are Go
+	// templates either way.
+	Engine string `yaml:"engine,omitempty"`
+
+	// Delimiters replace {{ and }} as the action delimiters of the Go
+	// template files, e.g. ["[[", "]]"] for files full of ${{ }} such as
+	// GitHub Actions workflows or Helm charts. Files may set their own.
+	Delimiters []string `yaml:"delimiters,omitempty"`
+
+	// Deprecation
+	Deprecated   bool   `yaml:"deprecated,omitempty"`
+	SupersededBy string `yaml:"superseded_by,omitempty"`
+	SunsetDate   string `yaml:"sunset_date,omitempty"` // YYYY-MM-DD
+
 	// Requirements
 	Requirements Requirements `yaml:"requirements"`
 
 	// Variables
 	Variables map[string]Variable `yaml:"variables"`
 
+	// Named variable presets selected with --profile
+	Profiles map[string]Profile `yaml:"profiles,omitempty"`
+
 	// Files
 	Files []FileSpec `yaml:"files"`
 
@@ -30,14 +70,159 @@ type Template struct {
 	// Healthcheck configuration
 	Healthcheck *Healthcheck `yaml:"healthcheck,omitempty"`
 
+	// Smoke test run by devinit new --smoke-test
+	SmokeTest *SmokeTest `yaml:"smoke_test,omitempty"`
+
+	// Instructions printed after generation (rendered with the Context)
+	NextSteps []string `yaml:"next_steps,omitempty"`
+
+	// Provenance prepends a comment naming devinit and the template to
+	// generated text files (see FileSpec.Provenance)
+	Provenance bool `yaml:"provenance,omitempty"`
+
+	// Fixtures of devinit templates test
+	Tests []TestCase `yaml:"tests,omitempty"`
+
+	// Origin is where an imported template came from
+	Origin *Origin `yaml:"origin,omitempty"`
+
 	// Internal fields (not in YAML)
+	ID   string `yaml:"-"` // Template reference (e.g. "python/fastapi")
 	Path string `yaml:"-"` // Path to template directory
+
+	// Overrides maps file sources to the project files replacing them (see
+	// Loader.SetOverlay)
+	Overrides map[string]string `yaml:"-"`
+}
+
+// Origin is the remote repository, registry or archive a template was
+// imported from, pinned in the lockfile of projects generated from it
+type Origin struct {
+	Source string `yaml:"source"`
+	// Commit is the git commit imported, the digest of the manifest of an
+	// OCI artifact or the checksum of an archive
+	Commit string `yaml:"commit,omitempty"`
+}
+
+// SourcePath returns the path of a file source: its project override, or
+// the file under files/. overridden reports which.
+func (t *Template) SourcePath(source string) (path string, overridden bool) {
+	if override, ok := t.Overrides[source]; ok {
+		return override, true
+	}
+	return filepath.Join(t.Path, "files", source), false
+}
+
+// OverriddenSources returns the file sources replaced by project overrides,
+// sorted
+func (t *Template) OverriddenSources() []string {
+	sources := make([]string, 0, len(t.Overrides))
+	for source := range t.Overrides {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	return sources
+}
+
+// Template engines
+const (
+	EngineGoTemplate = "gotemplate"
+	EngineJinja2     = "jinja2"
+)
+
+// DefaultType is the project type of templates that do not declare one
+const DefaultType = "api"
+
+// GetType returns the project type, defaulting to "api"
+func (t *Template) GetType() string {
+	if t.Type == "" {
+		return DefaultType
+	}
+	return t.Type
+}
+
+// HasTag reports whether the template carries the given tag (case-insensitive)
+func (t *Template) HasTag(tag string) bool {
+	for _, tt := range t.Tags {
+		if strings.EqualFold(tt, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter selects templates by metadata. Empty fields match everything.
+type Filter struct {
+	Language string
+	Type     string
+	Tags     []string // all tags must be present
+	Query    string   // free text matched against name, description and tags
+}
+
+// Matches reports whether a template satisfies the filter
+func (f Filter) Matches(t *Template) bool {
+	if f.Language != "" && !strings.EqualFold(f.Language, t.Language) {
+		return false
+	}
+	if f.Type != "" && !strings.EqualFold(f.Type, t.GetType()) {
+		return false
+	}
+	for _, tag := range f.Tags {
+		if !t.HasTag(tag) {
+			return false
+		}
+	}
+	if f.Query != "" && !t.matchesQuery(f.Query) {
+		return false
+	}
+	return true
+}
+
+// matchesQuery reports whether every word of the query appears in the
+// template's searchable metadata
+func (t *Template) matchesQuery(query string) bool {
+	haystack := strings.ToLower(strings.Join(append([]string{
+		t.ID, t.Name, t.Description, t.Language, t.Framework, t.GetType(),
+	}, t.Tags...), " "))
+
+	for _, word := range strings.Fields(strings.ToLower(query)) {
+		if !strings.Contains(haystack, word) {
+			return false
+		}
+	}
+	return true
+}
+
+// SunsetDateLayout is the expected format of sunset_date
+const SunsetDateLayout = "2006-01-02"
+
+// DeprecationNotice returns a human-readable deprecation message, or an empty
+// string if the template is not deprecated
+func (t *Template) DeprecationNotice() string {
+	if !t.Deprecated {
+		return ""
+	}
+
+	notice := fmt.Sprintf("template %s/%s is deprecated", t.Language, t.Framework)
+	if t.SunsetDate != "" {
+		if sunset, err := time.Parse(SunsetDateLayout, t.SunsetDate); err == nil && time.Now().After(sunset) {
+			notice += fmt.Sprintf(" and was sunset on %s", t.SunsetDate)
+		} else {
+			notice += fmt.Sprintf(" and will be removed on %s", t.SunsetDate)
+		}
+	}
+	if t.SupersededBy != "" {
+		notice += fmt.Sprintf("; use %s instead", t.SupersededBy)
+	}
+
+	return notice
 }
 
 // Requirements defines system requirements
 type Requirements struct {
 	System      []SystemRequirement      `yaml:"system,omitempty"`
 	Environment []EnvironmentRequirement `yaml:"environment,omitempty"`
+	Checks      []Check                  `yaml:"checks,omitempty"`
 }
 
 // SystemRequirement represents a required command/binary
@@ -47,6 +232,94 @@ type SystemRequirement struct {
 	Required    bool   `yaml:"required"`
 	When        string `yaml:"when,omitempty"`
 	InstallHint string `yaml:"install_hint,omitempty"`
+
+	// RequiredIn limits the requirement to the listed validation levels
+	// (basic, strict) instead of Required, e.g. docker required with
+	// --strict and optional otherwise
+	RequiredIn []string `yaml:"required_in,omitempty"`
+}
+
+// Check is a requirement beyond an installed command. Exactly one of
+// DiskFree, Memory, URL, DockerNetwork and Script is set.
+type Check struct {
+	Name string `yaml:"name,omitempty"`
+
+	// DiskFree is the free space needed where the project is generated and
+	// Memory the available memory, e.g. "2GB" or "512MiB"
+	DiskFree string `yaml:"disk_free,omitempty"`
+	Memory   string `yaml:"memory,omitempty"`
+
+	// URL must answer, e.g. an internal package mirror
+	URL string `yaml:"url,omitempty"`
+
+	// DockerNetwork must exist
+	DockerNetwork string `yaml:"docker_network,omitempty"`
+
+	// Script is a command run in the template directory that must exit
+	// with ExitCode
+	Script   string `yaml:"script,omitempty"`
+	ExitCode int    `yaml:"exit_code,omitempty"`
+
+	Required   bool     `yaml:"required"`
+	RequiredIn []string `yaml:"required_in,omitempty"`
+	When       string   `yaml:"when,omitempty"`
+	Fix        string   `yaml:"fix,omitempty"`
+}
+
+// Kind returns the name of the field that selects what the check verifies
+// (disk_free, memory, url, docker_network or script), or "" when none or
+// several are set
+func (c Check) Kind() string {
+	kind := ""
+	for name, value := range map[string]string{
+		"disk_free":      c.DiskFree,
+		"memory":         c.Memory,
+		"url":            c.URL,
+		"docker_network": c.DockerNetwork,
+		"script":         c.Script,
+	} {
+		if value == "" {
+			continue
+		}
+		if kind != "" {
+			return ""
+		}
+		kind = name
+	}
+	return kind
+}
+
+// byteUnits are the size suffixes Check.Bytes accepts
+var byteUnits = []struct {
+	suffix string
+	size   float64
+}{
+	{"KIB", 1 << 10}, {"MIB", 1 << 20}, {"GIB", 1 << 30}, {"TIB", 1 << 40},
+	{"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9}, {"TB", 1e12},
+	{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30}, {"T", 1 << 40},
+	{"B", 1},
+}
+
+// Bytes returns the size a disk_free or memory check needs
+func (c Check) Bytes() (uint64, error) {
+	value := c.DiskFree
+	if value == "" {
+		value = c.Memory
+	}
+
+	number := strings.ToUpper(strings.TrimSpace(value))
+	multiplier := 1.0
+	for _, unit := range byteUnits {
+		if strings.HasSuffix(number, unit.suffix) {
+			number, multiplier = strings.TrimSpace(strings.TrimSuffix(number, unit.suffix)), unit.size
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q: must be a number with an optional unit like 512MB or 2GiB", value)
+	}
+	return uint64(n * multiplier), nil
 }
 
 // EnvironmentRequirement represents required environment variable
@@ -60,10 +333,10 @@ type EnvironmentRequirement struct {
 type VariableType string
 
 const (
-	VariableTypeString  VariableType = "string"
-	VariableTypeBool    VariableType = "boolean"
-	VariableTypeChoice  VariableType = "choice"
-	VariableTypeInt     VariableType = "int"
+	VariableTypeString VariableType = "string"
+	VariableTypeBool   VariableType = "boolean"
+	VariableTypeChoice VariableType = "choice"
+	VariableTypeInt    VariableType = "int"
 )
 
 // Variable defines a template variable
@@ -71,19 +344,194 @@ type Variable struct {
 	Type        VariableType `yaml:"type"`
 	Required    bool         `yaml:"required"`
 	Default     interface{}  `yaml:"default,omitempty"`
-	Choices     []string     `yaml:"choices,omitempty"`
+	Choices     []Choice     `yaml:"choices,omitempty"`
 	Pattern     string       `yaml:"pattern,omitempty"`
 	Description string       `yaml:"description,omitempty"`
+
+	// Advanced variables are only asked for by interactive prompts with
+	// --advanced; hidden ones are also left out of the template's docs.
+	// --var sets both like any other.
+	Advanced bool `yaml:"advanced,omitempty"`
+	Hidden   bool `yaml:"hidden,omitempty"`
+}
+
+// Prompted reports whether interactive prompts ask for the variable:
+// advanced and hidden variables only when advanced is set, unless they are
+// required and have no default
+func (v Variable) Prompted(advanced bool) bool {
+	return advanced || !(v.Advanced || v.Hidden) || (v.Required && v.Default == nil)
+}
+
+// ChoiceValues returns the values of the variable's choices
+func (v Variable) ChoiceValues() []string {
+	if len(v.Choices) == 0 {
+		return nil
+	}
+	values := make([]string, len(v.Choices))
+	for i, choice := range v.Choices {
+		values[i] = choice.Value
+	}
+	return values
+}
+
+// Choice is an allowed value of a choice variable. In template.yaml it is
+// the value, or the value with the label and description prompts show for
+// it, while templates only ever see the value:
+//
+//	choices:
+//	  - value: postgres
+//	    label: PostgreSQL
+//	    description: production-grade relational DB
+//	  - none
+type Choice struct {
+	Value       string `yaml:"value" json:"value"`
+	Label       string `yaml:"label,omitempty" json:"label,omitempty"`
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+}
+
+func (c *Choice) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*c = Choice{Value: node.Value}
+		return nil
+	}
+	type plain Choice
+	return node.Decode((*plain)(c))
+}
+
+// MarshalYAML writes choices without a label or description as their value
+func (c Choice) MarshalYAML() (interface{}, error) {
+	if c.Label == "" && c.Description == "" {
+		return c.Value, nil
+	}
+	type plain Choice
+	return plain(c), nil
+}
+
+// Labeled reports whether the choice has a label or description
+func (c Choice) Labeled() bool {
+	return c.Label != "" || c.Description != ""
+}
+
+// String returns how prompts show the choice, e.g. "PostgreSQL —
+// production-grade relational DB": its label, else its value, and its
+// description
+func (c Choice) String() string {
+	s := c.Label
+	if s == "" {
+		s = c.Value
+	}
+	if c.Description != "" {
+		s += " — " + c.Description
+	}
+	return s
+}
+
+// Profile is a named set of variable values (e.g. minimal, standard, full).
+// Keys may be template variables or built-in variables such as IncludeDocker.
+type Profile struct {
+	Description string                 `yaml:"description,omitempty"`
+	Variables   map[string]interface{} `yaml:"variables"`
+}
+
+// ProfileNames returns the names of the template's profiles in sorted order
+func (t *Template) ProfileNames() []string {
+	names := make([]string, 0, len(t.Profiles))
+	for name := range t.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// FileDelimiters returns the action delimiters of a file of the template:
+// its own, else those of the template, else none for {{ and }}
+func (t *Template) FileDelimiters(file FileSpec) []string {
+	if len(file.Delimiters) > 0 {
+		return file.Delimiters
+	}
+	return t.Delimiters
 }
 
 // FileSpec specifies a file to be generated
 type FileSpec struct {
-	Source      string   `yaml:"src"`
-	Destination string   `yaml:"dest"`
-	Conditions  []string `yaml:"conditions,omitempty"`
-	Permissions string   `yaml:"permissions,omitempty"`
+	Source      string     `yaml:"src"`
+	Destination string     `yaml:"dest"`
+	Conditions  []string   `yaml:"conditions,omitempty"`
+	Permissions string     `yaml:"permissions,omitempty"`
+	Markers     MarkerMode `yaml:"markers,omitempty"`
+	Mode        WriteMode  `yaml:"mode,omitempty"`
+
+	// Delimiters override those of the template for this file
+	Delimiters []string `yaml:"delimiters,omitempty"`
+
+	// Merge selects how maps and lists are combined by the merge modes
+	Merge merge.Options `yaml:"merge,omitempty"`
+
+	// Provenance overrides whether the file gets a provenance header; by
+	// default it does when the template or --provenance enables headers
+	Provenance *bool `yaml:"provenance,omitempty"`
+
+	// Override lets the file replace one an earlier file spec generated at
+	// the same destination, which is otherwise an error
+	Override bool `yaml:"override,omitempty"`
+
+	// Glob is the src pattern of the spec a spec was expanded from, for
+	// specs matched by a glob
+	Glob string `yaml:"-"`
 }
 
+// WriteMode selects how a file is combined with an existing file at its
+// destination, written by an earlier file spec or already in the project
+type WriteMode string
+
+const (
+	// WriteOverwrite replaces the existing file (default)
+	WriteOverwrite WriteMode = "overwrite"
+	// WriteAppend adds the content at the end of the existing file
+	WriteAppend WriteMode = "append"
+	// WritePatch applies the content, a unified diff, to the existing file
+	WritePatch WriteMode = "patch"
+	// WriteMergeYAML merges the keys of a YAML document into the existing file
+	WriteMergeYAML WriteMode = "merge-yaml"
+	// WriteMergeJSON merges the keys of a JSON document into the existing file
+	WriteMergeJSON WriteMode = "merge-json"
+	// WriteMergeTOML merges the tables and keys of a TOML document into the
+	// existing file
+	WriteMergeTOML WriteMode = "merge-toml"
+	// WriteMerge merges a YAML, JSON or TOML document, chosen by the
+	// extension of the destination
+	WriteMerge WriteMode = "merge"
+)
+
+// MergeFormat returns the format merged by mode for a destination, or "" for
+// modes that do not merge
+func (m WriteMode) MergeFormat(dest string) merge.Format {
+	switch m {
+	case WriteMergeYAML:
+		return merge.FormatYAML
+	case WriteMergeJSON:
+		return merge.FormatJSON
+	case WriteMergeTOML:
+		return merge.FormatTOML
+	case WriteMerge:
+		return merge.FormatOf(dest)
+	}
+	return ""
+}
+
+// MarkerMode selects how "devinit:if" comment markers in a file are handled
+type MarkerMode string
+
+const (
+	// MarkersOff leaves the file untouched (default)
+	MarkersOff MarkerMode = ""
+	// MarkersStrip drops sections whose condition is false and the marker lines
+	MarkersStrip MarkerMode = "strip"
+	// MarkersKeep drops sections whose condition is false but keeps the
+	// marker lines of the others
+	MarkersKeep MarkerMode = "keep"
+)
+
 // GetPermissions returns the file permissions as os.FileMode
 func (f *FileSpec) GetPermissions() os.FileMode {
 	if f.Permissions == "" {
@@ -121,13 +569,76 @@ type Hook struct {
 	WorkingDir string     `yaml:"working_dir,omitempty"`
 	ErrorLevel ErrorLevel `yaml:"error_level,omitempty"`
 	Error      string     `yaml:"error,omitempty"` // Custom error message
+
+	// Network marks hooks that download (dependency installs, code
+	// generators fetching plugins); they are retried on failure
+	Network bool `yaml:"network,omitempty"`
+
+	// Name identifies the hook in after lists and reports
+	Name string `yaml:"name,omitempty"`
+
+	// When is a condition like those of files; the hook is skipped when it
+	// does not hold
+	When string `yaml:"when,omitempty"`
+
+	// Order sorts the hooks of a stage, lowest first; hooks with the same
+	// order keep their position
+	Order int `yaml:"order,omitempty"`
+
+	// After names hooks of the same stage that must run first. A hook is
+	// skipped when one of them failed without stopping generation.
+	After []string `yaml:"after,omitempty"`
+
+	// Idempotent marks hooks that are safe to run again on an existing
+	// project
+	Idempotent bool `yaml:"idempotent,omitempty"`
 }
 
 // Healthcheck defines healthcheck configuration for generated project
 type Healthcheck struct {
-	Command string `yaml:"command"`
-	Port    int    `yaml:"port"`
-	Timeout string `yaml:"timeout,omitempty"`
+	Command string `yaml:"command" json:"command"`
+	Port    int    `yaml:"port" json:"port"`
+	Timeout string `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+}
+
+// SmokeTest declares how to check that a generated project works. Commands
+// are rendered with the Context and run without a shell, like hooks.
+type SmokeTest struct {
+	// Setup commands run in order before the test, e.g. installing
+	// dependencies
+	Setup []string `yaml:"setup,omitempty" json:"setup,omitempty"`
+
+	// Run is the test command; an empty rendering (e.g. without tests)
+	// skips the smoke test
+	Run string `yaml:"run" json:"run"`
+
+	// Image is the container image used with --smoke-test=container
+	Image string `yaml:"image,omitempty" json:"image,omitempty"`
+
+	// Timeout bounds the whole smoke test (default 10m)
+	Timeout string `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+}
+
+// TestCase is a fixture of devinit templates test: a combination of values
+// the template must generate a working project with
+type TestCase struct {
+	Name      string                 `yaml:"name"`
+	Profile   string                 `yaml:"profile,omitempty"`
+	Variables map[string]interface{} `yaml:"variables,omitempty"`
+
+	// Matrix expands the case into one case per combination of values, e.g.
+	// Database: [postgres, sqlite] and IncludeDocker: [true, false] make four
+	Matrix map[string][]interface{} `yaml:"matrix,omitempty"`
+
+	// Expect lists files the generated project must (or must not) contain
+	Expect []ExpectedFile `yaml:"expect,omitempty"`
+}
+
+// ExpectedFile is a file a test case checks. It must be generated when the
+// When condition holds (or always, without one) and be absent otherwise.
+type ExpectedFile struct {
+	Path string `yaml:"path"`
+	When string `yaml:"when,omitempty"`
 }
 
 // Context represents the context for template rendering
@@ -143,30 +654,84 @@ type Context struct {
 	Template *Template
 
 	// Computed values
-	ProjectNameSnake  string
-	ProjectNameCamel  string
-	ProjectNamePascal string
-	ProjectNameKebab  string
+	ProjectNameSnake    string
+	ProjectNameCamel    string
+	ProjectNamePascal   string
+	ProjectNameKebab    string
+	ProjectNameConstant string
 
 	// Common template variables (exposed as fields for easy template access)
-	PythonVersion  string
-	IncludeDocker  bool
-	Database       string
-	IncludeTests   bool
-	CIProvider     string
+	PythonVersion string
+	IncludeDocker bool
+	Database      string
+	IncludeTests  bool
+	CIProvider    string
+	Observability bool
+	APIDocs       bool
+	Profile       string
+
+	// Docker base image flavour (slim, alpine, distroless); empty selects
+	// the template's default
+	DockerBase string
+
+	// Image is the reference of the image built from the generated project,
+	// shared by Dockerfile, compose and deployment templates
+	Image string
+
+	// Port the generated service listens on
+	Port int
+
+	// Package manager selected for the project (npm, pnpm, poetry, uv, ...);
+	// empty when the template offers no choice
+	PackageManager string
+
+	// OpenAPI is the contract the project is scaffolded from with
+	// --from-openapi ({{ range .OpenAPI.Endpoints }}); nil otherwise
+	OpenAPI *openapi.Spec
+
+	// Proto is the contract the project is scaffolded from with --from-proto
+	// ({{ range .Proto.Services }}); nil otherwise
+	Proto *proto.File
+
+	// DBSchema is the existing database the project is scaffolded from with
+	// --from-db ({{ range .DBSchema.Tables }}); nil otherwise
+	DBSchema *dbschema.Schema
+
+	// Services are the other services of the devinit workspace the project
+	// is generated into ({{ range .Services }}{{ .Host }}:{{ .Port }}{{ end }});
+	// empty outside a workspace
+	Services []Service
+
+	// Where, when and by whom the project is generated ({{ .OS }}, {{ .Year }}, ...)
+	Environment
+}
+
+// Service is a service generated into a devinit workspace
+type Service struct {
+	Name string `yaml:"name" json:"name"`
+
+	// Host is the name siblings reach the service at (the compose service)
+	Host string `yaml:"host" json:"host"`
+	Port int    `yaml:"port,omitempty" json:"port,omitempty"`
+
+	// Dir is the directory of the service relative to the workspace root
+	Dir      string `yaml:"dir" json:"dir"`
+	Template string `yaml:"template" json:"template"`
 }
 
 // NewContext creates a new template context
 func NewContext(projectName, outputDir string, variables map[string]interface{}, tmpl *Template) *Context {
 	ctx := &Context{
-		ProjectName:       projectName,
-		OutputDir:         outputDir,
-		Variables:         variables,
-		Template:          tmpl,
-		ProjectNameSnake:  toSnakeCase(projectName),
-		ProjectNameCamel:  toCamelCase(projectName),
-		ProjectNamePascal: toPascalCase(projectName),
-		ProjectNameKebab:  toKebabCase(projectName),
+		ProjectName:         projectName,
+		OutputDir:           outputDir,
+		Variables:           variables,
+		Template:            tmpl,
+		ProjectNameSnake:    toSnakeCase(projectName),
+		ProjectNameCamel:    toCamelCase(projectName),
+		ProjectNamePascal:   toPascalCase(projectName),
+		ProjectNameKebab:    toKebabCase(projectName),
+		ProjectNameConstant: toConstantCase(projectName),
+		Environment:         CurrentEnvironment(""),
 	}
 
 	// Extract common variables to fields for template access
@@ -185,6 +750,31 @@ func NewContext(projectName, outputDir string, variables map[string]interface{},
 	if v, ok := variables["CIProvider"].(string); ok {
 		ctx.CIProvider = v
 	}
+	if v, ok := variables["Observability"].(bool); ok {
+		ctx.Observability = v
+	}
+	if v, ok := variables["APIDocs"].(bool); ok {
+		ctx.APIDocs = v
+	}
+	if v, ok := variables["Profile"].(string); ok {
+		ctx.Profile = v
+	}
+	if v, ok := variables["DockerBase"].(string); ok {
+		ctx.DockerBase = v
+	}
+	if v, ok := variables["PackageManager"].(string); ok {
+		ctx.PackageManager = v
+	}
+	switch v := variables["Port"].(type) {
+	case int:
+		ctx.Port = v
+	case string:
+		ctx.Port, _ = strconv.Atoi(v)
+	}
+	ctx.Image = projectName + ":latest"
+	if v, ok := variables["Image"].(string); ok && v != "" {
+		ctx.Image = v
+	}
 
 	return ctx
 }
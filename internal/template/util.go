@@ -1,90 +1,160 @@
 package template
 
 import (
-	"regexp"
 	"strings"
 	"unicode"
 )
 
-// toSnakeCase converts a string to snake_case
-func toSnakeCase(s string) string {
-	// Replace hyphens with underscores
-	s = strings.ReplaceAll(s, "-", "_")
+// words splits s into the words every case conversion starts from. Words end
+// at separators (anything but letters, digits and combining marks), before
+// an upper-case letter following a lower-case one ("fooBar") and at the end
+// of an acronym ("HTTPServer" is HTTP and Server, "IDsFor" is IDs and For).
+// Digits stay with the word before them ("OAuth2Client").
+func words(s string) []string {
+	runes := []rune(s)
+	isWordRune := func(i int) bool {
+		r := runes[i]
+		return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)
+	}
 
-	// Insert underscore before uppercase letters
-	var result strings.Builder
-	for i, r := range s {
-		if unicode.IsUpper(r) && i > 0 {
-			result.WriteRune('_')
+	var result []string
+	start := -1
+	for i, r := range runes {
+		if !isWordRune(i) {
+			if start >= 0 {
+				result = append(result, string(runes[start:i]))
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+			continue
+		}
+
+		if unicode.IsUpper(r) && (!unicode.IsUpper(runes[i-1]) || endsAcronym(runes, i)) {
+			result = append(result, string(runes[start:i]))
+			start = i
 		}
-		result.WriteRune(unicode.ToLower(r))
+	}
+	if start >= 0 {
+		result = append(result, string(runes[start:]))
 	}
 
-	// Clean up multiple underscores
-	re := regexp.MustCompile(`_+`)
-	return re.ReplaceAllString(result.String(), "_")
+	return result
 }
 
-// toCamelCase converts a string to camelCase
-func toCamelCase(s string) string {
-	// Split on hyphens, underscores, and spaces
-	parts := regexp.MustCompile(`[-_\s]+`).Split(s, -1)
+// endsAcronym reports whether the upper-case rune at i, following another
+// one, starts a new word: it is followed by a lower-case letter other than
+// the plural s of the acronym
+func endsAcronym(runes []rune, i int) bool {
+	if i+1 >= len(runes) || !unicode.IsLower(runes[i+1]) {
+		return false
+	}
+	if runes[i+1] != 's' {
+		return true
+	}
+	// "URLs" or "IDsFor": the s ends the word
+	return i+2 < len(runes) && (unicode.IsLower(runes[i+2]) || unicode.IsDigit(runes[i+2]))
+}
 
-	if len(parts) == 0 {
-		return ""
+// isAcronym reports whether word is written in capitals, allowing a plural s
+// ("API", "IDs")
+func isAcronym(word string) bool {
+	word = strings.TrimSuffix(word, "s")
+	if len([]rune(word)) < 2 {
+		return false
 	}
+	for _, r := range word {
+		if unicode.IsLower(r) {
+			return false
+		}
+	}
+	return true
+}
 
-	var result strings.Builder
+// hasLower reports whether s contains a lower-case letter. Acronyms are only
+// recognised in mixed-case input; in "MY_PROJECT" every word is in capitals.
+func hasLower(s string) bool {
+	return strings.IndexFunc(s, unicode.IsLower) >= 0
+}
+
+// joinLower joins the words of s in lower case with sep
+func joinLower(s, sep string) string {
+	parts := words(s)
 	for i, part := range parts {
-		if part == "" {
-			continue
+		parts[i] = strings.ToLower(part)
+	}
+	return strings.Join(parts, sep)
+}
+
+// packagePath turns a dotted package name into nested directories, e.g.
+// com.acme.billing into com/acme/billing. The path uses slashes like
+// destinations do; they become the separator of the platform when files are
+// written. Empty segments and surrounding spaces are dropped.
+func packagePath(name string) string {
+	var parts []string
+	for _, part := range strings.Split(name, ".") {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
 		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// toSnakeCase converts a string to snake_case
+func toSnakeCase(s string) string {
+	return joinLower(s, "_")
+}
+
+// toKebabCase converts a string to kebab-case
+func toKebabCase(s string) string {
+	return joinLower(s, "-")
+}
+
+// toConstantCase converts a string to CONSTANT_CASE
+func toConstantCase(s string) string {
+	return strings.ToUpper(joinLower(s, "_"))
+}
 
+// toCamelCase converts a string to camelCase. Acronyms after the first word
+// keep their capitals ("parseHTTPRequest").
+func toCamelCase(s string) string {
+	parts := words(s)
+	keepAcronyms := hasLower(s)
+
+	var result strings.Builder
+	for i, part := range parts {
 		if i == 0 {
-			// First part is lowercase
 			result.WriteString(strings.ToLower(part))
 		} else {
-			// Capitalize first letter of subsequent parts
-			result.WriteString(capitalize(part))
+			result.WriteString(titleWord(part, keepAcronyms))
 		}
 	}
 
 	return result.String()
 }
 
-// toPascalCase converts a string to PascalCase
+// toPascalCase converts a string to PascalCase. Acronyms keep their capitals
+// ("HTTPServer").
 func toPascalCase(s string) string {
-	// Split on hyphens, underscores, and spaces
-	parts := regexp.MustCompile(`[-_\s]+`).Split(s, -1)
+	keepAcronyms := hasLower(s)
 
 	var result strings.Builder
-	for _, part := range parts {
-		if part == "" {
-			continue
-		}
-		result.WriteString(capitalize(part))
+	for _, part := range words(s) {
+		result.WriteString(titleWord(part, keepAcronyms))
 	}
 
 	return result.String()
 }
 
-// toKebabCase converts a string to kebab-case
-func toKebabCase(s string) string {
-	// Replace underscores with hyphens
-	s = strings.ReplaceAll(s, "_", "-")
-
-	// Insert hyphen before uppercase letters
-	var result strings.Builder
-	for i, r := range s {
-		if unicode.IsUpper(r) && i > 0 {
-			result.WriteRune('-')
-		}
-		result.WriteRune(unicode.ToLower(r))
+// titleWord upper-cases the first letter of word and lower-cases the rest,
+// unless keepAcronyms is set and word is an acronym
+func titleWord(word string, keepAcronyms bool) string {
+	if keepAcronyms && isAcronym(word) {
+		return word
 	}
-
-	// Clean up multiple hyphens
-	re := regexp.MustCompile(`-+`)
-	return re.ReplaceAllString(result.String(), "-")
+	return capitalize(strings.ToLower(word))
 }
 
 // capitalize capitalizes the first letter of a string
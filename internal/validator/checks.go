@@ -0,0 +1,297 @@
+package validator
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/renan-dev/devinit/internal/template"
+)
+
+// checkTimeout bounds URL, docker and script checks
+const checkTimeout = 10 * time.Second
+
+// errUnsupported is returned by probes that do not work on this platform
+var errUnsupported = errors.New("not supported on this platform")
+
+// CheckKind selects what a Check verifies
+type CheckKind string
+
+const (
+	CheckDiskFree      CheckKind = "disk_free"
+	CheckMemory        CheckKind = "memory"
+	CheckURL           CheckKind = "url"
+	CheckDockerNetwork CheckKind = "docker_network"
+	CheckScript        CheckKind = "script"
+)
+
+// Check represents a requirement beyond an installed command, such as free
+// disk space or a reachable package mirror
+type Check struct {
+	Name string
+	Kind CheckKind
+
+	// Target is the size as written in the template, the URL, the docker
+	// network or the script command
+	Target string
+
+	// Bytes is the size a disk_free or memory check needs
+	Bytes uint64
+
+	// ExitCode is the status a script must exit with
+	ExitCode int
+
+	Required   bool
+	RequiredIn []ValidationLevel
+	When       string
+	Fix        string
+
+	// Dir is the directory scripts run in
+	Dir string
+}
+
+// Label describes the check for display
+func (c Check) Label() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return fmt.Sprintf("%s %s", c.Kind, c.Target)
+}
+
+// RequiredAt reports whether a failing check is an error at level
+func (c Check) RequiredAt(level ValidationLevel) bool {
+	if len(c.RequiredIn) == 0 {
+		return c.Required
+	}
+	return containsLevel(c.RequiredIn, level)
+}
+
+// FromTemplateCheck converts a template.Check of the template in dir to a
+// Check. Invalid checks are rejected when the template is loaded.
+func FromTemplateCheck(tc template.Check, dir string) Check {
+	check := Check{
+		Name:     tc.Name,
+		Kind:     CheckKind(tc.Kind()),
+		ExitCode: tc.ExitCode,
+		Required: tc.Required,
+		When:     tc.When,
+		Fix:      tc.Fix,
+		Dir:      dir,
+	}
+	switch check.Kind {
+	case CheckDiskFree:
+		check.Target = tc.DiskFree
+		check.Bytes, _ = tc.Bytes()
+	case CheckMemory:
+		check.Target = tc.Memory
+		check.Bytes, _ = tc.Bytes()
+	case CheckURL:
+		check.Target = tc.URL
+	case CheckDockerNetwork:
+		check.Target = tc.DockerNetwork
+	case CheckScript:
+		check.Target = tc.Script
+	}
+	for _, name := range tc.RequiredIn {
+		if level, err := ParseValidationLevel(name); err == nil {
+			check.RequiredIn = append(check.RequiredIn, level)
+		}
+	}
+	return check
+}
+
+// ValidateCheck runs a check. A failing check is an error when it is
+// required at the validator's level and a warning otherwise; a check that
+// cannot run on this platform is always a warning. ValidationNone checks
+// nothing.
+func (v *SystemValidator) ValidateCheck(check Check) *ValidationResult {
+	result := &ValidationResult{
+		Errors:   []ValidationError{},
+		Warnings: []ValidationError{},
+	}
+	if v.Level == ValidationNone {
+		return result
+	}
+
+	err := v.runCheck(check)
+	if err == nil {
+		return result
+	}
+
+	valErr := ValidationError{
+		Command:     check.Label(),
+		Message:     err.Error(),
+		InstallHint: check.Fix,
+	}
+	if check.RequiredAt(v.Level) && !errors.Is(err, errUnsupported) {
+		result.Errors = append(result.Errors, valErr)
+	} else {
+		result.Warnings = append(result.Warnings, valErr)
+	}
+	return result
+}
+
+// runCheck returns why check fails, or nil when it passes
+func (v *SystemValidator) runCheck(check Check) error {
+	switch check.Kind {
+	case CheckDiskFree:
+		dir := v.OutputDir
+		if dir == "" {
+			dir = "."
+		}
+		free, err := diskFree(existingParent(dir))
+		if err != nil {
+			return fmt.Errorf("cannot measure free disk space: %w", err)
+		}
+		if free < check.Bytes {
+			return fmt.Errorf("%s free disk space, need %s", formatBytes(free), formatBytes(check.Bytes))
+		}
+	case CheckMemory:
+		available, err := availableMemory()
+		if err != nil {
+			return fmt.Errorf("cannot measure available memory: %w", err)
+		}
+		if available < check.Bytes {
+			return fmt.Errorf("%s memory available, need %s", formatBytes(available), formatBytes(check.Bytes))
+		}
+	case CheckURL:
+		return checkURL(check.Target)
+	case CheckDockerNetwork:
+		return checkDockerNetwork(check.Target)
+	case CheckScript:
+		return checkScript(check.Target, check.Dir, check.ExitCode)
+	default:
+		return fmt.Errorf("unknown check %q", check.Kind)
+	}
+	return nil
+}
+
+// existingParent returns dir or its closest ancestor that exists, since the
+// output directory is usually created by generation
+func existingParent(dir string) string {
+	dir, _ = filepath.Abs(dir)
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+}
+
+// availableMemory returns the memory available to new processes, from
+// /proc/meminfo
+func availableMemory() (uint64, error) {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0, errUnsupported
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "MemAvailable:" {
+			kb, err := strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				return 0, err
+			}
+			return kb * 1024, nil
+		}
+	}
+	return 0, errUnsupported
+}
+
+// checkURL fails unless url answers without a server error
+func checkURL(url string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s is not reachable: %w", url, errors.Unwrap(err))
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("%s answered %s", url, resp.Status)
+	}
+	return nil
+}
+
+// checkDockerNetwork fails unless the docker network exists
+func checkDockerNetwork(network string) error {
+	if _, err := exec.LookPath("docker"); err != nil {
+		return fmt.Errorf("docker not found")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+	if err := exec.CommandContext(ctx, "docker", "network", "inspect", network).Run(); err != nil {
+		return fmt.Errorf("docker network %s not found", network)
+	}
+	return nil
+}
+
+// checkScript runs command in dir and fails unless it exits with
+// exitCode. Like hooks, the command is executed directly, without a shell.
+func checkScript(command, dir string, exitCode int) error {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return fmt.Errorf("empty script")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+
+	code := 0
+	var exitErr *exec.ExitError
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("%s timed out after %s", command, checkTimeout)
+	case errors.As(err, &exitErr):
+		code = exitErr.ExitCode()
+	case err != nil:
+		return fmt.Errorf("cannot run %s: %w", command, err)
+	}
+
+	if code != exitCode {
+		msg := fmt.Sprintf("%s exited with %d, want %d", command, code, exitCode)
+		if lines := strings.Split(strings.TrimSpace(string(output)), "\n"); lines[len(lines)-1] != "" {
+			msg += ": " + lines[len(lines)-1]
+		}
+		return errors.New(msg)
+	}
+	return nil
+}
+
+// formatBytes formats a byte count for display
+func formatBytes(bytes uint64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := uint64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
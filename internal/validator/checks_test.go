@@ -0,0 +1,112 @@
+package validator
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateCheck(t *testing.T) {
+	dir := t.TempDir()
+	script := "#!/bin/sh\necho \"vpn is $1\"\nexit 3\n"
+	if err := os.WriteFile(filepath.Join(dir, "check-vpn"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/simple/":
+			w.WriteHeader(http.StatusOK)
+		case "/login":
+			w.WriteHeader(http.StatusUnauthorized)
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		check     Check
+		level     ValidationLevel
+		wantError string // substring of the error, "" when it passes
+		warning   bool
+	}{
+		{name: "enough disk", check: Check{Kind: CheckDiskFree, Bytes: 1}},
+		{
+			name:      "not enough disk",
+			check:     Check{Kind: CheckDiskFree, Bytes: 1 << 62, Required: true},
+			wantError: "free disk space, need 4.0 EiB",
+		},
+		{name: "reachable URL", check: Check{Kind: CheckURL, Target: server.URL + "/simple/", Required: true}},
+		{name: "URL needing login", check: Check{Kind: CheckURL, Target: server.URL + "/login", Required: true}},
+		{
+			name:      "server error",
+			check:     Check{Kind: CheckURL, Target: server.URL + "/down", Required: true},
+			wantError: "answered 503 Service Unavailable",
+		},
+		{
+			name:      "unreachable URL",
+			check:     Check{Kind: CheckURL, Target: "http://127.0.0.1:1/", Required: true},
+			wantError: "is not reachable",
+		},
+		{name: "expected exit code", check: Check{Kind: CheckScript, Target: "./check-vpn up", ExitCode: 3, Dir: dir, Required: true}},
+		{
+			name:      "unexpected exit code",
+			check:     Check{Kind: CheckScript, Target: "./check-vpn down", Dir: dir, Required: true},
+			wantError: "./check-vpn down exited with 3, want 0: vpn is down",
+		},
+		{
+			name:      "missing script",
+			check:     Check{Kind: CheckScript, Target: "./missing", Dir: dir},
+			wantError: "cannot run ./missing",
+			warning:   true,
+		},
+		{
+			name:      "required at strict only",
+			check:     Check{Kind: CheckScript, Target: "./check-vpn", Dir: dir, RequiredIn: []ValidationLevel{ValidationStrict}},
+			level:     ValidationBasic,
+			wantError: "exited with 3",
+			warning:   true,
+		},
+		{
+			name:      "docker network without docker",
+			check:     Check{Kind: CheckDockerNetwork, Target: "devnet", Required: true},
+			wantError: "docker not found",
+		},
+	}
+
+	t.Setenv("PATH", dir)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			level := tt.level
+			if level == ValidationNone {
+				level = ValidationStrict
+			}
+			v := NewSystemValidator(level)
+			v.OutputDir = filepath.Join(dir, "not-created-yet")
+
+			result := v.ValidateCheck(tt.check)
+			problems := result.Errors
+			if tt.warning {
+				problems = result.Warnings
+			}
+			if tt.wantError == "" {
+				if result.HasErrors() || result.HasWarnings() {
+					t.Fatalf("ValidateCheck() = %+v, want it to pass", result)
+				}
+				return
+			}
+			kind := "an error"
+			if tt.warning {
+				kind = "a warning"
+			}
+			if len(problems) != 1 || !strings.Contains(problems[0].Message, tt.wantError) {
+				t.Fatalf("ValidateCheck() = %+v, want %s containing %q", result, kind, tt.wantError)
+			}
+		})
+	}
+}
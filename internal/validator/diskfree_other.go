@@ -0,0 +1,8 @@
+//go:build !linux && !darwin
+
+package validator
+
+// diskFree is not implemented on this platform
+func diskFree(dir string) (uint64, error) {
+	return 0, errUnsupported
+}
@@ -0,0 +1,15 @@
+//go:build linux || darwin
+
+package validator
+
+import "syscall"
+
+// diskFree returns the space available to unprivileged users on the
+// filesystem of dir
+func diskFree(dir string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return 0, err
+	}
+	return stat.Bavail * uint64(stat.Bsize), nil
+}
@@ -0,0 +1,137 @@
+package validator
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultProbeTTL is how long probed tool versions are reused
+const DefaultProbeTTL = 10 * time.Minute
+
+// ProbeCache keeps the versions reported by tools (java -version, docker
+// --version, ...) on disk, keyed by the tool's resolved path, so repeated
+// requirement checks skip running them. An entry is reused until its TTL
+// expires or the executable changes (size or modification time).
+type ProbeCache struct {
+	path    string
+	ttl     time.Duration
+	refresh bool
+	now     func() time.Time
+
+	entries map[string]probeEntry
+	hits    map[string]time.Time // entries reused by this process
+	dirty   bool
+}
+
+type probeEntry struct {
+	Version   string    `json:"version"`
+	ModTime   time.Time `json:"mod_time"`
+	Size      int64     `json:"size"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// OpenProbeCache loads the cache stored in the file at path. A missing or
+// unreadable file starts an empty cache. With refresh, every tool is probed
+// again and the results replace the cached ones.
+func OpenProbeCache(path string, ttl time.Duration, refresh bool) *ProbeCache {
+	c := &ProbeCache{
+		path:    path,
+		ttl:     ttl,
+		refresh: refresh,
+		now:     time.Now,
+		entries: make(map[string]probeEntry),
+		hits:    make(map[string]time.Time),
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		json.Unmarshal(data, &c.entries) // a corrupt cache is rebuilt
+	}
+	return c
+}
+
+// lookup returns the cached version of the executable at path
+func (c *ProbeCache) lookup(path string) (string, bool) {
+	if c == nil || c.refresh {
+		return "", false
+	}
+	entry, ok := c.entries[path]
+	if !ok || c.now().Sub(entry.CheckedAt) > c.ttl {
+		return "", false
+	}
+	info, err := os.Stat(path)
+	if err != nil || !info.ModTime().Equal(entry.ModTime) || info.Size() != entry.Size {
+		return "", false
+	}
+
+	c.hits[path] = entry.CheckedAt
+	return entry.Version, true
+}
+
+// store records the version probed from the executable at path
+func (c *ProbeCache) store(path, version string) {
+	if c == nil {
+		return
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	c.entries[path] = probeEntry{Version: version, ModTime: info.ModTime(), Size: info.Size(), CheckedAt: c.now()}
+	delete(c.hits, path)
+	c.dirty = true
+}
+
+// Age returns how long ago the cached result reused for the executable at
+// path was probed; ok is false when the tool was probed by this process
+func (c *ProbeCache) Age(path string) (age time.Duration, ok bool) {
+	if c == nil {
+		return 0, false
+	}
+	checkedAt, ok := c.hits[path]
+	if !ok {
+		return 0, false
+	}
+	return c.now().Sub(checkedAt), true
+}
+
+// Save writes the cache back to its file if it changed, dropping expired
+// entries
+func (c *ProbeCache) Save() error {
+	if c == nil || !c.dirty {
+		return nil
+	}
+	for path, entry := range c.entries {
+		if c.now().Sub(entry.CheckedAt) > c.ttl {
+			delete(c.entries, path)
+		}
+	}
+
+	data, err := json.MarshalIndent(c.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+
+	// Replace the file atomically so concurrent runs never read a partial
+	// cache
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), ".probes-")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), c.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	c.dirty = false
+	return nil
+}
@@ -0,0 +1,116 @@
+package validator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeTool installs an executable named tool on PATH that prints
+// "tool 1.2.3" and counts its runs in the returned file
+func fakeTool(t *testing.T) (path, runs string) {
+	t.Helper()
+	dir := t.TempDir()
+	runs = filepath.Join(dir, "runs")
+	path = filepath.Join(dir, "tool")
+	script := "#!/bin/sh\necho x >> " + runs + "\necho tool 1.2.3\n"
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir)
+	return path, runs
+}
+
+func countRuns(t *testing.T, runs string) int {
+	t.Helper()
+	data, err := os.ReadFile(runs)
+	if os.IsNotExist(err) {
+		return 0
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Count(string(data), "x")
+}
+
+func TestProbeCache(t *testing.T) {
+	tests := []struct {
+		name    string
+		refresh bool
+		// change runs between the first and the second check
+		change   func(t *testing.T, tool string, cache *ProbeCache)
+		wantRuns int
+	}{
+		{name: "reused", wantRuns: 1},
+		{name: "refresh", refresh: true, wantRuns: 2},
+		{
+			name: "executable changed",
+			change: func(t *testing.T, tool string, cache *ProbeCache) {
+				later := time.Now().Add(time.Hour)
+				if err := os.Chtimes(tool, later, later); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantRuns: 2,
+		},
+		{
+			name: "expired",
+			change: func(t *testing.T, tool string, cache *ProbeCache) {
+				cache.now = func() time.Time { return time.Now().Add(DefaultProbeTTL + time.Minute) }
+			},
+			wantRuns: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tool, runs := fakeTool(t)
+			cache := OpenProbeCache(filepath.Join(t.TempDir(), "probes.json"), DefaultProbeTTL, tt.refresh)
+			v := NewSystemValidator(ValidationBasic)
+			v.Cache = cache
+
+			for i := 0; i < 2; i++ {
+				if i == 1 && tt.change != nil {
+					tt.change(t, tool, cache)
+				}
+				exists, version, err := v.CheckCommand("tool")
+				if err != nil || !exists || version != "1.2.3" {
+					t.Fatalf("CheckCommand() = %v, %q, %v", exists, version, err)
+				}
+			}
+
+			// Each check runs the first version flag once
+			if got := countRuns(t, runs); got != tt.wantRuns {
+				t.Errorf("tool ran %d times, want %d", got, tt.wantRuns)
+			}
+			if _, cached := v.CachedAge("tool"); cached != (tt.wantRuns == 1) {
+				t.Errorf("CachedAge() ok = %v, want %v", cached, tt.wantRuns == 1)
+			}
+		})
+	}
+}
+
+func TestProbeCacheSave(t *testing.T) {
+	_, runs := fakeTool(t)
+	file := filepath.Join(t.TempDir(), "cache", "probes.json")
+
+	v := NewSystemValidator(ValidationBasic)
+	v.Cache = OpenProbeCache(file, DefaultProbeTTL, false)
+	v.CheckCommand("tool")
+	if err := v.Cache.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	v.Cache = OpenProbeCache(file, DefaultProbeTTL, false)
+	if _, version, _ := v.CheckCommand("tool"); version != "1.2.3" {
+		t.Errorf("CheckCommand() version = %q, want 1.2.3", version)
+	}
+	if got := countRuns(t, runs); got != 1 {
+		t.Errorf("tool ran %d times after reloading the cache, want 1", got)
+	}
+	if age, ok := v.CachedAge("tool"); !ok || age < 0 {
+		t.Errorf("CachedAge() = %v, %v, want a cached age", age, ok)
+	}
+}
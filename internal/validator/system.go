@@ -6,11 +6,24 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // SystemValidator validates system requirements
 type SystemValidator struct {
 	Level ValidationLevel
+
+	// Policy overrides, by command, the validation levels at which
+	// requirements are required, e.g. from the organization config. An
+	// empty list makes the command optional at every level.
+	Policy map[string][]ValidationLevel
+
+	// Cache reuses tool versions probed recently (nil probes every time)
+	Cache *ProbeCache
+
+	// OutputDir is where the project is generated; disk space checks
+	// measure its filesystem (the current directory when empty)
+	OutputDir string
 }
 
 // NewSystemValidator creates a new system validator
@@ -20,13 +33,17 @@ func NewSystemValidator(level ValidationLevel) *SystemValidator {
 	}
 }
 
-// Validate checks if all requirements are met
+// Validate checks if all requirements are met. ValidationNone checks nothing.
 func (v *SystemValidator) Validate(reqs []Requirement) (*ValidationResult, error) {
 	result := &ValidationResult{
 		Errors:   []ValidationError{},
 		Warnings: []ValidationError{},
 	}
 
+	if v.Level == ValidationNone {
+		return result, nil
+	}
+
 	for _, req := range reqs {
 
 		exists, version, err := v.CheckCommand(req.Command)
@@ -38,7 +55,7 @@ func (v *SystemValidator) Validate(reqs []Requirement) (*ValidationResult, error
 				InstallHint: req.InstallHint,
 			}
 
-			if req.Required {
+			if v.Required(req) {
 				result.Errors = append(result.Errors, valErr)
 			} else {
 				result.Warnings = append(result.Warnings, valErr)
@@ -53,7 +70,7 @@ func (v *SystemValidator) Validate(reqs []Requirement) (*ValidationResult, error
 				InstallHint: req.InstallHint,
 			}
 
-			if req.Required {
+			if v.Required(req) {
 				result.Errors = append(result.Errors, valErr)
 			} else {
 				result.Warnings = append(result.Warnings, valErr)
@@ -98,18 +115,41 @@ func (v *SystemValidator) Validate(reqs []Requirement) (*ValidationResult, error
 	return result, nil
 }
 
+// Required reports whether a missing req is an error at the validator's
+// level, applying the policy
+func (v *SystemValidator) Required(req Requirement) bool {
+	if levels, ok := v.Policy[req.Command]; ok {
+		return containsLevel(levels, v.Level)
+	}
+	return req.RequiredAt(v.Level)
+}
+
 // CheckCommand checks if a command exists and returns its version
 func (v *SystemValidator) CheckCommand(cmd string) (exists bool, version string, err error) {
-	_, err = exec.LookPath(cmd)
+	path, err := exec.LookPath(cmd)
 	if err != nil {
 		return false, "", nil
 	}
 
+	if version, ok := v.Cache.lookup(path); ok {
+		return true, version, nil
+	}
 	version, _ = v.getCommandVersion(cmd)
+	v.Cache.store(path, version)
 
 	return true, version, nil
 }
 
+// CachedAge returns how long ago the version of cmd reused from the cache
+// was probed; ok is false when cmd was probed now
+func (v *SystemValidator) CachedAge(cmd string) (age time.Duration, ok bool) {
+	path, err := exec.LookPath(cmd)
+	if err != nil {
+		return 0, false
+	}
+	return v.Cache.Age(path)
+}
+
 // getCommandVersion attempts to get the version of a command
 func (v *SystemValidator) getCommandVersion(cmd string) (string, error) {
 	versionFlags := []string{"--version", "-version", "-v", "version"}
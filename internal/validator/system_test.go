@@ -267,6 +267,7 @@ func TestValidate(t *testing.T) {
 		name         string
 		level        ValidationLevel
 		requirements []Requirement
+		policy       map[string][]ValidationLevel
 		wantErrors   int
 		wantWarnings int
 	}{
@@ -322,11 +323,74 @@ func TestValidate(t *testing.T) {
 			wantErrors:   0,
 			wantWarnings: 1,
 		},
+		{
+			name:  "required only in strict, basic level",
+			level: ValidationBasic,
+			requirements: []Requirement{
+				{
+					Command:    "this-does-not-exist",
+					RequiredIn: []ValidationLevel{ValidationStrict},
+				},
+			},
+			wantErrors:   0,
+			wantWarnings: 1,
+		},
+		{
+			name:  "required only in strict, strict level",
+			level: ValidationStrict,
+			requirements: []Requirement{
+				{
+					Command:    "this-does-not-exist",
+					RequiredIn: []ValidationLevel{ValidationStrict},
+				},
+			},
+			wantErrors:   1,
+			wantWarnings: 0,
+		},
+		{
+			name:  "policy makes a requirement optional",
+			level: ValidationStrict,
+			requirements: []Requirement{
+				{
+					Command:  "this-does-not-exist",
+					Required: true,
+				},
+			},
+			policy:       map[string][]ValidationLevel{"this-does-not-exist": {}},
+			wantErrors:   0,
+			wantWarnings: 1,
+		},
+		{
+			name:  "policy requires an optional command",
+			level: ValidationBasic,
+			requirements: []Requirement{
+				{
+					Command:    "this-does-not-exist",
+					RequiredIn: []ValidationLevel{ValidationStrict},
+				},
+			},
+			policy:       map[string][]ValidationLevel{"this-does-not-exist": {ValidationBasic, ValidationStrict}},
+			wantErrors:   1,
+			wantWarnings: 0,
+		},
+		{
+			name:  "validation disabled",
+			level: ValidationNone,
+			requirements: []Requirement{
+				{
+					Command:  "this-does-not-exist",
+					Required: true,
+				},
+			},
+			wantErrors:   0,
+			wantWarnings: 0,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			validator := NewSystemValidator(tt.level)
+			validator.Policy = tt.policy
 			result, err := validator.Validate(tt.requirements)
 
 			if err != nil {
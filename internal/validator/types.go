@@ -1,6 +1,10 @@
 package validator
 
-import "github.com/renan-dev/devinit/internal/template"
+import (
+	"fmt"
+
+	"github.com/renan-dev/devinit/internal/template"
+)
 
 // ValidationLevel defines how strict validation should be
 type ValidationLevel int
@@ -28,6 +32,19 @@ func (v ValidationLevel) String() string {
 	}
 }
 
+// ParseValidationLevel parses the name of a level at which requirements can
+// be required (basic, strict)
+func ParseValidationLevel(name string) (ValidationLevel, error) {
+	switch name {
+	case "basic":
+		return ValidationBasic, nil
+	case "strict":
+		return ValidationStrict, nil
+	default:
+		return ValidationNone, fmt.Errorf("invalid validation level %q: must be basic or strict", name)
+	}
+}
+
 // ValidationResult contains the results of validation
 type ValidationResult struct {
 	Errors   []ValidationError
@@ -63,15 +80,43 @@ type Requirement struct {
 	Required    bool
 	When        string
 	InstallHint string
+
+	// RequiredIn lists the validation levels at which the requirement is
+	// required; when empty, Required applies at every level
+	RequiredIn []ValidationLevel
 }
 
-// FromTemplateRequirement converts a template.SystemRequirement to a Requirement
+// RequiredAt reports whether the requirement is required at level
+func (r Requirement) RequiredAt(level ValidationLevel) bool {
+	if len(r.RequiredIn) == 0 {
+		return r.Required
+	}
+	return containsLevel(r.RequiredIn, level)
+}
+
+func containsLevel(levels []ValidationLevel, level ValidationLevel) bool {
+	for _, l := range levels {
+		if l == level {
+			return true
+		}
+	}
+	return false
+}
+
+// FromTemplateRequirement converts a template.SystemRequirement to a
+// Requirement. Invalid levels are rejected when the template is loaded.
 func FromTemplateRequirement(tr template.SystemRequirement) Requirement {
-	return Requirement{
+	req := Requirement{
 		Command:     tr.Command,
 		Version:     tr.Version,
 		Required:    tr.Required,
 		When:        tr.When,
 		InstallHint: tr.InstallHint,
 	}
+	for _, name := range tr.RequiredIn {
+		if level, err := ParseValidationLevel(name); err == nil {
+			req.RequiredIn = append(req.RequiredIn, level)
+		}
+	}
+	return req
 }
@@ -0,0 +1,216 @@
+package vulnscan
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"path"
+	"strings"
+)
+
+// parsePipAudit parses `pip-audit --format json`. pip-audit reports no
+// severities.
+func parsePipAudit(report []byte) ([]Finding, error) {
+	type dependency struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+		Vulns   []struct {
+			ID          string   `json:"id"`
+			FixVersions []string `json:"fix_versions"`
+			Description string   `json:"description"`
+		} `json:"vulns"`
+	}
+	var doc struct {
+		Dependencies []dependency `json:"dependencies"`
+	}
+	// pip-audit before 2.5 printed the dependency list on its own
+	if trimmed := bytes.TrimSpace(report); bytes.HasPrefix(trimmed, []byte("[")) {
+		if err := json.Unmarshal(trimmed, &doc.Dependencies); err != nil {
+			return nil, err
+		}
+	} else if err := json.Unmarshal(report, &doc); err != nil {
+		return nil, err
+	}
+
+	var findings []Finding
+	for _, dep := range doc.Dependencies {
+		for _, vuln := range dep.Vulns {
+			finding := Finding{
+				Scanner:  "pip-audit",
+				ID:       vuln.ID,
+				Package:  dep.Name,
+				Version:  dep.Version,
+				Severity: SeverityUnknown,
+				Title:    firstLine(vuln.Description),
+			}
+			if len(vuln.FixVersions) > 0 {
+				finding.FixedVersion = vuln.FixVersions[0]
+			}
+			findings = append(findings, finding)
+		}
+	}
+	return findings, nil
+}
+
+// parseNpmAudit parses `npm audit --json` (npm 7 and later). Each advisory
+// is reported once, on the package it was published for.
+func parseNpmAudit(report []byte) ([]Finding, error) {
+	var doc struct {
+		Vulnerabilities map[string]struct {
+			Via          []json.RawMessage `json:"via"`
+			FixAvailable json.RawMessage   `json:"fixAvailable"`
+		} `json:"vulnerabilities"`
+		Error *struct {
+			Summary string `json:"summary"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(report, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Error != nil {
+		return nil, errors.New(doc.Error.Summary)
+	}
+
+	var findings []Finding
+	seen := make(map[string]bool)
+	for name, vuln := range doc.Vulnerabilities {
+		var fix struct {
+			Version string `json:"version"`
+		}
+		json.Unmarshal(vuln.FixAvailable, &fix) // false when there is no fix
+
+		for _, raw := range vuln.Via {
+			// Other entries name the vulnerable dependency that leads here
+			var advisory struct {
+				Name     string `json:"name"`
+				Title    string `json:"title"`
+				URL      string `json:"url"`
+				Severity string `json:"severity"`
+				Range    string `json:"range"`
+			}
+			if json.Unmarshal(raw, &advisory) != nil || advisory.Name != name {
+				continue
+			}
+			id := path.Base(advisory.URL)
+			if advisory.URL == "" {
+				id = advisory.Title
+			}
+			if seen[name+"|"+id] {
+				continue
+			}
+			seen[name+"|"+id] = true
+			findings = append(findings, Finding{
+				Scanner:      "npm",
+				ID:           id,
+				Package:      name,
+				Version:      advisory.Range,
+				FixedVersion: fix.Version,
+				Severity:     normalize(advisory.Severity),
+				Title:        advisory.Title,
+			})
+		}
+	}
+	return findings, nil
+}
+
+// parseGovulncheck parses the message stream of `govulncheck -json`. Only
+// vulnerabilities whose code is called are reported, as govulncheck does in
+// text mode; the Go vulnerability database has no severities.
+func parseGovulncheck(report []byte) ([]Finding, error) {
+	type frame struct {
+		Module   string `json:"module"`
+		Version  string `json:"version"`
+		Function string `json:"function"`
+	}
+	type message struct {
+		OSV *struct {
+			ID      string `json:"id"`
+			Summary string `json:"summary"`
+		} `json:"osv"`
+		Finding *struct {
+			OSV          string  `json:"osv"`
+			FixedVersion string  `json:"fixed_version"`
+			Trace        []frame `json:"trace"`
+		} `json:"finding"`
+	}
+
+	summaries := make(map[string]string)
+	var findings []Finding
+	seen := make(map[string]bool)
+	dec := json.NewDecoder(bytes.NewReader(report))
+	for {
+		var msg message
+		err := dec.Decode(&msg)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if msg.OSV != nil {
+			summaries[msg.OSV.ID] = msg.OSV.Summary
+		}
+		f := msg.Finding
+		if f == nil || len(f.Trace) == 0 || f.Trace[0].Function == "" || seen[f.OSV] {
+			continue
+		}
+		seen[f.OSV] = true
+		findings = append(findings, Finding{
+			Scanner:      "govulncheck",
+			ID:           f.OSV,
+			Package:      f.Trace[0].Module,
+			Version:      f.Trace[0].Version,
+			FixedVersion: f.FixedVersion,
+			Severity:     SeverityUnknown,
+		})
+	}
+
+	// OSV entries may follow the findings that refer to them
+	for i := range findings {
+		findings[i].Title = summaries[findings[i].ID]
+	}
+	return findings, nil
+}
+
+// parseTrivy parses `trivy fs|image --format json`
+func parseTrivy(report []byte) ([]Finding, error) {
+	var doc struct {
+		Results []struct {
+			Vulnerabilities []struct {
+				VulnerabilityID  string `json:"VulnerabilityID"`
+				PkgName          string `json:"PkgName"`
+				InstalledVersion string `json:"InstalledVersion"`
+				FixedVersion     string `json:"FixedVersion"`
+				Severity         string `json:"Severity"`
+				Title            string `json:"Title"`
+			} `json:"Vulnerabilities"`
+		} `json:"Results"`
+	}
+	if err := json.Unmarshal(report, &doc); err != nil {
+		return nil, err
+	}
+
+	var findings []Finding
+	for _, result := range doc.Results {
+		for _, vuln := range result.Vulnerabilities {
+			findings = append(findings, Finding{
+				Scanner:      "trivy",
+				ID:           vuln.VulnerabilityID,
+				Package:      vuln.PkgName,
+				Version:      vuln.InstalledVersion,
+				FixedVersion: vuln.FixedVersion,
+				Severity:     normalize(vuln.Severity),
+				Title:        vuln.Title,
+			})
+		}
+	}
+	return findings, nil
+}
+
+// firstLine returns the first line of s
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return line
+}
@@ -0,0 +1,168 @@
+// Package vulnscan runs vulnerability scanners (pip-audit, npm audit,
+// govulncheck, trivy) against a generated project or its image and
+// normalizes their findings, so scaffolds can be held to a severity policy.
+package vulnscan
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Severity of a finding, normalized across scanners
+type Severity string
+
+const (
+	SeverityLow      Severity = "low"
+	SeverityMedium   Severity = "medium"
+	SeverityHigh     Severity = "high"
+	SeverityCritical Severity = "critical"
+
+	// SeverityUnknown is reported by scanners without severity data
+	// (pip-audit, govulncheck)
+	SeverityUnknown Severity = "unknown"
+
+	// SeverityNone is a threshold no finding reaches: findings are reported
+	// but never fail
+	SeverityNone Severity = "none"
+)
+
+// Severities lists the accepted thresholds, from the lowest
+var Severities = []Severity{SeverityLow, SeverityMedium, SeverityHigh, SeverityCritical, SeverityNone}
+
+// rank orders severities. Unknown ranks as high, so findings a scanner
+// cannot rate fail the default policy rather than slip through.
+var rank = map[Severity]int{
+	SeverityLow:      1,
+	SeverityMedium:   2,
+	SeverityHigh:     3,
+	SeverityUnknown:  3,
+	SeverityCritical: 4,
+	SeverityNone:     5,
+}
+
+// normalize maps the severity names of scanners to a Severity
+func normalize(s string) Severity {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "info", "negligible", "low":
+		return SeverityLow
+	case "moderate", "medium":
+		return SeverityMedium
+	case "high":
+		return SeverityHigh
+	case "critical":
+		return SeverityCritical
+	default:
+		return SeverityUnknown
+	}
+}
+
+// ParseThreshold parses the severity findings must reach to fail a scan
+func ParseThreshold(s string) (Severity, error) {
+	for _, severity := range Severities {
+		if strings.EqualFold(s, string(severity)) {
+			return severity, nil
+		}
+	}
+	names := make([]string, len(Severities))
+	for i, severity := range Severities {
+		names[i] = string(severity)
+	}
+	return "", fmt.Errorf("severity must be one of %s (got %q)", strings.Join(names, ", "), s)
+}
+
+// AtLeast reports whether s reaches threshold
+func (s Severity) AtLeast(threshold Severity) bool {
+	return rank[s] >= rank[threshold]
+}
+
+// Finding is a vulnerability reported by a scanner
+type Finding struct {
+	Scanner      string   `json:"scanner"`
+	ID           string   `json:"id"`
+	Package      string   `json:"package"`
+	Version      string   `json:"version,omitempty"`
+	FixedVersion string   `json:"fixed_version,omitempty"`
+	Severity     Severity `json:"severity"`
+	Title        string   `json:"title,omitempty"`
+}
+
+// Failing returns the findings that reach threshold
+func Failing(findings []Finding, threshold Severity) []Finding {
+	var failing []Finding
+	for _, f := range findings {
+		if f.Severity.AtLeast(threshold) {
+			failing = append(failing, f)
+		}
+	}
+	return failing
+}
+
+// Count returns the number of findings per severity
+func Count(findings []Finding) map[Severity]int {
+	counts := make(map[Severity]int)
+	for _, f := range findings {
+		counts[f.Severity]++
+	}
+	return counts
+}
+
+// Sort orders findings from the most severe, then by package and ID
+func Sort(findings []Finding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if rank[a.Severity] != rank[b.Severity] {
+			return rank[a.Severity] > rank[b.Severity]
+		}
+		if a.Package != b.Package {
+			return a.Package < b.Package
+		}
+		return a.ID < b.ID
+	})
+}
+
+// Job is one scanner invocation. Args[0] is the scanner executable; the
+// command runs in the project directory and writes its report to stdout.
+type Job struct {
+	Scanner string
+	Args    []string
+	Parse   func(report []byte) ([]Finding, error)
+}
+
+// ProjectJobs returns the scanners that apply to the project in dir, based
+// on its manifests. Trivy is the fallback for projects none of the language
+// scanners cover.
+func ProjectJobs(dir string) []Job {
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(dir, name))
+		return err == nil
+	}
+
+	var jobs []Job
+	switch {
+	case exists("requirements.txt"):
+		jobs = append(jobs, Job{Scanner: "pip-audit", Args: []string{"pip-audit", "--format", "json", "--progress-spinner", "off", "-r", "requirements.txt"}, Parse: parsePipAudit})
+	case exists("pyproject.toml"):
+		jobs = append(jobs, Job{Scanner: "pip-audit", Args: []string{"pip-audit", "--format", "json", "--progress-spinner", "off", "."}, Parse: parsePipAudit})
+	}
+	// npm audit reads the lockfile, which the install hook writes
+	if exists("package-lock.json") {
+		jobs = append(jobs, Job{Scanner: "npm", Args: []string{"npm", "audit", "--json"}, Parse: parseNpmAudit})
+	}
+	if exists("go.mod") {
+		jobs = append(jobs, Job{Scanner: "govulncheck", Args: []string{"govulncheck", "-json", "./..."}, Parse: parseGovulncheck})
+	}
+	return jobs
+}
+
+// FilesystemJob scans the project directory with trivy
+func FilesystemJob() Job {
+	return Job{Scanner: "trivy", Args: []string{"trivy", "fs", "--format", "json", "--quiet", "--scanners", "vuln", "."}, Parse: parseTrivy}
+}
+
+// ImageJob scans a container image with trivy
+func ImageJob(image string) Job {
+	return Job{Scanner: "trivy", Args: []string{"trivy", "image", "--format", "json", "--quiet", "--scanners", "vuln", image}, Parse: parseTrivy}
+}
@@ -0,0 +1,161 @@
+package vulnscan
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParsers(t *testing.T) {
+	tests := []struct {
+		name    string
+		parse   func([]byte) ([]Finding, error)
+		report  string
+		want    []Finding
+		wantErr bool
+	}{
+		{
+			name:  "pip-audit",
+			parse: parsePipAudit,
+			report: `{"dependencies": [
+				{"name": "fastapi", "version": "0.104.1", "vulns": []},
+				{"name": "starlette", "version": "0.27.0", "vulns": [
+					{"id": "PYSEC-2023-1", "fix_versions": ["0.36.2", "0.40.0"], "description": "Denial of service.\nDetails follow."}
+				]}
+			], "fixes": []}`,
+			want: []Finding{
+				{Scanner: "pip-audit", ID: "PYSEC-2023-1", Package: "starlette", Version: "0.27.0", FixedVersion: "0.36.2", Severity: SeverityUnknown, Title: "Denial of service."},
+			},
+		},
+		{
+			name:   "pip-audit list format",
+			parse:  parsePipAudit,
+			report: `[{"name": "jinja2", "version": "2.0", "vulns": [{"id": "GHSA-1", "fix_versions": []}]}]`,
+			want: []Finding{
+				{Scanner: "pip-audit", ID: "GHSA-1", Package: "jinja2", Version: "2.0", Severity: SeverityUnknown},
+			},
+		},
+		{
+			name:  "npm audit",
+			parse: parseNpmAudit,
+			report: `{"auditReportVersion": 2, "vulnerabilities": {
+				"semver": {"name": "semver", "severity": "moderate", "via": [
+					{"source": 1, "name": "semver", "title": "ReDoS in semver", "url": "https://github.com/advisories/GHSA-c2qf", "severity": "moderate", "range": "<7.5.2"}
+				], "fixAvailable": {"name": "semver", "version": "7.5.4"}},
+				"make-dir": {"name": "make-dir", "severity": "moderate", "via": ["semver"], "fixAvailable": false}
+			}}`,
+			want: []Finding{
+				{Scanner: "npm", ID: "GHSA-c2qf", Package: "semver", Version: "<7.5.2", FixedVersion: "7.5.4", Severity: SeverityMedium, Title: "ReDoS in semver"},
+			},
+		},
+		{
+			name:    "npm audit error",
+			parse:   parseNpmAudit,
+			report:  `{"error": {"code": "ENOLOCK", "summary": "This command requires an existing lockfile."}}`,
+			wantErr: true,
+		},
+		{
+			name:  "govulncheck",
+			parse: parseGovulncheck,
+			report: `{"config": {"protocol_version": "v1.0.0"}}
+{"finding": {"osv": "GO-2023-1", "fixed_version": "v0.17.0", "trace": [{"module": "golang.org/x/net", "version": "v0.10.0", "package": "golang.org/x/net/html", "function": "Parse"}]}}
+{"finding": {"osv": "GO-2023-2", "trace": [{"module": "golang.org/x/text", "version": "v0.3.0"}]}}
+{"osv": {"id": "GO-2023-1", "summary": "Infinite loop in html.Parse"}}
+`,
+			want: []Finding{
+				{Scanner: "govulncheck", ID: "GO-2023-1", Package: "golang.org/x/net", Version: "v0.10.0", FixedVersion: "v0.17.0", Severity: SeverityUnknown, Title: "Infinite loop in html.Parse"},
+			},
+		},
+		{
+			name:  "trivy",
+			parse: parseTrivy,
+			report: `{"SchemaVersion": 2, "Results": [
+				{"Target": "demo:latest (debian 12.4)", "Vulnerabilities": [
+					{"VulnerabilityID": "CVE-2023-1", "PkgName": "openssl", "InstalledVersion": "3.0.11", "FixedVersion": "3.0.13", "Severity": "CRITICAL", "Title": "openssl: flaw"}
+				]},
+				{"Target": "requirements.txt"}
+			]}`,
+			want: []Finding{
+				{Scanner: "trivy", ID: "CVE-2023-1", Package: "openssl", Version: "3.0.11", FixedVersion: "3.0.13", Severity: SeverityCritical, Title: "openssl: flaw"},
+			},
+		},
+		{
+			name:    "not JSON",
+			parse:   parseTrivy,
+			report:  "FATAL image not found",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.parse([]byte(tt.report))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parse() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestThreshold(t *testing.T) {
+	findings := []Finding{
+		{ID: "a", Severity: SeverityLow},
+		{ID: "b", Severity: SeverityMedium},
+		{ID: "c", Severity: SeverityUnknown},
+		{ID: "d", Severity: SeverityCritical},
+	}
+
+	tests := []struct {
+		threshold string
+		want      int
+		wantErr   bool
+	}{
+		{threshold: "low", want: 4},
+		{threshold: "MEDIUM", want: 3},
+		{threshold: "high", want: 2},
+		{threshold: "critical", want: 1},
+		{threshold: "none", want: 0},
+		{threshold: "unknown", wantErr: true},
+		{threshold: "severe", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.threshold, func(t *testing.T) {
+			threshold, err := ParseThreshold(tt.threshold)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseThreshold() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := len(Failing(findings, threshold)); got != tt.want {
+				t.Errorf("Failing(%s) = %d findings, want %d", threshold, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProjectJobs(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"pyproject.toml", "requirements.txt", "package.json", "go.mod"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got [][]string
+	for _, job := range ProjectJobs(dir) {
+		got = append(got, job.Args)
+	}
+	want := [][]string{
+		{"pip-audit", "--format", "json", "--progress-spinner", "off", "-r", "requirements.txt"},
+		{"govulncheck", "-json", "./..."},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ProjectJobs() = %q, want %q (npm audit needs package-lock.json)", got, want)
+	}
+}
@@ -0,0 +1,290 @@
+// Package workspace wires services generated into a devinit workspace, a
+// directory holding devinit-workspace.yaml, into the files they share: the
+// root compose file, shared env files and the CI matrix. Structured files are
+// combined with the merge engine, so comments and unrelated keys survive.
+package workspace
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/renan-dev/devinit/internal/merge"
+	"github.com/renan-dev/devinit/internal/template"
+)
+
+// FileName is the file that marks the root of a workspace
+const FileName = "devinit-workspace.yaml"
+
+// Defaults for the shared files of a workspace
+const (
+	DefaultCompose = "compose.yaml"
+	DefaultEnvFile = ".env"
+)
+
+// Workspace is the configuration of a workspace and the services generated
+// into it
+type Workspace struct {
+	// Root is the directory holding the workspace file
+	Root string `yaml:"-"`
+
+	// Compose is the compose file services are added to, relative to Root
+	// (default compose.yaml)
+	Compose string `yaml:"compose,omitempty"`
+
+	// EnvFiles receive the host and port of every service and are passed to
+	// them by compose (default .env)
+	EnvFiles []string `yaml:"env_files,omitempty"`
+
+	// CI lists the services in a CI matrix (nil leaves CI alone)
+	CI *CI `yaml:"ci,omitempty"`
+
+	// Services generated into the workspace, maintained by devinit
+	Services []template.Service `yaml:"services,omitempty"`
+}
+
+// CI locates the list of services in a CI configuration
+type CI struct {
+	// File is the CI configuration, e.g. .github/workflows/ci.yml
+	File string `yaml:"file"`
+
+	// Matrix is the dotted path of the list of services in File, e.g.
+	// jobs.test.strategy.matrix.service
+	Matrix string `yaml:"matrix"`
+}
+
+// Find returns the workspace dir belongs to, looking for the workspace file
+// in dir and its parents, or nil when dir is not in a workspace
+func Find(dir string) (*Workspace, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, FileName)); err == nil {
+			return Load(dir)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+// Load reads the workspace rooted at root
+func Load(root string) (*Workspace, error) {
+	data, err := os.ReadFile(filepath.Join(root, FileName))
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Workspace{Root: root}
+	if err := yaml.Unmarshal(data, w); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", FileName, err)
+	}
+	if w.Compose == "" {
+		w.Compose = DefaultCompose
+	}
+	if len(w.EnvFiles) == 0 {
+		w.EnvFiles = []string{DefaultEnvFile}
+	}
+	if w.CI != nil && (w.CI.File == "" || w.CI.Matrix == "") {
+		return nil, fmt.Errorf("invalid %s: ci needs a file and a matrix path", FileName)
+	}
+	return w, nil
+}
+
+// Siblings returns the services of the workspace other than the named one
+func (w *Workspace) Siblings(name string) []template.Service {
+	var siblings []template.Service
+	for _, svc := range w.Services {
+		if svc.Name != name {
+			siblings = append(siblings, svc)
+		}
+	}
+	return siblings
+}
+
+// ServiceDir returns the directory of a project relative to the workspace
+// root, slash-separated
+func (w *Workspace) ServiceDir(projectDir string) (string, error) {
+	abs, err := filepath.Abs(projectDir)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(w.Root, abs)
+	if err != nil {
+		return "", err
+	}
+	return path.Clean(filepath.ToSlash(rel)), nil
+}
+
+// Wire adds svc to the shared files of the workspace and records it in the
+// workspace file, returning the files it changed relative to Root. The
+// compose file only gets services built from a Dockerfile. Each file is
+// wired independently; failures are joined in the error.
+func (w *Workspace) Wire(svc template.Service, docker bool) ([]string, error) {
+	var (
+		changed []string
+		errs    []error
+	)
+	wire := func(file string, fn func(string) error) {
+		if err := fn(filepath.Join(w.Root, filepath.FromSlash(file))); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", file, err))
+			return
+		}
+		changed = append(changed, file)
+	}
+
+	if docker {
+		wire(w.Compose, func(file string) error { return w.wireCompose(file, svc) })
+	}
+	for _, envFile := range w.EnvFiles {
+		wire(envFile, func(file string) error { return wireEnv(file, svc) })
+	}
+	if w.CI != nil {
+		wire(w.CI.File, func(file string) error { return wireCI(file, w.CI.Matrix, svc) })
+	}
+	wire(FileName, func(file string) error { return w.register(file, svc) })
+
+	return changed, errors.Join(errs...)
+}
+
+// wireCompose adds the service to the compose file, creating it if needed
+func (w *Workspace) wireCompose(file string, svc template.Service) error {
+	service := map[string]interface{}{
+		"build":    "./" + svc.Dir,
+		"env_file": w.EnvFiles,
+	}
+	if svc.Port != 0 {
+		port := strconv.Itoa(svc.Port)
+		service["ports"] = []string{port + ":" + port}
+	}
+	patch := map[string]interface{}{
+		"services": map[string]interface{}{svc.Name: service},
+	}
+	return mergeYAMLFile(file, patch, merge.ListsUnique, true)
+}
+
+// wireEnv adds the host and port of the service to an env file, keeping
+// values already there
+func wireEnv(file string, svc template.Service) error {
+	data, err := os.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	prefix := EnvPrefix(svc.Name)
+	entries := [][2]string{{prefix + "_HOST", svc.Host}}
+	if svc.Port != 0 {
+		entries = append(entries, [2]string{prefix + "_PORT", strconv.Itoa(svc.Port)})
+	}
+
+	var add bytes.Buffer
+	for _, entry := range entries {
+		if !hasEnvKey(data, entry[0]) {
+			fmt.Fprintf(&add, "%s=%s\n", entry[0], entry[1])
+		}
+	}
+	if add.Len() == 0 {
+		return nil
+	}
+	if len(data) > 0 && !bytes.HasSuffix(data, []byte("\n")) {
+		data = append(data, '\n')
+	}
+	return os.WriteFile(file, append(data, add.Bytes()...), 0644)
+}
+
+// hasEnvKey reports whether an env file assigns key
+func hasEnvKey(data []byte, key string) bool {
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimPrefix(strings.TrimSpace(line), "export ")
+		if name, _, ok := strings.Cut(line, "="); ok && strings.TrimSpace(name) == key {
+			return true
+		}
+	}
+	return false
+}
+
+// EnvPrefix returns the prefix of the environment variables naming a
+// service, e.g. ORDERS_API for orders-api
+func EnvPrefix(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// wireCI adds the service to the list at the dotted matrix path of an
+// existing CI configuration
+func wireCI(file, matrix string, svc template.Service) error {
+	if _, err := os.Stat(file); err != nil {
+		return err
+	}
+
+	keys := strings.Split(matrix, ".")
+	var patch interface{} = []string{svc.Name}
+	for i := len(keys) - 1; i >= 0; i-- {
+		patch = map[string]interface{}{keys[i]: patch}
+	}
+	return mergeYAMLFile(file, patch, merge.ListsUnique, false)
+}
+
+// register records the service in the workspace file, replacing an earlier
+// entry of the same name
+func (w *Workspace) register(file string, svc template.Service) error {
+	services := make([]template.Service, 0, len(w.Services)+1)
+	for _, existing := range w.Services {
+		if existing.Name != svc.Name {
+			services = append(services, existing)
+		}
+	}
+	services = append(services, svc)
+
+	if err := mergeYAMLFile(file, map[string]interface{}{"services": services}, merge.ListsReplace, false); err != nil {
+		return err
+	}
+	w.Services = services
+	return nil
+}
+
+// mergeYAMLFile merges patch into the YAML file, which is created when
+// create is set and it does not exist
+func mergeYAMLFile(file string, patch interface{}, lists merge.ListStrategy, create bool) error {
+	base, err := os.ReadFile(file)
+	if err != nil && !(create && os.IsNotExist(err)) {
+		return err
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(patch); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+
+	merged, err := merge.Merge(merge.FormatYAML, base, buf.Bytes(), merge.Options{Lists: lists})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(file, merged, 0644)
+}
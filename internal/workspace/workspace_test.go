@@ -0,0 +1,140 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/renan-dev/devinit/internal/template"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestFind(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, FileName), "env_files: [.env.shared]\n")
+
+	ws, err := Find(filepath.Join(root, "services", "billing"))
+	if err != nil {
+		t.Fatalf("Find() unexpected error: %v", err)
+	}
+	if ws == nil || ws.Compose != DefaultCompose || !reflect.DeepEqual(ws.EnvFiles, []string{".env.shared"}) {
+		t.Fatalf("Find() = %+v, want the workspace with defaults applied", ws)
+	}
+	if dir, _ := ws.ServiceDir(filepath.Join(root, "services", "billing")); dir != "services/billing" {
+		t.Errorf("ServiceDir() = %q, want services/billing", dir)
+	}
+
+	if ws, err := Find(t.TempDir()); ws != nil || err != nil {
+		t.Errorf("Find() outside a workspace = %+v, %v, want nil", ws, err)
+	}
+}
+
+func TestWire(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, FileName), `# Services of the shop
+ci:
+  file: .github/workflows/ci.yml
+  matrix: jobs.test.strategy.matrix.service
+services:
+  - name: orders
+    host: orders
+    port: 8000
+    dir: services/orders
+    template: python/fastapi
+`)
+	writeFile(t, filepath.Join(root, ".env"), "ORDERS_HOST=orders\nORDERS_PORT=8000")
+	writeFile(t, filepath.Join(root, DefaultCompose), `services:
+  # The first service
+  orders:
+    build: ./services/orders
+`)
+	writeFile(t, filepath.Join(root, ".github", "workflows", "ci.yml"), `on: push
+jobs:
+  test:
+    strategy:
+      matrix:
+        service: [orders]
+    runs-on: ubuntu-latest
+`)
+
+	ws, err := Load(root)
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+	if got := ws.Siblings("billing-api"); len(got) != 1 || got[0].Name != "orders" {
+		t.Errorf("Siblings() = %+v, want orders", got)
+	}
+
+	svc := template.Service{Name: "billing-api", Host: "billing-api", Port: 8001, Dir: "services/billing-api", Template: "go/chi"}
+	changed, err := ws.Wire(svc, true)
+	if err != nil {
+		t.Fatalf("Wire() unexpected error: %v", err)
+	}
+	if want := []string{DefaultCompose, ".env", ".github/workflows/ci.yml", FileName}; !reflect.DeepEqual(changed, want) {
+		t.Errorf("Wire() changed %q, want %q", changed, want)
+	}
+
+	// Wiring again changes nothing
+	if _, err := ws.Wire(svc, true); err != nil {
+		t.Fatalf("Wire() again unexpected error: %v", err)
+	}
+
+	for file, want := range map[string][]string{
+		DefaultCompose:             {"# The first service", "  billing-api:\n    build: ./services/billing-api\n    env_file:\n      - .env\n    ports:\n      - 8001:8001\n"},
+		".env":                     {"ORDERS_PORT=8000\nBILLING_API_HOST=billing-api\nBILLING_API_PORT=8001\n"},
+		".github/workflows/ci.yml": {"service: [orders, billing-api]", "runs-on: ubuntu-latest"},
+		FileName:                   {"# Services of the shop", "  - name: billing-api\n    host: billing-api\n    port: 8001\n"},
+	} {
+		got := readFile(t, filepath.Join(root, filepath.FromSlash(file)))
+		for _, w := range want {
+			if strings.Count(got, w) != 1 {
+				t.Errorf("%s = \n%s\nwant it to contain %q once", file, got, w)
+			}
+		}
+	}
+
+	reloaded, err := Load(root)
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+	if len(reloaded.Services) != 2 || reloaded.Services[1] != svc {
+		t.Errorf("Load() services = %+v, want orders and %+v", reloaded.Services, svc)
+	}
+}
+
+func TestWireMissingCI(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, FileName), "ci:\n  file: ci.yml\n  matrix: jobs.test.matrix\n")
+
+	ws, err := Load(root)
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+	changed, err := ws.Wire(template.Service{Name: "api", Host: "api"}, false)
+	if err == nil || !strings.Contains(err.Error(), "ci.yml") {
+		t.Errorf("Wire() error = %v, want the missing CI file", err)
+	}
+	if want := []string{".env", FileName}; !reflect.DeepEqual(changed, want) {
+		t.Errorf("Wire() changed %q, want %q (no compose without docker)", changed, want)
+	}
+}
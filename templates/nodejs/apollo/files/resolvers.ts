@@ -0,0 +1,19 @@
+import type { Book, Resolvers } from './generated/graphql.js';
+
+const books: Book[] = [
+  { id: '1', title: 'The Pragmatic Programmer', author: 'Hunt & Thomas' },
+];
+
+export const resolvers: Resolvers = {
+  Query: {
+    hello: (_parent, { name }) => `Hello, ${name ?? 'world'}!`,
+    books: () => books,
+  },
+  Mutation: {
+    addBook: (_parent, { title, author }) => {
+      const book = { id: String(books.length + 1), title, author };
+      books.push(book);
+      return book;
+    },
+  },
+};
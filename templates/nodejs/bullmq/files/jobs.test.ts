@@ -0,0 +1,22 @@
+import type { Job } from "bullmq";
+import { describe, expect, it } from "vitest";
+
+import { processJob } from "../src/jobs.js";
+
+const job = (name: string, data: unknown) => ({ name, data }) as Job;
+
+describe("processJob", () => {
+  it("adds numbers", async () => {
+    expect(await processJob(job("add", { x: 2, y: 3 }))).toBe(5);
+  });
+
+  it("sends welcome email", async () => {
+    expect(await processJob(job("sendWelcomeEmail", { email: "user@example.com" }))).toBe(
+      "sent to user@example.com",
+    );
+  });
+
+  it("rejects unknown jobs", async () => {
+    await expect(processJob(job("unknown", {}))).rejects.toThrow("unknown job");
+  });
+});
@@ -0,0 +1,28 @@
+import type { Job } from "bullmq";
+
+export interface JobPayloads {
+  add: { x: number; y: number };
+  sendWelcomeEmail: { email: string };
+}
+
+export type JobName = keyof JobPayloads;
+
+export const handlers: { [N in JobName]: (data: JobPayloads[N]) => Promise<unknown> } = {
+  async add({ x, y }) {
+    return x + y;
+  },
+
+  async sendWelcomeEmail({ email }) {
+    // Replace with a real email integration
+    console.log(`sending welcome email to ${email}`);
+    return `sent to ${email}`;
+  },
+};
+
+export async function processJob(job: Job) {
+  const handler = handlers[job.name as JobName] as ((data: unknown) => Promise<unknown>) | undefined;
+  if (!handler) {
+    throw new Error(`unknown job: ${job.name}`);
+  }
+  return handler(job.data);
+}
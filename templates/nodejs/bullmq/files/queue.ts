@@ -0,0 +1,16 @@
+import { Queue } from "bullmq";
+
+import { config, redisConnection } from "./config.js";
+import type { JobName, JobPayloads } from "./jobs.js";
+
+// Producer side: import this from an API project to enqueue jobs.
+export const queue = new Queue(config.queueName, { connection: redisConnection() });
+
+export function enqueue<N extends JobName>(name: N, data: JobPayloads[N]) {
+  return queue.add(name, data, {
+    attempts: 3,
+    backoff: { type: "exponential", delay: 1000 },
+    removeOnComplete: 1000,
+    removeOnFail: 5000,
+  });
+}
@@ -0,0 +1,39 @@
+import { createServer } from "node:http";
+
+import { Worker } from "bullmq";
+
+import { config, redisConnection } from "./config.js";
+import { processJob } from "./jobs.js";
+
+const worker = new Worker(config.queueName, processJob, {
+  connection: redisConnection(),
+  concurrency: config.concurrency,
+});
+
+worker.on("failed", (job, err) => {
+  console.error(`job ${job?.id} (${job?.name}) failed: ${err.message}`);
+});
+
+// Health endpoint for container orchestration
+const health = createServer((req, res) => {
+  if (req.url !== "/health") {
+    res.writeHead(404).end();
+    return;
+  }
+  const healthy = worker.isRunning();
+  res.writeHead(healthy ? 200 : 503, { "Content-Type": "application/json" });
+  res.end(JSON.stringify({ status: healthy ? "healthy" : "unhealthy" }));
+});
+
+health.listen(config.healthPort, () => {
+  console.log(`worker consuming "${config.queueName}", health on :${config.healthPort}`);
+});
+
+async function shutdown() {
+  health.close();
+  await worker.close();
+  process.exit(0);
+}
+
+process.on("SIGINT", shutdown);
+process.on("SIGTERM", shutdown);
@@ -0,0 +1,36 @@
+import request from 'supertest';
+import { describe, expect, it } from 'vitest';
+
+import { createApp } from '../src/app.js';
+
+const app = createApp();
+
+describe('app', () => {
+  it('reports healthy', async () => {
+    const response = await request(app).get('/health');
+    expect(response.status).toBe(200);
+    expect(response.body.status).toBe('healthy');
+  });
+
+  it('greets by name', async () => {
+    const response = await request(app).get('/greetings').query({ name: 'Ada' });
+    expect(response.status).toBe(200);
+    expect(response.body).toEqual({ message: 'Hello, Ada!' });
+  });
+
+  it('greets the world by default', async () => {
+    const response = await request(app).get('/greetings');
+    expect(response.body).toEqual({ message: 'Hello, world!' });
+  });
+
+  it('rejects invalid queries', async () => {
+    const response = await request(app).get('/greetings').query({ name: '' });
+    expect(response.status).toBe(400);
+  });
+
+  it('serves the OpenAPI document', async () => {
+    const response = await request(app).get('/openapi.json');
+    expect(response.status).toBe(200);
+    expect(response.body.paths).toHaveProperty('/greetings');
+  });
+});
@@ -0,0 +1,26 @@
+// Export the OpenAPI document built from the zod schemas to docs/openapi.json.
+//
+//   npm run openapi:export   write the spec
+//   npm run openapi:check    fail if the committed spec is stale
+import { existsSync, mkdirSync, readFileSync, writeFileSync } from 'node:fs';
+import { dirname } from 'node:path';
+import { fileURLToPath } from 'node:url';
+
+import '../src/app.js';
+import { generateOpenApiDocument } from '../src/openapi.js';
+
+const specPath = fileURLToPath(new URL('../docs/openapi.json', import.meta.url));
+const spec = JSON.stringify(generateOpenApiDocument(), null, 2) + '\n';
+
+if (process.argv.includes('--check')) {
+  const current = existsSync(specPath) ? readFileSync(specPath, 'utf8') : '';
+  if (current !== spec) {
+    console.error('docs/openapi.json is out of date; run npm run openapi:export');
+    process.exit(1);
+  }
+  console.log('docs/openapi.json is up to date');
+} else {
+  mkdirSync(dirname(specPath), { recursive: true });
+  writeFileSync(specPath, spec);
+  console.log('wrote docs/openapi.json');
+}
@@ -0,0 +1,55 @@
+import { Router } from 'express';
+
+import { registry, z } from '../openapi.js';
+
+const GreetingQuery = z.object({
+  name: z.string().min(1).max(100).optional().openapi({ example: 'Ada' }),
+});
+
+const Greeting = registry.register(
+  'Greeting',
+  z.object({
+    message: z.string().openapi({ example: 'Hello, Ada!' }),
+  }),
+);
+
+const ValidationError = registry.register(
+  'ValidationError',
+  z.object({
+    error: z.string(),
+    issues: z.array(z.object({ path: z.array(z.union([z.string(), z.number()])), message: z.string() })),
+  }),
+);
+
+registry.registerPath({
+  method: 'get',
+  path: '/greetings',
+  summary: 'Greet someone',
+  request: { query: GreetingQuery },
+  responses: {
+    200: {
+      description: 'The greeting',
+      content: { 'application/json': { schema: Greeting } },
+    },
+    400: {
+      description: 'Invalid query parameters',
+      content: { 'application/json': { schema: ValidationError } },
+    },
+  },
+});
+
+export const greetings = Router();
+
+greetings.get('/', (req, res) => {
+  const query = GreetingQuery.safeParse(req.query);
+  if (!query.success) {
+    res.status(400).json({
+      error: 'invalid query parameters',
+      issues: query.error.issues.map(({ path, message }) => ({ path, message })),
+    });
+    return;
+  }
+
+  const body: z.infer<typeof Greeting> = { message: `Hello, ${query.data.name ?? 'world'}!` };
+  res.json(body);
+});
@@ -0,0 +1,13 @@
+import { describe, expect, it } from "vitest";
+
+import { greet } from "../src/index.js";
+
+describe("greet", () => {
+  it("greets by name", () => {
+    expect(greet("World")).toBe("Hello, World!");
+  });
+
+  it("rejects empty names", () => {
+    expect(() => greet("")).toThrow("name must not be empty");
+  });
+});
@@ -0,0 +1,11 @@
+/**
+ * Returns a greeting for name.
+ *
+ * @throws {Error} if name is empty
+ */
+export function greet(name: string): string {
+  if (!name) {
+    throw new Error("name must not be empty");
+  }
+  return `Hello, ${name}!`;
+}
@@ -0,0 +1,13 @@
+<?php
+
+namespace Tests\Feature;
+
+use Tests\TestCase;
+
+class HealthTest extends TestCase
+{
+    public function test_health_endpoint_is_up(): void
+    {
+        $this->get('/up')->assertOk();
+    }
+}
@@ -0,0 +1,13 @@
+import logging
+
+logger = logging.getLogger(__name__)
+
+
+async def add(ctx: dict, x: int, y: int) -> int:
+    return x + y
+
+
+async def send_welcome_email(ctx: dict, email: str) -> str:
+    # Replace with a real email integration
+    logger.info("sending welcome email to %s", email)
+    return f"sent to {email}"
@@ -0,0 +1 @@
+# Test initialization
@@ -0,0 +1,13 @@
+import pytest
+
+from src.tasks import add, send_welcome_email
+
+
+@pytest.mark.asyncio
+async def test_add():
+    assert await add({}, 2, 3) == 5
+
+
+@pytest.mark.asyncio
+async def test_send_welcome_email():
+    assert await send_welcome_email({}, "user@example.com") == "sent to user@example.com"
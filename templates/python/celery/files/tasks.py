@@ -0,0 +1,17 @@
+import logging
+
+from src.worker import app
+
+logger = logging.getLogger(__name__)
+
+
+@app.task
+def add(x: int, y: int) -> int:
+    return x + y
+
+
+@app.task(bind=True, autoretry_for=(ConnectionError,), retry_backoff=True, max_retries=5)
+def send_welcome_email(self, email: str) -> str:
+    # Replace with a real email integration
+    logger.info("sending welcome email to %s", email)
+    return f"sent to {email}"
@@ -0,0 +1,19 @@
+import pytest
+
+from src.tasks import add, send_welcome_email
+from src.worker import app
+
+
+@pytest.fixture(autouse=True)
+def eager_tasks():
+    app.conf.task_always_eager = True
+    yield
+    app.conf.task_always_eager = False
+
+
+def test_add():
+    assert add.delay(2, 3).get() == 5
+
+
+def test_send_welcome_email():
+    assert send_welcome_email.delay("user@example.com").get() == "sent to user@example.com"
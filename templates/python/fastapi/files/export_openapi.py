@@ -0,0 +1,47 @@
+"""Export the OpenAPI spec generated by FastAPI to docs/openapi.json.
+
+Usage:
+    python scripts/export_openapi.py          # write the spec
+    python scripts/export_openapi.py --check  # fail if the committed spec is stale
+"""
+
+import argparse
+import json
+import sys
+from pathlib import Path
+
+ROOT = Path(__file__).resolve().parent.parent
+SPEC_PATH = ROOT / "docs" / "openapi.json"
+
+sys.path.insert(0, str(ROOT))
+
+from src.main import app  # noqa: E402
+
+
+def render() -> str:
+    return json.dumps(app.openapi(), indent=2, sort_keys=True) + "\n"
+
+
+def main() -> int:
+    parser = argparse.ArgumentParser(description=__doc__.splitlines()[0])
+    parser.add_argument("--check", action="store_true", help="compare instead of writing")
+    args = parser.parse_args()
+
+    spec = render()
+
+    if args.check:
+        current = SPEC_PATH.read_text() if SPEC_PATH.exists() else ""
+        if current != spec:
+            print(f"{SPEC_PATH.relative_to(ROOT)} is out of date; run scripts/export_openapi.py")
+            return 1
+        print(f"{SPEC_PATH.relative_to(ROOT)} is up to date")
+        return 0
+
+    SPEC_PATH.parent.mkdir(parents=True, exist_ok=True)
+    SPEC_PATH.write_text(spec)
+    print(f"wrote {SPEC_PATH.relative_to(ROOT)}")
+    return 0
+
+
+if __name__ == "__main__":
+    sys.exit(main())
@@ -0,0 +1,18 @@
+#!/bin/sh
+# Pin every image in compose.yaml to the digest it currently resolves to, so
+# every machine pulls exactly the same image. Re-run to pick up new releases.
+#
+# Usage: sh scripts/pin-images.sh [compose file]
+set -eu
+
+file="${1:-compose.yaml}"
+
+for image in $(sed -n -E 's/^[[:space:]]+image:[[:space:]]*([^@[:space:]]+).*/\1/p' "$file" | sort -u); do
+  docker pull --quiet "$image" >/dev/null
+  digest=$(docker image inspect --format '{{index .RepoDigests 0}}' "$image")
+  digest="${digest#*@}"
+  sed -i.bak -E "s|(image:[[:space:]]*)${image}(@sha256:[0-9a-f]+)?[[:space:]]*\$|\1${image}@${digest}|" "$file"
+  echo "${image} -> ${digest}"
+done
+
+rm -f "$file.bak"
@@ -0,0 +1 @@
+# Generated protobuf code lives here. Regenerate with `make generate`.
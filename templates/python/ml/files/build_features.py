@@ -0,0 +1,25 @@
+"""Build model features from the interim dataset."""
+import pandas as pd
+
+from src.config import INTERIM_DIR, PROCESSED_DIR
+
+TARGET = "target"
+
+
+def build_features(df: pd.DataFrame) -> pd.DataFrame:
+    features = df.drop(columns=[TARGET])
+    # Standardize numeric columns
+    features = (features - features.mean()) / features.std(ddof=0)
+    features[TARGET] = df[TARGET]
+    return features
+
+
+def main() -> None:
+    PROCESSED_DIR.mkdir(parents=True, exist_ok=True)
+    df = pd.read_csv(INTERIM_DIR / "dataset.csv")
+    build_features(df).to_csv(PROCESSED_DIR / "features.csv", index=False)
+    print(f"wrote features to {PROCESSED_DIR / 'features.csv'}")
+
+
+if __name__ == "__main__":
+    main()
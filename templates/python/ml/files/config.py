@@ -0,0 +1,19 @@
+"""Project paths and parameters."""
+from pathlib import Path
+
+import yaml
+
+ROOT = Path(__file__).resolve().parent.parent
+
+DATA_DIR = ROOT / "data"
+RAW_DIR = DATA_DIR / "raw"
+INTERIM_DIR = DATA_DIR / "interim"
+PROCESSED_DIR = DATA_DIR / "processed"
+EXTERNAL_DIR = DATA_DIR / "external"
+MODELS_DIR = ROOT / "models"
+REPORTS_DIR = ROOT / "reports"
+
+
+def load_params(path: Path = ROOT / "params.yaml") -> dict:
+    with open(path) as f:
+        return yaml.safe_load(f)
@@ -0,0 +1,24 @@
+"""Turn raw data into an interim dataset.
+
+Replace the sample dataset with your own loading logic reading from data/raw.
+"""
+import pandas as pd
+from sklearn.datasets import load_iris
+
+from src.config import INTERIM_DIR
+
+
+def make_dataset() -> pd.DataFrame:
+    df = load_iris(as_frame=True).frame
+    return df.dropna().drop_duplicates()
+
+
+def main() -> None:
+    INTERIM_DIR.mkdir(parents=True, exist_ok=True)
+    df = make_dataset()
+    df.to_csv(INTERIM_DIR / "dataset.csv", index=False)
+    print(f"wrote {len(df)} rows to {INTERIM_DIR / 'dataset.csv'}")
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,14 @@
+import pandas as pd
+
+from src.features.build_features import TARGET, build_features
+
+
+def test_build_features_standardizes_columns():
+    df = pd.DataFrame({"a": [1.0, 2.0, 3.0], "b": [10.0, 20.0, 30.0], TARGET: [0, 1, 0]})
+
+    features = build_features(df)
+
+    assert list(features.columns) == ["a", "b", TARGET]
+    assert abs(features["a"].mean()) < 1e-9
+    assert abs(features["b"].std(ddof=0) - 1.0) < 1e-9
+    assert features[TARGET].tolist() == [0, 1, 0]
@@ -0,0 +1,8 @@
+require "test_helper"
+
+class HealthTest < ActionDispatch::IntegrationTest
+  test "health check is up" do
+    get rails_health_check_path
+    assert_response :success
+  end
+end
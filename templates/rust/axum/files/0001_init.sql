@@ -0,0 +1,5 @@
+-- Initial schema
+CREATE TABLE IF NOT EXISTS greetings (
+    id INTEGER PRIMARY KEY,
+    message TEXT NOT NULL
+);
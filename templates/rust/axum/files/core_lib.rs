@@ -0,0 +1,26 @@
+//! Domain types shared between the workspace crates.
+
+use serde::{Deserialize, Serialize};
+
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct Greeting {
+    pub message: String,
+}
+
+impl Greeting {
+    pub fn new(name: &str) -> Self {
+        Self {
+            message: format!("Hello, {name}!"),
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn greeting_message() {
+        assert_eq!(Greeting::new("World").message, "Hello, World!");
+    }
+}
@@ -0,0 +1,11 @@
+// Package templates embeds the templates shipped with devinit, so a binary
+// installed on its own (go install, a symlink in PATH) still has them
+package templates
+
+import "embed"
+
+// FS holds the template directories of this directory. It also holds this
+// file, which is not a template and is skipped when the templates are used.
+//
+//go:embed all:*
+var FS embed.FS